@@ -1,6 +1,7 @@
 package config
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"time"
 
@@ -11,13 +12,18 @@ import (
 type Config struct {
 	App      AppConfig      `mapstructure:"app"`
 	Server   ServerConfig   `mapstructure:"server"`
+	GRPC     GRPCConfig     `mapstructure:"grpc"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Redis    RedisConfig    `mapstructure:"redis"`
 	Queue    QueueConfig    `mapstructure:"queue"`
 	Worker   WorkerConfig   `mapstructure:"worker"`
+	Stream   StreamConfig   `mapstructure:"stream"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	CORS     CORSConfig     `mapstructure:"cors"`
 	Models   ModelsConfig   `mapstructure:"models"`
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	Tracing  TracingConfig  `mapstructure:"tracing"`
 }
 
 // AppConfig 应用基本配置
@@ -33,6 +39,38 @@ type ServerConfig struct {
 	Port         int           `mapstructure:"port"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// ShutdownTimeout 收到停止信号后依次排空 HTTP 请求、Worker 和队列的总时长
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// MaxBodyBytes /api/v1 接口请求体的最大字节数，超过时返回 413
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+}
+
+// DefaultMaxBodyBytes max_body_bytes 未配置时的请求体上限
+const DefaultMaxBodyBytes int64 = 10 << 20
+
+// GetMaxBodyBytes 获取请求体上限，未配置时返回默认值
+func (c *ServerConfig) GetMaxBodyBytes() int64 {
+	if c.MaxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return c.MaxBodyBytes
+}
+
+// DefaultShutdownTimeout shutdown_timeout 未配置时的停止宽限时间
+const DefaultShutdownTimeout = 45 * time.Second
+
+// GetShutdownTimeout 获取停止宽限时间，未配置时返回默认值
+func (c *ServerConfig) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return c.ShutdownTimeout
+}
+
+// GRPCConfig 任务 gRPC 服务配置，与 HTTP 服务共用 server.host 和认证配置
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
 }
 
 // DatabaseConfig 数据库配置
@@ -62,15 +100,70 @@ type RedisConfig struct {
 
 // QueueConfig 队列配置
 type QueueConfig struct {
-	HighPriorityQueue   string        `mapstructure:"high_priority_queue"`
-	MediumPriorityQueue string        `mapstructure:"medium_priority_queue"`
-	LowPriorityQueue    string        `mapstructure:"low_priority_queue"`
-	DelayedQueue        string        `mapstructure:"delayed_queue"`
-	ProcessingQueue     string        `mapstructure:"processing_queue"`
-	MaxQueueSize        int           `mapstructure:"max_queue_size"`
-	TaskTimeout         time.Duration `mapstructure:"task_timeout"`
-	MaxRetries          int           `mapstructure:"max_retries"`
-	RetryDelay          time.Duration `mapstructure:"retry_delay"`
+	HighPriorityQueue   string          `mapstructure:"high_priority_queue"`
+	MediumPriorityQueue string          `mapstructure:"medium_priority_queue"`
+	LowPriorityQueue    string          `mapstructure:"low_priority_queue"`
+	DelayedQueue        string          `mapstructure:"delayed_queue"`
+	ProcessingQueue     string          `mapstructure:"processing_queue"`
+	StreamChannel       string          `mapstructure:"stream_channel"`
+	CancelChannel       string          `mapstructure:"cancel_channel"`
+	EventChannel        string          `mapstructure:"event_channel"`
+	IdempotencyKey      string          `mapstructure:"idempotency_key"`
+	IdempotencyTTL      time.Duration   `mapstructure:"idempotency_ttl"`
+	CacheTTL            time.Duration   `mapstructure:"cache_ttl"` // 任务结果缓存的有效期，0 表示不启用
+	InflightKey         string          `mapstructure:"inflight_key"`
+	CapacityKey         string          `mapstructure:"capacity_key"`
+	KeyInflightKey      string          `mapstructure:"key_inflight_key"` // 每个 API Key 处理中的任务数（Hash，field 为 Key 名称）
+	RateLimitKey        string          `mapstructure:"rate_limit_key"`   // 模型请求限流的键前缀，每个模型一个有序集合
+	MaxQueueSize        int             `mapstructure:"max_queue_size"`
+	HighPriorityBypass  bool            `mapstructure:"high_priority_bypass"` // 高优先级任务不受 max_queue_size 限制
+	MaxInputBytes       int             `mapstructure:"max_input_bytes"`
+	MaxOutputBytes      int             `mapstructure:"max_output_bytes"`
+	TaskTimeout         time.Duration   `mapstructure:"task_timeout"`
+	MaxRetries          int             `mapstructure:"max_retries"`
+	TypeMaxRetries      map[string]int  `mapstructure:"type_max_retries"` // 按任务类型覆盖 max_retries
+	RetryDelay          time.Duration   `mapstructure:"retry_delay"`
+	MaxDelayCount       int             `mapstructure:"max_delay_count"`
+	TaskRetentionDays   int             `mapstructure:"task_retention_days"`
+	ArchiveInterval     time.Duration   `mapstructure:"archive_interval"`
+	Degraded            DegradedConfig  `mapstructure:"degraded"`
+	Weights             PriorityWeights `mapstructure:"weights"`
+	// AgingThreshold 任务在低、中优先级队列中等待超过该时间后提升一级，0 表示不提升
+	AgingThreshold time.Duration `mapstructure:"aging_threshold"`
+	// BoostRetries 为 true 时手动重试的任务入队时提升一级优先级（最高为 high），任务记录中的优先级不变
+	BoostRetries bool `mapstructure:"boost_retries"`
+	// MetricsInterval 队列深度采样间隔，样本用于趋势图，0 表示不采样
+	MetricsInterval time.Duration `mapstructure:"metrics_interval"`
+	// MetricsRetention 队列深度样本的保留时间，更早的样本定期删除
+	MetricsRetention time.Duration `mapstructure:"metrics_retention"`
+	// ModelIsolation 为 true 时每个模型使用独立的一组优先级队列，一个模型的积压不会延迟其他模型
+	ModelIsolation bool `mapstructure:"model_isolation"`
+	// ModelQueuesKey 隔离模式下登记拥有独立队列的模型 ID（Set）
+	ModelQueuesKey string `mapstructure:"model_queues_key"`
+
+	// Backends 按名称配置的独立队列后端，模型通过 config.queue_backend 指定
+	Backends map[string]RedisConfig `mapstructure:"backends"`
+	// Shards 分片使用的队列后端名称（backends 中的名称或 default 表示共享 redis），
+	// 未指定 queue_backend 的模型按 模型ID % 分片数 路由；为空时全部使用共享 redis
+	Shards []string `mapstructure:"shards"`
+}
+
+// PriorityWeights 各优先级队列的出队权重，全部为 0 时严格按优先级出队
+type PriorityWeights struct {
+	High   int `mapstructure:"high"`
+	Medium int `mapstructure:"medium"`
+	Low    int `mapstructure:"low"`
+}
+
+// DegradedConfig 降级模式配置
+type DegradedConfig struct {
+	Key                  string        `mapstructure:"key"`
+	AutoEnabled          bool          `mapstructure:"auto_enabled"`
+	MinPriority          int           `mapstructure:"min_priority"`
+	QueueDepthThreshold  int64         `mapstructure:"queue_depth_threshold"`
+	FailureRateThreshold float64       `mapstructure:"failure_rate_threshold"`
+	FailureRateWindow    time.Duration `mapstructure:"failure_rate_window"`
+	CheckInterval        time.Duration `mapstructure:"check_interval"`
 }
 
 // WorkerConfig Worker 配置
@@ -79,6 +172,113 @@ type WorkerConfig struct {
 	MaxWorkers        int           `mapstructure:"max_workers"`
 	WorkerTimeout     time.Duration `mapstructure:"worker_timeout"`
 	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+
+	// IdlePollInterval 队列为空时 Worker 再次领取任务前的等待时间
+	IdlePollInterval time.Duration `mapstructure:"idle_poll_interval"`
+	// ErrorBackoff 领取或执行任务出错后 Worker 暂停的时间
+	ErrorBackoff time.Duration `mapstructure:"error_backoff"`
+
+	// InstanceID 当前实例标识，为空时使用 主机名-进程号
+	InstanceID string `mapstructure:"instance_id"`
+	// RosterKey 各实例共享的 Worker 名册键前缀，每个 Worker 一个 Hash（<roster_key>:<worker_id>）
+	RosterKey string `mapstructure:"roster_key"`
+	// StaleAfter 名册记录的 TTL，心跳超过该时间未刷新的 Worker 自动过期，为空时为 3 个心跳间隔
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+
+	// BatchSize 嵌入任务批量执行时每批最多的任务数，<= 1 表示不批量执行
+	BatchSize int `mapstructure:"batch_size"`
+	// BatchWait 凑批的最长等待时间，到期后即使不满 BatchSize 也立即执行
+	BatchWait time.Duration `mapstructure:"batch_wait"`
+
+	// HealthProbeInterval 模型健康探测间隔，0 表示不探测
+	HealthProbeInterval time.Duration `mapstructure:"health_probe_interval"`
+	// HealthProbeTimeout 单次探测的超时时间
+	HealthProbeTimeout time.Duration `mapstructure:"health_probe_timeout"`
+	// HealthProbeFailures 连续探测失败多少次后将模型切换为 maintenance
+	HealthProbeFailures int `mapstructure:"health_probe_failures"`
+
+	// RateLimitMaxWait 模型达到 requests_per_minute 限制时，需要等待的时间不超过该值则原地等待，
+	// 否则将任务放回延迟队列，0 表示总是放回
+	RateLimitMaxWait time.Duration `mapstructure:"rate_limit_max_wait"`
+
+	// CircuitBreaker 按模型统计上游调用失败率的熔断器
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// StartupDelay 启动后 Worker 开始领取任务前的等待时间，0 表示不等待
+	StartupDelay time.Duration `mapstructure:"startup_delay"`
+	// ReadinessGate 为 true 时 Worker 等到至少一个模型健康探测成功后才开始领取任务
+	ReadinessGate bool `mapstructure:"readiness_gate"`
+	// ReadinessTimeout 等待模型就绪的最长时间，超时后仍开始领取任务，0 表示一直等待
+	ReadinessTimeout time.Duration `mapstructure:"readiness_timeout"`
+
+	// TypeAffinity 专用于部分任务类型的 Worker，按配置顺序占用各模型 Worker 池的前几个槽位，
+	// 池中最后一个 Worker 始终不限类型
+	TypeAffinity []TypeAffinityConfig `mapstructure:"type_affinity"`
+}
+
+// TypeAffinityConfig 任务类型专用 Worker 配置
+type TypeAffinityConfig struct {
+	// ModelIDs 生效的模型，为空时对所有模型生效
+	ModelIDs []uint64 `mapstructure:"model_ids"`
+	// Types 专用 Worker 只领取这些类型的任务
+	Types []string `mapstructure:"types"`
+	// Workers 每个模型中专用于这些类型的 Worker 数
+	Workers int `mapstructure:"workers"`
+}
+
+// appliesTo 判断配置是否对指定模型生效
+func (a *TypeAffinityConfig) appliesTo(modelID uint64) bool {
+	if len(a.ModelIDs) == 0 {
+		return true
+	}
+	for _, id := range a.ModelIDs {
+		if id == modelID {
+			return true
+		}
+	}
+	return false
+}
+
+// AffinityFor 获取模型 Worker 池中按槽位排序的第 slot 个 Worker（从 0 开始）只领取的任务类型，nil 表示不限类型。
+// 池中最后一个 Worker 始终不限类型，保证其他类型的任务总有 Worker 领取
+func (c *WorkerConfig) AffinityFor(modelID uint64, slot, poolSize int) []string {
+	if slot >= poolSize-1 {
+		return nil
+	}
+	offset := 0
+	for i := range c.TypeAffinity {
+		affinity := &c.TypeAffinity[i]
+		if !affinity.appliesTo(modelID) {
+			continue
+		}
+		if slot < offset+affinity.Workers {
+			return affinity.Types
+		}
+		offset += affinity.Workers
+	}
+	return nil
+}
+
+// CircuitBreakerConfig 模型熔断器配置
+type CircuitBreakerConfig struct {
+	// Window 统计失败率的最近调用次数，0 表示不启用熔断
+	Window int `mapstructure:"window"`
+	// MinRequests 窗口内至少有这么多次调用才计算失败率
+	MinRequests int `mapstructure:"min_requests"`
+	// FailureRate 失败率达到该值（0-1）时熔断
+	FailureRate float64 `mapstructure:"failure_rate"`
+	// Cooldown 熔断后经过该时间进入半开状态，放行一次调用试探上游是否恢复
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+// StreamConfig 任务输出流（SSE）配置
+type StreamConfig struct {
+	FlushPolicy      string        `mapstructure:"flush_policy"` // token, bytes, interval
+	FlushBytes       int           `mapstructure:"flush_bytes"`
+	FlushInterval    time.Duration `mapstructure:"flush_interval"`
+	MinFlushInterval time.Duration `mapstructure:"min_flush_interval"`
+	MaxFlushInterval time.Duration `mapstructure:"max_flush_interval"`
+	MaxFlushBytes    int           `mapstructure:"max_flush_bytes"`
 }
 
 // LoggingConfig 日志配置
@@ -93,6 +293,29 @@ type LoggingConfig struct {
 	Compress    bool   `mapstructure:"compress"`
 }
 
+// TracingConfig OpenTelemetry 分布式追踪配置，Endpoint 为空时不启用
+type TracingConfig struct {
+	// Endpoint OTLP/HTTP 接收地址（host:port），例如 otel-collector:4318
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure 为 true 时使用 HTTP 连接，否则使用 HTTPS
+	Insecure bool `mapstructure:"insecure"`
+	// SampleRatio 新建追踪的采样比例（0-1]，上游请求已带采样决定时沿用上游
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// Enabled 是否启用追踪导出
+func (c *TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// GetSampleRatio 获取采样比例，未配置时全部采样
+func (c *TracingConfig) GetSampleRatio() float64 {
+	if c.SampleRatio <= 0 {
+		return 1
+	}
+	return c.SampleRatio
+}
+
 // CORSConfig CORS 配置
 type CORSConfig struct {
 	AllowOrigins     []string `mapstructure:"allow_origins"`
@@ -103,10 +326,114 @@ type CORSConfig struct {
 	MaxAge           string   `mapstructure:"max_age"`
 }
 
+// DefaultCORSMaxAge max_age 未配置或无法解析时预检结果的缓存时间
+const DefaultCORSMaxAge = 12 * time.Hour
+
+// GetMaxAge 解析预检结果的缓存时间，未配置时返回默认值；无法解析时返回默认值和解析错误，由调用方记录
+func (c *CORSConfig) GetMaxAge() (time.Duration, error) {
+	if c.MaxAge == "" {
+		return DefaultCORSMaxAge, nil
+	}
+	duration, err := time.ParseDuration(c.MaxAge)
+	if err != nil {
+		return DefaultCORSMaxAge, fmt.Errorf("invalid cors.max_age %q: %w", c.MaxAge, err)
+	}
+	return duration, nil
+}
+
+// 认证角色：admin 可以调用全部接口，viewer 只能调用查询接口
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
+// ValidRole 是否为已定义的认证角色
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleViewer
+}
+
+// AuthConfig API Key / JWT 认证与限流配置
+type AuthConfig struct {
+	Enabled          bool           `mapstructure:"enabled"`
+	Keys             []APIKeyConfig `mapstructure:"keys"`
+	JWT              JWTConfig      `mapstructure:"jwt"`
+	ExemptPaths      []string       `mapstructure:"exempt_paths"`
+	RateLimitWindow  time.Duration  `mapstructure:"rate_limit_window"`
+	DefaultRateLimit int            `mapstructure:"default_rate_limit"`
+	RateLimitKey     string         `mapstructure:"rate_limit_key"`
+	// DefaultMaxRunning 每个 API Key（包括 JWT 的 sub）同时执行的任务数上限，0 表示不限制
+	DefaultMaxRunning int `mapstructure:"default_max_running"`
+}
+
+// MaxRunningFor 获取 API Key 同时执行的任务数上限，Key 未单独配置 max_running 时使用 default_max_running，0 表示不限制
+func (c *AuthConfig) MaxRunningFor(name string) int {
+	for i := range c.Keys {
+		if c.Keys[i].Name == name && c.Keys[i].MaxRunning > 0 {
+			return c.Keys[i].MaxRunning
+		}
+	}
+	return c.DefaultMaxRunning
+}
+
+// LookupKey 查找与 key 匹配的 API Key 配置，按常量时间比较，未匹配时返回 nil
+func (c *AuthConfig) LookupKey(key string) *APIKeyConfig {
+	for i := range c.Keys {
+		if c.Keys[i].Key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(c.Keys[i].Key)) == 1 {
+			return &c.Keys[i]
+		}
+	}
+	return nil
+}
+
+// APIKeyConfig 单个 API Key 配置
+type APIKeyConfig struct {
+	Name      string `mapstructure:"name"`
+	Key       string `mapstructure:"key"`
+	RateLimit int    `mapstructure:"rate_limit"` // 每个窗口内允许的请求数，0 表示使用 default_rate_limit
+	Role      string `mapstructure:"role"`       // admin 或 viewer，为空时为 admin
+	// MaxRunning 该 Key 创建的任务同时执行的上限，0 表示使用 default_max_running
+	MaxRunning int `mapstructure:"max_running"`
+}
+
+// GetRole 获取 Key 的角色，未配置时为 admin，与引入角色之前的行为一致
+func (k *APIKeyConfig) GetRole() string {
+	if k.Role == "" {
+		return RoleAdmin
+	}
+	return k.Role
+}
+
+// JWTConfig Bearer JWT 认证配置，令牌使用 HS256 签名，role 声明为 admin 或 viewer
+type JWTConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Secret  string `mapstructure:"secret"`
+	// Issuer 不为空时要求令牌的 iss 声明与之相同
+	Issuer string `mapstructure:"issuer"`
+	// Leeway 校验 exp、nbf 时允许的时钟偏差
+	Leeway time.Duration `mapstructure:"leeway"`
+}
+
 // ModelsConfig 模型配置
 type ModelsConfig struct {
-	OpenAI OpenAIConfig `mapstructure:"openai"`
-	Local  LocalConfig  `mapstructure:"local"`
+	OpenAI           OpenAIConfig           `mapstructure:"openai"`
+	Local            LocalConfig            `mapstructure:"local"`
+	ProviderOverride ProviderOverrideConfig `mapstructure:"provider_override"`
+
+	// DefaultModel 创建任务未指定 model_id 时使用的模型（ID 或名称），为空表示必须指定
+	DefaultModel string `mapstructure:"default_model"`
+	// DefaultTaskType 创建任务未指定 type 时使用的任务类型，为空表示必须指定
+	DefaultTaskType string `mapstructure:"default_task_type"`
+	// AllowedTaskTypes 允许创建的任务类型，为空时只允许内置类型；模型配置 allow_custom_types 后不受限制
+	AllowedTaskTypes []string `mapstructure:"allowed_task_types"`
+}
+
+// ProviderOverrideConfig 任务级模型服务地址覆盖配置
+type ProviderOverrideConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Token 客户端需在 X-Provider-Override-Token 请求头中携带
+	Token string `mapstructure:"token"`
+	// AllowedHosts 允许覆盖到的主机名，为空时不限制
+	AllowedHosts []string `mapstructure:"allowed_hosts"`
 }
 
 // OpenAIConfig OpenAI 配置
@@ -122,6 +449,26 @@ type LocalConfig struct {
 	MaxRetries int           `mapstructure:"max_retries"`
 }
 
+// StorageConfig 任务输出外部存储配置
+type StorageConfig struct {
+	// OutputThreshold 输出超过该字节数时写入外部存储，数据库只保存引用 URI，0 表示不启用
+	OutputThreshold int `mapstructure:"output_threshold"`
+	// Backend 存储后端：local 或 s3
+	Backend  string          `mapstructure:"backend"`
+	LocalDir string          `mapstructure:"local_dir"`
+	S3       S3StorageConfig `mapstructure:"s3"`
+}
+
+// S3StorageConfig S3 兼容对象存储配置（AWS S3、MinIO 等），使用路径风格访问
+type S3StorageConfig struct {
+	Endpoint        string        `mapstructure:"endpoint"` // 例如 https://s3.us-east-1.amazonaws.com、http://minio:9000
+	Region          string        `mapstructure:"region"`
+	Bucket          string        `mapstructure:"bucket"`
+	AccessKeyID     string        `mapstructure:"access_key_id"`
+	SecretAccessKey string        `mapstructure:"secret_access_key"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+}
+
 // Load 加载配置
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
@@ -143,6 +490,9 @@ func Load() (*Config, error) {
 	viper.BindEnv("redis.port", "REDIS_PORT")
 	viper.BindEnv("redis.db", "REDIS_DB")
 	viper.BindEnv("redis.password", "REDIS_PASSWORD")
+	viper.BindEnv("storage.s3.access_key_id", "S3_ACCESS_KEY_ID")
+	viper.BindEnv("storage.s3.secret_access_key", "S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("auth.jwt.secret", "JWT_SECRET")
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// loadShippedConfig 加载仓库中的 config.yaml
+func loadShippedConfig(t *testing.T) *Config {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load config.yaml: %v", err)
+	}
+	return cfg
+}
+
+func TestShippedConfigAllowsCustomRequestHeaders(t *testing.T) {
+	cfg := loadShippedConfig(t)
+
+	// 浏览器跨域调用时自定义请求头需要通过预检
+	allowed := make(map[string]bool, len(cfg.CORS.AllowHeaders))
+	for _, header := range cfg.CORS.AllowHeaders {
+		allowed[header] = true
+	}
+	for _, header := range []string{"Content-Type", "Authorization", "X-API-Key", "Idempotency-Key"} {
+		if !allowed[header] {
+			t.Errorf("cors.allow_headers missing %s: %v", header, cfg.CORS.AllowHeaders)
+		}
+	}
+}
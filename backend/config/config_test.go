@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAffinityFor(t *testing.T) {
+	cfg := &WorkerConfig{
+		TypeAffinity: []TypeAffinityConfig{
+			{Types: []string{"embedding"}, Workers: 2},
+			{ModelIDs: []uint64{7}, Types: []string{"summarization"}, Workers: 1},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		modelID  uint64
+		slot     int
+		poolSize int
+		want     []string
+	}{
+		{"first affine slot", 1, 0, 4, []string{"embedding"}},
+		{"second affine slot", 1, 1, 4, []string{"embedding"}},
+		{"beyond affine slots", 1, 2, 4, nil},
+		{"last slot unrestricted", 1, 3, 4, nil},
+		{"model scoped affinity", 7, 2, 5, []string{"summarization"}},
+		{"model scoped affinity skipped for other models", 1, 2, 5, nil},
+		{"single worker pool is unrestricted", 1, 0, 1, nil},
+		{"last slot wins over affinity", 1, 1, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.AffinityFor(tt.modelID, tt.slot, tt.poolSize)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AffinityFor(%d, %d, %d) = %v, want %v", tt.modelID, tt.slot, tt.poolSize, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSGetMaxAge(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxAge  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"configured", "30m", 30 * time.Minute, false},
+		// 未配置时使用默认值
+		{"empty", "", DefaultCORSMaxAge, false},
+		// 无法解析时返回默认值而不是 0，并返回错误由调用方记录
+		{"invalid", "12 hours", DefaultCORSMaxAge, true},
+		{"missing unit", "3600", DefaultCORSMaxAge, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &CORSConfig{MaxAge: tt.maxAge}
+			got, err := cfg.GetMaxAge()
+			if got != tt.want {
+				t.Errorf("GetMaxAge() = %v, want %v", got, tt.want)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetMaxAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		configured time.Duration
+		want       time.Duration
+	}{
+		{20 * time.Second, 20 * time.Second},
+		// 未配置时使用默认值
+		{0, DefaultShutdownTimeout},
+	}
+	for _, tt := range tests {
+		cfg := &ServerConfig{ShutdownTimeout: tt.configured}
+		if got := cfg.GetShutdownTimeout(); got != tt.want {
+			t.Errorf("GetShutdownTimeout() with %v = %v, want %v", tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestGetMaxBodyBytes(t *testing.T) {
+	tests := []struct {
+		configured int64
+		want       int64
+	}{
+		{1 << 20, 1 << 20},
+		// 未配置时使用默认值
+		{0, DefaultMaxBodyBytes},
+	}
+	for _, tt := range tests {
+		cfg := &ServerConfig{MaxBodyBytes: tt.configured}
+		if got := cfg.GetMaxBodyBytes(); got != tt.want {
+			t.Errorf("GetMaxBodyBytes() with %d = %d, want %d", tt.configured, got, tt.want)
+		}
+	}
+}
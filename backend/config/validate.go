@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate 校验必填配置项，返回汇总了所有问题的错误
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	require(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535")
+	require(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	require(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	require(c.Server.ShutdownTimeout >= 0, "server.shutdown_timeout must not be negative")
+	require(c.Server.MaxBodyBytes >= 0, "server.max_body_bytes must not be negative")
+	if c.GRPC.Enabled {
+		require(c.GRPC.Port > 0 && c.GRPC.Port <= 65535, "grpc.port must be between 1 and 65535")
+		require(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+	}
+
+	require(c.Database.Host != "", "database.host is required")
+	require(c.Database.Port > 0, "database.port must be positive")
+	require(c.Database.Database != "", "database.database is required")
+
+	require(c.Redis.Host != "", "redis.host is required")
+	require(c.Redis.Port > 0, "redis.port must be positive")
+	backendNames := make([]string, 0, len(c.Queue.Backends))
+	for name := range c.Queue.Backends {
+		backendNames = append(backendNames, name)
+	}
+	sort.Strings(backendNames)
+	for _, name := range backendNames {
+		backend := c.Queue.Backends[name]
+		require(backend.Host != "", "queue.backends.%s.host is required", name)
+		require(backend.Port > 0, "queue.backends.%s.port must be positive", name)
+	}
+	seenShards := make(map[string]bool, len(c.Queue.Shards))
+	for _, name := range c.Queue.Shards {
+		_, configured := c.Queue.Backends[name]
+		require(name == "default" || configured, "queue.shards: backend %q is not configured in queue.backends", name)
+		require(!seenShards[name], "queue.shards: backend %q is listed more than once", name)
+		seenShards[name] = true
+	}
+	// 两个分片指向同一个 Redis 时路由到它们的模型共用队列，状态统计也会重复计算
+	shardEndpoints := make(map[string]string, len(c.Queue.Shards))
+	for _, name := range c.Queue.Shards {
+		redisCfg, configured := c.Queue.Backends[name]
+		if name == "default" {
+			redisCfg, configured = c.Redis, true
+		}
+		if !configured {
+			continue
+		}
+		endpoint := fmt.Sprintf("%s/%d", redisCfg.GetRedisAddr(), redisCfg.DB)
+		if other, exists := shardEndpoints[endpoint]; exists && other != name {
+			require(false, "queue.shards: backends %q and %q use the same redis %s", other, name, endpoint)
+			continue
+		}
+		shardEndpoints[endpoint] = name
+	}
+
+	// 频道、幂等键、并发计数等键名未配置时使用代码中的默认值，不要求配置
+	queueKeys := []struct {
+		name  string
+		value string
+	}{
+		{"queue.high_priority_queue", c.Queue.HighPriorityQueue},
+		{"queue.medium_priority_queue", c.Queue.MediumPriorityQueue},
+		{"queue.low_priority_queue", c.Queue.LowPriorityQueue},
+		{"queue.delayed_queue", c.Queue.DelayedQueue},
+		{"queue.processing_queue", c.Queue.ProcessingQueue},
+	}
+	for _, key := range queueKeys {
+		require(key.value != "", "%s is required", key.name)
+	}
+	require(c.Queue.MaxQueueSize >= 0, "queue.max_queue_size must not be negative")
+	require(c.Queue.CacheTTL >= 0, "queue.cache_ttl must not be negative")
+	require(c.Queue.TaskTimeout > 0, "queue.task_timeout must be positive")
+	require(c.Queue.MaxRetries >= 0, "queue.max_retries must not be negative")
+	taskTypes := make([]string, 0, len(c.Queue.TypeMaxRetries))
+	for taskType := range c.Queue.TypeMaxRetries {
+		taskTypes = append(taskTypes, taskType)
+	}
+	sort.Strings(taskTypes)
+	for _, taskType := range taskTypes {
+		require(c.Queue.TypeMaxRetries[taskType] >= 0, "queue.type_max_retries.%s must not be negative", taskType)
+	}
+
+	require(c.Queue.AgingThreshold >= 0, "queue.aging_threshold must not be negative")
+	require(c.Queue.MetricsInterval >= 0, "queue.metrics_interval must not be negative")
+	if c.Queue.MetricsInterval > 0 {
+		require(c.Queue.MetricsRetention >= c.Queue.MetricsInterval, "queue.metrics_retention must be at least queue.metrics_interval")
+	}
+	if c.Queue.ModelIsolation {
+		require(c.Queue.ModelQueuesKey != "", "queue.model_queues_key is required when queue.model_isolation is enabled")
+	}
+
+	require(c.Worker.MaxWorkers >= 1, "worker.max_workers must be at least 1")
+	require(c.Worker.WorkerTimeout > 0, "worker.worker_timeout must be positive")
+	require(c.Worker.BatchWait >= 0, "worker.batch_wait must not be negative")
+	require(c.Worker.IdlePollInterval >= 0, "worker.idle_poll_interval must not be negative")
+	require(c.Worker.ErrorBackoff >= 0, "worker.error_backoff must not be negative")
+	require(c.Worker.RateLimitMaxWait >= 0, "worker.rate_limit_max_wait must not be negative")
+	require(c.Worker.HealthProbeInterval >= 0, "worker.health_probe_interval must not be negative")
+	if c.Worker.HealthProbeInterval > 0 {
+		require(c.Worker.HealthProbeTimeout > 0, "worker.health_probe_timeout must be positive")
+		require(c.Worker.HealthProbeFailures >= 1, "worker.health_probe_failures must be at least 1")
+	}
+	require(c.Worker.CircuitBreaker.Window >= 0, "worker.circuit_breaker.window must not be negative")
+	if c.Worker.CircuitBreaker.Window > 0 {
+		breaker := c.Worker.CircuitBreaker
+		require(breaker.MinRequests >= 1 && breaker.MinRequests <= breaker.Window,
+			"worker.circuit_breaker.min_requests must be between 1 and window")
+		require(breaker.FailureRate > 0 && breaker.FailureRate <= 1, "worker.circuit_breaker.failure_rate must be in (0, 1]")
+		require(breaker.Cooldown > 0, "worker.circuit_breaker.cooldown must be positive")
+	}
+	require(c.Worker.StartupDelay >= 0, "worker.startup_delay must not be negative")
+	require(c.Worker.ReadinessTimeout >= 0, "worker.readiness_timeout must not be negative")
+	if c.Worker.ReadinessGate {
+		require(c.Worker.HealthProbeTimeout > 0, "worker.health_probe_timeout must be positive")
+	}
+	for i, affinity := range c.Worker.TypeAffinity {
+		require(len(affinity.Types) > 0, "worker.type_affinity[%d].types is required", i)
+		require(affinity.Workers >= 1, "worker.type_affinity[%d].workers must be at least 1", i)
+	}
+
+	require(c.Storage.OutputThreshold >= 0, "storage.output_threshold must not be negative")
+	if c.Storage.OutputThreshold > 0 {
+		switch c.Storage.Backend {
+		case "local":
+			require(c.Storage.LocalDir != "", "storage.local_dir is required")
+		case "s3":
+			require(c.Storage.S3.Endpoint != "", "storage.s3.endpoint is required")
+			require(c.Storage.S3.Region != "", "storage.s3.region is required")
+			require(c.Storage.S3.Bucket != "", "storage.s3.bucket is required")
+			require(c.Storage.S3.AccessKeyID != "" && c.Storage.S3.SecretAccessKey != "",
+				"storage.s3.access_key_id and storage.s3.secret_access_key are required")
+		default:
+			require(false, "storage.backend must be local or s3")
+		}
+	}
+
+	for i, key := range c.Auth.Keys {
+		require(ValidRole(key.GetRole()), "auth.keys[%d].role must be admin or viewer", i)
+		require(key.MaxRunning >= 0, "auth.keys[%d].max_running must not be negative", i)
+	}
+	require(c.Auth.DefaultMaxRunning >= 0, "auth.default_max_running must not be negative")
+	if c.Auth.JWT.Enabled {
+		// HS256 密钥不应短于摘要长度
+		require(len(c.Auth.JWT.Secret) >= 32, "auth.jwt.secret must be at least 32 bytes")
+		require(c.Auth.JWT.Leeway >= 0, "auth.jwt.leeway must not be negative")
+	}
+
+	// cors 中间件在来源配置无效时会 panic，启动前检查；max_age 无法解析时使用默认值，不在此拒绝
+	require(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins is required")
+	for _, origin := range c.CORS.AllowOrigins {
+		require(strings.Contains(origin, "*") || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors.allow_origins entry %q must start with http:// or https://", origin)
+	}
+	if maxAge, err := c.CORS.GetMaxAge(); err == nil {
+		require(maxAge >= 0, "cors.max_age must not be negative")
+	}
+
+	require(c.Logging.Format == "json" || c.Logging.Format == "text", "logging.format must be json or text")
+	switch c.Logging.Output {
+	case "stdout":
+	case "file":
+		require(c.Logging.FilePath != "", "logging.file_path is required")
+		require(c.Logging.MaxSize > 0, "logging.max_size must be positive")
+		require(c.Logging.MaxAge >= 0, "logging.max_age must not be negative")
+		require(c.Logging.MaxBackups >= 0, "logging.max_backups must not be negative")
+	default:
+		require(false, "logging.output must be stdout or file")
+	}
+
+	require(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validTestConfig 返回能通过校验的最小配置，测试在此基础上改坏单个字段
+func validTestConfig() *Config {
+	cfg := &Config{}
+	cfg.Server.Port = 8080
+	cfg.Server.ReadTimeout = 30 * time.Second
+	cfg.Server.WriteTimeout = 30 * time.Second
+	cfg.Database.Host = "localhost"
+	cfg.Database.Port = 3306
+	cfg.Database.Database = "llm_scheduler"
+	cfg.Redis.Host = "localhost"
+	cfg.Redis.Port = 6379
+	cfg.Queue.HighPriorityQueue = "llm_tasks:high"
+	cfg.Queue.MediumPriorityQueue = "llm_tasks:medium"
+	cfg.Queue.LowPriorityQueue = "llm_tasks:low"
+	cfg.Queue.DelayedQueue = "llm_tasks:delayed"
+	cfg.Queue.ProcessingQueue = "llm_tasks:processing"
+	cfg.Queue.TaskTimeout = 5 * time.Minute
+	cfg.Worker.MaxWorkers = 4
+	cfg.Worker.WorkerTimeout = 10 * time.Minute
+	cfg.CORS.AllowOrigins = []string{"http://localhost:3000"}
+	cfg.Logging.Format = "json"
+	cfg.Logging.Output = "stdout"
+	return cfg
+}
+
+func TestValidateAcceptsMinimalConfig(t *testing.T) {
+	// 频道、幂等键等有默认值的键名未配置时不报错
+	if err := validTestConfig().Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateAcceptsInvalidCORSMaxAge(t *testing.T) {
+	// max_age 无法解析时启动使用默认值，不拒绝配置
+	cfg := validTestConfig()
+	cfg.CORS.MaxAge = "12 hours"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string
+	}{
+		{
+			name:   "zero server port",
+			modify: func(c *Config) { c.Server.Port = 0 },
+			want:   []string{"server.port must be between 1 and 65535"},
+		},
+		{
+			name: "grpc port clashes with server port",
+			modify: func(c *Config) {
+				c.GRPC.Enabled = true
+				c.GRPC.Port = c.Server.Port
+			},
+			want: []string{"grpc.port must differ from server.port"},
+		},
+		{
+			name: "missing database",
+			modify: func(c *Config) {
+				c.Database.Host = ""
+				c.Database.Database = ""
+			},
+			want: []string{"database.host is required", "database.database is required"},
+		},
+		{
+			name:   "missing redis host",
+			modify: func(c *Config) { c.Redis.Host = "" },
+			want:   []string{"redis.host is required"},
+		},
+		{
+			name:   "missing processing queue",
+			modify: func(c *Config) { c.Queue.ProcessingQueue = "" },
+			want:   []string{"queue.processing_queue is required"},
+		},
+		{
+			name:   "no workers",
+			modify: func(c *Config) { c.Worker.MaxWorkers = 0 },
+			want:   []string{"worker.max_workers must be at least 1"},
+		},
+		{
+			name: "non-positive timeouts",
+			modify: func(c *Config) {
+				c.Queue.TaskTimeout = 0
+				c.Worker.WorkerTimeout = -time.Second
+			},
+			want: []string{"queue.task_timeout must be positive", "worker.worker_timeout must be positive"},
+		},
+		{
+			name:   "shard without backend",
+			modify: func(c *Config) { c.Queue.Shards = []string{"default", "east"} },
+			want:   []string{`queue.shards: backend "east" is not configured in queue.backends`},
+		},
+		{
+			name:   "duplicate shard",
+			modify: func(c *Config) { c.Queue.Shards = []string{"default", "default"} },
+			want:   []string{`queue.shards: backend "default" is listed more than once`},
+		},
+		{
+			name: "shards on the same redis",
+			modify: func(c *Config) {
+				c.Queue.Backends = map[string]RedisConfig{"east": {Host: "localhost", Port: 6379}}
+				c.Queue.Shards = []string{"default", "east"}
+			},
+			want: []string{`queue.shards: backends "default" and "east" use the same redis localhost:6379/0`},
+		},
+		{
+			name: "model isolation without registry key",
+			modify: func(c *Config) {
+				c.Queue.ModelIsolation = true
+			},
+			want: []string{"queue.model_queues_key is required when queue.model_isolation is enabled"},
+		},
+		{
+			name:   "missing cors origins",
+			modify: func(c *Config) { c.CORS.AllowOrigins = nil },
+			want:   []string{"cors.allow_origins is required"},
+		},
+		{
+			name:   "cors origin without scheme",
+			modify: func(c *Config) { c.CORS.AllowOrigins = []string{"localhost:3000"} },
+			want:   []string{`cors.allow_origins entry "localhost:3000" must start with http:// or https://`},
+		},
+		{
+			name:   "negative cors max age",
+			modify: func(c *Config) { c.CORS.MaxAge = "-1h" },
+			want:   []string{"cors.max_age must not be negative"},
+		},
+		{
+			name:   "negative max body bytes",
+			modify: func(c *Config) { c.Server.MaxBodyBytes = -1 },
+			want:   []string{"server.max_body_bytes must not be negative"},
+		},
+		{
+			name:   "unknown logging output",
+			modify: func(c *Config) { c.Logging.Output = "syslog" },
+			want:   []string{"logging.output must be stdout or file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validTestConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() = nil, want error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsShards(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.Queue.Backends = map[string]RedisConfig{
+		"east": {Host: "redis-east", Port: 6379},
+		"west": {Host: "localhost", Port: 6379, DB: 1},
+	}
+	cfg.Queue.Shards = []string{"default", "east", "west"}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.Server.Port = 0
+	cfg.Redis.Host = ""
+	cfg.Worker.MaxWorkers = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want error")
+	}
+	if got := strings.Count(err.Error(), "\n  - "); got != 3 {
+		t.Errorf("Validate() reported %d problems, want 3:\n%s", got, err)
+	}
+}
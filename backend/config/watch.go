@@ -0,0 +1,33 @@
+package config
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Reload 重新读取配置文件
+func Reload() (*Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Watch 监听配置文件变化，文件修改后重新解析并回调；解析失败时回调 onError
+func Watch(onChange func(*Config), onError func(error)) {
+	viper.OnConfigChange(func(fsnotify.Event) {
+		var config Config
+		if err := viper.Unmarshal(&config); err != nil {
+			onError(err)
+			return
+		}
+		onChange(&config)
+	})
+	viper.WatchConfig()
+}
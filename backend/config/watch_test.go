@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// writeConfigFile 先写临时文件再重命名，文件监听只收到一次创建事件
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename config: %v", err)
+	}
+}
+
+func TestWatchReloadsLogLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "logging:\n  level: info\n")
+
+	viper.Reset()
+	t.Cleanup(func() {
+		// 删除文件使监听协程退出后再重置全局配置
+		os.Remove(path)
+		viper.Reset()
+	})
+	viper.SetConfigFile(path)
+	cfg, err := Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if cfg.Logging.Level != "info" {
+		t.Fatalf("level = %q, want info", cfg.Logging.Level)
+	}
+
+	changes := make(chan string, 4)
+	Watch(func(cfg *Config) {
+		changes <- cfg.Logging.Level
+	}, func(err error) {
+		t.Errorf("watch error: %v", err)
+	})
+
+	writeConfigFile(t, path, "logging:\n  level: debug\n")
+	select {
+	case level := <-changes:
+		if level != "debug" {
+			t.Fatalf("reloaded level = %q, want debug", level)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not observed")
+	}
+}
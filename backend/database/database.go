@@ -14,6 +14,8 @@ func Init(cfg *config.Config) (*gorm.DB, error) {
 	dsn := cfg.Database.GetDSN()
 	gormConfig := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// 唯一索引冲突转换为 gorm.ErrDuplicatedKey，任务去重依赖该错误判断
+		TranslateError: true,
 	}
 
 	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
@@ -47,6 +49,9 @@ func migrate(db *gorm.DB) error {
 		&models.Task{},
 		&models.TaskLog{},
 		&models.SystemStats{},
+		&models.QueueMetric{},
+		&models.ScheduledTask{},
+		&models.ArchivedTask{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
@@ -81,6 +86,34 @@ func createIndexes(db *gorm.DB) error {
 		return err
 	}
 
+	// 任务内容全文索引，供任务列表的 q 参数检索；ngram 分词以支持中文
+	// MySQL 不支持 CREATE FULLTEXT INDEX IF NOT EXISTS，先检查是否已存在
+	if !db.Migrator().HasIndex(&models.Task{}, "ft_tasks_content") {
+		if err := db.Exec(`
+		CREATE FULLTEXT INDEX ft_tasks_content ON tasks(input, error_message) WITH PARSER ngram
+	`).Error; err != nil {
+			return err
+		}
+	}
+
+	// 任务去重：MySQL 不支持部分唯一索引，用只在 pending/running 时取 dedup_key 的生成列建唯一索引，
+	// 结束的任务该列为 NULL，不占用去重键
+	if !db.Migrator().HasColumn(&models.Task{}, "active_dedup_key") {
+		if err := db.Exec(`
+		ALTER TABLE tasks ADD COLUMN active_dedup_key VARCHAR(128)
+			GENERATED ALWAYS AS (IF(status IN ('pending', 'running') AND dedup_key <> '', dedup_key, NULL)) VIRTUAL
+	`).Error; err != nil {
+			return err
+		}
+	}
+	if !db.Migrator().HasIndex(&models.Task{}, "uk_tasks_active_dedup_key") {
+		if err := db.Exec(`
+		CREATE UNIQUE INDEX uk_tasks_active_dedup_key ON tasks(active_dedup_key)
+	`).Error; err != nil {
+			return err
+		}
+	}
+
 	// 模型表索引
 	if err := db.Exec(`
 		CREATE INDEX IF NOT EXISTS idx_models_type_status ON models(type, status)
@@ -0,0 +1,159 @@
+package testdb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sqlite "github.com/glebarez/go-sqlite"
+)
+
+// registerOnce 函数注册在驱动上全局生效，只能注册一次
+var registerOnce sync.Once
+
+// registerFunctions 注册查询中用到的 MySQL 函数。SQLite 自带的 JSON_EXTRACT 返回去掉引号的文本，
+// 因此 JSON_UNQUOTE 原样返回参数
+func registerFunctions() {
+	registerOnce.Do(func() {
+		sqlite.MustRegisterDeterministicScalarFunction("JSON_CONTAINS", 2, jsonContains)
+		sqlite.MustRegisterDeterministicScalarFunction("JSON_UNQUOTE", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return args[0], nil
+		})
+		sqlite.MustRegisterDeterministicScalarFunction("TIMESTAMPDIFF", 3, timestampDiff)
+		sqlite.MustRegisterDeterministicScalarFunction("MATCH_AGAINST", -1, matchAgainstPhrase)
+	})
+}
+
+// timestampDiffUnits TIMESTAMPDIFF 支持的单位
+var timestampDiffUnits = map[string]time.Duration{
+	"MICROSECOND": time.Microsecond,
+	"SECOND":      time.Second,
+	"MINUTE":      time.Minute,
+	"HOUR":        time.Hour,
+	"DAY":         24 * time.Hour,
+}
+
+// timestampDiff 实现 TIMESTAMPDIFF(unit, start, end)，单位由 rewrite 改写为字符串参数；
+// 任一时间为 NULL 时返回 NULL，结果与 MySQL 一样向零截断为整数
+func timestampDiff(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if args[1] == nil || args[2] == nil {
+		return nil, nil
+	}
+	unitName, _ := args[0].(string)
+	unit, ok := timestampDiffUnits[strings.ToUpper(unitName)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TIMESTAMPDIFF unit %v", args[0])
+	}
+	start, err := parseTime(args[1])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return int64(end.Sub(start) / unit), nil
+}
+
+// matchAgainstPhrase 近似 ngram 全文索引上的布尔模式短语检索：去掉短语两端的双引号后，
+// 任一列包含该短语（不区分大小写）即匹配，NULL 列视为不匹配
+func matchAgainstPhrase(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	phrase, _ := args[0].(string)
+	phrase = strings.ToLower(strings.TrimSpace(strings.Trim(phrase, `"`)))
+	if phrase == "" {
+		return int64(0), nil
+	}
+	for _, column := range args[1:] {
+		if text, ok := column.(string); ok && strings.Contains(strings.ToLower(text), phrase) {
+			return int64(1), nil
+		}
+	}
+	return int64(0), nil
+}
+
+// timeLayouts SQLite 驱动写入时间列使用的格式
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// parseTime 解析时间列的值
+func parseTime(value driver.Value) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time value %v", value)
+}
+
+// jsonContains 实现 JSON_CONTAINS(target, candidate)：数组包含候选值（候选为数组时包含其全部元素），
+// 其他类型要求两者相等；任一参数为 NULL 时返回 NULL
+func jsonContains(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	target, err := decodeJSON(args[0])
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := decodeJSON(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if contains(target, candidate) {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+// contains 判断 target 是否包含 candidate
+func contains(target, candidate interface{}) bool {
+	targetArray, ok := target.([]interface{})
+	if !ok {
+		return reflect.DeepEqual(target, candidate)
+	}
+	if candidateArray, ok := candidate.([]interface{}); ok {
+		for _, value := range candidateArray {
+			if !contains(targetArray, value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range targetArray {
+		if reflect.DeepEqual(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSON 解析 TEXT 或 BLOB 参数中的 JSON
+func decodeJSON(value driver.Value) (interface{}, error) {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return nil, fmt.Errorf("invalid JSON argument of type %T", value)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid JSON text: %w", err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,90 @@
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"sync"
+
+	"github.com/glebarez/sqlite"
+)
+
+// driverName 注册的改写驱动名称
+const driverName = "testdb-sqlite"
+
+// registerDriverOnce 驱动只能注册一次
+var registerDriverOnce sync.Once
+
+// timestampDiffUnit 匹配 TIMESTAMPDIFF 的单位关键字，SQLite 会把它当作列名解析
+var timestampDiffUnit = regexp.MustCompile(`(?i)TIMESTAMPDIFF\(\s*(MICROSECOND|SECOND|MINUTE|HOUR|DAY)\s*,`)
+
+// matchAgainst 匹配布尔模式的全文检索，改写为 MATCH_AGAINST(检索内容, 列...)，参数占位符的相对顺序不变
+var matchAgainst = regexp.MustCompile(`(?i)MATCH\s*\(([^)]*)\)\s*AGAINST\s*\(\s*\?\s+IN\s+BOOLEAN\s+MODE\s*\)`)
+
+// dateSubDays 匹配按天回溯的日期条件 DATE_SUB(CURDATE(), INTERVAL ? DAY)，改写为 SQLite 的日期修饰符
+var dateSubDays = regexp.MustCompile(`(?i)DATE_SUB\(\s*CURDATE\(\)\s*,\s*INTERVAL\s+\?\s+DAY\s*\)`)
+
+// rewrite 把 SQLite 无法解析的 MySQL 语法改写为等价写法，函数本身由 registerFunctions 注册
+func rewrite(query string) string {
+	query = timestampDiffUnit.ReplaceAllString(query, "TIMESTAMPDIFF('$1',")
+	query = dateSubDays.ReplaceAllString(query, "DATE('now', '-' || ? || ' days')")
+	return matchAgainst.ReplaceAllString(query, "MATCH_AGAINST(?, $1)")
+}
+
+// registerDriver 注册包装 SQLite 驱动的改写驱动，返回驱动名称
+func registerDriver() string {
+	registerDriverOnce.Do(func() {
+		db, err := sql.Open(sqlite.DriverName, "")
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		sql.Register(driverName, rewriteDriver{db.Driver()})
+	})
+	return driverName
+}
+
+// rewriteDriver 打开的连接在预编译语句前改写 SQL
+type rewriteDriver struct {
+	driver.Driver
+}
+
+func (d rewriteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteConn{conn: conn}, nil
+}
+
+// rewriteConn 只实现预编译和事务接口，database/sql 会对所有查询走预编译路径
+type rewriteConn struct {
+	conn driver.Conn
+}
+
+func (c *rewriteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(rewrite(query))
+}
+
+func (c *rewriteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, rewrite(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *rewriteConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *rewriteConn) Begin() (driver.Tx, error) {
+	return c.conn.Begin()
+}
+
+func (c *rewriteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Begin()
+}
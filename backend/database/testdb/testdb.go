@@ -0,0 +1,80 @@
+// Package testdb 为测试提供内存 SQLite 数据库，表结构与 database.Init 迁移的一致。
+// 仅供 _test.go 使用：MySQL 的 enum 列按 text 建表，测试用到的 MySQL 函数注册为同名 SQLite 函数，
+// SQLite 无法解析的 MySQL 语法在执行前改写
+package testdb
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// seq 区分同一进程中各测试的内存数据库
+var seq int64
+
+// dialector 在 SQLite 方言上把 MySQL 专有的列类型换成 SQLite 能解析的类型
+type dialector struct {
+	sqlite.Dialector
+}
+
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	m := d.Dialector.Migrator(db).(sqlite.Migrator)
+	m.Dialector = d
+	return m
+}
+
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum") {
+		return "text"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// New 创建迁移好的内存数据库，测试结束时关闭
+func New(t testing.TB) *gorm.DB {
+	t.Helper()
+	registerFunctions()
+
+	// 共享缓存让同一数据库的多个连接看到相同的数据，并发测试不会各自打开一个空库
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared&_pragma=busy_timeout(5000)", atomic.AddInt64(&seq, 1))
+	db, err := gorm.Open(dialector{sqlite.Dialector{DriverName: registerDriver(), DSN: dsn}}, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(
+		&models.Model{},
+		&models.Task{},
+		&models.TaskLog{},
+		&models.SystemStats{},
+		&models.QueueMetric{},
+		&models.ScheduledTask{},
+		&models.ArchivedTask{},
+	); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	// MySQL 用生成列实现的去重唯一索引，在 SQLite 中等价为部分唯一索引
+	if err := db.Exec(`
+		CREATE UNIQUE INDEX uk_tasks_active_dedup_key ON tasks(dedup_key)
+			WHERE status IN ('pending', 'running') AND dedup_key <> ''
+	`).Error; err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,146 @@
+package grpcserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"llm-scheduler/models"
+	"llm-scheduler/proto/taskpb"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// 任务检索内容的长度范围，与 REST 接口的 q 参数一致
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 200
+)
+
+// taskStatusToProto models.TaskStatus 与 proto 枚举的对应关系
+var taskStatusToProto = map[models.TaskStatus]taskpb.TaskStatus{
+	models.TaskStatusPending:   taskpb.TaskStatus_TASK_STATUS_PENDING,
+	models.TaskStatusRunning:   taskpb.TaskStatus_TASK_STATUS_RUNNING,
+	models.TaskStatusCompleted: taskpb.TaskStatus_TASK_STATUS_COMPLETED,
+	models.TaskStatusFailed:    taskpb.TaskStatus_TASK_STATUS_FAILED,
+	models.TaskStatusCancelled: taskpb.TaskStatus_TASK_STATUS_CANCELLED,
+}
+
+// taskStatusFromProto proto 枚举与 models.TaskStatus 的对应关系
+var taskStatusFromProto = func() map[taskpb.TaskStatus]models.TaskStatus {
+	m := make(map[taskpb.TaskStatus]models.TaskStatus, len(taskStatusToProto))
+	for status, value := range taskStatusToProto {
+		m[value] = status
+	}
+	return m
+}()
+
+// taskToProto 将任务转换为 proto 消息，params 必须是 JSON 兼容的值
+func taskToProto(task *models.Task) (*taskpb.Task, error) {
+	msg := &taskpb.Task{
+		Id:                  task.ID,
+		ModelId:             task.ModelID,
+		Type:                task.Type,
+		Input:               task.Input,
+		Output:              task.Output,
+		OutputTruncated:     task.OutputTruncated,
+		Status:              taskStatusToProto[task.Status],
+		Priority:            taskpb.TaskPriority(task.Priority),
+		RetryCount:          int32(task.RetryCount),
+		MaxRetries:          int32(task.MaxRetries),
+		DependsOn:           task.DependsOn,
+		WaitingDependencies: task.WaitingDeps,
+		NeedsAttention:      task.NeedsAttention,
+		ErrorMessage:        task.ErrorMessage,
+		StartedAt:           timestampToProto(task.StartedAt),
+		CompletedAt:         timestampToProto(task.CompletedAt),
+		CreatedAt:           timestamppb.New(task.CreatedAt),
+		UpdatedAt:           timestamppb.New(task.UpdatedAt),
+	}
+	if len(task.Params) > 0 {
+		params, err := structpb.NewStruct(task.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert params of task %d: %w", task.ID, err)
+		}
+		msg.Params = params
+	}
+	return msg, nil
+}
+
+// timestampToProto 转换可选的时间，nil 时返回 nil
+func timestampToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// timestampFromProto 转换可选的时间，未设置时返回 nil
+func timestampFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// createRequestFromProto 将创建请求转换为 models.TaskCreateRequest，幂等键和调用方由调用者设置
+func createRequestFromProto(req *taskpb.CreateTaskRequest) *models.TaskCreateRequest {
+	createReq := &models.TaskCreateRequest{
+		ModelID:   req.GetModelId(),
+		Type:      req.GetType(),
+		Input:     req.GetInput(),
+		Priority:  models.TaskPriority(req.GetPriority()),
+		DependsOn: req.GetDependsOn(),
+	}
+	if req.Params != nil {
+		createReq.Params = req.Params.AsMap()
+	}
+	if req.MaxRetries != nil {
+		maxRetries := int(req.GetMaxRetries())
+		createReq.MaxRetries = &maxRetries
+	}
+	return createReq
+}
+
+// listRequestFromProto 将列表请求转换为 models.TaskListRequest，未设置的过滤条件不生效
+func listRequestFromProto(req *taskpb.ListTasksRequest) (*models.TaskListRequest, error) {
+	listReq := &models.TaskListRequest{
+		ModelID:       req.ModelId,
+		Query:         strings.TrimSpace(req.GetQ()),
+		CreatedAfter:  timestampFromProto(req.GetCreatedAfter()),
+		CreatedBefore: timestampFromProto(req.GetCreatedBefore()),
+		Page:          int(req.GetPage()),
+		PageSize:      int(req.GetPageSize()),
+		OrderBy:       req.GetOrderBy(),
+		Order:         req.GetOrder(),
+	}
+	if listReq.OrderBy == "" {
+		listReq.OrderBy = "created_at"
+	}
+
+	if req.GetStatus() != taskpb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		status, ok := taskStatusFromProto[req.GetStatus()]
+		if !ok {
+			return nil, fmt.Errorf("无效的 status %d", req.GetStatus())
+		}
+		listReq.Status = &status
+	}
+	if req.GetPriority() != taskpb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+		priority := models.TaskPriority(req.GetPriority())
+		if !priority.IsValid() {
+			return nil, fmt.Errorf("无效的 priority %d", req.GetPriority())
+		}
+		listReq.Priority = &priority
+	}
+	if req.GetType() != "" {
+		taskType := req.GetType()
+		listReq.Type = &taskType
+	}
+	if n := utf8.RuneCountInString(listReq.Query); n > 0 && (n < minSearchQueryLength || n > maxSearchQueryLength) {
+		return nil, fmt.Errorf("q 长度应在 %d 到 %d 个字符之间", minSearchQueryLength, maxSearchQueryLength)
+	}
+	return listReq, nil
+}
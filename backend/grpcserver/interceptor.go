@@ -0,0 +1,135 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/tracing"
+	"llm-scheduler/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// caller 通过认证的调用方，名称与 REST 接口一致，JWT 认证的调用方为 jwt:<sub>
+type caller struct {
+	name string
+	role string
+}
+
+type callerKey struct{}
+
+// callerFrom 读取 context 中的调用方，未启用认证时返回零值
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// tracingInterceptor 为每个调用创建服务端 span，沿用 metadata 中 traceparent 的上游追踪上下文，
+// 创建的任务入队时随队列项传给 Worker
+func tracingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.Extract(ctx, firstValue(md, "traceparent"))
+	ctx, span := tracing.Tracer().Start(ctx, info.FullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", info.FullMethod),
+		),
+	)
+	defer func() {
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+		tracing.End(span, err)
+	}()
+	return handler(ctx, req)
+}
+
+// authInterceptor 校验 metadata 中的 x-api-key 或（启用 JWT 时）authorization: Bearer 令牌，
+// 规则与 REST 接口的 AuthMiddleware 一致；未启用认证时直接放行
+func authInterceptor(cfg *config.AuthConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !cfg.Enabled {
+			return handler(ctx, req)
+		}
+		c, err := authenticate(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, callerKey{}, c), req)
+	}
+}
+
+// authenticate 识别调用方，失败时返回 Unauthenticated
+func authenticate(ctx context.Context, cfg *config.AuthConfig) (caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if token, ok := bearerToken(md); ok && cfg.JWT.Enabled {
+		claims, err := utils.ParseJWT(token, &cfg.JWT, time.Now())
+		if err != nil {
+			if errors.Is(err, utils.ErrTokenExpired) {
+				return caller{}, status.Error(codes.Unauthenticated, "令牌已过期")
+			}
+			return caller{}, status.Error(codes.Unauthenticated, "无效的令牌")
+		}
+		return caller{name: "jwt:" + claims.Subject, role: claims.Role}, nil
+	}
+
+	key := firstValue(md, "x-api-key")
+	if key == "" {
+		if cfg.JWT.Enabled {
+			return caller{}, status.Error(codes.Unauthenticated, "缺少 API Key 或令牌")
+		}
+		return caller{}, status.Error(codes.Unauthenticated, "缺少 API Key")
+	}
+
+	apiKey := cfg.LookupKey(key)
+	if apiKey == nil {
+		return caller{}, status.Error(codes.Unauthenticated, "无效的 API Key")
+	}
+	return caller{name: apiKey.Name, role: apiKey.GetRole()}, nil
+}
+
+// requireRole 要求调用方具有指定角色，admin 满足任何角色要求；未启用认证时 context 中没有调用方，直接放行
+func requireRole(ctx context.Context, role string) error {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	if !ok {
+		return nil
+	}
+	if c.role != config.RoleAdmin && c.role != role {
+		return status.Error(codes.PermissionDenied, "权限不足")
+	}
+	return nil
+}
+
+// scopeIdempotencyKey 按调用方区分幂等键，作用域与 REST 接口一致，两种接口使用相同的键时返回同一个任务
+func scopeIdempotencyKey(ctx context.Context, key string) string {
+	if name := callerFrom(ctx).name; name != "" {
+		return "key:" + name + ":" + key
+	}
+	return "anon:" + key
+}
+
+// bearerToken 读取 authorization: Bearer metadata 中的令牌
+func bearerToken(md metadata.MD) (string, bool) {
+	value := firstValue(md, "authorization")
+	if len(value) < 7 || !strings.EqualFold(value[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(value[7:])
+	return token, token != ""
+}
+
+// firstValue 读取 metadata 中键的第一个值，不存在时返回空字符串
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
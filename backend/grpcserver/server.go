@@ -0,0 +1,173 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/proto/taskpb"
+	"llm-scheduler/services"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxIdempotencyKeyLength 幂等键的最大长度，与 REST 接口的 Idempotency-Key 请求头一致
+const maxIdempotencyKeyLength = 255
+
+// maxPageSize 列表接口的最大页面大小，与 REST 接口一致
+const maxPageSize = 100
+
+// TaskService gRPC 服务依赖的任务操作，由 *services.TaskService 实现
+type TaskService interface {
+	CreateTask(ctx context.Context, req *models.TaskCreateRequest) (*models.Task, error)
+	GetTask(id uint64) (*models.Task, error)
+	ResolveOutput(ctx context.Context, task *models.Task) error
+	ListTasks(req *models.TaskListRequest) ([]models.Task, int64, error)
+	CancelTask(ctx context.Context, id uint64) error
+}
+
+var _ TaskService = (*services.TaskService)(nil)
+
+// Server 任务 gRPC 服务，各方法委托给 TaskService，行为与 /api/v1/tasks 对应的 REST 接口一致
+type Server struct {
+	taskpb.UnimplementedTaskServiceServer
+
+	tasks  TaskService
+	logger *logrus.Logger
+}
+
+// NewServer 创建 gRPC 服务器并注册任务服务，启用认证时校验调用方身份
+func NewServer(cfg *config.Config, tasks TaskService, logger *logrus.Logger) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		tracingInterceptor,
+		authInterceptor(&cfg.Auth),
+	))
+	taskpb.RegisterTaskServiceServer(srv, &Server{tasks: tasks, logger: logger})
+	return srv
+}
+
+// CreateTask 创建任务
+func (s *Server) CreateTask(ctx context.Context, req *taskpb.CreateTaskRequest) (*taskpb.Task, error) {
+	if err := requireRole(ctx, config.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.GetInput() == "" {
+		return nil, status.Error(codes.InvalidArgument, "input 不能为空")
+	}
+	if len(req.GetIdempotencyKey()) > maxIdempotencyKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "idempotency_key 长度不能超过 %d", maxIdempotencyKeyLength)
+	}
+
+	createReq := createRequestFromProto(req)
+	if createReq.Priority == 0 {
+		createReq.Priority = models.TaskPriorityMedium
+	}
+	if req.GetIdempotencyKey() != "" {
+		createReq.IdempotencyKey = scopeIdempotencyKey(ctx, req.GetIdempotencyKey())
+	}
+	createReq.APIKeyName = callerFrom(ctx).name
+
+	task, err := s.tasks.CreateTask(ctx, createReq)
+	if err != nil {
+		return nil, s.statusError(err, "Failed to create task")
+	}
+	return s.taskResponse(task)
+}
+
+// GetTask 获取任务详情，输出保存在外部存储时读取内容返回
+func (s *Server) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.Task, error) {
+	task, err := s.tasks.GetTask(req.GetId())
+	if err != nil {
+		return nil, s.statusError(err, "Failed to get task")
+	}
+	if err := s.tasks.ResolveOutput(ctx, task); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to read task output")
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return s.taskResponse(task)
+}
+
+// ListTasks 分页获取任务列表
+func (s *Server) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+	listReq, err := listRequestFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if listReq.Page <= 0 {
+		listReq.Page = 1
+	}
+	if listReq.PageSize <= 0 {
+		listReq.PageSize = 20
+	}
+	if listReq.PageSize > maxPageSize {
+		listReq.PageSize = maxPageSize
+	}
+
+	tasks, total, err := s.tasks.ListTasks(listReq)
+	if err != nil {
+		return nil, s.statusError(err, "Failed to list tasks")
+	}
+
+	resp := &taskpb.ListTasksResponse{
+		Tasks:    make([]*taskpb.Task, 0, len(tasks)),
+		Total:    total,
+		Page:     int32(listReq.Page),
+		PageSize: int32(listReq.PageSize),
+	}
+	for i := range tasks {
+		msg, err := s.taskResponse(&tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		resp.Tasks = append(resp.Tasks, msg)
+	}
+	return resp, nil
+}
+
+// CancelTask 取消任务
+func (s *Server) CancelTask(ctx context.Context, req *taskpb.CancelTaskRequest) (*taskpb.CancelTaskResponse, error) {
+	if err := requireRole(ctx, config.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.tasks.CancelTask(ctx, req.GetId()); err != nil {
+		return nil, s.statusError(err, "Failed to cancel task")
+	}
+	return &taskpb.CancelTaskResponse{}, nil
+}
+
+// taskResponse 将任务转换为 proto 消息，转换失败时返回 Internal
+func (s *Server) taskResponse(task *models.Task) (*taskpb.Task, error) {
+	msg, err := taskToProto(task)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to convert task")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return msg, nil
+}
+
+// statusError 将服务层错误映射为 gRPC 状态码，对应 REST 接口的 HTTP 状态码；未识别的错误记录日志并返回 Internal
+func (s *Server) statusError(err error, msg string) error {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, validationErr.Error())
+	case errors.Is(err, services.ErrTaskNotFound):
+		return status.Error(codes.NotFound, "任务不存在")
+	case errors.Is(err, services.ErrModelNotFound):
+		return status.Error(codes.InvalidArgument, "模型不存在")
+	case errors.Is(err, services.ErrInvalidStatusTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, services.ErrIdempotentRequestInProgress):
+		return status.Error(codes.Aborted, "相同 idempotency_key 的请求正在处理中，请稍后重试")
+	case errors.Is(err, services.ErrIdempotencyKeyMismatch):
+		return status.Error(codes.FailedPrecondition, "idempotency_key 已被内容不同的请求使用")
+	case errors.Is(err, services.ErrQueueFull):
+		return status.Error(codes.Unavailable, "任务队列已满，请稍后重试")
+	}
+	s.logger.WithError(err).Error(msg)
+	return status.Error(codes.Internal, err.Error())
+}
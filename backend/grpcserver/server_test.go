@@ -0,0 +1,330 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/proto/taskpb"
+	"llm-scheduler/services"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeTaskService 在内存中保存任务，记录最近一次创建和列表请求
+type fakeTaskService struct {
+	mu         sync.Mutex
+	tasks      map[uint64]*models.Task
+	nextID     uint64
+	lastCreate *models.TaskCreateRequest
+	lastList   *models.TaskListRequest
+	// createErr 不为 nil 时 CreateTask 返回该错误
+	createErr error
+}
+
+func newFakeTaskService() *fakeTaskService {
+	return &fakeTaskService{tasks: make(map[uint64]*models.Task)}
+}
+
+func (f *fakeTaskService) CreateTask(ctx context.Context, req *models.TaskCreateRequest) (*models.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastCreate = req
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+
+	f.nextID++
+	maxRetries := 3
+	if req.MaxRetries != nil {
+		maxRetries = *req.MaxRetries
+	}
+	now := time.Now()
+	task := &models.Task{
+		ID:         f.nextID,
+		ModelID:    req.ModelID,
+		Type:       req.Type,
+		Input:      req.Input,
+		Params:     req.Params,
+		Status:     models.TaskStatusPending,
+		Priority:   req.Priority,
+		MaxRetries: maxRetries,
+		DependsOn:  req.DependsOn,
+		APIKeyName: req.APIKeyName,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	f.tasks[task.ID] = task
+	copied := *task
+	return &copied, nil
+}
+
+func (f *fakeTaskService) GetTask(id uint64) (*models.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	task, ok := f.tasks[id]
+	if !ok {
+		return nil, services.ErrTaskNotFound
+	}
+	copied := *task
+	return &copied, nil
+}
+
+func (f *fakeTaskService) ResolveOutput(ctx context.Context, task *models.Task) error {
+	return nil
+}
+
+func (f *fakeTaskService) ListTasks(req *models.TaskListRequest) ([]models.Task, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastList = req
+	var tasks []models.Task
+	for id := uint64(1); id <= f.nextID; id++ {
+		if task, ok := f.tasks[id]; ok && (req.Status == nil || task.Status == *req.Status) {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, int64(len(tasks)), nil
+}
+
+func (f *fakeTaskService) CancelTask(ctx context.Context, id uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	task, ok := f.tasks[id]
+	if !ok {
+		return services.ErrTaskNotFound
+	}
+	if !task.CanTransitionTo(models.TaskStatusCancelled) {
+		return fmt.Errorf("task cannot be cancelled in current status %s: %w", task.Status, services.ErrInvalidStatusTransition)
+	}
+	task.Status = models.TaskStatusCancelled
+	return nil
+}
+
+// newTestClient 通过 bufconn 启动 gRPC 服务并返回连接到它的客户端
+func newTestClient(t *testing.T, cfg *config.Config, tasks TaskService) taskpb.TaskServiceClient {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(cfg, tasks, logger)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return taskpb.NewTaskServiceClient(conn)
+}
+
+// withAPIKey 在调用的 metadata 中携带 API Key
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestTaskServiceRoundTrip(t *testing.T) {
+	tasks := newFakeTaskService()
+	client := newTestClient(t, &config.Config{}, tasks)
+	ctx := context.Background()
+
+	params, err := structpb.NewStruct(map[string]interface{}{"temperature": 0.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateTask(ctx, &taskpb.CreateTaskRequest{
+		ModelId:        1,
+		Type:           "summarization",
+		Input:          "hello",
+		Params:         params,
+		MaxRetries:     proto.Int32(0),
+		IdempotencyKey: "req-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if created.GetId() == 0 || created.GetStatus() != taskpb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("CreateTask() = id %d status %s, want new pending task", created.GetId(), created.GetStatus())
+	}
+
+	// 未指定优先级时与 REST 接口一样使用 medium，max_retries 为 0 时不允许重试
+	req := tasks.lastCreate
+	if req.Priority != models.TaskPriorityMedium {
+		t.Errorf("priority = %d, want medium", req.Priority)
+	}
+	if req.MaxRetries == nil || *req.MaxRetries != 0 {
+		t.Errorf("max_retries = %v, want explicit 0", req.MaxRetries)
+	}
+	if req.IdempotencyKey != "anon:req-1" {
+		t.Errorf("idempotency key = %q, want anon:req-1", req.IdempotencyKey)
+	}
+
+	got, err := client.GetTask(ctx, &taskpb.GetTaskRequest{Id: created.GetId()})
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if got.GetInput() != "hello" || got.GetType() != "summarization" || got.GetModelId() != 1 {
+		t.Errorf("GetTask() = %+v, want the created task", got)
+	}
+	if got.GetPriority() != taskpb.TaskPriority_TASK_PRIORITY_MEDIUM || got.GetMaxRetries() != 0 {
+		t.Errorf("GetTask() priority %s max_retries %d, want medium and 0", got.GetPriority(), got.GetMaxRetries())
+	}
+	if temp := got.GetParams().GetFields()["temperature"].GetNumberValue(); temp != 0.2 {
+		t.Errorf("params.temperature = %v, want 0.2", temp)
+	}
+	if got.GetCreatedAt() == nil || got.GetStartedAt() != nil {
+		t.Errorf("created_at %v started_at %v, want only created_at set", got.GetCreatedAt(), got.GetStartedAt())
+	}
+
+	if _, err := client.CancelTask(ctx, &taskpb.CancelTaskRequest{Id: created.GetId()}); err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+
+	list, err := client.ListTasks(ctx, &taskpb.ListTasksRequest{Status: taskpb.TaskStatus_TASK_STATUS_CANCELLED, PageSize: 500})
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if list.GetTotal() != 1 || len(list.GetTasks()) != 1 || list.GetTasks()[0].GetStatus() != taskpb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("ListTasks() = %+v, want the cancelled task", list)
+	}
+	if list.GetPage() != 1 || list.GetPageSize() != maxPageSize {
+		t.Errorf("ListTasks() page %d page_size %d, want 1 and %d", list.GetPage(), list.GetPageSize(), maxPageSize)
+	}
+	if tasks.lastList.OrderBy != "created_at" {
+		t.Errorf("order_by = %q, want created_at", tasks.lastList.OrderBy)
+	}
+}
+
+func TestTaskServiceErrorCodes(t *testing.T) {
+	tasks := newFakeTaskService()
+	client := newTestClient(t, &config.Config{}, tasks)
+	ctx := context.Background()
+
+	created, err := client.CreateTask(ctx, &taskpb.CreateTaskRequest{Input: "hello"})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if _, err := client.CancelTask(ctx, &taskpb.CancelTaskRequest{Id: created.GetId()}); err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"missing task", func() error {
+			_, err := client.GetTask(ctx, &taskpb.GetTaskRequest{Id: 404})
+			return err
+		}, codes.NotFound},
+		{"cancel finished task", func() error {
+			_, err := client.CancelTask(ctx, &taskpb.CancelTaskRequest{Id: created.GetId()})
+			return err
+		}, codes.FailedPrecondition},
+		{"empty input", func() error {
+			_, err := client.CreateTask(ctx, &taskpb.CreateTaskRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"query too short", func() error {
+			_, err := client.ListTasks(ctx, &taskpb.ListTasksRequest{Q: "a"})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown priority filter", func() error {
+			_, err := client.ListTasks(ctx, &taskpb.ListTasksRequest{Priority: 9})
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.want {
+				t.Errorf("code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	serviceErrors := []struct {
+		err  error
+		want codes.Code
+	}{
+		{services.ErrModelNotFound, codes.InvalidArgument},
+		{services.ErrQueueFull, codes.Unavailable},
+		{services.ErrIdempotentRequestInProgress, codes.Aborted},
+		{services.ErrIdempotencyKeyMismatch, codes.FailedPrecondition},
+		{&services.ValidationError{Fields: []models.FieldError{{Field: "input", Message: "too long"}}}, codes.InvalidArgument},
+		{fmt.Errorf("failed to create task: %w", context.DeadlineExceeded), codes.Internal},
+	}
+	for _, tt := range serviceErrors {
+		tasks.createErr = tt.err
+		_, err := client.CreateTask(ctx, &taskpb.CreateTaskRequest{Input: "hello"})
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("CreateTask() with %v code = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTaskServiceAuth(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.Enabled = true
+	cfg.Auth.Keys = []config.APIKeyConfig{
+		{Name: "ops", Key: "admin-key"},
+		{Name: "dashboard", Key: "viewer-key", Role: config.RoleViewer},
+	}
+	tasks := newFakeTaskService()
+	client := newTestClient(t, cfg, tasks)
+
+	create := &taskpb.CreateTaskRequest{Input: "hello", IdempotencyKey: "req-1"}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"missing key", context.Background(), codes.Unauthenticated},
+		{"unknown key", withAPIKey("nope"), codes.Unauthenticated},
+		{"viewer cannot create", withAPIKey("viewer-key"), codes.PermissionDenied},
+		{"admin creates", withAPIKey("admin-key"), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateTask(tt.ctx, create)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("CreateTask() code = %s, want %s (err %v)", got, tt.want, err)
+			}
+		})
+	}
+
+	// 创建任务记录调用方，幂等键按调用方区分
+	if tasks.lastCreate.APIKeyName != "ops" || tasks.lastCreate.IdempotencyKey != "key:ops:req-1" {
+		t.Errorf("create request api key %q idempotency key %q, want ops and key:ops:req-1",
+			tasks.lastCreate.APIKeyName, tasks.lastCreate.IdempotencyKey)
+	}
+
+	// viewer 只能查询
+	if _, err := client.GetTask(withAPIKey("viewer-key"), &taskpb.GetTaskRequest{Id: 1}); err != nil {
+		t.Errorf("viewer GetTask() error = %v, want nil", err)
+	}
+	if _, err := client.CancelTask(withAPIKey("viewer-key"), &taskpb.CancelTaskRequest{Id: 1}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("viewer CancelTask() code = %s, want PermissionDenied", status.Code(err))
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestServiceErrorStatusMapping(t *testing.T) {
+	env := newTestEnv(t, nil)
+	tasks := newTaskRouter(env)
+	modelRouter := newModelRouter(env)
+	running := env.createTask(t, models.TaskStatusRunning)
+	completed := env.createTask(t, models.TaskStatusCompleted)
+	missing := completed.ID + 100
+
+	tests := []struct {
+		name   string
+		router http.Handler
+		method string
+		url    string
+		body   string
+		status int
+	}{
+		// ErrTaskNotFound -> 404
+		{"get missing task", tasks, http.MethodGet, fmt.Sprintf("/tasks/%d", missing), "", http.StatusNotFound},
+		{"update missing task", tasks, http.MethodPut, fmt.Sprintf("/tasks/%d", missing), `{"priority":1}`, http.StatusNotFound},
+		{"cancel missing task", tasks, http.MethodDelete, fmt.Sprintf("/tasks/%d", missing), "", http.StatusNotFound},
+		{"retry missing task", tasks, http.MethodPost, fmt.Sprintf("/tasks/%d/retry", missing), "", http.StatusNotFound},
+		{"logs of missing task", tasks, http.MethodGet, fmt.Sprintf("/tasks/%d/logs", missing), "", http.StatusNotFound},
+		// ErrInvalidStatusTransition -> 409
+		{"cancel completed task", tasks, http.MethodDelete, fmt.Sprintf("/tasks/%d", completed.ID), "", http.StatusConflict},
+		{"retry running task", tasks, http.MethodPost, fmt.Sprintf("/tasks/%d/retry", running.ID), "", http.StatusConflict},
+		{"invalid status update", tasks, http.MethodPut, fmt.Sprintf("/tasks/%d", completed.ID), `{"status":"running"}`, http.StatusConflict},
+		// ErrModelNotFound -> 创建任务时为 400，模型接口为 404
+		{"create task for missing model", tasks, http.MethodPost, "/tasks", fmt.Sprintf(`{"model_id":%d,"type":"summarization","input":"text"}`, env.modelID+100), http.StatusBadRequest},
+		{"get missing model", modelRouter, http.MethodGet, fmt.Sprintf("/models/%d", env.modelID+100), "", http.StatusNotFound},
+		{"delete missing model", modelRouter, http.MethodDelete, fmt.Sprintf("/models/%d", env.modelID+100), "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
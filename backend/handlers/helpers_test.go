@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/database/testdb"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/services"
+	"llm-scheduler/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testEnv 连接内存数据库和 miniredis 的任务服务
+type testEnv struct {
+	cfg     *config.Config
+	db      *gorm.DB
+	redis   *miniredis.Miniredis
+	queue   *queue.Manager
+	tasks   *services.TaskService
+	logger  *logrus.Logger
+	modelID uint64
+}
+
+// newTestConfig 返回与 config.yaml 一致的队列键名配置
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		CancelChannel:       "llm_tasks:cancel",
+		EventChannel:        "llm_tasks:events",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+		TaskTimeout:         5 * time.Minute,
+		RetryDelay:          time.Minute,
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	return cfg
+}
+
+// newTestEnv 创建测试环境并登记一个在线模型，configure 可在创建服务前修改配置
+func newTestEnv(t *testing.T, configure func(*config.Config)) *testEnv {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db := testdb.New(t)
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	env := &testEnv{
+		cfg:    cfg,
+		db:     db,
+		redis:  server,
+		queue:  queueManager,
+		tasks:  services.NewTaskService(db, queueManager, nil, cfg, logger),
+		logger: logger,
+	}
+
+	model := &models.Model{
+		Name:       "test-model",
+		Type:       models.ModelTypeCustom,
+		Config:     models.ModelConfig{},
+		Status:     models.ModelStatusOnline,
+		MaxWorkers: 1,
+	}
+	if err := db.Create(model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	env.modelID = model.ID
+	return env
+}
+
+// createTask 直接在数据库中创建指定状态的任务
+func (env *testEnv) createTask(t *testing.T, status models.TaskStatus) *models.Task {
+	t.Helper()
+	task := &models.Task{
+		ModelID:  env.modelID,
+		Type:     models.TaskTypeTextGeneration,
+		Input:    "hello",
+		Priority: models.TaskPriorityMedium,
+		Status:   status,
+	}
+	if err := env.db.Create(task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+// startServer 以与 main 相同的方式启动 HTTP 服务：设置写超时，并通过 BaseContext 传递关闭通知
+func startServer(t *testing.T, router http.Handler, writeTimeout time.Duration) *httptest.Server {
+	t.Helper()
+
+	baseCtx, notifyShutdown := utils.WithShutdown(context.Background())
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Config.BaseContext = func(net.Listener) context.Context { return baseCtx }
+	srv.Config.RegisterOnShutdown(notifyShutdown)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sseEvent 输出流中的一个事件
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents 读取 SSE 响应中的事件，直到连接结束，心跳注释和 id 行被忽略
+func readEvents(r io.Reader) ([]sseEvent, error) {
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.name != "" || current.data != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "event:"):
+			current.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			current.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	return events, scanner.Err()
+}
+
+// waitFor 轮询直到条件成立或超时
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
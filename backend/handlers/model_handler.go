@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"strconv"
 
 	"llm-scheduler/models"
@@ -15,13 +15,15 @@ import (
 // ModelHandler 模型处理器
 type ModelHandler struct {
 	modelService *services.ModelService
+	taskService  *services.TaskService
 	logger       *logrus.Logger
 }
 
 // NewModelHandler 创建模型处理器
-func NewModelHandler(modelService *services.ModelService, logger *logrus.Logger) *ModelHandler {
+func NewModelHandler(modelService *services.ModelService, taskService *services.TaskService, logger *logrus.Logger) *ModelHandler {
 	return &ModelHandler{
 		modelService: modelService,
+		taskService:  taskService,
 		logger:       logger,
 	}
 }
@@ -49,11 +51,16 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 
 	createdModel, err := h.modelService.CreateModel(&model)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to create model")
-		if err.Error() == fmt.Sprintf("model with name '%s' already exists", model.Name) {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrModelNameExists) {
 			utils.BadRequest(c, err.Error())
 			return
 		}
+		h.logger.WithError(err).Error("Failed to create model")
 		utils.InternalServerError(c, err.Error())
 		return
 	}
@@ -61,6 +68,46 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 	utils.SuccessWithMessage(c, "模型创建成功", createdModel)
 }
 
+// UpsertModel 按名称创建或更新模型，已存在时只更新请求中给出的字段
+func (h *ModelHandler) UpsertModel(c *gin.Context) {
+	var model models.Model
+	if err := c.ShouldBindJSON(&model); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	if model.Name == "" {
+		utils.BadRequest(c, "模型名称不能为空")
+		return
+	}
+	if model.Type == "" {
+		utils.BadRequest(c, "模型类型不能为空")
+		return
+	}
+
+	result, created, err := h.modelService.UpsertModel(&model)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrModelNameExists) {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		h.logger.WithError(err).Error("Failed to upsert model")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	if created {
+		utils.SuccessWithMessage(c, "模型创建成功", result)
+		return
+	}
+	utils.SuccessWithMessage(c, "模型更新成功", result)
+}
+
 // GetModel 获取模型详情
 func (h *ModelHandler) GetModel(c *gin.Context) {
 	idStr := c.Param("id")
@@ -72,7 +119,7 @@ func (h *ModelHandler) GetModel(c *gin.Context) {
 
 	model, err := h.modelService.GetModel(id)
 	if err != nil {
-		if err.Error() == "model not found" {
+		if errors.Is(err, services.ErrModelNotFound) {
 			utils.NotFound(c, "模型不存在")
 			return
 		}
@@ -126,10 +173,19 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 
 	model, err := h.modelService.UpdateModel(id, &updates)
 	if err != nil {
-		if err.Error() == "model not found" {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrModelNotFound) {
 			utils.NotFound(c, "模型不存在")
 			return
 		}
+		if errors.Is(err, services.ErrModelNameExists) {
+			utils.BadRequest(c, err.Error())
+			return
+		}
 		h.logger.WithError(err).Error("Failed to update model")
 		utils.InternalServerError(c, err.Error())
 		return
@@ -138,6 +194,43 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 	utils.SuccessWithMessage(c, "模型更新成功", model)
 }
 
+// MergeModelConfig 合并更新模型配置，只修改请求中出现的键，值为 null 的键被删除
+func (h *ModelHandler) MergeModelConfig(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的模型ID")
+		return
+	}
+
+	var patch models.ModelConfig
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+	if len(patch) == 0 {
+		utils.BadRequest(c, "配置不能为空")
+		return
+	}
+
+	model, err := h.modelService.MergeConfig(id, patch)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to merge model config")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "模型配置更新成功", model)
+}
+
 // DeleteModel 删除模型
 func (h *ModelHandler) DeleteModel(c *gin.Context) {
 	idStr := c.Param("id")
@@ -148,6 +241,10 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 	}
 
 	if err := h.modelService.DeleteModel(id); err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
 		h.logger.WithError(err).Error("Failed to delete model")
 		utils.BadRequest(c, err.Error())
 		return
@@ -166,20 +263,38 @@ func (h *ModelHandler) UpdateModelStatus(c *gin.Context) {
 	}
 
 	var req struct {
-		Status models.ModelStatus `json:"status" binding:"required"`
+		Status models.ModelStatus `json:"status" binding:"required,oneof=online offline maintenance"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ValidationError(c, err)
 		return
 	}
 
+	// cancel_pending=true 时取消模型所有等待中的任务，否则任务保留在队列中，模型恢复在线后继续执行
+	cancelPending := c.Query("cancel_pending") == "true"
+	if cancelPending && req.Status == models.ModelStatusOnline {
+		utils.BadRequest(c, "cancel_pending 只能在模型下线或进入维护时使用")
+		return
+	}
+
 	if err := h.modelService.UpdateModelStatus(id, req.Status); err != nil {
 		h.logger.WithError(err).Error("Failed to update model status")
 		utils.InternalServerError(c, err.Error())
 		return
 	}
 
-	utils.SuccessWithMessage(c, "模型状态更新成功", nil)
+	if !cancelPending {
+		utils.SuccessWithMessage(c, "模型状态更新成功", nil)
+		return
+	}
+
+	cancelled, err := h.taskService.CancelModelPendingTasks(c.Request.Context(), id, c.Query("reason"))
+	if err != nil {
+		h.logger.WithError(err).WithField("model_id", id).Error("Failed to cancel pending tasks of model")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "模型状态更新成功", gin.H{"cancelled": cancelled})
 }
 
 // GetModelStats 获取模型统计
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newModelRouter 注册模型接口的测试路由
+func newModelRouter(env *testEnv) *gin.Engine {
+	h := NewModelHandler(services.NewModelService(env.db, env.logger), env.tasks, env.logger)
+	router := gin.New()
+	router.GET("/models", h.ListModels)
+	router.PUT("/models", h.UpsertModel)
+	router.GET("/models/:id", h.GetModel)
+	router.PATCH("/models/:id/config", h.MergeModelConfig)
+	router.PUT("/models/:id/status", h.UpdateModelStatus)
+	router.DELETE("/models/:id", h.DeleteModel)
+	return router
+}
+
+func TestDeleteModelKeepsHistoricalTaskModel(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newModelRouter(env)
+	task := env.createTask(t, models.TaskStatusRunning)
+	modelURL := fmt.Sprintf("/models/%d", env.modelID)
+
+	// 有运行中任务时拒绝删除
+	if w := serve(router, http.MethodDelete, modelURL); w.Code != http.StatusBadRequest {
+		t.Fatalf("delete with running task: status = %d, want 400: %s", w.Code, w.Body.String())
+	}
+
+	env.db.Model(task).Update("status", models.TaskStatusCompleted)
+	if w := serve(router, http.MethodDelete, modelURL); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, modelURL); w.Code != http.StatusNotFound {
+		t.Fatalf("second delete: status = %d, want 404", w.Code)
+	}
+	if w := serve(router, http.MethodGet, modelURL); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted model: status = %d, want 404", w.Code)
+	}
+
+	w := serve(router, http.MethodGet, "/models")
+	var list struct {
+		Data []models.Model `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode models: %v: %s", err, w.Body.String())
+	}
+	if len(list.Data) != 0 {
+		t.Fatalf("listed models = %+v, want deleted model hidden", list.Data)
+	}
+
+	// 历史任务详情仍包含模型名称
+	w = serve(newTaskRouter(env), http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get task: status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := decodeTask(t, w); got.Model == nil || got.Model.Name != "test-model" {
+		t.Fatalf("task model = %+v, want test-model", got.Model)
+	}
+}
+
+func TestMergeModelConfigEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newModelRouter(env)
+	if err := env.db.Model(&models.Model{}).Where("id = ?", env.modelID).
+		Update("config", models.ModelConfig{"host": "10.0.0.1", "port": 8080, "api_key": "sk-old"}).Error; err != nil {
+		t.Fatalf("set config: %v", err)
+	}
+	patch := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	configURL := fmt.Sprintf("/models/%d/config", env.modelID)
+
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		status int
+	}{
+		{"empty patch", configURL, `{}`, http.StatusBadRequest},
+		{"not an object", configURL, `["api_key"]`, http.StatusBadRequest},
+		{"invalid template", configURL, `{"prompt_template":"{{.Input"}`, http.StatusBadRequest},
+		{"invalid id", "/models/abc/config", `{"api_key":"sk"}`, http.StatusBadRequest},
+		{"unknown model", "/models/999/config", `{"api_key":"sk"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := patch(tt.url, tt.body); w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	// 只更新请求中的键，null 删除键，其他键保持不变
+	w := patch(configURL, `{"api_key":"sk-new","port":null}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge: status = %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.Model
+	if err := env.db.First(&stored, env.modelID).Error; err != nil {
+		t.Fatalf("reload model: %v", err)
+	}
+	if len(stored.Config) != 2 || stored.Config["host"] != "10.0.0.1" || stored.Config["api_key"] != "sk-new" {
+		t.Fatalf("stored config = %v, want host kept, api_key rotated and port removed", stored.Config)
+	}
+}
+
+func TestUpsertModelEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newModelRouter(env)
+	put := func(body string) (int, string, models.Model) {
+		req := httptest.NewRequest(http.MethodPut, "/models", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Message string       `json:"message"`
+			Data    models.Model `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Message, resp.Data
+	}
+
+	// 第一次创建，重复执行时更新同一个模型
+	code, message, created := put(`{"name":"gpt","type":"openai","config":{"base_url":"https://a.example.com"}}`)
+	if code != http.StatusOK || message != "模型创建成功" || created.ID == 0 {
+		t.Fatalf("first upsert: status = %d message = %q model = %+v", code, message, created)
+	}
+	code, message, updated := put(`{"name":"gpt","type":"openai","config":{"base_url":"https://b.example.com"}}`)
+	if code != http.StatusOK || message != "模型更新成功" || updated.ID != created.ID {
+		t.Fatalf("second upsert: status = %d message = %q model %d, want update of %d", code, message, updated.ID, created.ID)
+	}
+	if updated.Config["base_url"] != "https://b.example.com" {
+		t.Fatalf("config = %v, want updated base_url", updated.Config)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"type":"openai"}`},
+		{"missing type", `{"name":"gpt"}`},
+		{"invalid config", `{"name":"gpt","type":"openai","config":{"prompt_template":"{{.Input"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := put(tt.body); code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", code)
+			}
+		})
+	}
+}
+
+func TestUpdateModelStatusCancelPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        models.ModelStatus
+		query         string
+		code          int
+		wantCancelled bool
+	}{
+		// 默认保留等待中的任务，模型恢复在线后继续执行
+		{"keep pending tasks", models.ModelStatusMaintenance, "", http.StatusOK, false},
+		{"cancel pending tasks", models.ModelStatusMaintenance, "?cancel_pending=true&reason=upgrade", http.StatusOK, true},
+		{"cancel when offline", models.ModelStatusOffline, "?cancel_pending=true&reason=upgrade", http.StatusOK, true},
+		{"cancel when going online", models.ModelStatusOnline, "?cancel_pending=true", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			router := newModelRouter(env)
+			task, err := env.tasks.CreateTask(context.Background(), &models.TaskCreateRequest{ModelID: env.modelID, Type: models.TaskTypeTextGeneration, Input: "hello"})
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+
+			body := fmt.Sprintf(`{"status":%q}`, tt.status)
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/models/%d/status%s", env.modelID, tt.query), strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+
+			var got models.Task
+			if err := env.db.First(&got, task.ID).Error; err != nil {
+				t.Fatalf("reload task: %v", err)
+			}
+			status, err := env.queue.GetQueueStatus(context.Background())
+			if err != nil {
+				t.Fatalf("queue status: %v", err)
+			}
+			if !tt.wantCancelled {
+				if got.Status != models.TaskStatusPending || status.TotalCount != 1 {
+					t.Fatalf("task = %s with %d queued, want pending and still queued", got.Status, status.TotalCount)
+				}
+				return
+			}
+
+			var resp struct {
+				Data struct {
+					Cancelled int `json:"cancelled"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Cancelled != 1 {
+				t.Fatalf("response = %s (err %v), want 1 cancelled", w.Body.String(), err)
+			}
+			if got.Status != models.TaskStatusCancelled || got.ErrorMessage == nil || *got.ErrorMessage != "upgrade" || status.TotalCount != 0 {
+				t.Fatalf("task = %s %v with %d queued, want cancelled and removed", got.Status, got.ErrorMessage, status.TotalCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"strconv"
+
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/services"
+	"llm-scheduler/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultPeekLimit 查看队列默认返回的任务数
+	defaultPeekLimit = 10
+	// maxPeekLimit 查看队列最多返回的任务数
+	maxPeekLimit = 100
+)
+
+// QueueHandler 队列处理器
+type QueueHandler struct {
+	queueManager *queue.Manager
+	taskService  *services.TaskService
+	logger       *logrus.Logger
+}
+
+// NewQueueHandler 创建队列处理器
+func NewQueueHandler(queueManager *queue.Manager, taskService *services.TaskService, logger *logrus.Logger) *QueueHandler {
+	return &QueueHandler{
+		queueManager: queueManager,
+		taskService:  taskService,
+		logger:       logger,
+	}
+}
+
+// GetQueueStatus 获取队列状态
+func (h *QueueHandler) GetQueueStatus(c *gin.Context) {
+	status, err := h.queueManager.GetQueueStatus(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get queue status")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// PeekQueue 查看指定优先级队列中即将执行的任务，不会移除任务
+func (h *QueueHandler) PeekQueue(c *gin.Context) {
+	priority, ok := models.ParseTaskPriority(c.DefaultQuery("priority", "medium"))
+	if !ok {
+		utils.BadRequest(c, "无效的优先级")
+		return
+	}
+
+	limit := defaultPeekLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			utils.BadRequest(c, "无效的数量")
+			return
+		}
+		limit = n
+	}
+	if limit > maxPeekLimit {
+		limit = maxPeekLimit
+	}
+
+	items, err := h.queueManager.PeekQueue(c.Request.Context(), priority, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to peek queue")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, items)
+}
+
+// PurgeQueue 清空指定优先级队列，并将其中的任务标记为取消，需要携带 confirm=true
+func (h *QueueHandler) PurgeQueue(c *gin.Context) {
+	priority, ok := models.ParseTaskPriority(c.Param("priority"))
+	if !ok {
+		utils.BadRequest(c, "无效的优先级")
+		return
+	}
+	if c.Query("confirm") != "true" {
+		utils.BadRequest(c, "清空队列需要携带 confirm=true 参数")
+		return
+	}
+
+	result, err := h.taskService.PurgeQueue(c.Request.Context(), priority, c.Query("reason"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to purge queue")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "队列已清空", result)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newQueueRouter 注册队列接口的测试路由
+func newQueueRouter(env *testEnv) *gin.Engine {
+	h := NewQueueHandler(env.queue, env.tasks, env.logger)
+	router := gin.New()
+	router.GET("/queue/status", h.GetQueueStatus)
+	router.GET("/queue/peek", h.PeekQueue)
+	router.DELETE("/queue/:priority", h.PurgeQueue)
+	return router
+}
+
+func TestPeekQueueEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newQueueRouter(env)
+	for i := 0; i < 3; i++ {
+		task := env.createTask(t, models.TaskStatusPending)
+		if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   int
+	}{
+		{"default priority and limit", "", http.StatusOK, 3},
+		{"limit", "?priority=medium&limit=2", http.StatusOK, 2},
+		{"empty queue", "?priority=high", http.StatusOK, 0},
+		{"invalid priority", "?priority=urgent", http.StatusBadRequest, 0},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/queue/peek"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data []queue.QueueItem `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Data == nil || len(resp.Data) != tt.want {
+				t.Fatalf("peeked %d items, want %d: %s", len(resp.Data), tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// 查看后任务仍在队列中
+	w := serve(router, http.MethodGet, "/queue/status")
+	var status struct {
+		Data models.QueueStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v: %s", err, w.Body.String())
+	}
+	if status.Data.MediumPriorityCount != 3 {
+		t.Fatalf("queue status = %+v, want 3 medium tasks", status.Data)
+	}
+}
+
+func TestPurgeQueueEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newQueueRouter(env)
+	var queued []*models.Task
+	for i := 0; i < 2; i++ {
+		task := env.createTask(t, models.TaskStatusPending)
+		if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		queued = append(queued, task)
+	}
+
+	// 缺少确认参数或优先级无效时不清空
+	for _, url := range []string{"/queue/medium", "/queue/medium?confirm=yes", "/queue/processing?confirm=true"} {
+		if w := serve(router, http.MethodDelete, url); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", url, w.Code)
+		}
+	}
+
+	w := serve(router, http.MethodDelete, "/queue/medium?confirm=true&reason=incident")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.QueuePurgeResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	if resp.Data.Removed != 2 || resp.Data.Cancelled != 2 {
+		t.Fatalf("result = %+v, want 2 removed and cancelled", resp.Data)
+	}
+	for _, task := range queued {
+		var got models.Task
+		env.db.First(&got, task.ID)
+		if got.Status != models.TaskStatusCancelled || got.ErrorMessage == nil || *got.ErrorMessage != "incident" {
+			t.Fatalf("task %d = %s %v, want cancelled with reason", task.ID, got.Status, got.ErrorMessage)
+		}
+	}
+}
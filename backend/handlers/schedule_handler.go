@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+	"llm-scheduler/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduleHandler 定时任务处理器
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+	logger          *logrus.Logger
+}
+
+// NewScheduleHandler 创建定时任务处理器
+func NewScheduleHandler(scheduleService *services.ScheduleService, logger *logrus.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+		logger:          logger,
+	}
+}
+
+// CreateSchedule 创建定时任务
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req models.ScheduledTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(&req)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		h.logger.WithError(err).Error("Failed to create schedule")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "定时任务创建成功", schedule)
+}
+
+// GetSchedule 获取定时任务详情
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的定时任务ID")
+		return
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(id)
+	if err != nil {
+		if errors.Is(err, services.ErrScheduleNotFound) {
+			utils.NotFound(c, "定时任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get schedule")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, schedule)
+}
+
+// ListSchedules 获取定时任务列表
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.scheduleService.ListSchedules()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list schedules")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, schedules)
+}
+
+// UpdateSchedule 更新定时任务
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的定时任务ID")
+		return
+	}
+
+	var req models.ScheduledTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	schedule, err := h.scheduleService.UpdateSchedule(id, &req)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrScheduleNotFound) {
+			utils.NotFound(c, "定时任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to update schedule")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "定时任务更新成功", schedule)
+}
+
+// DeleteSchedule 删除定时任务
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的定时任务ID")
+		return
+	}
+
+	if err := h.scheduleService.DeleteSchedule(id); err != nil {
+		if errors.Is(err, services.ErrScheduleNotFound) {
+			utils.NotFound(c, "定时任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to delete schedule")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "定时任务删除成功", nil)
+}
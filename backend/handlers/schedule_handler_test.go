@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newScheduleRouter 注册定时任务接口的测试路由
+func newScheduleRouter(env *testEnv) *gin.Engine {
+	h := NewScheduleHandler(services.NewScheduleService(env.db, env.tasks, env.logger), env.logger)
+	router := gin.New()
+	router.POST("/schedules", h.CreateSchedule)
+	router.GET("/schedules", h.ListSchedules)
+	router.GET("/schedules/:id", h.GetSchedule)
+	router.PUT("/schedules/:id", h.UpdateSchedule)
+	router.DELETE("/schedules/:id", h.DeleteSchedule)
+	return router
+}
+
+// decodeSchedule 解析响应中的定时任务
+func decodeSchedule(t *testing.T, w *httptest.ResponseRecorder) models.ScheduledTask {
+	t.Helper()
+	var resp struct {
+		Data models.ScheduledTask `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	return resp.Data
+}
+
+func TestScheduleCRUD(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newScheduleRouter(env)
+	body := func(cron string) string {
+		return fmt.Sprintf(`{"name": "daily", "cron": %q, "model_id": %d, "type": "text-generation", "input_template": "summary {{.Date}}"}`, cron, env.modelID)
+	}
+
+	// 无效的 cron 表达式返回 400 和字段错误
+	w := postJSON(router, "/schedules", body("61 * * * *"), nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"cron"`) {
+		t.Fatalf("invalid cron: expected 400 with cron field error, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(router, "/schedules", body("@daily"), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	created := decodeSchedule(t, w)
+	if !created.Enabled || created.NextRunAt == nil {
+		t.Fatalf("created schedule = %+v, want enabled with next run", created)
+	}
+	path := fmt.Sprintf("/schedules/%d", created.ID)
+
+	// 停用后不再计算下一次执行时间
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(strings.Replace(body("@hourly"), `"name"`, `"enabled": false, "name"`, 1)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if updated := decodeSchedule(t, w); updated.Enabled || updated.NextRunAt != nil || updated.Cron != "@hourly" {
+		t.Fatalf("updated schedule = %+v, want disabled @hourly", updated)
+	}
+
+	if w := serve(router, http.MethodGet, "/schedules"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"@hourly"`) {
+		t.Fatalf("list: got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, path); w.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodGet, path); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, path); w.Code != http.StatusNotFound {
+		t.Fatalf("delete again: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"strconv"
 
+	"llm-scheduler/models"
 	"llm-scheduler/services"
 	"llm-scheduler/utils"
 
@@ -26,7 +28,7 @@ func NewStatsHandler(statsService *services.StatsService, logger *logrus.Logger)
 
 // GetDashboardStats 获取 Dashboard 统计数据
 func (h *StatsHandler) GetDashboardStats(c *gin.Context) {
-	stats, err := h.statsService.GetDashboardStats()
+	stats, err := h.statsService.GetDashboardStats(c.Request.Context())
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get dashboard stats")
 		utils.InternalServerError(c, err.Error())
@@ -53,9 +55,70 @@ func (h *StatsHandler) GetTaskStatsByDate(c *gin.Context) {
 		return
 	}
 
+	if utils.WantsCSV(c) {
+		utils.RespondCSV(c, "task-stats-by-date.csv", stats,
+			"date", "total", "completed", "failed", "avg_processing_ms")
+		return
+	}
 	utils.Success(c, stats)
 }
 
+// maxQueueHistoryHours 队列深度历史最多查询的小时数
+const maxQueueHistoryHours = 24 * 30
+
+// GetQueueHistory 获取队列深度历史样本，用于趋势图
+func (h *StatsHandler) GetQueueHistory(c *gin.Context) {
+	hours := 6 // 默认6小时
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		v, err := strconv.Atoi(hoursStr)
+		if err != nil || v <= 0 || v > maxQueueHistoryHours {
+			utils.BadRequest(c, fmt.Sprintf("hours must be between 1 and %d", maxQueueHistoryHours))
+			return
+		}
+		hours = v
+	}
+
+	metrics, err := h.statsService.GetQueueHistory(hours)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get queue history")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, metrics)
+}
+
+// GetLatencyPercentiles 获取已完成任务的处理耗时分位数，可按 model_id、type 过滤
+func (h *StatsHandler) GetLatencyPercentiles(c *gin.Context) {
+	req := models.LatencyRequest{Hours: 24} // 默认24小时
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		v, err := strconv.Atoi(hoursStr)
+		if err != nil || v <= 0 || v > maxQueueHistoryHours {
+			utils.BadRequest(c, fmt.Sprintf("hours must be between 1 and %d", maxQueueHistoryHours))
+			return
+		}
+		req.Hours = v
+	}
+	if modelIDStr := c.Query("model_id"); modelIDStr != "" {
+		modelID, err := strconv.ParseUint(modelIDStr, 10, 64)
+		if err != nil {
+			utils.BadRequest(c, "无效的模型ID")
+			return
+		}
+		req.ModelID = &modelID
+	}
+	req.Type = c.Query("type")
+
+	result, err := h.statsService.GetLatencyPercentiles(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get latency percentiles")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
 // GetTaskStatsByModel 按模型获取任务统计
 func (h *StatsHandler) GetTaskStatsByModel(c *gin.Context) {
 	stats, err := h.statsService.GetTaskStatsByModel()
@@ -65,6 +128,12 @@ func (h *StatsHandler) GetTaskStatsByModel(c *gin.Context) {
 		return
 	}
 
+	if utils.WantsCSV(c) {
+		utils.RespondCSV(c, "task-stats-by-model.csv", stats,
+			"model_name", "model_type", "total_tasks", "completed_tasks", "failed_tasks",
+			"pending_tasks", "running_tasks", "success_rate", "avg_processing_ms")
+		return
+	}
 	utils.Success(c, stats)
 }
 
@@ -79,3 +148,25 @@ func (h *StatsHandler) GetTaskStatsByType(c *gin.Context) {
 
 	utils.Success(c, stats)
 }
+
+// GetCostSummary 获取最近若干天的 token 用量和费用汇总
+func (h *StatsHandler) GetCostSummary(c *gin.Context) {
+	days := 30 // 默认30天
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 || d > 365 {
+			utils.BadRequest(c, "days must be between 1 and 365")
+			return
+		}
+		days = d
+	}
+
+	summary, err := h.statsService.GetCostSummary(days)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cost summary")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, summary)
+}
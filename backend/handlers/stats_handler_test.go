@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newStatsRouter 注册统计接口的测试路由
+func newStatsRouter(env *testEnv) *gin.Engine {
+	h := NewStatsHandler(services.NewStatsService(env.db, env.queue, nil, env.logger), env.logger)
+	router := gin.New()
+	router.GET("/stats/cost", h.GetCostSummary)
+	router.GET("/stats/tasks/date", h.GetTaskStatsByDate)
+	router.GET("/stats/tasks/model", h.GetTaskStatsByModel)
+	router.GET("/stats/queue/history", h.GetQueueHistory)
+	router.GET("/stats/latency", h.GetLatencyPercentiles)
+	return router
+}
+
+func TestCostSummaryDays(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newStatsRouter(env)
+	task := env.createTask(t, models.TaskStatusCompleted)
+	env.db.Model(task).Updates(map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 20, "cost_usd": 0.5})
+
+	tests := []struct {
+		query  string
+		status int
+		days   int
+	}{
+		{"", http.StatusOK, 30},
+		{"?days=7", http.StatusOK, 7},
+		{"?days=365", http.StatusOK, 365},
+		{"?days=0", http.StatusBadRequest, 0},
+		{"?days=366", http.StatusBadRequest, 0},
+		{"?days=week", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/stats/cost"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data models.CostSummary `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Data.Days != tt.days || resp.Data.PromptTokens != 10 || resp.Data.CompletionTokens != 20 || len(resp.Data.ByModel) != 1 {
+				t.Fatalf("summary = %+v", resp.Data)
+			}
+		})
+	}
+}
+
+func TestTaskStatsCSV(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newStatsRouter(env)
+	started := time.Now().Add(-time.Minute)
+	completed := started.Add(1500 * time.Millisecond)
+	done := env.createTask(t, models.TaskStatusCompleted)
+	env.db.Model(done).Updates(map[string]interface{}{"started_at": started, "completed_at": completed})
+	env.createTask(t, models.TaskStatusFailed)
+
+	tests := []struct {
+		url     string
+		header  []string
+		columns map[string]string
+	}{
+		{"/stats/tasks/date", []string{"date", "total", "completed", "failed", "avg_processing_ms"},
+			map[string]string{"total": "2", "completed": "1", "failed": "1", "avg_processing_ms": "1500"}},
+		{"/stats/tasks/model", []string{"model_name", "model_type", "total_tasks", "completed_tasks", "failed_tasks",
+			"pending_tasks", "running_tasks", "success_rate", "avg_processing_ms"},
+			map[string]string{"model_name": "test-model", "total_tasks": "2", "completed_tasks": "1", "failed_tasks": "1", "success_rate": "50", "avg_processing_ms": "1500"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			// 默认返回 JSON
+			w := serve(router, http.MethodGet, tt.url)
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				t.Fatalf("json: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+			}
+			var resp struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if len(resp.Data) != 1 {
+				t.Fatalf("json rows = %+v, want one row", resp.Data)
+			}
+			for column, want := range tt.columns {
+				if got := fmt.Sprint(resp.Data[0][column]); got != want {
+					t.Fatalf("json %s = %s, want %s", column, got, want)
+				}
+			}
+
+			// Accept: text/csv 和 ?format=csv 返回同一查询的 CSV，表头按固定顺序
+			acceptReq := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			acceptReq.Header.Set("Accept", "text/csv")
+			accept := httptest.NewRecorder()
+			router.ServeHTTP(accept, acceptReq)
+			for _, w := range []*httptest.ResponseRecorder{accept, serve(router, http.MethodGet, tt.url+"?format=csv")} {
+				if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+					t.Fatalf("csv: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+				}
+				records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+				if err != nil {
+					t.Fatalf("parse csv: %v: %s", err, w.Body.String())
+				}
+				if len(records) != 2 || strings.Join(records[0], ",") != strings.Join(tt.header, ",") {
+					t.Fatalf("csv = %v, want header %v and one row", records, tt.header)
+				}
+				for i, column := range records[0] {
+					if want, ok := tt.columns[column]; ok && records[1][i] != want {
+						t.Fatalf("csv %s = %s, want %s", column, records[1][i], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQueueHistory(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newStatsRouter(env)
+	now := time.Now().Truncate(time.Minute)
+	// 样本写入顺序与采样时间无关
+	for i, ago := range []time.Duration{5 * time.Hour, 10 * time.Hour, time.Hour, 30 * time.Minute} {
+		metric := &models.QueueMetric{SampledAt: now.Add(-ago), TotalCount: int64(i + 1)}
+		if err := env.db.Create(metric).Error; err != nil {
+			t.Fatalf("create metric: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		totals []int64
+	}{
+		// 默认返回最近 6 小时，按采样时间升序
+		{"", http.StatusOK, []int64{1, 3, 4}},
+		{"?hours=2", http.StatusOK, []int64{3, 4}},
+		{"?hours=12", http.StatusOK, []int64{2, 1, 3, 4}},
+		{"?hours=0", http.StatusBadRequest, nil},
+		{"?hours=721", http.StatusBadRequest, nil},
+		{"?hours=day", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/stats/queue/history"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data []models.QueueMetric `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			totals := make([]int64, 0, len(resp.Data))
+			for _, metric := range resp.Data {
+				totals = append(totals, metric.TotalCount)
+			}
+			if fmt.Sprint(totals) != fmt.Sprint(tt.totals) {
+				t.Fatalf("samples = %v, want %v", totals, tt.totals)
+			}
+		})
+	}
+}
+
+func TestLatencyPercentilesEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newStatsRouter(env)
+	// 两个文本生成任务分别耗时 100ms 和 300ms，一个摘要任务耗时 200ms
+	for _, task := range []struct {
+		taskType string
+		duration time.Duration
+	}{
+		{models.TaskTypeTextGeneration, 100 * time.Millisecond},
+		{models.TaskTypeTextGeneration, 300 * time.Millisecond},
+		{models.TaskTypeSummarization, 200 * time.Millisecond},
+	} {
+		created := env.createTask(t, models.TaskStatusCompleted)
+		completed := time.Now().Add(-time.Minute)
+		started := completed.Add(-task.duration)
+		if err := env.db.Model(created).Updates(map[string]interface{}{"type": task.taskType, "started_at": started, "completed_at": completed}).Error; err != nil {
+			t.Fatalf("update task: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		count  int
+		p50    float64
+		max    float64
+	}{
+		{"", http.StatusOK, 3, 200, 300},
+		{"?type=text-generation", http.StatusOK, 2, 100, 300},
+		{fmt.Sprintf("?model_id=%d&type=summarization&hours=1", env.modelID), http.StatusOK, 1, 200, 200},
+		{"?model_id=999", http.StatusOK, 0, 0, 0},
+		{"?model_id=abc", http.StatusBadRequest, 0, 0, 0},
+		{"?hours=0", http.StatusBadRequest, 0, 0, 0},
+		{"?hours=721", http.StatusBadRequest, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/stats/latency"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data models.LatencyPercentiles `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if got := resp.Data; got.Count != tt.count || got.P50Ms != tt.p50 || got.MaxMs != tt.max {
+				t.Fatalf("latency = %+v, want count %d p50 %v max %v", got, tt.count, tt.p50, tt.max)
+			}
+		})
+	}
+}
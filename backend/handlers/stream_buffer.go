@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 输出流刷新策略
+const (
+	FlushPerToken    = "token"    // 每收到一段输出立即刷新
+	FlushPerBytes    = "bytes"    // 累计到指定字节数后刷新
+	FlushPerInterval = "interval" // 按固定间隔刷新
+)
+
+// 未配置时使用的默认值
+const (
+	defaultFlushBytes       = 64
+	defaultFlushInterval    = 100 * time.Millisecond
+	defaultMinFlushInterval = 20 * time.Millisecond
+	defaultMaxFlushInterval = 2 * time.Second
+	defaultMaxFlushBytes    = 16 * 1024
+)
+
+// flushPolicy 输出流刷新策略
+type flushPolicy struct {
+	mode     string
+	bytes    int
+	interval time.Duration
+}
+
+// parseFlushPolicy 从查询参数解析刷新策略，未指定的部分使用配置，超出范围的值会被截断到上下限
+func parseFlushPolicy(c *gin.Context, cfg *config.StreamConfig) (flushPolicy, error) {
+	minInterval := cfg.MinFlushInterval
+	if minInterval <= 0 {
+		minInterval = defaultMinFlushInterval
+	}
+	maxInterval := cfg.MaxFlushInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxFlushInterval
+	}
+	maxBytes := cfg.MaxFlushBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFlushBytes
+	}
+
+	policy := flushPolicy{
+		mode:     cfg.FlushPolicy,
+		bytes:    cfg.FlushBytes,
+		interval: cfg.FlushInterval,
+	}
+	if policy.mode == "" {
+		policy.mode = FlushPerToken
+	}
+	if policy.bytes <= 0 {
+		policy.bytes = defaultFlushBytes
+	}
+	if policy.interval <= 0 {
+		policy.interval = defaultFlushInterval
+	}
+
+	if mode := c.Query("flush"); mode != "" {
+		policy.mode = strings.ToLower(mode)
+	}
+	switch policy.mode {
+	case FlushPerToken, FlushPerBytes, FlushPerInterval:
+	default:
+		return policy, fmt.Errorf("invalid flush policy: %s", policy.mode)
+	}
+
+	if value := c.Query("flush_bytes"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return policy, fmt.Errorf("invalid flush_bytes: %s", value)
+		}
+		policy.bytes = n
+	}
+	if value := c.Query("flush_interval"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return policy, fmt.Errorf("invalid flush_interval: %s", value)
+		}
+		policy.interval = d
+	}
+
+	if policy.bytes > maxBytes {
+		policy.bytes = maxBytes
+	}
+	if policy.interval < minInterval {
+		policy.interval = minInterval
+	}
+	if policy.interval > maxInterval {
+		policy.interval = maxInterval
+	}
+
+	// 按字节刷新时，输出很慢也不能无限期积压，最迟按上限间隔刷新一次
+	if policy.mode == FlushPerBytes {
+		policy.interval = maxInterval
+	}
+
+	return policy, nil
+}
+
+// streamBuffer 合并尚未发送的输出片段
+type streamBuffer struct {
+	policy flushPolicy
+	taskID uint64
+	chunk  strings.Builder
+}
+
+// newStreamBuffer 创建输出流缓冲
+func newStreamBuffer(taskID uint64, policy flushPolicy) *streamBuffer {
+	return &streamBuffer{policy: policy, taskID: taskID}
+}
+
+// ticker 返回定时刷新的计时器，逐段刷新时不需要计时器
+func (b *streamBuffer) ticker() *time.Ticker {
+	if b.policy.mode == FlushPerToken {
+		return nil
+	}
+	return time.NewTicker(b.policy.interval)
+}
+
+// add 追加一段输出，返回是否需要立即刷新
+func (b *streamBuffer) add(chunk string) bool {
+	b.chunk.WriteString(chunk)
+
+	switch b.policy.mode {
+	case FlushPerToken:
+		return true
+	case FlushPerBytes:
+		return b.chunk.Len() >= b.policy.bytes
+	default:
+		return false
+	}
+}
+
+// flush 将缓冲的输出作为一个 chunk 事件写出
+func (b *streamBuffer) flush(c *gin.Context) {
+	if b.chunk.Len() == 0 {
+		return
+	}
+
+	c.SSEvent(string(models.TaskStreamEventChunk), &models.TaskStreamEvent{
+		TaskID: b.taskID,
+		Event:  models.TaskStreamEventChunk,
+		Chunk:  b.chunk.String(),
+	})
+	b.chunk.Reset()
+}
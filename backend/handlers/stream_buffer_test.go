@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryContext 创建带查询参数的 gin 上下文
+func queryContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/stream?"+query, nil)
+	return c, w
+}
+
+func TestParseFlushPolicy(t *testing.T) {
+	cfg := &config.StreamConfig{
+		FlushPolicy:      FlushPerInterval,
+		FlushInterval:    200 * time.Millisecond,
+		MinFlushInterval: 50 * time.Millisecond,
+		MaxFlushInterval: time.Second,
+		MaxFlushBytes:    1024,
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *config.StreamConfig
+		query   string
+		want    flushPolicy
+		wantErr bool
+	}{
+		{"defaults", &config.StreamConfig{}, "", flushPolicy{FlushPerToken, defaultFlushBytes, defaultFlushInterval}, false},
+		{"config", cfg, "", flushPolicy{FlushPerInterval, defaultFlushBytes, 200 * time.Millisecond}, false},
+		{"query overrides", cfg, "flush=Interval&flush_interval=300ms", flushPolicy{FlushPerInterval, defaultFlushBytes, 300 * time.Millisecond}, false},
+		{"interval clamped to min", cfg, "flush_interval=1ms", flushPolicy{FlushPerInterval, defaultFlushBytes, 50 * time.Millisecond}, false},
+		{"interval clamped to max", cfg, "flush_interval=1m", flushPolicy{FlushPerInterval, defaultFlushBytes, time.Second}, false},
+		{"bytes clamped and flushed at max interval", cfg, "flush=bytes&flush_bytes=4096", flushPolicy{FlushPerBytes, 1024, time.Second}, false},
+		{"invalid mode", cfg, "flush=never", flushPolicy{}, true},
+		{"invalid bytes", cfg, "flush=bytes&flush_bytes=0", flushPolicy{}, true},
+		{"invalid interval", cfg, "flush_interval=soon", flushPolicy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := queryContext(tt.query)
+			got, err := parseFlushPolicy(c, tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("policy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamBufferFlushPolicies(t *testing.T) {
+	// 逐段刷新：每段都需要立即刷新，不需要计时器
+	token := newStreamBuffer(1, flushPolicy{mode: FlushPerToken})
+	if !token.add("a") || token.ticker() != nil {
+		t.Fatal("token policy should flush every chunk without a ticker")
+	}
+
+	// 按字节刷新：累计到阈值才刷新
+	bytes := newStreamBuffer(1, flushPolicy{mode: FlushPerBytes, bytes: 5, interval: time.Second})
+	if bytes.add("ab") || bytes.add("c") {
+		t.Fatal("bytes policy flushed before reaching the threshold")
+	}
+	if !bytes.add("de") {
+		t.Fatal("bytes policy should flush at the threshold")
+	}
+
+	// 按间隔刷新：只由计时器触发
+	interval := newStreamBuffer(1, flushPolicy{mode: FlushPerInterval, interval: time.Second})
+	if interval.add(strings.Repeat("x", 1<<16)) {
+		t.Fatal("interval policy should only flush on the ticker")
+	}
+	ticker := interval.ticker()
+	if ticker == nil {
+		t.Fatal("interval policy needs a ticker")
+	}
+	ticker.Stop()
+}
+
+func TestStreamBufferFlushMergesChunks(t *testing.T) {
+	c, w := queryContext("")
+	buffer := newStreamBuffer(7, flushPolicy{mode: FlushPerInterval, interval: time.Second})
+	buffer.add("hel")
+	buffer.add("lo")
+	buffer.flush(c)
+	// 缓冲为空时不写出事件
+	buffer.flush(c)
+
+	body := w.Body.String()
+	if strings.Count(body, "event:chunk") != 1 {
+		t.Fatalf("expected one chunk event, got %q", body)
+	}
+	if !strings.Contains(body, `"chunk":"hello"`) || !strings.Contains(body, `"task_id":7`) {
+		t.Fatalf("expected merged chunk for task 7, got %q", body)
+	}
+}
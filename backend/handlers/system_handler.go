@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"time"
+
 	"llm-scheduler/database"
+	"llm-scheduler/models"
 	"llm-scheduler/queue"
+	"llm-scheduler/services"
 	"llm-scheduler/utils"
+	"llm-scheduler/worker"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -13,19 +18,23 @@ import (
 
 // SystemHandler 系统处理器
 type SystemHandler struct {
-	db           *gorm.DB
-	redisClient  *redis.Client
-	queueManager *queue.Manager
-	logger       *logrus.Logger
+	db            *gorm.DB
+	redisClient   *redis.Client
+	queueManager  *queue.Manager
+	taskService   *services.TaskService
+	workerManager *worker.Manager
+	logger        *logrus.Logger
 }
 
 // NewSystemHandler 创建系统处理器
-func NewSystemHandler(db *gorm.DB, redisClient *redis.Client, queueManager *queue.Manager, logger *logrus.Logger) *SystemHandler {
+func NewSystemHandler(db *gorm.DB, redisClient *redis.Client, queueManager *queue.Manager, taskService *services.TaskService, workerManager *worker.Manager, logger *logrus.Logger) *SystemHandler {
 	return &SystemHandler{
-		db:           db,
-		redisClient:  redisClient,
-		queueManager: queueManager,
-		logger:       logger,
+		db:            db,
+		redisClient:   redisClient,
+		queueManager:  queueManager,
+		taskService:   taskService,
+		workerManager: workerManager,
+		logger:        logger,
 	}
 }
 
@@ -67,7 +76,21 @@ func (h *SystemHandler) HealthCheck(c *gin.Context) {
 		health["queue_status"] = queueStatus
 	}
 
-	if health["status"] == "ok" {
+	// 检查 Worker 存活状态，存在停滞的 Worker 时系统降级但仍可服务
+	healthy, problems := h.workerManager.IsHealthy()
+	health["worker_health"] = h.workerManager.GetWorkerHealth()
+	if healthy {
+		health["workers"] = "ok"
+	} else {
+		h.logger.WithField("problems", problems).Warn("Worker health check failed")
+		health["workers"] = "degraded"
+		health["worker_problems"] = problems
+		if health["status"] == "ok" {
+			health["status"] = "degraded"
+		}
+	}
+
+	if health["status"] != "error" {
 		utils.Success(c, health)
 	} else {
 		utils.InternalServerError(c, "系统健康检查失败")
@@ -100,3 +123,105 @@ func (h *SystemHandler) GetSystemInfo(c *gin.Context) {
 
 	utils.Success(c, info)
 }
+
+// GetDegradedMode 获取降级模式状态
+func (h *SystemHandler) GetDegradedMode(c *gin.Context) {
+	mode, err := h.queueManager.GetDegradedMode(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get degraded mode")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, mode)
+}
+
+// SetDegradedMode 手动进入降级模式
+func (h *SystemHandler) SetDegradedMode(c *gin.Context) {
+	var req struct {
+		MinPriority models.TaskPriority `json:"min_priority" binding:"required"`
+		Reason      string              `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	if !req.MinPriority.IsValid() {
+		utils.BadRequest(c, "无效的优先级")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.queueManager.SetDegradedMode(ctx, req.MinPriority, models.DegradedModeSourceManual, req.Reason); err != nil {
+		h.logger.WithError(err).Error("Failed to set degraded mode")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	mode, err := h.queueManager.GetDegradedMode(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get degraded mode")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已进入降级模式", mode)
+}
+
+// ClearDegradedMode 退出降级模式
+func (h *SystemHandler) ClearDegradedMode(c *gin.Context) {
+	if err := h.queueManager.ClearDegradedMode(c.Request.Context()); err != nil {
+		h.logger.WithError(err).Error("Failed to clear degraded mode")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已退出降级模式", nil)
+}
+
+// GetLogLevel 获取当前日志级别
+func (h *SystemHandler) GetLogLevel(c *gin.Context) {
+	utils.Success(c, gin.H{"level": h.logger.GetLevel().String()})
+}
+
+// SetLogLevel 运行时修改日志级别，重启后恢复为配置文件中的级别
+func (h *SystemHandler) SetLogLevel(c *gin.Context) {
+	var req struct {
+		Level string `json:"level" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	if err := utils.SetLogLevel(h.logger, req.Level); err != nil {
+		utils.BadRequest(c, "无效的日志级别")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "日志级别已更新", gin.H{"level": h.logger.GetLevel().String()})
+}
+
+// RecoverTasks 将崩溃后遗留在 running 状态、且不在处理中队列的任务重置为 pending 并重新入队。
+// older_than 指定 running 的最短时长（如 10m），默认使用 queue.task_timeout
+func (h *SystemHandler) RecoverTasks(c *gin.Context) {
+	var olderThan time.Duration
+	if value := c.Query("older_than"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			utils.BadRequest(c, "无效的 older_than，应为正的时长，如 10m")
+			return
+		}
+		olderThan = parsed
+	}
+
+	result, err := h.taskService.RecoverStuckTasks(c.Request.Context(), olderThan)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to recover stuck tasks")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "遗留任务已恢复", result)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetLogLevelChangesLoggerOutput(t *testing.T) {
+	var output bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&output)
+	logger.SetLevel(logrus.InfoLevel)
+
+	h := NewSystemHandler(nil, nil, nil, nil, nil, logger)
+	router := gin.New()
+	router.GET("/system/log-level", h.GetLogLevel)
+	router.PUT("/system/log-level", h.SetLogLevel)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/system/log-level", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	logger.Debug("before change")
+	if strings.Contains(output.String(), "before change") {
+		t.Fatal("debug log emitted at info level")
+	}
+
+	if w := put(`{"level":"debug"}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	logger.Debug("after change")
+	if !strings.Contains(output.String(), "after change") {
+		t.Fatalf("debug log not emitted after switching to debug: %s", output.String())
+	}
+	if w := serve(router, http.MethodGet, "/system/log-level"); !strings.Contains(w.Body.String(), `"level":"debug"`) {
+		t.Fatalf("GET log-level = %s, want debug", w.Body.String())
+	}
+
+	// 无效级别不改变当前级别
+	for _, body := range []string{`{"level":"verbose"}`, `{}`} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("level = %s, want debug", logger.GetLevel())
+	}
+
+	if w := put(`{"level":"warn"}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	output.Reset()
+	logger.Info("suppressed")
+	if output.Len() != 0 {
+		t.Fatalf("info log emitted at warn level: %s", output.String())
+	}
+}
+
+func TestRecoverTasksEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	h := NewSystemHandler(env.db, nil, env.queue, env.tasks, nil, env.logger)
+	router := gin.New()
+	router.POST("/system/recover", h.RecoverTasks)
+
+	stale := env.createTask(t, models.TaskStatusRunning)
+	fresh := env.createTask(t, models.TaskStatusRunning)
+	for task, started := range map[*models.Task]time.Duration{stale: time.Hour, fresh: time.Minute} {
+		if err := env.db.Model(task).Update("started_at", time.Now().Add(-started)).Error; err != nil {
+			t.Fatalf("update started_at: %v", err)
+		}
+	}
+
+	// 无效的阈值返回 400
+	for _, value := range []string{"soon", "-5m", "0s"} {
+		if w := serve(router, http.MethodPost, "/system/recover?older_than="+value); w.Code != http.StatusBadRequest {
+			t.Fatalf("older_than=%s: status = %d, want 400", value, w.Code)
+		}
+	}
+
+	w := serve(router, http.MethodPost, "/system/recover?older_than=10m")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.RecoverResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Recovered != 1 || len(resp.Data.TaskIDs) != 1 || resp.Data.TaskIDs[0] != stale.ID {
+		t.Fatalf("result = %+v, want task %d recovered", resp.Data, stale.ID)
+	}
+
+	var got models.Task
+	if err := env.db.First(&got, fresh.ID).Error; err != nil {
+		t.Fatalf("reload task: %v", err)
+	}
+	if got.Status != models.TaskStatusRunning {
+		t.Fatalf("fresh task: status %s, want running", got.Status)
+	}
+}
@@ -1,8 +1,20 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"llm-scheduler/config"
 	"llm-scheduler/models"
 	"llm-scheduler/services"
 	"llm-scheduler/utils"
@@ -11,24 +23,40 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// queueFullRetryAfter 队列已满时建议客户端等待的时间
+const queueFullRetryAfter = 30 * time.Second
+
 // TaskHandler 任务处理器
 type TaskHandler struct {
-	taskService *services.TaskService
-	logger      *logrus.Logger
+	taskService    *services.TaskService
+	streamConfig   *config.StreamConfig
+	overrideConfig *config.ProviderOverrideConfig
+	logger         *logrus.Logger
 }
 
 // NewTaskHandler 创建任务处理器
-func NewTaskHandler(taskService *services.TaskService, logger *logrus.Logger) *TaskHandler {
+func NewTaskHandler(taskService *services.TaskService, cfg *config.Config, logger *logrus.Logger) *TaskHandler {
 	return &TaskHandler{
-		taskService: taskService,
-		logger:      logger,
+		taskService:    taskService,
+		streamConfig:   &cfg.Stream,
+		overrideConfig: &cfg.Models.ProviderOverride,
+		logger:         logger,
 	}
 }
 
+// maxIdempotencyKeyLength Idempotency-Key 请求头的最大长度
+const maxIdempotencyKeyLength = 255
+
+// 任务检索内容的长度范围，全文索引按 2 字分词，更短的内容无法匹配
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 200
+)
+
 // CreateTask 创建任务
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var req models.TaskCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := utils.BindStrictJSON(c, &req); err != nil {
 		utils.ValidationError(c, err)
 		return
 	}
@@ -38,16 +66,108 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		req.Priority = models.TaskPriorityMedium
 	}
 
+	if req.ProviderOverride != nil {
+		if msg := h.authorizeProviderOverride(c, req.ProviderOverride); msg != "" {
+			utils.Forbidden(c, msg)
+			return
+		}
+	}
+
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		if len(key) > maxIdempotencyKeyLength {
+			utils.BadRequest(c, fmt.Sprintf("Idempotency-Key 长度不能超过 %d", maxIdempotencyKeyLength))
+			return
+		}
+		req.IdempotencyKey = scopeIdempotencyKey(c, key)
+	}
+
+	req.APIKeyName = c.GetString(utils.APIKeyNameContextKey)
+
 	task, err := h.taskService.CreateTask(c.Request.Context(), &req)
 	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrIdempotentRequestInProgress) {
+			utils.Conflict(c, "相同 Idempotency-Key 的请求正在处理中，请稍后重试")
+			return
+		}
+		if errors.Is(err, services.ErrIdempotencyKeyMismatch) {
+			utils.Conflict(c, "Idempotency-Key 已被内容不同的请求使用")
+			return
+		}
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.BadRequest(c, "模型不存在")
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			c.Header("Retry-After", strconv.Itoa(int(queueFullRetryAfter.Seconds())))
+			utils.ServiceUnavailable(c, "任务队列已满，请稍后重试")
+			return
+		}
 		h.logger.WithError(err).Error("Failed to create task")
 		utils.InternalServerError(c, err.Error())
 		return
 	}
 
+	h.fillEstimatedWait(c, task)
+	if task.Deduplicated {
+		utils.SuccessWithMessage(c, "已存在相同 dedup_key 的进行中任务", task)
+		return
+	}
 	utils.SuccessWithMessage(c, "任务创建成功", task)
 }
 
+// fillEstimatedWait 为排队中的任务附加预计等待时间，估算失败只记录日志，不影响响应
+func (h *TaskHandler) fillEstimatedWait(c *gin.Context, task *models.Task) {
+	if err := h.taskService.FillEstimatedWait(c.Request.Context(), task); err != nil {
+		h.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to estimate task wait time")
+	}
+}
+
+// CreateTasks 批量创建任务
+func (h *TaskHandler) CreateTasks(c *gin.Context) {
+	var req models.TaskBatchCreateRequest
+	if err := utils.BindStrictJSON(c, &req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	if len(req.Tasks) > models.MaxBatchTasks {
+		utils.BadRequest(c, fmt.Sprintf("单次最多创建 %d 个任务", models.MaxBatchTasks))
+		return
+	}
+
+	for _, task := range req.Tasks {
+		if task == nil {
+			utils.BadRequest(c, "任务不能为空")
+			return
+		}
+		// 设置默认优先级
+		if task.Priority == 0 {
+			task.Priority = models.TaskPriorityMedium
+		}
+		task.APIKeyName = c.GetString(utils.APIKeyNameContextKey)
+		if task.ProviderOverride != nil {
+			if msg := h.authorizeProviderOverride(c, task.ProviderOverride); msg != "" {
+				utils.Forbidden(c, msg)
+				return
+			}
+		}
+	}
+
+	results, err := h.taskService.CreateTasks(c.Request.Context(), req.Tasks)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to create tasks")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "批量创建完成", results)
+}
+
 // GetTask 获取任务详情
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	idStr := c.Param("id")
@@ -59,7 +179,7 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 
 	task, err := h.taskService.GetTask(id)
 	if err != nil {
-		if err.Error() == "task not found" {
+		if errors.Is(err, services.ErrTaskNotFound) {
 			utils.NotFound(c, "任务不存在")
 			return
 		}
@@ -68,9 +188,144 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 
+	// 输出保存在外部存储时默认读取内容返回，?output=uri 时只返回引用 URI
+	if c.Query("output") != "uri" {
+		if err := h.taskService.ResolveOutput(c.Request.Context(), task); err != nil {
+			h.logger.WithError(err).WithField("task_id", id).Error("Failed to read task output")
+			utils.Error(c, http.StatusBadGateway, err.Error())
+			return
+		}
+	}
+
+	h.fillEstimatedWait(c, task)
 	utils.Success(c, task)
 }
 
+// GetTaskTimeline 获取任务执行时间线
+func (h *TaskHandler) GetTaskTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	timeline, err := h.taskService.GetTaskTimeline(id)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			utils.NotFound(c, "任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get task timeline")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, timeline)
+}
+
+// exportRecord NDJSON 导出中的一行，Type 为 task、model、timings、log 或 warning
+type exportRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// ExportTask 导出任务、全部日志、模型快照和耗时，?format=ndjson 时每行一条记录并作为附件下载
+func (h *TaskHandler) ExportTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "ndjson" {
+		utils.BadRequest(c, "format 只支持 json 或 ndjson")
+		return
+	}
+
+	export, err := h.taskService.ExportTask(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			utils.NotFound(c, "任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to export task")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	if format == "json" {
+		utils.Success(c, export)
+		return
+	}
+
+	records := []exportRecord{
+		{Type: "task", Data: export.Task},
+		{Type: "model", Data: export.Model},
+		{Type: "timings", Data: export.Timings},
+	}
+	for _, log := range export.Logs {
+		records = append(records, exportRecord{Type: "log", Data: log})
+	}
+	for _, warning := range export.Warnings {
+		records = append(records, exportRecord{Type: "warning", Data: warning})
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="task-%d.ndjson"`, id))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			h.logger.WithError(err).WithField("task_id", id).Warn("Failed to write task export")
+			return
+		}
+	}
+}
+
+// ListTaskLogs 分页获取任务日志
+func (h *TaskHandler) ListTaskLogs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	var req models.TaskLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	if req.PageSize > 200 {
+		req.PageSize = 200 // 限制最大页面大小
+	}
+
+	logs, total, err := h.taskService.ListTaskLogs(id, &req)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrTaskNotFound) {
+			utils.NotFound(c, "任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list task logs")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessPaged(c, logs, total, req.Page, req.PageSize)
+}
+
 // ListTasks 获取任务列表
 func (h *TaskHandler) ListTasks(c *gin.Context) {
 	var req models.TaskListRequest
@@ -90,6 +345,16 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		req.PageSize = 100 // 限制最大页面大小
 	}
 
+	if err := parseTaskFilters(c, &req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if req.Cursor != nil {
+		h.listTasksByCursor(c, &req)
+		return
+	}
+
 	tasks, total, err := h.taskService.ListTasks(&req)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to list tasks")
@@ -100,6 +365,60 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 	utils.SuccessPaged(c, tasks, total, req.Page, req.PageSize)
 }
 
+// listTasksByCursor 游标分页获取任务列表，不返回总数，next_cursor 为空表示没有更多数据
+func (h *TaskHandler) listTasksByCursor(c *gin.Context, req *models.TaskListRequest) {
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > 100 {
+		req.Limit = 100 // 限制最大页面大小
+	}
+
+	tasks, nextCursor, err := h.taskService.ListTasksByCursor(req)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list tasks")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessCursor(c, tasks, nextCursor, req.Limit)
+}
+
+// BulkCancelTasks 按过滤条件批量取消任务，过滤参数与任务列表相同
+func (h *TaskHandler) BulkCancelTasks(c *gin.Context) {
+	h.bulkOperation(c, h.taskService.BulkCancelTasks, "cancel")
+}
+
+// BulkRetryTasks 按过滤条件批量重试任务，过滤参数与任务列表相同
+func (h *TaskHandler) BulkRetryTasks(c *gin.Context) {
+	h.bulkOperation(c, h.taskService.BulkRetryTasks, "retry")
+}
+
+func (h *TaskHandler) bulkOperation(c *gin.Context, op func(context.Context, *models.TaskListRequest) (*models.BulkTaskResult, error), name string) {
+	var req models.TaskListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+	if err := parseTaskFilters(c, &req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if !req.HasFilters() {
+		utils.BadRequest(c, "批量操作至少需要一个过滤条件")
+		return
+	}
+
+	result, err := op(c.Request.Context(), &req)
+	if err != nil {
+		h.logger.WithError(err).WithField("operation", name).Error("Failed to run bulk task operation")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
 // UpdateTask 更新任务
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	idStr := c.Param("id")
@@ -115,12 +434,21 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.UpdateTask(id, &req)
+	task, err := h.taskService.UpdateTask(c.Request.Context(), id, &req)
 	if err != nil {
-		if err.Error() == "task not found" {
+		if errors.Is(err, services.ErrTaskNotFound) {
 			utils.NotFound(c, "任务不存在")
 			return
 		}
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ValidationFailed(c, validationErr.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			utils.Conflict(c, err.Error())
+			return
+		}
 		h.logger.WithError(err).Error("Failed to update task")
 		utils.InternalServerError(c, err.Error())
 		return
@@ -139,10 +467,14 @@ func (h *TaskHandler) CancelTask(c *gin.Context) {
 	}
 
 	if err := h.taskService.CancelTask(c.Request.Context(), id); err != nil {
-		if err.Error() == "task not found" {
+		if errors.Is(err, services.ErrTaskNotFound) {
 			utils.NotFound(c, "任务不存在")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			utils.Conflict(c, err.Error())
+			return
+		}
 		h.logger.WithError(err).Error("Failed to cancel task")
 		utils.BadRequest(c, err.Error())
 		return
@@ -161,10 +493,23 @@ func (h *TaskHandler) RetryTask(c *gin.Context) {
 	}
 
 	if err := h.taskService.RetryTask(c.Request.Context(), id); err != nil {
-		if err.Error() == "task not found" {
+		if errors.Is(err, services.ErrTaskNotFound) {
 			utils.NotFound(c, "任务不存在")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			utils.Conflict(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrDedupKeyActive) {
+			utils.Conflict(c, "已有相同 dedup_key 的进行中任务，不能重试")
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			c.Header("Retry-After", strconv.Itoa(int(queueFullRetryAfter.Seconds())))
+			utils.ServiceUnavailable(c, "任务队列已满，请稍后重试")
+			return
+		}
 		h.logger.WithError(err).Error("Failed to retry task")
 		utils.BadRequest(c, err.Error())
 		return
@@ -173,6 +518,255 @@ func (h *TaskHandler) RetryTask(c *gin.Context) {
 	utils.SuccessWithMessage(c, "任务已重新提交", nil)
 }
 
+// streamStatusInterval 输出流兜底检查任务状态的间隔
+const streamStatusInterval = 2 * time.Second
+
+// disableWriteTimeout 取消 server.write_timeout 对当前连接的限制。SSE 输出流会持续到任务结束，
+// 写超时到期后连接会被直接断开；流的生命周期由请求 context 和服务关闭通知控制
+func disableWriteTimeout(c *gin.Context) {
+	// 不支持设置写超时的 ResponseWriter（如测试中的 ResponseRecorder）本身没有写超时，忽略错误
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
+
+const (
+	// logStreamPollInterval 实时日志流查询新日志的间隔
+	logStreamPollInterval = time.Second
+	// logStreamHeartbeat 实时日志流发送心跳注释的间隔，避免代理因长时间没有数据断开连接
+	logStreamHeartbeat = 15 * time.Second
+	// logStreamBatch 每次查询最多读取的日志条数
+	logStreamBatch = 200
+	// defaultLogStreamTail、maxLogStreamTail 连接后先输出的最近日志条数
+	defaultLogStreamTail = 50
+	maxLogStreamTail     = 200
+)
+
+// StreamTaskLogs 通过 SSE 实时推送任务日志（类似 tail -f）：先输出最近 tail 条，之后每条新日志一个 log 事件，
+// 事件 id 为日志 ID，断线重连时通过 Last-Event-ID 或 after_id 从断点继续。任务进入终态并输出剩余日志后发送 done 事件并结束
+func (h *TaskHandler) StreamTaskLogs(c *gin.Context) {
+	disableWriteTimeout(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	// 断点优先取 Last-Event-ID 请求头，其次 after_id 参数，都没有时从最近 tail 条开始
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("after_id")
+	}
+	var afterID uint64
+	if resume != "" {
+		if afterID, err = strconv.ParseUint(resume, 10, 64); err != nil {
+			utils.BadRequest(c, "无效的 after_id")
+			return
+		}
+	}
+
+	if resume == "" {
+		tail := defaultLogStreamTail
+		if value := c.Query("tail"); value != "" {
+			if tail, err = strconv.Atoi(value); err != nil || tail < 0 {
+				utils.BadRequest(c, "无效的 tail")
+				return
+			}
+			if tail > maxLogStreamTail {
+				tail = maxLogStreamTail
+			}
+		}
+		if afterID, err = h.taskService.TaskLogTailStart(id, tail); err != nil {
+			h.logger.WithError(err).Error("Failed to tail task logs")
+			utils.InternalServerError(c, err.Error())
+			return
+		}
+	}
+
+	logs, status, err := h.taskService.TailTaskLogs(id, afterID, logStreamBatch)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			utils.NotFound(c, "任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to tail task logs")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	shutdown := utils.ShutdownNotify(ctx)
+	poll := time.NewTicker(logStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	// 观察到终态后再查询一次，输出结束时写入的日志
+	finishing := false
+	c.Stream(func(w io.Writer) bool {
+		for _, log := range logs {
+			fmt.Fprintf(w, "id: %d\n", log.ID)
+			c.SSEvent("log", log)
+			afterID = log.ID
+		}
+		if len(logs) == logStreamBatch {
+			// 还有积压的日志，立即继续读取
+			logs, status, err = h.taskService.TailTaskLogs(id, afterID, logStreamBatch)
+			if err != nil {
+				h.logger.WithError(err).WithField("task_id", id).Error("Failed to tail task logs")
+				return false
+			}
+			return true
+		}
+		logs = nil
+
+		if finishing {
+			if task, err := h.taskService.GetTask(id); err == nil {
+				c.SSEvent(string(models.TaskStreamEventDone), taskDoneEvent(task))
+			}
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-shutdown:
+			// 服务关闭，客户端可以通过 Last-Event-ID 重连到其他实例继续读取
+			return false
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			return true
+		case <-poll.C:
+			finishing = status.IsTerminal()
+			logs, status, err = h.taskService.TailTaskLogs(id, afterID, logStreamBatch)
+			if err != nil {
+				h.logger.WithError(err).WithField("task_id", id).Error("Failed to tail task logs")
+				return false
+			}
+			return true
+		}
+	})
+}
+
+// StreamTask 通过 SSE 推送任务的增量输出，直到任务进入终态
+func (h *TaskHandler) StreamTask(c *gin.Context) {
+	disableWriteTimeout(c)
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	policy, err := parseFlushPolicy(c, h.streamConfig)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	shutdown := utils.ShutdownNotify(ctx)
+
+	// 先订阅再查询任务状态，避免错过两者之间发布的事件
+	events, unsubscribe, err := h.taskService.SubscribeTaskStream(ctx, id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to subscribe task stream")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+	defer unsubscribe()
+
+	task, err := h.taskService.GetTask(id)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			utils.NotFound(c, "任务不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get task")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if task.IsCompleted() {
+		c.SSEvent(string(models.TaskStreamEventDone), taskDoneEvent(task))
+		return
+	}
+
+	ticker := time.NewTicker(streamStatusInterval)
+	defer ticker.Stop()
+
+	// 按刷新策略合并输出片段，结束前总是先写出剩余内容
+	buffer := newStreamBuffer(id, policy)
+	var flushC <-chan time.Time
+	if flushTicker := buffer.ticker(); flushTicker != nil {
+		defer flushTicker.Stop()
+		flushC = flushTicker.C
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-shutdown:
+			buffer.flush(c)
+			return false
+		case event, ok := <-events:
+			if !ok {
+				buffer.flush(c)
+				return false
+			}
+			if event.Event == models.TaskStreamEventChunk {
+				if buffer.add(event.Chunk) {
+					buffer.flush(c)
+				}
+				return true
+			}
+			buffer.flush(c)
+			c.SSEvent(string(event.Event), event)
+			return event.Event != models.TaskStreamEventDone
+		case <-flushC:
+			buffer.flush(c)
+			return true
+		case <-ticker.C:
+			// 兜底：任务被取消或完成事件丢失时也能结束输出流
+			task, err := h.taskService.GetTask(id)
+			if err != nil {
+				h.logger.WithError(err).WithField("task_id", id).Error("Failed to check task status for stream")
+				return false
+			}
+			if task.IsCompleted() {
+				buffer.flush(c)
+				c.SSEvent(string(models.TaskStreamEventDone), taskDoneEvent(task))
+				return false
+			}
+			return true
+		}
+	})
+}
+
+// taskDoneEvent 根据任务终态构造结束事件
+func taskDoneEvent(task *models.Task) *models.TaskStreamEvent {
+	event := &models.TaskStreamEvent{
+		TaskID: task.ID,
+		Event:  models.TaskStreamEventDone,
+		Status: task.Status,
+	}
+	if task.ErrorMessage != nil {
+		event.ErrorMessage = *task.ErrorMessage
+	}
+	return event
+}
+
 // GetTaskStats 获取任务统计
 func (h *TaskHandler) GetTaskStats(c *gin.Context) {
 	stats, err := h.taskService.GetTaskStats()
@@ -184,3 +778,131 @@ func (h *TaskHandler) GetTaskStats(c *gin.Context) {
 
 	utils.Success(c, stats)
 }
+
+// CleanupTasks 手动归档创建时间早于 before 的已结束任务，before 支持 2006-01-02 或 RFC3339 格式
+func (h *TaskHandler) CleanupTasks(c *gin.Context) {
+	beforeStr := c.Query("before")
+	if beforeStr == "" {
+		utils.BadRequest(c, "缺少 before 参数")
+		return
+	}
+
+	before, err := parseDateParam(beforeStr)
+	if err != nil {
+		utils.BadRequest(c, "无效的 before 参数，格式应为 2006-01-02 或 RFC3339")
+		return
+	}
+
+	archived, err := h.taskService.ArchiveTasks(before)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to cleanup tasks")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "任务归档成功", &models.ArchiveResult{
+		Before:   before,
+		Archived: archived,
+	})
+}
+
+// parseDateParam 解析日期参数，支持 2006-01-02（本地时间零点）和 RFC3339
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseTaskFilters 解析任务列表的全文检索和创建时间过滤参数，任务列表和批量操作共用
+func parseTaskFilters(c *gin.Context, req *models.TaskListRequest) error {
+	req.Query = strings.TrimSpace(req.Query)
+	if n := utf8.RuneCountInString(req.Query); n > 0 && (n < minSearchQueryLength || n > maxSearchQueryLength) {
+		return fmt.Errorf("q 长度应在 %d 到 %d 个字符之间", minSearchQueryLength, maxSearchQueryLength)
+	}
+	var err error
+	if req.CreatedAfter, err = queryDate(c, "created_after"); err != nil {
+		return err
+	}
+	if req.CreatedBefore, err = queryDate(c, "created_before"); err != nil {
+		return err
+	}
+	return parseTagFilters(c, req)
+}
+
+// tagQueryPrefix 标签过滤参数的前缀，如 ?tag.project=alpha
+const tagQueryPrefix = "tag."
+
+// parseTagFilters 解析 tag.<key>=<value> 形式的标签过滤参数
+func parseTagFilters(c *gin.Context, req *models.TaskListRequest) error {
+	for name, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(name, tagQueryPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, tagQueryPrefix)
+		if !models.IsValidTagKey(key) {
+			return fmt.Errorf("无效的标签过滤参数 %s", name)
+		}
+		if len(values) > 1 {
+			return fmt.Errorf("标签过滤参数 %s 只能指定一次", name)
+		}
+		if req.Tags == nil {
+			req.Tags = make(models.TaskTags)
+		}
+		req.Tags[key] = values[0]
+	}
+	if len(req.Tags) > models.MaxTaskTags {
+		return fmt.Errorf("最多按 %d 个标签过滤", models.MaxTaskTags)
+	}
+	return nil
+}
+
+// queryDate 读取可选的日期查询参数，参数为空时返回 nil
+func queryDate(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseDateParam(value)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 %s 参数，格式应为 2006-01-02 或 RFC3339", name)
+	}
+	return &t, nil
+}
+
+// scopeIdempotencyKey 按 API Key 区分幂等键，不同调用方使用相同的键互不影响
+func scopeIdempotencyKey(c *gin.Context, key string) string {
+	if name := c.GetString(utils.APIKeyNameContextKey); name != "" {
+		return "key:" + name + ":" + key
+	}
+	return "anon:" + key
+}
+
+// authorizeProviderOverride 检查客户端是否可以覆盖模型服务地址，返回非空字符串表示拒绝原因
+func (h *TaskHandler) authorizeProviderOverride(c *gin.Context, override *models.ProviderOverride) string {
+	cfg := h.overrideConfig
+	if !cfg.Enabled || cfg.Token == "" {
+		return "未启用模型服务地址覆盖"
+	}
+
+	token := c.GetHeader("X-Provider-Override-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+		return "无权覆盖模型服务地址"
+	}
+
+	if len(cfg.AllowedHosts) > 0 {
+		parsed, err := url.Parse(override.BaseURL)
+		if err != nil {
+			// 格式错误交给服务层校验并返回字段级错误
+			return ""
+		}
+		for _, host := range cfg.AllowedHosts {
+			if strings.EqualFold(parsed.Hostname(), host) {
+				return ""
+			}
+		}
+		return "不允许覆盖到该地址"
+	}
+
+	return ""
+}
@@ -0,0 +1,1016 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+	"llm-scheduler/storage"
+	"llm-scheduler/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTaskRouter 注册任务接口的测试路由
+func newTaskRouter(env *testEnv) *gin.Engine {
+	h := NewTaskHandler(env.tasks, env.cfg, env.logger)
+	router := gin.New()
+	router.POST("/tasks", h.CreateTask)
+	router.GET("/tasks", h.ListTasks)
+	router.GET("/tasks/:id", h.GetTask)
+	router.GET("/tasks/:id/logs", h.ListTaskLogs)
+	router.GET("/tasks/:id/timeline", h.GetTaskTimeline)
+	router.GET("/tasks/:id/export", h.ExportTask)
+	router.PUT("/tasks/:id", h.UpdateTask)
+	router.DELETE("/tasks/:id", h.CancelTask)
+	router.POST("/tasks/:id/retry", h.RetryTask)
+	router.POST("/tasks/bulk/cancel", h.BulkCancelTasks)
+	router.POST("/tasks/bulk/retry", h.BulkRetryTasks)
+	router.DELETE("/tasks/cleanup", h.CleanupTasks)
+	return router
+}
+
+// serve 发送请求并返回响应
+func serve(router http.Handler, method, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+	return w
+}
+
+// postJSON 发送带 JSON 请求体的 POST 请求，header 为额外的请求头
+func postJSON(router http.Handler, url, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeTask 解析响应中的任务
+func decodeTask(t *testing.T, w *httptest.ResponseRecorder) models.Task {
+	t.Helper()
+	var resp struct {
+		Data models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	return resp.Data
+}
+
+func TestCreateTaskIdempotencyKey(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	body := fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "hello"}`, env.modelID)
+	key := map[string]string{"Idempotency-Key": "req-1"}
+
+	first := postJSON(router, "/tasks", body, key)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first create: expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+	original := decodeTask(t, first)
+
+	// 相同键、相同内容的重复请求返回首次创建的任务，不创建新任务
+	replay := postJSON(router, "/tasks", body, key)
+	if replay.Code != http.StatusOK {
+		t.Fatalf("replay: expected 200, got %d: %s", replay.Code, replay.Body.String())
+	}
+	if got := decodeTask(t, replay); got.ID != original.ID {
+		t.Fatalf("replay returned task %d, want %d", got.ID, original.ID)
+	}
+
+	// 相同键、不同内容的请求返回 409
+	other := fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "different"}`, env.modelID)
+	conflict := postJSON(router, "/tasks", other, key)
+	if conflict.Code != http.StatusConflict {
+		t.Fatalf("different body: expected 409, got %d: %s", conflict.Code, conflict.Body.String())
+	}
+
+	// 不同的键正常创建新任务
+	fresh := postJSON(router, "/tasks", other, map[string]string{"Idempotency-Key": "req-2"})
+	if fresh.Code != http.StatusOK {
+		t.Fatalf("new key: expected 200, got %d: %s", fresh.Code, fresh.Body.String())
+	}
+	if got := decodeTask(t, fresh); got.ID == original.ID {
+		t.Fatalf("new key returned the original task %d", got.ID)
+	}
+
+	var count int64
+	env.db.Model(&models.Task{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 tasks, got %d", count)
+	}
+}
+
+func TestRetryTaskQueueFull(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxQueueSize = 1 })
+	router := newTaskRouter(env)
+
+	queued := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.EnqueueTask(context.Background(), queued); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	task := env.createTask(t, models.TaskStatusFailed)
+	env.db.Model(task).Update("max_retries", 3)
+
+	// 队列已满时与创建任务一样返回 503 并带 Retry-After，任务保持失败状态
+	w := serve(router, http.MethodPost, fmt.Sprintf("/tasks/%d/retry", task.ID))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After header")
+	}
+	var got models.Task
+	env.db.First(&got, task.ID)
+	if got.Status != models.TaskStatusFailed {
+		t.Fatalf("expected task to stay failed, got %s", got.Status)
+	}
+}
+
+func TestCreateTaskQueueFull(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxQueueSize = 1 })
+	router := newTaskRouter(env)
+	body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello"}`, env.modelID)
+
+	if w := postJSON(router, "/tasks", body, nil); w.Code != http.StatusOK {
+		t.Fatalf("first task: status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// 队列已满时返回 503 和 Retry-After，不创建任务
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != strconv.Itoa(int(queueFullRetryAfter.Seconds())) {
+		t.Fatalf("Retry-After = %q, want %v", got, queueFullRetryAfter.Seconds())
+	}
+	var count int64
+	env.db.Model(&models.Task{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("tasks in database = %d, want 1", count)
+	}
+
+	// 任务出队后可以再次创建
+	if _, err := env.queue.DequeueTask(context.Background(), env.modelID, nil); err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if w := postJSON(router, "/tasks", body, nil); w.Code != http.StatusOK {
+		t.Fatalf("after dequeue: status = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateTaskReturnsFieldErrors(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+
+	// 翻译任务缺少目标语言时返回 400 和逐字段的错误
+	body := fmt.Sprintf(`{"model_id": %d, "type": "translation", "input": "hello"}`, env.modelID)
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []models.FieldError `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Field != "params.target_language" {
+		t.Fatalf("field errors = %+v, want params.target_language", resp.Data)
+	}
+}
+
+func TestCreateTaskProviderOverrideAuthorization(t *testing.T) {
+	enabled := func(cfg *config.Config) {
+		cfg.Models.ProviderOverride = config.ProviderOverrideConfig{
+			Enabled:      true,
+			Token:        "override-token",
+			AllowedHosts: []string{"staging.example.com"},
+		}
+	}
+	body := func(env *testEnv, baseURL string) string {
+		return fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "hello", "provider_override": {"base_url": %q, "headers": {"X-Tenant": "a"}}}`, env.modelID, baseURL)
+	}
+	token := map[string]string{"X-Provider-Override-Token": "override-token"}
+
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+		baseURL   string
+		header    map[string]string
+		status    int
+	}{
+		{"disabled", nil, "https://staging.example.com", token, http.StatusForbidden},
+		{"missing token", enabled, "https://staging.example.com", nil, http.StatusForbidden},
+		{"wrong token", enabled, "https://staging.example.com", map[string]string{"X-Provider-Override-Token": "guess"}, http.StatusForbidden},
+		{"host not allowed", enabled, "https://evil.example.com", token, http.StatusForbidden},
+		{"invalid url", enabled, "ftp://staging.example.com", token, http.StatusBadRequest},
+		{"authorized", enabled, "https://STAGING.example.com/v1", token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.configure)
+			w := postJSON(newTaskRouter(env), "/tasks", body(env, tt.baseURL), tt.header)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				var count int64
+				env.db.Model(&models.Task{}).Count(&count)
+				if count != 0 {
+					t.Fatalf("rejected request created %d tasks", count)
+				}
+				return
+			}
+
+			// 覆盖配置保存到任务中，但请求头不通过 API 返回
+			if strings.Contains(w.Body.String(), "X-Tenant") {
+				t.Fatalf("response leaks override headers: %s", w.Body.String())
+			}
+			var task models.Task
+			if err := env.db.First(&task, decodeTask(t, w).ID).Error; err != nil {
+				t.Fatalf("load task: %v", err)
+			}
+			if task.ProviderOverride == nil || task.ProviderOverride.BaseURL != tt.baseURL || task.ProviderOverride.Headers["X-Tenant"] != "a" {
+				t.Fatalf("unexpected stored override: %+v", task.ProviderOverride)
+			}
+		})
+	}
+}
+
+func TestCleanupTasksArchivesFinishedTasks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	completed := env.createTask(t, models.TaskStatusCompleted)
+	pending := env.createTask(t, models.TaskStatusPending)
+
+	for _, query := range []string{"", "?before=yesterday", "?before=2026-13-01"} {
+		if w := serve(router, http.MethodDelete, "/tasks/cleanup"+query); w.Code != http.StatusBadRequest {
+			t.Fatalf("cleanup%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+
+	before := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+	w := serve(router, http.MethodDelete, "/tasks/cleanup?before="+before)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cleanup: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.ArchiveResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Archived != 1 {
+		t.Fatalf("archived = %d, want 1", resp.Data.Archived)
+	}
+
+	// 只归档已结束的任务，pending 任务保留
+	var remaining []models.Task
+	env.db.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].ID != pending.ID {
+		t.Fatalf("remaining tasks = %+v, want only pending task %d", remaining, pending.ID)
+	}
+	var archived int64
+	env.db.Model(&models.ArchivedTask{}).Where("id = ?", completed.ID).Count(&archived)
+	if archived != 1 {
+		t.Fatalf("completed task %d not in archive", completed.ID)
+	}
+}
+
+func TestCreateTaskRejectsOversizedInput(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxInputBytes = 16 })
+	router := newTaskRouter(env)
+
+	body := fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": %q}`, env.modelID, strings.Repeat("x", 17))
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "input must not exceed 16 bytes") {
+		t.Fatalf("expected 400 with input size error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestListTaskLogsEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	task := env.createTask(t, models.TaskStatusCompleted)
+	for i := 0; i < 5; i++ {
+		level := models.LogLevelInfo
+		if i%2 == 0 {
+			level = models.LogLevelError
+		}
+		env.db.Create(&models.TaskLog{TaskID: task.ID, Level: level, Message: fmt.Sprintf("log %d", i)})
+	}
+	logsURL := fmt.Sprintf("/tasks/%d/logs", task.ID)
+
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		wantTotal int64
+		wantPage  int
+		wantSize  int
+		wantLogs  int
+	}{
+		{"defaults", "", http.StatusOK, 5, 1, 50, 5},
+		{"level and page", "?level=error&page=2&page_size=2", http.StatusOK, 3, 2, 2, 1},
+		{"page size capped", "?page_size=1000", http.StatusOK, 5, 1, 200, 5},
+		{"invalid level", "?level=trace", http.StatusBadRequest, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, logsURL+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data  []models.TaskLog `json:"data"`
+				Total int64            `json:"total"`
+				Page  int              `json:"page"`
+				Size  int              `json:"size"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Total != tt.wantTotal || resp.Page != tt.wantPage || resp.Size != tt.wantSize || len(resp.Data) != tt.wantLogs {
+				t.Fatalf("got total=%d page=%d size=%d logs=%d, want %d/%d/%d/%d",
+					resp.Total, resp.Page, resp.Size, len(resp.Data), tt.wantTotal, tt.wantPage, tt.wantSize, tt.wantLogs)
+			}
+		})
+	}
+
+	if w := serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/logs", task.ID+1)); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown task: status = %d, want 404", w.Code)
+	}
+}
+
+func TestListTasksSearchParameters(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	task := env.createTask(t, models.TaskStatusCompleted)
+	env.db.Model(task).Updates(map[string]interface{}{"input": "quarterly revenue", "created_at": time.Date(2024, 3, 2, 12, 0, 0, 0, time.Local)})
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		total  int64
+	}{
+		{"content match", "?q=revenue", http.StatusOK, 1},
+		{"content miss", "?q=invoice", http.StatusOK, 0},
+		{"date range", "?created_after=2024-03-02&created_before=2024-03-03", http.StatusOK, 1},
+		{"rfc3339 bound", "?created_after=" + url.QueryEscape(time.Date(2024, 3, 2, 13, 0, 0, 0, time.Local).Format(time.RFC3339)), http.StatusOK, 0},
+		{"query too short", "?q=a", http.StatusBadRequest, 0},
+		{"invalid date", "?created_before=03/02/2024", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/tasks"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Total int64 `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Total != tt.total {
+				t.Fatalf("total = %d, want %d", resp.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestCreateTaskRejectsInputFailingSchema(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	schema := map[string]interface{}{"type": "object", "required": []interface{}{"text"}}
+	env.db.Model(&models.Model{}).Where("id = ?", env.modelID).Update("config", models.ModelConfig{"input_schema": schema})
+
+	body := fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "{\"lang\":\"en\"}"}`, env.modelID)
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []models.FieldError `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Field != "input.text" || resp.Data[0].Message != "is required" {
+		t.Fatalf("field errors = %+v, want input.text is required", resp.Data)
+	}
+
+	body = fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "{\"text\":\"hi\"}"}`, env.modelID)
+	if w := postJSON(router, "/tasks", body, nil); w.Code != http.StatusOK {
+		t.Fatalf("valid input: status = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateTaskRecordsRequestID(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := gin.New()
+	router.Use(utils.RequestIDMiddleware())
+	h := NewTaskHandler(env.tasks, env.cfg, env.logger)
+	router.POST("/tasks", h.CreateTask)
+	router.GET("/tasks/:id/logs", h.ListTaskLogs)
+
+	body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello"}`, env.modelID)
+	w := postJSON(router, "/tasks", body, map[string]string{utils.RequestIDHeader: "client-req-7"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(utils.RequestIDHeader); got != "client-req-7" {
+		t.Fatalf("response request id = %q, want client-req-7", got)
+	}
+	task := decodeTask(t, w)
+	if task.TraceID != "client-req-7" {
+		t.Fatalf("task trace id = %q, want client-req-7", task.TraceID)
+	}
+
+	// 查询日志的请求生成了新的 ID，返回的任务日志仍带有创建任务时的 ID
+	w = serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/logs", task.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("logs status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []models.TaskLog `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	if len(resp.Data) == 0 {
+		t.Fatalf("expected creation log")
+	}
+	for _, log := range resp.Data {
+		if log.TraceID != "client-req-7" {
+			t.Fatalf("log %q trace id = %q, want client-req-7", log.Message, log.TraceID)
+		}
+	}
+}
+
+func TestBulkTaskOperations(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		status       int
+		wantMatched  int64
+		wantAffected int64
+	}{
+		// 三个任务中只有 pending 的可以取消
+		{"cancel by model", "/tasks/bulk/cancel?model_id=%d", http.StatusOK, 3, 1},
+		// 失败任务的重试次数为 0，不可重试
+		{"retry by status", "/tasks/bulk/retry?status=failed&model_id=%d", http.StatusOK, 1, 0},
+		{"cancel without filters", "/tasks/bulk/cancel", http.StatusBadRequest, 0, 0},
+		{"retry with invalid date", "/tasks/bulk/retry?created_after=yesterday&model_id=%d", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			router := newTaskRouter(env)
+			pending := env.createTask(t, models.TaskStatusPending)
+			completed := env.createTask(t, models.TaskStatusCompleted)
+			env.createTask(t, models.TaskStatusFailed)
+
+			target := tt.url
+			if strings.Contains(target, "%d") {
+				target = fmt.Sprintf(target, env.modelID)
+			}
+			w := postJSON(router, target, "", nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				// 参数错误时不修改任何任务
+				var got models.Task
+				env.db.First(&got, pending.ID)
+				if got.Status != models.TaskStatusPending {
+					t.Fatalf("pending task changed to %s", got.Status)
+				}
+				return
+			}
+			var resp struct {
+				Data models.BulkTaskResult `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Data.Matched != tt.wantMatched || resp.Data.Affected != tt.wantAffected {
+				t.Fatalf("result = %+v, want matched %d affected %d", resp.Data, tt.wantMatched, tt.wantAffected)
+			}
+			var got models.Task
+			env.db.First(&got, completed.ID)
+			if got.Status != models.TaskStatusCompleted {
+				t.Fatalf("completed task changed to %s", got.Status)
+			}
+		})
+	}
+}
+
+func TestGetTaskExternalOutput(t *testing.T) {
+	env := newTestEnv(t, nil)
+	store, err := storage.NewLocalStorage(filepath.Join(t.TempDir(), "outputs"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	env.tasks = services.NewTaskService(env.db, env.queue, store, env.cfg, env.logger)
+	router := newTaskRouter(env)
+
+	task := env.createTask(t, models.TaskStatusCompleted)
+	uri, err := store.Put(context.Background(), fmt.Sprintf("tasks/%d/output.txt", task.ID), []byte("large output"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	env.db.Model(task).Update("output_uri", uri)
+	missing := env.createTask(t, models.TaskStatusCompleted)
+	env.db.Model(missing).Update("output_uri", "local://tasks/missing/output.txt")
+
+	tests := []struct {
+		name       string
+		url        string
+		status     int
+		wantOutput string
+	}{
+		// 默认读取外部存储中的内容返回
+		{"resolved by default", fmt.Sprintf("/tasks/%d", task.ID), http.StatusOK, "large output"},
+		// ?output=uri 只返回引用 URI，不读取存储
+		{"uri only", fmt.Sprintf("/tasks/%d?output=uri", task.ID), http.StatusOK, ""},
+		{"missing object", fmt.Sprintf("/tasks/%d", missing.ID), http.StatusBadGateway, ""},
+		{"missing object uri only", fmt.Sprintf("/tasks/%d?output=uri", missing.ID), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, tt.url)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			got := decodeTask(t, w)
+			if got.OutputURI == nil {
+				t.Fatalf("expected output_uri in response")
+			}
+			if tt.wantOutput == "" {
+				if got.Output != nil {
+					t.Fatalf("output = %q, want only the uri", *got.Output)
+				}
+				return
+			}
+			if got.Output == nil || *got.Output != tt.wantOutput {
+				t.Fatalf("output = %v, want %q", got.Output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetTaskTimelineEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+
+	// 执行中的任务：排队 2 秒后开始执行
+	task := env.createTask(t, models.TaskStatusRunning)
+	startedAt := task.CreatedAt.Add(2 * time.Second)
+	env.db.Model(task).Update("started_at", startedAt)
+
+	w := serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/timeline", task.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.TaskTimeline `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	timeline := resp.Data
+	if timeline.TaskID != task.ID || !timeline.InProgress || timeline.QueueWaitMs != 2000 || timeline.ExecutionMs == nil {
+		t.Fatalf("timeline = %+v, want in-progress task with 2000ms queue wait", timeline)
+	}
+	if len(timeline.Events) != 2 || timeline.Events[1].Stage != "started" || timeline.Events[1].SincePrevMs != 2000 {
+		t.Fatalf("events = %+v, want created and started", timeline.Events)
+	}
+
+	if w := serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/timeline", task.ID+1)); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown task: status = %d, want 404", w.Code)
+	}
+	if w := serve(router, http.MethodGet, "/tasks/abc/timeline"); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid id: status = %d, want 400", w.Code)
+	}
+}
+
+func TestCreateTaskServedFromCache(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.CacheTTL = time.Hour })
+	router := newTaskRouter(env)
+	body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello","cache":true}`, env.modelID)
+
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first task: status = %d: %s", w.Code, w.Body.String())
+	}
+	first := decodeTask(t, w)
+	if first.Status != models.TaskStatusPending || first.CachedFromID != nil {
+		t.Fatalf("first task = %s cached_from %v, want pending", first.Status, first.CachedFromID)
+	}
+
+	// 第一个任务完成后，相同请求直接返回其结果
+	env.db.Model(&models.Task{}).Where("id = ?", first.ID).Updates(map[string]interface{}{
+		"status":       models.TaskStatusCompleted,
+		"output":       "world",
+		"completed_at": time.Now(),
+	})
+	w = postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cached task: status = %d: %s", w.Code, w.Body.String())
+	}
+	cached := decodeTask(t, w)
+	if cached.ID == first.ID || cached.Status != models.TaskStatusCompleted || cached.CachedFromID == nil || *cached.CachedFromID != first.ID {
+		t.Fatalf("cached task = %+v, want new completed task from %d", cached, first.ID)
+	}
+	if cached.Output == nil || *cached.Output != "world" {
+		t.Fatalf("cached output = %v, want world", cached.Output)
+	}
+}
+
+func TestTaskEstimatedWait(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello"}`, env.modelID)
+
+	// 前面没有任务时预计立即执行
+	w := postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if first := decodeTask(t, w); first.EstimatedWaitMs == nil || *first.EstimatedWaitMs != 0 {
+		t.Fatalf("first estimated_wait_ms = %v, want 0", first.EstimatedWaitMs)
+	}
+
+	// 前面有任务但没有历史数据时不返回预计等待时间
+	w = postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "estimated_wait_ms") {
+		t.Fatalf("response without history has estimated_wait_ms: %s", w.Body.String())
+	}
+	second := decodeTask(t, w)
+
+	// 模型最近的任务平均处理 2 秒，1 个 Worker
+	done := env.createTask(t, models.TaskStatusCompleted)
+	started := time.Now().Add(-time.Minute)
+	env.db.Model(done).Updates(map[string]interface{}{"started_at": started, "completed_at": started.Add(2 * time.Second)})
+
+	w = postJSON(router, "/tasks", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if third := decodeTask(t, w); third.EstimatedWaitMs == nil || *third.EstimatedWaitMs != 4000 {
+		t.Fatalf("third estimated_wait_ms = %v, want 4000", third.EstimatedWaitMs)
+	}
+	got := decodeTask(t, serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d", second.ID)))
+	if got.EstimatedWaitMs == nil || *got.EstimatedWaitMs != 2000 {
+		t.Fatalf("second estimated_wait_ms = %v, want 2000", got.EstimatedWaitMs)
+	}
+
+	// 已完成的任务不再估算
+	got = decodeTask(t, serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d", done.ID)))
+	if got.EstimatedWaitMs != nil {
+		t.Fatalf("completed task estimated_wait_ms = %d, want none", *got.EstimatedWaitMs)
+	}
+}
+
+func TestTaskTagsEndpoints(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+
+	// 创建时写入标签，详情中原样返回
+	for _, tags := range []string{`{"project":"alpha","customer":"acme"}`, `{"project":"beta"}`} {
+		body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello","tags":%s}`, env.modelID, tags)
+		w := postJSON(router, "/tasks", body, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("create: status = %d: %s", w.Code, w.Body.String())
+		}
+		created := decodeTask(t, w)
+		got := decodeTask(t, serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d", created.ID)))
+		if len(got.Tags) == 0 || got.Tags["project"] != created.Tags["project"] {
+			t.Fatalf("task tags = %v, want %s", got.Tags, tags)
+		}
+	}
+	if w := postJSON(router, "/tasks", fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello","tags":{"bad key":"x"}}`, env.modelID), nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid tag key: status = %d, want 400", w.Code)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		total  int64
+	}{
+		{"single tag", "?tag.project=alpha", http.StatusOK, 1},
+		{"two tags", "?tag.project=alpha&tag.customer=acme", http.StatusOK, 1},
+		{"tag mismatch", "?tag.project=alpha&tag.customer=other", http.StatusOK, 0},
+		{"no tag filter", "", http.StatusOK, 2},
+		{"invalid key", "?" + url.QueryEscape("tag.bad key") + "=x", http.StatusBadRequest, 0},
+		{"repeated tag", "?tag.project=alpha&tag.project=beta", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/tasks"+tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Total int64 `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			if resp.Total != tt.total {
+				t.Fatalf("total = %d, want %d", resp.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestCreateTaskDedupKey(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello","dedup_key":"order-42"}`, env.modelID)
+
+	first := postJSON(router, "/tasks", body, nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first: status = %d: %s", first.Code, first.Body.String())
+	}
+	created := decodeTask(t, first)
+
+	// 进行中的任务占用去重键，再次提交返回已有任务
+	second := postJSON(router, "/tasks", body, nil)
+	if second.Code != http.StatusOK {
+		t.Fatalf("second: status = %d: %s", second.Code, second.Body.String())
+	}
+	var resp struct {
+		Message string      `json:"message"`
+		Data    models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(second.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ID != created.ID || resp.Message != "已存在相同 dedup_key 的进行中任务" {
+		t.Fatalf("second response = %s, want existing task %d", second.Body.String(), created.ID)
+	}
+
+	// 超过长度上限的去重键被拒绝
+	long := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello","dedup_key":"%s"}`, env.modelID, strings.Repeat("k", 129))
+	if w := postJSON(router, "/tasks", long, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("long dedup_key: status = %d, want 400", w.Code)
+	}
+}
+
+func TestExportTaskEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	created := decodeTask(t, postJSON(router, "/tasks", fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello"}`, env.modelID), nil))
+
+	// 默认返回 JSON 导出包
+	w := serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/export", created.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.TaskExport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if resp.Data.Task == nil || resp.Data.Task.ID != created.ID || resp.Data.Model == nil || resp.Data.Model.ID != env.modelID || len(resp.Data.Logs) == 0 {
+		t.Fatalf("export = %s, want task, model and logs", w.Body.String())
+	}
+
+	// ndjson 每行一条记录，依次为任务、模型、耗时和日志
+	w = serve(router, http.MethodGet, fmt.Sprintf("/tasks/%d/export?format=ndjson", created.ID))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("ndjson export: status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if want := fmt.Sprintf(`attachment; filename="task-%d.ndjson"`, created.ID); w.Header().Get("Content-Disposition") != want {
+		t.Fatalf("Content-Disposition = %q, want %q", w.Header().Get("Content-Disposition"), want)
+	}
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var record struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decode record %q: %v", line, err)
+		}
+		types = append(types, record.Type)
+	}
+	if len(types) != 3+len(resp.Data.Logs) || types[0] != "task" || types[1] != "model" || types[2] != "timings" || types[3] != "log" {
+		t.Fatalf("record types = %v, want task, model, timings and %d logs", types, len(resp.Data.Logs))
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"unknown format", fmt.Sprintf("/tasks/%d/export?format=xml", created.ID), http.StatusBadRequest},
+		{"invalid id", "/tasks/abc/export", http.StatusBadRequest},
+		{"missing task", "/tasks/999/export", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(router, http.MethodGet, tt.url); w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestGetTaskReturnsJSONOutput(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	task := env.createTask(t, models.TaskStatusCompleted)
+	if err := env.db.Model(task).Updates(map[string]interface{}{
+		"type":          models.TaskTypeEmbedding,
+		"output":        "[0.1, 0.2, 0.3]",
+		"output_format": models.OutputFormatJSON,
+	}).Error; err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+
+	// 嵌入任务的输出在详情和列表中都是 JSON 数组，而不是字符串
+	for _, url := range []string{fmt.Sprintf("/tasks/%d", task.ID), "/tasks"} {
+		w := serve(router, http.MethodGet, url)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", url, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		var output struct {
+			Output []float64 `json:"output"`
+		}
+		if url == "/tasks" {
+			var items []json.RawMessage
+			if err := json.Unmarshal(resp.Data, &items); err != nil || len(items) != 1 {
+				t.Fatalf("decode list: %v: %s", err, resp.Data)
+			}
+			resp.Data = items[0]
+		}
+		if err := json.Unmarshal(resp.Data, &output); err != nil {
+			t.Fatalf("GET %s: output is not a JSON array: %v: %s", url, err, resp.Data)
+		}
+		if len(output.Output) != 3 || output.Output[2] != 0.3 {
+			t.Fatalf("GET %s: output = %v, want [0.1 0.2 0.3]", url, output.Output)
+		}
+	}
+}
+
+func TestCreateTaskRunAt(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+
+	// 过去的 run_at 返回 400 和字段错误
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	w := postJSON(router, "/tasks", fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "hello", "run_at": %q}`, env.modelID, past), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("past run_at: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp struct {
+		Data []models.FieldError `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(errResp.Data) != 1 || errResp.Data[0].Field != "run_at" {
+		t.Fatalf("field errors = %+v, want run_at", errResp.Data)
+	}
+
+	// 将来的 run_at 创建待执行任务，返回中带有执行时间
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	w = postJSON(router, "/tasks", fmt.Sprintf(`{"model_id": %d, "type": "text-generation", "input": "hello", "run_at": %q}`, env.modelID, future.Format(time.RFC3339)), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("future run_at: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Status != models.TaskStatusPending || resp.Data.RunAt == nil || !resp.Data.RunAt.Equal(future) {
+		t.Fatalf("task = %s run_at %v, want pending at %v", resp.Data.Status, resp.Data.RunAt, future)
+	}
+}
+
+func TestCreateTaskRejectsUnknownType(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+
+	// 未在允许列表中的类型返回 400 和 type 字段错误
+	w := postJSON(router, "/tasks", fmt.Sprintf(`{"model_id": %d, "type": "text-generaton", "input": "hello"}`, env.modelID), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []models.FieldError `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Field != "type" {
+		t.Fatalf("field errors = %+v, want type", resp.Data)
+	}
+
+	if w := postJSON(router, "/tasks", fmt.Sprintf(`{"model_id": %d, "type": "summarization", "input": "hello"}`, env.modelID), nil); w.Code != http.StatusOK {
+		t.Fatalf("listed type: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestListTasksCursorPagination(t *testing.T) {
+	env := newTestEnv(t, nil)
+	router := newTaskRouter(env)
+	var created []uint64
+	for i := 0; i < 5; i++ {
+		created = append(created, env.createTask(t, models.TaskStatusPending).ID)
+	}
+	type cursorPage struct {
+		Data       []models.Task `json:"data"`
+		NextCursor *uint64       `json:"next_cursor"`
+		Limit      int           `json:"limit"`
+		Total      *int64        `json:"total"`
+	}
+	get := func(url string) cursorPage {
+		t.Helper()
+		w := serve(router, http.MethodGet, url)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", url, w.Code, w.Body.String())
+		}
+		var page cursorPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return page
+	}
+
+	// 按 next_cursor 升序翻页，直到游标为空
+	var ids []uint64
+	url := "/tasks?cursor=0&limit=2&order=asc"
+	for pages := 0; ; pages++ {
+		if pages > len(created) {
+			t.Fatalf("cursor traversal did not terminate")
+		}
+		page := get(url)
+		if page.Limit != 2 || page.Total != nil || len(page.Data) > 2 {
+			t.Fatalf("page = %+v, want at most 2 tasks without total", page)
+		}
+		for _, task := range page.Data {
+			ids = append(ids, task.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		url = fmt.Sprintf("/tasks?cursor=%d&limit=2&order=asc", *page.NextCursor)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(created) {
+		t.Fatalf("cursor traversal = %v, want %v", ids, created)
+	}
+
+	// 每页数量上限与页码分页一致
+	if page := get("/tasks?cursor=0&limit=500"); page.Limit != 100 || len(page.Data) != len(created) {
+		t.Fatalf("limit = %d with %d tasks, want 100 and all tasks", page.Limit, len(page.Data))
+	}
+	// 未传 cursor 时仍为页码分页，返回总数
+	if page := get("/tasks?page=1&page_size=2"); page.Total == nil || *page.Total != int64(len(created)) || page.NextCursor != nil {
+		t.Fatalf("offset page = %+v, want total %d and no cursor", page, len(created))
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newStreamRouter 注册任务输出流和日志流路由
+func newStreamRouter(env *testEnv) *gin.Engine {
+	h := NewTaskHandler(env.tasks, env.cfg, env.logger)
+	router := gin.New()
+	router.GET("/tasks/:id/stream", h.StreamTask)
+	router.GET("/tasks/:id/logs/stream", h.StreamTaskLogs)
+	return router
+}
+
+// streamResult 后台读取的输出流
+type streamResult struct {
+	events []sseEvent
+	err    error
+}
+
+// openStream 在后台请求输出流并读取到连接结束
+func openStream(t *testing.T, url string) <-chan streamResult {
+	t.Helper()
+	result := make(chan streamResult, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			result <- streamResult{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			result <- streamResult{err: fmt.Errorf("status %d", resp.StatusCode)}
+			return
+		}
+		events, err := readEvents(resp.Body)
+		result <- streamResult{events: events, err: err}
+	}()
+	return result
+}
+
+// waitStreamSubscribed 等待输出流订阅任务事件，之后发布的事件不会丢失
+func waitStreamSubscribed(t *testing.T, env *testEnv) {
+	t.Helper()
+	if !waitFor(2*time.Second, func() bool { return len(env.redis.PubSubChannels("")) > 0 }) {
+		t.Fatal("stream did not subscribe to task events")
+	}
+}
+
+func receiveStream(t *testing.T, result <-chan streamResult, timeout time.Duration) streamResult {
+	t.Helper()
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(timeout):
+		t.Fatal("stream did not finish")
+		return streamResult{}
+	}
+}
+
+// 输出流持续时间超过 server.write_timeout 时不会被断开，之后的片段和结束事件都能收到
+func TestStreamTaskOutlivesWriteTimeout(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning)
+	srv := startServer(t, newStreamRouter(env), 100*time.Millisecond)
+
+	result := openStream(t, fmt.Sprintf("%s/tasks/%d/stream", srv.URL, task.ID))
+	waitStreamSubscribed(t, env)
+
+	ctx := context.Background()
+	time.Sleep(300 * time.Millisecond)
+	for _, chunk := range []string{"hello", " world"} {
+		if err := env.queue.PublishTaskEvent(ctx, &models.TaskStreamEvent{TaskID: task.ID, Event: models.TaskStreamEventChunk, Chunk: chunk}); err != nil {
+			t.Fatalf("publish chunk: %v", err)
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+	if err := env.queue.PublishTaskEvent(ctx, &models.TaskStreamEvent{TaskID: task.ID, Event: models.TaskStreamEventDone, Status: models.TaskStatusCompleted}); err != nil {
+		t.Fatalf("publish done: %v", err)
+	}
+
+	r := receiveStream(t, result, 5*time.Second)
+	if r.err != nil {
+		t.Fatalf("read stream: %v", r.err)
+	}
+	var output strings.Builder
+	var done bool
+	for _, event := range r.events {
+		switch event.name {
+		case string(models.TaskStreamEventChunk):
+			output.WriteString(event.data)
+		case string(models.TaskStreamEventDone):
+			done = true
+		}
+	}
+	if !strings.Contains(output.String(), "hello") || !strings.Contains(output.String(), "world") {
+		t.Errorf("chunks = %q, want both chunks after the write timeout", output.String())
+	}
+	if !done {
+		t.Errorf("events = %+v, want a done event", r.events)
+	}
+}
+
+// 服务关闭时输出流立即结束，Shutdown 不必等到超时
+func TestStreamTaskEndsOnShutdown(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning)
+	srv := startServer(t, newStreamRouter(env), time.Minute)
+	// 断言失败时结束输出流，避免关闭测试服务时一直等待
+	t.Cleanup(func() {
+		env.queue.PublishTaskEvent(context.Background(), &models.TaskStreamEvent{TaskID: task.ID, Event: models.TaskStreamEventDone})
+	})
+
+	result := openStream(t, fmt.Sprintf("%s/tasks/%d/stream", srv.URL, task.ID))
+	waitStreamSubscribed(t, env)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %s, want the stream to end promptly", elapsed)
+	}
+
+	if r := receiveStream(t, result, 2*time.Second); r.err != nil {
+		t.Fatalf("read stream: %v", r.err)
+	}
+}
+
+// 实时日志流持续时间超过 server.write_timeout 时不会被断开，之后写入的日志和结束事件都能收到
+func TestStreamTaskLogsOutlivesWriteTimeout(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning)
+	env.tasks.AddTaskLog(task.ID, models.LogLevelInfo, "first", nil)
+	srv := startServer(t, newStreamRouter(env), 100*time.Millisecond)
+
+	result := openStream(t, fmt.Sprintf("%s/tasks/%d/logs/stream", srv.URL, task.ID))
+
+	// 第一次轮询（1 秒后）时写超时早已到期，新日志仍然要能写出
+	time.Sleep(1500 * time.Millisecond)
+	env.tasks.AddTaskLog(task.ID, models.LogLevelInfo, "second", nil)
+	if err := env.db.Model(&models.Task{}).Where("id = ?", task.ID).Update("status", models.TaskStatusCompleted).Error; err != nil {
+		t.Fatalf("complete task: %v", err)
+	}
+
+	r := receiveStream(t, result, 10*time.Second)
+	if r.err != nil {
+		t.Fatalf("read stream: %v", r.err)
+	}
+	var messages []string
+	var done bool
+	for _, event := range r.events {
+		switch event.name {
+		case "log":
+			var log models.TaskLog
+			if err := json.Unmarshal([]byte(event.data), &log); err != nil {
+				t.Fatalf("unmarshal log event: %v", err)
+			}
+			messages = append(messages, log.Message)
+		case string(models.TaskStreamEventDone):
+			done = true
+		}
+	}
+	if strings.Join(messages, ",") != "first,second" {
+		t.Errorf("log messages = %v, want [first second]", messages)
+	}
+	if !done {
+		t.Errorf("events = %+v, want a done event", r.events)
+	}
+}
+
+// 服务关闭时实时日志流立即结束
+func TestStreamTaskLogsEndsOnShutdown(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning)
+	env.tasks.AddTaskLog(task.ID, models.LogLevelInfo, "first", nil)
+	srv := startServer(t, newStreamRouter(env), time.Minute)
+
+	result := openStream(t, fmt.Sprintf("%s/tasks/%d/logs/stream", srv.URL, task.ID))
+	// 断言失败时结束日志流，避免关闭测试服务时一直等待
+	t.Cleanup(func() {
+		env.db.Model(&models.Task{}).Where("id = ?", task.ID).Update("status", models.TaskStatusCompleted)
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %s, want the log stream to end promptly", elapsed)
+	}
+
+	r := receiveStream(t, result, 2*time.Second)
+	if r.err != nil {
+		t.Fatalf("read stream: %v", r.err)
+	}
+	if len(r.events) != 1 || r.events[0].name != "log" {
+		t.Errorf("events = %+v, want only the existing log", r.events)
+	}
+}
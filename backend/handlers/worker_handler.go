@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"llm-scheduler/services"
+	"llm-scheduler/utils"
+	"llm-scheduler/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// WorkerHandler Worker 池处理器
+type WorkerHandler struct {
+	workerManager *worker.Manager
+	modelService  *services.ModelService
+	logger        *logrus.Logger
+}
+
+// NewWorkerHandler 创建 Worker 池处理器
+func NewWorkerHandler(workerManager *worker.Manager, modelService *services.ModelService, logger *logrus.Logger) *WorkerHandler {
+	return &WorkerHandler{
+		workerManager: workerManager,
+		modelService:  modelService,
+		logger:        logger,
+	}
+}
+
+// GetModelWorkers 获取模型 Worker 池状态
+func (h *WorkerHandler) GetModelWorkers(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的模型ID")
+		return
+	}
+
+	model, err := h.modelService.GetModel(id)
+	if err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get model")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	utils.Success(c, h.workerManager.GetPoolStatus(model))
+}
+
+// SetModelWorkers 设置模型的目标 Worker 数量
+func (h *WorkerHandler) SetModelWorkers(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的模型ID")
+		return
+	}
+
+	var req struct {
+		Target *int `json:"target" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	status, err := h.workerManager.SetTargetWorkers(id, *req.Target)
+	if err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to set model workers")
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "Worker 数量已更新", status)
+}
+
+// ScaleModelWorkers 运行时扩缩容模型的 Worker，并更新模型的最大 Worker 数量
+func (h *WorkerHandler) ScaleModelWorkers(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的模型ID")
+		return
+	}
+
+	var req struct {
+		Count *int `json:"count" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err)
+		return
+	}
+
+	status, err := h.workerManager.ScaleModel(id, *req.Count)
+	if err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to scale model workers")
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "Worker 扩缩容成功", status)
+}
+
+// TestModel 使用模型配置发送一次测试请求，返回是否成功、耗时和上游错误，不创建任务
+func (h *WorkerHandler) TestModel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的模型ID")
+		return
+	}
+
+	model, err := h.modelService.GetModel(id)
+	if err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			utils.NotFound(c, "模型不存在")
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get model")
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
+	result := h.workerManager.TestModel(c.Request.Context(), model)
+	h.logger.WithFields(logrus.Fields{
+		"model_id":   id,
+		"model_name": model.Name,
+		"success":    result.Success,
+		"latency_ms": result.LatencyMs,
+		"error":      result.Error,
+	}).Info("Model connectivity tested")
+
+	utils.Success(c, result)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+	"llm-scheduler/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTestModelEndpoint(t *testing.T) {
+	env := newTestEnv(t, nil)
+	modelService := services.NewModelService(env.db, env.logger)
+	h := NewWorkerHandler(worker.NewManager(env.cfg, env.db, env.queue, env.tasks, modelService, nil, env.logger), modelService, env.logger)
+	router := gin.New()
+	router.POST("/models/:id/test", h.TestModel)
+
+	// 上游只接受 sk-valid
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "pong"}}},
+		})
+	}))
+	defer provider.Close()
+
+	createModel := func(apiKey string) uint64 {
+		model := &models.Model{
+			Name:       "openai-" + apiKey,
+			Type:       models.ModelTypeOpenAI,
+			Config:     models.ModelConfig{"base_url": provider.URL, "api_key": apiKey},
+			Status:     models.ModelStatusOffline,
+			MaxWorkers: 1,
+		}
+		if err := env.db.Create(model).Error; err != nil {
+			t.Fatalf("create model: %v", err)
+		}
+		return model.ID
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		status      int
+		wantSuccess bool
+		wantCode    int
+	}{
+		{"success", fmt.Sprintf("/models/%d/test", createModel("sk-valid")), http.StatusOK, true, 0},
+		// 上游认证失败时接口本身成功，结果中给出上游状态码和错误
+		{"auth failure", fmt.Sprintf("/models/%d/test", createModel("sk-wrong")), http.StatusOK, false, http.StatusUnauthorized},
+		{"unknown model", "/models/999/test", http.StatusNotFound, false, 0},
+		{"invalid id", "/models/abc/test", http.StatusBadRequest, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodPost, tt.url)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data models.ModelTestResult `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v: %s", err, w.Body.String())
+			}
+			result := resp.Data
+			if result.Success != tt.wantSuccess || result.StatusCode != tt.wantCode {
+				t.Fatalf("result = %+v, want success %v and status code %d", result, tt.wantSuccess, tt.wantCode)
+			}
+			if tt.wantSuccess && result.Output != "pong" {
+				t.Fatalf("output = %q, want pong", result.Output)
+			}
+			if !tt.wantSuccess && result.Error == "" {
+				t.Fatal("failed test result has no error")
+			}
+		})
+	}
+
+	// 测试不创建任务
+	var count int64
+	env.db.Model(&models.Task{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("task count = %d, want 0", count)
+	}
+}
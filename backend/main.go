@@ -3,23 +3,29 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"llm-scheduler/config"
 	"llm-scheduler/database"
+	"llm-scheduler/grpcserver"
 	"llm-scheduler/queue"
 	"llm-scheduler/routes"
 	"llm-scheduler/services"
+	"llm-scheduler/storage"
+	"llm-scheduler/tracing"
 	"llm-scheduler/utils"
 	"llm-scheduler/worker"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -31,15 +37,39 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to load config: ", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal(err)
+	}
+
+	logFile, err := utils.ConfigureLogOutput(logger, cfg.Logging)
+	if err != nil {
+		logger.Fatal("Failed to configure log output: ", err)
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
 
-	level, err := logrus.ParseLevel(cfg.Logging.Level)
-	if err == nil {
-		logger.SetLevel(level)
+	if err := utils.SetLogLevel(logger, cfg.Logging.Level); err != nil {
+		logger.WithError(err).Warn("Using default log level")
 	}
+	watchLogLevel(logger, cfg.Logging.Level)
 
 	logger.Info("Starting LLM Scheduler Server...")
 	logger.Infof("Version: %s, Environment: %s", cfg.App.Version, cfg.App.Env)
 
+	// tracing.endpoint 为空时不导出 span
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing: ", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.WithError(err).Warn("Failed to flush traces")
+		}
+	}()
+
 	db, err := database.Init(cfg)
 	if err != nil {
 		panic(err)
@@ -50,23 +80,38 @@ func main() {
 		sqlDB.Close()
 	}()
 
-	redisClient, err := queue.InitRedis(cfg)
+	redisClients, err := queue.InitRedis(cfg)
 	if err != nil {
 		logger.Fatal("Failed to initialize Redis: ", err)
 	}
-	defer redisClient.Close()
+	defer redisClients.Close()
+	redisClient := redisClients.Default
 
-	queueManager := queue.NewManager(redisClient, cfg, logger)
+	queueManager := queue.NewManager(redisClients, cfg, logger)
+	if err := queueManager.MigrateReadyQueues(context.Background()); err != nil {
+		logger.Fatal("Failed to migrate queues: ", err)
+	}
+	if err := queueManager.RebalanceReadyQueues(context.Background()); err != nil {
+		logger.Fatal("Failed to rebalance queues: ", err)
+	}
+
+	outputStorage, err := storage.New(&cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize output storage: ", err)
+	}
 
-	taskService := services.NewTaskService(db, queueManager, logger)
+	taskService := services.NewTaskService(db, queueManager, outputStorage, cfg, logger)
 	modelService := services.NewModelService(db, logger)
-	statsService := services.NewStatsService(db, logger)
 
-	workerManager := worker.NewManager(cfg, db, queueManager, taskService, modelService, logger)
+	scheduleService := services.NewScheduleService(db, taskService, logger)
+	workerManager := worker.NewManager(cfg, db, queueManager, taskService, modelService, scheduleService, logger)
+	statsService := services.NewStatsService(db, queueManager, workerManager, logger)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	workerDone := make(chan struct{})
 	go func() {
+		defer close(workerDone)
 		if err := workerManager.Start(ctx); err != nil {
 			logger.Error("Worker manager error: ", err)
 		}
@@ -75,6 +120,8 @@ func main() {
 	if cfg.App.Env == "live" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	// 校验错误中的字段名与请求中的 json 字段一致
+	utils.UseJSONFieldNames()
 	router := gin.New()
 
 	// middleware
@@ -90,20 +137,25 @@ func main() {
 		ExposeHeaders:    cfg.CORS.ExposeHeaders,
 		AllowCredentials: cfg.CORS.AllowCredentials,
 	}
-	if cfg.CORS.MaxAge != "" {
-		if duration, err := time.ParseDuration(cfg.CORS.MaxAge); err == nil {
-			corsConfig.MaxAge = duration
-		}
+	// max_age 为 0 时浏览器每个请求都会重新预检，解析失败时使用默认值
+	maxAge, err := cfg.CORS.GetMaxAge()
+	if err != nil {
+		logger.WithError(err).Warnf("Using default CORS max age %s", config.DefaultCORSMaxAge)
 	}
+	corsConfig.MaxAge = maxAge
 	router.Use(cors.New(corsConfig))
 
-	routes.RegisterRoutes(router, taskService, modelService, statsService, queueManager, logger)
+	routes.RegisterRoutes(router, cfg, db, redisClient, taskService, modelService, scheduleService, statsService, queueManager, workerManager, logger)
+	// Shutdown 不会中断进行中的请求，通过 BaseContext 通知 SSE 输出流在开始关闭时结束，避免长连接拖满关闭时间
+	baseCtx, notifyShutdown := utils.WithShutdown(context.Background())
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 		Handler:      router,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(notifyShutdown)
 
 	go func() {
 		logger.Infof("Server starting on http://%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -112,19 +164,122 @@ func main() {
 		}
 	}()
 
+	// grpc.enabled 为 true 时在单独端口提供任务 gRPC 服务
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcserver.NewServer(cfg, taskService, logger)
+		lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port))
+		if err != nil {
+			logger.Fatal("Failed to listen for gRPC: ", err)
+		}
+		go func() {
+			logger.Infof("gRPC server starting on %s", lis.Addr())
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Fatal("Failed to start gRPC server: ", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// SIGHUP 重新读取配置文件并应用日志级别
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			newCfg, err := config.Reload()
+			if err != nil {
+				logger.WithError(err).Error("Failed to reload config")
+				continue
+			}
+			if err := utils.SetLogLevel(logger, newCfg.Logging.Level); err != nil {
+				logger.WithError(err).Error("Failed to apply log level from config")
+			}
+		}
+	}()
+
 	<-quit
 
-	logger.Info("Shutting down server...")
+	shutdown(srv, grpcServer, workerManager, workerDone, queueManager, cfg.Server.GetShutdownTimeout(), logger)
+	logger.Info("Server exited")
+}
 
-	cancel()
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// shutdown 在 grace 内按顺序停止服务：先停止接收 HTTP 请求和 gRPC 调用（grpcServer 为 nil 表示未启用），
+// 并等待进行中的请求完成（共最多 grace 的一半），保证已受理的创建请求完成入队；再排空 Worker，执行中的任务完成或被放回队列；
+// 最后整理队列（预留 grace 的十分之一，最多 5 秒）。HTTP 阶段未用完的时间留给 Worker
+func shutdown(srv *http.Server, grpcServer *grpc.Server, workerManager *worker.Manager, workerDone <-chan struct{}, queueManager *queue.Manager, grace time.Duration, logger *logrus.Logger) {
+	start := time.Now()
+	deadline := start.Add(grace)
+	queueReserve := grace / 10
+	if queueReserve > 5*time.Second {
+		queueReserve = 5 * time.Second
+	}
+	logger.WithField("grace", grace.String()).Info("Shutting down server...")
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown: ", err)
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), grace/2)
+	defer httpCancel()
+	if err := srv.Shutdown(httpCtx); err != nil {
+		logger.WithError(err).Warn("Timeout waiting for HTTP requests, closing remaining connections")
+		srv.Close()
+	}
+	logger.WithField("elapsed", time.Since(start).Round(time.Millisecond).String()).Info("HTTP server stopped")
+	if grpcServer != nil {
+		stopGRPC(httpCtx, grpcServer, logger)
+		logger.WithField("elapsed", time.Since(start).Round(time.Millisecond).String()).Info("gRPC server stopped")
 	}
 
-	logger.Info("Server exited")
+	workerCtx, workerCancel := context.WithDeadline(context.Background(), deadline.Add(-queueReserve))
+	defer workerCancel()
+	workerManager.Shutdown(workerCtx)
+	select {
+	case <-workerDone:
+		logger.WithField("elapsed", time.Since(start).Round(time.Millisecond).String()).Info("Workers stopped")
+	case <-workerCtx.Done():
+		logger.Warn("Workers did not stop within shutdown grace period")
+	}
+
+	// 到期的延迟任务移回就绪队列，处理中的任务留给下次启动的卡住任务清理
+	drainCtx, drainCancel := context.WithDeadline(context.Background(), deadline)
+	defer drainCancel()
+	if err := queueManager.DrainOnShutdown(drainCtx); err != nil {
+		logger.WithError(err).Error("Failed to drain queues on shutdown")
+	}
+}
+
+// stopGRPC 停止接收 gRPC 调用并等待进行中的调用完成，ctx 到期时强制关闭剩余连接
+func stopGRPC(ctx context.Context, srv *grpc.Server, logger *logrus.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("Timeout waiting for gRPC calls, closing remaining connections")
+		srv.Stop()
+	}
+}
+
+// watchLogLevel 监听配置文件，logging.level 变化时更新日志级别；
+// 文件中其他配置项的修改不会覆盖通过接口设置的级别
+func watchLogLevel(logger *logrus.Logger, initial string) {
+	var mu sync.Mutex
+	current := initial
+
+	config.Watch(func(cfg *config.Config) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cfg.Logging.Level == current {
+			return
+		}
+		current = cfg.Logging.Level
+		if err := utils.SetLogLevel(logger, current); err != nil {
+			logger.WithError(err).Error("Failed to apply log level from config")
+		}
+	}, func(err error) {
+		logger.WithError(err).Error("Failed to reload config")
+	})
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/database/testdb"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/routes"
+	"llm-scheduler/services"
+	"llm-scheduler/worker"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// shutdownTestEnv 监听本地端口的完整 HTTP 服务，创建任务的请求在进入处理前等待 delay
+type shutdownTestEnv struct {
+	db            *gorm.DB
+	redis         *miniredis.Miniredis
+	cfg           *config.Config
+	srv           *http.Server
+	url           string
+	modelID       uint64
+	queueManager  *queue.Manager
+	workerManager *worker.Manager
+	started       chan struct{}
+}
+
+func newShutdownTestEnv(t *testing.T, delay time.Duration) *shutdownTestEnv {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		CancelChannel:       "llm_tasks:cancel",
+		EventChannel:        "llm_tasks:events",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+		TaskTimeout:         5 * time.Minute,
+		RetryDelay:          time.Minute,
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db := testdb.New(t)
+	model := &models.Model{Name: "test-model", Type: models.ModelTypeCustom, Config: models.ModelConfig{}, Status: models.ModelStatusOnline, MaxWorkers: 1}
+	if err := db.Create(model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	taskService := services.NewTaskService(db, queueManager, nil, cfg, logger)
+	modelService := services.NewModelService(db, logger)
+	scheduleService := services.NewScheduleService(db, taskService, logger)
+	workerManager := worker.NewManager(cfg, db, queueManager, taskService, modelService, scheduleService, logger)
+	statsService := services.NewStatsService(db, queueManager, workerManager, logger)
+
+	env := &shutdownTestEnv{
+		db:            db,
+		redis:         server,
+		cfg:           cfg,
+		modelID:       model.ID,
+		queueManager:  queueManager,
+		workerManager: workerManager,
+		started:       make(chan struct{}),
+	}
+
+	router := gin.New()
+	// 模拟处理较慢的创建请求：开始关闭时请求已被受理但尚未写入任务
+	router.Use(func(c *gin.Context) {
+		if c.Request.Method == http.MethodPost {
+			close(env.started)
+			time.Sleep(delay)
+		}
+		c.Next()
+	})
+	routes.RegisterRoutes(router, cfg, db, client, taskService, modelService, scheduleService, statsService, queueManager, workerManager, logger)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	env.srv = &http.Server{Handler: router}
+	env.url = "http://" + listener.Addr().String()
+	go env.srv.Serve(listener)
+	t.Cleanup(func() { env.srv.Close() })
+	return env
+}
+
+// createTask 发送创建任务请求，在后台等待响应
+func (env *shutdownTestEnv) createTask() <-chan *http.Response {
+	done := make(chan *http.Response, 1)
+	go func() {
+		body := fmt.Sprintf(`{"model_id":%d,"type":"text-generation","input":"hello"}`, env.modelID)
+		resp, err := http.Post(env.url+"/api/v1/tasks", "application/json", strings.NewReader(body))
+		if err != nil {
+			done <- nil
+			return
+		}
+		resp.Body.Close()
+		done <- resp
+	}()
+	return done
+}
+
+// shutdown 以 grace 为宽限时间执行 main 的停止流程，Worker 管理器未启动，视为已停止
+func (env *shutdownTestEnv) shutdown(grace time.Duration) time.Duration {
+	workerDone := make(chan struct{})
+	close(workerDone)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	start := time.Now()
+	shutdown(env.srv, nil, env.workerManager, workerDone, env.queueManager, grace, logger)
+	return time.Since(start)
+}
+
+func TestShutdownCompletesInflightCreateRequest(t *testing.T) {
+	env := newShutdownTestEnv(t, 300*time.Millisecond)
+	done := env.createTask()
+	<-env.started
+
+	// 请求进行中开始关闭，停止流程等待请求完成后才继续
+	env.shutdown(5 * time.Second)
+	var resp *http.Response
+	select {
+	case resp = <-done:
+	default:
+		t.Fatal("shutdown returned before the in-flight request finished")
+	}
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("create response = %+v, want 200", resp)
+	}
+
+	// 已受理的任务写入数据库并留在就绪队列中，下次启动后执行
+	var tasks []models.Task
+	if err := env.db.Find(&tasks).Error; err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Status != models.TaskStatusPending {
+		t.Fatalf("tasks = %+v, want one pending task", tasks)
+	}
+	members, err := env.redis.ZMembers(env.cfg.Queue.MediumPriorityQueue)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("ready queue = %v (err %v), want the created task", members, err)
+	}
+
+	// 关闭后不再接收新请求
+	if _, err := http.Get(env.url + "/api/v1/system/health"); err == nil {
+		t.Fatal("server still accepting requests after shutdown")
+	}
+}
+
+func TestShutdownBoundsHTTPDrainByGrace(t *testing.T) {
+	env := newShutdownTestEnv(t, 2*time.Second)
+	done := env.createTask()
+	<-env.started
+
+	// HTTP 阶段最多占用一半宽限时间，超时后关闭剩余连接，整个停止流程不超过宽限时间
+	grace := 400 * time.Millisecond
+	if elapsed := env.shutdown(grace); elapsed > grace+200*time.Millisecond {
+		t.Fatalf("shutdown took %v, want within %v", elapsed, grace)
+	}
+	if resp := <-done; resp != nil {
+		t.Fatalf("slow request got status %d, want connection closed", resp.StatusCode)
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// ArchivedTask 归档任务表结构，保存超过保留期限的已结束任务。
+// 不保存 provider_override（可能包含认证头），任务日志在归档时删除
+type ArchivedTask struct {
+	ID               uint64       `json:"id" gorm:"primaryKey"`
+	ModelID          uint64       `json:"model_id" gorm:"not null;index"`
+	Type             string       `json:"type" gorm:"type:varchar(50);not null"`
+	Input            string       `json:"input" gorm:"type:text;not null"`
+	Params           TaskParams   `json:"params,omitempty" gorm:"type:json"`
+	Tags             TaskTags     `json:"tags,omitempty" gorm:"type:json"`
+	Output           *string      `json:"output" gorm:"type:text"`
+	OutputTruncated  bool         `json:"output_truncated"`
+	OutputURI        *string      `json:"output_uri,omitempty" gorm:"type:varchar(512)"`
+	OutputFormat     OutputFormat `json:"output_format" gorm:"type:varchar(16);default:text"`
+	Status           TaskStatus   `json:"status" gorm:"type:enum('pending','running','completed','failed','cancelled')"`
+	Priority         TaskPriority `json:"priority" gorm:"type:tinyint"`
+	RetryCount       int          `json:"retry_count"`
+	MaxRetries       int          `json:"max_retries"`
+	DependsOn        TaskIDs      `json:"depends_on,omitempty" gorm:"type:json"`
+	NeedsAttention   bool         `json:"needs_attention"`
+	PromptTokens     int          `json:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens"`
+	CostUSD          float64      `json:"cost_usd" gorm:"type:decimal(12,6)"`
+	TraceID          string       `json:"trace_id" gorm:"type:varchar(64)"`
+	ErrorMessage     *string      `json:"error_message" gorm:"type:text"`
+	StartedAt        *time.Time   `json:"started_at"`
+	CompletedAt      *time.Time   `json:"completed_at"`
+	CreatedAt        time.Time    `json:"created_at" gorm:"index"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+	ArchivedAt       time.Time    `json:"archived_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (ArchivedTask) TableName() string {
+	return "archived_tasks"
+}
+
+// ArchiveResult 归档结果
+type ArchiveResult struct {
+	Before   time.Time `json:"before"`
+	Archived int64     `json:"archived"`
+}
@@ -3,7 +3,12 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"gorm.io/gorm"
@@ -53,6 +58,66 @@ func (mc ModelConfig) Value() (driver.Value, error) {
 	return json.Marshal(mc)
 }
 
+// redactedValue 日志中敏感配置的替换值
+const redactedValue = "***"
+
+// sensitiveConfigKeys 需要在日志中屏蔽的配置键（不区分大小写），嵌套对象中的同名键同样屏蔽
+var sensitiveConfigKeys = map[string]bool{
+	"api_key":       true,
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"authorization": true,
+}
+
+// Redacted 返回屏蔽了 api_key、password、token 等敏感值的副本，记录日志时使用，原配置不变
+func (mc ModelConfig) Redacted() ModelConfig {
+	if mc == nil {
+		return nil
+	}
+	return redactMap(mc)
+}
+
+// redactMap 递归复制配置对象并屏蔽敏感键
+func redactMap(values map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if sensitiveConfigKeys[strings.ToLower(key)] {
+			redacted[key] = redactedValue
+			continue
+		}
+		redacted[key] = redactValue(value)
+	}
+	return redacted
+}
+
+// redactValue 处理嵌套的对象和数组，其余值原样返回
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case ModelConfig:
+		return ModelConfig(redactMap(v))
+	case map[string]interface{}:
+		return redactMap(v)
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, item := range v {
+			if sensitiveConfigKeys[strings.ToLower(key)] {
+				item = redactedValue
+			}
+			redacted[key] = item
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
 // Model 模型表结构
 type Model struct {
 	ID              uint64      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -61,11 +126,19 @@ type Model struct {
 	Config          ModelConfig `json:"config" gorm:"type:json;not null"`
 	Status          ModelStatus `json:"status" gorm:"type:enum('online','offline','maintenance');default:offline"`
 	MaxWorkers      int         `json:"max_workers" gorm:"default:1"`
+	FallbackModelID *uint64     `json:"fallback_model_id" gorm:"index"`        // 模型离线或维护时任务改派到的同类型模型
+	AutoMaintenance bool        `json:"auto_maintenance" gorm:"default:false"` // 由健康探测自动切换为 maintenance，探测恢复后自动回到 online
 	CurrentWorkers  int         `json:"current_workers" gorm:"default:0"`
 	TotalRequests   uint64      `json:"total_requests" gorm:"default:0"`
 	SuccessRequests uint64      `json:"success_requests" gorm:"default:0"`
-	CreatedAt       time.Time   `json:"created_at"`
-	Updated         time.Time   `json:"updated_at"`
+	// 累计 token 用量和费用，任务归档或清理后仍然保留
+	TotalPromptTokens     uint64    `json:"total_prompt_tokens" gorm:"default:0"`
+	TotalCompletionTokens uint64    `json:"total_completion_tokens" gorm:"default:0"`
+	TotalCostUSD          float64   `json:"total_cost_usd" gorm:"type:decimal(14,6);default:0"`
+	CreatedAt             time.Time `json:"created_at"`
+	Updated               time.Time `json:"updated_at"`
+	// DeletedAt 软删除时间，删除后模型不再出现在列表中，历史任务仍可关联到模型
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// 关联关系
 	Tasks []Task `json:"tasks,omitempty" gorm:"foreignKey:ModelID"`
@@ -84,6 +157,11 @@ func (m *Model) GetSuccessRate() float64 {
 	return float64(m.SuccessRequests) / float64(m.TotalRequests) * 100
 }
 
+// IsOnline 检查模型是否在线，离线或维护中的模型没有 Worker 消费任务
+func (m *Model) IsOnline() bool {
+	return m.Status == ModelStatusOnline
+}
+
 // IsAvailable 检查模型是否可用
 func (m *Model) IsAvailable() bool {
 	return m.Status == ModelStatusOnline && m.CurrentWorkers < m.MaxWorkers
@@ -95,6 +173,174 @@ func (m *Model) GetConfigValue(key string) (interface{}, bool) {
 	return value, exists
 }
 
+var (
+	// ErrConfigMissing 模型配置中没有该键
+	ErrConfigMissing = errors.New("model config key not set")
+	// ErrConfigType 模型配置值的类型无法转换为需要的类型
+	ErrConfigType = errors.New("model config value has wrong type")
+)
+
+// GetConfigString 获取字符串配置，数字转换为不带多余小数位的字符串（如 8080 而不是 8080.000000）
+func (m *Model) GetConfigString(key string) (string, error) {
+	value, ok := m.Config[key]
+	if !ok || value == nil {
+		return "", fmt.Errorf("%s: %w", key, ErrConfigMissing)
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return "", fmt.Errorf("%s: %w: expected string, got %T", key, ErrConfigType, value)
+}
+
+// GetConfigInt 获取整数配置，支持 JSON 解码得到的 float64（必须是整数值）和数字字符串
+func (m *Model) GetConfigInt(key string) (int, error) {
+	value, ok := m.Config[key]
+	if !ok || value == nil {
+		return 0, fmt.Errorf("%s: %w", key, ErrConfigMissing)
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			return int(v), nil
+		}
+		return 0, fmt.Errorf("%s: %w: %v is not an integer", key, ErrConfigType, v)
+	case json.Number:
+		n, err := strconv.Atoi(v.String())
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w: %q is not an integer", key, ErrConfigType, v)
+		}
+		return n, nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w: %q is not an integer", key, ErrConfigType, v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s: %w: expected integer, got %T", key, ErrConfigType, value)
+}
+
+// GetConfigFloat 获取数值配置，支持整数、浮点数和数字字符串
+func (m *Model) GetConfigFloat(key string) (float64, error) {
+	value, ok := m.Config[key]
+	if !ok || value == nil {
+		return 0, fmt.Errorf("%s: %w", key, ErrConfigMissing)
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w: %q is not a number", key, ErrConfigType, v)
+		}
+		return f, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w: %q is not a number", key, ErrConfigType, v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%s: %w: expected number, got %T", key, ErrConfigType, value)
+}
+
+// GetInputSchema 获取模型配置的任务输入 JSON Schema，未配置时返回 false
+func (m *Model) GetInputSchema() (map[string]interface{}, bool) {
+	schema, ok := m.Config["input_schema"].(map[string]interface{})
+	return schema, ok
+}
+
+// PromptData 渲染模型 prompt_template 时可以使用的字段，如 {{.Input}}、{{.Type}}、{{.Tags.lang}}
+type PromptData struct {
+	Input  string
+	Type   string
+	Params TaskParams
+	Tags   TaskTags
+	TaskID uint64
+	Model  string
+}
+
+// GetPromptTemplate 解析模型配置的输入模板（Go text/template），未配置时返回 nil
+func (m *Model) GetPromptTemplate() (*template.Template, error) {
+	text, err := m.GetConfigString("prompt_template")
+	if errors.Is(err, ErrConfigMissing) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return template.New("prompt_template").Option("missingkey=zero").Parse(text)
+}
+
+// GetQueueBackend 获取模型使用的队列后端名称，未配置时返回空字符串（使用共享后端）
+func (m *Model) GetQueueBackend() string {
+	if backend, ok := m.Config["queue_backend"].(string); ok {
+		return backend
+	}
+	return ""
+}
+
+// AllowsCustomTaskTypes 模型配置了 allow_custom_types 时可以创建 models.allowed_task_types 之外的任务类型
+func (m *Model) AllowsCustomTaskTypes() bool {
+	allowed, _ := m.Config["allow_custom_types"].(bool)
+	return allowed
+}
+
+// GetTaskTimeout 获取模型配置的任务执行超时，支持 "90s" 形式的字符串或秒数
+func (m *Model) GetTaskTimeout() (time.Duration, bool) {
+	switch value := m.Config["task_timeout"].(type) {
+	case string:
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout, true
+		}
+	case float64:
+		if value > 0 {
+			return time.Duration(value * float64(time.Second)), true
+		}
+	}
+	return 0, false
+}
+
+// GetRequestsPerMinute 获取模型每分钟允许的上游请求数，未配置或 <= 0 时不限流
+func (m *Model) GetRequestsPerMinute() int {
+	if rpm, err := m.GetConfigInt("requests_per_minute"); err == nil && rpm > 0 {
+		return rpm
+	}
+	return 0
+}
+
+// GetTokenPrices 获取每 1000 个 token 的价格（美元），分别对应 prompt 和 completion，
+// 未配置 prompt_price_per_1k / completion_price_per_1k 时为 0
+func (m *Model) GetTokenPrices() (prompt, completion float64) {
+	prompt, _ = m.GetConfigFloat("prompt_price_per_1k")
+	completion, _ = m.GetConfigFloat("completion_price_per_1k")
+	return prompt, completion
+}
+
+// EstimateCost 按模型价格计算 token 用量对应的费用（美元）
+func (m *Model) EstimateCost(promptTokens, completionTokens int) float64 {
+	promptPrice, completionPrice := m.GetTokenPrices()
+	return float64(promptTokens)/1000*promptPrice + float64(completionTokens)/1000*completionPrice
+}
+
 // SetConfigValue 设置配置值
 func (m *Model) SetConfigValue(key string, value interface{}) {
 	if m.Config == nil {
@@ -124,4 +370,27 @@ type ModelStats struct {
 	RunningTasks  int64   `json:"running_tasks"`
 	SuccessRate   float64 `json:"success_rate"`
 	AvgResponseMs int64   `json:"avg_response_ms"`
+	// CircuitState 本实例中模型熔断器的状态，熔断器未启用或模型还没有调用记录时为空
+	CircuitState CircuitState `json:"circuit_state,omitempty" gorm:"-"`
+	// Degraded 熔断器处于 open 或 half_open，模型的任务暂不调用上游
+	Degraded bool `json:"degraded" gorm:"-"`
+}
+
+// ModelTestResult 模型连通性测试结果
+type ModelTestResult struct {
+	ModelID    uint64 `json:"model_id"`
+	Success    bool   `json:"success"`
+	LatencyMs  int64  `json:"latency_ms"`
+	StatusCode int    `json:"status_code,omitempty"` // 上游返回非 2xx 时的状态码
+	Output     string `json:"output,omitempty"`      // 模型输出（截断到 200 个字符）
+	Error      string `json:"error,omitempty"`
 }
+
+// CircuitState 模型熔断器状态
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常调用上游
+	CircuitOpen     CircuitState = "open"      // 熔断中，任务放回延迟队列
+	CircuitHalfOpen CircuitState = "half_open" // 冷却结束，放行一次调用试探
+)
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestModelConfigRedacted(t *testing.T) {
+	config := ModelConfig{
+		"api_key":  "sk-live",
+		"Password": "hunter2",
+		"base_url": "https://api.example.com",
+		"port":     float64(8080),
+		"auth": map[string]interface{}{
+			"token":  "tok-1",
+			"scheme": "bearer",
+		},
+		"headers": map[string]string{
+			"Authorization": "Bearer sk-live",
+			"X-Tenant":      "a",
+		},
+		"upstreams": []interface{}{
+			map[string]interface{}{"url": "http://a", "secret": "s-1"},
+			"plain",
+		},
+	}
+	want := ModelConfig{
+		"api_key":  "***",
+		"Password": "***",
+		"base_url": "https://api.example.com",
+		"port":     float64(8080),
+		"auth": map[string]interface{}{
+			"token":  "***",
+			"scheme": "bearer",
+		},
+		"headers": map[string]string{
+			"Authorization": "***",
+			"X-Tenant":      "a",
+		},
+		"upstreams": []interface{}{
+			map[string]interface{}{"url": "http://a", "secret": "***"},
+			"plain",
+		},
+	}
+
+	got := config.Redacted()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Redacted() = %#v, want %#v", got, want)
+	}
+
+	// 原配置不变，Worker 仍使用真实的密钥
+	if config["api_key"] != "sk-live" || config["auth"].(map[string]interface{})["token"] != "tok-1" ||
+		config["headers"].(map[string]string)["Authorization"] != "Bearer sk-live" {
+		t.Fatalf("Redacted modified the original config: %#v", config)
+	}
+
+	if ModelConfig(nil).Redacted() != nil {
+		t.Fatalf("nil config should stay nil")
+	}
+}
+
+func TestProviderOverrideRedacted(t *testing.T) {
+	override := &ProviderOverride{
+		BaseURL: "https://staging.example.com",
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Tenant": "a"},
+	}
+	got := override.Redacted()
+	if got.BaseURL != override.BaseURL || got.Headers["Authorization"] != "***" || got.Headers["X-Tenant"] != "a" {
+		t.Fatalf("Redacted() = %+v", got)
+	}
+	if override.Headers["Authorization"] != "Bearer secret" {
+		t.Fatalf("Redacted modified the original headers")
+	}
+	if (*ProviderOverride)(nil).Redacted() != nil {
+		t.Fatalf("nil override should stay nil")
+	}
+}
+
+func TestModelConfigAccessors(t *testing.T) {
+	// 从 JSON 解码的配置中数字都是 float64
+	var config ModelConfig
+	if err := json.Unmarshal([]byte(`{"port":8080,"ratio":0.5,"host":"localhost","port_str":" 11434 ","big":1e12,"frac":1.5,"flag":true,"empty":null}`), &config); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	config["int"] = 3
+	config["number"] = json.Number("42")
+	model := &Model{Config: config}
+
+	intTests := []struct {
+		key     string
+		want    int
+		wantErr error
+	}{
+		{"port", 8080, nil},
+		{"port_str", 11434, nil},
+		{"int", 3, nil},
+		{"number", 42, nil},
+		{"frac", 0, ErrConfigType},
+		{"big", 0, ErrConfigType},
+		{"host", 0, ErrConfigType},
+		{"flag", 0, ErrConfigType},
+		{"empty", 0, ErrConfigMissing},
+		{"missing", 0, ErrConfigMissing},
+	}
+	for _, tt := range intTests {
+		t.Run("int/"+tt.key, func(t *testing.T) {
+			got, err := model.GetConfigInt(tt.key)
+			if got != tt.want || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("GetConfigInt(%s) = %d, %v, want %d, %v", tt.key, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	stringTests := []struct {
+		key     string
+		want    string
+		wantErr error
+	}{
+		{"host", "localhost", nil},
+		// 数字不带多余的小数位
+		{"port", "8080", nil},
+		{"ratio", "0.5", nil},
+		{"int", "3", nil},
+		{"number", "42", nil},
+		{"flag", "", ErrConfigType},
+		{"missing", "", ErrConfigMissing},
+	}
+	for _, tt := range stringTests {
+		t.Run("string/"+tt.key, func(t *testing.T) {
+			got, err := model.GetConfigString(tt.key)
+			if got != tt.want || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("GetConfigString(%s) = %q, %v, want %q, %v", tt.key, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	floatTests := []struct {
+		key     string
+		want    float64
+		wantErr error
+	}{
+		{"ratio", 0.5, nil},
+		{"port", 8080, nil},
+		{"port_str", 11434, nil},
+		{"int", 3, nil},
+		{"number", 42, nil},
+		{"host", 0, ErrConfigType},
+		{"missing", 0, ErrConfigMissing},
+	}
+	for _, tt := range floatTests {
+		t.Run("float/"+tt.key, func(t *testing.T) {
+			got, err := model.GetConfigFloat(tt.key)
+			if got != tt.want || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("GetConfigFloat(%s) = %v, %v, want %v, %v", tt.key, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
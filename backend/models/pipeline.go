@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxPipelineSteps 流水线任务最多的步骤数
+const MaxPipelineSteps = 10
+
+// PipelineStep 流水线中的一个步骤，按顺序执行，上一步的输出作为下一步的输入
+type PipelineStep struct {
+	Type    string     `json:"type"`
+	ModelID uint64     `json:"model_id,omitempty"` // 为空时使用流水线任务所属的模型
+	Params  TaskParams `json:"params,omitempty"`
+}
+
+// PipelineDefinition 流水线任务的输入：初始输入和步骤列表
+type PipelineDefinition struct {
+	Input string         `json:"input"`
+	Steps []PipelineStep `json:"steps"`
+}
+
+// ParsePipeline 从任务输入中解析流水线定义
+func ParsePipeline(input string) (*PipelineDefinition, error) {
+	var def PipelineDefinition
+	if err := json.Unmarshal([]byte(input), &def); err != nil {
+		return nil, fmt.Errorf("pipeline input must be a JSON object with input and steps: %w", err)
+	}
+	return &def, nil
+}
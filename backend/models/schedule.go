@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduledTask 定时任务表结构，按 cron 表达式周期性创建任务
+type ScheduledTask struct {
+	ID            uint64       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name          string       `json:"name" gorm:"type:varchar(100);not null"`
+	Cron          string       `json:"cron" gorm:"type:varchar(100);not null"`
+	ModelID       uint64       `json:"model_id" gorm:"not null;index"`
+	Type          string       `json:"type" gorm:"type:varchar(50);not null"`
+	InputTemplate string       `json:"input_template" gorm:"type:text;not null"`
+	Params        TaskParams   `json:"params,omitempty" gorm:"type:json"`
+	Priority      TaskPriority `json:"priority" gorm:"type:tinyint;default:2"`
+	Enabled       bool         `json:"enabled" gorm:"default:true;index"`
+	LastRunAt     *time.Time   `json:"last_run_at"`
+	NextRunAt     *time.Time   `json:"next_run_at" gorm:"index"`
+	LastTaskID    *uint64      `json:"last_task_id"`
+	LastError     *string      `json:"last_error" gorm:"type:text"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+
+	// 关联关系
+	Model *Model `json:"model,omitempty" gorm:"foreignKey:ModelID"`
+}
+
+// TableName 指定表名
+func (ScheduledTask) TableName() string {
+	return "scheduled_tasks"
+}
+
+// ScheduledTaskRequest 创建/更新定时任务请求
+type ScheduledTaskRequest struct {
+	Name          string       `json:"name" binding:"required"`
+	Cron          string       `json:"cron" binding:"required"`
+	ModelID       uint64       `json:"model_id" binding:"required"`
+	Type          string       `json:"type" binding:"required"`
+	InputTemplate string       `json:"input_template" binding:"required"`
+	Params        TaskParams   `json:"params"`
+	Priority      TaskPriority `json:"priority"`
+	Enabled       *bool        `json:"enabled"`
+}
+
+// ScheduleTemplateData 渲染定时任务输入模板时可用的数据
+type ScheduleTemplateData struct {
+	Now  time.Time
+	Date string // 2006-01-02
+	Name string
+}
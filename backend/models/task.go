@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,6 +20,55 @@ const (
 	TaskStatusCancelled TaskStatus = "cancelled"
 )
 
+// taskTransitions 任务状态允许的转换。running → pending 用于 Worker 中断或任务恢复后重新入队，
+// failed → pending 用于重试；completed 和 cancelled 是最终状态
+var taskTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusPending: {TaskStatusRunning, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusRunning: {TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled, TaskStatusPending},
+	TaskStatusFailed:  {TaskStatusPending},
+}
+
+// IsValid 是否为已定义的任务状态
+func (s TaskStatus) IsValid() bool {
+	switch s {
+	case TaskStatusPending, TaskStatusRunning, TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsTerminal 是否为终态（completed、failed、cancelled）
+func (s TaskStatus) IsTerminal() bool {
+	return s == TaskStatusCompleted || s == TaskStatusFailed || s == TaskStatusCancelled
+}
+
+// 内置任务类型
+const (
+	TaskTypeTextGeneration = "text-generation"
+	TaskTypeTranslation    = "translation"
+	TaskTypeSummarization  = "summarization"
+	TaskTypeEmbedding      = "embedding"
+	TaskTypePipeline       = "pipeline" // 多步骤流水线，输入为 PipelineDefinition
+)
+
+// BuiltinTaskTypes 内置任务类型，未配置 models.allowed_task_types 时只允许这些类型
+var BuiltinTaskTypes = []string{
+	TaskTypeTextGeneration,
+	TaskTypeTranslation,
+	TaskTypeSummarization,
+	TaskTypeEmbedding,
+	TaskTypePipeline,
+}
+
+// OutputFormat 任务输出的编码格式
+type OutputFormat string
+
+const (
+	OutputFormatText   OutputFormat = "text"
+	OutputFormatJSON   OutputFormat = "json"   // 输出为 JSON 文本，API 响应中按 JSON 值返回
+	OutputFormatBase64 OutputFormat = "base64" // 二进制输出的 base64 编码
+)
+
 // TaskPriority 任务优先级枚举
 type TaskPriority int
 
@@ -26,22 +78,213 @@ const (
 	TaskPriorityHigh   TaskPriority = 3
 )
 
+// IsValid 检查优先级是否有效
+func (p TaskPriority) IsValid() bool {
+	return p >= TaskPriorityLow && p <= TaskPriorityHigh
+}
+
+// ParseTaskPriority 解析优先级，支持 high/medium/low 或 3/2/1
+func ParseTaskPriority(s string) (TaskPriority, bool) {
+	switch s {
+	case "high", "3":
+		return TaskPriorityHigh, true
+	case "medium", "2":
+		return TaskPriorityMedium, true
+	case "low", "1":
+		return TaskPriorityLow, true
+	default:
+		return 0, false
+	}
+}
+
+// TaskParams 任务附加参数（如翻译的目标语言），存储为 JSON
+type TaskParams map[string]interface{}
+
+// Scan 实现 sql.Scanner 接口
+func (tp *TaskParams) Scan(value interface{}) error {
+	if value == nil {
+		*tp = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal TaskParams: %v", value)
+	}
+
+	return json.Unmarshal(bytes, tp)
+}
+
+// Value 实现 driver.Valuer 接口
+func (tp TaskParams) Value() (driver.Value, error) {
+	if tp == nil {
+		return nil, nil
+	}
+	return json.Marshal(tp)
+}
+
+// GetString 获取字符串类型的参数
+func (tp TaskParams) GetString(key string) (string, bool) {
+	value, ok := tp[key].(string)
+	return value, ok
+}
+
+// TaskIDs 任务 ID 列表，存储为 JSON
+type TaskIDs []uint64
+
+// Scan 实现 sql.Scanner 接口
+func (ids *TaskIDs) Scan(value interface{}) error {
+	if value == nil {
+		*ids = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal TaskIDs: %v", value)
+	}
+
+	return json.Unmarshal(bytes, ids)
+}
+
+// Value 实现 driver.Valuer 接口
+func (ids TaskIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		return nil, nil
+	}
+	return json.Marshal(ids)
+}
+
+// MaxDedupKeyLength 去重键的最大长度
+const MaxDedupKeyLength = 128
+
+// 任务标签的数量和长度限制
+const (
+	MaxTaskTags       = 20
+	MaxTagKeyLength   = 64
+	MaxTagValueLength = 256
+)
+
+// TaskTags 任务标签（如项目、客户），存储为 JSON
+type TaskTags map[string]string
+
+// Scan 实现 sql.Scanner 接口
+func (tt *TaskTags) Scan(value interface{}) error {
+	if value == nil {
+		*tt = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal TaskTags: %v", value)
+	}
+
+	return json.Unmarshal(bytes, tt)
+}
+
+// Value 实现 driver.Valuer 接口
+func (tt TaskTags) Value() (driver.Value, error) {
+	if tt == nil {
+		return nil, nil
+	}
+	return json.Marshal(tt)
+}
+
+// IsValidTagKey 标签键只能包含字母、数字、下划线、连字符和点，长度不超过 MaxTagKeyLength
+func IsValidTagKey(key string) bool {
+	if key == "" || len(key) > MaxTagKeyLength {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ProviderOverride 任务级别的模型服务地址覆盖，仅授权客户端可以设置
+type ProviderOverride struct {
+	BaseURL string            `json:"base_url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Scan 实现 sql.Scanner 接口
+func (po *ProviderOverride) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal ProviderOverride: %v", value)
+	}
+
+	return json.Unmarshal(bytes, po)
+}
+
+// Value 实现 driver.Valuer 接口
+func (po *ProviderOverride) Value() (driver.Value, error) {
+	if po == nil {
+		return nil, nil
+	}
+	return json.Marshal(po)
+}
+
+// Redacted 返回屏蔽了 Authorization 等敏感请求头的副本
+func (po *ProviderOverride) Redacted() *ProviderOverride {
+	if po == nil {
+		return nil
+	}
+	redacted := &ProviderOverride{BaseURL: po.BaseURL}
+	if po.Headers != nil {
+		redacted.Headers = redactValue(po.Headers).(map[string]string)
+	}
+	return redacted
+}
+
 // Task 任务表结构
 type Task struct {
-	ID           uint64       `json:"id" gorm:"primaryKey;autoIncrement"`
-	ModelID      uint64       `json:"model_id" gorm:"not null;index:idx_model_status"`
-	Type         string       `json:"type" gorm:"type:varchar(50);not null;index"`
-	Input        string       `json:"input" gorm:"type:text;not null"`
-	Output       *string      `json:"output" gorm:"type:text"`
-	Status       TaskStatus   `json:"status" gorm:"type:enum('pending','running','completed','failed','cancelled');default:pending;index:idx_status_priority"`
-	Priority     TaskPriority `json:"priority" gorm:"type:tinyint;default:1;index:idx_status_priority"`
-	RetryCount   int          `json:"retry_count" gorm:"default:0"`
-	MaxRetries   int          `json:"max_retries" gorm:"default:3"`
-	ErrorMessage *string      `json:"error_message" gorm:"type:text"`
-	StartedAt    *time.Time   `json:"started_at"`
-	CompletedAt  *time.Time   `json:"completed_at"`
-	CreatedAt    time.Time    `json:"created_at" gorm:"index:idx_created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
+	ID      uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	ModelID uint64     `json:"model_id" gorm:"not null;index:idx_model_status"`
+	Type    string     `json:"type" gorm:"type:varchar(50);not null;index"`
+	Input   string     `json:"input" gorm:"type:text;not null"`
+	Params  TaskParams `json:"params,omitempty" gorm:"type:json"`
+	Tags    TaskTags   `json:"tags,omitempty" gorm:"type:json"`
+	// ProviderOverride 可能包含认证头，不通过 API 返回
+	ProviderOverride *ProviderOverride `json:"-" gorm:"type:json"`
+	Output           *string           `json:"output" gorm:"type:text"`
+	OutputTruncated  bool              `json:"output_truncated" gorm:"default:false"`         // 输出超过 max_output_bytes 被截断
+	OutputURI        *string           `json:"output_uri,omitempty" gorm:"type:varchar(512)"` // 输出保存在外部存储时的引用 URI，此时 output 为空
+	OutputFormat     OutputFormat      `json:"output_format" gorm:"type:varchar(16);default:text"`
+	Status           TaskStatus        `json:"status" gorm:"type:enum('pending','running','completed','failed','cancelled');default:pending;index:idx_status_priority"`
+	Priority         TaskPriority      `json:"priority" gorm:"type:tinyint;default:1;index:idx_status_priority"`
+	RetryCount       int               `json:"retry_count" gorm:"default:0"`
+	MaxRetries       int               `json:"max_retries"` // 创建时确定，不设置 gorm 默认值以便 0 也能写入
+	DependsOn        TaskIDs           `json:"depends_on,omitempty" gorm:"type:json"`
+	WaitingDeps      bool              `json:"waiting_dependencies" gorm:"column:waiting_dependencies;default:false;index"`
+	NeedsAttention   bool              `json:"needs_attention" gorm:"default:false;index"` // 反复超时后被升级，需人工处理
+	PromptTokens     int               `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int               `json:"completion_tokens" gorm:"default:0"`
+	CostUSD          float64           `json:"cost_usd" gorm:"type:decimal(12,6);default:0"`
+	TraceID          string            `json:"trace_id" gorm:"type:varchar(64);index"`             // 创建任务的请求关联 ID（X-Request-ID）
+	APIKeyName       string            `json:"api_key_name,omitempty" gorm:"type:varchar(128)"`    // 创建任务的 API Key 名称，用于限制每个 Key 同时执行的任务数
+	CacheKey         string            `json:"-" gorm:"type:char(64);index"`                       // 模型、类型、输入和参数的哈希，用于结果缓存
+	DedupKey         string            `json:"dedup_key,omitempty" gorm:"type:varchar(128);index"` // 去重键，同一时间最多一个 pending/running 任务
+	CachedFromID     *uint64           `json:"cached_from_id,omitempty"`                           // 结果来自缓存时为复用的任务 ID
+	EstimatedWaitMs  *int64            `json:"estimated_wait_ms,omitempty" gorm:"-"`               // 排队中任务的预计等待时间（毫秒），尽力估算，不落库
+	Deduplicated     bool              `json:"deduplicated,omitempty" gorm:"-"`                    // 创建请求因去重键相同返回了已有任务
+	TraceParent      string            `json:"-" gorm:"-"`                                         // 出队时队列项携带的 W3C 追踪上下文，不落库
+	ErrorMessage     *string           `json:"error_message" gorm:"type:text"`
+	Deadline         *time.Time        `json:"deadline,omitempty" gorm:"index"` // 截止时间，之后出队的任务不再执行，直接取消
+	RunAt            *time.Time        `json:"run_at,omitempty"`                // 计划执行时间，之前任务停留在延迟队列中
+	StartedAt        *time.Time        `json:"started_at"`
+	CompletedAt      *time.Time        `json:"completed_at"`
+	CreatedAt        time.Time         `json:"created_at" gorm:"index:idx_created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
 
 	// 关联关系
 	Model *Model    `json:"model,omitempty" gorm:"foreignKey:ModelID"`
@@ -53,6 +296,30 @@ func (Task) TableName() string {
 	return "tasks"
 }
 
+// MarshalJSON 按 OutputValue 输出 output 字段，JSON 格式的输出不再是需要客户端二次解析的字符串
+func (t Task) MarshalJSON() ([]byte, error) {
+	type taskJSON Task
+	return json.Marshal(struct {
+		taskJSON
+		Output interface{} `json:"output"`
+	}{
+		taskJSON: taskJSON(t),
+		Output:   t.OutputValue(),
+	})
+}
+
+// OutputValue 返回响应中 output 字段的值：JSON 格式且未被截断的输出原样作为 JSON 返回，
+// 其他情况（包括内容不是合法 JSON）返回字符串
+func (t *Task) OutputValue() interface{} {
+	if t.Output == nil {
+		return nil
+	}
+	if t.OutputFormat == OutputFormatJSON && !t.OutputTruncated && json.Valid([]byte(*t.Output)) {
+		return json.RawMessage(*t.Output)
+	}
+	return *t.Output
+}
+
 // GetProcessingTimeMS 获取处理时间（毫秒）
 func (t *Task) GetProcessingTimeMS() int64 {
 	if t.StartedAt == nil || t.CompletedAt == nil {
@@ -68,11 +335,46 @@ func (t *Task) CanRetry() bool {
 
 // IsCompleted 检查任务是否已完成
 func (t *Task) IsCompleted() bool {
-	return t.Status == TaskStatusCompleted || 
-		   t.Status == TaskStatusFailed || 
-		   t.Status == TaskStatusCancelled
+	return t.Status.IsTerminal()
+}
+
+// CanTransitionTo 检查任务能否从当前状态转换到 status
+func (t *Task) CanTransitionTo(status TaskStatus) bool {
+	for _, next := range taskTransitions[t.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
 }
 
+// TransitionSources 返回可以转换到 status 的状态，用于按状态条件更新数据库
+func TransitionSources(status TaskStatus) []TaskStatus {
+	var sources []TaskStatus
+	for from, targets := range taskTransitions {
+		for _, next := range targets {
+			if next == status {
+				sources = append(sources, from)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// DeadlineExceeded 任务设置了截止时间且 now 已超过
+func (t *Task) DeadlineExceeded(now time.Time) bool {
+	return t.Deadline != nil && now.After(*t.Deadline)
+}
+
+// ScheduledAfter 任务设置了计划执行时间且晚于 now
+func (t *Task) ScheduledAfter(now time.Time) bool {
+	return t.RunAt != nil && t.RunAt.After(now)
+}
+
+// DeadlineExceededMessage 任务因超过截止时间被取消时的原因
+const DeadlineExceededMessage = "deadline exceeded"
+
 // GetPriorityString 获取优先级字符串表示
 func (t *Task) GetPriorityString() string {
 	switch t.Priority {
@@ -95,6 +397,11 @@ func (t *Task) BeforeCreate(tx *gorm.DB) error {
 	if t.Priority == 0 {
 		t.Priority = TaskPriorityMedium
 	}
+	// created_at 列精确到秒，写入前截断，避免数据库四舍五入后与首次入队时的队列 score 不一致
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	t.CreatedAt = t.CreatedAt.Truncate(time.Second)
 	return nil
 }
 
@@ -114,10 +421,115 @@ func (t *Task) BeforeUpdate(tx *gorm.DB) error {
 
 // TaskCreateRequest 创建任务请求结构
 type TaskCreateRequest struct {
-	ModelID  uint64       `json:"model_id" binding:"required"`
-	Type     string       `json:"type" binding:"required"`
-	Input    string       `json:"input" binding:"required"`
-	Priority TaskPriority `json:"priority"`
+	ModelID          uint64            `json:"model_id"` // 为空时使用 models.default_model
+	Type             string            `json:"type"`     // 为空时使用 models.default_task_type
+	Input            string            `json:"input" binding:"required"`
+	Params           TaskParams        `json:"params"`
+	Tags             TaskTags          `json:"tags"`
+	Priority         TaskPriority      `json:"priority"`
+	MaxRetries       *int              `json:"max_retries"` // 为空时使用任务类型或全局默认值，0 表示不允许重试
+	DependsOn        []uint64          `json:"depends_on"`
+	ProviderOverride *ProviderOverride `json:"provider_override"`
+	// Cache 为 true 时，queue.cache_ttl 内有相同模型、类型、输入和参数的已完成任务则直接复用其结果
+	Cache bool `json:"cache"`
+	// DedupKey 去重键，已有相同键的 pending/running 任务时不再创建，直接返回该任务
+	DedupKey string `json:"dedup_key"`
+	// Deadline 截止时间，超过后仍在排队的任务被取消而不是执行；与 TTLSeconds 二选一
+	Deadline *time.Time `json:"deadline"`
+	// TTLSeconds 从创建起的有效秒数，等价于 deadline = 创建时间 + ttl_seconds
+	TTLSeconds *int `json:"ttl_seconds"`
+	// RunAt 计划执行时间，必须晚于当前时间；到时间前任务保持 pending 并停留在延迟队列中
+	RunAt *time.Time `json:"run_at"`
+
+	// IdempotencyKey 来自 Idempotency-Key 请求头（已按 API Key 区分），相同键的重复请求返回首次创建的任务
+	IdempotencyKey string `json:"-"`
+	// APIKeyName 创建任务的 API Key 名称（来自认证中间件），未启用认证时为空
+	APIKeyName string `json:"-"`
+}
+
+// MaxBatchTasks 批量创建任务的最大条数
+const MaxBatchTasks = 500
+
+// TaskBatchCreateRequest 批量创建任务请求
+type TaskBatchCreateRequest struct {
+	Tasks []*TaskCreateRequest `json:"tasks" binding:"required,min=1"`
+}
+
+// BatchResult 批量创建中单个条目的结果
+type BatchResult struct {
+	Index  int          `json:"index"`
+	TaskID uint64       `json:"task_id,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// RecoverResult 恢复遗留 running 任务的结果，Scanned 为超过时间阈值的 running 任务数，Recovered 为重新入队的任务数
+type RecoverResult struct {
+	Scanned   int      `json:"scanned"`
+	Recovered int      `json:"recovered"`
+	TaskIDs   []uint64 `json:"task_ids"`
+}
+
+// BulkTaskResult 批量取消/重试的结果，Matched 为符合过滤条件的任务数，Affected 为实际处理的任务数
+type BulkTaskResult struct {
+	Matched  int64 `json:"matched"`
+	Affected int64 `json:"affected"`
+}
+
+// TaskTimelineEvent 任务时间线中的一个事件，Stage 为 created、enqueued、started、completed、failed、cancelled，
+// 其他任务日志的 Stage 为 log
+type TaskTimelineEvent struct {
+	Time        time.Time `json:"time"`
+	Stage       string    `json:"stage"`
+	Message     string    `json:"message,omitempty"`
+	Level       LogLevel  `json:"level,omitempty"`
+	SincePrevMs int64     `json:"since_prev_ms"` // 距上一个事件的毫秒数
+}
+
+// TaskTimeline 任务执行时间线。任务尚未结束时最后一个阶段没有结束时间，相应耗时计算到当前时间
+type TaskTimeline struct {
+	TaskID     uint64              `json:"task_id"`
+	Status     TaskStatus          `json:"status"`
+	InProgress bool                `json:"in_progress"`
+	Events     []TaskTimelineEvent `json:"events"`
+	// QueueWaitMs 最后一次入队到开始执行（未开始时到结束或当前时间）的毫秒数
+	QueueWaitMs int64 `json:"queue_wait_ms"`
+	// ExecutionMs 最后一次开始执行到结束（未结束时到当前时间）的毫秒数，从未开始执行时为空
+	ExecutionMs *int64 `json:"execution_ms"`
+	// TotalMs 创建到结束（未结束时到当前时间）的毫秒数
+	TotalMs int64 `json:"total_ms"`
+}
+
+// TaskTimings 任务各阶段耗时，含义与 TaskTimeline 中的同名字段相同
+type TaskTimings struct {
+	QueueWaitMs int64  `json:"queue_wait_ms"`
+	ExecutionMs *int64 `json:"execution_ms"`
+	TotalMs     int64  `json:"total_ms"`
+}
+
+// TaskExport 单个任务的导出包，用于附在问题报告中：任务、全部日志（按时间升序）、模型快照和耗时。
+// 模型配置和任务级请求头中的敏感值已屏蔽
+type TaskExport struct {
+	ExportedAt time.Time   `json:"exported_at"`
+	Task       *Task       `json:"task"`
+	Model      *Model      `json:"model"`
+	Logs       []TaskLog   `json:"logs"`
+	Timings    TaskTimings `json:"timings"`
+	// Warnings 导出过程中未能获取的内容，如外部存储中的输出读取失败
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// HasFilters 是否指定了任何过滤条件，批量操作不允许作用于全部任务
+func (r *TaskListRequest) HasFilters() bool {
+	return r.ModelID != nil || r.Status != nil || r.Type != nil || r.Priority != nil ||
+		r.NeedsAttention != nil || r.Query != "" || r.CreatedAfter != nil || r.CreatedBefore != nil || len(r.Tags) > 0
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag,omitempty"` // 未通过的校验规则，例如 required、oneof
+	Message string `json:"message"`
 }
 
 // TaskUpdateRequest 更新任务请求结构
@@ -128,14 +540,21 @@ type TaskUpdateRequest struct {
 
 // TaskListRequest 任务列表请求结构
 type TaskListRequest struct {
-	ModelID  *uint64     `form:"model_id"`
-	Status   *TaskStatus `form:"status"`
-	Type     *string     `form:"type"`
-	Priority *TaskPriority `form:"priority"`
-	Page     int         `form:"page,default=1"`
-	PageSize int         `form:"page_size,default=20"`
-	OrderBy  string      `form:"order_by,default=created_at"`
-	Order    string      `form:"order,default=desc"`
+	ModelID        *uint64       `form:"model_id"`
+	Status         *TaskStatus   `form:"status"`
+	Type           *string       `form:"type"`
+	Priority       *TaskPriority `form:"priority"`
+	NeedsAttention *bool         `form:"needs_attention"`
+	Query          string        `form:"q"` // 按输入内容和错误信息全文检索
+	CreatedAfter   *time.Time    `form:"-"` // 创建时间不早于该时间
+	CreatedBefore  *time.Time    `form:"-"` // 创建时间早于该时间
+	Tags           TaskTags      `form:"-"` // 按标签过滤（?tag.<key>=<value>），多个标签同时满足
+	Page           int           `form:"page,default=1"`
+	PageSize       int           `form:"page_size,default=20"`
+	Cursor         *uint64       `form:"cursor"`           // 游标分页：返回 ID 在游标之后的任务，0 表示从头开始，设置后忽略 page
+	Limit          int           `form:"limit,default=20"` // 游标分页每页数量
+	OrderBy        string        `form:"order_by,default=created_at"`
+	Order          string        `form:"order,default=desc"`
 }
 
 // TaskStats 任务统计信息
@@ -149,3 +568,82 @@ type TaskStats struct {
 	SuccessRate      float64 `json:"success_rate"`
 	AvgProcessingMS  int64   `json:"avg_processing_ms"`
 }
+
+// TokenUsage 任务执行消耗的 token 数和费用
+type TokenUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// TotalTokens 获取总 token 数
+func (u TokenUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// CostBreakdown 按维度汇总的 token 用量和费用
+type CostBreakdown struct {
+	Name             string  `json:"name"` // 模型名称或任务类型
+	Tasks            int64   `json:"tasks"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// CostSummary 最近若干天的费用汇总
+type CostSummary struct {
+	Days             int             `json:"days"`
+	PromptTokens     int64           `json:"prompt_tokens"`
+	CompletionTokens int64           `json:"completion_tokens"`
+	CostUSD          float64         `json:"cost_usd"`
+	ByModel          []CostBreakdown `json:"by_model"`
+	ByType           []CostBreakdown `json:"by_type"`
+}
+
+// LatencyRequest 处理耗时分位数查询条件
+type LatencyRequest struct {
+	ModelID *uint64
+	Type    string
+	Hours   int // 统计最近 hours 小时内完成的任务
+}
+
+// LatencyPercentiles 已完成任务的处理耗时（started_at 到 completed_at）分位数，单位毫秒，使用最近邻秩法
+type LatencyPercentiles struct {
+	ModelID *uint64 `json:"model_id,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Hours   int     `json:"hours"`
+	Count   int     `json:"count"`   // 参与统计的任务数，为 0 时各分位数为 0
+	Sampled bool    `json:"sampled"` // 任务数超过上限时只统计最近完成的部分任务
+	P50Ms   float64 `json:"p50_ms"`
+	P90Ms   float64 `json:"p90_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// TaskEvent 任务状态变更事件，发布到 queue.event_channel
+type TaskEvent struct {
+	TaskID    uint64     `json:"task_id"`
+	ModelID   uint64     `json:"model_id"`
+	OldStatus TaskStatus `json:"old_status"`
+	NewStatus TaskStatus `json:"new_status"`
+	TraceID   string     `json:"trace_id,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// TaskStreamEventType 任务输出流事件类型
+type TaskStreamEventType string
+
+const (
+	TaskStreamEventChunk TaskStreamEventType = "chunk"
+	TaskStreamEventDone  TaskStreamEventType = "done"
+)
+
+// TaskStreamEvent 任务输出流事件
+type TaskStreamEvent struct {
+	TaskID       uint64              `json:"task_id"`
+	Event        TaskStreamEventType `json:"event"`
+	Chunk        string              `json:"chunk,omitempty"`
+	Status       TaskStatus          `json:"status,omitempty"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+}
@@ -17,6 +17,15 @@ const (
 	LogLevelError LogLevel = "error"
 )
 
+// IsValid 检查日志级别是否合法
+func (l LogLevel) IsValid() bool {
+	switch l {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+		return true
+	}
+	return false
+}
+
 // LogData 日志附加数据，存储为 JSON
 type LogData map[string]interface{}
 
@@ -50,6 +59,7 @@ type TaskLog struct {
 	Level     LogLevel  `json:"level" gorm:"type:enum('info','warn','error','debug');default:info;index:idx_level_created"`
 	Message   string    `json:"message" gorm:"type:text;not null"`
 	Data      LogData   `json:"data" gorm:"type:json"`
+	TraceID   string    `json:"trace_id,omitempty" gorm:"type:varchar(64);index"` // 所属任务的请求关联 ID
 	CreatedAt time.Time `json:"created_at" gorm:"index:idx_task_created,idx_level_created"`
 
 	// 关联关系
@@ -78,6 +88,13 @@ func (tl *TaskLog) GetData(key string) (interface{}, bool) {
 	return value, exists
 }
 
+// TaskLogListRequest 任务日志列表请求结构
+type TaskLogListRequest struct {
+	Level    *LogLevel `form:"level"`
+	Page     int       `form:"page,default=1"`
+	PageSize int       `form:"page_size,default=50"`
+}
+
 // SystemStats 系统统计表结构
 type SystemStats struct {
 	ID                   uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -96,6 +113,23 @@ func (SystemStats) TableName() string {
 	return "system_stats"
 }
 
+// QueueMetric 队列深度样本，按采样间隔对齐，多实例部署时每个时间点只保留一条
+type QueueMetric struct {
+	ID                  uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	SampledAt           time.Time `json:"sampled_at" gorm:"uniqueIndex;not null"`
+	HighPriorityCount   int64     `json:"high_priority_count"`
+	MediumPriorityCount int64     `json:"medium_priority_count"`
+	LowPriorityCount    int64     `json:"low_priority_count"`
+	ProcessingCount     int64     `json:"processing_count"`
+	DelayedCount        int64     `json:"delayed_count"`
+	TotalCount          int64     `json:"total_count"`
+}
+
+// TableName 指定表名
+func (QueueMetric) TableName() string {
+	return "queue_metrics"
+}
+
 // QueueStatus 队列状态信息
 type QueueStatus struct {
 	HighPriorityCount   int64 `json:"high_priority_count"`
@@ -104,25 +138,90 @@ type QueueStatus struct {
 	ProcessingCount     int64 `json:"processing_count"`
 	DelayedCount        int64 `json:"delayed_count"`
 	TotalCount          int64 `json:"total_count"`
+
+	ModelInflight map[uint64]int64 `json:"model_inflight,omitempty"`
+	DegradedMode  *DegradedMode    `json:"degraded_mode,omitempty"`
+	// ModelQueues 队列隔离模式下各模型就绪队列中的任务数
+	ModelQueues map[uint64]*ModelQueueCount `json:"model_queues,omitempty"`
+}
+
+// ModelQueueCount 模型独立就绪队列中等待的任务数
+type ModelQueueCount struct {
+	HighPriorityCount   int64 `json:"high_priority_count"`
+	MediumPriorityCount int64 `json:"medium_priority_count"`
+	LowPriorityCount    int64 `json:"low_priority_count"`
+	TotalCount          int64 `json:"total_count"`
+}
+
+// QueuePurgeResult 清空队列的结果
+type QueuePurgeResult struct {
+	Priority  TaskPriority `json:"priority"`
+	Removed   int          `json:"removed"`   // 从队列中移除的任务数
+	Cancelled int          `json:"cancelled"` // 被标记为取消的任务数
+}
+
+// DegradedModeSource 降级模式触发来源
+type DegradedModeSource string
+
+const (
+	DegradedModeSourceManual DegradedModeSource = "manual"
+	DegradedModeSourceAuto   DegradedModeSource = "auto"
+)
+
+// DegradedMode 降级模式状态：启用时 Worker 只处理不低于 MinPriority 的任务
+type DegradedMode struct {
+	Enabled     bool               `json:"enabled"`
+	MinPriority TaskPriority       `json:"min_priority,omitempty"`
+	Source      DegradedModeSource `json:"source,omitempty"`
+	Reason      string             `json:"reason,omitempty"`
+	Since       *time.Time         `json:"since,omitempty"`
 }
 
 // WorkerStatus Worker 状态信息
 type WorkerStatus struct {
 	WorkerID      string    `json:"worker_id"`
+	InstanceID    string    `json:"instance_id"`
 	ModelID       uint64    `json:"model_id"`
 	ModelName     string    `json:"model_name"`
 	Status        string    `json:"status"`
 	CurrentTaskID *uint64   `json:"current_task_id"`
+	TaskTypes     []string  `json:"task_types,omitempty"` // 只领取这些类型的任务，为空表示不限类型
 	StartTime     time.Time `json:"start_time"`
 	LastHeartbeat time.Time `json:"last_heartbeat"`
 }
 
+// WorkerHealth Worker 存活状态
+type WorkerHealth struct {
+	WorkerID      string    `json:"worker_id"`
+	ModelID       uint64    `json:"model_id"`
+	Status        string    `json:"status"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Healthy       bool      `json:"healthy"`
+}
+
+// WorkerPoolStatus 模型 Worker 池状态
+type WorkerPoolStatus struct {
+	ModelID         uint64 `json:"model_id"`
+	TargetWorkers   int    `json:"target_workers"`
+	CurrentWorkers  int    `json:"current_workers"`
+	DrainingWorkers int    `json:"draining_workers"`
+	MaxWorkers      int    `json:"max_workers"`
+}
+
 // DashboardStats Dashboard 统计数据
 type DashboardStats struct {
-	TaskStats     TaskStats       `json:"task_stats"`
-	ModelStats    []ModelStats    `json:"model_stats"`
-	QueueStatus   QueueStatus     `json:"queue_status"`
-	WorkerStatus  []WorkerStatus  `json:"worker_status"`
-	SystemStats   SystemStats     `json:"system_stats"`
-	RecentTasks   []Task          `json:"recent_tasks"`
+	TaskStats    TaskStats              `json:"task_stats"`
+	ModelStats   []ModelStats           `json:"model_stats"`
+	QueueStatus  QueueStatus            `json:"queue_status"`
+	WorkerStatus []WorkerStatus         `json:"worker_status"`
+	SystemStats  SystemStats            `json:"system_stats"`
+	RecentTasks  []Task                 `json:"recent_tasks"`
+	Instances    []InstanceWorkerStatus `json:"instances"`
+}
+
+// InstanceWorkerStatus 单个实例的 Worker 状态
+type InstanceWorkerStatus struct {
+	InstanceID    string         `json:"instance_id"`
+	LastHeartbeat time.Time      `json:"last_heartbeat"`
+	Workers       []WorkerStatus `json:"workers"`
 }
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+)
+
+var allTaskStatuses = []TaskStatus{
+	TaskStatusPending,
+	TaskStatusRunning,
+	TaskStatusCompleted,
+	TaskStatusFailed,
+	TaskStatusCancelled,
+}
+
+func TestTaskCanTransitionTo(t *testing.T) {
+	allowed := map[TaskStatus]map[TaskStatus]bool{
+		TaskStatusPending: {
+			TaskStatusRunning:   true,
+			TaskStatusFailed:    true,
+			TaskStatusCancelled: true,
+		},
+		TaskStatusRunning: {
+			TaskStatusCompleted: true,
+			TaskStatusFailed:    true,
+			TaskStatusCancelled: true,
+			TaskStatusPending:   true,
+		},
+		TaskStatusFailed: {
+			TaskStatusPending: true,
+		},
+		TaskStatusCompleted: {},
+		TaskStatusCancelled: {},
+	}
+
+	for _, from := range allTaskStatuses {
+		for _, to := range allTaskStatuses {
+			task := &Task{Status: from}
+			if got, want := task.CanTransitionTo(to), allowed[from][to]; got != want {
+				t.Errorf("CanTransitionTo(%s -> %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestTaskCannotTransitionToUnknownStatus(t *testing.T) {
+	for _, from := range allTaskStatuses {
+		task := &Task{Status: from}
+		if task.CanTransitionTo("archived") {
+			t.Errorf("CanTransitionTo(%s -> archived) = true, want false", from)
+		}
+	}
+	if (&Task{Status: "archived"}).CanTransitionTo(TaskStatusPending) {
+		t.Error("CanTransitionTo(archived -> pending) = true, want false")
+	}
+}
+
+func TestTransitionSources(t *testing.T) {
+	tests := []struct {
+		status TaskStatus
+		want   []TaskStatus
+	}{
+		{TaskStatusPending, []TaskStatus{TaskStatusFailed, TaskStatusRunning}},
+		{TaskStatusRunning, []TaskStatus{TaskStatusPending}},
+		{TaskStatusCompleted, []TaskStatus{TaskStatusRunning}},
+		{TaskStatusFailed, []TaskStatus{TaskStatusPending, TaskStatusRunning}},
+		{TaskStatusCancelled, []TaskStatus{TaskStatusPending, TaskStatusRunning}},
+	}
+
+	for _, tt := range tests {
+		got := TransitionSources(tt.status)
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if len(got) != len(tt.want) {
+			t.Errorf("TransitionSources(%s) = %v, want %v", tt.status, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("TransitionSources(%s) = %v, want %v", tt.status, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTaskBeforeCreateTruncatesCreatedAt(t *testing.T) {
+	// created_at 列精确到秒，写入前截断，重新加载的任务与首次入队时的创建时间一致
+	createdAt := time.Date(2024, 1, 2, 15, 4, 5, 900_000_000, time.UTC)
+	task := &Task{CreatedAt: createdAt}
+	if err := task.BeforeCreate(nil); err != nil {
+		t.Fatalf("before create: %v", err)
+	}
+	if want := createdAt.Truncate(time.Second); !task.CreatedAt.Equal(want) {
+		t.Fatalf("expected created_at %v, got %v", want, task.CreatedAt)
+	}
+
+	// 未设置创建时间时使用当前时间，同样截断到秒
+	task = &Task{}
+	if err := task.BeforeCreate(nil); err != nil {
+		t.Fatalf("before create: %v", err)
+	}
+	if task.CreatedAt.IsZero() || task.CreatedAt.Nanosecond() != 0 {
+		t.Fatalf("expected created_at truncated to seconds, got %v", task.CreatedAt)
+	}
+}
+
+func TestTaskMarshalJSONOutput(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name      string
+		output    *string
+		format    OutputFormat
+		truncated bool
+		want      string
+	}{
+		// JSON 格式的输出按 JSON 值返回，不再是需要二次解析的字符串
+		{"json array", str("[0.1, 0.2, 0.3]"), OutputFormatJSON, false, `[0.1,0.2,0.3]`},
+		{"json object", str(`{"label":"spam"}`), OutputFormatJSON, false, `{"label":"spam"}`},
+		// 被截断或内容不是合法 JSON 时仍返回字符串
+		{"truncated json", str("[0.1, 0.2"), OutputFormatJSON, true, `"[0.1, 0.2"`},
+		{"invalid json", str("[0.1, 0.2, ...]"), OutputFormatJSON, false, `"[0.1, 0.2, ...]"`},
+		{"text", str("[0.1]"), OutputFormatText, false, `"[0.1]"`},
+		{"base64", str("aGVsbG8="), OutputFormatBase64, false, `"aGVsbG8="`},
+		{"no output", nil, OutputFormatJSON, false, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(Task{Output: tt.output, OutputFormat: tt.format, OutputTruncated: tt.truncated})
+			if err != nil {
+				t.Fatalf("marshal task: %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("unmarshal task: %v", err)
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, fields["output"]); err != nil {
+				t.Fatalf("compact output: %v", err)
+			}
+			if compact.String() != tt.want {
+				t.Fatalf("output = %s, want %s", compact.String(), tt.want)
+			}
+			if string(fields["output_format"]) != `"`+string(tt.format)+`"` {
+				t.Fatalf("output_format = %s, want %q", fields["output_format"], tt.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// agingScanLimit 每次每个队列最多检查的等待超时任务数
+const agingScanLimit = 500
+
+// PromoteAgedTasks 将在低、中优先级队列中等待超过 threshold 的任务提升一级，返回被提升的任务。
+// 等待时间从入队（或上次提升）开始计算，任务表中的优先级不变；降级模式下不提升低于下限的队列，避免绕过降级
+func (m *Manager) PromoteAgedTasks(ctx context.Context, threshold time.Duration) ([]QueueItem, error) {
+	promoted := []QueueItem{}
+	if threshold <= 0 {
+		return promoted, nil
+	}
+
+	minPriority := m.getMinPriority(ctx)
+	now := time.Now()
+	for name, client := range m.allClients() {
+		modelIDs, err := m.queueModels(ctx, client)
+		if err != nil {
+			return promoted, err
+		}
+		for _, modelID := range modelIDs {
+			// 先处理中优先级，本轮从低优先级提升上来的任务不会被再次提升
+			for _, priority := range []models.TaskPriority{models.TaskPriorityMedium, models.TaskPriorityLow} {
+				if priority < minPriority {
+					continue
+				}
+				items, err := m.promoteAged(ctx, client, modelID, priority, threshold, now)
+				if err != nil {
+					return promoted, fmt.Errorf("failed to promote aged tasks on backend %s: %w", name, err)
+				}
+				promoted = append(promoted, items...)
+			}
+		}
+	}
+	return promoted, nil
+}
+
+// promoteAged 提升指定优先级队列中等待超时的任务，modelID 为隔离模式下的模型队列
+func (m *Manager) promoteAged(ctx context.Context, client *redis.Client, modelID uint64, priority models.TaskPriority, threshold time.Duration, now time.Time) ([]QueueItem, error) {
+	fromKey := m.readyKey(modelID, priority)
+	toKey := m.readyKey(modelID, priority+1)
+
+	// score 按创建时间排序，创建时间晚于 now - threshold 的任务不可能等待超时
+	results, err := client.ZRangeByScoreWithScores(ctx, fromKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(readyScore(now.Add(-threshold), 0), 10),
+		Count: agingScanLimit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []QueueItem
+	for _, z := range results {
+		raw, _ := z.Member.(string)
+
+		var item QueueItem
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			continue
+		}
+		waitingSince := item.CreatedAt
+		if item.PromotedAt != nil {
+			waitingSince = *item.PromotedAt
+		}
+		if now.Sub(waitingSince) < threshold {
+			continue
+		}
+
+		item.Priority = int(priority + 1)
+		item.PromotedAt = &now
+		itemBytes, err := json.Marshal(&item)
+		if err != nil {
+			continue
+		}
+
+		// 移除成功才加入新队列，返回 0 说明任务已被 Worker 取走或被其他实例提升
+		removed, err := client.ZRem(ctx, fromKey, raw).Result()
+		if err != nil {
+			return promoted, err
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := client.ZAdd(ctx, toKey, readyMember(&item, itemBytes)).Err(); err != nil {
+			// 放回原队列，保持原有位置
+			if pushErr := client.ZAdd(ctx, fromKey, &redis.Z{Score: z.Score, Member: raw}).Err(); pushErr != nil {
+				m.logger.WithError(pushErr).WithField("task_id", item.TaskID).Error("Failed to restore task after promotion failure")
+			}
+			return promoted, err
+		}
+
+		m.logger.WithFields(logrus.Fields{
+			"task_id":       item.TaskID,
+			"model_id":      item.ModelID,
+			"from_priority": priority,
+			"to_priority":   item.Priority,
+			"waited":        now.Sub(waitingSince).Round(time.Second).String(),
+			"trace_id":      item.TraceID,
+		}).Info("Aged task promoted")
+		promoted = append(promoted, item)
+	}
+	return promoted, nil
+}
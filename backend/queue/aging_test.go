@@ -0,0 +1,138 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// peekTaskIDs 返回指定优先级队列中的任务 ID
+func peekTaskIDs(t *testing.T, m *Manager, priority models.TaskPriority) []uint64 {
+	t.Helper()
+	items, err := m.PeekQueue(context.Background(), priority, 100)
+	if err != nil {
+		t.Fatalf("PeekQueue: %v", err)
+	}
+	ids := make([]uint64, 0, len(items))
+	for _, item := range items {
+		if item.Priority != int(priority) {
+			t.Fatalf("task %d in %v queue has priority %d", item.TaskID, priority, item.Priority)
+		}
+		ids = append(ids, item.TaskID)
+	}
+	return ids
+}
+
+// sameIDs 比较两组任务 ID，顺序必须一致
+func sameIDs(got, want []uint64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPromoteAgedTasks(t *testing.T) {
+	tests := []struct {
+		name         string
+		configure    func(*config.Config)
+		threshold    time.Duration
+		minPriority  models.TaskPriority
+		wantPromoted int
+		wantHigh     []uint64
+		wantMedium   []uint64
+		wantLow      []uint64
+	}{
+		// 等待超时的低优先级任务升到中，中优先级升到高，新任务不变；同一轮中低优先级只提升一级
+		{"aged tasks promoted one level", nil, time.Minute, 0, 2, []uint64{2}, []uint64{1, 4}, []uint64{3}},
+		{"isolated model queues", withModelIsolation, time.Minute, 0, 2, []uint64{2}, []uint64{1, 4}, []uint64{3}},
+		// 阈值为 0 表示不提升
+		{"disabled", nil, 0, 0, 0, nil, []uint64{2, 4}, []uint64{1, 3}},
+		// 降级模式只消费中优先级以上时不提升低优先级任务，避免绕过降级
+		{"degraded mode keeps low queue", nil, time.Minute, models.TaskPriorityMedium, 1, []uint64{2}, []uint64{4}, []uint64{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, tt.configure)
+			ctx := context.Background()
+			if tt.minPriority != 0 {
+				if err := m.SetDegradedMode(ctx, tt.minPriority, models.DegradedModeSourceManual, "maintenance"); err != nil {
+					t.Fatalf("set degraded mode: %v", err)
+				}
+			}
+
+			// 任务 1、2 早已入队，任务 3、4 刚刚创建
+			oldLow := newTestTask(1, 1, "")
+			oldLow.Priority = models.TaskPriorityLow
+			oldMedium := newTestTask(2, 2, "")
+			newLow := newTestTask(3, 1, "")
+			newLow.Priority = models.TaskPriorityLow
+			newLow.CreatedAt = time.Now()
+			newMedium := newTestTask(4, 2, "")
+			newMedium.CreatedAt = time.Now()
+			mustEnqueue(t, m, oldLow, oldMedium, newLow, newMedium)
+
+			promoted, err := m.PromoteAgedTasks(ctx, tt.threshold)
+			if err != nil {
+				t.Fatalf("PromoteAgedTasks: %v", err)
+			}
+			if len(promoted) != tt.wantPromoted {
+				t.Fatalf("promoted %+v, want %d tasks", promoted, tt.wantPromoted)
+			}
+			for _, item := range promoted {
+				if item.PromotedAt == nil {
+					t.Fatalf("promoted task %d has no promoted_at", item.TaskID)
+				}
+			}
+
+			if got := peekTaskIDs(t, m, models.TaskPriorityHigh); !sameIDs(got, tt.wantHigh) {
+				t.Fatalf("high queue = %v, want %v", got, tt.wantHigh)
+			}
+			if got := peekTaskIDs(t, m, models.TaskPriorityMedium); !sameIDs(got, tt.wantMedium) {
+				t.Fatalf("medium queue = %v, want %v", got, tt.wantMedium)
+			}
+			if got := peekTaskIDs(t, m, models.TaskPriorityLow); !sameIDs(got, tt.wantLow) {
+				t.Fatalf("low queue = %v, want %v", got, tt.wantLow)
+			}
+		})
+	}
+}
+
+func TestPromoteAgedTasksWaitsAgainAfterPromotion(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	task := newTestTask(1, 1, "")
+	task.Priority = models.TaskPriorityLow
+	mustEnqueue(t, m, task)
+
+	if promoted, err := m.PromoteAgedTasks(ctx, time.Minute); err != nil || len(promoted) != 1 {
+		t.Fatalf("first promotion = %+v (err %v), want one task", promoted, err)
+	}
+
+	// 提升后重新计算等待时间，下一轮扫描不会立即升到高优先级
+	promoted, err := m.PromoteAgedTasks(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("PromoteAgedTasks: %v", err)
+	}
+	if len(promoted) != 0 {
+		t.Fatalf("promoted again right away: %+v", promoted)
+	}
+	if got := peekTaskIDs(t, m, models.TaskPriorityMedium); !sameIDs(got, []uint64{1}) {
+		t.Fatalf("medium queue = %v, want [1]", got)
+	}
+
+	// 提升后的任务按原创建时间排序，排在中优先级队列中较晚创建的任务之前
+	later := newTestTask(2, 1, "")
+	mustEnqueue(t, m, later)
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("dequeued %+v (err %v), want promoted task 1", item, err)
+	}
+}
@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBackend 共享队列后端名称
+const DefaultBackend = "default"
+
+// SetModelBackend 设置模型使用的队列后端，backend 为空时使用共享后端
+func (m *Manager) SetModelBackend(modelID uint64, backend string) {
+	if backend == "" {
+		backend = DefaultBackend
+	}
+
+	if backend != DefaultBackend {
+		if _, exists := m.backends[backend]; !exists {
+			m.logger.WithFields(logrus.Fields{
+				"model_id": modelID,
+				"backend":  backend,
+			}).Warn("Queue backend not configured, falling back to default")
+			backend = DefaultBackend
+		}
+	}
+
+	m.backendsMutex.Lock()
+	defer m.backendsMutex.Unlock()
+	if backend == DefaultBackend {
+		delete(m.modelBackends, modelID)
+		return
+	}
+	m.modelBackends[modelID] = backend
+}
+
+// clientFor 获取模型对应的队列后端连接
+func (m *Manager) clientFor(modelID uint64) *redis.Client {
+	m.backendsMutex.RLock()
+	backend, exists := m.modelBackends[modelID]
+	m.backendsMutex.RUnlock()
+
+	if exists {
+		if client, ok := m.backends[backend]; ok {
+			return client
+		}
+	}
+	return m.shardClient(modelID)
+}
+
+// shardClient 按 queue.shards 为未指定 queue_backend 的模型选择分片（模型ID % 分片数），未配置分片时使用共享后端
+func (m *Manager) shardClient(modelID uint64) *redis.Client {
+	if len(m.shards) == 0 {
+		return m.client
+	}
+	return m.shards[modelID%uint64(len(m.shards))]
+}
+
+// allClients 获取所有队列后端连接（包括共享后端）
+func (m *Manager) allClients() map[string]*redis.Client {
+	clients := make(map[string]*redis.Client, len(m.backends)+1)
+	clients[DefaultBackend] = m.client
+	for name, client := range m.backends {
+		clients[name] = client
+	}
+	return clients
+}
@@ -0,0 +1,202 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// newShardedTestManager 创建使用三个分片（共享 Redis、east、west）的队列管理器
+func newShardedTestManager(t *testing.T) (*Manager, map[string]*redis.Client) {
+	t.Helper()
+
+	connect := func() *redis.Client {
+		client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	defaultClient := connect()
+	backends := map[string]*redis.Client{"east": connect(), "west": connect()}
+
+	cfg := newTestConfig()
+	cfg.Queue.Shards = []string{DefaultBackend, "east", "west"}
+	clients, err := NewClients(defaultClient, backends, cfg.Queue.Shards)
+	if err != nil {
+		t.Fatalf("new clients: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	byName := map[string]*redis.Client{DefaultBackend: defaultClient, "east": backends["east"], "west": backends["west"]}
+	return NewManager(clients, cfg, logger), byName
+}
+
+func TestNewClientsRejectsUnknownShard(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer client.Close()
+
+	if _, err := NewClients(client, nil, []string{DefaultBackend, "east"}); err == nil {
+		t.Fatal("expected error for shard without backend")
+	}
+}
+
+func TestShardRoutingByModelID(t *testing.T) {
+	m, clients := newShardedTestManager(t)
+
+	// 模型ID % 3：3 → default，4 → east，5 → west，7 → east
+	mustEnqueue(t, m,
+		newTestTask(1, 3, "text-generation"),
+		newTestTask(2, 4, "text-generation"),
+		newTestTask(3, 5, "text-generation"),
+		newTestTask(4, 7, "text-generation"),
+	)
+
+	want := map[string]int64{DefaultBackend: 1, "east": 2, "west": 1}
+	for name, client := range clients {
+		if got := readyCount(t, m, client, 0); got != want[name] {
+			t.Errorf("shard %s has %d tasks, want %d", name, got, want[name])
+		}
+	}
+
+	// 同一模型的出队从它所在的分片读取
+	item, err := m.DequeueTask(context.Background(), 7, nil)
+	if err != nil || item == nil || item.TaskID != 4 {
+		t.Fatalf("expected task 4 from east shard, got %+v, %v", item, err)
+	}
+	if count, _ := clients["east"].ZCard(context.Background(), m.config.Queue.ProcessingQueue).Result(); count != 1 {
+		t.Errorf("east processing queue has %d tasks, want 1", count)
+	}
+}
+
+func TestQueueBackendOverridesShard(t *testing.T) {
+	m, clients := newShardedTestManager(t)
+
+	// 模型 4 按分片属于 east，指定了 queue_backend 后使用 west
+	m.SetModelBackend(4, "west")
+	mustEnqueue(t, m, newTestTask(1, 4, "text-generation"))
+
+	if got := readyCount(t, m, clients["west"], 4); got != 1 {
+		t.Errorf("west has %d tasks for model 4, want 1", got)
+	}
+	if got := readyCount(t, m, clients["east"], 4); got != 0 {
+		t.Errorf("east has %d tasks for model 4, want 0", got)
+	}
+}
+
+func TestQueueStatusAggregatesShards(t *testing.T) {
+	m, _ := newShardedTestManager(t)
+	ctx := context.Background()
+
+	high := newTestTask(1, 3, "text-generation")
+	high.Priority = models.TaskPriorityHigh
+	mustEnqueue(t, m,
+		high,
+		newTestTask(2, 4, "text-generation"),
+		newTestTask(3, 5, "text-generation"),
+		newTestTask(4, 5, "text-generation"),
+	)
+	if item, err := m.DequeueTask(ctx, 5, nil); err != nil || item == nil {
+		t.Fatalf("expected task from west shard, got %+v, %v", item, err)
+	}
+
+	status, err := m.GetQueueStatus(ctx)
+	if err != nil {
+		t.Fatalf("get status: %v", err)
+	}
+	if status.HighPriorityCount != 1 {
+		t.Errorf("high priority count = %d, want 1", status.HighPriorityCount)
+	}
+	if status.MediumPriorityCount != 2 {
+		t.Errorf("medium priority count = %d, want 2", status.MediumPriorityCount)
+	}
+	if status.ProcessingCount != 1 {
+		t.Errorf("processing count = %d, want 1", status.ProcessingCount)
+	}
+	if status.TotalCount != 4 {
+		t.Errorf("total count = %d, want 4", status.TotalCount)
+	}
+}
+
+func TestQueueStatusPerModelWithIsolatedShards(t *testing.T) {
+	m, _ := newShardedTestManager(t)
+	m.config.Queue.ModelIsolation = true
+	ctx := context.Background()
+
+	mustEnqueue(t, m,
+		newTestTask(1, 4, "text-generation"),
+		newTestTask(2, 5, "text-generation"),
+		newTestTask(3, 5, "text-generation"),
+	)
+
+	status, err := m.GetQueueStatus(ctx)
+	if err != nil {
+		t.Fatalf("get status: %v", err)
+	}
+	if got := status.ModelQueues[4]; got == nil || got.TotalCount != 1 {
+		t.Errorf("model 4 queue = %+v, want 1 task", got)
+	}
+	if got := status.ModelQueues[5]; got == nil || got.TotalCount != 2 {
+		t.Errorf("model 5 queue = %+v, want 2 tasks", got)
+	}
+	if status.TotalCount != 3 {
+		t.Errorf("total count = %d, want 3", status.TotalCount)
+	}
+}
+
+func TestExclusiveBackendKeepsQueuesOffSharedRedis(t *testing.T) {
+	shared := miniredis.RunT(t)
+	exclusive := miniredis.RunT(t)
+	defaultClient := redis.NewClient(&redis.Options{Addr: shared.Addr()})
+	gpuClient := redis.NewClient(&redis.Options{Addr: exclusive.Addr()})
+	t.Cleanup(func() { defaultClient.Close(); gpuClient.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := NewManager(&Clients{Default: defaultClient, Backends: map[string]*redis.Client{"gpu": gpuClient}}, newTestConfig(), logger)
+	m.SetModelBackend(9, "gpu")
+	ctx := context.Background()
+
+	// 独占后端模型的任务走完入队、出队、重试、延迟转移和完成的全部流程
+	mustEnqueue(t, m, newTestTask(1, 9, "text-generation"), newTestTask(2, 9, "text-generation"))
+	if got := readyCount(t, m, gpuClient, 9); got != 2 {
+		t.Fatalf("gpu backend has %d ready tasks, want 2", got)
+	}
+	item, err := m.DequeueTask(ctx, 9, nil)
+	if err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("expected task 1, got %+v, %v", item, err)
+	}
+	if err := m.RequeueTask(ctx, item, 0); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if err := m.ProcessDelayedTasks(ctx); err != nil {
+		t.Fatalf("process delayed: %v", err)
+	}
+	for _, want := range []uint64{1, 2} {
+		item, err := m.DequeueTask(ctx, 9, nil)
+		if err != nil || item == nil || item.TaskID != want {
+			t.Fatalf("expected task %d, got %+v, %v", want, item, err)
+		}
+		if err := m.CompleteTask(ctx, item.TaskID); err != nil {
+			t.Fatalf("complete task %d: %v", item.TaskID, err)
+		}
+	}
+
+	// 共享 Redis 上只允许出现全局并发计数，不能出现任何队列键
+	allowed := map[string]bool{
+		m.config.Queue.InflightKey:    true,
+		m.config.Queue.KeyInflightKey: true,
+		m.config.Queue.CapacityKey:    true,
+	}
+	for _, key := range shared.Keys() {
+		if !allowed[key] {
+			t.Errorf("shared redis has key %q for an exclusive-backend model", key)
+		}
+	}
+}
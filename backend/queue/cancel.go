@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PublishTaskCancel 广播取消任务的消息，正在执行该任务的 Worker 收到后中断执行
+func (m *Manager) PublishTaskCancel(ctx context.Context, taskID uint64) error {
+	if err := m.client.Publish(ctx, m.getCancelChannel(), strconv.FormatUint(taskID, 10)).Err(); err != nil {
+		return fmt.Errorf("failed to publish task cancel: %w", err)
+	}
+	return nil
+}
+
+// SubscribeTaskCancels 订阅任务取消消息，调用方负责关闭返回的 PubSub
+func (m *Manager) SubscribeTaskCancels(ctx context.Context) (*redis.PubSub, error) {
+	pubsub := m.client.Subscribe(ctx, m.getCancelChannel())
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe task cancels: %w", err)
+	}
+
+	return pubsub, nil
+}
+
+// getCancelChannel 获取任务取消消息的频道名
+func (m *Manager) getCancelChannel() string {
+	if m.config.Queue.CancelChannel != "" {
+		return m.config.Queue.CancelChannel
+	}
+	return "llm_tasks:cancel"
+}
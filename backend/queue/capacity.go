@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrQueueFull 等待中的任务数已达到 max_queue_size，新任务需要稍后重试
+var ErrQueueFull = errors.New("queue is full")
+
+// CheckCapacity 检查队列是否还能接收指定优先级的任务，max_queue_size 为 0 时不限制。
+// 开启 high_priority_bypass 时高优先级任务不受限制
+func (m *Manager) CheckCapacity(ctx context.Context, priority models.TaskPriority) error {
+	limit := m.config.Queue.MaxQueueSize
+	if limit <= 0 {
+		return nil
+	}
+	if priority == models.TaskPriorityHigh && m.config.Queue.HighPriorityBypass {
+		return nil
+	}
+
+	pending, err := m.PendingCount(ctx)
+	if err != nil {
+		return err
+	}
+	if pending >= int64(limit) {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// PendingCount 统计各后端中等待执行的任务数，包括三个优先级队列和延迟队列
+func (m *Manager) PendingCount(ctx context.Context) (int64, error) {
+	var total int64
+	for name, client := range m.allClients() {
+		modelIDs, err := m.queueModels(ctx, client)
+		if err != nil {
+			return 0, err
+		}
+		keys := []string{m.config.Queue.DelayedQueue}
+		for _, modelID := range modelIDs {
+			for _, priority := range priorities {
+				keys = append(keys, m.readyKey(modelID, priority))
+			}
+		}
+
+		cmds := make([]*redis.IntCmd, 0, len(keys))
+		if _, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				cmds = append(cmds, pipe.ZCard(ctx, key))
+			}
+			return nil
+		}); err != nil {
+			return 0, fmt.Errorf("failed to count pending tasks on backend %s: %w", name, err)
+		}
+		for _, cmd := range cmds {
+			total += cmd.Val()
+		}
+	}
+	return total, nil
+}
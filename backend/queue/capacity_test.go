@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestEnqueueTaskRejectsWhenQueueFull(t *testing.T) {
+	m, _ := newTestManager(t, func(cfg *config.Config) { cfg.Queue.MaxQueueSize = 2 })
+	ctx := context.Background()
+	mustEnqueue(t, m, newTestTask(1, 1, ""), newTestTask(2, 2, ""))
+
+	// 各模型和优先级的等待任务合计达到上限后拒绝入队
+	if err := m.EnqueueTask(ctx, newTestTask(3, 1, "")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("enqueue at capacity error = %v, want ErrQueueFull", err)
+	}
+	if pending, err := m.PendingCount(ctx); err != nil || pending != 2 {
+		t.Fatalf("pending = %d (err %v), want 2", pending, err)
+	}
+
+	// 出队后腾出空间，可以再次入队；处理中的任务不计入上限
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil {
+		t.Fatalf("dequeue = %+v (err %v)", item, err)
+	}
+	if err := m.EnqueueTask(ctx, newTestTask(3, 1, "")); err != nil {
+		t.Fatalf("enqueue after dequeue: %v", err)
+	}
+	if err := m.EnqueueTask(ctx, newTestTask(4, 1, "")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("enqueue when full again error = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		bypass   bool
+		delayed  bool
+		priority models.TaskPriority
+		wantFull bool
+	}{
+		{"unlimited", 0, false, false, models.TaskPriorityLow, false},
+		{"below limit", 3, false, false, models.TaskPriorityMedium, false},
+		{"at limit", 2, false, false, models.TaskPriorityMedium, true},
+		// 延迟队列中的任务同样计入等待任务数
+		{"delayed tasks counted", 3, false, true, models.TaskPriorityMedium, true},
+		// 开启 high_priority_bypass 时只有高优先级任务不受限制
+		{"high priority bypass", 2, true, false, models.TaskPriorityHigh, false},
+		{"bypass only for high priority", 2, true, false, models.TaskPriorityMedium, true},
+		{"high priority without bypass", 2, false, false, models.TaskPriorityHigh, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, func(cfg *config.Config) {
+				cfg.Queue.MaxQueueSize = tt.limit
+				cfg.Queue.HighPriorityBypass = tt.bypass
+			})
+			ctx := context.Background()
+			low := newTestTask(2, 1, "")
+			low.Priority = models.TaskPriorityLow
+			mustEnqueue(t, m, newTestTask(1, 1, ""), low)
+			if tt.delayed {
+				scheduled := newTestTask(3, 1, "")
+				runAt := time.Now().Add(time.Hour)
+				scheduled.RunAt = &runAt
+				mustEnqueue(t, m, scheduled)
+			}
+
+			err := m.CheckCapacity(ctx, tt.priority)
+			if tt.wantFull != errors.Is(err, ErrQueueFull) {
+				t.Fatalf("CheckCapacity error = %v, want full %v", err, tt.wantFull)
+			}
+			if !tt.wantFull && err != nil {
+				t.Fatalf("CheckCapacity: %v", err)
+			}
+		})
+	}
+}
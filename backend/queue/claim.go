@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// errTaskClaimed 任务已不在就绪队列中，被其他 Worker 取走或被清理
+var errTaskClaimed = errors.New("task already claimed")
+
+// claimScript 原子地将任务从就绪队列（KEYS[1]）移到处理中队列（KEYS[2]），score 为开始处理时间，
+// 同时占用模型（KEYS[3] 计数、KEYS[4] 上限）和 API Key（KEYS[5] 计数，ARGV[6] 上限，为 0 不限制）的并发名额。
+// 任务已不在就绪队列时返回 0，模型并发已满返回 -1，API Key 已达上限返回 -2，都不做任何写入。
+// 所有读取和类型检查都在第一次写入之前完成，脚本出错时不会留下只执行了一半的出队；
+// 进程在出队后崩溃时任务已在处理中队列且名额已占用，由 CleanupStuckTasks 超时后放回并释放名额
+var claimScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+local capacity = tonumber(redis.call('HGET', KEYS[4], ARGV[4]) or '0')
+local current = tonumber(redis.call('HGET', KEYS[3], ARGV[4]) or '0')
+if capacity > 0 and current >= capacity then
+	return -1
+end
+local keyLimit = tonumber(ARGV[6])
+if ARGV[5] ~= '' and keyLimit > 0 then
+	local running = tonumber(redis.call('HGET', KEYS[5], ARGV[5]) or '0')
+	if running >= keyLimit then
+		return -2
+	end
+end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HINCRBY', KEYS[3], ARGV[4], 1)
+if ARGV[5] ~= '' and keyLimit > 0 then
+	redis.call('HINCRBY', KEYS[5], ARGV[5], 1)
+end
+return 1
+`)
+
+// moveScript 原子地将任务从就绪队列（KEYS[1]）移到处理中队列（KEYS[2]），不涉及并发计数。
+// 任务已不在就绪队列时返回 0，不写入处理中队列
+var moveScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+redis.call('ZREM', KEYS[1], ARGV[1])
+return 1
+`)
+
+// claimTask 为任务占用模型和 API Key 的并发名额，并将其从就绪队列原子地移到处理中队列。
+// 模型并发已满时返回 errModelAtCapacity，API Key 执行中的任务已达上限时返回 errKeyAtCapacity，
+// 任务已被其他 Worker 取走时返回 errTaskClaimed，未取到任务时不占用名额
+func (m *Manager) claimTask(ctx context.Context, client *redis.Client, queueKey, raw string, item *QueueItem) error {
+	itemBytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	// 并发计数保存在共享 Redis 中，任务在独立后端时无法在同一个脚本中占用名额
+	if client != m.client {
+		return m.claimOnBackend(ctx, client, queueKey, raw, itemBytes, item)
+	}
+
+	keys := []string{queueKey, m.config.Queue.ProcessingQueue, m.getInflightKey(), m.getCapacityKey(), m.getKeyInflightKey()}
+	var keyLimit int
+	if item.APIKey != "" {
+		keyLimit = m.config.Auth.MaxRunningFor(item.APIKey)
+	}
+	result, err := claimScript.Run(ctx, client, keys,
+		raw, itemBytes, time.Now().Unix(), item.ModelID, item.APIKey, keyLimit).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to dequeue from %s: %w", queueKey, err)
+	}
+
+	switch result {
+	case 0:
+		return errTaskClaimed
+	case -1:
+		return errModelAtCapacity
+	case -2:
+		return errKeyAtCapacity
+	}
+	return nil
+}
+
+// claimOnBackend 任务在独立队列后端时，先在共享 Redis 中占用名额，再在后端中移动任务，
+// 移动失败或任务已被取走时释放已占用的名额
+func (m *Manager) claimOnBackend(ctx context.Context, client *redis.Client, queueKey, raw string, itemBytes []byte, item *QueueItem) error {
+	if err := m.acquireInflight(ctx, item.ModelID); err != nil {
+		return err
+	}
+	if err := m.acquireKeyInflight(ctx, item.APIKey); err != nil {
+		m.releaseInflight(ctx, item.ModelID)
+		return err
+	}
+
+	keys := []string{queueKey, m.config.Queue.ProcessingQueue}
+	moved, err := moveScript.Run(ctx, client, keys, raw, itemBytes, time.Now().Unix()).Int64()
+	if err != nil || moved == 0 {
+		m.releaseInflight(ctx, item.ModelID)
+		m.releaseKeyInflight(ctx, item.APIKey)
+		if err != nil {
+			return fmt.Errorf("failed to dequeue from %s: %w", queueKey, err)
+		}
+		return errTaskClaimed
+	}
+
+	return nil
+}
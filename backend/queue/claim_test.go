@@ -0,0 +1,270 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// readyCount 获取模型 medium 优先级就绪队列中的任务数
+func readyCount(t *testing.T, m *Manager, client *redis.Client, modelID uint64) int64 {
+	t.Helper()
+	count, err := client.ZCard(context.Background(), m.readyKey(modelID, models.TaskPriorityMedium)).Result()
+	if err != nil {
+		t.Fatalf("read ready queue: %v", err)
+	}
+	return count
+}
+
+// modelInflight 获取模型处理中的名额数
+func modelInflight(t *testing.T, m *Manager, modelID uint64) int64 {
+	t.Helper()
+	inflight, err := m.GetModelInflight(context.Background())
+	if err != nil {
+		t.Fatalf("get inflight: %v", err)
+	}
+	return inflight[modelID]
+}
+
+func TestDequeueTaskClaimsSlotAndMovesToProcessing(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	mustEnqueue(t, m, newTestTask(1, 1, "text-generation"))
+
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("expected task 1, got %+v, %v", item, err)
+	}
+
+	if got := readyCount(t, m, m.client, 1); got != 0 {
+		t.Errorf("ready queue has %d tasks, want 0", got)
+	}
+	if items := processingItems(t, m); len(items) != 1 || items[0].TaskID != 1 {
+		t.Errorf("processing queue = %+v, want task 1", items)
+	}
+	if got := modelInflight(t, m, 1); got != 1 {
+		t.Errorf("model inflight = %d, want 1", got)
+	}
+
+	if err := m.CompleteTask(ctx, 1); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got := modelInflight(t, m, 1); got != 0 {
+		t.Errorf("model inflight after complete = %d, want 0", got)
+	}
+}
+
+func TestDequeueTaskAtModelCapacityLeavesTaskReady(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	if err := m.SetModelCapacity(ctx, 1, 1); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	mustEnqueue(t, m, newTestTask(1, 1, "text-generation"), newTestTask(2, 1, "text-generation"))
+
+	if item, err := m.DequeueTask(ctx, 1, nil); err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("expected task 1, got %+v, %v", item, err)
+	}
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item != nil {
+		t.Fatalf("expected no task at capacity, got task %d", item.TaskID)
+	}
+	if got := readyCount(t, m, m.client, 1); got != 1 {
+		t.Errorf("ready queue has %d tasks, want 1", got)
+	}
+	if got := modelInflight(t, m, 1); got != 1 {
+		t.Errorf("model inflight = %d, want 1", got)
+	}
+}
+
+func TestClaimScriptErrorLeavesNoPartialClaim(t *testing.T) {
+	m, server := newTestManager(t, nil)
+	ctx := context.Background()
+	mustEnqueue(t, m, newTestTask(1, 1, "text-generation"))
+
+	// 处理中队列的键类型错误，脚本在写入时出错
+	server.Set(m.config.Queue.ProcessingQueue, "corrupted")
+
+	if _, err := m.DequeueTask(ctx, 1, nil); err == nil {
+		t.Fatal("expected dequeue error")
+	}
+	if got := readyCount(t, m, m.client, 1); got != 1 {
+		t.Errorf("ready queue has %d tasks, want the task to stay ready", got)
+	}
+	if got := modelInflight(t, m, 1); got != 0 {
+		t.Errorf("model inflight = %d, want no slot taken", got)
+	}
+}
+
+func TestTaskRecoverableAfterCrashFollowingClaim(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	mustEnqueue(t, m, newTestTask(1, 1, "text-generation"))
+
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil {
+		t.Fatalf("expected task 1, got %+v, %v", item, err)
+	}
+
+	// Worker 在脚本执行后崩溃，任务既未完成也未放回；将开始处理时间改到超时之前
+	raws, err := m.client.ZRange(ctx, m.config.Queue.ProcessingQueue, 0, -1).Result()
+	if err != nil || len(raws) != 1 {
+		t.Fatalf("expected one processing item, got %v, %v", raws, err)
+	}
+	m.client.ZAdd(ctx, m.config.Queue.ProcessingQueue, &redis.Z{Score: 0, Member: raws[0]})
+
+	if err := m.CleanupStuckTasks(ctx); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+
+	if items := processingItems(t, m); len(items) != 0 {
+		t.Errorf("processing queue = %+v, want empty", items)
+	}
+	if got := modelInflight(t, m, 1); got != 0 {
+		t.Errorf("model inflight = %d, want slot released", got)
+	}
+	delayed, err := m.client.ZRange(ctx, m.config.Queue.DelayedQueue, 0, -1).Result()
+	if err != nil || len(delayed) != 1 {
+		t.Fatalf("expected task in delayed queue, got %v, %v", delayed, err)
+	}
+}
+
+func TestClaimOnBackendReleasesSlotWhenTaskGone(t *testing.T) {
+	shared := miniredis.RunT(t)
+	backend := miniredis.RunT(t)
+	sharedClient := redis.NewClient(&redis.Options{Addr: shared.Addr()})
+	backendClient := redis.NewClient(&redis.Options{Addr: backend.Addr()})
+	t.Cleanup(func() {
+		sharedClient.Close()
+		backendClient.Close()
+	})
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	clients := &Clients{Default: sharedClient, Backends: map[string]*redis.Client{"gpu": backendClient}}
+	m := NewManager(clients, newTestConfig(), logger)
+	m.SetModelBackend(1, "gpu")
+	ctx := context.Background()
+	mustEnqueue(t, m, newTestTask(1, 1, "text-generation"))
+
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("expected task 1, got %+v, %v", item, err)
+	}
+	if got := modelInflight(t, m, 1); got != 1 {
+		t.Errorf("model inflight = %d, want 1", got)
+	}
+
+	// 任务已被其他实例取走时释放刚占用的名额
+	raw := `{"task_id":2,"model_id":1}`
+	err = m.claimTask(ctx, backendClient, m.readyKey(1, models.TaskPriorityMedium), raw, &QueueItem{TaskID: 2, ModelID: 1})
+	if err != errTaskClaimed {
+		t.Fatalf("claim = %v, want errTaskClaimed", err)
+	}
+	if got := modelInflight(t, m, 1); got != 1 {
+		t.Errorf("model inflight = %d, want 1 after failed claim", got)
+	}
+}
+
+// claimConcurrently 为 modelIDs 中的每个模型各启动一个 goroutine 同时领取任务，返回领取到的任务
+func claimConcurrently(t *testing.T, m *Manager, modelIDs []uint64) []*QueueItem {
+	t.Helper()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed []*QueueItem
+		start   = make(chan struct{})
+	)
+	for _, modelID := range modelIDs {
+		wg.Add(1)
+		go func(modelID uint64) {
+			defer wg.Done()
+			<-start
+			item, err := m.DequeueTask(context.Background(), modelID, nil)
+			if err != nil {
+				t.Errorf("dequeue: %v", err)
+				return
+			}
+			if item != nil {
+				mu.Lock()
+				claimed = append(claimed, item)
+				mu.Unlock()
+			}
+		}(modelID)
+	}
+	close(start)
+	wg.Wait()
+	return claimed
+}
+
+func TestConcurrentClaimsRespectModelCapacity(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	if err := m.SetModelCapacity(ctx, 1, 1); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+
+	const workers = 16
+	modelIDs := make([]uint64, workers)
+	for i := range modelIDs {
+		modelIDs[i] = 1
+		mustEnqueue(t, m, newTestTask(uint64(i+1), 1, ""))
+	}
+
+	// MaxWorkers=1 的模型同时被多个 Worker 领取，只有一个能占到名额
+	claimed := claimConcurrently(t, m, modelIDs)
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d tasks, want 1", len(claimed))
+	}
+	if got := modelInflight(t, m, 1); got != 1 {
+		t.Errorf("model inflight = %d, want 1", got)
+	}
+	if got := readyCount(t, m, m.client, 1); got != workers-1 {
+		t.Errorf("ready queue has %d tasks, want %d", got, workers-1)
+	}
+}
+
+func TestConcurrentClaimsRespectAPIKeyMaxRunning(t *testing.T) {
+	m, _ := newTestManager(t, func(cfg *config.Config) {
+		cfg.Auth.Keys = []config.APIKeyConfig{{Name: "team-a", MaxRunning: 1}}
+	})
+	ctx := context.Background()
+
+	// 同一个 Key 的任务分布在不同模型上，各模型的 Worker 同时领取，Key 只能占用一个名额
+	const workers = 16
+	modelIDs := make([]uint64, workers)
+	for i := range modelIDs {
+		modelIDs[i] = uint64(i + 1)
+		task := newTestTask(uint64(i+1), modelIDs[i], "")
+		task.APIKeyName = "team-a"
+		mustEnqueue(t, m, task)
+	}
+
+	claimed := claimConcurrently(t, m, modelIDs)
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d tasks, want 1", len(claimed))
+	}
+	if got := m.client.HGet(ctx, m.config.Queue.KeyInflightKey, "team-a").Val(); got != "1" {
+		t.Errorf("api key inflight = %q, want 1", got)
+	}
+
+	// 完成后名额释放，其他模型的 Worker 可以领取该 Key 的下一个任务
+	if err := m.CompleteTask(ctx, claimed[0].TaskID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	next := claimConcurrently(t, m, modelIDs)
+	if len(next) != 1 {
+		t.Fatalf("claimed %d tasks after release, want 1", len(next))
+	}
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordExpired 注册过期回调，返回读取已通知过期的任务 ID 的函数
+func recordExpired(m *Manager) func() []uint64 {
+	var mu sync.Mutex
+	var ids []uint64
+	m.AddTaskExpiredHook(func(ctx context.Context, taskID uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, taskID)
+	})
+	return func() []uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]uint64(nil), ids...)
+	}
+}
+
+func TestDequeueDropsExpiredTasks(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	expired := recordExpired(m)
+
+	// 任务 1 排队期间超过截止时间，任务 2 仍在有效期内，任务 3 没有截止时间
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Minute)
+	stale := newTestTask(1, 1, "")
+	stale.Deadline = &past
+	fresh := newTestTask(2, 1, "")
+	fresh.Deadline = &future
+	mustEnqueue(t, m, stale, fresh, newTestTask(3, 1, ""))
+
+	for _, want := range []uint64{2, 3} {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil || item.TaskID != want {
+			t.Fatalf("dequeued %+v (err %v), want task %d", item, err, want)
+		}
+	}
+	// 过期任务从队列中移除，不占用 Worker，并通知取消
+	if item, err := m.DequeueTask(ctx, 1, nil); err != nil || item != nil {
+		t.Fatalf("dequeued %+v (err %v), want empty queue", item, err)
+	}
+	if got := expired(); !sameIDs(got, []uint64{1}) {
+		t.Fatalf("expired tasks = %v, want [1]", got)
+	}
+}
+
+func TestDequeueDropsExpiredTasksOfOtherModels(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	expired := recordExpired(m)
+	past := time.Now().Add(-time.Second)
+	stale := newTestTask(1, 2, "")
+	stale.Deadline = &past
+	mustEnqueue(t, m, stale)
+
+	// 任何模型的 Worker 扫描到过期任务都直接丢弃
+	if item, err := m.DequeueTask(context.Background(), 1, nil); err != nil || item != nil {
+		t.Fatalf("dequeued %+v (err %v), want nothing for model 1", item, err)
+	}
+	if got := expired(); !sameIDs(got, []uint64{1}) {
+		t.Fatalf("expired tasks = %v, want [1]", got)
+	}
+	if got := peekTaskIDs(t, m, stale.Priority); len(got) != 0 {
+		t.Fatalf("queue = %v, want expired task removed", got)
+	}
+}
+
+func TestProcessDelayedTasksDropsExpiredTasks(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	expired := recordExpired(m)
+
+	// 两个任务都已到执行时间，其中一个在延迟期间超过了截止时间
+	past := time.Now().Add(-time.Second)
+	stale := &QueueItem{TaskID: 1, ModelID: 1, Priority: 2, CreatedAt: time.Now(), Deadline: &past}
+	due := &QueueItem{TaskID: 2, ModelID: 1, Priority: 2, CreatedAt: time.Now()}
+	for _, item := range []*QueueItem{stale, due} {
+		if err := m.enqueueAt(ctx, item, past); err != nil {
+			t.Fatalf("enqueue delayed: %v", err)
+		}
+	}
+
+	if err := m.ProcessDelayedTasks(ctx); err != nil {
+		t.Fatalf("ProcessDelayedTasks: %v", err)
+	}
+	if got := peekTaskIDs(t, m, 2); !sameIDs(got, []uint64{2}) {
+		t.Fatalf("ready queue = %v, want only task 2", got)
+	}
+	if n, err := m.client.ZCard(ctx, m.config.Queue.DelayedQueue).Result(); err != nil || n != 0 {
+		t.Fatalf("delayed queue has %d items (err %v), want 0", n, err)
+	}
+	if got := expired(); !sameIDs(got, []uint64{1}) {
+		t.Fatalf("expired tasks = %v, want [1]", got)
+	}
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// GetDegradedMode 获取降级模式状态
+func (m *Manager) GetDegradedMode(ctx context.Context) (*models.DegradedMode, error) {
+	values, err := m.client.HGetAll(ctx, m.getDegradedKey()).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get degraded mode: %w", err)
+	}
+
+	mode := &models.DegradedMode{}
+	if len(values) == 0 {
+		return mode, nil
+	}
+
+	priority, err := strconv.Atoi(values["min_priority"])
+	if err != nil || !models.TaskPriority(priority).IsValid() {
+		return mode, nil
+	}
+
+	mode.Enabled = true
+	mode.MinPriority = models.TaskPriority(priority)
+	mode.Source = models.DegradedModeSource(values["source"])
+	mode.Reason = values["reason"]
+	if since, err := strconv.ParseInt(values["since"], 10, 64); err == nil {
+		t := time.Unix(since, 0)
+		mode.Since = &t
+	}
+
+	return mode, nil
+}
+
+// SetDegradedMode 进入降级模式，低于 minPriority 的任务将保留在队列中不被消费
+func (m *Manager) SetDegradedMode(ctx context.Context, minPriority models.TaskPriority, source models.DegradedModeSource, reason string) error {
+	if !minPriority.IsValid() {
+		return fmt.Errorf("invalid min priority: %d", minPriority)
+	}
+
+	if err := m.client.HSet(ctx, m.getDegradedKey(), map[string]interface{}{
+		"min_priority": int(minPriority),
+		"source":       string(source),
+		"reason":       reason,
+		"since":        time.Now().Unix(),
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to set degraded mode: %w", err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"min_priority": minPriority,
+		"source":       source,
+		"reason":       reason,
+	}).Warn("Degraded mode enabled")
+
+	return nil
+}
+
+// ClearDegradedMode 退出降级模式
+func (m *Manager) ClearDegradedMode(ctx context.Context) error {
+	if err := m.client.Del(ctx, m.getDegradedKey()).Err(); err != nil {
+		return fmt.Errorf("failed to clear degraded mode: %w", err)
+	}
+
+	m.logger.Info("Degraded mode cleared")
+	return nil
+}
+
+// getMinPriority 获取当前允许消费的最低优先级，未降级时为最低优先级
+func (m *Manager) getMinPriority(ctx context.Context) models.TaskPriority {
+	mode, err := m.GetDegradedMode(ctx)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to read degraded mode, assuming normal mode")
+		return models.TaskPriorityLow
+	}
+	if !mode.Enabled {
+		return models.TaskPriorityLow
+	}
+	return mode.MinPriority
+}
+
+// getDegradedKey 获取降级模式状态的键名
+func (m *Manager) getDegradedKey() string {
+	if m.config.Queue.Degraded.Key != "" {
+		return m.config.Queue.Degraded.Key
+	}
+	return "llm_tasks:degraded"
+}
@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestDegradedModeHoldsTasksBelowFloor(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	low := newTestTask(1, 1, "")
+	low.Priority = models.TaskPriorityLow
+	medium := newTestTask(2, 1, "")
+	high := newTestTask(3, 1, "")
+	high.Priority = models.TaskPriorityHigh
+	mustEnqueue(t, m, low, medium, high)
+
+	if err := m.SetDegradedMode(ctx, models.TaskPriorityHigh, models.DegradedModeSourceManual, "maintenance"); err != nil {
+		t.Fatalf("set degraded mode: %v", err)
+	}
+	mode, err := m.GetDegradedMode(ctx)
+	if err != nil {
+		t.Fatalf("get degraded mode: %v", err)
+	}
+	if !mode.Enabled || mode.MinPriority != models.TaskPriorityHigh || mode.Source != models.DegradedModeSourceManual || mode.Since == nil {
+		t.Fatalf("unexpected degraded mode: %+v", mode)
+	}
+
+	// 降级期间只消费高优先级任务，低于下限的任务留在队列中
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil || item.TaskID != high.ID {
+		t.Fatalf("expected high priority task, got %+v, %v", item, err)
+	}
+	if item, err := m.DequeueTask(ctx, 1, nil); err != nil || item != nil {
+		t.Fatalf("expected below-floor tasks to be held, got %+v, %v", item, err)
+	}
+	if got := readyCount(t, m, m.client, 1); got != 1 {
+		t.Fatalf("medium queue has %d tasks, want 1", got)
+	}
+	if got := m.client.ZCard(ctx, m.config.Queue.LowPriorityQueue).Val(); got != 1 {
+		t.Fatalf("low queue has %d tasks, want 1", got)
+	}
+
+	// 退出降级后被保留的任务按优先级恢复消费
+	if err := m.ClearDegradedMode(ctx); err != nil {
+		t.Fatalf("clear degraded mode: %v", err)
+	}
+	got := map[uint64]bool{}
+	for i := 0; i < 2; i++ {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil {
+			t.Fatalf("expected held task after recovery, got %+v, %v", item, err)
+		}
+		got[item.TaskID] = true
+	}
+	if !got[low.ID] || !got[medium.ID] {
+		t.Fatalf("released tasks = %v, want %d and %d", got, low.ID, medium.ID)
+	}
+}
+
+func TestSetDegradedModeRejectsInvalidPriority(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	if err := m.SetDegradedMode(ctx, models.TaskPriority(7), models.DegradedModeSourceManual, ""); err == nil {
+		t.Fatal("expected error for invalid min priority")
+	}
+	mode, err := m.GetDegradedMode(ctx)
+	if err != nil {
+		t.Fatalf("get degraded mode: %v", err)
+	}
+	if mode.Enabled {
+		t.Fatalf("expected normal mode, got %+v", mode)
+	}
+}
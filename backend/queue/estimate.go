@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CountAhead 统计模型的就绪队列中排在任务前面的任务数：更高优先级队列中的全部任务，
+// 加上同一优先级队列中创建时间更早的任务。共享队列中混有多个模型的任务，只统计同一模型的
+func (m *Manager) CountAhead(ctx context.Context, task *models.Task) (int64, error) {
+	client := m.clientFor(task.ModelID)
+
+	var ahead int64
+	for priority := models.TaskPriorityHigh; priority >= task.Priority && priority >= models.TaskPriorityLow; priority-- {
+		queueKey := m.readyKey(task.ModelID, priority)
+		max := "+inf"
+		if priority == task.Priority {
+			// 同一优先级内按创建时间和 ID 出队，score 小于本任务的排在前面
+			max = "(" + strconv.FormatInt(readyScore(task.CreatedAt, task.ID), 10)
+		}
+
+		results, err := client.ZRangeByScore(ctx, queueKey, &redis.ZRangeBy{Min: "-inf", Max: max}).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan %s: %w", queueKey, err)
+		}
+		for _, raw := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(raw), &item); err != nil {
+				continue
+			}
+			if item.ModelID == task.ModelID && item.TaskID != task.ID {
+				ahead++
+			}
+		}
+	}
+	return ahead, nil
+}
+
+// EstimateWait 估算任务开始执行前的等待时间：排在前面的任务数 × 平均处理时间 ÷ Worker 数。
+// 这是粗略的估计，假设 Worker 全部空闲可用、后续不会有更高优先级的任务插队，且不考虑延迟队列、
+// 模型并发上限和请求频率限制；avgProcessing 或 workers 不大于 0 时无法估算，返回 false
+func (m *Manager) EstimateWait(ctx context.Context, task *models.Task, avgProcessing time.Duration, workers int) (time.Duration, bool, error) {
+	ahead, err := m.CountAhead(ctx, task)
+	if err != nil {
+		return 0, false, err
+	}
+	if ahead == 0 {
+		return 0, true, nil
+	}
+	if avgProcessing <= 0 || workers <= 0 {
+		return 0, false, nil
+	}
+	return time.Duration(ahead) * avgProcessing / time.Duration(workers), true, nil
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestEstimateWait(t *testing.T) {
+	tests := []struct {
+		name          string
+		configure     func(*config.Config)
+		priority      models.TaskPriority
+		avgProcessing time.Duration
+		workers       int
+		wantAhead     int64
+		wantWait      time.Duration
+		wantOK        bool
+	}{
+		// 高优先级 1 个 + 同优先级更早的 2 个，每个 2 秒、2 个 Worker 并行
+		{"medium task", nil, models.TaskPriorityMedium, 2 * time.Second, 2, 3, 3 * time.Second, true},
+		{"isolated model queues", withModelIsolation, models.TaskPriorityMedium, 2 * time.Second, 2, 3, 3 * time.Second, true},
+		// 低优先级任务排在所有中、高优先级任务之后
+		{"low task", nil, models.TaskPriorityLow, time.Second, 1, 4, 4 * time.Second, true},
+		// 高优先级队列中没有更早的任务时不需要等待，不依赖历史数据
+		{"nothing ahead", nil, models.TaskPriorityHigh, 0, 0, 0, 0, true},
+		// 前面有任务但缺少平均处理时间或 Worker 时无法估算
+		{"no history", nil, models.TaskPriorityMedium, 0, 2, 3, 0, false},
+		{"no workers", nil, models.TaskPriorityMedium, time.Second, 0, 3, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, tt.configure)
+			ctx := context.Background()
+
+			// 模型 1：高优先级任务 1，中优先级任务 2、3，以及待估算任务之后创建的任务 5；模型 2 的任务不计入
+			high := newTestTask(1, 1, "")
+			high.Priority = models.TaskPriorityHigh
+			other := newTestTask(6, 2, "")
+			other.Priority = models.TaskPriorityHigh
+			mustEnqueue(t, m, high, newTestTask(2, 1, ""), newTestTask(3, 1, ""), newTestTask(5, 1, ""), other)
+
+			task := newTestTask(4, 1, "")
+			task.Priority = tt.priority
+			if tt.priority == models.TaskPriorityHigh {
+				// 比任务 1 更早创建
+				task.CreatedAt = high.CreatedAt.Add(-time.Second)
+			}
+			mustEnqueue(t, m, task)
+
+			ahead, err := m.CountAhead(ctx, task)
+			if err != nil {
+				t.Fatalf("CountAhead: %v", err)
+			}
+			if ahead != tt.wantAhead {
+				t.Fatalf("ahead = %d, want %d", ahead, tt.wantAhead)
+			}
+			wait, ok, err := m.EstimateWait(ctx, task, tt.avgProcessing, tt.workers)
+			if err != nil {
+				t.Fatalf("EstimateWait: %v", err)
+			}
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Fatalf("wait = %v (ok %v), want %v (ok %v)", wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
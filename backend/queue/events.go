@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PublishTaskStatusEvent 发布任务状态变更事件，webhook、Dashboard、指标等订阅者各自独立消费
+func (m *Manager) PublishTaskStatusEvent(ctx context.Context, event *models.TaskEvent) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task event: %w", err)
+	}
+
+	if err := m.client.Publish(ctx, m.getEventChannel(), eventBytes).Err(); err != nil {
+		return fmt.Errorf("failed to publish task event: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeTaskStatusEvents 订阅任务状态变更事件，调用方负责关闭返回的 PubSub
+func (m *Manager) SubscribeTaskStatusEvents(ctx context.Context) (*redis.PubSub, error) {
+	pubsub := m.client.Subscribe(ctx, m.getEventChannel())
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe task events: %w", err)
+	}
+
+	return pubsub, nil
+}
+
+// getEventChannel 获取任务状态变更事件频道名
+func (m *Manager) getEventChannel() string {
+	if m.config.Queue.EventChannel != "" {
+		return m.config.Queue.EventChannel
+	}
+	return "llm_tasks:events"
+}
@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestConfig 返回与 config.yaml 一致的队列键名配置
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		CancelChannel:       "llm_tasks:cancel",
+		EventChannel:        "llm_tasks:events",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+		TaskTimeout:         5 * time.Minute,
+		RetryDelay:          time.Minute,
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	return cfg
+}
+
+// newTestManager 创建连接 miniredis 的队列管理器，configure 可在创建前修改配置
+func newTestManager(t *testing.T, configure func(*config.Config)) (*Manager, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewManager(&Clients{Default: client}, cfg, logger), server
+}
+
+// newTestTask 构造一个待入队的任务，创建时间按 id 递增，保证出队顺序与 id 一致
+func newTestTask(id, modelID uint64, taskType string) *models.Task {
+	task := &models.Task{
+		ModelID:  modelID,
+		Type:     taskType,
+		Priority: models.TaskPriorityMedium,
+		Status:   models.TaskStatusPending,
+	}
+	task.ID = id
+	task.CreatedAt = time.Unix(1700000000, 0).Add(time.Duration(id) * time.Millisecond)
+	return task
+}
+
+// mustEnqueue 将任务加入队列，失败时终止测试
+func mustEnqueue(t *testing.T, m *Manager, tasks ...*models.Task) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := m.EnqueueTask(context.Background(), task); err != nil {
+			t.Fatalf("enqueue task %d: %v", task.ID, err)
+		}
+	}
+}
+
+// processingItems 读取处理中队列中的所有任务
+func processingItems(t *testing.T, m *Manager) []QueueItem {
+	t.Helper()
+	raws, err := m.client.ZRange(context.Background(), m.config.Queue.ProcessingQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("read processing queue: %v", err)
+	}
+	items := make([]QueueItem, 0, len(raws))
+	for _, raw := range raws {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			t.Fatalf("unmarshal processing item: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
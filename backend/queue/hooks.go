@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"context"
+
+	"llm-scheduler/models"
+)
+
+// TaskCompletedHook 任务进入终态后的回调
+type TaskCompletedHook func(ctx context.Context, taskID uint64, status models.TaskStatus)
+
+// AddTaskCompletedHook 注册任务进入终态后的回调
+func (m *Manager) AddTaskCompletedHook(hook TaskCompletedHook) {
+	m.hooksMutex.Lock()
+	defer m.hooksMutex.Unlock()
+	m.completedHooks = append(m.completedHooks, hook)
+}
+
+// OnTaskCompleted 通知任务已进入终态（完成、失败或取消），依次执行已注册的回调
+func (m *Manager) OnTaskCompleted(ctx context.Context, taskID uint64, status models.TaskStatus) {
+	m.hooksMutex.RLock()
+	hooks := make([]TaskCompletedHook, len(m.completedHooks))
+	copy(hooks, m.completedHooks)
+	m.hooksMutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, taskID, status)
+	}
+}
+
+// TaskEscalatedHook 任务反复进入延迟队列、被升级给人工处理时的回调
+type TaskEscalatedHook func(ctx context.Context, taskID uint64, delayCount int)
+
+// AddTaskEscalatedHook 注册任务升级回调
+func (m *Manager) AddTaskEscalatedHook(hook TaskEscalatedHook) {
+	m.hooksMutex.Lock()
+	defer m.hooksMutex.Unlock()
+	m.escalatedHooks = append(m.escalatedHooks, hook)
+}
+
+// OnTaskEscalated 通知任务已被升级，依次执行已注册的回调
+func (m *Manager) OnTaskEscalated(ctx context.Context, taskID uint64, delayCount int) {
+	m.hooksMutex.RLock()
+	hooks := make([]TaskEscalatedHook, len(m.escalatedHooks))
+	copy(hooks, m.escalatedHooks)
+	m.hooksMutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, taskID, delayCount)
+	}
+}
+
+// TaskExpiredHook 排队中的任务超过截止时间、被从队列丢弃时的回调
+type TaskExpiredHook func(ctx context.Context, taskID uint64)
+
+// AddTaskExpiredHook 注册任务过期回调
+func (m *Manager) AddTaskExpiredHook(hook TaskExpiredHook) {
+	m.hooksMutex.Lock()
+	defer m.hooksMutex.Unlock()
+	m.expiredHooks = append(m.expiredHooks, hook)
+}
+
+// OnTaskExpired 通知任务已过期，依次执行已注册的回调
+func (m *Manager) OnTaskExpired(ctx context.Context, taskID uint64) {
+	m.hooksMutex.RLock()
+	hooks := make([]TaskExpiredHook, len(m.expiredHooks))
+	copy(hooks, m.expiredHooks)
+	m.hooksMutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, taskID)
+	}
+}
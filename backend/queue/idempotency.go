@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// idempotencyPending 幂等键已占用但任务尚未创建完成时的占位值
+const idempotencyPending = "pending"
+
+// ErrIdempotencyKeyMismatch 幂等键已被请求内容不同的请求使用
+var ErrIdempotencyKeyMismatch = errors.New("idempotency key reused with a different request")
+
+// ReserveIdempotencyKey 占用幂等键。返回 reserved 为 true 表示由调用方创建任务；
+// 否则 taskID 为之前请求创建的任务，taskID 为 0 表示之前的请求仍在处理中。
+// fingerprint 为请求内容的摘要，与首次请求不一致时返回 ErrIdempotencyKeyMismatch
+func (m *Manager) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string) (taskID uint64, reserved bool, err error) {
+	redisKey := m.getIdempotencyKey(key)
+
+	ok, err := m.client.SetNX(ctx, redisKey, idempotencyPending, m.getIdempotencyTTL()).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if ok {
+		return 0, true, nil
+	}
+
+	value, err := m.client.Get(ctx, redisKey).Result()
+	if err == redis.Nil {
+		// 占位刚好过期，按仍在处理中返回，由客户端稍后重试
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if value == idempotencyPending {
+		return 0, false, nil
+	}
+
+	// 值为 "任务 ID:请求摘要"，旧版本只保存了任务 ID，此时不校验请求内容
+	idPart, storedFingerprint, _ := strings.Cut(value, ":")
+	taskID, err = strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid idempotency key value %q: %w", value, err)
+	}
+	if storedFingerprint != "" && storedFingerprint != fingerprint {
+		return taskID, false, ErrIdempotencyKeyMismatch
+	}
+	return taskID, false, nil
+}
+
+// CompleteIdempotencyKey 记录幂等键对应的任务 ID 和请求摘要
+func (m *Manager) CompleteIdempotencyKey(ctx context.Context, key, fingerprint string, taskID uint64) error {
+	value := strconv.FormatUint(taskID, 10) + ":" + fingerprint
+	if err := m.client.Set(ctx, m.getIdempotencyKey(key), value, m.getIdempotencyTTL()).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey 任务创建失败时释放幂等键，允许客户端重试
+func (m *Manager) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return m.client.Del(ctx, m.getIdempotencyKey(key)).Err()
+}
+
+// getIdempotencyKey 获取幂等键在 Redis 中的键名
+func (m *Manager) getIdempotencyKey(key string) string {
+	prefix := m.config.Queue.IdempotencyKey
+	if prefix == "" {
+		prefix = "llm_tasks:idempotency"
+	}
+	return prefix + ":" + key
+}
+
+// getIdempotencyTTL 获取幂等键的保留时间
+func (m *Manager) getIdempotencyTTL() time.Duration {
+	if m.config.Queue.IdempotencyTTL > 0 {
+		return m.config.Queue.IdempotencyTTL
+	}
+	return 24 * time.Hour
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// errModelAtCapacity 模型处理中的任务数已达到并发上限
+	errModelAtCapacity = errors.New("model at capacity")
+	// errKeyAtCapacity API Key 处理中的任务数已达到 max_running
+	errKeyAtCapacity = errors.New("api key at capacity")
+)
+
+// acquireInflightScript 未超过模型并发上限时原子地增加处理中计数，超限返回 -1
+var acquireInflightScript = redis.NewScript(`
+local capacity = tonumber(redis.call('HGET', KEYS[2], ARGV[1]) or '0')
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if capacity > 0 and current >= capacity then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`)
+
+// acquireKeyInflightScript 未超过 ARGV[2] 时原子地增加 API Key 的处理中计数，超限返回 -1
+var acquireKeyInflightScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if current >= tonumber(ARGV[2]) then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`)
+
+// releaseInflightScript 减少处理中计数，归零时删除字段
+var releaseInflightScript = redis.NewScript(`
+local current = redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+if current <= 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return current
+`)
+
+// SetModelCapacity 设置模型的并发上限，capacity <= 0 表示不限制
+func (m *Manager) SetModelCapacity(ctx context.Context, modelID uint64, capacity int) error {
+	field := strconv.FormatUint(modelID, 10)
+	if capacity <= 0 {
+		return m.client.HDel(ctx, m.getCapacityKey(), field).Err()
+	}
+	return m.client.HSet(ctx, m.getCapacityKey(), field, capacity).Err()
+}
+
+// GetModelInflight 获取各模型处理中的任务数
+func (m *Manager) GetModelInflight(ctx context.Context) (map[uint64]int64, error) {
+	values, err := m.client.HGetAll(ctx, m.getInflightKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model inflight counts: %w", err)
+	}
+
+	inflight := make(map[uint64]int64, len(values))
+	for field, value := range values {
+		modelID, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		inflight[modelID] = count
+	}
+
+	return inflight, nil
+}
+
+// acquireInflight 为模型占用一个处理中名额
+func (m *Manager) acquireInflight(ctx context.Context, modelID uint64) error {
+	keys := []string{m.getInflightKey(), m.getCapacityKey()}
+	result, err := acquireInflightScript.Run(ctx, m.client, keys, modelID).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to acquire inflight slot: %w", err)
+	}
+	if result < 0 {
+		return errModelAtCapacity
+	}
+	return nil
+}
+
+// releaseInflight 释放模型的一个处理中名额
+func (m *Manager) releaseInflight(ctx context.Context, modelID uint64) {
+	keys := []string{m.getInflightKey()}
+	if err := releaseInflightScript.Run(ctx, m.client, keys, modelID).Err(); err != nil {
+		m.logger.WithError(err).WithField("model_id", modelID).Error("Failed to release inflight slot")
+	}
+}
+
+// acquireKeyInflight 为 API Key 占用一个处理中名额，没有 Key 或 Key 不限制时不计数
+func (m *Manager) acquireKeyInflight(ctx context.Context, apiKey string) error {
+	if apiKey == "" {
+		return nil
+	}
+	limit := m.config.Auth.MaxRunningFor(apiKey)
+	if limit <= 0 {
+		return nil
+	}
+
+	keys := []string{m.getKeyInflightKey()}
+	result, err := acquireKeyInflightScript.Run(ctx, m.client, keys, apiKey, limit).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to acquire api key slot: %w", err)
+	}
+	if result < 0 {
+		return errKeyAtCapacity
+	}
+	return nil
+}
+
+// releaseKeyInflight 释放 API Key 的一个处理中名额。上限可能在任务执行期间被修改，
+// 计数归零时字段被删除，多释放不会变为负数
+func (m *Manager) releaseKeyInflight(ctx context.Context, apiKey string) {
+	if apiKey == "" {
+		return
+	}
+	keys := []string{m.getKeyInflightKey()}
+	if err := releaseInflightScript.Run(ctx, m.client, keys, apiKey).Err(); err != nil {
+		m.logger.WithError(err).WithField("api_key", apiKey).Error("Failed to release api key slot")
+	}
+}
+
+// getInflightKey 获取模型处理中计数的键名
+func (m *Manager) getInflightKey() string {
+	if m.config.Queue.InflightKey != "" {
+		return m.config.Queue.InflightKey
+	}
+	return "llm_tasks:model_inflight"
+}
+
+// getKeyInflightKey 获取 API Key 处理中计数的键名
+func (m *Manager) getKeyInflightKey() string {
+	if m.config.Queue.KeyInflightKey != "" {
+		return m.config.Queue.KeyInflightKey
+	}
+	return "llm_tasks:key_inflight"
+}
+
+// getCapacityKey 获取模型并发上限的键名
+func (m *Manager) getCapacityKey() string {
+	if m.config.Queue.CapacityKey != "" {
+		return m.config.Queue.CapacityKey
+	}
+	return "llm_tasks:model_capacity"
+}
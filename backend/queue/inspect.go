@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// PeekQueue 查看指定优先级队列中即将出队的前 limit 个任务，不会移除任务。
+// 多个队列后端（以及隔离模式下各模型队列）的任务按入队时间合并，无法解析的条目直接跳过
+func (m *Manager) PeekQueue(ctx context.Context, priority models.TaskPriority, limit int) ([]QueueItem, error) {
+	items := []QueueItem{}
+	if limit <= 0 {
+		return items, nil
+	}
+
+	for name, client := range m.allClients() {
+		modelIDs, err := m.queueModels(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, modelID := range modelIDs {
+			queueKey := m.readyKey(modelID, priority)
+			// 按创建时间从早到晚出队
+			results, err := client.ZRange(ctx, queueKey, 0, int64(limit-1)).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to peek %s on backend %s: %w", queueKey, name, err)
+			}
+
+			for _, raw := range results {
+				var item QueueItem
+				if err := json.Unmarshal([]byte(raw), &item); err != nil {
+					m.logger.WithError(err).WithField("queue", queueKey).Warn("Skipping invalid queue item")
+					continue
+				}
+				items = append(items, item)
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// PurgeQueue 清空指定优先级队列并返回被移除的任务，只允许清空三个优先级队列，
+// 不会触及处理中队列和延迟队列。各后端的读取与删除在同一事务中执行，期间入队的任务不会丢失
+func (m *Manager) PurgeQueue(ctx context.Context, priority models.TaskPriority) ([]QueueItem, error) {
+	if !priority.IsValid() {
+		return nil, fmt.Errorf("invalid priority: %d", priority)
+	}
+	queueKey := m.getQueueKey(priority)
+	if queueKey == "" || queueKey == m.config.Queue.ProcessingQueue || queueKey == m.config.Queue.DelayedQueue {
+		return nil, fmt.Errorf("refusing to purge %q: not a priority queue", queueKey)
+	}
+
+	items := []QueueItem{}
+	for name, client := range m.allClients() {
+		modelIDs, err := m.queueModels(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, modelID := range modelIDs {
+			readyKey := m.readyKey(modelID, priority)
+			var zrange *redis.StringSliceCmd
+			if _, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				zrange = pipe.ZRange(ctx, readyKey, 0, -1)
+				pipe.Del(ctx, readyKey)
+				return nil
+			}); err != nil {
+				return nil, fmt.Errorf("failed to purge %s on backend %s: %w", readyKey, name, err)
+			}
+
+			for _, raw := range zrange.Val() {
+				var item QueueItem
+				if err := json.Unmarshal([]byte(raw), &item); err != nil {
+					m.logger.WithError(err).WithField("queue", readyKey).Warn("Dropping invalid queue item")
+					continue
+				}
+				items = append(items, item)
+			}
+		}
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"queue":   queueKey,
+		"removed": len(items),
+	}).Warn("Queue purged")
+
+	return items, nil
+}
+
+// ProcessingTaskIDs 返回各后端处理中队列里的任务 ID
+func (m *Manager) ProcessingTaskIDs(ctx context.Context) (map[uint64]bool, error) {
+	ids := make(map[uint64]bool)
+	for name, client := range m.allClients() {
+		results, err := client.ZRange(ctx, m.config.Queue.ProcessingQueue, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list processing tasks on backend %s: %w", name, err)
+		}
+		for _, result := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(result), &item); err != nil {
+				continue
+			}
+			ids[item.TaskID] = true
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestPeekQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+		priority  models.TaskPriority
+		limit     int
+		want      []uint64
+	}{
+		{"limit smaller than queue", nil, models.TaskPriorityMedium, 2, []uint64{1, 2}},
+		{"limit larger than queue", nil, models.TaskPriorityMedium, 10, []uint64{1, 2, 4}},
+		{"zero limit", nil, models.TaskPriorityMedium, 0, nil},
+		{"other priority", nil, models.TaskPriorityHigh, 10, []uint64{3}},
+		{"empty queue", nil, models.TaskPriorityLow, 10, nil},
+		{"isolated queues merged by creation time", withModelIsolation, models.TaskPriorityMedium, 2, []uint64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, tt.configure)
+			ctx := context.Background()
+
+			// 任务 2 属于另一个模型，任务 3 为高优先级
+			high := newTestTask(3, 1, "")
+			high.Priority = models.TaskPriorityHigh
+			mustEnqueue(t, m, newTestTask(4, 1, ""), newTestTask(1, 1, ""), newTestTask(2, 2, ""), high)
+
+			items, err := m.PeekQueue(ctx, tt.priority, tt.limit)
+			if err != nil {
+				t.Fatalf("PeekQueue: %v", err)
+			}
+			if items == nil {
+				t.Fatal("PeekQueue returned nil, want empty slice")
+			}
+			var got []uint64
+			for _, item := range items {
+				got = append(got, item.TaskID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("peeked %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("peeked %v, want %v", got, tt.want)
+				}
+			}
+
+			// 查看不会移除任务
+			status, err := m.GetQueueStatus(ctx)
+			if err != nil {
+				t.Fatalf("GetQueueStatus: %v", err)
+			}
+			if status.MediumPriorityCount != 3 || status.HighPriorityCount != 1 || status.LowPriorityCount != 0 {
+				t.Fatalf("queue status after peek = %+v", status)
+			}
+		})
+	}
+}
+
+func TestPeekQueueSkipsInvalidItems(t *testing.T) {
+	m, server := newTestManager(t, nil)
+	mustEnqueue(t, m, newTestTask(1, 1, ""), newTestTask(2, 1, ""))
+	if _, err := server.ZAdd(m.config.Queue.MediumPriorityQueue, 0, "not-json"); err != nil {
+		t.Fatalf("add invalid item: %v", err)
+	}
+
+	items, err := m.PeekQueue(context.Background(), models.TaskPriorityMedium, 10)
+	if err != nil {
+		t.Fatalf("PeekQueue: %v", err)
+	}
+	if len(items) != 2 || items[0].TaskID != 1 || items[1].TaskID != 2 {
+		t.Fatalf("peeked %+v, want tasks 1 and 2", items)
+	}
+}
@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// sharedQueues 共享模式下 queueModels 返回的占位模型 ID，readyKey 忽略模型 ID
+const sharedQueues uint64 = 0
+
+// isolated 是否开启按模型隔离队列
+func (m *Manager) isolated() bool {
+	return m.config.Queue.ModelIsolation
+}
+
+// readyKey 获取模型指定优先级的就绪队列键：共享模式下所有模型共用三个优先级队列，
+// 隔离模式下每个模型一组队列（<priority_queue>:model:<id>），一个模型的积压不会影响其他模型出队
+func (m *Manager) readyKey(modelID uint64, priority models.TaskPriority) string {
+	queueKey := m.getQueueKey(priority)
+	if !m.isolated() {
+		return queueKey
+	}
+	return fmt.Sprintf("%s:model:%d", queueKey, modelID)
+}
+
+// pushReady 将任务加入就绪队列，隔离模式下同时把模型登记到 model_queues_key，用于遍历各模型的队列
+func (m *Manager) pushReady(ctx context.Context, client *redis.Client, queueKey string, modelID uint64, member *redis.Z) error {
+	if !m.isolated() {
+		return client.ZAdd(ctx, queueKey, member).Err()
+	}
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, queueKey, member)
+		pipe.SAdd(ctx, m.config.Queue.ModelQueuesKey, modelID)
+		return nil
+	})
+	return err
+}
+
+// queueModels 获取后端中拥有就绪队列的模型 ID，与 readyKey 配合遍历全部就绪队列。
+// 共享模式下只返回 sharedQueues
+func (m *Manager) queueModels(ctx context.Context, client *redis.Client) ([]uint64, error) {
+	if !m.isolated() {
+		return []uint64{sharedQueues}, nil
+	}
+	return m.registeredModels(ctx, client)
+}
+
+// registeredModels 读取 model_queues_key 中登记的模型 ID
+func (m *Manager) registeredModels(ctx context.Context, client *redis.Client) ([]uint64, error) {
+	members, err := client.SMembers(ctx, m.config.Queue.ModelQueuesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model queues: %w", err)
+	}
+	ids := make([]uint64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// RebalanceReadyQueues 切换队列隔离模式后迁移已排队的任务，启动时在 MigrateReadyQueues 之后调用：
+// 隔离模式下把共享队列中的任务移入各模型的队列，共享模式下把各模型队列中的任务移回共享队列。
+// 所有实例需使用相同的模式，否则任务会在两种队列间来回迁移
+func (m *Manager) RebalanceReadyQueues(ctx context.Context) error {
+	for name, client := range m.allClients() {
+		moved, err := m.rebalance(ctx, client)
+		if err != nil {
+			return fmt.Errorf("failed to rebalance ready queues on backend %s: %w", name, err)
+		}
+		if moved > 0 {
+			m.logger.WithFields(logrus.Fields{
+				"backend":   name,
+				"items":     moved,
+				"isolation": m.isolated(),
+			}).Info("Moved queued tasks after queue isolation change")
+		}
+	}
+	return nil
+}
+
+// rebalance 迁移单个后端中不属于当前模式的就绪队列
+func (m *Manager) rebalance(ctx context.Context, client *redis.Client) (int, error) {
+	moved := 0
+	if m.isolated() {
+		for _, priority := range priorities {
+			n, err := m.moveReady(ctx, client, m.getQueueKey(priority), priority)
+			if err != nil {
+				return moved, err
+			}
+			moved += n
+		}
+		return moved, nil
+	}
+
+	if m.config.Queue.ModelQueuesKey == "" {
+		return 0, nil
+	}
+	modelIDs, err := m.registeredModels(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	for _, modelID := range modelIDs {
+		for _, priority := range priorities {
+			fromKey := fmt.Sprintf("%s:model:%d", m.getQueueKey(priority), modelID)
+			n, err := m.moveReady(ctx, client, fromKey, priority)
+			if err != nil {
+				return moved, err
+			}
+			moved += n
+		}
+		if err := client.SRem(ctx, m.config.Queue.ModelQueuesKey, modelID).Err(); err != nil {
+			return moved, fmt.Errorf("failed to unregister model queue: %w", err)
+		}
+	}
+	return moved, nil
+}
+
+// moveReady 将 fromKey 中的任务逐个移到当前模式下对应的就绪队列，保持原 score
+func (m *Manager) moveReady(ctx context.Context, client *redis.Client, fromKey string, priority models.TaskPriority) (int, error) {
+	results, err := client.ZRangeWithScores(ctx, fromKey, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", fromKey, err)
+	}
+
+	moved := 0
+	for _, z := range results {
+		raw, _ := z.Member.(string)
+
+		var item QueueItem
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			continue
+		}
+		toKey := m.readyKey(item.ModelID, priority)
+		if toKey == fromKey {
+			continue
+		}
+
+		// 移除成功才加入新队列，返回 0 说明任务已被 Worker 取走
+		removed, err := client.ZRem(ctx, fromKey, raw).Result()
+		if err != nil {
+			return moved, fmt.Errorf("failed to move task from %s: %w", fromKey, err)
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := m.pushReady(ctx, client, toKey, item.ModelID, &redis.Z{Score: z.Score, Member: raw}); err != nil {
+			if pushErr := client.ZAdd(ctx, fromKey, &redis.Z{Score: z.Score, Member: raw}).Err(); pushErr != nil {
+				m.logger.WithError(pushErr).WithField("task_id", item.TaskID).Error("Failed to restore task after move failure")
+			}
+			return moved, fmt.Errorf("failed to move task to %s: %w", toKey, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
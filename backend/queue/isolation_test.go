@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// withModelIsolation 开启按模型隔离队列
+func withModelIsolation(cfg *config.Config) {
+	cfg.Queue.ModelIsolation = true
+}
+
+func TestIsolatedModelQueueIgnoresOtherModelBacklog(t *testing.T) {
+	m, _ := newTestManager(t, withModelIsolation)
+	ctx := context.Background()
+
+	// 模型 1 积压大量高优先级任务，模型 2 随后提交一个低优先级任务
+	backlog := 5 * dequeueScanLimit
+	for id := uint64(1); id <= uint64(backlog); id++ {
+		task := newTestTask(id, 1, "")
+		task.Priority = models.TaskPriorityHigh
+		mustEnqueue(t, m, task)
+	}
+	late := newTestTask(uint64(backlog)+1, 2, "")
+	late.Priority = models.TaskPriorityLow
+	mustEnqueue(t, m, late)
+
+	// 模型 2 的队列中只有自己的任务，排在前面的任务数为 0
+	if got := m.client.ZCard(ctx, m.readyKey(2, models.TaskPriorityLow)).Val(); got != 1 {
+		t.Fatalf("model 2 low queue has %d tasks, want 1", got)
+	}
+	if got := m.client.ZCard(ctx, m.readyKey(2, models.TaskPriorityHigh)).Val(); got != 0 {
+		t.Fatalf("model 2 high queue has %d tasks, want 0", got)
+	}
+	ahead, err := m.CountAhead(ctx, late)
+	if err != nil {
+		t.Fatalf("count ahead: %v", err)
+	}
+	if ahead != 0 {
+		t.Fatalf("expected no tasks ahead of model 2's task, got %d", ahead)
+	}
+
+	// 模型 2 的 Worker 直接取到任务，模型 1 的积压原样保留
+	item, err := m.DequeueTask(ctx, 2, nil)
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item == nil || item.TaskID != late.ID {
+		t.Fatalf("expected task %d, got %+v", late.ID, item)
+	}
+	if got := m.client.ZCard(ctx, m.readyKey(1, models.TaskPriorityHigh)).Val(); got != int64(backlog) {
+		t.Fatalf("model 1 backlog = %d, want %d", got, backlog)
+	}
+
+	// 共享队列中没有任何任务
+	for _, priority := range priorities {
+		if got := m.client.ZCard(ctx, m.getQueueKey(priority)).Val(); got != 0 {
+			t.Errorf("shared %s has %d tasks, want 0", m.getQueueKey(priority), got)
+		}
+	}
+}
+
+func TestRebalanceReadyQueuesMovesSharedBacklog(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	mustEnqueue(t, m, newTestTask(1, 1, ""), newTestTask(2, 2, ""), newTestTask(3, 1, ""))
+
+	// 开启隔离后迁移：共享队列中的任务按模型分到各自的队列，保持原顺序
+	m.config.Queue.ModelIsolation = true
+	if err := m.RebalanceReadyQueues(ctx); err != nil {
+		t.Fatalf("rebalance: %v", err)
+	}
+	if got := m.client.ZCard(ctx, m.config.Queue.MediumPriorityQueue).Val(); got != 0 {
+		t.Fatalf("shared queue has %d tasks after rebalance, want 0", got)
+	}
+	for _, want := range []struct{ modelID, taskID uint64 }{{1, 1}, {1, 3}, {2, 2}} {
+		item, err := m.DequeueTask(ctx, want.modelID, nil)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if item == nil || item.TaskID != want.taskID {
+			t.Fatalf("model %d: expected task %d, got %+v", want.modelID, want.taskID, item)
+		}
+	}
+
+	// 关闭隔离后迁移回共享队列
+	mustEnqueue(t, m, newTestTask(4, 1, ""), newTestTask(5, 2, ""))
+	m.config.Queue.ModelIsolation = false
+	if err := m.RebalanceReadyQueues(ctx); err != nil {
+		t.Fatalf("rebalance: %v", err)
+	}
+	if got := m.client.ZCard(ctx, m.config.Queue.MediumPriorityQueue).Val(); got != 2 {
+		t.Fatalf("shared queue has %d tasks after rebalance, want 2", got)
+	}
+	if got := m.client.SCard(ctx, m.config.Queue.ModelQueuesKey).Val(); got != 0 {
+		t.Fatalf("model queues still registered: %d", got)
+	}
+}
@@ -3,21 +3,36 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"llm-scheduler/config"
 	"llm-scheduler/models"
+	"llm-scheduler/tracing"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Manager 队列管理器
 type Manager struct {
-	client *redis.Client
-	config *config.Config
-	logger *logrus.Logger
+	client         *redis.Client
+	backends       map[string]*redis.Client
+	shards         []*redis.Client
+	modelBackends  map[uint64]string
+	backendsMutex  sync.RWMutex
+	instanceID     string
+	completedHooks []TaskCompletedHook
+	escalatedHooks []TaskEscalatedHook
+	expiredHooks   []TaskExpiredHook
+	cursor         priorityCursor
+	hooksMutex     sync.RWMutex
+	config         *config.Config
+	logger         *logrus.Logger
 }
 
 // QueueItem 队列项目
@@ -26,35 +41,108 @@ type QueueItem struct {
 	ModelID   uint64    `json:"model_id"`
 	Priority  int       `json:"priority"`
 	CreatedAt time.Time `json:"created_at"`
+	// DelayCount 任务进入延迟队列的次数
+	DelayCount int `json:"delay_count,omitempty"`
+	// TraceID 创建任务的请求关联 ID，Worker 日志中携带
+	TraceID string `json:"trace_id,omitempty"`
+	// TraceParent 入队时的 W3C 追踪上下文，Worker 执行任务的 span 以其为父 span
+	TraceParent string `json:"traceparent,omitempty"`
+	// PromotedAt 因等待过久被提升优先级的时间，之后的等待时间从该时间开始计算
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+	// Deadline 任务的截止时间，超过后扫描队列时直接丢弃并通知取消
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// APIKey 创建任务的 API Key 名称，用于限制每个 Key 同时执行的任务数
+	APIKey string `json:"api_key,omitempty"`
+	// Type 任务类型，用于只领取部分类型的 Worker 过滤任务
+	Type string `json:"type,omitempty"`
 }
 
-// NewManager 创建队列管理器
-func NewManager(client *redis.Client, cfg *config.Config, logger *logrus.Logger) *Manager {
+// matchesType 判断任务是否属于指定类型之一，types 为空表示不限类型
+func (item *QueueItem) matchesType(types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if item.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// expired 任务设置了截止时间且已超过
+func (item *QueueItem) expired(now time.Time) bool {
+	return item.Deadline != nil && now.After(*item.Deadline)
+}
+
+// NewManager 创建队列管理器，clients 为共享后端、按名称配置的独立队列后端和分片的连接
+func NewManager(clients *Clients, cfg *config.Config, logger *logrus.Logger) *Manager {
+	backends := clients.Backends
+	if backends == nil {
+		backends = make(map[string]*redis.Client)
+	}
 	return &Manager{
-		client: client,
-		config: cfg,
-		logger: logger,
+		client:        clients.Default,
+		backends:      backends,
+		shards:        clients.Shards,
+		modelBackends: make(map[uint64]string),
+		instanceID:    resolveInstanceID(cfg),
+		config:        cfg,
+		logger:        logger,
 	}
 }
 
-// EnqueueTask 将任务加入队列
-func (m *Manager) EnqueueTask(ctx context.Context, task *models.Task) error {
-	queueKey := m.getQueueKey(models.TaskPriority(task.Priority))
-	
+// EnqueueTask 将任务加入队列，入队的 span 上下文随队列项保存，Worker 执行时延续同一个追踪
+func (m *Manager) EnqueueTask(ctx context.Context, task *models.Task) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "queue.enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.Int64("task.id", int64(task.ID)),
+			attribute.Int64("model.id", int64(task.ModelID)),
+			attribute.Int("task.priority", int(task.Priority)),
+		))
+	defer func() { tracing.End(span, err) }()
+
+	// 等待中的任务数达到上限时拒绝入队，避免队列无限增长耗尽 Redis 内存
+	if err := m.CheckCapacity(ctx, models.TaskPriority(task.Priority)); err != nil {
+		return err
+	}
+
+	queueKey := m.readyKey(task.ModelID, models.TaskPriority(task.Priority))
+
 	item := QueueItem{
-		TaskID:    task.ID,
-		ModelID:   task.ModelID,
-		Priority:  int(task.Priority),
-		CreatedAt: task.CreatedAt,
+		TaskID:      task.ID,
+		ModelID:     task.ModelID,
+		Priority:    int(task.Priority),
+		CreatedAt:   task.CreatedAt,
+		TraceID:     task.TraceID,
+		TraceParent: tracing.Inject(ctx),
+		Deadline:    task.Deadline,
+		APIKey:      task.APIKeyName,
+		Type:        task.Type,
+	}
+
+	// 计划在将来执行的任务先放入延迟队列，到时间后由 ProcessDelayedTasks 移入就绪队列
+	if task.ScheduledAfter(time.Now()) {
+		if err := m.enqueueAt(ctx, &item, *task.RunAt); err != nil {
+			return fmt.Errorf("failed to schedule task: %w", err)
+		}
+		m.logger.WithFields(logrus.Fields{
+			"task_id":  task.ID,
+			"model_id": task.ModelID,
+			"run_at":   task.RunAt,
+			"trace_id": task.TraceID,
+		}).Info("Task scheduled")
+		return nil
 	}
-	
+
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
 		return fmt.Errorf("failed to marshal queue item: %w", err)
 	}
 
-	// 使用 Redis List 作为队列，LPUSH 保证 FIFO
-	if err := m.client.LPush(ctx, queueKey, itemBytes).Err(); err != nil {
+	// 每个优先级一个有序集合，score 为创建时间，同一优先级内始终按创建时间先后出队
+	if err := m.pushReady(ctx, m.clientFor(task.ModelID), queueKey, task.ModelID, readyMember(&item, itemBytes)); err != nil {
 		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
@@ -63,96 +151,222 @@ func (m *Manager) EnqueueTask(ctx context.Context, task *models.Task) error {
 		"model_id": task.ModelID,
 		"priority": task.Priority,
 		"queue":    queueKey,
+		"trace_id": task.TraceID,
 	}).Info("Task enqueued")
 
 	return nil
 }
 
-// DequeueTask 从队列中获取任务
-func (m *Manager) DequeueTask(ctx context.Context, modelID uint64) (*QueueItem, error) {
-	// 按优先级顺序检查队列
-	queues := []string{
-		m.config.Queue.HighPriorityQueue,
-		m.config.Queue.MediumPriorityQueue,
-		m.config.Queue.LowPriorityQueue,
+// DequeueTask 从队列中获取任务，types 不为空时只领取这些类型的任务。
+// 取到任务时在任务的追踪中记录出队 span，队列为空的轮询不产生 span
+func (m *Manager) DequeueTask(ctx context.Context, modelID uint64, types []string) (*QueueItem, error) {
+	start := time.Now()
+	client := m.clientFor(modelID)
+
+	// 按加权轮询的顺序检查队列，降级模式下低于下限的队列保持不动
+	var queues []string
+	for _, priority := range m.dequeueOrder(m.getMinPriority(ctx)) {
+		queues = append(queues, m.readyKey(modelID, priority))
 	}
 
 	for _, queueKey := range queues {
-		// 使用 BRPOP 阻塞式获取任务，超时时间设为 1 秒
-		result, err := m.client.BRPop(ctx, 1*time.Second, queueKey).Result()
+		item, err := m.dequeueFrom(ctx, client, queueKey, modelID, types)
 		if err != nil {
-			if err == redis.Nil {
-				// 队列为空，继续检查下一个队列
-				continue
-			}
-			return nil, fmt.Errorf("failed to dequeue from %s: %w", queueKey, err)
-		}
-
-		if len(result) != 2 {
-			continue
-		}
-
-		var item QueueItem
-		if err := json.Unmarshal([]byte(result[1]), &item); err != nil {
-			m.logger.WithError(err).Error("Failed to unmarshal queue item")
-			continue
+			return nil, err
 		}
-
-		// 检查是否是指定模型的任务
-		if modelID != 0 && item.ModelID != modelID {
-			// 如果不是指定模型的任务，将任务放回队列末尾
-			if err := m.client.LPush(ctx, queueKey, result[1]).Err(); err != nil {
-				m.logger.WithError(err).Error("Failed to requeue task")
-			}
+		if item == nil {
+			// 队列中没有可执行的任务，继续检查下一个队列
 			continue
 		}
 
-		// 将任务移到处理中队列
-		if err := m.moveToProcessing(ctx, &item); err != nil {
-			m.logger.WithError(err).Error("Failed to move task to processing queue")
-			// 将任务放回原队列
-			m.client.LPush(ctx, queueKey, result[1])
-			return nil, err
-		}
-
 		m.logger.WithFields(logrus.Fields{
 			"task_id":  item.TaskID,
 			"model_id": item.ModelID,
 			"priority": item.Priority,
 			"queue":    queueKey,
+			"trace_id": item.TraceID,
 		}).Info("Task dequeued")
 
-		return &item, nil
+		_, span := tracing.Tracer().Start(tracing.Extract(ctx, item.TraceParent), "queue.dequeue",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithTimestamp(start),
+			trace.WithAttributes(
+				attribute.Int64("task.id", int64(item.TaskID)),
+				attribute.Int64("model.id", int64(item.ModelID)),
+				attribute.String("queue", queueKey),
+			))
+		span.End()
+
+		return item, nil
 	}
 
 	// 所有队列都为空
 	return nil, nil
 }
 
-// moveToProcessing 将任务移到处理中队列
-func (m *Manager) moveToProcessing(ctx context.Context, item *QueueItem) error {
-	itemBytes, err := json.Marshal(item)
-	if err != nil {
-		return err
+// DequeueBatch 为指定模型一次取出最多 max 个任务，队列中没有可执行的任务时提前返回，
+// 取出的任务与 DequeueTask 一样进入处理中队列并各自占用一个并发名额
+func (m *Manager) DequeueBatch(ctx context.Context, modelID uint64, types []string, max int) ([]*QueueItem, error) {
+	items := make([]*QueueItem, 0, max)
+	for len(items) < max {
+		item, err := m.DequeueTask(ctx, modelID, types)
+		if err != nil {
+			if len(items) > 0 {
+				// 已取出的任务已在处理中队列，交给调用方执行，避免丢失
+				m.logger.WithError(err).WithField("model_id", modelID).Warn("Batch dequeue stopped early")
+				return items, nil
+			}
+			return nil, err
+		}
+		if item == nil {
+			break
+		}
+		items = append(items, item)
 	}
+	return items, nil
+}
 
-	// 使用有序集合存储处理中的任务，score 为开始处理时间
-	score := float64(time.Now().Unix())
-	return m.client.ZAdd(ctx, m.config.Queue.ProcessingQueue, &redis.Z{
-		Score:  score,
+// dequeueScanLimit 出队时每次从就绪队列读取的任务数
+const dequeueScanLimit = 100
+
+// readyScoreIDSlots score 中留给任务 ID 的位数，同一秒创建的任务按 ID 排序
+const readyScoreIDSlots = 1000000
+
+// readyScore 计算任务在优先级队列中的 score：创建时间截断到秒（与数据库 created_at 的精度一致）
+// 再加上任务 ID 的后六位。从数据库重新加载的任务与首次入队时 score 相同，同一秒内创建的任务按 ID 先后出队
+func readyScore(createdAt time.Time, taskID uint64) int64 {
+	return createdAt.Unix()*readyScoreIDSlots + int64(taskID%readyScoreIDSlots)
+}
+
+// readyMember 生成优先级队列的有序集合成员，score 见 readyScore，
+// 重新入队的任务保持原 score，不会插队也不会排到后面
+func readyMember(item *QueueItem, itemBytes []byte) *redis.Z {
+	return &redis.Z{
+		Score:  float64(readyScore(item.CreatedAt, item.TaskID)),
 		Member: itemBytes,
-	}).Err()
+	}
+}
+
+// dequeueFrom 从最早创建的任务开始查找第一个属于指定模型和类型且未超并发上限的任务并取出。
+// 其他任务保持在原位置，不再弹出后放回，避免多模型混排时 Worker 空转。
+// 每次读取 dequeueScanLimit 个任务，本页没有可领取的任务时继续向后翻页直到队尾，
+// 避免队首堆积的其他模型、其他类型或并发已满的任务让本 Worker 饿死
+func (m *Manager) dequeueFrom(ctx context.Context, client *redis.Client, queueKey string, modelID uint64, types []string) (*QueueItem, error) {
+	atCapacity := make(map[uint64]bool)
+	keysAtCapacity := make(map[string]bool)
+	now := time.Now()
+
+	var offset int64
+	for {
+		results, err := client.ZRange(ctx, queueKey, offset, offset+dequeueScanLimit-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to dequeue from %s: %w", queueKey, err)
+		}
+
+		// 本页中被移除的任务让后面的任务前移，下一页的起点相应减少
+		removed := 0
+		for _, raw := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(raw), &item); err != nil {
+				m.logger.WithError(err).Error("Failed to unmarshal queue item, dropping it")
+				client.ZRem(ctx, queueKey, raw)
+				removed++
+				continue
+			}
+
+			// 已超过截止时间的任务不占用 Worker，任何模型的 Worker 扫描到都直接丢弃
+			if item.expired(now) {
+				m.dropExpired(ctx, client, queueKey, raw, &item)
+				removed++
+				continue
+			}
+
+			// 检查是否是指定模型的任务
+			if modelID != 0 && item.ModelID != modelID {
+				continue
+			}
+			// 类型专用 Worker 跳过其他类型的任务，留给不限类型的 Worker
+			if !item.matchesType(types) {
+				continue
+			}
+			if atCapacity[item.ModelID] || (item.APIKey != "" && keysAtCapacity[item.APIKey]) {
+				continue
+			}
+
+			// 占用并发名额后原子地将任务移到处理中队列，未取到的任务始终留在就绪队列的原位置
+			if err := m.claimTask(ctx, client, queueKey, raw, &item); err != nil {
+				if errors.Is(err, errTaskClaimed) {
+					// 已被其他 Worker 取走
+					removed++
+					continue
+				}
+				if errors.Is(err, errModelAtCapacity) {
+					if modelID != 0 {
+						// 只领取该模型的任务时无需继续扫描
+						return nil, nil
+					}
+					// 模型并发已满，跳过该模型的其他任务
+					atCapacity[item.ModelID] = true
+					continue
+				}
+				if errors.Is(err, errKeyAtCapacity) {
+					// 该 API Key 执行中的任务已达上限，跳过它的其他任务，让其他调用方的任务先执行
+					keysAtCapacity[item.APIKey] = true
+					continue
+				}
+				m.logger.WithError(err).Error("Failed to move task to processing queue")
+				return nil, err
+			}
+
+			return &item, nil
+		}
+
+		if len(results) < dequeueScanLimit {
+			return nil, nil
+		}
+		offset += int64(len(results) - removed)
+	}
 }
 
 // CompleteTask 完成任务，从处理中队列移除
 func (m *Manager) CompleteTask(ctx context.Context, taskID uint64) error {
-	// 从处理中队列中移除任务
-	processingKey := m.config.Queue.ProcessingQueue
-	
-	// 获取所有处理中的任务
-	results, err := m.client.ZRange(ctx, processingKey, 0, -1).Result()
+	// 任务所在的后端未知，依次在各后端中查找
+	for _, client := range m.allClients() {
+		found, err := m.completeTask(ctx, client, taskID)
+		if err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// completeTask 从指定后端的处理中队列移除任务
+func (m *Manager) completeTask(ctx context.Context, client *redis.Client, taskID uint64) (bool, error) {
+	raw, item, err := m.findProcessing(ctx, client, taskID)
+	if err != nil || item == nil {
+		return false, err
+	}
+
+	removed, err := client.ZRem(ctx, m.config.Queue.ProcessingQueue, raw).Result()
 	if err != nil {
-		return err
+		return false, err
+	}
+	if removed > 0 {
+		m.releaseInflight(ctx, item.ModelID)
+		m.releaseKeyInflight(ctx, item.APIKey)
+	}
+	return true, nil
+}
+
+// findProcessing 在指定后端的处理中队列中查找任务，返回原始成员和解析后的队列项，不存在时返回 nil
+func (m *Manager) findProcessing(ctx context.Context, client *redis.Client, taskID uint64) (string, *QueueItem, error) {
+	results, err := client.ZRange(ctx, m.config.Queue.ProcessingQueue, 0, -1).Result()
+	if err != nil {
+		return "", nil, err
 	}
 
 	for _, result := range results {
@@ -160,13 +374,11 @@ func (m *Manager) CompleteTask(ctx context.Context, taskID uint64) error {
 		if err := json.Unmarshal([]byte(result), &item); err != nil {
 			continue
 		}
-
 		if item.TaskID == taskID {
-			return m.client.ZRem(ctx, processingKey, result).Err()
+			return result, &item, nil
 		}
 	}
-
-	return nil
+	return "", nil, nil
 }
 
 // RequeueTask 重新将任务加入队列（用于重试失败的任务）
@@ -177,28 +389,33 @@ func (m *Manager) RequeueTask(ctx context.Context, item *QueueItem, delay time.D
 	}
 
 	// 否则直接加入对应优先级队列
-	queueKey := m.getQueueKey(models.TaskPriority(item.Priority))
-	
+	queueKey := m.readyKey(item.ModelID, models.TaskPriority(item.Priority))
+
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
 		return err
 	}
 
-	return m.client.LPush(ctx, queueKey, itemBytes).Err()
+	return m.pushReady(ctx, m.clientFor(item.ModelID), queueKey, item.ModelID, readyMember(item, itemBytes))
 }
 
 // enqueueDelayed 将任务加入延迟队列
 func (m *Manager) enqueueDelayed(ctx context.Context, item *QueueItem, delay time.Duration) error {
+	item.DelayCount++
+	return m.enqueueAt(ctx, item, time.Now().Add(delay))
+}
+
+// enqueueAt 将任务加入延迟队列，executeAt 之后移入就绪队列；不计入延迟次数
+func (m *Manager) enqueueAt(ctx context.Context, item *QueueItem, executeAt time.Time) error {
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
 		return err
 	}
 
 	// 使用有序集合存储延迟任务，score 为执行时间
-	executeAt := time.Now().Add(delay)
 	score := float64(executeAt.Unix())
 
-	return m.client.ZAdd(ctx, m.config.Queue.DelayedQueue, &redis.Z{
+	return m.clientFor(item.ModelID).ZAdd(ctx, m.config.Queue.DelayedQueue, &redis.Z{
 		Score:  score,
 		Member: itemBytes,
 	}).Err()
@@ -206,11 +423,21 @@ func (m *Manager) enqueueDelayed(ctx context.Context, item *QueueItem, delay tim
 
 // ProcessDelayedTasks 处理延迟任务，将到期任务移到正常队列
 func (m *Manager) ProcessDelayedTasks(ctx context.Context) error {
+	for name, client := range m.allClients() {
+		if err := m.processDelayedTasks(ctx, client); err != nil {
+			return fmt.Errorf("failed to process delayed tasks on backend %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// processDelayedTasks 处理指定后端的延迟任务
+func (m *Manager) processDelayedTasks(ctx context.Context, client *redis.Client) error {
 	delayedKey := m.config.Queue.DelayedQueue
 	now := float64(time.Now().Unix())
 
 	// 获取所有到期的延迟任务
-	results, err := m.client.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{
+	results, err := client.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{
 		Min: "0",
 		Max: fmt.Sprintf("%f", now),
 	}).Result()
@@ -225,15 +452,20 @@ func (m *Manager) ProcessDelayedTasks(ctx context.Context) error {
 			continue
 		}
 
-		// 将任务移到正常队列
-		queueKey := m.getQueueKey(models.TaskPriority(item.Priority))
-		if err := m.client.LPush(ctx, queueKey, result).Err(); err != nil {
+		if item.expired(time.Now()) {
+			m.dropExpired(ctx, client, delayedKey, result, &item)
+			continue
+		}
+
+		// 将任务移到正常队列，按创建时间排在同优先级任务中的原位置
+		queueKey := m.readyKey(item.ModelID, models.TaskPriority(item.Priority))
+		if err := m.pushReady(ctx, client, queueKey, item.ModelID, readyMember(&item, []byte(result))); err != nil {
 			m.logger.WithError(err).Error("Failed to move delayed task to queue")
 			continue
 		}
 
 		// 从延迟队列中移除
-		if err := m.client.ZRem(ctx, delayedKey, result).Err(); err != nil {
+		if err := client.ZRem(ctx, delayedKey, result).Err(); err != nil {
 			m.logger.WithError(err).Error("Failed to remove task from delayed queue")
 		}
 
@@ -243,14 +475,44 @@ func (m *Manager) ProcessDelayedTasks(ctx context.Context) error {
 	return nil
 }
 
+// dropExpired 从队列中移除已超过截止时间的任务并通知取消，已被其他实例移除时不重复通知
+func (m *Manager) dropExpired(ctx context.Context, client *redis.Client, queueKey, raw string, item *QueueItem) {
+	removed, err := client.ZRem(ctx, queueKey, raw).Result()
+	if err != nil {
+		m.logger.WithError(err).WithField("task_id", item.TaskID).Error("Failed to drop expired task")
+		return
+	}
+	if removed == 0 {
+		return
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"task_id":  item.TaskID,
+		"queue":    queueKey,
+		"deadline": item.Deadline,
+		"trace_id": item.TraceID,
+	}).Info("Task deadline exceeded, dropped from queue")
+	m.OnTaskExpired(ctx, item.TaskID)
+}
+
 // CleanupStuckTasks 清理卡住的任务
 func (m *Manager) CleanupStuckTasks(ctx context.Context) error {
+	for name, client := range m.allClients() {
+		if err := m.cleanupStuckTasks(ctx, client); err != nil {
+			return fmt.Errorf("failed to cleanup stuck tasks on backend %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// cleanupStuckTasks 清理指定后端中卡住的任务
+func (m *Manager) cleanupStuckTasks(ctx context.Context, client *redis.Client) error {
 	processingKey := m.config.Queue.ProcessingQueue
 	timeout := m.config.Queue.TaskTimeout
 
 	// 获取超时的处理中任务
 	cutoff := float64(time.Now().Add(-timeout).Unix())
-	results, err := m.client.ZRangeByScore(ctx, processingKey, &redis.ZRangeBy{
+	results, err := client.ZRangeByScore(ctx, processingKey, &redis.ZRangeBy{
 		Min: "0",
 		Max: fmt.Sprintf("%f", cutoff),
 	}).Result()
@@ -264,16 +526,27 @@ func (m *Manager) CleanupStuckTasks(ctx context.Context) error {
 			continue
 		}
 
-		// 将超时任务重新加入队列或标记为失败
-		m.logger.WithField("task_id", item.TaskID).Warn("Found stuck task, requeueing")
-		
-		// 重新加入延迟队列，等待重试
-		if err := m.enqueueDelayed(ctx, &item, m.config.Queue.RetryDelay); err != nil {
-			m.logger.WithError(err).Error("Failed to requeue stuck task")
+		// 将超时任务重新加入队列，反复超时的任务升级给人工处理，不再自动重试
+		if maxDelays := m.config.Queue.MaxDelayCount; maxDelays > 0 && item.DelayCount >= maxDelays {
+			m.logger.WithFields(logrus.Fields{
+				"task_id":     item.TaskID,
+				"delay_count": item.DelayCount,
+			}).Error("Task delayed too many times, escalating")
+			m.OnTaskEscalated(ctx, item.TaskID, item.DelayCount)
+		} else {
+			m.logger.WithField("task_id", item.TaskID).Warn("Found stuck task, requeueing")
+
+			// 重新加入延迟队列，等待重试
+			if err := m.enqueueDelayed(ctx, &item, m.config.Queue.RetryDelay); err != nil {
+				m.logger.WithError(err).Error("Failed to requeue stuck task")
+			}
 		}
 
 		// 从处理中队列移除
-		m.client.ZRem(ctx, processingKey, result)
+		if removed, err := client.ZRem(ctx, processingKey, result).Result(); err == nil && removed > 0 {
+			m.releaseInflight(ctx, item.ModelID)
+			m.releaseKeyInflight(ctx, item.APIKey)
+		}
 	}
 
 	return nil
@@ -283,24 +556,57 @@ func (m *Manager) CleanupStuckTasks(ctx context.Context) error {
 func (m *Manager) GetQueueStatus(ctx context.Context) (*models.QueueStatus, error) {
 	status := &models.QueueStatus{}
 
-	// 获取各队列长度
-	highCount, _ := m.client.LLen(ctx, m.config.Queue.HighPriorityQueue).Result()
-	mediumCount, _ := m.client.LLen(ctx, m.config.Queue.MediumPriorityQueue).Result()
-	lowCount, _ := m.client.LLen(ctx, m.config.Queue.LowPriorityQueue).Result()
-	processingCount, _ := m.client.ZCard(ctx, m.config.Queue.ProcessingQueue).Result()
-	delayedCount, _ := m.client.ZCard(ctx, m.config.Queue.DelayedQueue).Result()
+	// 汇总各后端的队列长度，隔离模式下同时按模型分别统计
+	for _, client := range m.allClients() {
+		modelIDs, err := m.queueModels(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, modelID := range modelIDs {
+			highCount, _ := client.ZCard(ctx, m.readyKey(modelID, models.TaskPriorityHigh)).Result()
+			mediumCount, _ := client.ZCard(ctx, m.readyKey(modelID, models.TaskPriorityMedium)).Result()
+			lowCount, _ := client.ZCard(ctx, m.readyKey(modelID, models.TaskPriorityLow)).Result()
+
+			status.HighPriorityCount += highCount
+			status.MediumPriorityCount += mediumCount
+			status.LowPriorityCount += lowCount
+
+			if m.isolated() {
+				if status.ModelQueues == nil {
+					status.ModelQueues = make(map[uint64]*models.ModelQueueCount)
+				}
+				count := status.ModelQueues[modelID]
+				if count == nil {
+					count = &models.ModelQueueCount{}
+					status.ModelQueues[modelID] = count
+				}
+				count.HighPriorityCount += highCount
+				count.MediumPriorityCount += mediumCount
+				count.LowPriorityCount += lowCount
+				count.TotalCount += highCount + mediumCount + lowCount
+			}
+		}
+
+		processingCount, _ := client.ZCard(ctx, m.config.Queue.ProcessingQueue).Result()
+		delayedCount, _ := client.ZCard(ctx, m.config.Queue.DelayedQueue).Result()
+		status.ProcessingCount += processingCount
+		status.DelayedCount += delayedCount
+	}
+	status.TotalCount = status.HighPriorityCount + status.MediumPriorityCount + status.LowPriorityCount +
+		status.ProcessingCount + status.DelayedCount
+
+	if inflight, err := m.GetModelInflight(ctx); err == nil && len(inflight) > 0 {
+		status.ModelInflight = inflight
+	}
 
-	status.HighPriorityCount = highCount
-	status.MediumPriorityCount = mediumCount
-	status.LowPriorityCount = lowCount
-	status.ProcessingCount = processingCount
-	status.DelayedCount = delayedCount
-	status.TotalCount = highCount + mediumCount + lowCount + processingCount + delayedCount
+	if degradedMode, err := m.GetDegradedMode(ctx); err == nil && degradedMode.Enabled {
+		status.DegradedMode = degradedMode
+	}
 
 	return status, nil
 }
 
-// getQueueKey 根据优先级获取队列键名
+// getQueueKey 根据优先级获取共享队列键名，读写就绪队列使用 readyKey
 func (m *Manager) getQueueKey(priority models.TaskPriority) string {
 	switch priority {
 	case models.TaskPriorityHigh:
@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestDequeueTaskTypeAffinity(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	mustEnqueue(t, m,
+		newTestTask(1, 1, "text-generation"),
+		newTestTask(2, 1, "text-generation"),
+		newTestTask(3, 1, "embedding"),
+	)
+
+	// 只领取 embedding 的 Worker 跳过排在前面的其他类型任务
+	item, err := m.DequeueTask(ctx, 1, []string{"embedding"})
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item == nil || item.TaskID != 3 {
+		t.Fatalf("expected embedding task 3, got %+v", item)
+	}
+
+	// 没有 embedding 任务时不领取其他类型
+	item, err = m.DequeueTask(ctx, 1, []string{"embedding"})
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item != nil {
+		t.Fatalf("expected no task for embedding worker, got task %d", item.TaskID)
+	}
+
+	// 跳过的任务留在原位置，不限类型的 Worker 按创建顺序领取
+	for _, want := range []uint64{1, 2} {
+		item, err = m.DequeueTask(ctx, 1, nil)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if item == nil || item.TaskID != want {
+			t.Fatalf("expected task %d, got %+v", want, item)
+		}
+	}
+}
+
+func TestDequeueTaskPagesPastOtherModelsBacklog(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	// 模型 1 的任务分散在大量模型 2 的积压之后，超出单次读取的任务数
+	var id uint64
+	var want []uint64
+	for round := 0; round < 3; round++ {
+		for i := 0; i < dequeueScanLimit+20; i++ {
+			id++
+			mustEnqueue(t, m, newTestTask(id, 2, ""))
+		}
+		id++
+		mustEnqueue(t, m, newTestTask(id, 1, ""))
+		want = append(want, id)
+	}
+
+	// 模型 1 的 Worker 翻页找到每个任务，按创建顺序领取
+	for _, taskID := range want {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if item == nil || item.TaskID != taskID {
+			t.Fatalf("expected task %d, got %+v", taskID, item)
+		}
+	}
+
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item != nil {
+		t.Fatalf("expected no task for model 1, got task %d", item.TaskID)
+	}
+
+	// 模型 2 的任务都留在原位置
+	if got := m.client.ZCard(ctx, m.config.Queue.MediumPriorityQueue).Val(); got != int64(3*(dequeueScanLimit+20)) {
+		t.Fatalf("expected other model tasks to stay queued, got %d", got)
+	}
+}
+
+func TestDequeueTaskPagesPastExpiredTasks(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	// 队首的过期任务在扫描时被丢弃，下一页的起点随之前移，不会漏掉其后的任务
+	past := time.Now().Add(-time.Minute)
+	var id uint64
+	for i := 0; i < dequeueScanLimit; i++ {
+		id++
+		task := newTestTask(id, 2, "")
+		task.Deadline = &past
+		mustEnqueue(t, m, task)
+	}
+	for i := 0; i < dequeueScanLimit; i++ {
+		id++
+		mustEnqueue(t, m, newTestTask(id, 2, ""))
+	}
+	id++
+	mustEnqueue(t, m, newTestTask(id, 1, ""))
+
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if item == nil || item.TaskID != id {
+		t.Fatalf("expected task %d, got %+v", id, item)
+	}
+}
+
+func TestDequeueTaskFIFOAcrossReloadedTasks(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	// 同一秒内创建的三个任务：10 刚创建，时间带毫秒；9 和 11 从数据库重新加载后入队
+	// （重试、恢复、改派），created_at 只精确到秒。出队顺序仍按 ID，不受时间精度和成员字典序影响
+	second := time.Unix(1700000000, 0)
+	fresh := newTestTask(10, 1, "")
+	fresh.CreatedAt = second.Add(900 * time.Millisecond)
+	reloaded := func(id uint64) *models.Task {
+		task := newTestTask(id, 1, "")
+		task.CreatedAt = second
+		return task
+	}
+	mustEnqueue(t, m, reloaded(11), fresh, reloaded(9))
+
+	for _, want := range []uint64{9, 10, 11} {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if item == nil || item.TaskID != want {
+			t.Fatalf("expected task %d, got %+v", want, item)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// MigrateReadyQueues 将旧版本使用 List 存储的优先级队列转换为有序集合，启动时调用。
+// 转换在 WATCH 事务中完成，无法解析的条目被丢弃
+func (m *Manager) MigrateReadyQueues(ctx context.Context) error {
+	for name, client := range m.allClients() {
+		for _, priority := range priorities {
+			queueKey := m.getQueueKey(priority)
+			migrated, err := m.migrateListQueue(ctx, client, queueKey)
+			if err != nil {
+				return fmt.Errorf("failed to migrate %s on backend %s: %w", queueKey, name, err)
+			}
+			if migrated > 0 {
+				m.logger.WithFields(logrus.Fields{
+					"backend": name,
+					"queue":   queueKey,
+					"items":   migrated,
+				}).Info("Migrated list queue to sorted set")
+			}
+		}
+	}
+	return nil
+}
+
+// migrateListQueue 转换单个队列，键不存在或已是有序集合时不做任何操作。
+// 读取期间队列被修改时事务失败，由调用方重启后重试
+func (m *Manager) migrateListQueue(ctx context.Context, client *redis.Client, queueKey string) (int, error) {
+	migrated := 0
+	err := client.Watch(ctx, func(tx *redis.Tx) error {
+		keyType, err := tx.Type(ctx, queueKey).Result()
+		if err != nil {
+			return err
+		}
+		if keyType != "list" {
+			return nil
+		}
+
+		results, err := tx.LRange(ctx, queueKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		members := make([]*redis.Z, 0, len(results))
+		for _, raw := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(raw), &item); err != nil {
+				m.logger.WithError(err).WithField("queue", queueKey).Warn("Dropping invalid queue item")
+				continue
+			}
+			members = append(members, readyMember(&item, []byte(raw)))
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, queueKey)
+			if len(members) > 0 {
+				pipe.ZAdd(ctx, queueKey, members...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		migrated = len(members)
+		return nil
+	}, queueKey)
+	return migrated, err
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitWindow 模型请求限流的统计窗口，对应配置中的 requests_per_minute
+const rateLimitWindow = time.Minute
+
+// AcquireRateLimit 为模型的一次上游调用获取令牌，所有实例共享同一个滑动窗口。
+// 返回是否放行以及超限时需要等待的时间，rpm <= 0 表示不限流
+func (m *Manager) AcquireRateLimit(ctx context.Context, modelID uint64, rpm int) (bool, time.Duration, error) {
+	if rpm <= 0 {
+		return true, 0, nil
+	}
+
+	key := fmt.Sprintf("%s:%d", m.getRateLimitKey(), modelID)
+	allowed, retryAfter, err := utils.SlidingWindowAllow(ctx, m.client, key, rpm, rateLimitWindow)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to acquire rate limit: %w", err)
+	}
+	return allowed, retryAfter, nil
+}
+
+// deferScript 原子地将任务从处理中队列（KEYS[1]）原样移到延迟队列（KEYS[2]），score 为到期时间，
+// 同时释放模型（KEYS[3]）和 API Key（KEYS[4]）的并发名额。任务已不在处理中队列时返回 0，不做任何写入
+var deferScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+if redis.call('HINCRBY', KEYS[3], ARGV[3], -1) <= 0 then
+	redis.call('HDEL', KEYS[3], ARGV[3])
+end
+if ARGV[4] ~= '' and redis.call('HINCRBY', KEYS[4], ARGV[4], -1) <= 0 then
+	redis.call('HDEL', KEYS[4], ARGV[4])
+end
+return 1
+`)
+
+// DeferTask 将已出队的任务从处理中队列移回延迟队列并释放并发名额，到期后重新出队。
+// 队列项保持出队时的内容（包括老化提升后的优先级和提升时间），不从数据库重建；
+// 用于模型限流等非故障原因的延后，不计入 delay_count。任务已不在处理中队列（如已被取消）时不做处理
+func (m *Manager) DeferTask(ctx context.Context, taskID uint64, delay time.Duration) error {
+	executeAt := time.Now().Add(delay).Unix()
+
+	// 任务所在的后端未知，依次在各后端中查找
+	for _, client := range m.allClients() {
+		raw, item, err := m.findProcessing(ctx, client, taskID)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			continue
+		}
+
+		// 并发计数保存在共享 Redis 中，任务在独立后端时先移动任务，移动成功后再释放名额
+		if client != m.client {
+			keys := []string{m.config.Queue.ProcessingQueue, m.config.Queue.DelayedQueue}
+			moved, err := moveScript.Run(ctx, client, keys, raw, raw, executeAt).Int64()
+			if err != nil {
+				return fmt.Errorf("failed to defer task: %w", err)
+			}
+			if moved == 1 {
+				m.releaseInflight(ctx, item.ModelID)
+				m.releaseKeyInflight(ctx, item.APIKey)
+			}
+			return nil
+		}
+
+		keys := []string{m.config.Queue.ProcessingQueue, m.config.Queue.DelayedQueue, m.getInflightKey(), m.getKeyInflightKey()}
+		if err := deferScript.Run(ctx, client, keys, raw, executeAt, item.ModelID, item.APIKey).Err(); err != nil {
+			return fmt.Errorf("failed to defer task: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// getRateLimitKey 获取模型请求限流的键名前缀
+func (m *Manager) getRateLimitKey() string {
+	if m.config.Queue.RateLimitKey != "" {
+		return m.config.Queue.RateLimitKey
+	}
+	return "llm_tasks:model_rate"
+}
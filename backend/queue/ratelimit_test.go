@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestAcquireRateLimitPerModel(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	// 模型 1 每分钟 2 次，第三次超限并返回需要等待的时间
+	for i := 0; i < 2; i++ {
+		allowed, _, err := m.AcquireRateLimit(ctx, 1, 2)
+		if err != nil || !allowed {
+			t.Fatalf("request %d: expected allowed, got %v (err %v)", i+1, allowed, err)
+		}
+	}
+	allowed, retryAfter, err := m.AcquireRateLimit(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if allowed {
+		t.Fatalf("expected third request to be limited")
+	}
+	if retryAfter <= 0 || retryAfter > rateLimitWindow {
+		t.Fatalf("expected retry after within the window, got %s", retryAfter)
+	}
+
+	// 每个模型使用独立的窗口
+	if allowed, _, err := m.AcquireRateLimit(ctx, 2, 2); err != nil || !allowed {
+		t.Fatalf("expected model 2 to be allowed, got %v (err %v)", allowed, err)
+	}
+	// rpm <= 0 不限流
+	for i := 0; i < 5; i++ {
+		if allowed, _, err := m.AcquireRateLimit(ctx, 1, 0); err != nil || !allowed {
+			t.Fatalf("expected unlimited model to be allowed, got %v (err %v)", allowed, err)
+		}
+	}
+}
+
+// delayedItems 读取延迟队列中的任务和到期时间
+func delayedItems(t *testing.T, m *Manager, client *redis.Client) map[uint64]redis.Z {
+	t.Helper()
+	results, err := client.ZRangeWithScores(context.Background(), m.config.Queue.DelayedQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("read delayed queue: %v", err)
+	}
+	items := make(map[uint64]redis.Z, len(results))
+	for _, z := range results {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(z.Member.(string)), &item); err != nil {
+			t.Fatalf("unmarshal delayed item: %v", err)
+		}
+		items[item.TaskID] = z
+	}
+	return items
+}
+
+func TestDeferTaskMovesOriginalItemAndReleasesSlots(t *testing.T) {
+	m, _ := newTestManager(t, func(cfg *config.Config) {
+		cfg.Auth.Keys = []config.APIKeyConfig{{Name: "team-a", Key: "secret", MaxRunning: 1}}
+	})
+	ctx := context.Background()
+
+	// 老化提升过优先级的任务：队列项中的优先级和提升时间与数据库中的任务不同
+	promotedAt := time.Unix(1700000100, 0)
+	item := &QueueItem{
+		TaskID:     1,
+		ModelID:    1,
+		Priority:   int(models.TaskPriorityHigh),
+		CreatedAt:  time.Unix(1700000000, 0),
+		PromotedAt: &promotedAt,
+		APIKey:     "team-a",
+	}
+	if err := m.RequeueTask(ctx, item, 0); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	claimed, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || claimed == nil {
+		t.Fatalf("expected to claim task, got %+v (err %v)", claimed, err)
+	}
+	processing, _ := m.client.ZRange(ctx, m.config.Queue.ProcessingQueue, 0, 0).Result()
+
+	before := time.Now()
+	if err := m.DeferTask(ctx, 1, time.Minute); err != nil {
+		t.Fatalf("defer: %v", err)
+	}
+
+	// 延迟队列中是出队时的原始队列项，到期时间为 delay 之后
+	delayed := delayedItems(t, m, m.client)
+	z, ok := delayed[1]
+	if !ok {
+		t.Fatalf("expected task in delayed queue")
+	}
+	if z.Member.(string) != processing[0] {
+		t.Fatalf("expected original item %s, got %s", processing[0], z.Member)
+	}
+	if executeAt := time.Unix(int64(z.Score), 0); executeAt.Before(before.Add(time.Minute).Truncate(time.Second)) {
+		t.Fatalf("expected execute time after delay, got %v", executeAt)
+	}
+
+	// 处理中队列、模型和 API Key 的并发名额都已释放
+	if items := processingItems(t, m); len(items) != 0 {
+		t.Fatalf("expected processing queue empty, got %+v", items)
+	}
+	if inflight, _ := m.GetModelInflight(ctx); inflight[1] != 0 {
+		t.Fatalf("expected model inflight released, got %d", inflight[1])
+	}
+	if running := m.client.HGet(ctx, m.config.Queue.KeyInflightKey, "team-a").Val(); running != "" {
+		t.Fatalf("expected api key slot released, got %q", running)
+	}
+}
+
+func TestDeferTaskNotProcessing(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	// 任务已不在处理中队列（如已被取消）时不放回队列，也不改变并发计数
+	if err := m.DeferTask(ctx, 1, time.Minute); err != nil {
+		t.Fatalf("defer: %v", err)
+	}
+	if delayed := delayedItems(t, m, m.client); len(delayed) != 0 {
+		t.Fatalf("expected delayed queue empty, got %v", delayed)
+	}
+	if inflight, _ := m.GetModelInflight(ctx); len(inflight) != 0 {
+		t.Fatalf("expected no inflight counts, got %v", inflight)
+	}
+}
+
+func TestDeferTaskOnBackend(t *testing.T) {
+	m, clients := newShardedTestManager(t)
+	ctx := context.Background()
+
+	// 模型 4 的任务在 east 分片，并发计数在共享 Redis 中
+	mustEnqueue(t, m, newTestTask(1, 4, "text-generation"))
+	if item, err := m.DequeueTask(ctx, 4, nil); err != nil || item == nil {
+		t.Fatalf("expected to claim task, got %+v (err %v)", item, err)
+	}
+
+	if err := m.DeferTask(ctx, 1, time.Minute); err != nil {
+		t.Fatalf("defer: %v", err)
+	}
+	if _, ok := delayedItems(t, m, clients["east"])[1]; !ok {
+		t.Fatalf("expected task in east delayed queue")
+	}
+	if n := clients["east"].ZCard(ctx, m.config.Queue.ProcessingQueue).Val(); n != 0 {
+		t.Fatalf("expected east processing queue empty, got %d", n)
+	}
+	if inflight, _ := m.GetModelInflight(ctx); inflight[4] != 0 {
+		t.Fatalf("expected model inflight released, got %d", inflight[4])
+	}
+}
@@ -9,19 +9,86 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
-// InitRedis 初始化 Redis 连接
-func InitRedis(cfg *config.Config) (*redis.Client, error) {
+// Clients 队列使用的 Redis 连接集合
+type Clients struct {
+	// Default 共享 Redis，保存并发计数、名册等全局数据，未分片的模型队列也在其中
+	Default *redis.Client
+	// Backends 按名称配置的独立队列后端
+	Backends map[string]*redis.Client
+	// Shards 按 queue.shards 顺序排列的分片连接，元素为 Default 或 Backends 中的连接；为空时不分片
+	Shards []*redis.Client
+}
+
+// InitRedis 初始化共享 Redis、独立队列后端和分片的连接，未配置后端和分片时只连接共享 Redis
+func InitRedis(cfg *config.Config) (*Clients, error) {
+	defaultClient, err := newRedisClient(&cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	backends := make(map[string]*redis.Client, len(cfg.Queue.Backends))
+	for name, redisCfg := range cfg.Queue.Backends {
+		redisCfg := redisCfg
+		rdb, err := newRedisClient(&redisCfg)
+		if err != nil {
+			(&Clients{Default: defaultClient, Backends: backends}).Close()
+			return nil, fmt.Errorf("queue backend %s: %w", name, err)
+		}
+		backends[name] = rdb
+	}
+
+	clients, err := NewClients(defaultClient, backends, cfg.Queue.Shards)
+	if err != nil {
+		(&Clients{Default: defaultClient, Backends: backends}).Close()
+		return nil, err
+	}
+	return clients, nil
+}
+
+// NewClients 由已建立的连接组成连接集合，按名称解析分片，default 表示共享 Redis
+func NewClients(defaultClient *redis.Client, backends map[string]*redis.Client, shards []string) (*Clients, error) {
+	if backends == nil {
+		backends = make(map[string]*redis.Client)
+	}
+	clients := &Clients{Default: defaultClient, Backends: backends}
+	for _, name := range shards {
+		if name == DefaultBackend {
+			clients.Shards = append(clients.Shards, defaultClient)
+			continue
+		}
+		client, ok := backends[name]
+		if !ok {
+			return nil, fmt.Errorf("queue shard %s: backend not configured", name)
+		}
+		clients.Shards = append(clients.Shards, client)
+	}
+	return clients, nil
+}
+
+// Close 关闭所有连接
+func (c *Clients) Close() {
+	for _, rdb := range c.Backends {
+		rdb.Close()
+	}
+	if c.Default != nil {
+		c.Default.Close()
+	}
+}
+
+// newRedisClient 创建 Redis 连接并测试连通性
+func newRedisClient(redisCfg *config.RedisConfig) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:         cfg.Redis.GetRedisAddr(),
-		Password:     cfg.Redis.Password,
-		DB:           cfg.Redis.DB,
-		PoolSize:     cfg.Redis.PoolSize,
-		MinIdleConns: cfg.Redis.MinIdleConns,
+		Addr:         redisCfg.GetRedisAddr(),
+		Password:     redisCfg.Password,
+		DB:           redisCfg.DB,
+		PoolSize:     redisCfg.PoolSize,
+		MinIdleConns: redisCfg.MinIdleConns,
 	})
 
 	// 测试连接
 	ctx := context.Background()
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
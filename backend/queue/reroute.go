@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RemoveQueuedTask 从模型所在后端的优先级队列中移除等待执行的任务，返回是否移除成功。
+// ZREM 按原始内容删除，多个实例同时移除同一任务时只有一个会成功
+func (m *Manager) RemoveQueuedTask(ctx context.Context, modelID, taskID uint64) (bool, error) {
+	client := m.clientFor(modelID)
+
+	for _, priority := range priorities {
+		queueKey := m.readyKey(modelID, priority)
+		results, err := client.ZRange(ctx, queueKey, 0, -1).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", queueKey, err)
+		}
+
+		for _, raw := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(raw), &item); err != nil {
+				continue
+			}
+			if item.TaskID != taskID || item.ModelID != modelID {
+				continue
+			}
+
+			removed, err := client.ZRem(ctx, queueKey, raw).Result()
+			if err != nil {
+				return false, fmt.Errorf("failed to remove task from %s: %w", queueKey, err)
+			}
+			return removed > 0, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RemoveModelTasks 从模型所在后端的优先级队列和延迟队列中移除该模型的全部任务，返回被移除的任务
+func (m *Manager) RemoveModelTasks(ctx context.Context, modelID uint64) ([]QueueItem, error) {
+	client := m.clientFor(modelID)
+
+	keys := make([]string, 0, len(priorities)+1)
+	for _, priority := range priorities {
+		keys = append(keys, m.readyKey(modelID, priority))
+	}
+	keys = append(keys, m.config.Queue.DelayedQueue)
+
+	items := []QueueItem{}
+	for _, key := range keys {
+		results, err := client.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return items, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+
+		for _, raw := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(raw), &item); err != nil {
+				continue
+			}
+			if item.ModelID != modelID {
+				continue
+			}
+
+			// 返回 0 说明任务已被 Worker 取走或被其他实例移除
+			removed, err := client.ZRem(ctx, key, raw).Result()
+			if err != nil {
+				return items, fmt.Errorf("failed to remove task from %s: %w", key, err)
+			}
+			if removed > 0 {
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
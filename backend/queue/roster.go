@@ -0,0 +1,201 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// resolveInstanceID 获取当前实例标识，未配置时使用 主机名-进程号
+func resolveInstanceID(cfg *config.Config) string {
+	if cfg.Worker.InstanceID != "" {
+		return cfg.Worker.InstanceID
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// InstanceID 获取当前实例标识
+func (m *Manager) InstanceID() string {
+	return m.instanceID
+}
+
+// ReportWorker 上报 Worker 心跳：每个 Worker 一个 Hash（<roster_key>:<instance_id>:<worker_id>），
+// 每次心跳刷新 TTL，进程退出或崩溃后记录在 stale_after 后自动过期
+func (m *Manager) ReportWorker(ctx context.Context, status models.WorkerStatus) error {
+	status.InstanceID = m.instanceID
+
+	currentTaskID := ""
+	if status.CurrentTaskID != nil {
+		currentTaskID = strconv.FormatUint(*status.CurrentTaskID, 10)
+	}
+
+	key := m.getWorkerKey(status.WorkerID)
+	_, err := m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"worker_id":       status.WorkerID,
+			"instance_id":     status.InstanceID,
+			"model_id":        status.ModelID,
+			"status":          status.Status,
+			"current_task_id": currentTaskID,
+			"task_types":      strings.Join(status.TaskTypes, ","),
+			"start_time":      status.StartTime.Format(time.RFC3339Nano),
+			"last_heartbeat":  status.LastHeartbeat.Format(time.RFC3339Nano),
+		})
+		pipe.Expire(ctx, key, m.getStaleAfter())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to report worker: %w", err)
+	}
+	return nil
+}
+
+// RemoveWorker 从 Worker 名册中移除 Worker
+func (m *Manager) RemoveWorker(ctx context.Context, workerID string) error {
+	return m.client.Del(ctx, m.getWorkerKey(workerID)).Err()
+}
+
+// ListWorkers 获取名册中所有存活 Worker 的状态，按 Worker ID 排序
+func (m *Manager) ListWorkers(ctx context.Context) ([]models.WorkerStatus, error) {
+	var keys []string
+	iter := m.client.Scan(ctx, 0, m.getRosterKey()+":*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan worker roster: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := m.client.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HGetAll(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get worker roster: %w", err)
+	}
+
+	workers := make([]models.WorkerStatus, 0, len(keys))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		// 扫描到读取之间过期的记录
+		if len(fields) == 0 {
+			continue
+		}
+
+		status, err := parseWorkerStatus(fields)
+		if err != nil {
+			m.logger.WithError(err).WithField("key", keys[i]).Warn("Failed to parse worker status")
+			continue
+		}
+		workers = append(workers, status)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	return workers, nil
+}
+
+// GetGlobalWorkerStatus 获取所有实例的 Worker 状态，按实例分组
+func (m *Manager) GetGlobalWorkerStatus(ctx context.Context) ([]models.InstanceWorkerStatus, error) {
+	workers, err := m.ListWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make(map[string]*models.InstanceWorkerStatus)
+	for _, status := range workers {
+		instance, exists := instances[status.InstanceID]
+		if !exists {
+			instance = &models.InstanceWorkerStatus{InstanceID: status.InstanceID}
+			instances[status.InstanceID] = instance
+		}
+		instance.Workers = append(instance.Workers, status)
+		if status.LastHeartbeat.After(instance.LastHeartbeat) {
+			instance.LastHeartbeat = status.LastHeartbeat
+		}
+	}
+
+	result := make([]models.InstanceWorkerStatus, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, *instance)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].InstanceID < result[j].InstanceID
+	})
+
+	return result, nil
+}
+
+// parseWorkerStatus 解析名册中的 Worker 记录
+func parseWorkerStatus(fields map[string]string) (models.WorkerStatus, error) {
+	status := models.WorkerStatus{
+		WorkerID:   fields["worker_id"],
+		InstanceID: fields["instance_id"],
+		Status:     fields["status"],
+	}
+
+	var err error
+	if status.ModelID, err = strconv.ParseUint(fields["model_id"], 10, 64); err != nil {
+		return status, fmt.Errorf("invalid model_id: %w", err)
+	}
+	if taskID := fields["current_task_id"]; taskID != "" {
+		id, err := strconv.ParseUint(taskID, 10, 64)
+		if err != nil {
+			return status, fmt.Errorf("invalid current_task_id: %w", err)
+		}
+		status.CurrentTaskID = &id
+	}
+	if taskTypes := fields["task_types"]; taskTypes != "" {
+		status.TaskTypes = strings.Split(taskTypes, ",")
+	}
+	if status.StartTime, err = time.Parse(time.RFC3339Nano, fields["start_time"]); err != nil {
+		return status, fmt.Errorf("invalid start_time: %w", err)
+	}
+	if status.LastHeartbeat, err = time.Parse(time.RFC3339Nano, fields["last_heartbeat"]); err != nil {
+		return status, fmt.Errorf("invalid last_heartbeat: %w", err)
+	}
+	return status, nil
+}
+
+// getWorkerKey 获取 Worker 名册记录的键名，Worker ID 仅在实例内唯一，键名中带上实例标识
+func (m *Manager) getWorkerKey(workerID string) string {
+	return m.getRosterKey() + ":" + m.instanceID + ":" + workerID
+}
+
+// getRosterKey 获取 Worker 名册记录的键名前缀
+func (m *Manager) getRosterKey() string {
+	if m.config.Worker.RosterKey != "" {
+		return m.config.Worker.RosterKey
+	}
+	return "llm_tasks:workers"
+}
+
+// getStaleAfter 获取 Worker 名册记录的过期时间，未配置时为 3 个心跳间隔
+func (m *Manager) getStaleAfter() time.Duration {
+	if m.config.Worker.StaleAfter > 0 {
+		return m.config.Worker.StaleAfter
+	}
+	if m.config.Worker.HeartbeatInterval > 0 {
+		return 3 * m.config.Worker.HeartbeatInterval
+	}
+	return 90 * time.Second
+}
@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// withStaleAfter 设置 Worker 名册记录的过期时间
+func withStaleAfter(d time.Duration) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Worker.StaleAfter = d
+	}
+}
+
+// newInstanceManager 创建连接同一个 miniredis、使用另一个实例标识的队列管理器
+func newInstanceManager(t *testing.T, server *miniredis.Miniredis, instanceID string) *Manager {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newTestConfig()
+	cfg.Worker.InstanceID = instanceID
+	cfg.Worker.StaleAfter = time.Minute
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewManager(&Clients{Default: client}, cfg, logger)
+}
+
+// newWorkerStatus 构造一个 Worker 心跳
+func newWorkerStatus(workerID string, modelID uint64) models.WorkerStatus {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return models.WorkerStatus{
+		WorkerID:      workerID,
+		ModelID:       modelID,
+		Status:        "idle",
+		StartTime:     started,
+		LastHeartbeat: started,
+	}
+}
+
+func TestReportWorkerRegistersInRoster(t *testing.T) {
+	m, server := newTestManager(t, withStaleAfter(time.Minute))
+	ctx := context.Background()
+
+	status := newWorkerStatus("worker-1-0", 1)
+	taskID := uint64(42)
+	status.Status = "busy"
+	status.CurrentTaskID = &taskID
+	status.TaskTypes = []string{"embedding", "translation"}
+	if err := m.ReportWorker(ctx, status); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	key := "llm_tasks:workers:test-instance:worker-1-0"
+	if !server.Exists(key) {
+		t.Fatalf("roster key %s not found, keys: %v", key, server.Keys())
+	}
+	if ttl := server.TTL(key); ttl != time.Minute {
+		t.Errorf("roster ttl = %s, want 1m", ttl)
+	}
+
+	workers, err := m.ListWorkers(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(workers) != 1 {
+		t.Fatalf("workers = %+v, want one", workers)
+	}
+	got := workers[0]
+	if got.WorkerID != "worker-1-0" || got.InstanceID != "test-instance" || got.ModelID != 1 || got.Status != "busy" {
+		t.Errorf("worker = %+v", got)
+	}
+	if got.CurrentTaskID == nil || *got.CurrentTaskID != 42 {
+		t.Errorf("current task = %v, want 42", got.CurrentTaskID)
+	}
+	if len(got.TaskTypes) != 2 || got.TaskTypes[0] != "embedding" || got.TaskTypes[1] != "translation" {
+		t.Errorf("task types = %v", got.TaskTypes)
+	}
+	if !got.StartTime.Equal(status.StartTime) {
+		t.Errorf("start time = %s, want %s", got.StartTime, status.StartTime)
+	}
+}
+
+func TestHeartbeatRefreshesRosterTTL(t *testing.T) {
+	m, server := newTestManager(t, withStaleAfter(time.Minute))
+	ctx := context.Background()
+
+	status := newWorkerStatus("worker-1-0", 1)
+	if err := m.ReportWorker(ctx, status); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	// 过期前再次心跳，TTL 重新计算，记录更新为最新状态
+	server.FastForward(50 * time.Second)
+	status.LastHeartbeat = status.LastHeartbeat.Add(50 * time.Second)
+	if err := m.ReportWorker(ctx, status); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	server.FastForward(50 * time.Second)
+
+	workers, err := m.ListWorkers(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(workers) != 1 {
+		t.Fatalf("workers = %+v, want worker kept alive by heartbeat", workers)
+	}
+	if !workers[0].LastHeartbeat.Equal(status.LastHeartbeat) {
+		t.Errorf("last heartbeat = %s, want %s", workers[0].LastHeartbeat, status.LastHeartbeat)
+	}
+}
+
+func TestRosterEntryExpiresWithoutHeartbeat(t *testing.T) {
+	m, server := newTestManager(t, withStaleAfter(time.Minute))
+	ctx := context.Background()
+
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-1", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	// worker-1-1 继续心跳，worker-1-0 停滞
+	server.FastForward(40 * time.Second)
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-1", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	server.FastForward(30 * time.Second)
+
+	workers, err := m.ListWorkers(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(workers) != 1 || workers[0].WorkerID != "worker-1-1" {
+		t.Fatalf("workers = %+v, want only worker-1-1", workers)
+	}
+}
+
+func TestRemoveWorkerDeletesRosterEntry(t *testing.T) {
+	m, _ := newTestManager(t, withStaleAfter(time.Minute))
+	ctx := context.Background()
+
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if err := m.RemoveWorker(ctx, "worker-1-0"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	workers, err := m.ListWorkers(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(workers) != 0 {
+		t.Fatalf("workers = %+v, want none", workers)
+	}
+}
+
+func TestGlobalWorkerStatusGroupsInstances(t *testing.T) {
+	m, server := newTestManager(t, withStaleAfter(time.Minute))
+	other := newInstanceManager(t, server, "other-instance")
+	ctx := context.Background()
+
+	// 两个实例中相同 ID 的 Worker 互不覆盖
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if err := other.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	late := newWorkerStatus("worker-2-0", 2)
+	late.LastHeartbeat = late.LastHeartbeat.Add(time.Minute)
+	if err := other.ReportWorker(ctx, late); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	instances, err := m.GetGlobalWorkerStatus(ctx)
+	if err != nil {
+		t.Fatalf("global status: %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("instances = %+v, want 2", instances)
+	}
+	if instances[0].InstanceID != "other-instance" || len(instances[0].Workers) != 2 {
+		t.Errorf("first instance = %+v, want other-instance with 2 workers", instances[0])
+	}
+	if !instances[0].LastHeartbeat.Equal(late.LastHeartbeat) {
+		t.Errorf("instance heartbeat = %s, want latest worker heartbeat %s", instances[0].LastHeartbeat, late.LastHeartbeat)
+	}
+	if instances[1].InstanceID != "test-instance" || len(instances[1].Workers) != 1 {
+		t.Errorf("second instance = %+v, want test-instance with 1 worker", instances[1])
+	}
+}
+
+func TestGlobalWorkerStatusExcludesStaleInstances(t *testing.T) {
+	m, server := newTestManager(t, withStaleAfter(time.Minute))
+	crashed := newInstanceManager(t, server, "crashed-instance")
+	ctx := context.Background()
+
+	for _, id := range []string{"worker-1-0", "worker-1-1"} {
+		if err := crashed.ReportWorker(ctx, newWorkerStatus(id, 1)); err != nil {
+			t.Fatalf("report: %v", err)
+		}
+	}
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	// crashed-instance 停止心跳，本实例持续心跳；超过 stale_after 后只统计本实例
+	server.FastForward(40 * time.Second)
+	if err := m.ReportWorker(ctx, newWorkerStatus("worker-1-0", 1)); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	server.FastForward(30 * time.Second)
+
+	instances, err := m.GetGlobalWorkerStatus(ctx)
+	if err != nil {
+		t.Fatalf("global status: %v", err)
+	}
+	if len(instances) != 1 || instances[0].InstanceID != "test-instance" {
+		t.Fatalf("instances = %+v, want only test-instance", instances)
+	}
+	if len(instances[0].Workers) != 1 {
+		t.Errorf("workers = %+v, want 1", instances[0].Workers)
+	}
+}
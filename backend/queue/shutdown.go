@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DrainOnShutdown 停止服务前的最后一次整理：把已经到期的延迟任务移回就绪队列，
+// 处理中队列保持不变，由下次启动后的卡住任务清理处理；记录各后端遗留的任务数便于排查
+func (m *Manager) DrainOnShutdown(ctx context.Context) error {
+	if err := m.ProcessDelayedTasks(ctx); err != nil {
+		return err
+	}
+
+	for name, client := range m.allClients() {
+		delayed, err := client.ZCard(ctx, m.config.Queue.DelayedQueue).Result()
+		if err != nil {
+			return fmt.Errorf("failed to count delayed tasks on backend %s: %w", name, err)
+		}
+		processing, err := client.ZCard(ctx, m.config.Queue.ProcessingQueue).Result()
+		if err != nil {
+			return fmt.Errorf("failed to count processing tasks on backend %s: %w", name, err)
+		}
+
+		m.logger.WithFields(logrus.Fields{
+			"backend":    name,
+			"delayed":    delayed,
+			"processing": processing,
+		}).Info("Queue state left at shutdown")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDrainOnShutdown(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+	var buf bytes.Buffer
+	m.logger.SetOutput(&buf)
+	m.logger.SetFormatter(&logrus.JSONFormatter{})
+
+	// 任务 1 已经到期，任务 2 一小时后才到期，任务 3 仍在执行中
+	if err := m.enqueueAt(ctx, &QueueItem{TaskID: 1, ModelID: 1, Priority: 2, CreatedAt: time.Now()}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("enqueue delayed: %v", err)
+	}
+	if err := m.enqueueAt(ctx, &QueueItem{TaskID: 2, ModelID: 1, Priority: 2, CreatedAt: time.Now()}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("enqueue delayed: %v", err)
+	}
+	mustEnqueue(t, m, newTestTask(3, 1, ""))
+	if item, err := m.DequeueTask(ctx, 1, nil); err != nil || item == nil || item.TaskID != 3 {
+		t.Fatalf("dequeue = %+v (err %v), want task 3", item, err)
+	}
+
+	if err := m.DrainOnShutdown(ctx); err != nil {
+		t.Fatalf("DrainOnShutdown: %v", err)
+	}
+
+	// 到期的延迟任务回到就绪队列，未到期的留在延迟队列，处理中的任务保持不变
+	item, err := m.DequeueTask(ctx, 1, nil)
+	if err != nil || item == nil || item.TaskID != 1 {
+		t.Fatalf("dequeue after drain = %+v (err %v), want task 1", item, err)
+	}
+	if next, err := m.DequeueTask(ctx, 1, nil); err != nil || next != nil {
+		t.Fatalf("unexpected ready task after drain: %+v (err %v)", next, err)
+	}
+	processing := map[uint64]bool{}
+	for _, item := range processingItems(t, m) {
+		processing[item.TaskID] = true
+	}
+	if len(processing) != 2 || !processing[1] || !processing[3] {
+		t.Fatalf("processing = %v, want task 3 kept and task 1 claimed", processing)
+	}
+
+	// 日志中记录遗留的延迟和处理中任务数（统计发生在再次出队之前）
+	var entry struct {
+		Msg        string `json:"msg"`
+		Backend    string `json:"backend"`
+		Delayed    int64  `json:"delayed"`
+		Processing int64  `json:"processing"`
+	}
+	found := false
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if err := json.Unmarshal(line, &entry); err == nil && entry.Msg == "Queue state left at shutdown" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("missing shutdown state log:\n%s", buf.String())
+	}
+	if entry.Delayed != 1 || entry.Processing != 1 {
+		t.Fatalf("shutdown log = %+v, want 1 delayed and 1 processing", entry)
+	}
+}
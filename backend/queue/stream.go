@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PublishTaskEvent 发布任务输出流事件
+func (m *Manager) PublishTaskEvent(ctx context.Context, event *models.TaskStreamEvent) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+
+	if err := m.client.Publish(ctx, m.getStreamChannel(event.TaskID), eventBytes).Err(); err != nil {
+		return fmt.Errorf("failed to publish stream event: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeTaskEvents 订阅任务输出流，调用方负责关闭返回的 PubSub
+func (m *Manager) SubscribeTaskEvents(ctx context.Context, taskID uint64) (*redis.PubSub, error) {
+	pubsub := m.client.Subscribe(ctx, m.getStreamChannel(taskID))
+
+	// 等待订阅确认，保证之后发布的事件不会丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe task stream: %w", err)
+	}
+
+	return pubsub, nil
+}
+
+// getStreamChannel 获取任务输出流频道名
+func (m *Manager) getStreamChannel(taskID uint64) string {
+	prefix := m.config.Queue.StreamChannel
+	if prefix == "" {
+		prefix = "llm_tasks:stream"
+	}
+	return fmt.Sprintf("%s:%d", prefix, taskID)
+}
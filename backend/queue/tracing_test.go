@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useMemoryTracer 将全局 TracerProvider 替换为同步写入内存导出器的实现，测试结束后恢复
+func useMemoryTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(&config.Config{}, sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestQueueItemCarriesTraceContext(t *testing.T) {
+	exporter := useMemoryTracer(t)
+	m, _ := newTestManager(t, nil)
+
+	ctx, root := tracing.Tracer().Start(context.Background(), "POST /api/v1/tasks")
+	mustEnqueue(t, m, newTestTask(1, 1, models.TaskTypeTextGeneration))
+	if err := m.EnqueueTask(ctx, newTestTask(2, 1, models.TaskTypeTextGeneration)); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	root.End()
+
+	first, err := m.DequeueTask(context.Background(), 1, nil)
+	if err != nil || first == nil {
+		t.Fatalf("dequeue = %v, %v, want task 1", first, err)
+	}
+	second, err := m.DequeueTask(context.Background(), 1, nil)
+	if err != nil || second == nil || second.TaskID != 2 {
+		t.Fatalf("dequeue = %v, %v, want task 2", second, err)
+	}
+
+	// 没有上游追踪的任务自成一个追踪，有上游追踪的任务延续请求的追踪
+	rootTraceID := root.SpanContext().TraceID()
+	if got := traceIDOf(t, first.TraceParent); got == rootTraceID.String() {
+		t.Errorf("task 1 traceparent joined unrelated request trace")
+	}
+	if got := traceIDOf(t, second.TraceParent); got != rootTraceID.String() {
+		t.Errorf("task 2 trace = %s, want request trace %s", got, rootTraceID)
+	}
+
+	spans := make(map[string][]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+	if len(spans["queue.enqueue"]) != 2 || len(spans["queue.dequeue"]) != 2 {
+		t.Fatalf("spans = %d enqueue and %d dequeue, want 2 each", len(spans["queue.enqueue"]), len(spans["queue.dequeue"]))
+	}
+	enqueue, dequeue := spans["queue.enqueue"][1], spans["queue.dequeue"][1]
+	if enqueue.Parent.SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("enqueue parent = %s, want request span", enqueue.Parent.SpanID())
+	}
+	if dequeue.Parent.SpanID() != enqueue.SpanContext.SpanID() {
+		t.Errorf("dequeue parent = %s, want enqueue span %s", dequeue.Parent.SpanID(), enqueue.SpanContext.SpanID())
+	}
+}
+
+func TestDequeueEmptyQueueRecordsNoSpan(t *testing.T) {
+	exporter := useMemoryTracer(t)
+	m, _ := newTestManager(t, nil)
+
+	item, err := m.DequeueTask(context.Background(), 1, nil)
+	if err != nil || item != nil {
+		t.Fatalf("dequeue = %v, %v, want empty queue", item, err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("spans = %d, want none for empty poll", len(spans))
+	}
+}
+
+// traceIDOf 解析 traceparent 中的追踪 ID
+func traceIDOf(t *testing.T, traceParent string) string {
+	t.Helper()
+	// 格式为 version-traceid-spanid-flags
+	if len(traceParent) != 55 {
+		t.Fatalf("traceparent = %q, want W3C format", traceParent)
+	}
+	return traceParent[3:35]
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"sync"
+
+	"llm-scheduler/models"
+)
+
+// priorities 按优先级从高到低排列
+var priorities = []models.TaskPriority{
+	models.TaskPriorityHigh,
+	models.TaskPriorityMedium,
+	models.TaskPriorityLow,
+}
+
+// priorityCursor 平滑加权轮询的游标，记录各优先级当前的累计权重
+type priorityCursor struct {
+	mu      sync.Mutex
+	current map[models.TaskPriority]int
+}
+
+// dequeueOrder 获取本次出队检查各优先级队列的顺序。
+// 按 queue.weights 加权轮询选出首先检查的优先级，其余优先级按从高到低排在后面，
+// 首选队列为空时仍会检查其他队列；未配置权重时严格按优先级从高到低。
+func (m *Manager) dequeueOrder(minPriority models.TaskPriority) []models.TaskPriority {
+	var eligible []models.TaskPriority
+	for _, priority := range priorities {
+		if priority >= minPriority {
+			eligible = append(eligible, priority)
+		}
+	}
+
+	first, ok := m.nextWeightedPriority(eligible)
+	if !ok {
+		return eligible
+	}
+
+	order := make([]models.TaskPriority, 0, len(eligible))
+	order = append(order, first)
+	for _, priority := range eligible {
+		if priority != first {
+			order = append(order, priority)
+		}
+	}
+	return order
+}
+
+// nextWeightedPriority 平滑加权轮询：每轮各优先级累加自身权重，选出累计值最大者并减去总权重。
+// 权重为 5:3:1 时每 9 次出队中高、中、低优先级分别首选 5、3、1 次，且交错分布
+func (m *Manager) nextWeightedPriority(eligible []models.TaskPriority) (models.TaskPriority, bool) {
+	m.cursor.mu.Lock()
+	defer m.cursor.mu.Unlock()
+
+	if m.cursor.current == nil {
+		m.cursor.current = make(map[models.TaskPriority]int)
+	}
+
+	total := 0
+	var best models.TaskPriority
+	for _, priority := range eligible {
+		weight := m.getPriorityWeight(priority)
+		if weight <= 0 {
+			continue
+		}
+		m.cursor.current[priority] += weight
+		total += weight
+		if best == 0 || m.cursor.current[priority] > m.cursor.current[best] {
+			best = priority
+		}
+	}
+
+	if total == 0 {
+		return 0, false
+	}
+
+	m.cursor.current[best] -= total
+	return best, true
+}
+
+// getPriorityWeight 获取优先级的出队权重
+func (m *Manager) getPriorityWeight(priority models.TaskPriority) int {
+	weights := m.config.Queue.Weights
+	switch priority {
+	case models.TaskPriorityHigh:
+		return weights.High
+	case models.TaskPriorityMedium:
+		return weights.Medium
+	case models.TaskPriorityLow:
+		return weights.Low
+	default:
+		return 0
+	}
+}
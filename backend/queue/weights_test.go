@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// withWeights 设置出队权重
+func withWeights(high, medium, low int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Queue.Weights = config.PriorityWeights{High: high, Medium: medium, Low: low}
+	}
+}
+
+// newPriorityTask 构造指定优先级的任务
+func newPriorityTask(id uint64, priority models.TaskPriority) *models.Task {
+	task := newTestTask(id, 1, "text-generation")
+	task.Priority = priority
+	return task
+}
+
+func TestWeightedDequeueBoundsLowPriorityWait(t *testing.T) {
+	m, _ := newTestManager(t, withWeights(5, 3, 1))
+	ctx := context.Background()
+
+	// 持续有高优先级任务到达，低优先级任务只有一个
+	const lowTaskID = 1
+	mustEnqueue(t, m, newPriorityTask(lowTaskID, models.TaskPriorityLow))
+	nextID := uint64(100)
+	for i := 0; i < 3; i++ {
+		mustEnqueue(t, m, newPriorityTask(nextID, models.TaskPriorityHigh))
+		nextID++
+	}
+
+	// 权重 5:3:1 时每 9 次出队至少首选一次低优先级队列
+	const bound = 9
+	for i := 1; i <= bound; i++ {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil {
+			t.Fatalf("dequeue %d: got %+v, %v", i, item, err)
+		}
+		if item.TaskID == lowTaskID {
+			return
+		}
+		mustEnqueue(t, m, newPriorityTask(nextID, models.TaskPriorityHigh))
+		nextID++
+	}
+	t.Fatalf("low priority task not dequeued within %d dequeues under continuous high priority load", bound)
+}
+
+func TestWeightedDequeueFollowsRatio(t *testing.T) {
+	m, _ := newTestManager(t, withWeights(5, 3, 1))
+	ctx := context.Background()
+
+	nextID := uint64(1)
+	for _, priority := range priorities {
+		for i := 0; i < 9; i++ {
+			mustEnqueue(t, m, newPriorityTask(nextID, priority))
+			nextID++
+		}
+	}
+
+	counts := make(map[int]int)
+	for i := 0; i < 9; i++ {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil {
+			t.Fatalf("dequeue %d: got %+v, %v", i, item, err)
+		}
+		counts[item.Priority]++
+	}
+
+	want := map[int]int{int(models.TaskPriorityHigh): 5, int(models.TaskPriorityMedium): 3, int(models.TaskPriorityLow): 1}
+	for priority, n := range want {
+		if counts[priority] != n {
+			t.Errorf("priority %d dequeued %d times in 9, want %d", priority, counts[priority], n)
+		}
+	}
+}
+
+func TestWeightedDequeueFallsBackWhenPreferredQueueEmpty(t *testing.T) {
+	m, _ := newTestManager(t, withWeights(5, 3, 1))
+	ctx := context.Background()
+
+	// 只有低优先级任务时每次出队都能取到，不会因首选队列为空而空转
+	for id := uint64(1); id <= 3; id++ {
+		mustEnqueue(t, m, newPriorityTask(id, models.TaskPriorityLow))
+	}
+	for id := uint64(1); id <= 3; id++ {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil || item.TaskID != id {
+			t.Fatalf("expected low task %d, got %+v, %v", id, item, err)
+		}
+	}
+}
+
+func TestUnweightedDequeueIsStrictPriority(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	ctx := context.Background()
+
+	mustEnqueue(t, m, newPriorityTask(1, models.TaskPriorityLow))
+	for id := uint64(2); id <= 20; id++ {
+		mustEnqueue(t, m, newPriorityTask(id, models.TaskPriorityHigh))
+	}
+
+	for i := 0; i < 19; i++ {
+		item, err := m.DequeueTask(ctx, 1, nil)
+		if err != nil || item == nil {
+			t.Fatalf("dequeue %d: got %+v, %v", i, item, err)
+		}
+		if item.Priority != int(models.TaskPriorityHigh) {
+			t.Fatalf("dequeue %d got priority %d before high priority queue drained", i, item.Priority)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package routes
 
 import (
+	"llm-scheduler/config"
 	"llm-scheduler/handlers"
 	"llm-scheduler/queue"
 	"llm-scheduler/services"
 	"llm-scheduler/utils"
+	"llm-scheduler/worker"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -15,68 +17,122 @@ import (
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(
 	router *gin.Engine,
+	cfg *config.Config,
+	db *gorm.DB,
+	redisClient *redis.Client,
 	taskService *services.TaskService,
 	modelService *services.ModelService,
+	scheduleService *services.ScheduleService,
 	statsService *services.StatsService,
 	queueManager *queue.Manager,
+	workerManager *worker.Manager,
 	logger *logrus.Logger,
 ) {
-	// 获取依赖（这里需要修改，实际应该从参数传入）
-	var db *gorm.DB
-	var redisClient *redis.Client
-	
 	// 创建处理器
-	taskHandler := handlers.NewTaskHandler(taskService, logger)
-	modelHandler := handlers.NewModelHandler(modelService, logger)
+	taskHandler := handlers.NewTaskHandler(taskService, cfg, logger)
+	modelHandler := handlers.NewModelHandler(modelService, taskService, logger)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService, logger)
 	statsHandler := handlers.NewStatsHandler(statsService, logger)
-	systemHandler := handlers.NewSystemHandler(db, redisClient, queueManager, logger)
+	systemHandler := handlers.NewSystemHandler(db, redisClient, queueManager, taskService, workerManager, logger)
+	workerHandler := handlers.NewWorkerHandler(workerManager, modelService, logger)
+	queueHandler := handlers.NewQueueHandler(queueManager, taskService, logger)
 
 	// 添加中间件
+	router.Use(utils.RequestIDMiddleware())
+	router.Use(utils.TracingMiddleware())
 	router.Use(utils.RequestLoggerMiddleware(logger))
 	router.Use(utils.ErrorHandlerMiddleware(logger))
 
 	// API 版本分组
 	v1 := router.Group("/api/v1")
+	v1.Use(utils.BodyLimitMiddleware(cfg.Server.GetMaxBodyBytes()))
+	v1.Use(utils.AuthMiddleware(&cfg.Auth))
+	v1.Use(utils.RateLimitMiddleware(redisClient, &cfg.Auth, logger))
+
+	// 修改数据或影响调度的接口只允许 admin 调用，viewer 只能查询
+	admin := utils.RequireRole(config.RoleAdmin)
 	{
 		// 系统相关路由
 		system := v1.Group("/system")
 		{
 			system.GET("/health", systemHandler.HealthCheck)
 			system.GET("/info", systemHandler.GetSystemInfo)
+			system.GET("/degraded", systemHandler.GetDegradedMode)             // 降级模式状态
+			system.PUT("/degraded", admin, systemHandler.SetDegradedMode)      // 进入降级模式
+			system.DELETE("/degraded", admin, systemHandler.ClearDegradedMode) // 退出降级模式
+			system.GET("/log-level", systemHandler.GetLogLevel)                // 当前日志级别
+			system.PUT("/log-level", admin, systemHandler.SetLogLevel)         // 修改日志级别
+			system.POST("/recover", admin, systemHandler.RecoverTasks)         // 恢复遗留的 running 任务
+		}
+
+		// 队列相关路由
+		queues := v1.Group("/queue")
+		{
+			queues.GET("/status", queueHandler.GetQueueStatus)          // 队列状态
+			queues.GET("/peek", queueHandler.PeekQueue)                 // 查看即将执行的任务
+			queues.DELETE("/:priority", admin, queueHandler.PurgeQueue) // 清空指定优先级队列
 		}
 
 		// 任务相关路由
 		tasks := v1.Group("/tasks")
 		{
-			tasks.POST("", taskHandler.CreateTask)           // 创建任务
-			tasks.GET("", taskHandler.ListTasks)            // 获取任务列表
-			tasks.GET("/:id", taskHandler.GetTask)          // 获取任务详情
-			tasks.PUT("/:id", taskHandler.UpdateTask)       // 更新任务
-			tasks.DELETE("/:id", taskHandler.CancelTask)    // 取消任务
-			tasks.POST("/:id/retry", taskHandler.RetryTask) // 重试任务
-			tasks.GET("/stats", taskHandler.GetTaskStats)   // 任务统计
+			tasks.POST("", admin, taskHandler.CreateTask)                  // 创建任务
+			tasks.POST("/batch", admin, taskHandler.CreateTasks)           // 批量创建任务
+			tasks.POST("/bulk/cancel", admin, taskHandler.BulkCancelTasks) // 按条件批量取消
+			tasks.POST("/bulk/retry", admin, taskHandler.BulkRetryTasks)   // 按条件批量重试
+			tasks.GET("", taskHandler.ListTasks)                           // 获取任务列表
+			tasks.GET("/:id", taskHandler.GetTask)                         // 获取任务详情
+			tasks.PUT("/:id", admin, taskHandler.UpdateTask)               // 更新任务
+			tasks.DELETE("/:id", admin, taskHandler.CancelTask)            // 取消任务
+			tasks.POST("/:id/retry", admin, taskHandler.RetryTask)         // 重试任务
+			tasks.GET("/:id/stream", taskHandler.StreamTask)               // 任务输出流 (SSE)
+			tasks.GET("/:id/logs", taskHandler.ListTaskLogs)               // 任务日志
+			tasks.GET("/:id/logs/stream", taskHandler.StreamTaskLogs)      // 实时日志流 (SSE)
+			tasks.GET("/:id/timeline", taskHandler.GetTaskTimeline)        // 任务执行时间线
+			tasks.GET("/:id/export", taskHandler.ExportTask)               // 导出任务、日志和模型快照
+			tasks.GET("/stats", taskHandler.GetTaskStats)                  // 任务统计
+			tasks.DELETE("/cleanup", admin, taskHandler.CleanupTasks)      // 归档历史任务
 		}
 
 		// 模型相关路由
 		models := v1.Group("/models")
 		{
-			models.POST("", modelHandler.CreateModel)                    // 创建模型
-			models.GET("", modelHandler.ListModels)                     // 获取模型列表
-			models.GET("/available", modelHandler.GetAvailableModels)   // 获取可用模型
-			models.GET("/stats", modelHandler.GetModelStats)            // 模型统计
-			models.GET("/:id", modelHandler.GetModel)                   // 获取模型详情
-			models.PUT("/:id", modelHandler.UpdateModel)                // 更新模型
-			models.DELETE("/:id", modelHandler.DeleteModel)             // 删除模型
-			models.PUT("/:id/status", modelHandler.UpdateModelStatus)   // 更新模型状态
+			models.POST("", admin, modelHandler.CreateModel)                    // 创建模型
+			models.PUT("", admin, modelHandler.UpsertModel)                     // 按名称创建或更新模型
+			models.GET("", modelHandler.ListModels)                             // 获取模型列表
+			models.GET("/available", modelHandler.GetAvailableModels)           // 获取可用模型
+			models.GET("/stats", modelHandler.GetModelStats)                    // 模型统计
+			models.GET("/:id", modelHandler.GetModel)                           // 获取模型详情
+			models.PUT("/:id", admin, modelHandler.UpdateModel)                 // 更新模型
+			models.PATCH("/:id/config", admin, modelHandler.MergeModelConfig)   // 合并更新模型配置
+			models.DELETE("/:id", admin, modelHandler.DeleteModel)              // 删除模型
+			models.PUT("/:id/status", admin, modelHandler.UpdateModelStatus)    // 更新模型状态
+			models.POST("/:id/test", admin, workerHandler.TestModel)            // 测试模型连通性
+			models.GET("/:id/workers", workerHandler.GetModelWorkers)           // 获取 Worker 池状态
+			models.PUT("/:id/workers", admin, workerHandler.SetModelWorkers)    // 设置目标 Worker 数量
+			models.POST("/:id/workers", admin, workerHandler.ScaleModelWorkers) // 扩缩容 Worker 并更新最大数量
+		}
+
+		// 定时任务相关路由
+		schedules := v1.Group("/schedules")
+		{
+			schedules.POST("", admin, scheduleHandler.CreateSchedule)       // 创建定时任务
+			schedules.GET("", scheduleHandler.ListSchedules)                // 获取定时任务列表
+			schedules.GET("/:id", scheduleHandler.GetSchedule)              // 获取定时任务详情
+			schedules.PUT("/:id", admin, scheduleHandler.UpdateSchedule)    // 更新定时任务
+			schedules.DELETE("/:id", admin, scheduleHandler.DeleteSchedule) // 删除定时任务
 		}
 
 		// 统计相关路由
 		stats := v1.Group("/stats")
 		{
-			stats.GET("/dashboard", statsHandler.GetDashboardStats)      // Dashboard 统计
-			stats.GET("/tasks/date", statsHandler.GetTaskStatsByDate)    // 按日期统计任务
-			stats.GET("/tasks/model", statsHandler.GetTaskStatsByModel)  // 按模型统计任务
-			stats.GET("/tasks/type", statsHandler.GetTaskStatsByType)    // 按类型统计任务
+			stats.GET("/dashboard", statsHandler.GetDashboardStats)     // Dashboard 统计
+			stats.GET("/tasks/date", statsHandler.GetTaskStatsByDate)   // 按日期统计任务
+			stats.GET("/tasks/model", statsHandler.GetTaskStatsByModel) // 按模型统计任务
+			stats.GET("/tasks/type", statsHandler.GetTaskStatsByType)   // 按类型统计任务
+			stats.GET("/cost", statsHandler.GetCostSummary)             // 费用统计
+			stats.GET("/queue/history", statsHandler.GetQueueHistory)   // 队列深度历史
+			stats.GET("/latency", statsHandler.GetLatencyPercentiles)   // 处理耗时分位数
 		}
 	}
 
@@ -0,0 +1,233 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/database/testdb"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/services"
+	"llm-scheduler/worker"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestConfig 返回与 config.yaml 一致的队列键名，并启用 admin 和 viewer 两个 API Key
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		CancelChannel:       "llm_tasks:cancel",
+		EventChannel:        "llm_tasks:events",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+		TaskTimeout:         5 * time.Minute,
+		RetryDelay:          time.Minute,
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	cfg.Auth = config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{
+			{Name: "ops", Key: "admin-key", Role: config.RoleAdmin},
+			{Name: "dashboard", Key: "viewer-key", Role: config.RoleViewer},
+		},
+		ExemptPaths: []string{"/api/v1/system/health"},
+	}
+	return cfg
+}
+
+// newTestRouter 按 main 的方式组装服务并注册全部路由，configure 可在创建服务前修改配置
+func newTestRouter(t *testing.T, configure func(*config.Config)) *gin.Engine {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db := testdb.New(t)
+	model := &models.Model{Name: "test-model", Type: models.ModelTypeCustom, Config: models.ModelConfig{}, Status: models.ModelStatusOnline, MaxWorkers: 1}
+	if err := db.Create(model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	taskService := services.NewTaskService(db, queueManager, nil, cfg, logger)
+	modelService := services.NewModelService(db, logger)
+	scheduleService := services.NewScheduleService(db, taskService, logger)
+	workerManager := worker.NewManager(cfg, db, queueManager, taskService, modelService, scheduleService, logger)
+	statsService := services.NewStatsService(db, queueManager, workerManager, logger)
+
+	router := gin.New()
+	RegisterRoutes(router, cfg, db, client, taskService, modelService, scheduleService, statsService, queueManager, workerManager, logger)
+	return router
+}
+
+// request 发送请求，key 不为空时携带 X-API-Key
+func request(router http.Handler, method, url, key, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, url, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHealthReportsWorkerLiveness(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	// 健康检查在免认证路径中，不需要 API Key
+	w := request(router, http.MethodGet, "/api/v1/system/health", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Status       string                `json:"status"`
+			Database     string                `json:"database"`
+			Redis        string                `json:"redis"`
+			Workers      string                `json:"workers"`
+			WorkerHealth []models.WorkerHealth `json:"worker_health"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Status != "ok" || resp.Data.Database != "ok" || resp.Data.Redis != "ok" || resp.Data.Workers != "ok" {
+		t.Fatalf("health = %+v, want all ok", resp.Data)
+	}
+	if resp.Data.WorkerHealth == nil {
+		t.Fatalf("health response has no worker breakdown: %s", w.Body.String())
+	}
+}
+
+func TestLogLevelRequiresAdmin(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"no key", "", http.StatusUnauthorized},
+		{"viewer", "viewer-key", http.StatusForbidden},
+		{"admin", "admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodPut, "/api/v1/system/log-level", tt.key, `{"level":"warn"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	// viewer 可以查看当前级别
+	if w := request(router, http.MethodGet, "/api/v1/system/log-level", "viewer-key", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"warning"`) {
+		t.Fatalf("GET log-level = %d %s, want warning", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIRequestBodyLimit(t *testing.T) {
+	router := newTestRouter(t, func(cfg *config.Config) { cfg.Server.MaxBodyBytes = 256 })
+	small := `{"model_id":1,"type":"text-generation","input":"hello"}`
+	large := `{"model_id":1,"type":"text-generation","input":"` + strings.Repeat("x", 512) + `"}`
+
+	tests := []struct {
+		name    string
+		url     string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"within limit", "/api/v1/tasks", small, false, http.StatusOK},
+		// Content-Length 超过上限时不读取请求体直接拒绝
+		{"content length over limit", "/api/v1/tasks", large, false, http.StatusRequestEntityTooLarge},
+		// 未声明长度的请求在读取超过上限时拒绝
+		{"chunked body over limit", "/api/v1/tasks", large, true, http.StatusRequestEntityTooLarge},
+		{"other endpoint", "/api/v1/tasks/batch", `{"tasks":[` + large + `]}`, false, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Key", "admin-key")
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateTaskRejectsUnknownFields(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	tests := []struct {
+		name  string
+		url   string
+		body  string
+		field string
+	}{
+		// 拼写错误的字段不再被静默忽略
+		{"single task", "/api/v1/tasks", `{"model_id":1,"type":"text-generation","input":"hello","priorty":3}`, "priorty"},
+		{"batch", "/api/v1/tasks/batch", `{"tasks":[{"model_id":1,"type":"text-generation","input":"hello"}],"dry_run":true}`, "dry_run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodPost, tt.url, "admin-key", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data []models.FieldError `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Data) != 1 || resp.Data[0].Field != tt.field || resp.Data[0].Tag != "unknown" {
+				t.Fatalf("field errors = %+v, want unknown field %s", resp.Data, tt.field)
+			}
+		})
+	}
+}
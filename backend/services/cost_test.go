@@ -0,0 +1,105 @@
+package services
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newStatsService 创建不统计 Worker 的统计服务
+func newStatsService(env *testEnv) *StatsService {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewStatsService(env.db, env.queue, nil, logger)
+}
+
+func TestCompleteTaskRecordsUsage(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning, nil)
+
+	usage := models.TokenUsage{PromptTokens: 120, CompletionTokens: 80, CostUSD: 0.25}
+	if err := env.tasks.CompleteTask(task.ID, "done", models.OutputFormatText, usage); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.PromptTokens != 120 || got.CompletionTokens != 80 || math.Abs(got.CostUSD-0.25) > 1e-9 {
+		t.Fatalf("task usage = %d/%d/%v, want 120/80/0.25", got.PromptTokens, got.CompletionTokens, got.CostUSD)
+	}
+
+	// 模型累计用量
+	modelService := newModelService(env)
+	for _, u := range []models.TokenUsage{usage, {PromptTokens: 30, CompletionTokens: 20, CostUSD: 0.05}, {}} {
+		if err := modelService.RecordUsage(env.modelID, u); err != nil {
+			t.Fatalf("RecordUsage: %v", err)
+		}
+	}
+	model, err := modelService.GetModel(env.modelID)
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if model.TotalPromptTokens != 150 || model.TotalCompletionTokens != 100 || math.Abs(model.TotalCostUSD-0.3) > 1e-9 {
+		t.Fatalf("model totals = %d/%d/%v, want 150/100/0.3", model.TotalPromptTokens, model.TotalCompletionTokens, model.TotalCostUSD)
+	}
+}
+
+func TestCostSummaryRollsUpByModelAndType(t *testing.T) {
+	env := newTestEnv(t, nil)
+	other := env.createModel(t, "other-model", models.ModelTypeCustom, models.ModelStatusOnline, 0)
+
+	withUsage := func(modelID uint64, taskType string, prompt, completion int, cost float64) func(*models.Task) {
+		return func(task *models.Task) {
+			task.ModelID = modelID
+			task.Type = taskType
+			task.PromptTokens = prompt
+			task.CompletionTokens = completion
+			task.CostUSD = cost
+		}
+	}
+	env.createTask(t, models.TaskStatusCompleted, withUsage(env.modelID, "summarization", 100, 50, 1.0))
+	env.createTask(t, models.TaskStatusCompleted, withUsage(env.modelID, "translation", 200, 100, 2.0))
+	env.createTask(t, models.TaskStatusCompleted, withUsage(other.ID, "summarization", 10, 5, 0.5))
+	// 统计窗口之外的任务不计入
+	old := env.createTask(t, models.TaskStatusCompleted, withUsage(other.ID, "summarization", 1000, 1000, 10))
+	env.db.Model(old).UpdateColumn("created_at", time.Now().AddDate(0, 0, -31))
+
+	summary, err := newStatsService(env).GetCostSummary(30)
+	if err != nil {
+		t.Fatalf("GetCostSummary: %v", err)
+	}
+	if summary.Days != 30 || summary.PromptTokens != 310 || summary.CompletionTokens != 155 || math.Abs(summary.CostUSD-3.5) > 1e-9 {
+		t.Fatalf("summary totals = %+v", summary)
+	}
+
+	check := func(kind string, got []models.CostBreakdown, want []models.CostBreakdown) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s breakdown = %+v, want %+v", kind, got, want)
+		}
+		for i := range want {
+			g, w := got[i], want[i]
+			if g.Name != w.Name || g.Tasks != w.Tasks || g.PromptTokens != w.PromptTokens || g.CompletionTokens != w.CompletionTokens || math.Abs(g.CostUSD-w.CostUSD) > 1e-9 {
+				t.Fatalf("%s breakdown[%d] = %+v, want %+v", kind, i, g, w)
+			}
+		}
+	}
+	// 按费用从高到低排列
+	check("model", summary.ByModel, []models.CostBreakdown{
+		{Name: "test-model", Tasks: 2, PromptTokens: 300, CompletionTokens: 150, CostUSD: 3.0},
+		{Name: "other-model", Tasks: 1, PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.5},
+	})
+	check("type", summary.ByType, []models.CostBreakdown{
+		{Name: "translation", Tasks: 1, PromptTokens: 200, CompletionTokens: 100, CostUSD: 2.0},
+		{Name: "summarization", Tasks: 2, PromptTokens: 110, CompletionTokens: 55, CostUSD: 1.5},
+	})
+
+	// 没有任务时返回空数组
+	empty, err := newStatsService(newTestEnv(t, nil)).GetCostSummary(30)
+	if err != nil || empty.ByModel == nil || empty.ByType == nil || empty.CostUSD != 0 {
+		t.Fatalf("empty summary = %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+
+	"llm-scheduler/queue"
+)
+
+// 服务层错误，处理器通过 errors.Is 判断并映射 HTTP 状态码
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrScheduleNotFound = errors.New("schedule not found")
+
+	// ErrModelNameExists 模型名称已被占用（包括已删除的模型）
+	ErrModelNameExists = errors.New("model name already exists")
+	// ErrInvalidStatusTransition 任务当前状态不允许该操作
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	// ErrIdempotentRequestInProgress 相同幂等键的请求仍在处理中
+	ErrIdempotentRequestInProgress = errors.New("idempotent request in progress")
+	// ErrIdempotencyKeyMismatch 幂等键已被请求内容不同的请求使用
+	ErrIdempotencyKeyMismatch = queue.ErrIdempotencyKeyMismatch
+	// ErrOutputUnavailable 任务输出保存在外部存储中，但读取失败
+	ErrOutputUnavailable = errors.New("task output unavailable")
+	// ErrDedupKeyActive 已有相同去重键的 pending/running 任务
+	ErrDedupKeyActive = errors.New("another active task has the same dedup key")
+	// ErrQueueFull 等待中的任务数已达到队列上限
+	ErrQueueFull = queue.ErrQueueFull
+)
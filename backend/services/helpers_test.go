@@ -0,0 +1,181 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/database/testdb"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// testEnv 连接内存数据库和 miniredis 的任务服务
+type testEnv struct {
+	cfg     *config.Config
+	db      *gorm.DB
+	redis   *miniredis.Miniredis
+	queue   *queue.Manager
+	tasks   *TaskService
+	modelID uint64
+}
+
+// newTestConfig 返回与 config.yaml 一致的队列键名配置
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		CancelChannel:       "llm_tasks:cancel",
+		EventChannel:        "llm_tasks:events",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+		TaskTimeout:         5 * time.Minute,
+		RetryDelay:          time.Minute,
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	return cfg
+}
+
+// newTestEnv 创建测试环境并登记一个在线模型，configure 可在创建服务前修改配置
+func newTestEnv(t *testing.T, configure func(*config.Config)) *testEnv {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db := testdb.New(t)
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	env := &testEnv{
+		cfg:   cfg,
+		db:    db,
+		redis: server,
+		queue: queueManager,
+		tasks: NewTaskService(db, queueManager, nil, cfg, logger),
+	}
+
+	model := &models.Model{
+		Name:       "test-model",
+		Type:       models.ModelTypeCustom,
+		Config:     models.ModelConfig{},
+		Status:     models.ModelStatusOnline,
+		MaxWorkers: 1,
+	}
+	if err := db.Create(model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	env.modelID = model.ID
+	return env
+}
+
+// createTask 直接在数据库中创建指定状态的任务，configure 可在写入前修改任务
+func (env *testEnv) createTask(t *testing.T, status models.TaskStatus, configure func(*models.Task)) *models.Task {
+	t.Helper()
+	task := &models.Task{
+		ModelID:    env.modelID,
+		Type:       models.TaskTypeTextGeneration,
+		Input:      "hello",
+		Priority:   models.TaskPriorityMedium,
+		Status:     status,
+		MaxRetries: 3,
+	}
+	if configure != nil {
+		configure(task)
+	}
+	if err := env.db.Create(task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+// reloadTask 从数据库读取任务的最新状态
+func (env *testEnv) reloadTask(t *testing.T, id uint64) *models.Task {
+	t.Helper()
+	var task models.Task
+	if err := env.db.First(&task, id).Error; err != nil {
+		t.Fatalf("reload task %d: %v", id, err)
+	}
+	return &task
+}
+
+// queuedItems 返回各优先级就绪队列中的队列项
+func (env *testEnv) queuedItems(t *testing.T) []queue.QueueItem {
+	t.Helper()
+	var items []queue.QueueItem
+	for _, key := range []string{env.cfg.Queue.HighPriorityQueue, env.cfg.Queue.MediumPriorityQueue, env.cfg.Queue.LowPriorityQueue} {
+		if !env.redis.Exists(key) {
+			continue
+		}
+		members, err := env.redis.ZMembers(key)
+		if err != nil {
+			t.Fatalf("read %s: %v", key, err)
+		}
+		for _, member := range members {
+			var item queue.QueueItem
+			if err := json.Unmarshal([]byte(member), &item); err != nil {
+				t.Fatalf("unmarshal queue item: %v", err)
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// queuedTaskIDs 返回各优先级就绪队列中的任务 ID
+func (env *testEnv) queuedTaskIDs(t *testing.T) []uint64 {
+	t.Helper()
+	var ids []uint64
+	for _, item := range env.queuedItems(t) {
+		ids = append(ids, item.TaskID)
+	}
+	return ids
+}
+
+// expireAll 把有序集合中所有成员的分数改为 0：处理中的任务视为超时，延迟任务视为到期
+func (env *testEnv) expireAll(t *testing.T, key string) {
+	t.Helper()
+	members, err := env.redis.ZMembers(key)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+	for _, member := range members {
+		if _, err := env.redis.ZAdd(key, 0, member); err != nil {
+			t.Fatalf("update %s: %v", key, err)
+		}
+	}
+}
+
+// zcard 返回有序集合的成员数，键不存在时为 0
+func (env *testEnv) zcard(t *testing.T, key string) int {
+	t.Helper()
+	if !env.redis.Exists(key) {
+		return 0
+	}
+	members, err := env.redis.ZMembers(key)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+	return len(members)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestMergeConfig(t *testing.T) {
+	base := func() models.ModelConfig {
+		return models.ModelConfig{
+			"host":    "10.0.0.1",
+			"port":    float64(8080),
+			"api_key": "sk-old",
+			"headers": map[string]interface{}{"X-Org": "acme", "X-Env": "prod"},
+		}
+	}
+	tests := []struct {
+		name   string
+		patch  models.ModelConfig
+		want   models.ModelConfig
+		fields string
+	}{
+		{"rotate one key", models.ModelConfig{"api_key": "sk-new"}, models.ModelConfig{
+			"host": "10.0.0.1", "port": float64(8080), "api_key": "sk-new",
+			"headers": map[string]interface{}{"X-Org": "acme", "X-Env": "prod"},
+		}, ""},
+		{"add key", models.ModelConfig{"timeout": "30s"}, models.ModelConfig{
+			"host": "10.0.0.1", "port": float64(8080), "api_key": "sk-old", "timeout": "30s",
+			"headers": map[string]interface{}{"X-Org": "acme", "X-Env": "prod"},
+		}, ""},
+		// 值为 null 的键被删除
+		{"delete key", models.ModelConfig{"api_key": nil}, models.ModelConfig{
+			"host": "10.0.0.1", "port": float64(8080),
+			"headers": map[string]interface{}{"X-Org": "acme", "X-Env": "prod"},
+		}, ""},
+		// 对象递归合并，嵌套的 null 同样删除
+		{"nested merge", models.ModelConfig{"headers": map[string]interface{}{"X-Env": nil, "X-Team": "ml"}}, models.ModelConfig{
+			"host": "10.0.0.1", "port": float64(8080), "api_key": "sk-old",
+			"headers": map[string]interface{}{"X-Org": "acme", "X-Team": "ml"},
+		}, ""},
+		// 合并后的配置不合法时不修改
+		{"invalid template", models.ModelConfig{"prompt_template": "{{.Input"}, base(), "config.prompt_template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			service := newModelService(env)
+			model := env.createModel(t, "configured", models.ModelTypeLocal, models.ModelStatusOnline, 0)
+			if err := env.db.Model(model).Update("config", base()).Error; err != nil {
+				t.Fatalf("set config: %v", err)
+			}
+
+			updated, err := service.MergeConfig(model.ID, tt.patch)
+			if got := strings.Join(fieldNames(t, err), ","); got != tt.fields {
+				t.Fatalf("invalid fields = %q, want %q", got, tt.fields)
+			}
+			if err == nil && !reflect.DeepEqual(updated.Config, tt.want) {
+				t.Fatalf("returned config = %v, want %v", updated.Config, tt.want)
+			}
+
+			stored, err := service.GetModel(model.ID)
+			if err != nil {
+				t.Fatalf("GetModel: %v", err)
+			}
+			if !reflect.DeepEqual(stored.Config, tt.want) {
+				t.Fatalf("stored config = %v, want %v", stored.Config, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfigUnknownModel(t *testing.T) {
+	env := newTestEnv(t, nil)
+	if _, err := newModelService(env).MergeConfig(999, models.ModelConfig{"api_key": "sk"}); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("error = %v, want ErrModelNotFound", err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestDeletedModelStillResolvesForHistoricalTasks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	modelService := newModelService(env)
+	task := env.createTask(t, models.TaskStatusPending, nil)
+
+	// 存在未完成任务时拒绝删除
+	if err := modelService.DeleteModel(env.modelID); err == nil {
+		t.Fatal("expected delete to be refused while a task is pending")
+	}
+
+	setStatus(t, env.db, task.ID, models.TaskStatusCompleted)
+	if err := modelService.DeleteModel(env.modelID); err != nil {
+		t.Fatalf("DeleteModel: %v", err)
+	}
+	if err := modelService.DeleteModel(env.modelID); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("second delete error = %v, want ErrModelNotFound", err)
+	}
+
+	// 已删除的模型不再出现在模型列表和可用模型中
+	if _, err := modelService.GetModel(env.modelID); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("GetModel error = %v, want ErrModelNotFound", err)
+	}
+	if list, err := modelService.ListModels(nil, nil); err != nil || len(list) != 0 {
+		t.Fatalf("ListModels = %v, %v; want empty", list, err)
+	}
+	if list, err := modelService.GetAvailableModels(); err != nil || len(list) != 0 {
+		t.Fatalf("GetAvailableModels = %v, %v; want empty", list, err)
+	}
+
+	// 历史任务仍能展示模型名称
+	got, err := env.tasks.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if got.Model.Name != "test-model" {
+		t.Fatalf("task model name = %q, want test-model", got.Model.Name)
+	}
+	tasks, _, err := env.tasks.ListTasks(&models.TaskListRequest{Page: 1, PageSize: 20, OrderBy: "created_at", Order: "desc"})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Model.Name != "test-model" {
+		t.Fatalf("listed tasks = %+v, want the task with its model name", tasks)
+	}
+
+	// 已删除模型的名称仍被占用
+	_, err = modelService.CreateModel(&models.Model{Name: "test-model", Type: models.ModelTypeCustom, Config: models.ModelConfig{}})
+	if !errors.Is(err, ErrModelNameExists) {
+		t.Fatalf("CreateModel error = %v, want ErrModelNameExists", err)
+	}
+}
+
+func TestDeleteModelDisablesSchedules(t *testing.T) {
+	env := newTestEnv(t, nil)
+	next := time.Now().Add(time.Hour)
+	schedule := &models.ScheduledTask{Name: "nightly", Cron: "0 0 * * *", ModelID: env.modelID, Type: "summarization", InputTemplate: "text", Enabled: true, NextRunAt: &next}
+	if err := env.db.Create(schedule).Error; err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	if err := newModelService(env).DeleteModel(env.modelID); err != nil {
+		t.Fatalf("DeleteModel: %v", err)
+	}
+	var got models.ScheduledTask
+	if err := env.db.First(&got, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	if got.Enabled || got.NextRunAt != nil {
+		t.Fatalf("schedule = enabled %v next_run_at %v, want disabled", got.Enabled, got.NextRunAt)
+	}
+}
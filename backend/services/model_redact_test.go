@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestModelLogsRedactSecrets(t *testing.T) {
+	env := newTestEnv(t, nil)
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	modelService := NewModelService(env.db, logger)
+
+	model, err := modelService.CreateModel(&models.Model{
+		Name:   "secret-model",
+		Type:   models.ModelTypeCustom,
+		Status: models.ModelStatusOnline,
+		Config: models.ModelConfig{"api_key": "sk-create", "base_url": "https://api.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	if _, err := modelService.UpdateModel(model.ID, &models.Model{
+		Config: models.ModelConfig{"api_key": "sk-update", "auth": map[string]interface{}{"password": "pw-update"}},
+	}); err != nil {
+		t.Fatalf("update model: %v", err)
+	}
+	if _, err := modelService.MergeConfig(model.ID, models.ModelConfig{"token": "tok-merge"}); err != nil {
+		t.Fatalf("merge config: %v", err)
+	}
+
+	// 创建、更新和合并配置的日志都不包含密钥，对应字段记录为 ***
+	output := buf.String()
+	for _, secret := range []string{"sk-create", "sk-update", "pw-update", "tok-merge"} {
+		if strings.Contains(output, secret) {
+			t.Fatalf("log output leaks %q:\n%s", secret, output)
+		}
+	}
+	logged := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		msg, _ := entry["msg"].(string)
+		logged[msg] = strings.Contains(line, `"***"`)
+	}
+	for _, msg := range []string{"Model created", "Model updated", "Model config merged"} {
+		if masked, ok := logged[msg]; !ok || !masked {
+			t.Fatalf("log %q present=%v masked=%v, want masked entry:\n%s", msg, ok, masked, output)
+		}
+	}
+
+	// 数据库中保存的仍是真实配置
+	stored, err := modelService.GetModel(model.ID)
+	if err != nil {
+		t.Fatalf("get model: %v", err)
+	}
+	if stored.Config["api_key"] != "sk-update" || stored.Config["token"] != "tok-merge" {
+		t.Fatalf("stored config = %v, want real secrets", stored.Config)
+	}
+}
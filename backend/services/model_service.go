@@ -1,12 +1,14 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 
 	"llm-scheduler/models"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ModelService 模型服务
@@ -25,14 +27,26 @@ func NewModelService(db *gorm.DB, logger *logrus.Logger) *ModelService {
 
 // CreateModel 创建模型
 func (s *ModelService) CreateModel(req *models.Model) (*models.Model, error) {
-	// 检查模型名称是否已存在
+	// 检查模型名称是否已存在，已删除的模型仍占用名称（历史任务按名称展示）
 	var existingModel models.Model
-	if err := s.db.Where("name = ?", req.Name).First(&existingModel).Error; err == nil {
-		return nil, fmt.Errorf("model with name '%s' already exists", req.Name)
+	if err := s.db.Unscoped().Where("name = ?", req.Name).First(&existingModel).Error; err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrModelNameExists, req.Name)
 	} else if err != gorm.ErrRecordNotFound {
 		return nil, fmt.Errorf("failed to check existing model: %w", err)
 	}
 
+	if err := validateModelConfig(req.Config); err != nil {
+		return nil, err
+	}
+	// auto_maintenance 只由健康探测设置
+	req.AutoMaintenance = false
+
+	if req.FallbackModelID != nil {
+		if err := s.validateFallback(0, req.Type, *req.FallbackModelID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 设置默认值
 	if req.Status == "" {
 		req.Status = models.ModelStatusOffline
@@ -50,17 +64,71 @@ func (s *ModelService) CreateModel(req *models.Model) (*models.Model, error) {
 		"model_id":   req.ID,
 		"model_name": req.Name,
 		"model_type": req.Type,
+		"config":     req.Config.Redacted(),
 	}).Info("Model created")
 
 	return req, nil
 }
 
+// UpsertModel 按名称创建或更新模型，供重复执行的部署脚本使用：不存在时创建，存在时按 UpdateModel
+// 的规则更新请求中给出的字段，请求计数、用量等统计保持不变。返回的 created 表示是否新建。
+// 名称被已删除的模型占用时与创建一样返回 ErrModelNameExists
+func (s *ModelService) UpsertModel(req *models.Model) (*models.Model, bool, error) {
+	existing, err := s.findModelByNameUnscoped(req.Name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		if req.Config == nil {
+			req.Config = make(models.ModelConfig)
+		}
+		model, err := s.CreateModel(req)
+		if err == nil {
+			return model, true, nil
+		}
+		// 并发执行时另一请求已创建同名模型，改为更新
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, false, err
+		}
+		if existing, err = s.findModelByNameUnscoped(req.Name); err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return nil, false, fmt.Errorf("%w: %s", ErrModelNameExists, req.Name)
+		}
+	}
+
+	if existing.DeletedAt.Valid {
+		return nil, false, fmt.Errorf("%w: %s (deleted)", ErrModelNameExists, req.Name)
+	}
+
+	model, err := s.UpdateModel(existing.ID, req)
+	if err != nil {
+		return nil, false, err
+	}
+	return model, false, nil
+}
+
+// findModelByNameUnscoped 按名称查找模型（包括已删除的），不存在时返回 nil
+func (s *ModelService) findModelByNameUnscoped(name string) (*models.Model, error) {
+	var model models.Model
+	err := s.db.Unscoped().Where("name = ?", name).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing model: %w", err)
+	}
+	return &model, nil
+}
+
 // GetModel 获取模型详情
 func (s *ModelService) GetModel(id uint64) (*models.Model, error) {
 	var model models.Model
 	if err := s.db.First(&model, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("model not found")
+			return nil, ErrModelNotFound
 		}
 		return nil, fmt.Errorf("failed to get model: %w", err)
 	}
@@ -72,7 +140,7 @@ func (s *ModelService) GetModelByName(name string) (*models.Model, error) {
 	var model models.Model
 	if err := s.db.Where("name = ?", name).First(&model).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("model not found")
+			return nil, ErrModelNotFound
 		}
 		return nil, fmt.Errorf("failed to get model: %w", err)
 	}
@@ -103,7 +171,7 @@ func (s *ModelService) UpdateModel(id uint64, updates *models.Model) (*models.Mo
 	var model models.Model
 	if err := s.db.First(&model, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("model not found")
+			return nil, ErrModelNotFound
 		}
 		return nil, fmt.Errorf("failed to get model: %w", err)
 	}
@@ -114,8 +182,8 @@ func (s *ModelService) UpdateModel(id uint64, updates *models.Model) (*models.Mo
 	if updates.Name != "" && updates.Name != model.Name {
 		// 检查新名称是否已存在
 		var existingModel models.Model
-		if err := s.db.Where("name = ? AND id != ?", updates.Name, id).First(&existingModel).Error; err == nil {
-			return nil, fmt.Errorf("model with name '%s' already exists", updates.Name)
+		if err := s.db.Unscoped().Where("name = ? AND id != ?", updates.Name, id).First(&existingModel).Error; err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrModelNameExists, updates.Name)
 		} else if err != gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("failed to check existing model: %w", err)
 		}
@@ -125,19 +193,40 @@ func (s *ModelService) UpdateModel(id uint64, updates *models.Model) (*models.Mo
 	if updates.Type != "" {
 		updateMap["type"] = updates.Type
 	}
-	
+
 	if updates.Config != nil {
+		if err := validateModelConfig(updates.Config); err != nil {
+			return nil, err
+		}
 		updateMap["config"] = updates.Config
 	}
-	
+
 	if updates.Status != "" {
+		// 手动设置状态后不再由健康探测自动恢复
 		updateMap["status"] = updates.Status
+		updateMap["auto_maintenance"] = false
 	}
-	
+
 	if updates.MaxWorkers > 0 {
 		updateMap["max_workers"] = updates.MaxWorkers
 	}
 
+	// fallback_model_id 为 0 表示清除备用模型
+	if updates.FallbackModelID != nil {
+		if *updates.FallbackModelID == 0 {
+			updateMap["fallback_model_id"] = nil
+		} else {
+			modelType := model.Type
+			if updates.Type != "" {
+				modelType = updates.Type
+			}
+			if err := s.validateFallback(id, modelType, *updates.FallbackModelID); err != nil {
+				return nil, err
+			}
+			updateMap["fallback_model_id"] = *updates.FallbackModelID
+		}
+	}
+
 	if len(updateMap) > 0 {
 		if err := s.db.Model(&model).Updates(updateMap).Error; err != nil {
 			return nil, fmt.Errorf("failed to update model: %w", err)
@@ -146,13 +235,148 @@ func (s *ModelService) UpdateModel(id uint64, updates *models.Model) (*models.Mo
 		s.logger.WithFields(logrus.Fields{
 			"model_id":   id,
 			"model_name": model.Name,
-			"updates":    updateMap,
+			"updates":    redactUpdates(updateMap),
 		}).Info("Model updated")
 	}
 
 	return s.GetModel(id)
 }
 
+// MergeConfig 将 patch 合并到模型现有配置中：patch 中的键覆盖原值，值为 null 的键被删除，
+// 两边都是对象的键递归合并（JSON Merge Patch 语义），未出现在 patch 中的键保持不变
+func (s *ModelService) MergeConfig(id uint64, patch models.ModelConfig) (*models.Model, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var model models.Model
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrModelNotFound
+			}
+			return fmt.Errorf("failed to get model: %w", err)
+		}
+
+		merged := models.ModelConfig(mergeConfigPatch(model.Config, patch))
+		if err := validateModelConfig(merged); err != nil {
+			return err
+		}
+		if err := tx.Model(&model).Update("config", merged).Error; err != nil {
+			return fmt.Errorf("failed to update model config: %w", err)
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"model_id":   id,
+			"model_name": model.Name,
+			"patch":      patch.Redacted(),
+		}).Info("Model config merged")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetModel(id)
+}
+
+// mergeConfigPatch 返回 target 合并 patch 后的新 map，不修改 target
+func mergeConfigPatch(target, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(target)+len(patch))
+	for key, value := range target {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		patchObject, isObject := value.(map[string]interface{})
+		if !isObject {
+			merged[key] = value
+			continue
+		}
+		existing, _ := merged[key].(map[string]interface{})
+		merged[key] = mergeConfigPatch(existing, patchObject)
+	}
+	return merged
+}
+
+// redactUpdates 复制更新字段用于日志，屏蔽 config 中的敏感值
+func redactUpdates(updates map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(updates))
+	for key, value := range updates {
+		if config, ok := value.(models.ModelConfig); ok {
+			value = config.Redacted()
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// withDeletedModels 预加载关联模型时包含已软删除的模型，使历史任务仍能显示模型名称
+func withDeletedModels(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// validateFallback 校验备用模型：必须存在、不能是模型自身且类型相同
+func (s *ModelService) validateFallback(id uint64, modelType models.ModelType, fallbackID uint64) error {
+	fieldErr := func(msg string) error {
+		return &ValidationError{Fields: []models.FieldError{{Field: "fallback_model_id", Message: msg}}}
+	}
+
+	if fallbackID == id {
+		return fieldErr("fallback model must be a different model")
+	}
+
+	var fallback models.Model
+	if err := s.db.First(&fallback, fallbackID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fieldErr("fallback model not found")
+		}
+		return fmt.Errorf("failed to get fallback model: %w", err)
+	}
+	if fallback.Type != modelType {
+		return fieldErr(fmt.Sprintf("fallback model must have the same type (%s)", modelType))
+	}
+	return nil
+}
+
+// GetRerouteCandidates 获取离线或维护中且配置了备用模型的模型
+func (s *ModelService) GetRerouteCandidates() ([]models.Model, error) {
+	var modelList []models.Model
+	if err := s.db.Where("status <> ? AND fallback_model_id IS NOT NULL", models.ModelStatusOnline).
+		Find(&modelList).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reroute candidates: %w", err)
+	}
+	return modelList, nil
+}
+
+// GetProbeModels 获取需要健康探测的模型：在线的模型，以及被健康探测自动切换为维护中的模型
+func (s *ModelService) GetProbeModels() ([]models.Model, error) {
+	var modelList []models.Model
+	if err := s.db.Where("status = ? OR (status = ? AND auto_maintenance = ?)",
+		models.ModelStatusOnline, models.ModelStatusMaintenance, true).
+		Find(&modelList).Error; err != nil {
+		return nil, fmt.Errorf("failed to get models to probe: %w", err)
+	}
+	return modelList, nil
+}
+
+// SetModelHealth 根据健康探测结果切换模型状态：不健康的在线模型切换为 maintenance，
+// 恢复的模型只有是被健康探测切换为 maintenance 时才回到 online，返回状态是否发生变化
+func (s *ModelService) SetModelHealth(id uint64, healthy bool) (bool, error) {
+	query := s.db.Model(&models.Model{}).Where("id = ?", id)
+	var result *gorm.DB
+	if healthy {
+		result = query.Where("status = ? AND auto_maintenance = ?", models.ModelStatusMaintenance, true).
+			Updates(map[string]interface{}{"status": models.ModelStatusOnline, "auto_maintenance": false})
+	} else {
+		result = query.Where("status = ?", models.ModelStatusOnline).
+			Updates(map[string]interface{}{"status": models.ModelStatusMaintenance, "auto_maintenance": true})
+	}
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update model health: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // DeleteModel 删除模型
 func (s *ModelService) DeleteModel(id uint64) error {
 	// 检查是否有正在执行的任务
@@ -168,8 +392,29 @@ func (s *ModelService) DeleteModel(id uint64) error {
 		return fmt.Errorf("cannot delete model with %d running/pending tasks", runningTaskCount)
 	}
 
-	// 删除模型
-	if err := s.db.Delete(&models.Model{}, id).Error; err != nil {
+	// 软删除模型：保留记录供历史任务关联，同时下线模型并停用引用它的定时任务
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Model{}).Where("id = ?", id).
+			Update("status", models.ModelStatusOffline).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ScheduledTask{}).Where("model_id = ?", id).
+			Updates(map[string]interface{}{"enabled": false, "next_run_at": nil}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Model{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err == gorm.ErrRecordNotFound {
+		return ErrModelNotFound
+	}
+	if err != nil {
 		return fmt.Errorf("failed to delete model: %w", err)
 	}
 
@@ -177,11 +422,11 @@ func (s *ModelService) DeleteModel(id uint64) error {
 	return nil
 }
 
-// UpdateModelStatus 更新模型状态
+// UpdateModelStatus 更新模型状态，手动设置状态后不再由健康探测自动恢复
 func (s *ModelService) UpdateModelStatus(id uint64, status models.ModelStatus) error {
 	if err := s.db.Model(&models.Model{}).
 		Where("id = ?", id).
-		Update("status", status).Error; err != nil {
+		Updates(map[string]interface{}{"status": status, "auto_maintenance": false}).Error; err != nil {
 		return fmt.Errorf("failed to update model status: %w", err)
 	}
 
@@ -193,6 +438,16 @@ func (s *ModelService) UpdateModelStatus(id uint64, status models.ModelStatus) e
 	return nil
 }
 
+// SetMaxWorkers 更新模型的最大 Worker 数量
+func (s *ModelService) SetMaxWorkers(id uint64, maxWorkers int) error {
+	if err := s.db.Model(&models.Model{}).
+		Where("id = ?", id).
+		Update("max_workers", maxWorkers).Error; err != nil {
+		return fmt.Errorf("failed to update max workers: %w", err)
+	}
+	return nil
+}
+
 // IncrementWorkerCount 增加 Worker 数量
 func (s *ModelService) IncrementWorkerCount(id uint64) error {
 	if err := s.db.Model(&models.Model{}).
@@ -231,6 +486,25 @@ func (s *ModelService) IncrementRequestCount(id uint64, success bool) error {
 	return nil
 }
 
+// RecordUsage 累加模型的 token 用量和费用
+func (s *ModelService) RecordUsage(id uint64, usage models.TokenUsage) error {
+	if usage.TotalTokens() == 0 && usage.CostUSD == 0 {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"total_prompt_tokens":     gorm.Expr("total_prompt_tokens + ?", usage.PromptTokens),
+		"total_completion_tokens": gorm.Expr("total_completion_tokens + ?", usage.CompletionTokens),
+		"total_cost_usd":          gorm.Expr("total_cost_usd + ?", usage.CostUSD),
+	}
+	if err := s.db.Model(&models.Model{}).
+		Where("id = ?", id).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record model usage: %w", err)
+	}
+	return nil
+}
+
 // GetAvailableModels 获取可用的模型（在线且有空闲 Worker）
 func (s *ModelService) GetAvailableModels() ([]models.Model, error) {
 	var models_list []models.Model
@@ -270,6 +544,7 @@ func (s *ModelService) GetModelStats() ([]models.ModelStats, error) {
 			FROM tasks 
 			GROUP BY model_id
 		) t ON m.id = t.model_id
+		WHERE m.deleted_at IS NULL
 	`
 
 	if err := s.db.Raw(query).Scan(&stats).Error; err != nil {
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestUpsertModelCreatesThenUpdates(t *testing.T) {
+	env := newTestEnv(t, nil)
+	modelService := newModelService(env)
+
+	// 不存在时创建
+	model, created, err := modelService.UpsertModel(&models.Model{
+		Name:       "gpt",
+		Type:       models.ModelTypeOpenAI,
+		Config:     models.ModelConfig{"base_url": "https://a.example.com"},
+		MaxWorkers: 2,
+	})
+	if err != nil || !created {
+		t.Fatalf("first upsert = created %v, err %v; want created", created, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := modelService.IncrementRequestCount(model.ID, i > 0); err != nil {
+			t.Fatalf("IncrementRequestCount: %v", err)
+		}
+	}
+
+	// 部署脚本重复执行：同名模型被更新而不是报错，统计保持不变
+	updated, created, err := modelService.UpsertModel(&models.Model{
+		Name:   "gpt",
+		Type:   models.ModelTypeOpenAI,
+		Config: models.ModelConfig{"base_url": "https://b.example.com"},
+		Status: models.ModelStatusMaintenance,
+	})
+	if err != nil || created {
+		t.Fatalf("second upsert = created %v, err %v; want updated", created, err)
+	}
+	if updated.ID != model.ID {
+		t.Fatalf("updated model %d, want %d", updated.ID, model.ID)
+	}
+
+	got, err := modelService.GetModel(model.ID)
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if got.Config["base_url"] != "https://b.example.com" || got.Status != models.ModelStatusMaintenance {
+		t.Fatalf("model = config %v status %s, want updated config and status", got.Config, got.Status)
+	}
+	// 请求中未给出的字段保持原值
+	if got.MaxWorkers != 2 {
+		t.Fatalf("max_workers = %d, want 2", got.MaxWorkers)
+	}
+	if got.TotalRequests != 3 || got.SuccessRequests != 2 {
+		t.Fatalf("requests = %d total %d success, want 3 and 2", got.TotalRequests, got.SuccessRequests)
+	}
+
+	var count int64
+	if err := env.db.Model(&models.Model{}).Where("name = ?", "gpt").Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("models named gpt = %d (err %v), want 1", count, err)
+	}
+}
+
+func TestUpsertModelRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		config  models.ModelConfig
+		wantErr func(error) bool
+	}{
+		// 名称被已删除的模型占用时与创建一致
+		{"deleted model name", true, nil, func(err error) bool { return errors.Is(err, ErrModelNameExists) }},
+		{"invalid config", false, models.ModelConfig{"prompt_template": "{{.Input"}, func(err error) bool {
+			var validationErr *ValidationError
+			return errors.As(err, &validationErr)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			modelService := newModelService(env)
+			if tt.deleted {
+				if err := modelService.DeleteModel(env.modelID); err != nil {
+					t.Fatalf("DeleteModel: %v", err)
+				}
+			}
+
+			_, _, err := modelService.UpsertModel(&models.Model{Name: "test-model", Type: models.ModelTypeCustom, Config: tt.config})
+			if !tt.wantErr(err) {
+				t.Fatalf("UpsertModel error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ScheduleService 定时任务服务
+type ScheduleService struct {
+	db          *gorm.DB
+	taskService *TaskService
+	logger      *logrus.Logger
+}
+
+// NewScheduleService 创建定时任务服务
+func NewScheduleService(db *gorm.DB, taskService *TaskService, logger *logrus.Logger) *ScheduleService {
+	return &ScheduleService{
+		db:          db,
+		taskService: taskService,
+		logger:      logger,
+	}
+}
+
+// CreateSchedule 创建定时任务
+func (s *ScheduleService) CreateSchedule(req *models.ScheduledTaskRequest) (*models.ScheduledTask, error) {
+	schedule, err := s.validateSchedule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sched := &models.ScheduledTask{
+		Name:          req.Name,
+		Cron:          req.Cron,
+		ModelID:       req.ModelID,
+		Type:          req.Type,
+		InputTemplate: req.InputTemplate,
+		Params:        req.Params,
+		Priority:      req.Priority,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	if sched.Priority == 0 {
+		sched.Priority = models.TaskPriorityMedium
+	}
+	if sched.Enabled {
+		next := schedule.Next(time.Now())
+		sched.NextRunAt = &next
+	}
+
+	// enabled 列默认为 true，gorm 创建时会把 false 当作零值换成默认值，停用的定时任务需要单独更新
+	enabled := sched.Enabled
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sched).Error; err != nil {
+			return err
+		}
+		if !enabled {
+			sched.Enabled = false
+			return tx.Model(sched).Update("enabled", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"schedule_id": sched.ID,
+		"name":        sched.Name,
+		"cron":        sched.Cron,
+	}).Info("Schedule created")
+
+	return sched, nil
+}
+
+// GetSchedule 获取定时任务详情
+func (s *ScheduleService) GetSchedule(id uint64) (*models.ScheduledTask, error) {
+	var sched models.ScheduledTask
+	if err := s.db.Preload("Model", withDeletedModels).First(&sched, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return &sched, nil
+}
+
+// ListSchedules 获取定时任务列表
+func (s *ScheduleService) ListSchedules() ([]models.ScheduledTask, error) {
+	var schedules []models.ScheduledTask
+	if err := s.db.Preload("Model", withDeletedModels).Order("id").Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule 更新定时任务，cron 或启用状态变化时重新计算下一次执行时间
+func (s *ScheduleService) UpdateSchedule(id uint64, req *models.ScheduledTaskRequest) (*models.ScheduledTask, error) {
+	var sched models.ScheduledTask
+	if err := s.db.First(&sched, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	schedule, err := s.validateSchedule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+	priority := req.Priority
+	if priority == 0 {
+		priority = models.TaskPriorityMedium
+	}
+
+	updates := map[string]interface{}{
+		"name":           req.Name,
+		"cron":           req.Cron,
+		"model_id":       req.ModelID,
+		"type":           req.Type,
+		"input_template": req.InputTemplate,
+		"params":         req.Params,
+		"priority":       priority,
+		"enabled":        enabled,
+		"next_run_at":    nil,
+	}
+	if enabled {
+		updates["next_run_at"] = schedule.Next(time.Now())
+	}
+
+	if err := s.db.Model(&sched).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	s.logger.WithField("schedule_id", id).Info("Schedule updated")
+	return s.GetSchedule(id)
+}
+
+// DeleteSchedule 删除定时任务，已创建的任务不受影响
+func (s *ScheduleService) DeleteSchedule(id uint64) error {
+	result := s.db.Delete(&models.ScheduledTask{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete schedule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+
+	s.logger.WithField("schedule_id", id).Info("Schedule deleted")
+	return nil
+}
+
+// SkipMissedRuns 跳过停机期间错过的执行：下一次执行时间已过期的定时任务直接推进到未来，不补跑
+func (s *ScheduleService) SkipMissedRuns(now time.Time) error {
+	var schedules []models.ScheduledTask
+	if err := s.db.Where("enabled = ? AND next_run_at < ?", true, now).Find(&schedules).Error; err != nil {
+		return fmt.Errorf("failed to query missed schedules: %w", err)
+	}
+
+	for _, sched := range schedules {
+		schedule, err := ParseCron(sched.Cron)
+		if err != nil {
+			s.logger.WithError(err).WithField("schedule_id", sched.ID).Error("Invalid cron spec")
+			continue
+		}
+
+		next := schedule.Next(now)
+		if err := s.db.Model(&sched).Update("next_run_at", next).Error; err != nil {
+			s.logger.WithError(err).WithField("schedule_id", sched.ID).Error("Failed to skip missed schedule runs")
+			continue
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"schedule_id": sched.ID,
+			"missed_at":   sched.NextRunAt,
+			"next_run_at": next,
+		}).Warn("Skipped missed schedule run")
+	}
+
+	return nil
+}
+
+// RunDueSchedules 为到期的定时任务创建任务，返回创建的任务数
+func (s *ScheduleService) RunDueSchedules(ctx context.Context, now time.Time) int {
+	var schedules []models.ScheduledTask
+	if err := s.db.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to query due schedules")
+		return 0
+	}
+
+	created := 0
+	for i := range schedules {
+		if s.runSchedule(ctx, &schedules[i], now) {
+			created++
+		}
+	}
+	return created
+}
+
+// runSchedule 执行单个定时任务。先以条件更新推进 next_run_at 占住本次执行，多实例部署时只有一个实例会创建任务
+func (s *ScheduleService) runSchedule(ctx context.Context, sched *models.ScheduledTask, now time.Time) bool {
+	logger := s.logger.WithField("schedule_id", sched.ID)
+
+	schedule, err := ParseCron(sched.Cron)
+	if err != nil {
+		logger.WithError(err).Error("Invalid cron spec")
+		return false
+	}
+
+	// 从当前时间计算下一次执行，错过的多次执行不会补跑
+	next := schedule.Next(now)
+	result := s.db.Model(&models.ScheduledTask{}).
+		Where("id = ? AND next_run_at = ?", sched.ID, sched.NextRunAt).
+		Updates(map[string]interface{}{
+			"next_run_at": next,
+			"last_run_at": now,
+		})
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to claim schedule run")
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	input, err := renderScheduleInput(sched, now)
+	if err == nil {
+		var task *models.Task
+		task, err = s.taskService.CreateTask(ctx, &models.TaskCreateRequest{
+			ModelID:  sched.ModelID,
+			Type:     sched.Type,
+			Input:    input,
+			Params:   sched.Params,
+			Priority: sched.Priority,
+		})
+		if err == nil {
+			s.db.Model(sched).Updates(map[string]interface{}{
+				"last_task_id": task.ID,
+				"last_error":   nil,
+			})
+			logger.WithField("task_id", task.ID).Info("Scheduled task created")
+			return true
+		}
+	}
+
+	logger.WithError(err).Error("Failed to create scheduled task")
+	s.db.Model(sched).Update("last_error", err.Error())
+	return false
+}
+
+// validateSchedule 校验 cron 表达式、输入模板和模型，返回解析后的计划
+func (s *ScheduleService) validateSchedule(req *models.ScheduledTaskRequest) (CronSchedule, error) {
+	var fields []models.FieldError
+
+	schedule, err := ParseCron(req.Cron)
+	if err != nil {
+		fields = append(fields, models.FieldError{Field: "cron", Message: err.Error()})
+	}
+	if _, err := template.New("input").Parse(req.InputTemplate); err != nil {
+		fields = append(fields, models.FieldError{Field: "input_template", Message: err.Error()})
+	}
+	if req.Priority != 0 && !req.Priority.IsValid() {
+		fields = append(fields, models.FieldError{Field: "priority", Message: "priority must be 1, 2 or 3"})
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Model{}).Where("id = ?", req.ModelID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to query model: %w", err)
+	}
+	if count == 0 {
+		fields = append(fields, models.FieldError{Field: "model_id", Message: "model not found"})
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+	return schedule, nil
+}
+
+// renderScheduleInput 渲染定时任务的输入模板，模板中可使用 {{.Date}}、{{.Now}}、{{.Name}}
+func renderScheduleInput(sched *models.ScheduledTask, now time.Time) (string, error) {
+	tmpl, err := template.New("input").Parse(sched.InputTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid input template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	data := models.ScheduleTemplateData{
+		Now:  now,
+		Date: now.Format("2006-01-02"),
+		Name: sched.Name,
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render input template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseCron(t *testing.T) {
+	// 2026-10-15 是星期四
+	base := time.Date(2026, 10, 15, 10, 7, 30, 500, time.UTC)
+
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"@every 1s", time.Date(2026, 10, 15, 10, 7, 31, 0, time.UTC), false},
+		{"@every 90s", time.Date(2026, 10, 15, 10, 9, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2026, 10, 15, 10, 15, 0, 0, time.UTC), false},
+		{"0 9 * * 1-5", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), false},
+		{"@daily", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), false},
+		{"0 12 1 * *", time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC), false},
+		{"@every 500ms", time.Time{}, true},
+		{"60 * * * *", time.Time{}, true},
+		{"* * * *", time.Time{}, true},
+		{"*/0 * * * *", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.spec, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Fatalf("next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// newScheduleService 创建使用测试环境任务服务的定时任务服务
+func newScheduleService(env *testEnv) *ScheduleService {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewScheduleService(env.db, env.tasks, logger)
+}
+
+func TestRunDueSchedulesEverySecond(t *testing.T) {
+	env := newTestEnv(t, nil)
+	schedules := newScheduleService(env)
+	ctx := context.Background()
+
+	sched, err := schedules.CreateSchedule(&models.ScheduledTaskRequest{
+		Name:          "daily-summary",
+		Cron:          "@every 1s",
+		ModelID:       env.modelID,
+		Type:          models.TaskTypeTextGeneration,
+		InputTemplate: "summary for {{.Name}} on {{.Date}}",
+	})
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	if sched.NextRunAt == nil {
+		t.Fatal("enabled schedule has no next_run_at")
+	}
+
+	// 未到期时不创建任务
+	if n := schedules.RunDueSchedules(ctx, sched.NextRunAt.Add(-time.Millisecond)); n != 0 {
+		t.Fatalf("created %d tasks before the schedule was due", n)
+	}
+
+	// 每秒到期一次，同一时刻重复执行不会重复创建
+	now := *sched.NextRunAt
+	for tick := 1; tick <= 3; tick++ {
+		if n := schedules.RunDueSchedules(ctx, now); n != 1 {
+			t.Fatalf("tick %d: created %d tasks, want 1", tick, n)
+		}
+		if n := schedules.RunDueSchedules(ctx, now); n != 0 {
+			t.Fatalf("tick %d: rerun created %d tasks, want 0", tick, n)
+		}
+		now = now.Add(time.Second)
+	}
+
+	var tasks []models.Task
+	env.db.Order("id").Find(&tasks)
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(tasks))
+	}
+	wantInput := "summary for daily-summary on " + sched.NextRunAt.Format("2006-01-02")
+	if tasks[0].Input != wantInput || tasks[0].ModelID != env.modelID {
+		t.Fatalf("task = %+v, want input %q", tasks[0], wantInput)
+	}
+
+	got, err := schedules.GetSchedule(sched.ID)
+	if err != nil {
+		t.Fatalf("get schedule: %v", err)
+	}
+	if got.LastTaskID == nil || *got.LastTaskID != tasks[2].ID || got.LastRunAt == nil {
+		t.Fatalf("schedule = %+v, want last run recorded for task %d", got, tasks[2].ID)
+	}
+	if !got.NextRunAt.After(*got.LastRunAt) {
+		t.Fatalf("next_run_at %s not after last_run_at %s", got.NextRunAt, got.LastRunAt)
+	}
+}
+
+func TestSkipMissedRunsDoesNotCatchUp(t *testing.T) {
+	env := newTestEnv(t, nil)
+	schedules := newScheduleService(env)
+	ctx := context.Background()
+
+	sched, err := schedules.CreateSchedule(&models.ScheduledTaskRequest{
+		Name:          "hourly",
+		Cron:          "@every 1s",
+		ModelID:       env.modelID,
+		Type:          models.TaskTypeTextGeneration,
+		InputTemplate: "tick",
+	})
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	// 模拟停机一小时：启动时跳过错过的执行，只在之后按计划触发
+	restart := sched.NextRunAt.Add(time.Hour)
+	if err := schedules.SkipMissedRuns(restart); err != nil {
+		t.Fatalf("skip missed runs: %v", err)
+	}
+	if n := schedules.RunDueSchedules(ctx, restart); n != 0 {
+		t.Fatalf("created %d tasks for missed runs, want 0", n)
+	}
+	got, _ := schedules.GetSchedule(sched.ID)
+	if got.NextRunAt == nil || !got.NextRunAt.After(restart) {
+		t.Fatalf("next_run_at = %v, want after restart %s", got.NextRunAt, restart)
+	}
+	if n := schedules.RunDueSchedules(ctx, *got.NextRunAt); n != 1 {
+		t.Fatalf("created %d tasks on the next tick, want 1", n)
+	}
+}
+
+func TestCreateScheduleValidation(t *testing.T) {
+	env := newTestEnv(t, nil)
+	schedules := newScheduleService(env)
+
+	_, err := schedules.CreateSchedule(&models.ScheduledTaskRequest{
+		Name:          "broken",
+		Cron:          "* * *",
+		ModelID:       env.modelID + 100,
+		Type:          models.TaskTypeTextGeneration,
+		InputTemplate: "{{.Missing",
+		Priority:      9,
+	})
+	got := fieldNames(t, err)
+	want := []string{"cron", "input_template", "priority", "model_id"}
+	if len(got) != len(want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("fields = %v, want %v", got, want)
+		}
+	}
+
+	// 停用的定时任务不计算下一次执行时间
+	disabled := false
+	sched, err := schedules.CreateSchedule(&models.ScheduledTaskRequest{
+		Name:          "off",
+		Cron:          "@hourly",
+		ModelID:       env.modelID,
+		Type:          models.TaskTypeTextGeneration,
+		InputTemplate: "tick",
+		Enabled:       &disabled,
+	})
+	if err != nil {
+		t.Fatalf("create disabled schedule: %v", err)
+	}
+	if sched.Enabled || sched.NextRunAt != nil {
+		t.Fatalf("disabled schedule = %+v, want no next run", sched)
+	}
+	if got, _ := schedules.GetSchedule(sched.ID); got.Enabled {
+		t.Fatal("disabled schedule stored as enabled")
+	}
+}
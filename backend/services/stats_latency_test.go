@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		// 最近邻秩法：取第 ceil(p/100*n) 个样本
+		{0, 10},
+		{50, 50},
+		{51, 60},
+		{90, 90},
+		{95, 100},
+		{100, 100},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(p%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]float64{42}, 99); got != 42 {
+		t.Errorf("percentile of single sample = %v, want 42", got)
+	}
+}
+
+// createTimedTask 创建一分钟前完成、处理耗时为 duration 的任务，configure 可修改模型、类型等字段
+func (env *testEnv) createTimedTask(t *testing.T, duration time.Duration, configure func(*models.Task)) *models.Task {
+	t.Helper()
+	return env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) {
+		completed := time.Now().Add(-time.Minute)
+		started := completed.Add(-duration)
+		task.StartedAt = &started
+		task.CompletedAt = &completed
+		if configure != nil {
+			configure(task)
+		}
+	})
+}
+
+func TestGetLatencyPercentiles(t *testing.T) {
+	env := newTestEnv(t, nil)
+	other := &models.Model{Name: "other-model", Type: models.ModelTypeCustom, Config: models.ModelConfig{}, Status: models.ModelStatusOnline}
+	if err := env.db.Create(other).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+
+	// 测试模型的 100 个文本生成任务耗时 10ms、20ms … 1000ms
+	for i := 1; i <= 100; i++ {
+		env.createTimedTask(t, time.Duration(i*10)*time.Millisecond, nil)
+	}
+	// 其他模型、其他类型的任务和统计窗口之外、未成功的任务用于验证过滤条件
+	env.createTimedTask(t, 5*time.Second, func(task *models.Task) { task.ModelID = other.ID })
+	env.createTimedTask(t, 3*time.Second, func(task *models.Task) { task.Type = models.TaskTypeSummarization })
+	env.createTimedTask(t, 9*time.Second, func(task *models.Task) {
+		completed := time.Now().Add(-48 * time.Hour)
+		started := completed.Add(-9 * time.Second)
+		task.StartedAt, task.CompletedAt = &started, &completed
+	})
+	env.createTimedTask(t, 7*time.Second, func(task *models.Task) { task.Status = models.TaskStatusFailed })
+
+	stats := NewStatsService(env.db, env.queue, nil, logrus.New())
+	modelID := env.modelID
+	tests := []struct {
+		name  string
+		req   models.LatencyRequest
+		count int
+		want  [5]float64 // p50、p90、p95、p99、max
+	}{
+		{"model and type", models.LatencyRequest{ModelID: &modelID, Type: models.TaskTypeTextGeneration, Hours: 24}, 100, [5]float64{500, 900, 950, 990, 1000}},
+		{"model only", models.LatencyRequest{ModelID: &modelID, Hours: 24}, 101, [5]float64{510, 910, 960, 1000, 3000}},
+		{"type only", models.LatencyRequest{Type: models.TaskTypeSummarization, Hours: 24}, 1, [5]float64{3000, 3000, 3000, 3000, 3000}},
+		{"all tasks", models.LatencyRequest{Hours: 24}, 102, [5]float64{510, 920, 970, 3000, 5000}},
+		// 窗口放宽后包含两天前完成的任务
+		{"wider window", models.LatencyRequest{ModelID: &modelID, Type: models.TaskTypeTextGeneration, Hours: 72}, 101, [5]float64{510, 910, 960, 1000, 9000}},
+		{"no matches", models.LatencyRequest{Type: models.TaskTypeEmbedding, Hours: 24}, 0, [5]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stats.GetLatencyPercentiles(&tt.req)
+			if err != nil {
+				t.Fatalf("GetLatencyPercentiles: %v", err)
+			}
+			if got.Count != tt.count || got.Sampled {
+				t.Fatalf("count = %d sampled = %v, want %d", got.Count, got.Sampled, tt.count)
+			}
+			if values := [5]float64{got.P50Ms, got.P90Ms, got.P95Ms, got.P99Ms, got.MaxMs}; values != tt.want {
+				t.Fatalf("percentiles = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
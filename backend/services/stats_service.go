@@ -1,32 +1,46 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"llm-scheduler/models"
+	"llm-scheduler/queue"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// WorkerStatusProvider 提供本实例运行中 Worker 和模型熔断器的状态（由 worker.Manager 实现，避免循环依赖）
+type WorkerStatusProvider interface {
+	GetLocalWorkerStatus() []models.WorkerStatus
+	GetCircuitStates() map[uint64]models.CircuitState
+}
+
 // StatsService 统计服务
 type StatsService struct {
-	db     *gorm.DB
-	logger *logrus.Logger
+	db           *gorm.DB
+	queueManager *queue.Manager
+	workers      WorkerStatusProvider
+	logger       *logrus.Logger
 }
 
 // NewStatsService 创建统计服务
-func NewStatsService(db *gorm.DB, logger *logrus.Logger) *StatsService {
+func NewStatsService(db *gorm.DB, queueManager *queue.Manager, workers WorkerStatusProvider, logger *logrus.Logger) *StatsService {
 	return &StatsService{
-		db:     db,
-		logger: logger,
+		db:           db,
+		queueManager: queueManager,
+		workers:      workers,
+		logger:       logger,
 	}
 }
 
 // GetDashboardStats 获取 Dashboard 统计数据
-func (s *StatsService) GetDashboardStats() (*models.DashboardStats, error) {
+func (s *StatsService) GetDashboardStats(ctx context.Context) (*models.DashboardStats, error) {
 	stats := &models.DashboardStats{}
 
 	// 获取任务统计
@@ -43,11 +57,23 @@ func (s *StatsService) GetDashboardStats() (*models.DashboardStats, error) {
 	}
 	stats.ModelStats = modelStats
 
-	// 获取队列状态（这里先返回空值，实际应该从队列管理器获取）
-	stats.QueueStatus = models.QueueStatus{}
+	// 获取队列状态
+	queueStatus, err := s.queueManager.GetQueueStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue status: %w", err)
+	}
+	stats.QueueStatus = *queueStatus
 
-	// 获取 Worker 状态（这里先返回空值，实际应该从 Worker 管理器获取）
+	// 获取所有实例的 Worker 状态
+	instances, err := s.getInstanceWorkerStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker status: %w", err)
+	}
+	stats.Instances = instances
 	stats.WorkerStatus = []models.WorkerStatus{}
+	for _, instance := range instances {
+		stats.WorkerStatus = append(stats.WorkerStatus, instance.Workers...)
+	}
 
 	// 获取系统统计
 	systemStats, err := s.getTodaySystemStats()
@@ -66,6 +92,82 @@ func (s *StatsService) GetDashboardStats() (*models.DashboardStats, error) {
 	return stats, nil
 }
 
+// getInstanceWorkerStatus 汇总各实例的 Worker 状态，并补充模型名称
+func (s *StatsService) getInstanceWorkerStatus(ctx context.Context) ([]models.InstanceWorkerStatus, error) {
+	instances, err := s.queueManager.GetGlobalWorkerStatus(ctx)
+	if err != nil {
+		// 名册不可用时只展示本实例的 Worker
+		s.logger.WithError(err).Warn("Failed to get global worker status")
+		instances = nil
+	}
+
+	// 本实例以内存中的实时状态为准，名册中的状态最多滞后一个心跳间隔
+	instances = mergeLocalWorkers(instances, s.queueManager.InstanceID(), s.workers.GetLocalWorkerStatus())
+
+	modelIDs := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	for _, instance := range instances {
+		for _, worker := range instance.Workers {
+			if !seen[worker.ModelID] {
+				seen[worker.ModelID] = true
+				modelIDs = append(modelIDs, worker.ModelID)
+			}
+		}
+	}
+	if len(modelIDs) == 0 {
+		return instances, nil
+	}
+
+	var modelList []models.Model
+	if err := s.db.Select("id", "name").Where("id IN ?", modelIDs).Find(&modelList).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint64]string, len(modelList))
+	for _, model := range modelList {
+		names[model.ID] = model.Name
+	}
+	for i := range instances {
+		for j := range instances[i].Workers {
+			instances[i].Workers[j].ModelName = names[instances[i].Workers[j].ModelID]
+		}
+	}
+
+	return instances, nil
+}
+
+// mergeLocalWorkers 用本实例的实时 Worker 状态替换名册中本实例的记录
+func mergeLocalWorkers(instances []models.InstanceWorkerStatus, instanceID string, local []models.WorkerStatus) []models.InstanceWorkerStatus {
+	merged := make([]models.InstanceWorkerStatus, 0, len(instances)+1)
+	for _, instance := range instances {
+		if instance.InstanceID != instanceID {
+			merged = append(merged, instance)
+		}
+	}
+
+	if len(local) == 0 {
+		return merged
+	}
+
+	current := models.InstanceWorkerStatus{InstanceID: instanceID}
+	for _, worker := range local {
+		worker.InstanceID = instanceID
+		current.Workers = append(current.Workers, worker)
+		if worker.LastHeartbeat.After(current.LastHeartbeat) {
+			current.LastHeartbeat = worker.LastHeartbeat
+		}
+	}
+	sort.Slice(current.Workers, func(i, j int) bool {
+		return current.Workers[i].WorkerID < current.Workers[j].WorkerID
+	})
+
+	merged = append(merged, current)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].InstanceID < merged[j].InstanceID
+	})
+	return merged
+}
+
 // getTaskStats 获取任务统计
 func (s *StatsService) getTaskStats() (*models.TaskStats, error) {
 	var stats models.TaskStats
@@ -128,6 +230,7 @@ func (s *StatsService) getModelStats() ([]models.ModelStats, error) {
 			FROM tasks 
 			GROUP BY model_id
 		) t ON m.id = t.model_id
+		WHERE m.deleted_at IS NULL
 		ORDER BY m.id
 	`
 
@@ -135,6 +238,17 @@ func (s *StatsService) getModelStats() ([]models.ModelStats, error) {
 		return nil, fmt.Errorf("failed to get model stats: %w", err)
 	}
 
+	// 熔断中的模型标记为 degraded
+	circuits := s.workers.GetCircuitStates()
+	for i := range stats {
+		state, ok := circuits[stats[i].ID]
+		if !ok {
+			continue
+		}
+		stats[i].CircuitState = state
+		stats[i].Degraded = state != models.CircuitClosed
+	}
+
 	return stats, nil
 }
 
@@ -167,7 +281,7 @@ func (s *StatsService) getTodaySystemStats() (*models.SystemStats, error) {
 // getRecentTasks 获取最近任务
 func (s *StatsService) getRecentTasks(limit int) ([]models.Task, error) {
 	var tasks []models.Task
-	err := s.db.Preload("Model").
+	err := s.db.Preload("Model", withDeletedModels).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&tasks).Error
@@ -274,6 +388,121 @@ func (s *StatsService) GetTaskStatsByType() ([]map[string]interface{}, error) {
 	return results, nil
 }
 
+// GetCostSummary 汇总最近 days 天创建的任务的 token 用量和费用，按模型和任务类型分组
+func (s *StatsService) GetCostSummary(days int) (*models.CostSummary, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	summary := &models.CostSummary{Days: days}
+
+	byModel := `
+		SELECT
+			m.name as name,
+			COUNT(t.id) as tasks,
+			COALESCE(SUM(t.prompt_tokens), 0) as prompt_tokens,
+			COALESCE(SUM(t.completion_tokens), 0) as completion_tokens,
+			COALESCE(SUM(t.cost_usd), 0) as cost_usd
+		FROM tasks t
+		JOIN models m ON m.id = t.model_id
+		WHERE t.created_at >= ?
+		GROUP BY m.id, m.name
+		ORDER BY cost_usd DESC, m.id
+	`
+	if err := s.db.Raw(byModel, since).Scan(&summary.ByModel).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cost by model: %w", err)
+	}
+
+	byType := `
+		SELECT
+			type as name,
+			COUNT(*) as tasks,
+			COALESCE(SUM(prompt_tokens), 0) as prompt_tokens,
+			COALESCE(SUM(completion_tokens), 0) as completion_tokens,
+			COALESCE(SUM(cost_usd), 0) as cost_usd
+		FROM tasks
+		WHERE created_at >= ?
+		GROUP BY type
+		ORDER BY cost_usd DESC, type
+	`
+	if err := s.db.Raw(byType, since).Scan(&summary.ByType).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cost by type: %w", err)
+	}
+
+	// 每个任务恰好属于一种类型，按类型的合计即为总计
+	for _, item := range summary.ByType {
+		summary.PromptTokens += item.PromptTokens
+		summary.CompletionTokens += item.CompletionTokens
+		summary.CostUSD += item.CostUSD
+	}
+	if summary.ByModel == nil {
+		summary.ByModel = []models.CostBreakdown{}
+	}
+	if summary.ByType == nil {
+		summary.ByType = []models.CostBreakdown{}
+	}
+
+	return summary, nil
+}
+
+// maxLatencySamples 计算耗时分位数时最多读取的任务数，超过时只统计最近完成的任务
+const maxLatencySamples = 100000
+
+// GetLatencyPercentiles 统计最近 req.Hours 小时内完成的任务的处理耗时分位数，可按模型和任务类型过滤。
+// MySQL 没有分位数聚合函数，读取耗时后在内存中排序计算
+func (s *StatsService) GetLatencyPercentiles(req *models.LatencyRequest) (*models.LatencyPercentiles, error) {
+	since := time.Now().Add(-time.Duration(req.Hours) * time.Hour)
+	query := s.db.Model(&models.Task{}).
+		Where("status = ? AND started_at IS NOT NULL AND completed_at >= ?", models.TaskStatusCompleted, since)
+	if req.ModelID != nil {
+		query = query.Where("model_id = ?", *req.ModelID)
+	}
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+
+	var durations []float64
+	if err := query.Order("completed_at DESC").
+		Limit(maxLatencySamples+1).
+		Pluck("TIMESTAMPDIFF(MICROSECOND, started_at, completed_at) / 1000", &durations).Error; err != nil {
+		return nil, fmt.Errorf("failed to query task durations: %w", err)
+	}
+
+	result := &models.LatencyPercentiles{ModelID: req.ModelID, Type: req.Type, Hours: req.Hours}
+	if len(durations) > maxLatencySamples {
+		durations = durations[:maxLatencySamples]
+		result.Sampled = true
+	}
+	result.Count = len(durations)
+	if len(durations) == 0 {
+		return result, nil
+	}
+
+	sort.Float64s(durations)
+	result.P50Ms = percentile(durations, 50)
+	result.P90Ms = percentile(durations, 90)
+	result.P95Ms = percentile(durations, 95)
+	result.P99Ms = percentile(durations, 99)
+	result.MaxMs = durations[len(durations)-1]
+	return result, nil
+}
+
+// percentile 最近邻秩法：升序样本中第 ceil(p/100*n) 个值，sorted 不能为空
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// GetQueueHistory 获取最近 hours 小时的队列深度样本，按采样时间升序
+func (s *StatsService) GetQueueHistory(hours int) ([]models.QueueMetric, error) {
+	metrics := []models.QueueMetric{}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	if err := s.db.Where("sampled_at >= ?", since).Order("sampled_at ASC").Find(&metrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to query queue metrics: %w", err)
+	}
+	return metrics, nil
+}
+
 // UpdateDailyStats 更新每日统计
 func (s *StatsService) UpdateDailyStats() error {
 	today := time.Now().Format("2006-01-02")
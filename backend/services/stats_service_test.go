@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeWorkers 返回固定的本实例 Worker 状态和熔断器状态
+type fakeWorkers struct {
+	local    []models.WorkerStatus
+	circuits map[uint64]models.CircuitState
+}
+
+func (f *fakeWorkers) GetLocalWorkerStatus() []models.WorkerStatus      { return f.local }
+func (f *fakeWorkers) GetCircuitStates() map[uint64]models.CircuitState { return f.circuits }
+
+// reportAs 以另一个实例的身份向名册上报 Worker 心跳
+func (env *testEnv) reportAs(t *testing.T, instanceID string, workerIDs ...string) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: env.redis.Addr()})
+	defer client.Close()
+
+	cfg := *env.cfg
+	cfg.Worker.InstanceID = instanceID
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	manager := queue.NewManager(&queue.Clients{Default: client}, &cfg, logger)
+	for _, id := range workerIDs {
+		status := models.WorkerStatus{WorkerID: id, ModelID: env.modelID, Status: "idle", StartTime: time.Now(), LastHeartbeat: time.Now()}
+		if err := manager.ReportWorker(context.Background(), status); err != nil {
+			t.Fatalf("report %s/%s: %v", instanceID, id, err)
+		}
+	}
+}
+
+func TestInstanceWorkerStatusExcludesStaleInstances(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Worker.StaleAfter = time.Minute })
+	workers := &fakeWorkers{local: []models.WorkerStatus{{WorkerID: "worker-1-0", ModelID: env.modelID, Status: "busy"}}}
+	stats := NewStatsService(env.db, env.queue, workers, logrus.New())
+
+	// crashed 停止心跳后过期；live 持续心跳；本实例名册中的旧记录被内存中的实时状态替换
+	env.reportAs(t, "crashed", "worker-1-0", "worker-1-1")
+	env.reportAs(t, env.cfg.Worker.InstanceID, "worker-1-0", "worker-1-1")
+	env.redis.FastForward(40 * time.Second)
+	env.reportAs(t, "live", "worker-1-0")
+	env.redis.FastForward(30 * time.Second)
+
+	instances, err := stats.getInstanceWorkerStatus(context.Background())
+	if err != nil {
+		t.Fatalf("instance worker status: %v", err)
+	}
+	if len(instances) != 2 || instances[0].InstanceID != "live" || instances[1].InstanceID != env.cfg.Worker.InstanceID {
+		t.Fatalf("instances = %+v, want live and the local instance", instances)
+	}
+	local := instances[1].Workers
+	if len(local) != 1 || local[0].Status != "busy" || local[0].ModelName != "test-model" {
+		t.Errorf("local workers = %+v, want the in-memory busy worker", local)
+	}
+}
+
+func TestDashboardStatsReportsLiveQueueAndWorkers(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	// 两个任务排队，高优先级任务已由 Worker 领取，另有一个已完成的任务耗时 1.5 秒
+	for _, priority := range []models.TaskPriority{models.TaskPriorityMedium, models.TaskPriorityLow} {
+		task := env.createTask(t, models.TaskStatusPending, func(task *models.Task) { task.Priority = priority })
+		if err := env.queue.EnqueueTask(ctx, task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	running := env.createTask(t, models.TaskStatusPending, func(task *models.Task) { task.Priority = models.TaskPriorityHigh })
+	if err := env.queue.EnqueueTask(ctx, running); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	started := time.Now().Add(-time.Minute)
+	env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) {
+		completed := started.Add(1500 * time.Millisecond)
+		task.StartedAt = &started
+		task.CompletedAt = &completed
+	})
+
+	workers := &fakeWorkers{local: []models.WorkerStatus{{
+		WorkerID:      "worker-1-0",
+		ModelID:       env.modelID,
+		Status:        "busy",
+		CurrentTaskID: &running.ID,
+	}}}
+	stats, err := NewStatsService(env.db, env.queue, workers, logrus.New()).GetDashboardStats(ctx)
+	if err != nil {
+		t.Fatalf("dashboard stats: %v", err)
+	}
+
+	queueStatus := stats.QueueStatus
+	if queueStatus.HighPriorityCount != 0 || queueStatus.MediumPriorityCount != 1 || queueStatus.LowPriorityCount != 1 || queueStatus.ProcessingCount != 1 {
+		t.Errorf("queue status = %+v, want one medium, one low and one processing", queueStatus)
+	}
+	if len(stats.WorkerStatus) != 1 {
+		t.Fatalf("worker status = %+v, want the running worker", stats.WorkerStatus)
+	}
+	worker := stats.WorkerStatus[0]
+	if worker.Status != "busy" || worker.ModelName != "test-model" || worker.CurrentTaskID == nil || *worker.CurrentTaskID != running.ID {
+		t.Errorf("worker = %+v, want busy on task %d of test-model", worker, running.ID)
+	}
+	if stats.TaskStats.AvgProcessingMS != 1500 {
+		t.Errorf("avg processing = %dms, want 1500", stats.TaskStats.AvgProcessingMS)
+	}
+	if len(stats.ModelStats) != 1 || stats.ModelStats[0].AvgResponseMs != 1500 {
+		t.Errorf("model stats = %+v, want avg 1500ms", stats.ModelStats)
+	}
+}
+
+func TestModelStatsMarksOpenCircuitDegraded(t *testing.T) {
+	tests := []struct {
+		name         string
+		state        models.CircuitState // 为空表示模型还没有调用记录
+		wantDegraded bool
+	}{
+		{"no calls yet", "", false},
+		{"closed", models.CircuitClosed, false},
+		{"open", models.CircuitOpen, true},
+		{"half open", models.CircuitHalfOpen, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			workers := &fakeWorkers{circuits: map[uint64]models.CircuitState{}}
+			if tt.state != "" {
+				workers.circuits[env.modelID] = tt.state
+			}
+			stats, err := NewStatsService(env.db, env.queue, workers, logrus.New()).GetDashboardStats(context.Background())
+			if err != nil {
+				t.Fatalf("dashboard stats: %v", err)
+			}
+			if len(stats.ModelStats) != 1 {
+				t.Fatalf("model stats = %+v, want one model", stats.ModelStats)
+			}
+			got := stats.ModelStats[0]
+			if got.CircuitState != tt.state || got.Degraded != tt.wantDegraded {
+				t.Fatalf("model stats = state %q degraded %v, want %q %v", got.CircuitState, got.Degraded, tt.state, tt.wantDegraded)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// archiveBatchSize 每个事务归档的任务数，避免长事务锁表
+const archiveBatchSize = 500
+
+// archiveColumns 从 tasks 复制到 archived_tasks 的列
+const archiveColumns = "id, model_id, type, input, params, tags, output, output_truncated, output_uri, output_format, status, priority, retry_count, max_retries, " +
+	"depends_on, needs_attention, prompt_tokens, completion_tokens, cost_usd, trace_id, error_message, started_at, completed_at, created_at, updated_at"
+
+// terminalStatuses 可以归档的任务状态，pending 和 running 的任务永远不会被归档
+var terminalStatuses = []models.TaskStatus{
+	models.TaskStatusCompleted,
+	models.TaskStatusFailed,
+	models.TaskStatusCancelled,
+}
+
+// ArchiveTasks 将创建时间早于 before 且已结束的任务移入 archived_tasks，并删除其日志，返回归档的任务数
+func (s *TaskService) ArchiveTasks(before time.Time) (int64, error) {
+	var total int64
+	for {
+		var ids []uint64
+		err := s.db.Model(&models.Task{}).
+			Where("created_at < ? AND status IN ?", before, terminalStatuses).
+			Order("id").
+			Limit(archiveBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return total, fmt.Errorf("failed to query tasks to archive: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		archived, err := s.archiveBatch(ids)
+		if err != nil {
+			return total, err
+		}
+		total += archived
+
+		if len(ids) < archiveBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"before":   before,
+			"archived": total,
+		}).Info("Tasks archived")
+	}
+	return total, nil
+}
+
+// archiveBatch 在一个事务中复制任务到归档表、删除任务日志和任务。
+// 先锁定仍处于结束状态的任务，期间被重试回 pending 的任务不会被归档
+func (s *TaskService) archiveBatch(ids []uint64) (int64, error) {
+	var archived int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var lockedIDs []uint64
+		if err := tx.Model(&models.Task{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND status IN ?", ids, terminalStatuses).
+			Pluck("id", &lockedIDs).Error; err != nil {
+			return fmt.Errorf("failed to lock tasks to archive: %w", err)
+		}
+		if len(lockedIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec(
+			"INSERT INTO archived_tasks ("+archiveColumns+", archived_at) "+
+				"SELECT "+archiveColumns+", ? FROM tasks WHERE id IN ?",
+			time.Now(), lockedIDs,
+		).Error; err != nil {
+			return fmt.Errorf("failed to copy tasks to archive: %w", err)
+		}
+
+		if err := tx.Where("task_id IN ?", lockedIDs).Delete(&models.TaskLog{}).Error; err != nil {
+			return fmt.Errorf("failed to delete task logs: %w", err)
+		}
+		result := tx.Where("id IN ?", lockedIDs).Delete(&models.Task{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete archived tasks: %w", result.Error)
+		}
+		archived = result.RowsAffected
+		return nil
+	})
+	return archived, err
+}
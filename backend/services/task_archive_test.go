@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestArchiveTasksAgeCutoff(t *testing.T) {
+	env := newTestEnv(t, nil)
+	cutoff := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		status    models.TaskStatus
+		createdAt time.Time
+		archived  bool
+	}{
+		{"completed before cutoff", models.TaskStatusCompleted, cutoff.Add(-time.Second), true},
+		{"failed long before cutoff", models.TaskStatusFailed, cutoff.AddDate(0, -3, 0), true},
+		{"cancelled before cutoff", models.TaskStatusCancelled, cutoff.Add(-time.Millisecond), true},
+		{"completed at cutoff", models.TaskStatusCompleted, cutoff, false},
+		{"completed after cutoff", models.TaskStatusCompleted, cutoff.Add(time.Second), false},
+		{"pending before cutoff", models.TaskStatusPending, cutoff.AddDate(0, -1, 0), false},
+		{"running before cutoff", models.TaskStatusRunning, cutoff.AddDate(0, -1, 0), false},
+	}
+	ids := make([]uint64, len(tests))
+	for i, tt := range tests {
+		createdAt := tt.createdAt
+		task := env.createTask(t, tt.status, func(task *models.Task) { task.CreatedAt = createdAt })
+		env.tasks.addTaskLog(task.ID, models.LogLevelInfo, "created", nil)
+		ids[i] = task.ID
+	}
+
+	archived, err := env.tasks.ArchiveTasks(cutoff)
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if archived != 3 {
+		t.Errorf("archived %d tasks, want 3", archived)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tasks, archivedRows, logs int64
+			env.db.Model(&models.Task{}).Where("id = ?", ids[i]).Count(&tasks)
+			env.db.Model(&models.ArchivedTask{}).Where("id = ?", ids[i]).Count(&archivedRows)
+			env.db.Model(&models.TaskLog{}).Where("task_id = ?", ids[i]).Count(&logs)
+
+			if tt.archived && (tasks != 0 || archivedRows != 1 || logs != 0) {
+				t.Fatalf("tasks=%d archived=%d logs=%d, want moved to archive with logs deleted", tasks, archivedRows, logs)
+			}
+			if !tt.archived && (tasks != 1 || archivedRows != 0 || logs != 1) {
+				t.Fatalf("tasks=%d archived=%d logs=%d, want left in place", tasks, archivedRows, logs)
+			}
+		})
+	}
+
+	// 归档后的任务保留原始内容
+	var row models.ArchivedTask
+	if err := env.db.First(&row, ids[0]).Error; err != nil {
+		t.Fatalf("load archived task: %v", err)
+	}
+	if row.Status != models.TaskStatusCompleted || row.Input != "hello" || row.ModelID != env.modelID || row.ArchivedAt.IsZero() {
+		t.Fatalf("archived row = %+v", row)
+	}
+
+	// 再次归档不会重复处理
+	if again, err := env.tasks.ArchiveTasks(cutoff); err != nil || again != 0 {
+		t.Fatalf("second archive = %d, %v; want 0", again, err)
+	}
+}
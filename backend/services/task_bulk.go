@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cancellableStatuses 可以取消的任务状态
+var cancellableStatuses = []models.TaskStatus{models.TaskStatusPending, models.TaskStatusRunning}
+
+// isRetriable 判断任务是否可以重试：状态为 failed 且未超过最大重试次数
+func isRetriable(task *models.Task) bool {
+	return task.Status == models.TaskStatusFailed && task.RetryCount < task.MaxRetries
+}
+
+// BulkCancelTasks 取消所有符合过滤条件的任务，不可取消的任务跳过
+func (s *TaskService) BulkCancelTasks(ctx context.Context, filter *models.TaskListRequest) (*models.BulkTaskResult, error) {
+	result := &models.BulkTaskResult{}
+	var tasks []models.Task
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyTaskFilters(tx.Model(&models.Task{}), filter).Count(&result.Matched).Error; err != nil {
+			return fmt.Errorf("failed to count tasks: %w", err)
+		}
+		if err := applyTaskFilters(tx.Model(&models.Task{}), filter).
+			Where("status IN ?", cancellableStatuses).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&tasks).Error; err != nil {
+			return fmt.Errorf("failed to load tasks: %w", err)
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.Task{}).Where("id IN ?", taskIDs(tasks)).Updates(map[string]interface{}{
+			"status":               models.TaskStatusCancelled,
+			"completed_at":         time.Now(),
+			"waiting_dependencies": false,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to cancel tasks: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再处理队列和事件，避免回滚后已通知 Worker
+	for i := range tasks {
+		s.afterTaskCancelled(ctx, &tasks[i], "Task cancelled by bulk operation")
+	}
+	result.Affected = int64(len(tasks))
+
+	s.logger.WithFields(logrus.Fields{
+		"matched":  result.Matched,
+		"affected": result.Affected,
+	}).Info("Tasks cancelled in bulk")
+
+	return result, nil
+}
+
+// BulkRetryTasks 重试所有符合过滤条件的任务，不可重试的任务跳过
+func (s *TaskService) BulkRetryTasks(ctx context.Context, filter *models.TaskListRequest) (*models.BulkTaskResult, error) {
+	result := &models.BulkTaskResult{}
+	var tasks []models.Task
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyTaskFilters(tx.Model(&models.Task{}), filter).Count(&result.Matched).Error; err != nil {
+			return fmt.Errorf("failed to count tasks: %w", err)
+		}
+		if err := applyTaskFilters(tx.Model(&models.Task{}), filter).
+			Where("status = ? AND retry_count < max_retries", models.TaskStatusFailed).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&tasks).Error; err != nil {
+			return fmt.Errorf("failed to load tasks: %w", err)
+		}
+		// 去重键已被进行中任务占用的任务跳过，否则整批更新会因唯一索引冲突失败
+		var err error
+		if tasks, err = dropDedupConflicts(tx, tasks); err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.Task{}).Where("id IN ?", taskIDs(tasks)).Updates(map[string]interface{}{
+			"status":          models.TaskStatusPending,
+			"error_message":   nil,
+			"started_at":      nil,
+			"completed_at":    nil,
+			"retry_count":     gorm.Expr("retry_count + 1"),
+			"needs_attention": false,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update tasks for retry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err := s.afterTaskRetried(ctx, &tasks[i]); err != nil {
+			// 与单个重试一致，入队失败的任务恢复为失败状态，不计入 affected
+			s.logger.WithError(err).WithField("task_id", tasks[i].ID).Error("Failed to enqueue bulk retried task")
+			continue
+		}
+		result.Affected++
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"matched":  result.Matched,
+		"affected": result.Affected,
+	}).Info("Tasks retried in bulk")
+
+	return result, nil
+}
+
+// afterTaskCancelled 任务状态更新为 cancelled 之后的处理：发布事件、通知 Worker、释放依赖并记录日志，task 为更新前的状态
+func (s *TaskService) afterTaskCancelled(ctx context.Context, task *models.Task, message string) {
+	s.publishEvent(&models.Task{ID: task.ID, ModelID: task.ModelID, Status: models.TaskStatusCancelled, TraceID: task.TraceID}, task.Status)
+
+	// 如果任务在处理中，从处理队列中移除并通知执行该任务的 Worker 中断执行
+	if task.Status == models.TaskStatusRunning {
+		s.queueManager.CompleteTask(ctx, task.ID)
+		if err := s.queueManager.PublishTaskCancel(ctx, task.ID); err != nil {
+			s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to publish task cancel")
+		}
+	}
+	s.queueManager.OnTaskCompleted(ctx, task.ID, models.TaskStatusCancelled)
+
+	s.addTaskLog(task.ID, models.LogLevelInfo, message, nil)
+}
+
+// afterTaskRetried 任务状态重置为 pending 之后的处理：重新入队、发布事件并记录日志，task 为更新前的状态。
+// 入队失败（Redis 不可用或队列已满）时把任务恢复为更新前的失败状态，避免任务停留在 pending 却没有队列项
+func (s *TaskService) afterTaskRetried(ctx context.Context, task *models.Task) error {
+	// 开启 boost_retries 时只提升入队的优先级，任务记录保留原优先级用于统计
+	queued := *task
+	queued.Status = models.TaskStatusPending
+	queued.RetryCount++
+	if s.config.Queue.BoostRetries && queued.Priority < models.TaskPriorityHigh {
+		queued.Priority++
+	}
+	if err := s.queueManager.EnqueueTask(ctx, &queued); err != nil {
+		s.revertRetry(task)
+		return fmt.Errorf("failed to enqueue retry task: %w", err)
+	}
+
+	s.setTaskStatus(task, models.TaskStatusPending)
+	task.RetryCount++
+
+	var data models.LogData
+	if queued.Priority != task.Priority {
+		data = models.LogData{"priority": task.Priority, "queued_priority": queued.Priority}
+	}
+	s.addTaskLog(task.ID, models.LogLevelInfo,
+		fmt.Sprintf("Task retried (attempt %d/%d)", task.RetryCount+1, task.MaxRetries), data)
+	return nil
+}
+
+// revertRetry 重新入队失败后把任务恢复为重试前的状态，task 为更新前的状态。
+// 只恢复仍为 pending 的任务，期间已被取消的任务保持取消
+func (s *TaskService) revertRetry(task *models.Task) {
+	if err := s.db.Model(&models.Task{}).Where("id = ? AND status = ?", task.ID, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":          task.Status,
+			"error_message":   task.ErrorMessage,
+			"started_at":      task.StartedAt,
+			"completed_at":    task.CompletedAt,
+			"retry_count":     task.RetryCount,
+			"needs_attention": task.NeedsAttention,
+		}).Error; err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to revert task after retry enqueue failure")
+	}
+}
+
+func taskIDs(tasks []models.Task) []uint64 {
+	ids := make([]uint64, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
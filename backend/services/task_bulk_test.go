@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// failedTask 创建一个可重试的失败任务
+func (env *testEnv) failedTask(t *testing.T) *models.Task {
+	t.Helper()
+	return env.createTask(t, models.TaskStatusFailed, func(task *models.Task) {
+		message := "model unavailable"
+		completedAt := time.Now()
+		task.ErrorMessage = &message
+		task.CompletedAt = &completedAt
+		task.RetryCount = 1
+	})
+}
+
+// assertStillFailed 检查重试入队失败的任务恢复为重试前的状态且没有队列项
+func assertStillFailed(t *testing.T, env *testEnv, task *models.Task) {
+	t.Helper()
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusFailed {
+		t.Fatalf("expected task %d to stay failed, got %s", task.ID, got.Status)
+	}
+	if got.RetryCount != task.RetryCount {
+		t.Fatalf("expected retry_count %d, got %d", task.RetryCount, got.RetryCount)
+	}
+	if got.ErrorMessage == nil || *got.ErrorMessage != *task.ErrorMessage {
+		t.Fatalf("expected error_message %q to be kept, got %v", *task.ErrorMessage, got.ErrorMessage)
+	}
+	if got.CompletedAt == nil {
+		t.Fatalf("expected completed_at to be kept")
+	}
+}
+
+func TestRetryTaskRevertsWhenQueueFull(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxQueueSize = 1 })
+	ctx := context.Background()
+
+	// 队列中已有一个任务，达到上限
+	pending := env.createTask(t, models.TaskStatusPending, nil)
+	if err := env.queue.EnqueueTask(ctx, pending); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	task := env.failedTask(t)
+	err := env.tasks.RetryTask(ctx, task.ID)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	assertStillFailed(t, env, task)
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != pending.ID {
+		t.Fatalf("expected only task %d queued, got %v", pending.ID, ids)
+	}
+
+	// 队列腾出空间后可以再次重试
+	env.redis.FlushAll()
+	if err := env.tasks.RetryTask(ctx, task.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusPending || got.RetryCount != task.RetryCount+1 {
+		t.Fatalf("expected pending task with retry_count %d, got %s/%d", task.RetryCount+1, got.Status, got.RetryCount)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != task.ID {
+		t.Fatalf("expected task %d queued, got %v", task.ID, ids)
+	}
+}
+
+func TestRetryTaskRevertsWhenRedisUnavailable(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.failedTask(t)
+
+	env.redis.Close()
+	if err := env.tasks.RetryTask(context.Background(), task.ID); err == nil {
+		t.Fatalf("expected retry to fail while redis is down")
+	}
+	assertStillFailed(t, env, task)
+}
+
+func TestBulkRetryTasksRevertsTasksThatCannotBeQueued(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxQueueSize = 1 })
+
+	first := env.failedTask(t)
+	second := env.failedTask(t)
+
+	// 第一个任务入队后队列已满，第二个任务恢复为失败状态，不计入 affected
+	result, err := env.tasks.BulkRetryTasks(context.Background(), &models.TaskListRequest{})
+	if err != nil {
+		t.Fatalf("bulk retry: %v", err)
+	}
+	if result.Matched != 2 || result.Affected != 1 {
+		t.Fatalf("expected matched 2 affected 1, got %+v", result)
+	}
+	if got := env.reloadTask(t, first.ID); got.Status != models.TaskStatusPending {
+		t.Fatalf("expected first task pending, got %s", got.Status)
+	}
+	assertStillFailed(t, env, second)
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != first.ID {
+		t.Fatalf("expected only task %d queued, got %v", first.ID, ids)
+	}
+}
+
+func TestBulkCancelTasksOnlyCancelsEligibleTasks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+	other := env.createModel(t, "other-model", models.ModelTypeCustom, models.ModelStatusOnline, 0)
+
+	pending := env.createTask(t, models.TaskStatusPending, nil)
+	running := env.createTask(t, models.TaskStatusRunning, nil)
+	completed := env.createTask(t, models.TaskStatusCompleted, nil)
+	failed := env.failedTask(t)
+	cancelled := env.createTask(t, models.TaskStatusCancelled, nil)
+	otherPending := env.createTask(t, models.TaskStatusPending, func(task *models.Task) { task.ModelID = other.ID })
+
+	// 过滤条件匹配本模型的 5 个任务，只有 pending 和 running 的任务被取消
+	result, err := env.tasks.BulkCancelTasks(ctx, &models.TaskListRequest{ModelID: &env.modelID})
+	if err != nil {
+		t.Fatalf("bulk cancel: %v", err)
+	}
+	if result.Matched != 5 || result.Affected != 2 {
+		t.Fatalf("result = %+v, want matched 5 affected 2", result)
+	}
+
+	want := map[uint64]models.TaskStatus{
+		pending.ID:      models.TaskStatusCancelled,
+		running.ID:      models.TaskStatusCancelled,
+		completed.ID:    models.TaskStatusCompleted,
+		failed.ID:       models.TaskStatusFailed,
+		cancelled.ID:    models.TaskStatusCancelled,
+		otherPending.ID: models.TaskStatusPending,
+	}
+	for id, status := range want {
+		if got := env.reloadTask(t, id); got.Status != status {
+			t.Fatalf("task %d status = %s, want %s", id, got.Status, status)
+		}
+	}
+	if got := env.reloadTask(t, pending.ID); got.CompletedAt == nil {
+		t.Fatalf("cancelled task has no completed_at")
+	}
+
+	// 再次执行时没有可取消的任务
+	result, err = env.tasks.BulkCancelTasks(ctx, &models.TaskListRequest{ModelID: &env.modelID})
+	if err != nil {
+		t.Fatalf("bulk cancel: %v", err)
+	}
+	if result.Matched != 5 || result.Affected != 0 {
+		t.Fatalf("second result = %+v, want matched 5 affected 0", result)
+	}
+}
+
+func TestBulkRetryTasksOnlyRetriesEligibleTasks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	retriable := env.failedTask(t)
+	exhausted := env.createTask(t, models.TaskStatusFailed, func(task *models.Task) { task.RetryCount = task.MaxRetries })
+	completed := env.createTask(t, models.TaskStatusCompleted, nil)
+	pending := env.createTask(t, models.TaskStatusPending, nil)
+
+	// 只有未用完重试次数的失败任务被重试
+	result, err := env.tasks.BulkRetryTasks(ctx, &models.TaskListRequest{ModelID: &env.modelID})
+	if err != nil {
+		t.Fatalf("bulk retry: %v", err)
+	}
+	if result.Matched != 4 || result.Affected != 1 {
+		t.Fatalf("result = %+v, want matched 4 affected 1", result)
+	}
+
+	got := env.reloadTask(t, retriable.ID)
+	if got.Status != models.TaskStatusPending || got.RetryCount != retriable.RetryCount+1 || got.ErrorMessage != nil || got.CompletedAt != nil {
+		t.Fatalf("retried task = %s retry_count %d, want pending with retry_count %d and cleared result", got.Status, got.RetryCount, retriable.RetryCount+1)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != retriable.ID {
+		t.Fatalf("queued = %v, want [%d]", ids, retriable.ID)
+	}
+	for _, task := range []*models.Task{exhausted, completed, pending} {
+		if got := env.reloadTask(t, task.ID); got.Status != task.Status || got.RetryCount != task.RetryCount {
+			t.Fatalf("task %d = %s/%d, want unchanged %s/%d", task.ID, got.Status, got.RetryCount, task.Status, task.RetryCount)
+		}
+	}
+
+	// 按状态过滤时只统计匹配的任务
+	status := models.TaskStatusCompleted
+	result, err = env.tasks.BulkRetryTasks(ctx, &models.TaskListRequest{Status: &status})
+	if err != nil {
+		t.Fatalf("bulk retry: %v", err)
+	}
+	if result.Matched != 1 || result.Affected != 0 {
+		t.Fatalf("status filter result = %+v, want matched 1 affected 0", result)
+	}
+}
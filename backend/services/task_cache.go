@@ -0,0 +1,61 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// taskCacheKey 计算结果缓存的键：模型、任务类型、输入和参数相同的任务输出视为可复用。
+// 参数按键排序序列化，字段顺序不同的请求得到相同的键
+func taskCacheKey(modelID uint64, taskType, input string, params models.TaskParams) string {
+	paramsJSON, _ := json.Marshal(params)
+
+	h := sha256.New()
+	for _, part := range []string{strconv.FormatUint(modelID, 10), taskType, input, string(paramsJSON)} {
+		// 每段带长度前缀，避免不同的拆分拼接出相同的内容
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findCachedTask 查找 queue.cache_ttl 内以相同缓存键完成的最近一个任务，未启用缓存或未命中时返回 nil
+func (s *TaskService) findCachedTask(cacheKey string) (*models.Task, error) {
+	ttl := s.config.Queue.CacheTTL
+	if ttl <= 0 || cacheKey == "" {
+		return nil, nil
+	}
+
+	var cached models.Task
+	err := s.db.Where("cache_key = ? AND status = ? AND completed_at >= ?",
+		cacheKey, models.TaskStatusCompleted, time.Now().Add(-ttl)).
+		Order("completed_at DESC").
+		First(&cached).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cached task: %w", err)
+	}
+	return &cached, nil
+}
+
+// applyCachedResult 将命中缓存的结果复制到新任务，新任务直接以 completed 状态创建，不入队、不计用量
+func applyCachedResult(task, cached *models.Task) {
+	now := time.Now()
+	task.Status = models.TaskStatusCompleted
+	task.Output = cached.Output
+	task.OutputTruncated = cached.OutputTruncated
+	task.OutputURI = cached.OutputURI
+	task.OutputFormat = cached.OutputFormat
+	task.CachedFromID = &cached.ID
+	task.StartedAt = &now
+	task.CompletedAt = &now
+}
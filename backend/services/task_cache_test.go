@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestTaskCacheKey(t *testing.T) {
+	base := taskCacheKey(1, models.TaskTypeTextGeneration, "hello", models.TaskParams{"temperature": 0.2, "max_tokens": float64(10)})
+
+	// 参数字段顺序不影响缓存键
+	if got := taskCacheKey(1, models.TaskTypeTextGeneration, "hello", models.TaskParams{"max_tokens": float64(10), "temperature": 0.2}); got != base {
+		t.Fatalf("cache key depends on params order")
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"other model", taskCacheKey(2, models.TaskTypeTextGeneration, "hello", models.TaskParams{"temperature": 0.2, "max_tokens": float64(10)})},
+		{"other type", taskCacheKey(1, models.TaskTypeSummarization, "hello", models.TaskParams{"temperature": 0.2, "max_tokens": float64(10)})},
+		{"other input", taskCacheKey(1, models.TaskTypeTextGeneration, "hello!", models.TaskParams{"temperature": 0.2, "max_tokens": float64(10)})},
+		{"other params", taskCacheKey(1, models.TaskTypeTextGeneration, "hello", models.TaskParams{"temperature": 0.3, "max_tokens": float64(10)})},
+		{"no params", taskCacheKey(1, models.TaskTypeTextGeneration, "hello", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.key == base {
+				t.Fatalf("cache key collides with base request")
+			}
+		})
+	}
+
+	// 各段带长度前缀，类型和输入拆分不同的请求不会拼出相同的键
+	if taskCacheKey(1, "ab", "c", nil) == taskCacheKey(1, "a", "bc", nil) {
+		t.Fatalf("cache key collides across field boundaries")
+	}
+}
+
+func TestCreateTaskResultCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		prior     models.TaskStatus
+		priorAge  time.Duration
+		configure func(req *models.TaskCreateRequest)
+		wantHit   bool
+	}{
+		{"hit", time.Hour, models.TaskStatusCompleted, time.Minute, nil, true},
+		// 请求未开启 cache 时正常入队
+		{"not requested", time.Hour, models.TaskStatusCompleted, time.Minute, func(req *models.TaskCreateRequest) { req.Cache = false }, false},
+		{"cache disabled", 0, models.TaskStatusCompleted, time.Minute, nil, false},
+		{"expired", time.Hour, models.TaskStatusCompleted, 2 * time.Hour, nil, false},
+		{"prior task failed", time.Hour, models.TaskStatusFailed, time.Minute, nil, false},
+		{"different input", time.Hour, models.TaskStatusCompleted, time.Minute, func(req *models.TaskCreateRequest) { req.Input = "other" }, false},
+		// 计划在将来执行的任务到时间后再执行，不复用缓存
+		{"scheduled task", time.Hour, models.TaskStatusCompleted, time.Minute, func(req *models.TaskCreateRequest) {
+			runAt := time.Now().Add(time.Hour)
+			req.RunAt = &runAt
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.CacheTTL = tt.ttl })
+			output := "cached output"
+			completedAt := time.Now().Add(-tt.priorAge)
+			prior := env.createTask(t, tt.prior, func(task *models.Task) {
+				task.CacheKey = taskCacheKey(env.modelID, models.TaskTypeTextGeneration, "hello", nil)
+				task.Output = &output
+				task.OutputFormat = models.OutputFormatText
+				task.CompletedAt = &completedAt
+			})
+
+			req := env.createRequest()
+			req.Cache = true
+			if tt.configure != nil {
+				tt.configure(req)
+			}
+			task, err := env.tasks.CreateTask(context.Background(), req)
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+
+			got := env.reloadTask(t, task.ID)
+			if !tt.wantHit {
+				if got.Status != models.TaskStatusPending || got.CachedFromID != nil || got.Output != nil {
+					t.Fatalf("cache miss: task = %s cached_from %v, want pending task", got.Status, got.CachedFromID)
+				}
+				if ids := env.queuedTaskIDs(t); req.RunAt == nil && (len(ids) != 1 || ids[0] != task.ID) {
+					t.Fatalf("queued = %v, want [%d]", ids, task.ID)
+				}
+				return
+			}
+
+			// 命中缓存的任务直接完成，复用原任务的输出且不入队
+			if got.Status != models.TaskStatusCompleted || got.CachedFromID == nil || *got.CachedFromID != prior.ID {
+				t.Fatalf("cache hit: task = %s cached_from %v, want completed from %d", got.Status, got.CachedFromID, prior.ID)
+			}
+			if got.Output == nil || *got.Output != output || got.StartedAt == nil || got.CompletedAt == nil {
+				t.Fatalf("cache hit: output %v started %v completed %v", got.Output, got.StartedAt, got.CompletedAt)
+			}
+			if got.PromptTokens != 0 || got.CompletionTokens != 0 {
+				t.Fatalf("cache hit recorded usage: %d/%d", got.PromptTokens, got.CompletionTokens)
+			}
+			if ids := env.queuedTaskIDs(t); len(ids) != 0 {
+				t.Fatalf("cache hit enqueued tasks: %v", ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskRejectsWhenQueueFull(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Queue.MaxQueueSize = 1
+		cfg.Queue.HighPriorityBypass = true
+	})
+	ctx := context.Background()
+	first := env.mustCreate(t, env.createRequest())
+
+	// 队列已满时在写入数据库前拒绝，不留下入队失败的任务
+	if _, err := env.tasks.CreateTask(ctx, env.createRequest()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("create at capacity error = %v, want ErrQueueFull", err)
+	}
+	var count int64
+	env.db.Model(&models.Task{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("tasks in database = %d, want 1", count)
+	}
+
+	// 等待依赖的任务不入队，不受队列上限限制
+	dependent := env.mustCreate(t, env.createRequest(first.ID))
+	if !dependent.WaitingDeps {
+		t.Fatalf("dependent task should wait for dependencies")
+	}
+
+	// 开启 high_priority_bypass 时高优先级任务仍可创建
+	high := env.createRequest()
+	high.Priority = models.TaskPriorityHigh
+	env.mustCreate(t, high)
+
+	// 任务出队后中优先级任务可以再次创建
+	if _, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if _, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	env.mustCreate(t, env.createRequest())
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// traverseByCursor 从头按游标翻页直到 next_cursor 为空，返回依次读到的任务 ID 和页数
+func (env *testEnv) traverseByCursor(t *testing.T, req models.TaskListRequest) ([]uint64, int) {
+	t.Helper()
+	var ids []uint64
+	cursor := uint64(0)
+	for pages := 1; ; pages++ {
+		req.Cursor = &cursor
+		tasks, next, err := env.tasks.ListTasksByCursor(&req)
+		if err != nil {
+			t.Fatalf("ListTasksByCursor: %v", err)
+		}
+		if len(tasks) > req.Limit {
+			t.Fatalf("page %d has %d tasks, limit %d", pages, len(tasks), req.Limit)
+		}
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		if next == nil {
+			return ids, pages
+		}
+		if *next != tasks[len(tasks)-1].ID {
+			t.Fatalf("next_cursor = %d, want last ID %d", *next, tasks[len(tasks)-1].ID)
+		}
+		cursor = *next
+	}
+}
+
+// traverseByOffset 按 ID 排序用页码翻页直到取完，返回依次读到的任务 ID
+func (env *testEnv) traverseByOffset(t *testing.T, req models.TaskListRequest) []uint64 {
+	t.Helper()
+	var ids []uint64
+	req.OrderBy = "id"
+	req.PageSize = req.Limit
+	for req.Page = 1; ; req.Page++ {
+		tasks, total, err := env.tasks.ListTasks(&req)
+		if err != nil {
+			t.Fatalf("ListTasks: %v", err)
+		}
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		if int64(len(ids)) >= total || len(tasks) == 0 {
+			return ids
+		}
+	}
+}
+
+func TestListTasksByCursorMatchesOffsetTraversal(t *testing.T) {
+	env := newTestEnv(t, nil)
+	// 25 个任务，每 3 个中有 1 个已完成
+	for i := 0; i < 25; i++ {
+		status := models.TaskStatusPending
+		if i%3 == 0 {
+			status = models.TaskStatusCompleted
+		}
+		env.createTask(t, status, nil)
+	}
+
+	completed := models.TaskStatusCompleted
+	tests := []struct {
+		name      string
+		req       models.TaskListRequest
+		wantCount int
+		wantPages int
+	}{
+		{"descending", models.TaskListRequest{Limit: 7, Order: "desc"}, 25, 4},
+		{"ascending", models.TaskListRequest{Limit: 7, Order: "asc"}, 25, 4},
+		// 恰好整页时最后一页之后不再返回游标
+		{"exact pages", models.TaskListRequest{Limit: 5, Order: "desc"}, 25, 5},
+		{"filtered", models.TaskListRequest{Limit: 4, Order: "desc", Status: &completed}, 9, 3},
+		{"single page", models.TaskListRequest{Limit: 100, Order: "desc"}, 25, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursorIDs, pages := env.traverseByCursor(t, tt.req)
+			offsetIDs := env.traverseByOffset(t, tt.req)
+
+			// 游标翻页与页码翻页得到相同的任务，顺序一致且不重复不遗漏
+			if len(cursorIDs) != tt.wantCount || pages != tt.wantPages {
+				t.Fatalf("cursor traversal read %d tasks in %d pages, want %d in %d", len(cursorIDs), pages, tt.wantCount, tt.wantPages)
+			}
+			if fmt.Sprint(cursorIDs) != fmt.Sprint(offsetIDs) {
+				t.Fatalf("cursor traversal = %v, offset traversal = %v", cursorIDs, offsetIDs)
+			}
+		})
+	}
+}
+
+func TestListTasksByCursorSkipsConcurrentInserts(t *testing.T) {
+	env := newTestEnv(t, nil)
+	for i := 0; i < 6; i++ {
+		env.createTask(t, models.TaskStatusPending, nil)
+	}
+
+	// 降序翻页过程中新建的任务 ID 更大，不会插入后续页面造成重复
+	cursor := uint64(0)
+	req := models.TaskListRequest{Limit: 3, Order: "desc", Cursor: &cursor}
+	first, next, err := env.tasks.ListTasksByCursor(&req)
+	if err != nil || next == nil {
+		t.Fatalf("first page = %d tasks next %v (err %v)", len(first), next, err)
+	}
+	env.createTask(t, models.TaskStatusPending, nil)
+
+	req.Cursor = next
+	second, next, err := env.tasks.ListTasksByCursor(&req)
+	if err != nil {
+		t.Fatalf("ListTasksByCursor: %v", err)
+	}
+	if len(second) != 3 || next != nil {
+		t.Fatalf("second page = %d tasks next %v, want the remaining 3 and no cursor", len(second), next)
+	}
+	if second[0].ID >= first[len(first)-1].ID {
+		t.Fatalf("second page starts at %d, want below %d", second[0].ID, first[len(first)-1].ID)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskDeadline(t *testing.T) {
+	env := newTestEnv(t, nil)
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	ttl := 60
+
+	// deadline 原样保存，ttl_seconds 换算为创建时间之后的截止时间
+	req := env.createRequest()
+	req.Deadline = &deadline
+	task := env.mustCreate(t, req)
+	if got := env.reloadTask(t, task.ID); got.Deadline == nil || !got.Deadline.Equal(deadline) {
+		t.Fatalf("deadline = %v, want %v", got.Deadline, deadline)
+	}
+
+	req = env.createRequest()
+	req.TTLSeconds = &ttl
+	before := time.Now()
+	task = env.mustCreate(t, req)
+	got := env.reloadTask(t, task.ID)
+	if got.Deadline == nil || got.Deadline.Before(before.Add(time.Minute-time.Second)) || got.Deadline.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("deadline = %v, want about one minute from now", got.Deadline)
+	}
+
+	// 队列项携带截止时间，出队时据此判断是否过期
+	for _, item := range env.queuedItems(t) {
+		if item.Deadline == nil {
+			t.Fatalf("queued task %d has no deadline", item.TaskID)
+		}
+	}
+}
+
+func TestCreateTaskRejectsInvalidDeadline(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+	zero, ttl := 0, 60
+	tests := []struct {
+		name      string
+		deadline  *time.Time
+		ttl       *int
+		wantField string
+	}{
+		{"deadline in the past", &past, nil, "deadline"},
+		{"non-positive ttl", nil, &zero, "ttl_seconds"},
+		{"both deadline and ttl", &future, &ttl, "ttl_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			req := env.createRequest()
+			req.Deadline = tt.deadline
+			req.TTLSeconds = tt.ttl
+
+			_, err := env.tasks.CreateTask(context.Background(), req)
+			if err == nil {
+				t.Fatalf("CreateTask succeeded, want validation error")
+			}
+			if fields := fieldNames(t, err); len(fields) != 1 || fields[0] != tt.wantField {
+				t.Fatalf("invalid fields = %v, want [%s]", fields, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestTaskExpiresWhileQueued(t *testing.T) {
+	env := newTestEnv(t, nil)
+	req := env.createRequest()
+	deadline := time.Now().Add(30 * time.Millisecond)
+	req.Deadline = &deadline
+	task := env.mustCreate(t, req)
+
+	// 排队期间超过截止时间，出队时丢弃并取消，不交给 Worker
+	time.Sleep(50 * time.Millisecond)
+	item, err := env.queue.DequeueTask(context.Background(), env.modelID, nil)
+	if err != nil || item != nil {
+		t.Fatalf("dequeued %+v (err %v), want expired task dropped", item, err)
+	}
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled || got.ErrorMessage == nil || *got.ErrorMessage != models.DeadlineExceededMessage {
+		t.Fatalf("task status %s error %v, want cancelled with %q", got.Status, got.ErrorMessage, models.DeadlineExceededMessage)
+	}
+	if got.StartedAt != nil || got.CompletedAt == nil {
+		t.Fatalf("started_at = %v completed_at = %v, want never started and completed", got.StartedAt, got.CompletedAt)
+	}
+}
+
+func TestExpireTaskOnlyCancelsPendingTasks(t *testing.T) {
+	tests := []struct {
+		status models.TaskStatus
+		want   models.TaskStatus
+	}{
+		{models.TaskStatusPending, models.TaskStatusCancelled},
+		// 已开始执行或已结束的任务不受截止时间影响
+		{models.TaskStatusRunning, models.TaskStatusRunning},
+		{models.TaskStatusCompleted, models.TaskStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			task := env.createTask(t, tt.status, nil)
+			if err := env.tasks.ExpireTask(context.Background(), task.ID); err != nil {
+				t.Fatalf("ExpireTask: %v", err)
+			}
+			if got := env.reloadTask(t, task.ID); got.Status != tt.want {
+				t.Fatalf("status = %s, want %s", got.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// findActiveDuplicate 查找去重键相同的 pending/running 任务，未设置去重键或不存在时返回 nil
+func findActiveDuplicate(db *gorm.DB, dedupKey string) (*models.Task, error) {
+	if dedupKey == "" {
+		return nil, nil
+	}
+
+	var task models.Task
+	err := db.Where("dedup_key = ? AND status IN ?", dedupKey, cancellableStatuses).First(&task).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task by dedup key: %w", err)
+	}
+	return &task, nil
+}
+
+// returnDuplicate 创建请求命中去重键时返回已有任务并记录日志
+func (s *TaskService) returnDuplicate(existing *models.Task) *models.Task {
+	existing.Deduplicated = true
+	s.addTaskLog(existing.ID, models.LogLevelInfo, "Duplicate create request returned this task",
+		models.LogData{"dedup_key": existing.DedupKey})
+	return existing
+}
+
+// dropDedupConflicts 从待重试的任务中去掉去重键已被其他进行中任务占用的任务，
+// 多个待重试任务的去重键相同时只保留第一个
+func dropDedupConflicts(tx *gorm.DB, tasks []models.Task) ([]models.Task, error) {
+	var keys []string
+	for _, task := range tasks {
+		if task.DedupKey != "" {
+			keys = append(keys, task.DedupKey)
+		}
+	}
+	if len(keys) == 0 {
+		return tasks, nil
+	}
+
+	var active []string
+	if err := tx.Model(&models.Task{}).
+		Where("dedup_key IN ? AND status IN ?", keys, cancellableStatuses).
+		Distinct().Pluck("dedup_key", &active).Error; err != nil {
+		return nil, fmt.Errorf("failed to query active dedup keys: %w", err)
+	}
+
+	taken := make(map[string]bool, len(active))
+	for _, key := range active {
+		taken[key] = true
+	}
+
+	kept := tasks[:0]
+	for _, task := range tasks {
+		if task.DedupKey != "" {
+			if taken[task.DedupKey] {
+				continue
+			}
+			taken[task.DedupKey] = true
+		}
+		kept = append(kept, task)
+	}
+	return kept, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// dedupRequest 构造带去重键的任务请求
+func (env *testEnv) dedupRequest(key string) *models.TaskCreateRequest {
+	req := env.createRequest()
+	req.DedupKey = key
+	return req
+}
+
+// countTasks 返回数据库中的任务数
+func (env *testEnv) countTasks(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := env.db.Model(&models.Task{}).Count(&n).Error; err != nil {
+		t.Fatalf("count tasks: %v", err)
+	}
+	return n
+}
+
+func TestCreateTaskDedupCollapsesActiveSubmissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.TaskStatus
+	}{
+		{"pending", models.TaskStatusPending},
+		{"running", models.TaskStatusRunning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			first := env.mustCreate(t, env.dedupRequest("order-42"))
+			if first.Deduplicated {
+				t.Fatalf("first submission marked as deduplicated")
+			}
+			if tt.status != models.TaskStatusPending {
+				setStatus(t, env.db, first.ID, tt.status)
+			}
+
+			// 进行中的任务占用去重键，再次提交返回同一个任务
+			second := env.mustCreate(t, env.dedupRequest("order-42"))
+			if second.ID != first.ID || !second.Deduplicated {
+				t.Fatalf("second submission = task %d (deduplicated %v), want task %d", second.ID, second.Deduplicated, first.ID)
+			}
+			if n := env.countTasks(t); n != 1 {
+				t.Fatalf("got %d tasks, want 1", n)
+			}
+			if ids := env.queuedTaskIDs(t); tt.status == models.TaskStatusPending && len(ids) != 1 {
+				t.Fatalf("queued tasks = %v, want only the first task", ids)
+			}
+
+			logs, _, err := env.tasks.ListTaskLogs(first.ID, &models.TaskLogListRequest{Page: 1, PageSize: 50})
+			if err != nil {
+				t.Fatalf("list logs: %v", err)
+			}
+			found := false
+			for _, log := range logs {
+				if log.Message == "Duplicate create request returned this task" && log.Data["dedup_key"] == "order-42" {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("logs = %+v, want duplicate request log", logs)
+			}
+		})
+	}
+}
+
+func TestCreateTaskDedupAllowsResubmitAfterFinish(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish func(env *testEnv, t *testing.T, id uint64)
+	}{
+		{"completed", func(env *testEnv, t *testing.T, id uint64) {
+			env.finish(t, id, models.TaskStatusCompleted)
+		}},
+		{"failed", func(env *testEnv, t *testing.T, id uint64) {
+			env.finish(t, id, models.TaskStatusFailed)
+		}},
+		{"cancelled", func(env *testEnv, t *testing.T, id uint64) {
+			if err := env.tasks.CancelTask(context.Background(), id); err != nil {
+				t.Fatalf("cancel task: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			first := env.mustCreate(t, env.dedupRequest("order-42"))
+			tt.finish(env, t, first.ID)
+
+			// 任务结束后去重键释放，可以再次提交
+			second := env.mustCreate(t, env.dedupRequest("order-42"))
+			if second.ID == first.ID || second.Deduplicated {
+				t.Fatalf("resubmission returned task %d (deduplicated %v), want a new task", second.ID, second.Deduplicated)
+			}
+			if n := env.countTasks(t); n != 2 {
+				t.Fatalf("got %d tasks, want 2", n)
+			}
+		})
+	}
+}
+
+func TestCreateTaskWithoutSharedDedupKey(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	// 去重键不同或未设置去重键的任务各自创建
+	for _, keys := range [][2]string{{"a", "b"}, {"", ""}} {
+		first := env.mustCreate(t, env.dedupRequest(keys[0]))
+		second := env.mustCreate(t, env.dedupRequest(keys[1]))
+		if first.ID == second.ID || second.Deduplicated {
+			t.Fatalf("keys %q: tasks %d and %d collapsed", keys, first.ID, second.ID)
+		}
+	}
+	if n := env.countTasks(t); n != 4 {
+		t.Fatalf("got %d tasks, want 4", n)
+	}
+}
+
+func TestCreateTasksDedup(t *testing.T) {
+	env := newTestEnv(t, nil)
+	existing := env.mustCreate(t, env.dedupRequest("held"))
+
+	results, err := env.tasks.CreateTasks(context.Background(), []*models.TaskCreateRequest{
+		env.dedupRequest("new"),
+		env.dedupRequest("new"),
+		env.dedupRequest("held"),
+	})
+	if err != nil {
+		t.Fatalf("CreateTasks: %v", err)
+	}
+
+	// 同一批次中重复的去重键报错，已被进行中任务占用的返回已有任务
+	if results[0].TaskID == 0 || results[0].Error != "" {
+		t.Fatalf("result 0 = %+v, want created task", results[0])
+	}
+	if results[1].TaskID != 0 || results[1].Error != "duplicate dedup_key within batch" {
+		t.Fatalf("result 1 = %+v, want duplicate within batch error", results[1])
+	}
+	if results[2].TaskID != existing.ID || results[2].Error != "" {
+		t.Fatalf("result 2 = %+v, want existing task %d", results[2], existing.ID)
+	}
+	if n := env.countTasks(t); n != 2 {
+		t.Fatalf("got %d tasks, want 2", n)
+	}
+}
+
+func TestRetryTaskDedupKeyActive(t *testing.T) {
+	env := newTestEnv(t, nil)
+	failed := env.failedTask(t)
+	if err := env.db.Model(failed).Update("dedup_key", "order-42").Error; err != nil {
+		t.Fatalf("set dedup key: %v", err)
+	}
+	active := env.mustCreate(t, env.dedupRequest("order-42"))
+
+	// 去重键已被新提交的任务占用，失败任务不能重试
+	if err := env.tasks.RetryTask(context.Background(), failed.ID); !errors.Is(err, ErrDedupKeyActive) {
+		t.Fatalf("RetryTask error = %v, want ErrDedupKeyActive", err)
+	}
+	if got := env.reloadTask(t, failed.ID); got.Status != models.TaskStatusFailed {
+		t.Fatalf("failed task status = %s, want failed", got.Status)
+	}
+
+	// 占用的任务结束后可以重试
+	env.finish(t, active.ID, models.TaskStatusCompleted)
+	if err := env.tasks.RetryTask(context.Background(), failed.ID); err != nil {
+		t.Fatalf("RetryTask after release: %v", err)
+	}
+}
+
+func TestBulkRetryTasksSkipsDedupConflicts(t *testing.T) {
+	env := newTestEnv(t, nil)
+	var failed []*models.Task
+	for _, key := range []string{"shared", "shared", "held", ""} {
+		task := env.failedTask(t)
+		if err := env.db.Model(task).Update("dedup_key", key).Error; err != nil {
+			t.Fatalf("set dedup key: %v", err)
+		}
+		failed = append(failed, task)
+	}
+	env.mustCreate(t, env.dedupRequest("held"))
+
+	// 去重键相同的失败任务只重试第一个，已被占用的跳过，没有去重键的照常重试
+	status := models.TaskStatusFailed
+	result, err := env.tasks.BulkRetryTasks(context.Background(), &models.TaskListRequest{Status: &status})
+	if err != nil {
+		t.Fatalf("BulkRetryTasks: %v", err)
+	}
+	if result.Matched != 4 || result.Affected != 2 {
+		t.Fatalf("result = %+v, want 4 matched and 2 affected", result)
+	}
+	want := []models.TaskStatus{models.TaskStatusPending, models.TaskStatusFailed, models.TaskStatusFailed, models.TaskStatusPending}
+	for i, task := range failed {
+		if got := env.reloadTask(t, task.ID); got.Status != want[i] {
+			t.Fatalf("task %d status = %s, want %s", i, got.Status, want[i])
+		}
+	}
+}
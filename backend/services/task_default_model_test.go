@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskUsesDefaultModel(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultModel func(env *testEnv) string
+		wantErr      string
+	}{
+		{"by name", func(env *testEnv) string { return "test-model" }, ""},
+		{"by id", func(env *testEnv) string { return strconv.FormatUint(env.modelID, 10) }, ""},
+		{"not configured", func(env *testEnv) string { return "" }, "model_id is required"},
+		{"not found", func(env *testEnv) string { return "missing-model" }, "default model missing-model not found"},
+		{"offline", func(env *testEnv) string { return "offline-model" }, "default model offline-model is offline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			offline := &models.Model{Name: "offline-model", Type: models.ModelTypeCustom, Status: models.ModelStatusOffline, MaxWorkers: 1}
+			if err := env.db.Create(offline).Error; err != nil {
+				t.Fatalf("create offline model: %v", err)
+			}
+			env.cfg.Models.DefaultModel = tt.defaultModel(env)
+
+			req := env.createRequest()
+			req.ModelID = 0
+			task, err := env.tasks.CreateTask(context.Background(), req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("create task: %v", err)
+				}
+				if task.ModelID != env.modelID {
+					t.Fatalf("task model = %d, want default model %d", task.ModelID, env.modelID)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != "model_id" {
+				t.Fatalf("expected model_id validation error, got %v", err)
+			}
+			if !strings.Contains(validationErr.Fields[0].Message, tt.wantErr) {
+				t.Fatalf("message = %q, want %q", validationErr.Fields[0].Message, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateTaskExplicitModelIgnoresDefault(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Models.DefaultModel = "missing-model" })
+
+	// 显式指定的 model_id 不受默认模型配置影响
+	task := env.mustCreate(t, env.createRequest())
+	if task.ModelID != env.modelID {
+		t.Fatalf("task model = %d, want %d", task.ModelID, env.modelID)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// uniqueTaskIDs 去除重复的任务 ID，保持原有顺序
+func uniqueTaskIDs(ids []uint64) models.TaskIDs {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	seen := make(map[uint64]bool, len(ids))
+	result := make(models.TaskIDs, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// getDependencies 查询依赖任务的当前状态，任一依赖不存在时返回校验错误
+func (s *TaskService) getDependencies(ids models.TaskIDs) ([]models.Task, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var deps []models.Task
+	if err := s.db.Select("id", "status").Where("id IN ?", []uint64(ids)).Find(&deps).Error; err != nil {
+		return nil, fmt.Errorf("failed to query dependencies: %w", err)
+	}
+
+	if len(deps) != len(ids) {
+		found := make(map[uint64]bool, len(deps))
+		for _, dep := range deps {
+			found[dep.ID] = true
+		}
+
+		var fields []models.FieldError
+		for _, id := range ids {
+			if !found[id] {
+				fields = append(fields, models.FieldError{
+					Field:   "depends_on",
+					Message: fmt.Sprintf("dependency task %d not found", id),
+				})
+			}
+		}
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	return deps, nil
+}
+
+// checkDependencies 根据依赖状态决定等待中的任务是入队、失败还是继续等待
+func (s *TaskService) checkDependencies(ctx context.Context, task *models.Task, deps []models.Task) {
+	for _, dep := range deps {
+		if dep.Status == models.TaskStatusFailed || dep.Status == models.TaskStatusCancelled {
+			s.failWaitingTask(ctx, task.ID, fmt.Sprintf("dependency failed: task %d is %s", dep.ID, dep.Status))
+			return
+		}
+	}
+
+	for _, dep := range deps {
+		if dep.Status != models.TaskStatusCompleted {
+			return
+		}
+	}
+
+	s.releaseWaitingTask(ctx, task)
+}
+
+// resolveDependents 任务进入终态后检查依赖它的等待中任务
+func (s *TaskService) resolveDependents(ctx context.Context, taskID uint64, status models.TaskStatus) {
+	var dependents []models.Task
+	if err := s.db.Where("waiting_dependencies = ? AND status = ? AND JSON_CONTAINS(depends_on, ?)",
+		true, models.TaskStatusPending, strconv.FormatUint(taskID, 10)).
+		Find(&dependents).Error; err != nil {
+		s.logger.WithError(err).WithField("task_id", taskID).Error("Failed to query dependent tasks")
+		return
+	}
+
+	for i := range dependents {
+		dependent := &dependents[i]
+
+		deps, err := s.getDependencies(dependent.DependsOn)
+		if err != nil {
+			s.logger.WithError(err).WithField("task_id", dependent.ID).Error("Failed to check task dependencies")
+			continue
+		}
+
+		s.checkDependencies(ctx, dependent, deps)
+	}
+}
+
+// releaseWaitingTask 依赖全部完成后将任务入队，通过条件更新保证只入队一次
+func (s *TaskService) releaseWaitingTask(ctx context.Context, task *models.Task) {
+	result := s.db.Model(&models.Task{}).
+		Where("id = ? AND waiting_dependencies = ?", task.ID, true).
+		Update("waiting_dependencies", false)
+	if result.Error != nil {
+		s.logger.WithError(result.Error).WithField("task_id", task.ID).Error("Failed to release waiting task")
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	if err := s.queueManager.EnqueueTask(ctx, task); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to enqueue task")
+		_ = s.FailTask(task.ID, "Failed to enqueue task")
+		s.queueManager.OnTaskCompleted(ctx, task.ID, models.TaskStatusFailed)
+		return
+	}
+
+	s.addTaskLog(task.ID, models.LogLevelInfo, "Dependencies completed, task enqueued", nil)
+
+	s.logger.WithFields(logrus.Fields{
+		"task_id":    task.ID,
+		"depends_on": []uint64(task.DependsOn),
+	}).Info("Task dependencies completed")
+}
+
+// failWaitingTask 依赖失败或取消时将等待中的任务标记为失败，并继续通知依赖它的任务
+func (s *TaskService) failWaitingTask(ctx context.Context, id uint64, errorMsg string) {
+	result := s.db.Model(&models.Task{}).
+		Where("id = ? AND waiting_dependencies = ? AND status = ?", id, true, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":               models.TaskStatusFailed,
+			"error_message":        errorMsg,
+			"completed_at":         time.Now(),
+			"waiting_dependencies": false,
+		})
+	if result.Error != nil {
+		s.logger.WithError(result.Error).WithField("task_id", id).Error("Failed to fail waiting task")
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	if task, err := s.loadTaskState(id); err == nil {
+		s.publishEvent(task, models.TaskStatusPending)
+	}
+
+	s.addTaskLog(id, models.LogLevelError, "Task failed", map[string]interface{}{
+		"error": errorMsg,
+	})
+
+	s.publishDone(ctx, id, models.TaskStatusFailed, errorMsg)
+
+	s.logger.WithFields(logrus.Fields{
+		"task_id": id,
+		"error":   errorMsg,
+	}).Warn("Task failed due to dependency")
+
+	s.queueManager.OnTaskCompleted(ctx, id, models.TaskStatusFailed)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// createRequest 构造使用测试模型的文本生成任务请求
+func (env *testEnv) createRequest(dependsOn ...uint64) *models.TaskCreateRequest {
+	return &models.TaskCreateRequest{
+		ModelID:   env.modelID,
+		Type:      models.TaskTypeTextGeneration,
+		Input:     "hello",
+		DependsOn: dependsOn,
+	}
+}
+
+// mustCreate 通过任务服务创建任务，失败时终止测试
+func (env *testEnv) mustCreate(t *testing.T, req *models.TaskCreateRequest) *models.Task {
+	t.Helper()
+	task, err := env.tasks.CreateTask(context.Background(), req)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+// finish 将任务标记为完成或失败并触发任务结束的回调，与 Worker 的收尾一致
+func (env *testEnv) finish(t *testing.T, id uint64, status models.TaskStatus) {
+	t.Helper()
+	setStatus(t, env.db, id, models.TaskStatusRunning)
+	var err error
+	if status == models.TaskStatusCompleted {
+		err = env.tasks.CompleteTask(id, "done", models.OutputFormatText, models.TokenUsage{})
+	} else {
+		err = env.tasks.FailTask(id, "upstream error")
+	}
+	if err != nil {
+		t.Fatalf("finish task %d: %v", id, err)
+	}
+	env.queue.OnTaskCompleted(context.Background(), id, status)
+}
+
+// containsID 判断 ids 中是否有 id
+func containsID(ids []uint64, id uint64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDependentTaskWaitsThenEnqueues(t *testing.T) {
+	env := newTestEnv(t, nil)
+	first := env.mustCreate(t, env.createRequest())
+	second := env.mustCreate(t, env.createRequest())
+	waiting := env.mustCreate(t, env.createRequest(first.ID, second.ID, first.ID))
+
+	// 依赖未完成时任务保持等待，不进入队列
+	got := env.reloadTask(t, waiting.ID)
+	if !got.WaitingDeps || got.Status != models.TaskStatusPending || len(got.DependsOn) != 2 {
+		t.Fatalf("expected waiting task with 2 deduplicated dependencies, got %+v", got)
+	}
+	if containsID(env.queuedTaskIDs(t), waiting.ID) {
+		t.Fatal("waiting task should not be queued")
+	}
+
+	// 只完成一个依赖时继续等待
+	env.finish(t, first.ID, models.TaskStatusCompleted)
+	if got := env.reloadTask(t, waiting.ID); !got.WaitingDeps || containsID(env.queuedTaskIDs(t), waiting.ID) {
+		t.Fatal("task released before all dependencies completed")
+	}
+
+	// 全部依赖完成后入队
+	env.finish(t, second.ID, models.TaskStatusCompleted)
+	if got := env.reloadTask(t, waiting.ID); got.WaitingDeps || got.Status != models.TaskStatusPending {
+		t.Fatalf("expected released pending task, got waiting=%v status=%s", got.WaitingDeps, got.Status)
+	}
+	if !containsID(env.queuedTaskIDs(t), waiting.ID) {
+		t.Fatal("expected released task to be queued")
+	}
+}
+
+func TestDependencyFailureCascades(t *testing.T) {
+	env := newTestEnv(t, nil)
+	root := env.mustCreate(t, env.createRequest())
+	child := env.mustCreate(t, env.createRequest(root.ID))
+	grandchild := env.mustCreate(t, env.createRequest(child.ID))
+
+	// 依赖失败时等待中的任务失败，并继续传递给依赖它的任务
+	env.finish(t, root.ID, models.TaskStatusFailed)
+	for _, id := range []uint64{child.ID, grandchild.ID} {
+		got := env.reloadTask(t, id)
+		if got.Status != models.TaskStatusFailed || got.WaitingDeps {
+			t.Fatalf("task %d: expected failed, got %s (waiting %v)", id, got.Status, got.WaitingDeps)
+		}
+		if got.ErrorMessage == nil || !strings.Contains(*got.ErrorMessage, "dependency failed") {
+			t.Fatalf("task %d: error message = %v", id, got.ErrorMessage)
+		}
+	}
+}
+
+func TestDependencyStatusCheckedAtCreation(t *testing.T) {
+	env := newTestEnv(t, nil)
+	done := env.createTask(t, models.TaskStatusCompleted, nil)
+	cancelled := env.createTask(t, models.TaskStatusCancelled, nil)
+
+	// 依赖已完成时直接入队
+	ready := env.mustCreate(t, env.createRequest(done.ID))
+	if got := env.reloadTask(t, ready.ID); got.WaitingDeps || !containsID(env.queuedTaskIDs(t), ready.ID) {
+		t.Fatal("expected task with completed dependency to be queued")
+	}
+
+	// 依赖已取消时直接失败
+	doomed := env.mustCreate(t, env.createRequest(done.ID, cancelled.ID))
+	if got := env.reloadTask(t, doomed.ID); got.Status != models.TaskStatusFailed {
+		t.Fatalf("expected task with cancelled dependency to fail, got %s", got.Status)
+	}
+
+	// 依赖不存在时校验失败
+	_, err := env.tasks.CreateTask(context.Background(), env.createRequest(done.ID, 9999))
+	if names := fieldNames(t, err); len(names) != 1 || names[0] != "depends_on" {
+		t.Fatalf("expected depends_on validation error, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestStuckTaskEscalatedAfterMaxDelays(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Queue.MaxDelayCount = 2
+		cfg.Queue.TaskTimeout = time.Minute
+		cfg.Queue.RetryDelay = time.Second
+	})
+	ctx := context.Background()
+	task := env.createTask(t, models.TaskStatusPending, nil)
+	if err := env.queue.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	// 任务每次被领取后都卡住超时：前两次进入延迟队列重试，第三次超过阈值被升级
+	for attempt := 1; attempt <= 3; attempt++ {
+		item, err := env.queue.DequeueTask(ctx, env.modelID, nil)
+		if err != nil || item == nil || item.TaskID != task.ID {
+			t.Fatalf("attempt %d: expected to claim task %d, got %+v (err %v)", attempt, task.ID, item, err)
+		}
+		if item.DelayCount != attempt-1 {
+			t.Fatalf("attempt %d: delay count = %d, want %d", attempt, item.DelayCount, attempt-1)
+		}
+		setStatus(t, env.db, task.ID, models.TaskStatusRunning)
+
+		env.expireAll(t, env.cfg.Queue.ProcessingQueue)
+		if err := env.queue.CleanupStuckTasks(ctx); err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+		if n := env.zcard(t, env.cfg.Queue.ProcessingQueue); n != 0 {
+			t.Fatalf("attempt %d: processing queue has %d items", attempt, n)
+		}
+		if attempt == 3 {
+			break
+		}
+
+		if got := env.reloadTask(t, task.ID); got.NeedsAttention {
+			t.Fatalf("attempt %d: task escalated before reaching the threshold", attempt)
+		}
+		env.expireAll(t, env.cfg.Queue.DelayedQueue)
+		if err := env.queue.ProcessDelayedTasks(ctx); err != nil {
+			t.Fatalf("process delayed: %v", err)
+		}
+	}
+
+	got := env.reloadTask(t, task.ID)
+	if !got.NeedsAttention || got.Status != models.TaskStatusFailed {
+		t.Fatalf("task = %s (needs_attention %v), want failed and escalated", got.Status, got.NeedsAttention)
+	}
+	if n := env.zcard(t, env.cfg.Queue.DelayedQueue); n != 0 {
+		t.Fatalf("escalated task still in delayed queue (%d items)", n)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 0 {
+		t.Fatalf("escalated task requeued: %v", ids)
+	}
+
+	// 重试被升级的任务会清除需人工处理标记
+	if err := env.tasks.RetryTask(ctx, task.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := env.reloadTask(t, task.ID); got.NeedsAttention {
+		t.Fatal("retry kept needs_attention")
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// estimateSampleSize 估算平均处理时间时取模型最近完成的任务数
+const estimateSampleSize = 100
+
+// FillEstimatedWait 为排队中的任务填充 EstimatedWaitMs：
+// 同模型排在前面的任务数 × 模型最近任务的平均处理时间 ÷ 模型的 Worker 数。
+// 只是尽力估算，不计入延迟队列、并发上限与频率限制，也不预测后续插队的高优先级任务；
+// 任务不在排队、模型没有可用 Worker 或缺少历史数据时不填充
+func (s *TaskService) FillEstimatedWait(ctx context.Context, task *models.Task) error {
+	if task.Status != models.TaskStatusPending || task.WaitingDeps || task.ScheduledAfter(time.Now()) {
+		return nil
+	}
+
+	var model models.Model
+	if err := s.db.Select("id", "max_workers", "current_workers").First(&model, task.ModelID).Error; err != nil {
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+	workers := model.CurrentWorkers
+	if workers <= 0 {
+		workers = model.MaxWorkers
+	}
+
+	avgProcessing, err := s.recentAvgProcessing(task.ModelID)
+	if err != nil {
+		return err
+	}
+
+	wait, ok, err := s.queueManager.EstimateWait(ctx, task, avgProcessing, workers)
+	if err != nil {
+		return fmt.Errorf("failed to estimate wait: %w", err)
+	}
+	if ok {
+		ms := wait.Milliseconds()
+		task.EstimatedWaitMs = &ms
+	}
+	return nil
+}
+
+// recentAvgProcessing 返回模型最近完成任务的平均处理时间，没有历史数据时返回 0
+func (s *TaskService) recentAvgProcessing(modelID uint64) (time.Duration, error) {
+	recent := s.db.Model(&models.Task{}).
+		Select("TIMESTAMPDIFF(MICROSECOND, started_at, completed_at) AS duration_us").
+		Where("model_id = ? AND status = ? AND started_at IS NOT NULL AND completed_at IS NOT NULL",
+			modelID, models.TaskStatusCompleted).
+		Order("completed_at DESC").
+		Limit(estimateSampleSize)
+
+	var avgUs sql.NullFloat64
+	if err := s.db.Table("(?) AS recent", recent).Select("AVG(duration_us)").Scan(&avgUs).Error; err != nil {
+		return 0, fmt.Errorf("failed to query average processing time: %w", err)
+	}
+	if !avgUs.Valid {
+		return 0, nil
+	}
+	return time.Duration(avgUs.Float64) * time.Microsecond, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// createCompletedTask 创建处理耗时为 duration 的已完成任务，作为平均处理时间的历史数据
+func (env *testEnv) createCompletedTask(t *testing.T, duration time.Duration) {
+	t.Helper()
+	env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) {
+		startedAt := time.Now().Add(-time.Hour)
+		completedAt := startedAt.Add(duration)
+		task.StartedAt = &startedAt
+		task.CompletedAt = &completedAt
+	})
+}
+
+func TestFillEstimatedWait(t *testing.T) {
+	tests := []struct {
+		name           string
+		history        []time.Duration
+		currentWorkers int
+		ahead          int
+		configure      func(*models.Task)
+		want           int64 // -1 表示不填充
+	}{
+		// 前面 2 个任务，平均 3 秒，1 个 Worker
+		{"queued behind tasks", []time.Duration{2 * time.Second, 4 * time.Second}, 0, 2, nil, 6000},
+		// 运行中的 Worker 数优先于 max_workers
+		{"current workers", []time.Duration{2 * time.Second, 4 * time.Second}, 2, 2, nil, 3000},
+		// 前面没有任务时预计立即执行
+		{"nothing ahead", nil, 0, 0, nil, 0},
+		// 缺少历史数据、任务不在排队或尚未到执行时间时不填充
+		{"no history", nil, 0, 2, nil, -1},
+		{"running task", []time.Duration{time.Second}, 0, 2, func(task *models.Task) { task.Status = models.TaskStatusRunning }, -1},
+		{"scheduled task", []time.Duration{time.Second}, 0, 2, func(task *models.Task) {
+			at := time.Now().Add(time.Hour)
+			task.RunAt = &at
+		}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			if tt.currentWorkers > 0 {
+				env.db.Model(&models.Model{}).Where("id = ?", env.modelID).Update("current_workers", tt.currentWorkers)
+			}
+			for _, duration := range tt.history {
+				env.createCompletedTask(t, duration)
+			}
+			for i := 0; i < tt.ahead; i++ {
+				env.mustCreate(t, env.createRequest())
+			}
+
+			task := env.mustCreate(t, env.createRequest())
+			if tt.configure != nil {
+				tt.configure(task)
+			}
+			if err := env.tasks.FillEstimatedWait(context.Background(), task); err != nil {
+				t.Fatalf("FillEstimatedWait: %v", err)
+			}
+			got := int64(-1)
+			if task.EstimatedWaitMs != nil {
+				got = *task.EstimatedWaitMs
+			}
+			if got != tt.want {
+				t.Fatalf("estimated wait = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
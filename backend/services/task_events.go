@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// loadTaskState 读取任务当前状态，状态变更后用于发布事件
+func (s *TaskService) loadTaskState(id uint64) (*models.Task, error) {
+	var task models.Task
+	if err := s.db.Select("id", "model_id", "status", "trace_id").First(&task, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return &task, nil
+}
+
+// publishEvent 发布任务状态变更事件，task.Status 为变更后的状态；状态未变化时不发布
+func (s *TaskService) publishEvent(task *models.Task, oldStatus models.TaskStatus) {
+	if task.Status == oldStatus {
+		return
+	}
+
+	event := &models.TaskEvent{
+		TaskID:    task.ID,
+		ModelID:   task.ModelID,
+		OldStatus: oldStatus,
+		NewStatus: task.Status,
+		TraceID:   task.TraceID,
+		Timestamp: time.Now(),
+	}
+	if err := s.queueManager.PublishTaskStatusEvent(context.Background(), event); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task status event")
+	}
+}
+
+// setTaskStatus 在 task 上记录新状态并发布变更事件
+func (s *TaskService) setTaskStatus(task *models.Task, status models.TaskStatus) {
+	oldStatus := task.Status
+	task.Status = status
+	s.publishEvent(task, oldStatus)
+}
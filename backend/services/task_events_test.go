@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// receiveTaskEvents 读取订阅中的任务状态事件，直到 timeout 内没有新消息
+func receiveTaskEvents(t *testing.T, pubsub *redis.PubSub, timeout time.Duration) []models.TaskEvent {
+	t.Helper()
+	var events []models.TaskEvent
+	for {
+		msg, err := pubsub.ReceiveTimeout(context.Background(), timeout)
+		if err != nil {
+			return events
+		}
+		message, ok := msg.(*redis.Message)
+		if !ok {
+			continue
+		}
+		var event models.TaskEvent
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestStatusChangePublishesOneEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      models.TaskStatus
+		change    func(env *testEnv, id uint64) error
+		wantEvent bool
+		to        models.TaskStatus
+	}{
+		{"start", models.TaskStatusPending, func(env *testEnv, id uint64) error { return env.tasks.StartTask(id) }, true, models.TaskStatusRunning},
+		{"complete", models.TaskStatusRunning, func(env *testEnv, id uint64) error {
+			return env.tasks.CompleteTask(id, "done", models.OutputFormatText, models.TokenUsage{})
+		}, true, models.TaskStatusCompleted},
+		{"fail", models.TaskStatusRunning, func(env *testEnv, id uint64) error { return env.tasks.FailTask(id, "boom") }, true, models.TaskStatusFailed},
+		{"cancel pending", models.TaskStatusPending, func(env *testEnv, id uint64) error {
+			return env.tasks.CancelTask(context.Background(), id)
+		}, true, models.TaskStatusCancelled},
+		{"cancel running", models.TaskStatusRunning, func(env *testEnv, id uint64) error {
+			return env.tasks.CancelTask(context.Background(), id)
+		}, true, models.TaskStatusCancelled},
+		{"complete after cancel", models.TaskStatusCancelled, func(env *testEnv, id uint64) error {
+			return env.tasks.CompleteTask(id, "late", models.OutputFormatText, models.TokenUsage{})
+		}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			task := env.createTask(t, tt.from, nil)
+
+			pubsub, err := env.queue.SubscribeTaskStatusEvents(context.Background())
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			defer pubsub.Close()
+
+			before := time.Now()
+			err = tt.change(env, task.ID)
+			if tt.wantEvent && err != nil {
+				t.Fatalf("status change: %v", err)
+			}
+
+			events := receiveTaskEvents(t, pubsub, 200*time.Millisecond)
+			if !tt.wantEvent {
+				if len(events) != 0 {
+					t.Fatalf("events = %+v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want exactly one: %+v", len(events), events)
+			}
+			event := events[0]
+			if event.TaskID != task.ID || event.ModelID != env.modelID || event.OldStatus != tt.from || event.NewStatus != tt.to {
+				t.Fatalf("event = %+v, want %s -> %s for task %d", event, tt.from, tt.to, task.ID)
+			}
+			if event.Timestamp.Before(before.Add(-time.Second)) || event.Timestamp.After(time.Now().Add(time.Second)) {
+				t.Fatalf("event timestamp %v out of range", event.Timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// ExportTask 汇总任务、全部日志、模型快照和耗时，生成用于问题报告的导出包。
+// 外部存储中的输出读取失败时保留输出 URI 并记录在 Warnings 中，不影响导出
+func (s *TaskService) ExportTask(ctx context.Context, id uint64) (*models.TaskExport, error) {
+	var task models.Task
+	if err := s.db.Preload("Model", withDeletedModels).First(&task, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	var logs []models.TaskLog
+	if err := s.db.Where("task_id = ?", id).
+		Order("created_at ASC, id ASC").
+		Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list task logs: %w", err)
+	}
+
+	now := time.Now()
+	export := &models.TaskExport{
+		ExportedAt: now,
+		Task:       &task,
+		Logs:       logs,
+	}
+
+	if err := s.ResolveOutput(ctx, &task); err != nil {
+		export.Warnings = append(export.Warnings, err.Error())
+	}
+
+	// 模型单独放在导出包中，任务中不再重复
+	if task.Model != nil {
+		model := *task.Model
+		model.Config = model.Config.Redacted()
+		export.Model = &model
+		task.Model = nil
+	}
+	task.ProviderOverride = task.ProviderOverride.Redacted()
+
+	timeline := buildTaskTimeline(&task, logs, now)
+	export.Timings = models.TaskTimings{
+		QueueWaitMs: timeline.QueueWaitMs,
+		ExecutionMs: timeline.ExecutionMs,
+		TotalMs:     timeline.TotalMs,
+	}
+
+	return export, nil
+}
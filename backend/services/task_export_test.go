@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestExportTask(t *testing.T) {
+	env := newTestEnv(t, nil)
+	if err := env.db.Model(&models.Model{}).Where("id = ?", env.modelID).
+		Update("config", models.ModelConfig{"api_key": "sk-secret", "model_name": "gpt"}).Error; err != nil {
+		t.Fatalf("update model config: %v", err)
+	}
+	task := env.mustCreate(t, env.createRequest())
+	// 与 Worker 领取任务一致记录开始时间
+	if err := env.db.Model(task).Update("started_at", time.Now()).Error; err != nil {
+		t.Fatalf("set started_at: %v", err)
+	}
+	env.finish(t, task.ID, models.TaskStatusCompleted)
+
+	export, err := env.tasks.ExportTask(context.Background(), task.ID)
+	if err != nil {
+		t.Fatalf("ExportTask: %v", err)
+	}
+
+	// 导出包包含任务本身，模型单独放在 Model 中
+	if export.Task == nil || export.Task.ID != task.ID || export.Task.Status != models.TaskStatusCompleted {
+		t.Fatalf("export task = %+v, want completed task %d", export.Task, task.ID)
+	}
+	if export.Task.Output == nil || *export.Task.Output != "done" {
+		t.Fatalf("export task output = %v, want done", export.Task.Output)
+	}
+	if export.Task.Model != nil {
+		t.Fatalf("model repeated inside task")
+	}
+	if export.Model == nil || export.Model.ID != env.modelID || export.Model.Name != "test-model" {
+		t.Fatalf("export model = %+v, want test-model", export.Model)
+	}
+	// 模型快照中的密钥已屏蔽，其他配置保留
+	if export.Model.Config["api_key"] != "***" || export.Model.Config["model_name"] != "gpt" {
+		t.Fatalf("export model config = %v, want api_key redacted", export.Model.Config)
+	}
+
+	// 导出全部日志，按时间升序
+	_, total, err := env.tasks.ListTaskLogs(task.ID, &models.TaskLogListRequest{Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if total == 0 || int64(len(export.Logs)) != total {
+		t.Fatalf("export has %d logs, want %d", len(export.Logs), total)
+	}
+	for i := 1; i < len(export.Logs); i++ {
+		prev, cur := export.Logs[i-1], export.Logs[i]
+		if cur.CreatedAt.Before(prev.CreatedAt) || (cur.CreatedAt.Equal(prev.CreatedAt) && cur.ID < prev.ID) {
+			t.Fatalf("logs not in ascending order: %d before %d", prev.ID, cur.ID)
+		}
+	}
+
+	if export.Timings.ExecutionMs == nil || export.Timings.TotalMs < 0 {
+		t.Fatalf("timings = %+v, want execution time for a finished task", export.Timings)
+	}
+	if len(export.Warnings) != 0 {
+		t.Fatalf("warnings = %v, want none", export.Warnings)
+	}
+}
+
+func TestExportTaskNotFound(t *testing.T) {
+	env := newTestEnv(t, nil)
+	if _, err := env.tasks.ExportTask(context.Background(), 999); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("ExportTask error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestExportTaskWarnsWhenOutputUnavailable(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.withOutputStorage(failingStorage{}, 1)
+	uri := "local://outputs/1"
+	task := env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) {
+		task.OutputURI = &uri
+	})
+
+	// 外部存储中的输出读取失败时仍然导出，保留输出 URI 并给出警告
+	export, err := env.tasks.ExportTask(context.Background(), task.ID)
+	if err != nil {
+		t.Fatalf("ExportTask: %v", err)
+	}
+	if len(export.Warnings) != 1 {
+		t.Fatalf("warnings = %v, want one", export.Warnings)
+	}
+	if export.Task.OutputURI == nil || *export.Task.OutputURI != uri {
+		t.Fatalf("output uri = %v, want %s", export.Task.OutputURI, uri)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskRejectsOversizedInput(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxInputBytes = 8 })
+
+	req := env.createRequest()
+	req.Input = "你好世界"
+	_, err := env.tasks.CreateTask(context.Background(), req)
+	if names := fieldNames(t, err); len(names) != 1 || names[0] != "input" {
+		t.Fatalf("fields = %v, want input", names)
+	}
+
+	// 恰好等于上限的输入允许创建
+	req = env.createRequest()
+	req.Input = "12345678"
+	env.mustCreate(t, req)
+}
+
+func TestCompleteTaskTruncatesOutput(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.MaxOutputBytes = 10 })
+
+	tests := []struct {
+		name      string
+		output    string
+		want      string
+		truncated bool
+	}{
+		{"within limit", "short", "short", false},
+		{"ascii", "hello world!", "hello worl", true},
+		// 截断不会切断多字节字符
+		{"multibyte", "输出内容很长", "输出内", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := env.createTask(t, models.TaskStatusRunning, nil)
+			if err := env.tasks.CompleteTask(task.ID, tt.output, models.OutputFormatText, models.TokenUsage{}); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+
+			got := env.reloadTask(t, task.ID)
+			if got.Output == nil || *got.Output != tt.want || got.OutputTruncated != tt.truncated {
+				t.Fatalf("output = %v (truncated %v), want %q (truncated %v)", got.Output, got.OutputTruncated, tt.want, tt.truncated)
+			}
+
+			var logs []models.TaskLog
+			env.db.Where("task_id = ? AND message = ?", task.ID, "Task output truncated").Find(&logs)
+			if !tt.truncated {
+				if len(logs) != 0 {
+					t.Fatalf("unexpected truncation log: %+v", logs)
+				}
+				return
+			}
+			if len(logs) != 1 {
+				t.Fatalf("expected one truncation log, got %d", len(logs))
+			}
+			data := logs[0].Data
+			if data["original_bytes"] != float64(len(tt.output)) || data["stored_bytes"] != float64(len(tt.want)) {
+				t.Fatalf("log data = %v, want original %d and stored %d bytes", data, len(tt.output), len(tt.want))
+			}
+		})
+	}
+}
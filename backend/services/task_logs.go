@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// recentTaskLogLimit 任务详情中返回的最近日志条数，完整日志通过 ListTaskLogs 分页获取
+const recentTaskLogLimit = 50
+
+// ListTaskLogs 分页获取任务日志，按创建时间升序排列，可按级别过滤
+func (s *TaskService) ListTaskLogs(taskID uint64, req *models.TaskLogListRequest) ([]models.TaskLog, int64, error) {
+	if req.Level != nil && !req.Level.IsValid() {
+		return nil, 0, &ValidationError{Fields: []models.FieldError{
+			{Field: "level", Message: "level must be one of debug, info, warn, error"},
+		}}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Task{}).Where("id = ?", taskID).Count(&count).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query task: %w", err)
+	}
+	if count == 0 {
+		return nil, 0, ErrTaskNotFound
+	}
+
+	query := s.db.Model(&models.TaskLog{}).Where("task_id = ?", taskID)
+	if req.Level != nil {
+		query = query.Where("level = ?", *req.Level)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count task logs: %w", err)
+	}
+
+	logs := []models.TaskLog{}
+	offset := (req.Page - 1) * req.PageSize
+	if int64(offset) >= total {
+		return logs, total, nil
+	}
+
+	err := query.Order("created_at ASC, id ASC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list task logs: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+// recentTaskLogs 预加载最近的 recentTaskLogLimit 条日志，仅用于单个任务的查询
+func recentTaskLogs(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at DESC, id DESC").Limit(recentTaskLogLimit)
+}
+
+// reverseTaskLogs 将按时间倒序取出的日志恢复为升序
+func reverseTaskLogs(logs []models.TaskLog) {
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+}
+
+// TailTaskLogs 获取任务中 ID 大于 afterID 的日志（按 ID 升序，最多 limit 条）和任务当前状态，用于实时日志流。
+// 状态在日志之前读取，任务结束时写入的最后几条日志可能要到下一次调用才能读到
+func (s *TaskService) TailTaskLogs(taskID, afterID uint64, limit int) ([]models.TaskLog, models.TaskStatus, error) {
+	var task models.Task
+	if err := s.db.Select("id", "status").First(&task, taskID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrTaskNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get task: %w", err)
+	}
+
+	logs := []models.TaskLog{}
+	if err := s.db.Where("task_id = ? AND id > ?", taskID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to tail task logs: %w", err)
+	}
+	return logs, task.Status, nil
+}
+
+// TaskLogTailStart 返回从任务最近 n 条日志开始输出时使用的 afterID，日志不足 n 条时为 0
+func (s *TaskService) TaskLogTailStart(taskID uint64, n int) (uint64, error) {
+	var ids []uint64
+	if err := s.db.Model(&models.TaskLog{}).
+		Where("task_id = ?", taskID).
+		Order("id DESC").
+		Offset(n).
+		Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to query task logs: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// AddTaskLog 为任务追加一条日志，供 Worker 记录执行过程中的细节（如模型调用）
+func (s *TaskService) AddTaskLog(taskID uint64, level models.LogLevel, message string, data models.LogData) {
+	s.addTaskLog(taskID, level, message, data)
+}
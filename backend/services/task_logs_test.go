@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// insertLogs 为任务写入 n 条日志，每条间隔一秒，第 i 条（从 0 开始）每三条中有一条为 warn
+func (env *testEnv) insertLogs(t *testing.T, taskID uint64, n int) {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		level := models.LogLevelInfo
+		if i%3 == 0 {
+			level = models.LogLevelWarn
+		}
+		log := &models.TaskLog{TaskID: taskID, Level: level, Message: fmt.Sprintf("log %d", i), CreatedAt: base.Add(time.Duration(i) * time.Second)}
+		if err := env.db.Create(log).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+}
+
+func TestListTaskLogsPagination(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusCompleted, nil)
+	env.insertLogs(t, task.ID, 10)
+	warn := models.LogLevelWarn
+
+	tests := []struct {
+		name      string
+		req       models.TaskLogListRequest
+		wantTotal int64
+		want      []string
+	}{
+		{"first page", models.TaskLogListRequest{Page: 1, PageSize: 4}, 10, []string{"log 0", "log 1", "log 2", "log 3"}},
+		{"last partial page", models.TaskLogListRequest{Page: 3, PageSize: 4}, 10, []string{"log 8", "log 9"}},
+		{"page past the end", models.TaskLogListRequest{Page: 4, PageSize: 4}, 10, nil},
+		{"exact last page", models.TaskLogListRequest{Page: 2, PageSize: 5}, 10, []string{"log 5", "log 6", "log 7", "log 8", "log 9"}},
+		{"level filter", models.TaskLogListRequest{Level: &warn, Page: 1, PageSize: 10}, 4, []string{"log 0", "log 3", "log 6", "log 9"}},
+		{"level filter second page", models.TaskLogListRequest{Level: &warn, Page: 2, PageSize: 3}, 4, []string{"log 9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, total, err := env.tasks.ListTaskLogs(task.ID, &tt.req)
+			if err != nil {
+				t.Fatalf("ListTaskLogs: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Fatalf("total = %d, want %d", total, tt.wantTotal)
+			}
+			var got []string
+			for _, log := range logs {
+				got = append(got, log.Message)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("logs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	invalid := models.LogLevel("trace")
+	_, _, err := env.tasks.ListTaskLogs(task.ID, &models.TaskLogListRequest{Level: &invalid, Page: 1, PageSize: 10})
+	if names := fieldNames(t, err); len(names) != 1 || names[0] != "level" {
+		t.Fatalf("fields = %v, want level", names)
+	}
+	if _, _, err := env.tasks.ListTaskLogs(task.ID+1, &models.TaskLogListRequest{Page: 1, PageSize: 10}); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestGetTaskReturnsRecentLogs(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusCompleted, nil)
+	env.insertLogs(t, task.ID, recentTaskLogLimit+5)
+
+	got, err := env.tasks.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if len(got.Logs) != recentTaskLogLimit {
+		t.Fatalf("got %d logs, want %d", len(got.Logs), recentTaskLogLimit)
+	}
+	// 只返回最近的日志，并按时间升序排列
+	if got.Logs[0].Message != "log 5" || got.Logs[len(got.Logs)-1].Message != fmt.Sprintf("log %d", recentTaskLogLimit+4) {
+		t.Fatalf("logs span %q..%q, want the most recent in ascending order", got.Logs[0].Message, got.Logs[len(got.Logs)-1].Message)
+	}
+}
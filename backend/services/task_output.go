@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// outputObjectKey 任务输出在外部存储中的对象键
+func outputObjectKey(id uint64) string {
+	return fmt.Sprintf("tasks/%d/output.txt", id)
+}
+
+// offloadOutput 输出超过 storage.output_threshold 时写入外部存储并返回引用 URI，
+// 未启用或写入失败时返回空字符串，输出仍保存在数据库中
+func (s *TaskService) offloadOutput(id uint64, output string) string {
+	if s.outputStorage == nil || len(output) <= s.config.Storage.OutputThreshold {
+		return ""
+	}
+
+	uri, err := s.outputStorage.Put(context.Background(), outputObjectKey(id), []byte(output))
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to store task output externally, keeping it in database")
+		return ""
+	}
+	s.logger.WithFields(logrus.Fields{
+		"task_id": id,
+		"bytes":   len(output),
+		"uri":     uri,
+	}).Debug("Task output stored externally")
+	return uri
+}
+
+// ResolveOutput 输出保存在外部存储中时读取内容填充 task.Output
+func (s *TaskService) ResolveOutput(ctx context.Context, task *models.Task) error {
+	if task.OutputURI == nil || task.Output != nil {
+		return nil
+	}
+	if s.outputStorage == nil {
+		return fmt.Errorf("%w: output storage is not configured", ErrOutputUnavailable)
+	}
+
+	data, err := s.outputStorage.Get(ctx, *task.OutputURI)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOutputUnavailable, err)
+	}
+	output := string(data)
+	task.Output = &output
+	return nil
+}
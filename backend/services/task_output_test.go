@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+	"llm-scheduler/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// failingStorage 写入和读取都失败的输出存储
+type failingStorage struct{}
+
+func (failingStorage) Put(ctx context.Context, key string, data []byte) (string, error) {
+	return "", errors.New("storage unavailable")
+}
+
+func (failingStorage) Get(ctx context.Context, uri string) ([]byte, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+// withOutputStorage 使用 store 作为输出存储重新创建任务服务，输出超过 threshold 字节时写入存储
+func (env *testEnv) withOutputStorage(store storage.OutputStorage, threshold int) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	env.cfg.Storage.OutputThreshold = threshold
+	env.tasks = NewTaskService(env.db, env.queue, store, env.cfg, logger)
+}
+
+func TestCompleteTaskOffloadsLargeOutput(t *testing.T) {
+	large := strings.Repeat("x", 64)
+	tests := []struct {
+		name       string
+		output     string
+		failing    bool
+		wantStored bool
+	}{
+		{"large output stored externally", large, false, true},
+		// 未超过阈值的输出仍保存在数据库中
+		{"small output kept in database", "short", false, false},
+		// 写入外部存储失败时输出保存在数据库中，任务仍然完成
+		{"storage failure falls back to database", large, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			var store storage.OutputStorage = failingStorage{}
+			if !tt.failing {
+				local, err := storage.NewLocalStorage(filepath.Join(t.TempDir(), "outputs"))
+				if err != nil {
+					t.Fatalf("NewLocalStorage: %v", err)
+				}
+				store = local
+			}
+			env.withOutputStorage(store, 32)
+			task := env.createTask(t, models.TaskStatusRunning, nil)
+
+			if err := env.tasks.CompleteTask(task.ID, tt.output, models.OutputFormatText, models.TokenUsage{}); err != nil {
+				t.Fatalf("CompleteTask: %v", err)
+			}
+
+			got := env.reloadTask(t, task.ID)
+			if got.Status != models.TaskStatusCompleted {
+				t.Fatalf("status = %s, want completed", got.Status)
+			}
+			if !tt.wantStored {
+				if got.OutputURI != nil || got.Output == nil || *got.Output != tt.output {
+					t.Fatalf("output = %v uri = %v, want output in database", got.Output, got.OutputURI)
+				}
+				return
+			}
+			if got.Output != nil || got.OutputURI == nil || *got.OutputURI != "local://"+outputObjectKey(task.ID) {
+				t.Fatalf("output = %v uri = %v, want only a local:// reference", got.Output, got.OutputURI)
+			}
+
+			// 读取任务时从外部存储取回完整输出
+			if err := env.tasks.ResolveOutput(context.Background(), got); err != nil {
+				t.Fatalf("ResolveOutput: %v", err)
+			}
+			if got.Output == nil || *got.Output != tt.output {
+				t.Fatalf("resolved output = %v, want stored output", got.Output)
+			}
+		})
+	}
+}
+
+func TestResolveOutputUnavailable(t *testing.T) {
+	uri := "local://tasks/1/output.txt"
+	tests := []struct {
+		name  string
+		store storage.OutputStorage
+	}{
+		// 关闭外部存储后无法读取之前写入的输出
+		{"storage not configured", nil},
+		{"storage read fails", failingStorage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			env.withOutputStorage(tt.store, 32)
+			task := &models.Task{ID: 1, Status: models.TaskStatusCompleted, OutputURI: &uri}
+			if err := env.tasks.ResolveOutput(context.Background(), task); !errors.Is(err, ErrOutputUnavailable) {
+				t.Fatalf("ResolveOutput error = %v, want ErrOutputUnavailable", err)
+			}
+			if task.Output != nil {
+				t.Fatalf("output filled despite error")
+			}
+		})
+	}
+
+	// 输出在数据库中时不读取外部存储
+	env := newTestEnv(t, nil)
+	env.withOutputStorage(failingStorage{}, 32)
+	output := "inline"
+	task := &models.Task{ID: 1, Output: &output}
+	if err := env.tasks.ResolveOutput(context.Background(), task); err != nil || *task.Output != "inline" {
+		t.Fatalf("ResolveOutput = %v, output %q, want inline output untouched", err, *task.Output)
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"fmt"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+// RecordPipelineStep 在任务日志中记录流水线步骤的中间输出，输出超过 max_output_bytes 时截断保存
+func (s *TaskService) RecordPipelineStep(taskID uint64, index int, step models.PipelineStep, modelID uint64, output string) {
+	stored, truncated := utils.TruncateBytes(output, s.getMaxOutputBytes())
+	s.addTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Pipeline step %d completed", index+1), models.LogData{
+		"step":             index + 1,
+		"type":             step.Type,
+		"model_id":         modelID,
+		"output":           stored,
+		"output_truncated": truncated,
+	})
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPurgeReason 清空队列时未指定原因使用的错误信息
+const defaultPurgeReason = "purged from queue by operator"
+
+// PurgeQueue 清空指定优先级队列，并将其中仍为 pending 的任务标记为取消
+func (s *TaskService) PurgeQueue(ctx context.Context, priority models.TaskPriority, reason string) (*models.QueuePurgeResult, error) {
+	if reason == "" {
+		reason = defaultPurgeReason
+	}
+
+	items, err := s.queueManager.PurgeQueue(ctx, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.QueuePurgeResult{Priority: priority, Removed: len(items)}
+	for _, item := range items {
+		cancelled, err := s.cancelPendingTask(ctx, item.TaskID, reason, "Task cancelled, queue purged")
+		if err != nil {
+			s.logger.WithError(err).WithField("task_id", item.TaskID).Error("Failed to cancel purged task")
+			continue
+		}
+		if cancelled {
+			result.Cancelled++
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"priority":  priority,
+		"removed":   result.Removed,
+		"cancelled": result.Cancelled,
+		"reason":    reason,
+	}).Warn("Queue purged, tasks cancelled")
+
+	return result, nil
+}
+
+// defaultMaintenanceReason 模型进入维护时取消等待任务使用的错误信息
+const defaultMaintenanceReason = "model taken out of service"
+
+// CancelModelPendingTasks 模型停止服务时取消其所有 pending 任务：先从优先级队列和延迟队列中移除，
+// 再将数据库中仍为 pending 的任务（包括等待依赖的任务）标记为取消，返回取消的任务数
+func (s *TaskService) CancelModelPendingTasks(ctx context.Context, modelID uint64, reason string) (int, error) {
+	if reason == "" {
+		reason = defaultMaintenanceReason
+	}
+
+	items, err := s.queueManager.RemoveModelTasks(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []uint64
+	if err := s.db.Model(&models.Task{}).
+		Where("model_id = ? AND status = ?", modelID, models.TaskStatusPending).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to query pending tasks: %w", err)
+	}
+
+	cancelled := 0
+	for _, id := range ids {
+		ok, err := s.cancelPendingTask(ctx, id, reason, "Task cancelled, model taken out of service")
+		if err != nil {
+			s.logger.WithError(err).WithField("task_id", id).Error("Failed to cancel pending task of model")
+			continue
+		}
+		if ok {
+			cancelled++
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"model_id":  modelID,
+		"removed":   len(items),
+		"cancelled": cancelled,
+		"reason":    reason,
+	}).Warn("Pending tasks of model cancelled")
+
+	return cancelled, nil
+}
+
+// cancelPendingTask 将已移出队列的任务标记为取消并记录 message 日志，已不是 pending 的任务保持不变
+func (s *TaskService) cancelPendingTask(ctx context.Context, id uint64, reason, message string) (bool, error) {
+	update := s.db.Model(&models.Task{}).
+		Where("id = ? AND status = ?", id, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":               models.TaskStatusCancelled,
+			"error_message":        reason,
+			"completed_at":         time.Now(),
+			"waiting_dependencies": false,
+		})
+	if update.Error != nil {
+		return false, fmt.Errorf("failed to cancel task: %w", update.Error)
+	}
+	if update.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if task, err := s.loadTaskState(id); err == nil {
+		s.publishEvent(task, models.TaskStatusPending)
+	}
+	s.addTaskLog(id, models.LogLevelWarn, message, models.LogData{
+		"reason": reason,
+	})
+	s.queueManager.OnTaskCompleted(ctx, id, models.TaskStatusCancelled)
+	return true, nil
+}
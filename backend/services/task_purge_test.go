@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestPurgeQueueCancelsQueuedTasks(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		wantReason string
+	}{
+		{"custom reason", "bad prompts", "bad prompts"},
+		{"default reason", "", defaultPurgeReason},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+
+			// 高优先级任务已出队执行，清空中优先级队列不影响处理中队列和其他优先级
+			running := env.createRequest()
+			running.Priority = models.TaskPriorityHigh
+			runningTask := env.mustCreate(t, running)
+			if _, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil {
+				t.Fatalf("dequeue: %v", err)
+			}
+			setStatus(t, env.db, runningTask.ID, models.TaskStatusRunning)
+			low := env.createRequest()
+			low.Priority = models.TaskPriorityLow
+			lowTask := env.mustCreate(t, low)
+
+			var queued []*models.Task
+			for i := 0; i < 3; i++ {
+				queued = append(queued, env.mustCreate(t, env.createRequest()))
+			}
+			// 队列项仍在但任务已被取消，只移除不重复取消
+			setStatus(t, env.db, queued[2].ID, models.TaskStatusCancelled)
+
+			result, err := env.tasks.PurgeQueue(ctx, models.TaskPriorityMedium, tt.reason)
+			if err != nil {
+				t.Fatalf("PurgeQueue: %v", err)
+			}
+			if result.Priority != models.TaskPriorityMedium || result.Removed != 3 || result.Cancelled != 2 {
+				t.Fatalf("result = %+v, want 3 removed and 2 cancelled", result)
+			}
+			if n := env.zcard(t, env.cfg.Queue.MediumPriorityQueue); n != 0 {
+				t.Fatalf("medium queue has %d items after purge", n)
+			}
+
+			for _, task := range queued[:2] {
+				got := env.reloadTask(t, task.ID)
+				if got.Status != models.TaskStatusCancelled || got.ErrorMessage == nil || *got.ErrorMessage != tt.wantReason || got.CompletedAt == nil {
+					t.Fatalf("task %d = %s %v, want cancelled with %q", task.ID, got.Status, got.ErrorMessage, tt.wantReason)
+				}
+				var logs int64
+				env.db.Model(&models.TaskLog{}).Where("task_id = ? AND message = ?", task.ID, "Task cancelled, queue purged").Count(&logs)
+				if logs != 1 {
+					t.Fatalf("task %d has %d purge logs, want 1", task.ID, logs)
+				}
+			}
+			if got := env.reloadTask(t, queued[2].ID); got.ErrorMessage != nil {
+				t.Fatalf("already cancelled task was updated: %v", *got.ErrorMessage)
+			}
+
+			if got := env.reloadTask(t, runningTask.ID); got.Status != models.TaskStatusRunning {
+				t.Fatalf("running task status = %s", got.Status)
+			}
+			if n := env.zcard(t, env.cfg.Queue.ProcessingQueue); n != 1 {
+				t.Fatalf("processing queue has %d items, want 1", n)
+			}
+			if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != lowTask.ID {
+				t.Fatalf("queued = %v, want only the low priority task", ids)
+			}
+		})
+	}
+}
+
+func TestPurgeQueueEmptyAndInvalidPriority(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	result, err := env.tasks.PurgeQueue(ctx, models.TaskPriorityHigh, "")
+	if err != nil {
+		t.Fatalf("PurgeQueue: %v", err)
+	}
+	if result.Removed != 0 || result.Cancelled != 0 {
+		t.Fatalf("result = %+v, want nothing removed", result)
+	}
+	if _, err := env.tasks.PurgeQueue(ctx, models.TaskPriority(9), ""); err == nil {
+		t.Fatal("expected error for invalid priority")
+	}
+}
+
+func TestCancelModelPendingTasks(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		wantReason string
+	}{
+		{"custom reason", "gpu upgrade", "gpu upgrade"},
+		{"default reason", "", defaultMaintenanceReason},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			other := &models.Model{Name: "other-model", Type: models.ModelTypeCustom, Config: models.ModelConfig{}, Status: models.ModelStatusOnline}
+			if err := env.db.Create(other).Error; err != nil {
+				t.Fatalf("create model: %v", err)
+			}
+
+			// 一个任务已在执行，其余任务分别在就绪队列、延迟队列中或等待依赖
+			running := env.mustCreate(t, env.createRequest())
+			if _, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil {
+				t.Fatalf("dequeue: %v", err)
+			}
+			setStatus(t, env.db, running.ID, models.TaskStatusRunning)
+			low := env.createRequest()
+			low.Priority = models.TaskPriorityLow
+			scheduled := env.createRequest()
+			runAt := time.Now().Add(time.Hour)
+			scheduled.RunAt = &runAt
+			pending := []*models.Task{
+				env.mustCreate(t, env.createRequest()),
+				env.mustCreate(t, low),
+				env.mustCreate(t, scheduled),
+				env.mustCreate(t, env.createRequest(running.ID)),
+			}
+			// 其他模型的任务不受影响
+			otherReq := env.createRequest()
+			otherReq.ModelID = other.ID
+			otherTask := env.mustCreate(t, otherReq)
+
+			cancelled, err := env.tasks.CancelModelPendingTasks(ctx, env.modelID, tt.reason)
+			if err != nil {
+				t.Fatalf("CancelModelPendingTasks: %v", err)
+			}
+			if cancelled != len(pending) {
+				t.Fatalf("cancelled = %d, want %d", cancelled, len(pending))
+			}
+			for _, task := range pending {
+				got := env.reloadTask(t, task.ID)
+				if got.Status != models.TaskStatusCancelled || got.ErrorMessage == nil || *got.ErrorMessage != tt.wantReason || got.WaitingDeps {
+					t.Fatalf("task %d = %s %v, want cancelled with %q", task.ID, got.Status, got.ErrorMessage, tt.wantReason)
+				}
+			}
+			if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != otherTask.ID {
+				t.Fatalf("queued = %v, want only task %d of the other model", ids, otherTask.ID)
+			}
+			if n := env.zcard(t, env.cfg.Queue.DelayedQueue); n != 0 {
+				t.Fatalf("delayed queue has %d items, want 0", n)
+			}
+			if got := env.reloadTask(t, running.ID); got.Status != models.TaskStatusRunning {
+				t.Fatalf("running task status = %s", got.Status)
+			}
+			if got := env.reloadTask(t, otherTask.ID); got.Status != models.TaskStatusPending {
+				t.Fatalf("other model task status = %s", got.Status)
+			}
+		})
+	}
+}
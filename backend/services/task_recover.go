@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RecoverStuckTasks 恢复崩溃后遗留的任务：数据库中 running 超过 olderThan、且不在任何处理中队列里的任务
+// 没有 Worker 会再处理，将其重置为 pending 并重新入队。olderThan <= 0 时使用 queue.task_timeout。
+// 处理中队列里的任务由 CleanupStuckTasks 负责，这里不处理
+func (s *TaskService) RecoverStuckTasks(ctx context.Context, olderThan time.Duration) (*models.RecoverResult, error) {
+	if olderThan <= 0 {
+		olderThan = s.config.Queue.TaskTimeout
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	var candidates []models.Task
+	if err := s.db.Where("status = ? AND started_at < ?", models.TaskStatusRunning, cutoff).
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to query running tasks: %w", err)
+	}
+
+	result := &models.RecoverResult{Scanned: len(candidates), TaskIDs: []uint64{}}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	processing, err := s.queueManager.ProcessingTaskIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		task := &candidates[i]
+		if processing[task.ID] {
+			continue
+		}
+
+		// 条件更新，避免与刚好完成或被其他请求恢复的任务冲突
+		update := s.db.Model(&models.Task{}).
+			Where("id = ? AND status = ? AND started_at < ?", task.ID, models.TaskStatusRunning, cutoff).
+			Updates(map[string]interface{}{"status": models.TaskStatusPending, "started_at": nil})
+		if update.Error != nil {
+			return nil, fmt.Errorf("failed to reset task %d: %w", task.ID, update.Error)
+		}
+		if update.RowsAffected == 0 {
+			continue
+		}
+		s.setTaskStatus(task, models.TaskStatusPending)
+
+		// 恢复的是已经接收的任务，不受队列长度上限限制
+		item := &queue.QueueItem{
+			TaskID:    task.ID,
+			ModelID:   task.ModelID,
+			Priority:  int(task.Priority),
+			CreatedAt: task.CreatedAt,
+			TraceID:   task.TraceID,
+			Deadline:  task.Deadline,
+			APIKey:    task.APIKeyName,
+			Type:      task.Type,
+		}
+		if err := s.queueManager.RequeueTask(ctx, item, 0); err != nil {
+			return nil, fmt.Errorf("failed to requeue task %d: %w", task.ID, err)
+		}
+
+		s.addTaskLog(task.ID, models.LogLevelWarn, "Task recovered from stale running state, re-enqueued", models.LogData{
+			"started_at": task.StartedAt,
+		})
+		result.TaskIDs = append(result.TaskIDs, task.ID)
+	}
+	result.Recovered = len(result.TaskIDs)
+
+	s.logger.WithFields(logrus.Fields{
+		"scanned":    result.Scanned,
+		"recovered":  result.Recovered,
+		"older_than": olderThan.String(),
+	}).Info("Stale running tasks recovered")
+
+	return result, nil
+}
+
+// InactiveTaskIDs 返回 taskIDs 中已结束或在数据库中不存在的任务 ID，用于清理处理中队列里的残留条目
+func (s *TaskService) InactiveTaskIDs(taskIDs []uint64) ([]uint64, error) {
+	var tasks []models.Task
+	if err := s.db.Select("id", "status").Where("id IN ?", taskIDs).Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to query task status: %w", err)
+	}
+
+	active := make(map[uint64]bool, len(tasks))
+	for _, task := range tasks {
+		if !task.Status.IsTerminal() {
+			active[task.ID] = true
+		}
+	}
+
+	var inactive []uint64
+	for _, id := range taskIDs {
+		if !active[id] {
+			inactive = append(inactive, id)
+		}
+	}
+	return inactive, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// createRunningTask 创建 started 之前开始运行的任务
+func (env *testEnv) createRunningTask(t *testing.T, started time.Duration) *models.Task {
+	t.Helper()
+	return env.createTask(t, models.TaskStatusRunning, func(task *models.Task) {
+		startedAt := time.Now().Add(-started)
+		task.StartedAt = &startedAt
+	})
+}
+
+func TestRecoverStuckTasks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	stale := env.createRunningTask(t, time.Hour)
+	fresh := env.createRunningTask(t, time.Minute)
+	// 仍在处理中队列里的任务由 Worker 或 CleanupStuckTasks 负责
+	claimed := env.createRunningTask(t, time.Hour)
+	if err := env.queue.EnqueueTask(ctx, claimed); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if item, err := env.queue.DequeueTask(ctx, env.modelID, nil); err != nil || item == nil || item.TaskID != claimed.ID {
+		t.Fatalf("dequeued %+v (err %v), want task %d", item, err, claimed.ID)
+	}
+
+	result, err := env.tasks.RecoverStuckTasks(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("RecoverStuckTasks: %v", err)
+	}
+	if result.Scanned != 2 || result.Recovered != 1 || len(result.TaskIDs) != 1 || result.TaskIDs[0] != stale.ID {
+		t.Fatalf("result = %+v, want task %d recovered out of 2 scanned", result, stale.ID)
+	}
+
+	got := env.reloadTask(t, stale.ID)
+	if got.Status != models.TaskStatusPending || got.StartedAt != nil {
+		t.Fatalf("stale task: status %s, started_at %v, want pending without started_at", got.Status, got.StartedAt)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != stale.ID {
+		t.Fatalf("queued tasks = %v, want [%d]", ids, stale.ID)
+	}
+	for _, task := range []*models.Task{fresh, claimed} {
+		if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusRunning {
+			t.Fatalf("task %d: status %s, want running", task.ID, got.Status)
+		}
+	}
+
+	// 再次恢复时不会重复入队
+	result, err = env.tasks.RecoverStuckTasks(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("RecoverStuckTasks: %v", err)
+	}
+	if result.Recovered != 0 {
+		t.Fatalf("second run recovered %v, want none", result.TaskIDs)
+	}
+}
+
+func TestRecoverStuckTasksDefaultsToTaskTimeout(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	// 未指定阈值时使用 queue.task_timeout（5 分钟）
+	stale := env.createRunningTask(t, 10*time.Minute)
+	env.createRunningTask(t, time.Minute)
+
+	result, err := env.tasks.RecoverStuckTasks(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("RecoverStuckTasks: %v", err)
+	}
+	if result.Recovered != 1 || result.TaskIDs[0] != stale.ID {
+		t.Fatalf("result = %+v, want only task %d recovered", result, stale.ID)
+	}
+}
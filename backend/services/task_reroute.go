@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rerouteBatchSize 每次改派的最大任务数
+const rerouteBatchSize = 500
+
+// maxFallbackDepth 沿备用模型链查找的最大层数，防止配置成环
+const maxFallbackDepth = 5
+
+// ResolveFallbackModel 沿备用模型链查找第一个在线且类型相同的模型，找不到时返回错误
+func (s *TaskService) ResolveFallbackModel(model *models.Model) (*models.Model, error) {
+	visited := map[uint64]bool{model.ID: true}
+	current := model
+
+	for depth := 0; depth < maxFallbackDepth; depth++ {
+		if current.FallbackModelID == nil || visited[*current.FallbackModelID] {
+			break
+		}
+
+		var fallback models.Model
+		if err := s.db.First(&fallback, *current.FallbackModelID).Error; err != nil {
+			break
+		}
+		visited[fallback.ID] = true
+
+		if fallback.Type == model.Type && fallback.IsOnline() {
+			return &fallback, nil
+		}
+		current = &fallback
+	}
+
+	return nil, fmt.Errorf("no fallback model available for model %d", model.ID)
+}
+
+// RerouteTasks 将离线或维护中模型的等待任务改派到备用模型，返回改派的任务数。
+// 只处理已在队列中的任务，等待依赖的任务入队后在下一轮改派
+func (s *TaskService) RerouteTasks(ctx context.Context, model *models.Model) (int, error) {
+	if model.IsOnline() {
+		return 0, nil
+	}
+
+	target, err := s.ResolveFallbackModel(model)
+	if err != nil {
+		return 0, err
+	}
+
+	var tasks []models.Task
+	if err := s.db.Where("model_id = ? AND status = ? AND waiting_dependencies = ?",
+		model.ID, models.TaskStatusPending, false).
+		Order("id").
+		Limit(rerouteBatchSize).
+		Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("failed to query tasks to reroute: %w", err)
+	}
+
+	s.queueManager.SetModelBackend(target.ID, target.GetQueueBackend())
+
+	rerouted := 0
+	for i := range tasks {
+		if s.rerouteTask(ctx, &tasks[i], model, target) {
+			rerouted++
+		}
+	}
+
+	if rerouted > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"from_model_id": model.ID,
+			"to_model_id":   target.ID,
+			"count":         rerouted,
+		}).Warn("Tasks rerouted to fallback model")
+	}
+	return rerouted, nil
+}
+
+// rerouteTask 改派单个任务：先从原队列移除，再更新模型并加入备用模型的队列。
+// 不在队列中的任务（执行中、延迟重试中或已被其他实例改派）保持不变
+func (s *TaskService) rerouteTask(ctx context.Context, task *models.Task, from, to *models.Model) bool {
+	logger := s.logger.WithField("task_id", task.ID)
+
+	removed, err := s.queueManager.RemoveQueuedTask(ctx, from.ID, task.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to remove task from queue for reroute")
+		return false
+	}
+	if !removed {
+		return false
+	}
+
+	result := s.db.Model(&models.Task{}).
+		Where("id = ? AND model_id = ? AND status = ?", task.ID, from.ID, models.TaskStatusPending).
+		Update("model_id", to.ID)
+	if result.Error != nil || result.RowsAffected == 0 {
+		// 任务已被取消或状态已变化，不再需要放回队列
+		if result.Error != nil {
+			logger.WithError(result.Error).Error("Failed to update rerouted task")
+		}
+		return false
+	}
+
+	task.ModelID = to.ID
+	if err := s.queueManager.EnqueueTask(ctx, task); err != nil {
+		logger.WithError(err).Error("Failed to enqueue rerouted task")
+		s.FailTask(task.ID, "Failed to enqueue task after reroute")
+		return false
+	}
+
+	s.addTaskLog(task.ID, models.LogLevelWarn, "Task rerouted to fallback model", models.LogData{
+		"from_model_id": from.ID,
+		"from_status":   from.Status,
+		"to_model_id":   to.ID,
+	})
+	return true
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// createModel 创建指定类型和状态的模型，fallback 不为 0 时设置备用模型
+func (env *testEnv) createModel(t *testing.T, name string, modelType models.ModelType, status models.ModelStatus, fallback uint64) *models.Model {
+	t.Helper()
+	model := &models.Model{Name: name, Type: modelType, Config: models.ModelConfig{}, Status: status, MaxWorkers: 1}
+	if fallback != 0 {
+		model.FallbackModelID = &fallback
+	}
+	if err := env.db.Create(model).Error; err != nil {
+		t.Fatalf("create model %s: %v", name, err)
+	}
+	return model
+}
+
+// newModelService 创建使用测试环境数据库的模型服务
+func newModelService(env *testEnv) *ModelService {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewModelService(env.db, logger)
+}
+
+func TestRerouteTasksToFallbackModel(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 创建备用模型链，返回离线的主模型和期望改派到的模型（nil 表示无可用备用模型）
+		setup func(t *testing.T, env *testEnv) (primary, want *models.Model)
+	}{
+		{"direct fallback", func(t *testing.T, env *testEnv) (*models.Model, *models.Model) {
+			fallback := env.createModel(t, "fallback", models.ModelTypeCustom, models.ModelStatusOnline, 0)
+			return env.createModel(t, "primary", models.ModelTypeCustom, models.ModelStatusOffline, fallback.ID), fallback
+		}},
+		{"skips offline fallback in chain", func(t *testing.T, env *testEnv) (*models.Model, *models.Model) {
+			last := env.createModel(t, "last", models.ModelTypeCustom, models.ModelStatusOnline, 0)
+			middle := env.createModel(t, "middle", models.ModelTypeCustom, models.ModelStatusMaintenance, last.ID)
+			return env.createModel(t, "primary", models.ModelTypeCustom, models.ModelStatusOffline, middle.ID), last
+		}},
+		{"no fallback", func(t *testing.T, env *testEnv) (*models.Model, *models.Model) {
+			return env.createModel(t, "primary", models.ModelTypeCustom, models.ModelStatusOffline, 0), nil
+		}},
+		{"fallback of another type", func(t *testing.T, env *testEnv) (*models.Model, *models.Model) {
+			other := env.createModel(t, "other", models.ModelTypeOpenAI, models.ModelStatusOnline, 0)
+			return env.createModel(t, "primary", models.ModelTypeCustom, models.ModelStatusOffline, other.ID), nil
+		}},
+		{"fallback cycle", func(t *testing.T, env *testEnv) (*models.Model, *models.Model) {
+			a := env.createModel(t, "a", models.ModelTypeCustom, models.ModelStatusOffline, 0)
+			b := env.createModel(t, "b", models.ModelTypeCustom, models.ModelStatusOffline, a.ID)
+			env.db.Model(a).Update("fallback_model_id", b.ID)
+			return a, nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			primary, want := tt.setup(t, env)
+
+			// 目标模型不在线时仍可创建任务，并记录警告日志
+			req := env.createRequest()
+			req.ModelID = primary.ID
+			task := env.mustCreate(t, req)
+			var warnings int64
+			env.db.Model(&models.TaskLog{}).Where("task_id = ? AND message = ?", task.ID, "Target model is not online").Count(&warnings)
+			if warnings != 1 {
+				t.Fatalf("expected a warning log for the offline target, got %d", warnings)
+			}
+
+			rerouted, err := env.tasks.RerouteTasks(ctx, primary)
+			if want == nil {
+				if err == nil || rerouted != 0 {
+					t.Fatalf("reroute = %d, %v; want error without fallback", rerouted, err)
+				}
+				if got := env.reloadTask(t, task.ID); got.ModelID != primary.ID {
+					t.Fatalf("task moved to model %d without a fallback", got.ModelID)
+				}
+				if items := env.queuedItems(t); len(items) != 1 || items[0].ModelID != primary.ID {
+					t.Fatalf("queue = %+v, want the task still queued for the primary", items)
+				}
+				return
+			}
+
+			if err != nil || rerouted != 1 {
+				t.Fatalf("reroute = %d, %v; want 1", rerouted, err)
+			}
+			if got := env.reloadTask(t, task.ID); got.ModelID != want.ID || got.Status != models.TaskStatusPending {
+				t.Fatalf("task = model %d (%s), want pending on model %d", got.ModelID, got.Status, want.ID)
+			}
+			if items := env.queuedItems(t); len(items) != 1 || items[0].TaskID != task.ID || items[0].ModelID != want.ID {
+				t.Fatalf("queue = %+v, want the task queued once for model %d", items, want.ID)
+			}
+			var logs int64
+			env.db.Model(&models.TaskLog{}).Where("task_id = ? AND message = ?", task.ID, "Task rerouted to fallback model").Count(&logs)
+			if logs != 1 {
+				t.Fatalf("expected a reroute log, got %d", logs)
+			}
+
+			// 已改派的任务不会被再次改派
+			if again, err := env.tasks.RerouteTasks(ctx, primary); err != nil || again != 0 {
+				t.Fatalf("second reroute = %d, %v; want 0", again, err)
+			}
+		})
+	}
+}
+
+func TestRerouteTasksIgnoresOnlineModel(t *testing.T) {
+	env := newTestEnv(t, nil)
+	fallback := env.createModel(t, "fallback", models.ModelTypeCustom, models.ModelStatusOnline, 0)
+	env.db.Model(&models.Model{}).Where("id = ?", env.modelID).Update("fallback_model_id", fallback.ID)
+	env.mustCreate(t, env.createRequest())
+
+	var primary models.Model
+	env.db.First(&primary, env.modelID)
+	if rerouted, err := env.tasks.RerouteTasks(context.Background(), &primary); err != nil || rerouted != 0 {
+		t.Fatalf("reroute = %d, %v; want online model left alone", rerouted, err)
+	}
+}
+
+func TestModelFallbackValidation(t *testing.T) {
+	env := newTestEnv(t, nil)
+	modelService := newModelService(env)
+	openai := env.createModel(t, "openai", models.ModelTypeOpenAI, models.ModelStatusOnline, 0)
+
+	tests := []struct {
+		name     string
+		fallback uint64
+		wantErr  bool
+	}{
+		{"same type", env.modelID, false},
+		{"not found", 9999, true},
+		{"different type", openai.ID, true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallback := tt.fallback
+			model := &models.Model{Name: "model-" + string(rune('a'+i)), Type: models.ModelTypeCustom, Config: models.ModelConfig{}, FallbackModelID: &fallback}
+			_, err := modelService.CreateModel(model)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("CreateModel: %v", err)
+				}
+				return
+			}
+			if names := fieldNames(t, err); len(names) != 1 || names[0] != "fallback_model_id" {
+				t.Fatalf("fields = %v, want fallback_model_id", names)
+			}
+		})
+	}
+
+	// 模型不能把自己设为备用模型
+	self := env.modelID
+	if _, err := modelService.UpdateModel(env.modelID, &models.Model{FallbackModelID: &self}); err == nil {
+		t.Fatal("expected error for self fallback")
+	} else if names := fieldNames(t, err); len(names) != 1 || names[0] != "fallback_model_id" {
+		t.Fatalf("fields = %v, want fallback_model_id", names)
+	}
+}
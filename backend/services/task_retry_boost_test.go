@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestRetryTaskBoostsQueuedPriority(t *testing.T) {
+	tests := []struct {
+		name       string
+		boost      bool
+		priority   models.TaskPriority
+		wantQueued models.TaskPriority
+	}{
+		{"boost medium to high", true, models.TaskPriorityMedium, models.TaskPriorityHigh},
+		{"boost low to medium", true, models.TaskPriorityLow, models.TaskPriorityMedium},
+		// 最高为 high
+		{"high stays high", true, models.TaskPriorityHigh, models.TaskPriorityHigh},
+		{"boost disabled", false, models.TaskPriorityMedium, models.TaskPriorityMedium},
+	}
+	retries := []struct {
+		name  string
+		retry func(env *testEnv, task *models.Task) error
+	}{
+		{"retry", func(env *testEnv, task *models.Task) error {
+			return env.tasks.RetryTask(context.Background(), task.ID)
+		}},
+		{"bulk retry", func(env *testEnv, task *models.Task) error {
+			_, err := env.tasks.BulkRetryTasks(context.Background(), &models.TaskListRequest{ModelID: &env.modelID})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		for _, r := range retries {
+			t.Run(tt.name+"/"+r.name, func(t *testing.T) {
+				env := newTestEnv(t, func(cfg *config.Config) { cfg.Queue.BoostRetries = tt.boost })
+				task := env.failedTask(t)
+				env.db.Model(task).Update("priority", tt.priority)
+				task.Priority = tt.priority
+
+				if err := r.retry(env, task); err != nil {
+					t.Fatalf("retry: %v", err)
+				}
+
+				items := env.queuedItems(t)
+				if len(items) != 1 || items[0].TaskID != task.ID {
+					t.Fatalf("queued items = %+v, want task %d", items, task.ID)
+				}
+				if got := models.TaskPriority(items[0].Priority); got != tt.wantQueued {
+					t.Fatalf("queued priority = %v, want %v", got, tt.wantQueued)
+				}
+				// 任务记录保留原优先级，便于统计
+				if got := env.reloadTask(t, task.ID); got.Priority != tt.priority || got.Status != models.TaskStatusPending {
+					t.Fatalf("task = %s priority %v, want pending with priority %v", got.Status, got.Priority, tt.priority)
+				}
+
+				logs, _, err := env.tasks.ListTaskLogs(task.ID, &models.TaskLogListRequest{Page: 1, PageSize: 50})
+				if err != nil || len(logs) == 0 {
+					t.Fatalf("list logs = %v (err %v), want the retry log", logs, err)
+				}
+				data := logs[len(logs)-1].Data
+				boosted := tt.wantQueued != tt.priority
+				if gotBoosted := data["queued_priority"] != nil; gotBoosted != boosted {
+					t.Fatalf("retry log data = %v, want queued_priority logged: %v", data, boosted)
+				}
+				if boosted && fmt.Sprint(data["queued_priority"]) != fmt.Sprint(int(tt.wantQueued)) {
+					t.Fatalf("logged queued_priority = %v, want %d", data["queued_priority"], tt.wantQueued)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+)
+
+func TestCreateTaskRunAtDefersExecution(t *testing.T) {
+	env := newTestEnv(t, nil)
+	runAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	req := env.createRequest()
+	req.RunAt = &runAt
+	task := env.mustCreate(t, req)
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusPending || got.RunAt == nil || !got.RunAt.Equal(runAt) {
+		t.Fatalf("task = %s run_at %v, want pending at %v", got.Status, got.RunAt, runAt)
+	}
+
+	// 计划任务进入延迟队列，分数为执行时间，到期前不在就绪队列中
+	if ids := env.queuedTaskIDs(t); len(ids) != 0 {
+		t.Fatalf("ready queue = %v, want empty before run_at", ids)
+	}
+	members, err := env.redis.ZMembers(env.cfg.Queue.DelayedQueue)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("delayed queue = %v (err %v), want the scheduled task", members, err)
+	}
+	var item queue.QueueItem
+	if err := json.Unmarshal([]byte(members[0]), &item); err != nil || item.TaskID != task.ID {
+		t.Fatalf("delayed item = %+v (err %v), want task %d", item, err, task.ID)
+	}
+	if score, _ := env.redis.ZScore(env.cfg.Queue.DelayedQueue, members[0]); score != float64(runAt.Unix()) {
+		t.Fatalf("delayed score = %v, want %d", score, runAt.Unix())
+	}
+
+	// 尚未到期时处理延迟队列不会提前执行
+	if err := env.queue.ProcessDelayedTasks(context.Background()); err != nil {
+		t.Fatalf("ProcessDelayedTasks: %v", err)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 0 {
+		t.Fatalf("ready queue = %v, want empty before run_at", ids)
+	}
+
+	// 到达执行时间后移入就绪队列
+	env.expireAll(t, env.cfg.Queue.DelayedQueue)
+	if err := env.queue.ProcessDelayedTasks(context.Background()); err != nil {
+		t.Fatalf("ProcessDelayedTasks: %v", err)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 || ids[0] != task.ID {
+		t.Fatalf("ready queue = %v, want [%d]", ids, task.ID)
+	}
+	if n := env.zcard(t, env.cfg.Queue.DelayedQueue); n != 0 {
+		t.Fatalf("delayed queue has %d items, want 0", n)
+	}
+}
+
+func TestCreateTaskRejectsInvalidRunAt(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	soon := time.Now().Add(time.Minute)
+	later := time.Now().Add(time.Hour)
+	ttl := 60
+	tests := []struct {
+		name     string
+		runAt    time.Time
+		deadline *time.Time
+		ttl      *int
+	}{
+		{"run_at in the past", past, nil, nil},
+		// 执行时间不能晚于截止时间，ttl_seconds 换算出的截止时间同样适用
+		{"run_at after deadline", later, &soon, nil},
+		{"run_at equal to deadline", soon, &soon, nil},
+		{"run_at after ttl", later, nil, &ttl},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			req := env.createRequest()
+			req.RunAt = &tt.runAt
+			req.Deadline = tt.deadline
+			req.TTLSeconds = tt.ttl
+
+			_, err := env.tasks.CreateTask(context.Background(), req)
+			if err == nil {
+				t.Fatalf("CreateTask succeeded, want validation error")
+			}
+			if fields := fieldNames(t, err); len(fields) != 1 || fields[0] != "run_at" {
+				t.Fatalf("invalid fields = %v, want [run_at]", fields)
+			}
+			if n := env.countTasks(t); n != 0 {
+				t.Fatalf("created %d tasks, want 0", n)
+			}
+		})
+	}
+}
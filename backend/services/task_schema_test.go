@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskValidatesInputSchema(t *testing.T) {
+	env := newTestEnv(t, nil)
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"text"},
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{"type": "string"},
+		},
+	}
+	env.db.Model(&models.Model{}).Where("id = ?", env.modelID).Update("config", models.ModelConfig{"input_schema": schema})
+
+	tests := []struct {
+		name       string
+		input      string
+		wantFields []string
+	}{
+		{"valid", `{"text":"hello"}`, nil},
+		{"missing field", `{"lang":"en"}`, []string{"input.text"}},
+		{"wrong field type", `{"text":42}`, []string{"input.text"}},
+		{"not json", `hello`, []string{"input"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := env.createRequest()
+			req.Input = tt.input
+			task, err := env.tasks.CreateTask(context.Background(), req)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("CreateTask: %v", err)
+				}
+				if task.Status != models.TaskStatusPending {
+					t.Fatalf("status = %s, want pending", task.Status)
+				}
+				return
+			}
+			if names := fieldNames(t, err); len(names) != len(tt.wantFields) || names[0] != tt.wantFields[0] {
+				t.Fatalf("fields = %v, want %v", names, tt.wantFields)
+			}
+		})
+	}
+
+	// 校验失败的任务不会入队
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 {
+		t.Fatalf("queued = %v, want only the valid task", ids)
+	}
+}
+
+func TestModelConfigRejectsInvalidInputSchema(t *testing.T) {
+	env := newTestEnv(t, nil)
+	modelService := newModelService(env)
+
+	_, err := modelService.CreateModel(&models.Model{
+		Name:   "schema-model",
+		Type:   models.ModelTypeCustom,
+		Config: models.ModelConfig{"input_schema": map[string]interface{}{"type": "text"}},
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "config.input_schema" {
+		t.Fatalf("error = %v, want config.input_schema validation error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// listTaskIDs 按 ID 升序返回 ListTasks 的结果
+func (env *testEnv) listTaskIDs(t *testing.T, req *models.TaskListRequest) []uint64 {
+	t.Helper()
+	req.Page, req.PageSize, req.OrderBy, req.Order = 1, 100, "id", "asc"
+	tasks, total, err := env.tasks.ListTasks(req)
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	ids := make([]uint64, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if int64(len(ids)) != total {
+		t.Fatalf("total = %d, listed %d", total, len(ids))
+	}
+	return ids
+}
+
+func TestListTasksContentSearch(t *testing.T) {
+	env := newTestEnv(t, nil)
+	report := env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) { task.Input = "Summarize the quarterly revenue report" })
+	notes := env.createTask(t, models.TaskStatusCompleted, func(task *models.Task) { task.Input = "meeting notes" })
+	failed := env.createTask(t, models.TaskStatusFailed, func(task *models.Task) {
+		task.Input = "translate the notes"
+		message := "upstream timeout after 30s"
+		task.ErrorMessage = &message
+	})
+	failedStatus := models.TaskStatusFailed
+
+	tests := []struct {
+		name string
+		req  models.TaskListRequest
+		want []uint64
+	}{
+		{"input match", models.TaskListRequest{Query: "revenue"}, []uint64{report.ID}},
+		{"case insensitive", models.TaskListRequest{Query: "QUARTERLY"}, []uint64{report.ID}},
+		{"error message match", models.TaskListRequest{Query: "timeout"}, []uint64{failed.ID}},
+		{"several matches", models.TaskListRequest{Query: "notes"}, []uint64{notes.ID, failed.ID}},
+		{"phrase", models.TaskListRequest{Query: "meeting notes"}, []uint64{notes.ID}},
+		{"quotes stripped", models.TaskListRequest{Query: `"notes"`}, []uint64{notes.ID, failed.ID}},
+		{"combined with status", models.TaskListRequest{Query: "notes", Status: &failedStatus}, []uint64{failed.ID}},
+		{"no match", models.TaskListRequest{Query: "invoice"}, []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := env.listTaskIDs(t, &tt.req)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListTasksCreatedRange(t *testing.T) {
+	env := newTestEnv(t, nil)
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for day := 0; day < 3; day++ {
+		task := env.createTask(t, models.TaskStatusCompleted, nil)
+		if err := env.db.Model(task).UpdateColumn("created_at", base.AddDate(0, 0, day)).Error; err != nil {
+			t.Fatalf("set created_at: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+	at := func(days int) *time.Time {
+		v := base.AddDate(0, 0, days)
+		return &v
+	}
+
+	tests := []struct {
+		name   string
+		after  *time.Time
+		before *time.Time
+		want   []uint64
+	}{
+		{"after is inclusive", at(1), nil, ids[1:]},
+		{"before is exclusive", nil, at(1), ids[:1]},
+		{"range", at(1), at(2), ids[1:2]},
+		{"empty range", at(2), at(1), []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := env.listTaskIDs(t, &models.TaskListRequest{CreatedAfter: tt.after, CreatedBefore: tt.before})
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -2,11 +2,21 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"llm-scheduler/config"
 	"llm-scheduler/models"
 	"llm-scheduler/queue"
+	"llm-scheduler/storage"
+	"llm-scheduler/utils"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -16,97 +26,456 @@ import (
 type TaskService struct {
 	db           *gorm.DB
 	queueManager *queue.Manager
+	config       *config.Config
 	logger       *logrus.Logger
+	validators   taskValidators
+	// outputStorage 大输出的外部存储，为 nil 表示所有输出保存在数据库中
+	outputStorage storage.OutputStorage
 }
 
 // NewTaskService 创建任务服务
-func NewTaskService(db *gorm.DB, queueManager *queue.Manager, logger *logrus.Logger) *TaskService {
-	return &TaskService{
-		db:           db,
-		queueManager: queueManager,
-		logger:       logger,
+func NewTaskService(db *gorm.DB, queueManager *queue.Manager, outputStorage storage.OutputStorage, cfg *config.Config, logger *logrus.Logger) *TaskService {
+	s := &TaskService{
+		db:            db,
+		queueManager:  queueManager,
+		outputStorage: outputStorage,
+		config:        cfg,
+		logger:        logger,
+		validators: taskValidators{
+			validators: make(map[string]TaskValidator),
+		},
 	}
+	s.registerBuiltinValidators()
+	queueManager.AddTaskCompletedHook(s.resolveDependents)
+	queueManager.AddTaskEscalatedHook(s.escalateTask)
+	queueManager.AddTaskExpiredHook(s.expireTask)
+	return s
 }
 
-// CreateTask 创建任务
+// CreateTask 创建任务。请求带幂等键时，相同键的重复请求直接返回首次创建的任务，
+// 请求内容与首次不同时返回 ErrIdempotencyKeyMismatch
 func (s *TaskService) CreateTask(ctx context.Context, req *models.TaskCreateRequest) (*models.Task, error) {
+	if req.IdempotencyKey == "" {
+		return s.createTask(ctx, req)
+	}
+
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+	taskID, reserved, err := s.queueManager.ReserveIdempotencyKey(ctx, req.IdempotencyKey, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		if taskID == 0 {
+			return nil, ErrIdempotentRequestInProgress
+		}
+		s.logger.WithField("task_id", taskID).Info("Idempotent task creation replayed")
+		return s.GetTask(taskID)
+	}
+
+	task, err := s.createTask(ctx, req)
+	if err != nil {
+		// 创建失败时释放幂等键，允许客户端使用相同的键重试
+		if releaseErr := s.queueManager.ReleaseIdempotencyKey(ctx, req.IdempotencyKey); releaseErr != nil {
+			s.logger.WithError(releaseErr).Warn("Failed to release idempotency key")
+		}
+		return nil, err
+	}
+
+	if err := s.queueManager.CompleteIdempotencyKey(ctx, req.IdempotencyKey, fingerprint, task.ID); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Error("Failed to store idempotency key")
+	}
+	return task, nil
+}
+
+// requestFingerprint 计算创建请求内容的摘要，用于识别复用幂等键的不同请求
+func requestFingerprint(req *models.TaskCreateRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// createTask 校验、写入并分发单个任务
+func (s *TaskService) createTask(ctx context.Context, req *models.TaskCreateRequest) (*models.Task, error) {
+	// 已有相同去重键的进行中任务时直接返回，不做容量检查
+	existing, err := findActiveDuplicate(s.db, req.DedupKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return s.returnDuplicate(existing), nil
+	}
+
+	task, model, deps, err := s.buildTask(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(task).Error; err != nil {
+		// 并发创建时由唯一索引兜底，返回先写入的任务
+		if errors.Is(err, gorm.ErrDuplicatedKey) && task.DedupKey != "" {
+			if existing, findErr := findActiveDuplicate(s.db, task.DedupKey); findErr == nil && existing != nil {
+				return s.returnDuplicate(existing), nil
+			}
+		}
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	if err := s.dispatchTask(ctx, task, model, deps); err != nil {
+		return nil, err
+	}
+
+	if task.WaitingDeps {
+		// 依赖检查可能已改变任务状态，返回最新数据
+		if err := s.db.First(task, task.ID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get task: %w", err)
+		}
+	}
+
+	return task, nil
+}
+
+// CreateTasks 批量创建任务，校验失败的条目不会写入，其余条目在同一事务中创建后逐个入队
+func (s *TaskService) CreateTasks(ctx context.Context, reqs []*models.TaskCreateRequest) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(reqs))
+
+	type pendingTask struct {
+		index int
+		task  *models.Task
+		model *models.Model
+		deps  []models.Task
+	}
+	var pending []pendingTask
+	// 本批次已使用的去重键
+	dedupKeys := make(map[string]bool)
+
+	for i, req := range reqs {
+		results[i].Index = i
+
+		s.applyDefaultTaskType(req)
+		if fields := validateRequiredFields(req); len(fields) > 0 {
+			results[i].Errors = fields
+			continue
+		}
+
+		if req.DedupKey != "" {
+			if dedupKeys[req.DedupKey] {
+				results[i].Error = "duplicate dedup_key within batch"
+				continue
+			}
+			existing, err := findActiveDuplicate(s.db, req.DedupKey)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if existing != nil {
+				results[i].TaskID = s.returnDuplicate(existing).ID
+				continue
+			}
+			dedupKeys[req.DedupKey] = true
+		}
+
+		task, model, deps, err := s.buildTask(ctx, req)
+		if err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				results[i].Errors = validationErr.Fields
+			} else {
+				results[i].Error = err.Error()
+			}
+			continue
+		}
+
+		pending = append(pending, pendingTask{index: i, task: task, model: model, deps: deps})
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	// 所有有效条目在同一事务中写入
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range pending {
+			if err := tx.Create(p.task).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create tasks: %w", err)
+	}
+
+	// 入队失败只影响对应条目
+	for _, p := range pending {
+		results[p.index].TaskID = p.task.ID
+		if err := s.dispatchTask(ctx, p.task, p.model, p.deps); err != nil {
+			results[p.index].Error = err.Error()
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"total":   len(reqs),
+		"created": len(pending),
+	}).Info("Batch tasks created")
+
+	return results, nil
+}
+
+// buildTask 校验创建请求并构造任务，返回任务所属模型和依赖任务的当前状态
+func (s *TaskService) buildTask(ctx context.Context, req *models.TaskCreateRequest) (*models.Task, *models.Model, []models.Task, error) {
+	s.applyDefaultTaskType(req)
+
+	// 按任务类型校验输入，避免无效任务占用 Worker
+	if err := s.ValidateTaskRequest(req); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 未指定模型时使用配置的默认模型
+	if req.ModelID == 0 {
+		defaultModel, err := s.getDefaultModel()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		req.ModelID = defaultModel.ID
+	}
+
 	// 验证模型是否存在
 	var model models.Model
 	if err := s.db.First(&model, req.ModelID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("model not found")
+			return nil, nil, nil, ErrModelNotFound
+		}
+		return nil, nil, nil, fmt.Errorf("failed to query model: %w", err)
+	}
+
+	// 未允许的任务类型会被 Worker 当作自定义任务执行，在入队前拒绝
+	if err := s.validateTaskType(&model, req.Type); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 模型要求特定的输入结构时，在入队前拒绝不符合的输入
+	if err := validateInputSchema(&model, req.Input); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 检查依赖任务
+	dependsOn := uniqueTaskIDs(req.DependsOn)
+	deps, err := s.getDependencies(dependsOn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 任务级覆盖了服务地址时结果可能与模型配置不同，不参与缓存
+	var cacheKey string
+	if req.ProviderOverride == nil {
+		cacheKey = taskCacheKey(req.ModelID, req.Type, req.Input, req.Params)
+	}
+
+	var cached *models.Task
+	// 计划在将来执行的任务不复用缓存，到时间后再执行
+	if req.Cache && len(dependsOn) == 0 && req.RunAt == nil {
+		if cached, err = s.findCachedTask(cacheKey); err != nil {
+			return nil, nil, nil, err
 		}
-		return nil, fmt.Errorf("failed to query model: %w", err)
 	}
 
-	// 创建任务
+	// 队列已满时在写入数据库前拒绝，避免留下入队失败的任务；等待依赖或命中缓存的任务不占用队列
+	if len(dependsOn) == 0 && cached == nil {
+		if err := s.queueManager.CheckCapacity(ctx, req.Priority); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// 存在依赖时先不入队
 	task := &models.Task{
-		ModelID:  req.ModelID,
-		Type:     req.Type,
-		Input:    req.Input,
-		Priority: req.Priority,
-		Status:   models.TaskStatusPending,
+		ModelID:          req.ModelID,
+		Type:             req.Type,
+		Input:            req.Input,
+		Params:           req.Params,
+		Tags:             req.Tags,
+		Priority:         req.Priority,
+		MaxRetries:       s.resolveMaxRetries(req),
+		Status:           models.TaskStatusPending,
+		DependsOn:        dependsOn,
+		WaitingDeps:      len(dependsOn) > 0,
+		ProviderOverride: req.ProviderOverride,
+		TraceID:          traceIDFrom(ctx),
+		APIKeyName:       req.APIKeyName,
+		CacheKey:         cacheKey,
+		DedupKey:         req.DedupKey,
+		Deadline:         req.Deadline,
+		RunAt:            req.RunAt,
+	}
+	if req.TTLSeconds != nil {
+		deadline := time.Now().Add(time.Duration(*req.TTLSeconds) * time.Second)
+		task.Deadline = &deadline
+	}
+	if cached != nil {
+		applyCachedResult(task, cached)
 	}
 
-	if err := s.db.Create(task).Error; err != nil {
-		return nil, fmt.Errorf("failed to create task: %w", err)
+	return task, &model, deps, nil
+}
+
+// getDefaultModel 获取配置的默认模型（按 ID 或名称），未配置、不存在或不在线时返回校验错误
+func (s *TaskService) getDefaultModel() (*models.Model, error) {
+	ref := strings.TrimSpace(s.config.Models.DefaultModel)
+	if ref == "" {
+		return nil, &ValidationError{Fields: []models.FieldError{{
+			Field:   "model_id",
+			Message: "model_id is required",
+		}}}
+	}
+
+	var model models.Model
+	query := s.db.Where("name = ?", ref)
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		query = s.db.Where("id = ?", id)
+	}
+	if err := query.First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &ValidationError{Fields: []models.FieldError{{
+				Field:   "model_id",
+				Message: fmt.Sprintf("default model %s not found", ref),
+			}}}
+		}
+		return nil, fmt.Errorf("failed to query default model: %w", err)
+	}
+
+	if model.Status != models.ModelStatusOnline {
+		return nil, &ValidationError{Fields: []models.FieldError{{
+			Field:   "model_id",
+			Message: fmt.Sprintf("default model %s is %s", model.Name, model.Status),
+		}}}
+	}
+
+	return &model, nil
+}
+
+// resolveMaxRetries 确定任务的最大重试次数：请求指定 > 任务类型默认值 > queue.max_retries
+func (s *TaskService) resolveMaxRetries(req *models.TaskCreateRequest) int {
+	if req.MaxRetries != nil {
+		return *req.MaxRetries
+	}
+	if n, ok := s.config.Queue.TypeMaxRetries[req.Type]; ok {
+		return n
+	}
+	return s.config.Queue.MaxRetries
+}
+
+// dispatchTask 将已创建的任务加入队列，存在依赖时改为等待依赖完成
+func (s *TaskService) dispatchTask(ctx context.Context, task *models.Task, model *models.Model, deps []models.Task) error {
+	// 命中结果缓存的任务创建时已经完成，不再入队
+	if task.CachedFromID != nil {
+		s.addTaskLog(task.ID, models.LogLevelInfo, "Task result served from cache", models.LogData{
+			"cached_from_id": *task.CachedFromID,
+		})
+		s.publishEvent(task, models.TaskStatusPending)
+		s.logger.WithFields(logrus.Fields{
+			"task_id":        task.ID,
+			"cached_from_id": *task.CachedFromID,
+			"trace_id":       task.TraceID,
+		}).Info("Task created from cache")
+		return nil
+	}
+
+	s.queueManager.SetModelBackend(model.ID, model.GetQueueBackend())
+
+	// 目标模型当前不在线时仍然创建任务，配置了备用模型时会被改派，否则等待模型上线
+	if !model.IsOnline() {
+		s.logger.WithFields(logrus.Fields{
+			"task_id":      task.ID,
+			"model_id":     model.ID,
+			"model_status": model.Status,
+		}).Warn("Task created for a model that is not online")
+		s.addTaskLog(task.ID, models.LogLevelWarn, "Target model is not online", models.LogData{
+			"model_id":          model.ID,
+			"model_status":      model.Status,
+			"fallback_model_id": model.FallbackModelID,
+		})
+	}
+
+	if task.WaitingDeps {
+		s.addTaskLog(task.ID, models.LogLevelInfo, "Task created and waiting for dependencies", models.LogData{
+			"depends_on": []uint64(task.DependsOn),
+		})
+
+		// 依赖可能在创建前已全部结束，此时不会再收到完成通知，需要立即检查一次
+		s.checkDependencies(ctx, task, deps)
+		return nil
 	}
 
-	// 将任务加入队列
+	// 将任务加入模型对应的队列后端
 	if err := s.queueManager.EnqueueTask(ctx, task); err != nil {
 		s.logger.WithError(err).Error("Failed to enqueue task")
 		// 任务创建成功但入队失败，更新状态
 		s.db.Model(task).Update("status", models.TaskStatusFailed)
 		s.db.Model(task).Update("error_message", "Failed to enqueue task")
-		return nil, fmt.Errorf("failed to enqueue task: %w", err)
+		s.publishEvent(task, models.TaskStatusPending)
+		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
 	// 记录日志
-	s.addTaskLog(task.ID, models.LogLevelInfo, "Task created and enqueued", nil)
+	if task.ScheduledAfter(time.Now()) {
+		s.addTaskLog(task.ID, models.LogLevelInfo, "Task created and scheduled", models.LogData{
+			"run_at": task.RunAt,
+		})
+	} else {
+		s.addTaskLog(task.ID, models.LogLevelInfo, "Task created and enqueued", nil)
+	}
 
 	s.logger.WithFields(logrus.Fields{
 		"task_id":  task.ID,
 		"model_id": task.ModelID,
 		"type":     task.Type,
 		"priority": task.Priority,
+		"trace_id": task.TraceID,
 	}).Info("Task created")
 
-	return task, nil
+	return nil
 }
 
-// GetTask 获取任务详情
+// GetTask 获取任务详情，只包含最近的日志
 func (s *TaskService) GetTask(id uint64) (*models.Task, error) {
 	var task models.Task
-	err := s.db.Preload("Model").Preload("Logs").First(&task, id).Error
+	err := s.db.Preload("Model", withDeletedModels).Preload("Logs", recentTaskLogs).First(&task, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("task not found")
+			return nil, ErrTaskNotFound
 		}
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
+	reverseTaskLogs(task.Logs)
 	return &task, nil
 }
 
+// taskOrderColumns 任务列表允许排序的列
+var taskOrderColumns = map[string]bool{
+	"id":           true,
+	"created_at":   true,
+	"updated_at":   true,
+	"started_at":   true,
+	"completed_at": true,
+	"priority":     true,
+	"status":       true,
+}
+
+// fulltextPhrase 将检索内容转换为布尔模式下的短语，去掉双引号避免破坏短语语法
+func fulltextPhrase(q string) string {
+	return `"` + strings.ReplaceAll(q, `"`, " ") + `"`
+}
+
 // ListTasks 获取任务列表
 func (s *TaskService) ListTasks(req *models.TaskListRequest) ([]models.Task, int64, error) {
 	var tasks []models.Task
 	var total int64
 
-	query := s.db.Model(&models.Task{}).Preload("Model")
-
-	// 过滤条件
-	if req.ModelID != nil {
-		query = query.Where("model_id = ?", *req.ModelID)
-	}
-	if req.Status != nil {
-		query = query.Where("status = ?", *req.Status)
-	}
-	if req.Type != nil {
-		query = query.Where("type = ?", *req.Type)
-	}
-	if req.Priority != nil {
-		query = query.Where("priority = ?", *req.Priority)
-	}
+	query := applyTaskFilters(s.db.Model(&models.Task{}).Preload("Model", withDeletedModels), req)
 
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -116,11 +485,11 @@ func (s *TaskService) ListTasks(req *models.TaskListRequest) ([]models.Task, int
 	// 分页和排序
 	offset := (req.Page - 1) * req.PageSize
 	orderBy := req.OrderBy
-	if orderBy == "" {
+	if !taskOrderColumns[orderBy] {
 		orderBy = "created_at"
 	}
-	order := req.Order
-	if order == "" {
+	order := strings.ToLower(req.Order)
+	if order != "asc" {
 		order = "desc"
 	}
 
@@ -136,34 +505,151 @@ func (s *TaskService) ListTasks(req *models.TaskListRequest) ([]models.Task, int
 	return tasks, total, nil
 }
 
+// ListTasksByCursor 按游标分页获取任务列表：按 ID 排序（默认降序），返回 ID 在游标之后的任务，
+// 不做 COUNT 和 OFFSET，深分页性能不随页码下降；没有下一页时 nextCursor 为 nil
+func (s *TaskService) ListTasksByCursor(req *models.TaskListRequest) ([]models.Task, *uint64, error) {
+	var tasks []models.Task
+
+	query := applyTaskFilters(s.db.Model(&models.Task{}).Preload("Model", withDeletedModels), req)
+
+	order := "desc"
+	if strings.ToLower(req.Order) == "asc" {
+		order = "asc"
+	}
+	if req.Cursor != nil && *req.Cursor > 0 {
+		if order == "asc" {
+			query = query.Where("id > ?", *req.Cursor)
+		} else {
+			query = query.Where("id < ?", *req.Cursor)
+		}
+	}
+
+	// 多取一条判断是否还有下一页
+	err := query.Order("id " + order).
+		Limit(req.Limit + 1).
+		Find(&tasks).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	if len(tasks) <= req.Limit {
+		return tasks, nil, nil
+	}
+	tasks = tasks[:req.Limit]
+	nextCursor := tasks[len(tasks)-1].ID
+	return tasks, &nextCursor, nil
+}
+
+// tagPath 返回标签键对应的 JSON 路径，键名加引号以支持包含 '.' 和 '-' 的键
+func tagPath(key string) string {
+	return `$."` + key + `"`
+}
+
+// applyTaskFilters 按列表过滤条件追加查询条件，任务列表和批量操作共用
+func applyTaskFilters(query *gorm.DB, req *models.TaskListRequest) *gorm.DB {
+	if req.ModelID != nil {
+		query = query.Where("model_id = ?", *req.ModelID)
+	}
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+	if req.Type != nil {
+		query = query.Where("type = ?", *req.Type)
+	}
+	if req.Priority != nil {
+		query = query.Where("priority = ?", *req.Priority)
+	}
+	if req.NeedsAttention != nil {
+		query = query.Where("needs_attention = ?", *req.NeedsAttention)
+	}
+	if req.Query != "" {
+		// 使用 ft_tasks_content 全文索引（ngram 分词），按短语匹配
+		query = query.Where("MATCH(input, error_message) AGAINST (? IN BOOLEAN MODE)", fulltextPhrase(req.Query))
+	}
+	if req.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *req.CreatedAfter)
+	}
+	if req.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *req.CreatedBefore)
+	}
+	if len(req.Tags) > 0 {
+		keys := make([]string, 0, len(req.Tags))
+		for key := range req.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// 键名已校验只含安全字符，仍以参数绑定 JSON 路径，值按字符串比较
+			query = query.Where("JSON_UNQUOTE(JSON_EXTRACT(tags, ?)) = ?", tagPath(key), req.Tags[key])
+		}
+	}
+	return query
+}
+
 // UpdateTask 更新任务
-func (s *TaskService) UpdateTask(id uint64, req *models.TaskUpdateRequest) (*models.Task, error) {
+func (s *TaskService) UpdateTask(ctx context.Context, id uint64, req *models.TaskUpdateRequest) (*models.Task, error) {
 	var task models.Task
 	if err := s.db.First(&task, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("task not found")
+			return nil, ErrTaskNotFound
 		}
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	updates := make(map[string]interface{})
-	
+	// 状态只能按状态机转换，设置为当前状态视为未修改
+	var newStatus models.TaskStatus
+	if req.Status != nil && *req.Status != task.Status {
+		newStatus = *req.Status
+		if !newStatus.IsValid() {
+			return nil, &ValidationError{Fields: []models.FieldError{{
+				Field:   "status",
+				Message: fmt.Sprintf("unknown status %q", newStatus),
+			}}}
+		}
+		if !task.CanTransitionTo(newStatus) {
+			return nil, fmt.Errorf("task cannot transition from %s to %s: %w", task.Status, newStatus, ErrInvalidStatusTransition)
+		}
+	}
+
 	if req.Priority != nil {
-		updates["priority"] = *req.Priority
-		s.addTaskLog(id, models.LogLevelInfo, 
+		if err := s.db.Model(&models.Task{}).Where("id = ?", id).Update("priority", *req.Priority).Error; err != nil {
+			return nil, fmt.Errorf("failed to update task: %w", err)
+		}
+		s.addTaskLog(id, models.LogLevelInfo,
 			fmt.Sprintf("Priority updated to %d", *req.Priority), nil)
 	}
-	
-	if req.Status != nil {
-		updates["status"] = *req.Status
-		s.addTaskLog(id, models.LogLevelInfo, 
-			fmt.Sprintf("Status updated to %s", *req.Status), nil)
-	}
 
-	if len(updates) > 0 {
-		if err := s.db.Model(&task).Updates(updates).Error; err != nil {
-			return nil, fmt.Errorf("failed to update task: %w", err)
+	switch newStatus {
+	case "":
+	case models.TaskStatusCancelled:
+		// 取消需要中断执行和释放依赖，与取消接口一致
+		if err := s.CancelTask(ctx, id); err != nil {
+			return nil, err
+		}
+	default:
+		updates := map[string]interface{}{"status": newStatus}
+		now := time.Now()
+		switch newStatus {
+		case models.TaskStatusRunning:
+			updates["started_at"] = now
+		case models.TaskStatusCompleted, models.TaskStatusFailed:
+			updates["completed_at"] = now
+		case models.TaskStatusPending:
+			updates["started_at"] = nil
+			updates["completed_at"] = nil
+		}
+
+		// 按读取时的状态条件更新，期间状态已被其他操作改变时拒绝
+		update := s.db.Model(&models.Task{}).Where("id = ? AND status = ?", id, task.Status).Updates(updates)
+		if update.Error != nil {
+			return nil, fmt.Errorf("failed to update task: %w", update.Error)
+		}
+		if update.RowsAffected == 0 {
+			return nil, fmt.Errorf("task status changed concurrently: %w", ErrInvalidStatusTransition)
 		}
+		s.setTaskStatus(&task, newStatus)
+		s.addTaskLog(id, models.LogLevelInfo,
+			fmt.Sprintf("Status updated to %s", newStatus), nil)
 	}
 
 	return s.GetTask(id)
@@ -174,33 +660,22 @@ func (s *TaskService) CancelTask(ctx context.Context, id uint64) error {
 	var task models.Task
 	if err := s.db.First(&task, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("task not found")
+			return ErrTaskNotFound
 		}
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// 只有 pending 和 running 状态的任务可以取消
-	if task.Status != models.TaskStatusPending && task.Status != models.TaskStatusRunning {
-		return fmt.Errorf("task cannot be cancelled in current status: %s", task.Status)
-	}
-
-	// 更新状态
-	if err := s.db.Model(&task).Updates(map[string]interface{}{
-		"status":       models.TaskStatusCancelled,
-		"completed_at": time.Now(),
-	}).Error; err != nil {
+	// 只有 pending 和 running 状态的任务可以取消，读取后已结束的任务不会被改为取消
+	if err := s.transitionTask(&task, models.TaskStatusCancelled, map[string]interface{}{
+		"completed_at":         time.Now(),
+		"waiting_dependencies": false,
+	}); err != nil {
 		return fmt.Errorf("failed to cancel task: %w", err)
 	}
+	s.afterTaskCancelled(ctx, &task, "Task cancelled by user")
 
-	// 如果任务在处理中，从处理队列中移除
-	if task.Status == models.TaskStatusRunning {
-		s.queueManager.CompleteTask(ctx, id)
-	}
-
-	s.addTaskLog(id, models.LogLevelInfo, "Task cancelled by user", nil)
-	
 	s.logger.WithField("task_id", id).Info("Task cancelled")
-	
+
 	return nil
 }
 
@@ -209,95 +684,259 @@ func (s *TaskService) RetryTask(ctx context.Context, id uint64) error {
 	var task models.Task
 	if err := s.db.First(&task, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("task not found")
+			return ErrTaskNotFound
 		}
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// 只有失败的任务可以重试
-	if task.Status != models.TaskStatusFailed {
-		return fmt.Errorf("task cannot be retried in current status: %s", task.Status)
+	// 只有失败且未超过重试次数的任务可以重试；running → pending 是中断后的恢复，不属于重试
+	if task.Status != models.TaskStatusFailed || !task.CanTransitionTo(models.TaskStatusPending) {
+		return fmt.Errorf("task cannot be retried in current status %s: %w", task.Status, ErrInvalidStatusTransition)
 	}
-
-	// 检查重试次数
-	if task.RetryCount >= task.MaxRetries {
+	if !isRetriable(&task) {
 		return fmt.Errorf("task has exceeded maximum retry count")
 	}
 
-	// 重置任务状态
+	// 重置任务状态，读取后已被其他请求重试的任务不会再次重置
 	updates := map[string]interface{}{
-		"status":        models.TaskStatusPending,
-		"error_message": nil,
-		"started_at":    nil,
-		"completed_at":  nil,
-		"retry_count":   task.RetryCount + 1,
+		"error_message":   nil,
+		"started_at":      nil,
+		"completed_at":    nil,
+		"retry_count":     task.RetryCount + 1,
+		"needs_attention": false,
 	}
 
-	if err := s.db.Model(&task).Updates(updates).Error; err != nil {
+	if err := s.transitionTask(&task, models.TaskStatusPending, updates); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrDedupKeyActive
+		}
 		return fmt.Errorf("failed to update task for retry: %w", err)
 	}
 
-	// 重新入队
-	task.Status = models.TaskStatusPending
-	task.RetryCount++
-	if err := s.queueManager.EnqueueTask(ctx, &task); err != nil {
-		return fmt.Errorf("failed to enqueue retry task: %w", err)
+	if err := s.afterTaskRetried(ctx, &task); err != nil {
+		return err
 	}
 
-	s.addTaskLog(id, models.LogLevelInfo, 
-		fmt.Sprintf("Task retried (attempt %d/%d)", task.RetryCount+1, task.MaxRetries), nil)
-	
 	s.logger.WithFields(logrus.Fields{
-		"task_id":      id,
-		"retry_count":  task.RetryCount + 1,
-		"max_retries":  task.MaxRetries,
+		"task_id":     id,
+		"retry_count": task.RetryCount,
+		"max_retries": task.MaxRetries,
 	}).Info("Task retried")
-	
+
 	return nil
 }
 
-// StartTask 开始执行任务
+// transitionTask 按状态机将任务从读取时的状态 task.Status 更新为 status 并写入 updates 中的其他字段。
+// 以读取时的状态为条件更新，读取后状态已被其他操作改变时同样返回 ErrInvalidStatusTransition，
+// 并发的取消、完成、重试之间只有一个生效
+func (s *TaskService) transitionTask(task *models.Task, status models.TaskStatus, updates map[string]interface{}) error {
+	if !task.CanTransitionTo(status) {
+		return fmt.Errorf("task cannot transition from %s to %s: %w", task.Status, status, ErrInvalidStatusTransition)
+	}
+
+	updates["status"] = status
+	update := s.db.Model(&models.Task{}).
+		Where("id = ? AND status = ?", task.ID, task.Status).
+		Updates(updates)
+	if update.Error != nil {
+		return update.Error
+	}
+	if update.RowsAffected == 0 {
+		return fmt.Errorf("task status changed concurrently: %w", ErrInvalidStatusTransition)
+	}
+	return nil
+}
+
+// StartTask 开始执行任务，任务已不是 pending 时返回 ErrInvalidStatusTransition
 func (s *TaskService) StartTask(id uint64) error {
-	updates := map[string]interface{}{
-		"status":     models.TaskStatusRunning,
-		"started_at": time.Now(),
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		return err
 	}
 
-	if err := s.db.Model(&models.Task{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	// 只有 pending 的任务可以开始执行，出队后到这里之间被取消的任务不再被改回 running
+	if err := s.transitionTask(task, models.TaskStatusRunning, map[string]interface{}{
+		"started_at": time.Now(),
+	}); err != nil {
 		return fmt.Errorf("failed to start task: %w", err)
 	}
+	s.setTaskStatus(task, models.TaskStatusRunning)
 
 	s.addTaskLog(id, models.LogLevelInfo, "Task execution started", nil)
 	return nil
 }
 
-// CompleteTask 完成任务
-func (s *TaskService) CompleteTask(id uint64, output string) error {
+// CompleteTask 完成任务，输出超过 max_output_bytes 时截断保存并在日志中记录原始长度。
+// 任务已不在执行中（如执行期间被取消）时不写入结果，返回 ErrInvalidStatusTransition
+func (s *TaskService) CompleteTask(id uint64, output string, format models.OutputFormat, usage models.TokenUsage) error {
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		return err
+	}
+
+	originalBytes := len(output)
+	output, truncated := utils.TruncateBytes(output, s.getMaxOutputBytes())
+
 	updates := map[string]interface{}{
-		"status":       models.TaskStatusCompleted,
-		"output":       output,
-		"completed_at": time.Now(),
+		"output":            output,
+		"output_truncated":  truncated,
+		"output_uri":        nil,
+		"output_format":     format,
+		"prompt_tokens":     usage.PromptTokens,
+		"completion_tokens": usage.CompletionTokens,
+		"cost_usd":          usage.CostUSD,
+		"completed_at":      time.Now(),
+	}
+
+	if uri := s.offloadOutput(id, output); uri != "" {
+		updates["output"] = nil
+		updates["output_uri"] = uri
 	}
 
-	if err := s.db.Model(&models.Task{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	// 只有执行中的任务可以完成，已取消或已结束的任务不再被执行结果覆盖
+	if err := s.transitionTask(task, models.TaskStatusCompleted, updates); err != nil {
 		return fmt.Errorf("failed to complete task: %w", err)
 	}
+	s.setTaskStatus(task, models.TaskStatusCompleted)
+
+	if truncated {
+		s.addTaskLog(id, models.LogLevelWarn, "Task output truncated", models.LogData{
+			"original_bytes": originalBytes,
+			"stored_bytes":   len(output),
+		})
+	}
 
 	s.addTaskLog(id, models.LogLevelInfo, "Task completed successfully", nil)
 	return nil
 }
 
-// FailTask 任务失败
+// getMaxOutputBytes 获取任务输出的最大字节数，-1 表示不限制
+func (s *TaskService) getMaxOutputBytes() int {
+	if s.config.Queue.MaxOutputBytes > 0 {
+		return s.config.Queue.MaxOutputBytes
+	}
+	return -1
+}
+
+// escalateTask 任务反复超时进入延迟队列后升级为需人工处理：标记失败且不再自动重试
+func (s *TaskService) escalateTask(ctx context.Context, id uint64, delayCount int) {
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Error("Failed to escalate task")
+		return
+	}
+
+	errorMsg := fmt.Sprintf("task delayed %d times without completing, needs attention", delayCount)
+	updates := map[string]interface{}{
+		"error_message":   errorMsg,
+		"completed_at":    time.Now(),
+		"needs_attention": true,
+	}
+
+	// 期间已被取消或已结束的任务保持原状态
+	if err := s.transitionTask(task, models.TaskStatusFailed, updates); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Error("Failed to escalate task")
+		return
+	}
+	s.setTaskStatus(task, models.TaskStatusFailed)
+
+	s.addTaskLog(id, models.LogLevelError, "Task escalated, needs attention", models.LogData{
+		"delay_count": delayCount,
+	})
+	s.publishDone(ctx, id, models.TaskStatusFailed, errorMsg)
+
+	s.logger.WithFields(logrus.Fields{
+		"task_id":     id,
+		"delay_count": delayCount,
+	}).Error("Task escalated, needs attention")
+
+	s.queueManager.OnTaskCompleted(ctx, id, models.TaskStatusFailed)
+}
+
+// ExpireTask 将超过截止时间、尚未开始执行的任务标记为取消，原因为 deadline exceeded；
+// 任务已开始执行或已结束时不做处理
+func (s *TaskService) ExpireTask(ctx context.Context, id uint64) error {
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		return err
+	}
+
+	update := s.db.Model(&models.Task{}).
+		Where("id = ? AND status = ?", id, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":               models.TaskStatusCancelled,
+			"error_message":        models.DeadlineExceededMessage,
+			"completed_at":         time.Now(),
+			"waiting_dependencies": false,
+		})
+	if update.Error != nil {
+		return fmt.Errorf("failed to expire task: %w", update.Error)
+	}
+	if update.RowsAffected == 0 {
+		return nil
+	}
+
+	s.afterTaskCancelled(ctx, task, "Task cancelled: "+models.DeadlineExceededMessage)
+	s.publishDone(ctx, id, models.TaskStatusCancelled, models.DeadlineExceededMessage)
+	s.logger.WithField("task_id", id).Info("Task cancelled, deadline exceeded")
+	return nil
+}
+
+// expireTask 队列丢弃过期任务后的回调
+func (s *TaskService) expireTask(ctx context.Context, id uint64) {
+	if err := s.ExpireTask(ctx, id); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Error("Failed to expire task")
+	}
+}
+
+// publishDone 发布任务结束事件，通知输出流订阅者
+func (s *TaskService) publishDone(ctx context.Context, id uint64, status models.TaskStatus, errorMsg string) {
+	event := &models.TaskStreamEvent{
+		TaskID:       id,
+		Event:        models.TaskStreamEventDone,
+		Status:       status,
+		ErrorMessage: errorMsg,
+	}
+	if err := s.queueManager.PublishTaskEvent(ctx, event); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to publish task done event")
+	}
+}
+
+// ResetTask 将执行中断的任务重置为 pending，等待重新入队
+func (s *TaskService) ResetTask(id uint64, reason string) error {
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.transitionTask(task, models.TaskStatusPending, map[string]interface{}{
+		"started_at": nil,
+	}); err != nil {
+		return fmt.Errorf("failed to reset task: %w", err)
+	}
+	s.setTaskStatus(task, models.TaskStatusPending)
+
+	s.addTaskLog(id, models.LogLevelWarn, reason, nil)
+	return nil
+}
+
+// FailTask 任务失败，任务已被取消或已结束时返回 ErrInvalidStatusTransition
 func (s *TaskService) FailTask(id uint64, errorMsg string) error {
+	task, err := s.loadTaskState(id)
+	if err != nil {
+		return err
+	}
+
 	updates := map[string]interface{}{
-		"status":        models.TaskStatusFailed,
 		"error_message": errorMsg,
 		"completed_at":  time.Now(),
 	}
 
-	if err := s.db.Model(&models.Task{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	// 已取消或已结束的任务保持原状态
+	if err := s.transitionTask(task, models.TaskStatusFailed, updates); err != nil {
 		return fmt.Errorf("failed to fail task: %w", err)
 	}
+	s.setTaskStatus(task, models.TaskStatusFailed)
 
 	s.addTaskLog(id, models.LogLevelError, "Task failed", map[string]interface{}{
 		"error": errorMsg,
@@ -305,6 +944,34 @@ func (s *TaskService) FailTask(id uint64, errorMsg string) error {
 	return nil
 }
 
+// SubscribeTaskStream 订阅任务输出流，返回事件通道和取消订阅函数
+func (s *TaskService) SubscribeTaskStream(ctx context.Context, id uint64) (<-chan *models.TaskStreamEvent, func(), error) {
+	pubsub, err := s.queueManager.SubscribeTaskEvents(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan *models.TaskStreamEvent)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event models.TaskStreamEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				s.logger.WithError(err).Warn("Failed to unmarshal task stream event")
+				continue
+			}
+
+			select {
+			case events <- &event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, func() { pubsub.Close() }, nil
+}
+
 // GetTaskStats 获取任务统计
 func (s *TaskService) GetTaskStats() (*models.TaskStats, error) {
 	var stats models.TaskStats
@@ -335,6 +1002,30 @@ func (s *TaskService) GetTaskStats() (*models.TaskStats, error) {
 	return &stats, nil
 }
 
+// GetRecentFailureRate 获取最近一段时间内结束任务的失败率（百分比）
+func (s *TaskService) GetRecentFailureRate(window time.Duration) (float64, error) {
+	since := time.Now().Add(-window)
+
+	var finished, failed int64
+	if err := s.db.Model(&models.Task{}).
+		Where("completed_at >= ? AND status IN ?", since,
+			[]models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusFailed}).
+		Count(&finished).Error; err != nil {
+		return 0, fmt.Errorf("failed to count finished tasks: %w", err)
+	}
+	if finished == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.Model(&models.Task{}).
+		Where("completed_at >= ? AND status = ?", since, models.TaskStatusFailed).
+		Count(&failed).Error; err != nil {
+		return 0, fmt.Errorf("failed to count failed tasks: %w", err)
+	}
+
+	return float64(failed) / float64(finished) * 100, nil
+}
+
 // addTaskLog 添加任务日志
 func (s *TaskService) addTaskLog(taskID uint64, level models.LogLevel, message string, data models.LogData) {
 	log := &models.TaskLog{
@@ -342,8 +1033,9 @@ func (s *TaskService) addTaskLog(taskID uint64, level models.LogLevel, message s
 		Level:   level,
 		Message: message,
 		Data:    data,
+		TraceID: s.taskTraceID(taskID),
 	}
-	
+
 	if err := s.db.Create(log).Error; err != nil {
 		s.logger.WithError(err).Error("Failed to create task log")
 	}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// interleaveUpdate 在下一次 UPDATE 执行前运行一次 change，模拟读取任务之后、写入之前另一个请求改变了任务
+func interleaveUpdate(t *testing.T, db *gorm.DB, change func(db *gorm.DB)) {
+	t.Helper()
+	var once sync.Once
+	name := "test:interleave"
+	if err := db.Callback().Update().Before("gorm:update").Register(name, func(tx *gorm.DB) {
+		once.Do(func() { change(db.Session(&gorm.Session{NewDB: true})) })
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	t.Cleanup(func() { db.Callback().Update().Remove(name) })
+}
+
+// setStatus 直接修改任务状态
+func setStatus(t *testing.T, db *gorm.DB, id uint64, status models.TaskStatus) {
+	t.Helper()
+	if err := db.Exec("UPDATE tasks SET status = ? WHERE id = ?", status, id).Error; err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+}
+
+func TestCompleteTaskAfterCancelKeepsCancelled(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning, nil)
+
+	if err := env.tasks.CancelTask(context.Background(), task.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	// 取消后返回的执行结果不覆盖取消状态
+	err := env.tasks.CompleteTask(task.ID, "late output", models.OutputFormatText, models.TokenUsage{})
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	err = env.tasks.FailTask(task.ID, "late failure")
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled {
+		t.Fatalf("expected cancelled, got %s", got.Status)
+	}
+	if got.Output != nil || got.ErrorMessage != nil {
+		t.Fatalf("expected no output or error written, got output %v error %v", got.Output, got.ErrorMessage)
+	}
+}
+
+func TestCancelTaskAfterCompleteFails(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning, nil)
+
+	if err := env.tasks.CompleteTask(task.ID, "done", models.OutputFormatText, models.TokenUsage{}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if err := env.tasks.CancelTask(context.Background(), task.ID); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusCompleted {
+		t.Fatalf("expected completed, got %s", got.Status)
+	}
+}
+
+func TestCancelTaskDoesNotOverwriteConcurrentCompletion(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusRunning, nil)
+
+	// 取消请求读到 running 之后任务已完成，取消不再覆盖完成状态
+	interleaveUpdate(t, env.db, func(db *gorm.DB) { setStatus(t, db, task.ID, models.TaskStatusCompleted) })
+	if err := env.tasks.CancelTask(context.Background(), task.ID); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusCompleted {
+		t.Fatalf("expected completed, got %s", got.Status)
+	}
+}
+
+func TestCompleteTaskDoesNotOverwriteConcurrentStateChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		concurrent models.TaskStatus
+	}{
+		{"cancelled", models.TaskStatusCancelled},
+		{"failed", models.TaskStatusFailed},
+		{"reset to pending", models.TaskStatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			task := env.createTask(t, models.TaskStatusRunning, nil)
+
+			// 完成前任务已被其他操作改变状态，执行结果不写入
+			interleaveUpdate(t, env.db, func(db *gorm.DB) { setStatus(t, db, task.ID, tt.concurrent) })
+			err := env.tasks.CompleteTask(task.ID, "done", models.OutputFormatText, models.TokenUsage{})
+			if !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+			}
+			got := env.reloadTask(t, task.ID)
+			if got.Status != tt.concurrent || got.Output != nil {
+				t.Fatalf("expected %s without output, got %s", tt.concurrent, got.Status)
+			}
+		})
+	}
+}
+
+func TestRetryTaskDoesNotResetConcurrentlyStartedTask(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusFailed, nil)
+
+	// 重试请求读到 failed 之后任务已被另一个重试请求重置并开始执行，不再被重置为 pending
+	interleaveUpdate(t, env.db, func(db *gorm.DB) { setStatus(t, db, task.ID, models.TaskStatusRunning) })
+	if err := env.tasks.RetryTask(context.Background(), task.ID); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusRunning {
+		t.Fatalf("expected running, got %s", got.Status)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 0 {
+		t.Fatalf("expected nothing queued, got %v", ids)
+	}
+}
+
+func TestCancelTaskRacesComplete(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		task := env.createTask(t, models.TaskStatusRunning, nil)
+
+		// 同时取消和完成，只有一个操作生效，最终状态与生效的操作一致
+		var cancelErr, completeErr error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cancelErr = env.tasks.CancelTask(ctx, task.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			completeErr = env.tasks.CompleteTask(task.ID, "done", models.OutputFormatText, models.TokenUsage{})
+		}()
+		wg.Wait()
+
+		got := env.reloadTask(t, task.ID)
+		switch {
+		case cancelErr == nil && completeErr == nil:
+			t.Fatalf("task %d: both cancel and complete succeeded, final status %s", task.ID, got.Status)
+		case cancelErr == nil:
+			if !errors.Is(completeErr, ErrInvalidStatusTransition) {
+				t.Fatalf("task %d: unexpected complete error: %v", task.ID, completeErr)
+			}
+			if got.Status != models.TaskStatusCancelled || got.Output != nil {
+				t.Fatalf("task %d: expected cancelled without output, got %s", task.ID, got.Status)
+			}
+		case completeErr == nil:
+			if !errors.Is(cancelErr, ErrInvalidStatusTransition) {
+				t.Fatalf("task %d: unexpected cancel error: %v", task.ID, cancelErr)
+			}
+			if got.Status != models.TaskStatusCompleted {
+				t.Fatalf("task %d: expected completed, got %s", task.ID, got.Status)
+			}
+		default:
+			t.Fatalf("task %d: both failed: cancel %v, complete %v", task.ID, cancelErr, completeErr)
+		}
+	}
+}
+
+func TestRetryTaskConcurrentRetriesQueueOnce(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.createTask(t, models.TaskStatusFailed, func(task *models.Task) { task.RetryCount = 1 })
+
+	// 同时重试同一个任务，只有一次生效，任务只入队一次
+	const retries = 5
+	errs := make([]error, retries)
+	var wg sync.WaitGroup
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = env.tasks.RetryTask(context.Background(), task.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Fatalf("unexpected retry error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one retry to succeed, got %d", succeeded)
+	}
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusPending || got.RetryCount != 2 {
+		t.Fatalf("expected pending with retry_count 2, got %s/%d", got.Status, got.RetryCount)
+	}
+	if ids := env.queuedTaskIDs(t); len(ids) != 1 {
+		t.Fatalf("expected task queued once, got %v", ids)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskWithTags(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	req := env.createRequest()
+	req.Tags = models.TaskTags{"project": "alpha", "customer.id": "c-42"}
+	task := env.mustCreate(t, req)
+
+	got := env.reloadTask(t, task.ID)
+	if len(got.Tags) != 2 || got.Tags["project"] != "alpha" || got.Tags["customer.id"] != "c-42" {
+		t.Fatalf("stored tags = %v, want project and customer.id", got.Tags)
+	}
+}
+
+func TestCreateTaskRejectsInvalidTags(t *testing.T) {
+	tooMany := models.TaskTags{}
+	for i := 0; i <= models.MaxTaskTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	tests := []struct {
+		name   string
+		tags   models.TaskTags
+		fields string
+	}{
+		{"invalid key", models.TaskTags{"bad key": "x", "ok": "y"}, "tags.bad key"},
+		{"long key", models.TaskTags{strings.Repeat("k", models.MaxTagKeyLength+1): "x"}, "tags." + strings.Repeat("k", models.MaxTagKeyLength+1)},
+		{"long value", models.TaskTags{"project": strings.Repeat("v", models.MaxTagValueLength+1)}, "tags.project"},
+		{"too many", tooMany, "tags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			req := env.createRequest()
+			req.Tags = tt.tags
+			_, err := env.tasks.CreateTask(context.Background(), req)
+			if got := strings.Join(fieldNames(t, err), ","); got != tt.fields {
+				t.Fatalf("invalid fields = %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestListTasksFiltersByTags(t *testing.T) {
+	env := newTestEnv(t, nil)
+	create := func(tags models.TaskTags) uint64 {
+		req := env.createRequest()
+		req.Tags = tags
+		return env.mustCreate(t, req).ID
+	}
+	alphaA := create(models.TaskTags{"project": "alpha", "customer.id": "a"})
+	alphaB := create(models.TaskTags{"project": "alpha", "customer.id": "b"})
+	beta := create(models.TaskTags{"project": "beta"})
+	create(nil)
+
+	tests := []struct {
+		name string
+		tags models.TaskTags
+		want []uint64
+	}{
+		{"single tag", models.TaskTags{"project": "alpha"}, []uint64{alphaB, alphaA}},
+		{"all tags must match", models.TaskTags{"project": "alpha", "customer.id": "b"}, []uint64{alphaB}},
+		{"other value", models.TaskTags{"project": "beta"}, []uint64{beta}},
+		{"no match", models.TaskTags{"project": "gamma"}, nil},
+		{"missing key", models.TaskTags{"team": "alpha"}, nil},
+		// 值按字符串参数绑定，不会改变查询
+		{"value is not sql", models.TaskTags{"project": "alpha' OR '1'='1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, total, err := env.tasks.ListTasks(&models.TaskListRequest{Page: 1, PageSize: 20, Tags: tt.tags})
+			if err != nil {
+				t.Fatalf("ListTasks: %v", err)
+			}
+			if total != int64(len(tt.want)) || len(tasks) != len(tt.want) {
+				t.Fatalf("got %d tasks (total %d), want %v", len(tasks), total, tt.want)
+			}
+			for i, task := range tasks {
+				if task.ID != tt.want[i] {
+					t.Fatalf("task %d = %d, want %v", i, task.ID, tt.want)
+				}
+			}
+		})
+	}
+}
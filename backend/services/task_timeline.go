@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// timelineLogLimit 时间线中最多包含的任务日志条数
+const timelineLogLimit = 500
+
+// timelineLogStages 按任务日志消息前缀识别的阶段，未列出的日志作为普通 log 事件
+var timelineLogStages = []struct {
+	prefix string
+	stage  string
+}{
+	{"Task created and enqueued", "enqueued"},
+	{"Dependencies completed, task enqueued", "enqueued"},
+	{"Task retried", "enqueued"},
+	{"Task recovered", "enqueued"},
+	{"Task execution started", "started"},
+	{"Pipeline step", "pipeline_step"},
+	{"Task completed successfully", "completed"},
+	{"Task failed", "failed"},
+	{"Task cancelled", "cancelled"},
+}
+
+// GetTaskTimeline 根据任务的时间字段和任务日志生成执行时间线，并计算排队和执行耗时
+func (s *TaskService) GetTaskTimeline(id uint64) (*models.TaskTimeline, error) {
+	var task models.Task
+	if err := s.db.First(&task, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	var logs []models.TaskLog
+	if err := s.db.Where("task_id = ?", id).
+		Order("created_at ASC, id ASC").
+		Limit(timelineLogLimit).
+		Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list task logs: %w", err)
+	}
+
+	return buildTaskTimeline(&task, logs, time.Now()), nil
+}
+
+// buildTaskTimeline 按时间排序事件并计算各阶段耗时，now 用于未结束阶段的耗时
+func buildTaskTimeline(task *models.Task, logs []models.TaskLog, now time.Time) *models.TaskTimeline {
+	events := []models.TaskTimelineEvent{{Time: task.CreatedAt, Stage: "created"}}
+	stages := make(map[string]bool)
+	for _, log := range logs {
+		stage := timelineLogStage(log.Message)
+		stages[stage] = true
+		events = append(events, models.TaskTimelineEvent{
+			Time:    log.CreatedAt,
+			Stage:   stage,
+			Message: log.Message,
+			Level:   log.Level,
+		})
+	}
+
+	// 日志缺失时用任务的时间字段补齐开始和结束事件
+	if task.StartedAt != nil && !stages["started"] {
+		events = append(events, models.TaskTimelineEvent{Time: *task.StartedAt, Stage: "started"})
+	}
+	if task.CompletedAt != nil && !stages[string(task.Status)] {
+		events = append(events, models.TaskTimelineEvent{Time: *task.CompletedAt, Stage: string(task.Status)})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	for i := 1; i < len(events); i++ {
+		events[i].SincePrevMs = events[i].Time.Sub(events[i-1].Time).Milliseconds()
+	}
+
+	end := now
+	if task.CompletedAt != nil {
+		end = *task.CompletedAt
+	}
+
+	// 重试会重置开始时间，排队耗时从开始执行前的最后一次入队算起
+	enqueuedAt := task.CreatedAt
+	for _, event := range events {
+		if event.Stage != "enqueued" {
+			continue
+		}
+		if task.StartedAt != nil && event.Time.After(*task.StartedAt) {
+			break
+		}
+		enqueuedAt = event.Time
+	}
+
+	timeline := &models.TaskTimeline{
+		TaskID:     task.ID,
+		Status:     task.Status,
+		InProgress: !task.IsCompleted(),
+		Events:     events,
+		TotalMs:    end.Sub(task.CreatedAt).Milliseconds(),
+	}
+	if task.StartedAt != nil {
+		timeline.QueueWaitMs = task.StartedAt.Sub(enqueuedAt).Milliseconds()
+		execution := end.Sub(*task.StartedAt).Milliseconds()
+		timeline.ExecutionMs = &execution
+	} else {
+		timeline.QueueWaitMs = end.Sub(enqueuedAt).Milliseconds()
+	}
+	return timeline
+}
+
+func timelineLogStage(message string) string {
+	for _, s := range timelineLogStages {
+		if strings.HasPrefix(message, s.prefix) {
+			return s.stage
+		}
+	}
+	return "log"
+}
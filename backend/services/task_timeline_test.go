@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestBuildTaskTimeline(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return created.Add(time.Duration(ms) * time.Millisecond) }
+	ptr := func(ms int) *time.Time { v := at(ms); return &v }
+	log := func(ms int, message string) models.TaskLog {
+		return models.TaskLog{Level: models.LogLevelInfo, Message: message, CreatedAt: at(ms)}
+	}
+
+	tests := []struct {
+		name          string
+		task          models.Task
+		logs          []models.TaskLog
+		now           int
+		wantStages    []string
+		wantSince     []int64
+		wantQueueWait int64
+		wantExecution int64 // -1 表示从未开始执行
+		wantTotal     int64
+		inProgress    bool
+	}{
+		{
+			name: "completed task",
+			task: models.Task{Status: models.TaskStatusCompleted, StartedAt: ptr(2000), CompletedAt: ptr(5000)},
+			logs: []models.TaskLog{
+				log(100, "Task created and enqueued"),
+				log(2000, "Task execution started"),
+				log(3000, "Calling model"),
+				log(5000, "Task completed successfully"),
+			},
+			now:           9000,
+			wantStages:    []string{"created", "enqueued", "started", "log", "completed"},
+			wantSince:     []int64{0, 100, 1900, 1000, 2000},
+			wantQueueWait: 1900,
+			wantExecution: 3000,
+			wantTotal:     5000,
+		},
+		{
+			// 排队中的任务到当前时间为止都计入排队耗时
+			name:          "pending task",
+			task:          models.Task{Status: models.TaskStatusPending},
+			logs:          []models.TaskLog{log(100, "Task created and enqueued")},
+			now:           3000,
+			wantStages:    []string{"created", "enqueued"},
+			wantSince:     []int64{0, 100},
+			wantQueueWait: 2900,
+			wantExecution: -1,
+			wantTotal:     3000,
+			inProgress:    true,
+		},
+		{
+			// 执行中的任务执行耗时算到当前时间
+			name:          "running task",
+			task:          models.Task{Status: models.TaskStatusRunning, StartedAt: ptr(1000)},
+			logs:          []models.TaskLog{log(0, "Task created and enqueued"), log(1000, "Task execution started")},
+			now:           4000,
+			wantStages:    []string{"created", "enqueued", "started"},
+			wantSince:     []int64{0, 0, 1000},
+			wantQueueWait: 1000,
+			wantExecution: 3000,
+			wantTotal:     4000,
+			inProgress:    true,
+		},
+		{
+			// 重试后排队耗时从最后一次入队算起，执行耗时从最后一次开始算起
+			name: "retried task",
+			task: models.Task{Status: models.TaskStatusCompleted, StartedAt: ptr(5000), CompletedAt: ptr(6000)},
+			logs: []models.TaskLog{
+				log(0, "Task created and enqueued"),
+				log(1000, "Task execution started"),
+				log(2000, "Task failed: model unavailable"),
+				log(3000, "Task retried"),
+				log(5000, "Task execution started"),
+				log(6000, "Task completed successfully"),
+			},
+			now:           9000,
+			wantStages:    []string{"created", "enqueued", "started", "failed", "enqueued", "started", "completed"},
+			wantSince:     []int64{0, 0, 1000, 1000, 1000, 2000, 1000},
+			wantQueueWait: 2000,
+			wantExecution: 1000,
+			wantTotal:     6000,
+		},
+		{
+			// 日志缺失时用任务的时间字段补齐开始和结束事件
+			name:          "missing logs",
+			task:          models.Task{Status: models.TaskStatusFailed, StartedAt: ptr(500), CompletedAt: ptr(1500)},
+			now:           9000,
+			wantStages:    []string{"created", "started", "failed"},
+			wantSince:     []int64{0, 500, 1000},
+			wantQueueWait: 500,
+			wantExecution: 1000,
+			wantTotal:     1500,
+		},
+		{
+			// 排队期间被取消的任务没有执行耗时
+			name:          "cancelled while queued",
+			task:          models.Task{Status: models.TaskStatusCancelled, CompletedAt: ptr(2500)},
+			logs:          []models.TaskLog{log(0, "Task created and enqueued"), log(2500, "Task cancelled by user")},
+			now:           9000,
+			wantStages:    []string{"created", "enqueued", "cancelled"},
+			wantSince:     []int64{0, 0, 2500},
+			wantQueueWait: 2500,
+			wantExecution: -1,
+			wantTotal:     2500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.ID = 7
+			task.CreatedAt = created
+			timeline := buildTaskTimeline(&task, tt.logs, at(tt.now))
+
+			if len(timeline.Events) != len(tt.wantStages) {
+				t.Fatalf("events = %+v, want stages %v", timeline.Events, tt.wantStages)
+			}
+			for i, event := range timeline.Events {
+				if event.Stage != tt.wantStages[i] || event.SincePrevMs != tt.wantSince[i] {
+					t.Fatalf("event %d = %s (+%dms), want %s (+%dms)", i, event.Stage, event.SincePrevMs, tt.wantStages[i], tt.wantSince[i])
+				}
+			}
+			if timeline.TaskID != 7 || timeline.Status != task.Status || timeline.InProgress != tt.inProgress {
+				t.Fatalf("timeline = %+v, want task 7 %s in progress %v", timeline, task.Status, tt.inProgress)
+			}
+			if timeline.QueueWaitMs != tt.wantQueueWait {
+				t.Fatalf("queue wait = %dms, want %dms", timeline.QueueWaitMs, tt.wantQueueWait)
+			}
+			if tt.wantExecution < 0 {
+				if timeline.ExecutionMs != nil {
+					t.Fatalf("execution = %dms, want none", *timeline.ExecutionMs)
+				}
+			} else if timeline.ExecutionMs == nil || *timeline.ExecutionMs != tt.wantExecution {
+				t.Fatalf("execution = %v, want %dms", timeline.ExecutionMs, tt.wantExecution)
+			}
+			if timeline.TotalMs != tt.wantTotal {
+				t.Fatalf("total = %dms, want %dms", timeline.TotalMs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestGetTaskTimeline(t *testing.T) {
+	env := newTestEnv(t, nil)
+	task := env.mustCreate(t, env.createRequest())
+	if err := env.tasks.StartTask(task.ID); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := env.tasks.CompleteTask(task.ID, "done", models.OutputFormatText, models.TokenUsage{}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	// 时间线由服务写入的任务日志组成，阶段按执行顺序排列
+	timeline, err := env.tasks.GetTaskTimeline(task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskTimeline: %v", err)
+	}
+	var stages []string
+	for _, event := range timeline.Events {
+		if event.Stage != "log" {
+			stages = append(stages, event.Stage)
+		}
+	}
+	want := []string{"created", "enqueued", "started", "completed"}
+	if len(stages) != len(want) {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Fatalf("stages = %v, want %v", stages, want)
+		}
+	}
+	if timeline.InProgress || timeline.ExecutionMs == nil || timeline.QueueWaitMs < 0 {
+		t.Fatalf("timeline = %+v, want finished task with execution time", timeline)
+	}
+
+	if _, err := env.tasks.GetTaskTimeline(task.ID + 100); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("missing task error = %v, want ErrTaskNotFound", err)
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+// traceIDFrom 获取创建任务的请求关联 ID，不是由 HTTP 请求创建（如定时任务）时生成新的 ID
+func traceIDFrom(ctx context.Context) string {
+	if traceID := utils.TraceIDFromContext(ctx); traceID != "" {
+		return traceID
+	}
+	return utils.NewTraceID()
+}
+
+// taskTraceID 查询任务的关联 ID，写入任务日志；查询失败时返回空字符串，不影响日志写入
+func (s *TaskService) taskTraceID(taskID uint64) string {
+	var traceIDs []string
+	if err := s.db.Model(&models.Task{}).Where("id = ?", taskID).Limit(1).Pluck("trace_id", &traceIDs).Error; err != nil {
+		return ""
+	}
+	if len(traceIDs) == 0 {
+		return ""
+	}
+	return traceIDs[0]
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+func TestTraceIDFollowsTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel func(env *testEnv, ctx context.Context, task *models.Task) error
+	}{
+		{"cancel", func(env *testEnv, ctx context.Context, task *models.Task) error {
+			return env.tasks.CancelTask(ctx, task.ID)
+		}},
+		{"bulk cancel", func(env *testEnv, ctx context.Context, task *models.Task) error {
+			_, err := env.tasks.BulkCancelTasks(ctx, &models.TaskListRequest{ModelID: &env.modelID})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := utils.WithTraceID(context.Background(), "req-trace-1")
+
+			task, err := env.tasks.CreateTask(ctx, env.createRequest())
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+			if task.TraceID != "req-trace-1" {
+				t.Fatalf("task trace id = %q, want req-trace-1", task.TraceID)
+			}
+
+			// 队列项携带同一个 ID，Worker 领取后写入日志
+			items := env.queuedItems(t)
+			if len(items) != 1 || items[0].TraceID != "req-trace-1" {
+				t.Fatalf("queued items = %+v, want trace id req-trace-1", items)
+			}
+
+			pubsub, err := env.queue.SubscribeTaskStatusEvents(context.Background())
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			defer pubsub.Close()
+
+			// 取消任务的请求带有另一个 ID，事件和日志仍使用创建任务的 ID
+			if err := tt.cancel(env, utils.WithTraceID(context.Background(), "req-trace-2"), task); err != nil {
+				t.Fatalf("cancel task: %v", err)
+			}
+			events := receiveTaskEvents(t, pubsub, 200*time.Millisecond)
+			if len(events) != 1 || events[0].TraceID != "req-trace-1" {
+				t.Fatalf("events = %+v, want one event with trace id req-trace-1", events)
+			}
+
+			logs, _, err := env.tasks.ListTaskLogs(task.ID, &models.TaskLogListRequest{Page: 1, PageSize: 50})
+			if err != nil {
+				t.Fatalf("list logs: %v", err)
+			}
+			if len(logs) < 2 {
+				t.Fatalf("got %d logs, want creation and cancellation logs", len(logs))
+			}
+			for _, log := range logs {
+				if log.TraceID != "req-trace-1" {
+					t.Fatalf("log %q trace id = %q, want req-trace-1", log.Message, log.TraceID)
+				}
+			}
+		})
+	}
+}
+
+func TestTraceIDGeneratedWithoutRequest(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	// 不是由 HTTP 请求创建的任务（如定时任务）生成新的 ID，每个任务不同
+	first := env.mustCreate(t, env.createRequest())
+	second := env.mustCreate(t, env.createRequest())
+	if len(first.TraceID) != 32 || len(second.TraceID) != 32 || first.TraceID == second.TraceID {
+		t.Fatalf("trace ids = %q, %q, want two distinct generated ids", first.TraceID, second.TraceID)
+	}
+	if got := env.reloadTask(t, first.ID); got.TraceID != first.TraceID {
+		t.Fatalf("stored trace id = %q, want %q", got.TraceID, first.TraceID)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestCreateTaskTypeAllowlist(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowed      []string
+		defaultType  string
+		customModel  bool
+		taskType     string
+		wantType     string
+		wantRejected bool
+	}{
+		{"builtin type", nil, "", false, models.TaskTypeSummarization, models.TaskTypeSummarization, false},
+		// 拼写错误的类型不再被当作自定义任务执行
+		{"typo rejected", nil, "", false, "text-generaton", "", true},
+		{"listed custom type", []string{"text-generation", "sentiment"}, "", false, "sentiment", "sentiment", false},
+		// 配置了允许列表后，不在列表中的内置类型同样被拒绝
+		{"unlisted builtin type", []string{"sentiment"}, "", false, models.TaskTypeTextGeneration, "", true},
+		{"model allows custom types", nil, "", true, "sentiment", "sentiment", false},
+		{"default type", nil, models.TaskTypeSummarization, false, "", models.TaskTypeSummarization, false},
+		{"no type and no default", nil, "", false, "", "", true},
+		// 默认类型同样要通过允许列表校验
+		{"default type not allowed", []string{"sentiment"}, models.TaskTypeSummarization, false, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.Models.AllowedTaskTypes = tt.allowed
+				cfg.Models.DefaultTaskType = tt.defaultType
+			})
+			if tt.customModel {
+				if err := env.db.Model(&models.Model{ID: env.modelID}).Update("config", models.ModelConfig{"allow_custom_types": true}).Error; err != nil {
+					t.Fatalf("update model: %v", err)
+				}
+			}
+			req := env.createRequest()
+			req.Type = tt.taskType
+
+			task, err := env.tasks.CreateTask(context.Background(), req)
+			if tt.wantRejected {
+				if fields := fieldNames(t, err); len(fields) != 1 || fields[0] != "type" {
+					t.Fatalf("invalid fields = %v (err %v), want [type]", fields, err)
+				}
+				if n := env.countTasks(t); n != 0 {
+					t.Fatalf("created %d tasks, want 0", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+			if got := env.reloadTask(t, task.ID); got.Type != tt.wantType {
+				t.Fatalf("type = %q, want %q", got.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestCreateTasksTypeAllowlist(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Models.DefaultTaskType = models.TaskTypeSummarization })
+	valid := env.createRequest()
+	defaulted := env.createRequest()
+	defaulted.Type = ""
+	unknown := env.createRequest()
+	unknown.Type = "text-generaton"
+
+	// 批量创建时未知类型只影响对应条目，未指定类型的条目使用默认类型
+	results, err := env.tasks.CreateTasks(context.Background(), []*models.TaskCreateRequest{valid, defaulted, unknown})
+	if err != nil {
+		t.Fatalf("CreateTasks: %v", err)
+	}
+	if results[0].TaskID == 0 || results[1].TaskID == 0 {
+		t.Fatalf("results = %+v, want first two created", results)
+	}
+	if got := env.reloadTask(t, results[1].TaskID); got.Type != models.TaskTypeSummarization {
+		t.Fatalf("defaulted type = %q, want %q", got.Type, models.TaskTypeSummarization)
+	}
+	if r := results[2]; r.TaskID != 0 || len(r.Errors) != 1 || r.Errors[0].Field != "type" {
+		t.Fatalf("unknown type result = %+v, want a type field error", r)
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+// TaskValidator 任务输入校验器，返回的每一项对应一个字段错误
+type TaskValidator func(req *models.TaskCreateRequest) []models.FieldError
+
+// ValidationError 任务参数校验失败
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// taskValidators 按任务类型注册的校验器
+type taskValidators struct {
+	mu         sync.RWMutex
+	validators map[string]TaskValidator
+}
+
+// RegisterTaskValidator 注册任务类型的输入校验器，同一类型重复注册会覆盖之前的校验器
+func (s *TaskService) RegisterTaskValidator(taskType string, validator TaskValidator) {
+	s.validators.mu.Lock()
+	defer s.validators.mu.Unlock()
+	s.validators.validators[taskType] = validator
+}
+
+// ValidateTaskRequest 按任务类型校验创建请求，未注册校验器的类型只做通用校验
+func (s *TaskService) ValidateTaskRequest(req *models.TaskCreateRequest) error {
+	s.validators.mu.RLock()
+	validator, exists := s.validators.validators[req.Type]
+	s.validators.mu.RUnlock()
+
+	var fields []models.FieldError
+	if req.Type == "" {
+		fields = append(fields, models.FieldError{Field: "type", Message: "type is required"})
+	}
+	fields = append(fields, validateProviderOverride(req.ProviderOverride)...)
+	fields = append(fields, validateTags(req.Tags)...)
+	if max := s.config.Queue.MaxInputBytes; max > 0 && len(req.Input) > max {
+		fields = append(fields, models.FieldError{
+			Field:   "input",
+			Message: fmt.Sprintf("input must not exceed %d bytes", max),
+		})
+	}
+	if len(req.DedupKey) > models.MaxDedupKeyLength {
+		fields = append(fields, models.FieldError{
+			Field:   "dedup_key",
+			Message: fmt.Sprintf("dedup_key must not exceed %d bytes", models.MaxDedupKeyLength),
+		})
+	}
+	if req.MaxRetries != nil && *req.MaxRetries < 0 {
+		fields = append(fields, models.FieldError{Field: "max_retries", Message: "max_retries must not be negative"})
+	}
+	now := time.Now()
+	fields = append(fields, validateDeadline(req, now)...)
+	fields = append(fields, validateRunAt(req, now)...)
+	if exists {
+		fields = append(fields, validator(req)...)
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validateDeadline 校验截止时间：deadline 与 ttl_seconds 不能同时设置，且都必须在未来
+func validateDeadline(req *models.TaskCreateRequest, now time.Time) []models.FieldError {
+	var fields []models.FieldError
+	if req.Deadline != nil && req.TTLSeconds != nil {
+		fields = append(fields, models.FieldError{Field: "ttl_seconds", Message: "deadline and ttl_seconds are mutually exclusive"})
+	}
+	if req.Deadline != nil && !req.Deadline.After(now) {
+		fields = append(fields, models.FieldError{Field: "deadline", Message: "deadline must be in the future"})
+	}
+	if req.TTLSeconds != nil && *req.TTLSeconds <= 0 {
+		fields = append(fields, models.FieldError{Field: "ttl_seconds", Message: "ttl_seconds must be positive"})
+	}
+	return fields
+}
+
+// validateRunAt run_at 必须晚于当前时间，且早于 deadline（或 ttl_seconds 换算出的截止时间），否则任务到期时已被取消
+func validateRunAt(req *models.TaskCreateRequest, now time.Time) []models.FieldError {
+	if req.RunAt == nil {
+		return nil
+	}
+	if !req.RunAt.After(now) {
+		return []models.FieldError{{Field: "run_at", Message: "run_at must be in the future"}}
+	}
+
+	deadline := req.Deadline
+	if deadline == nil && req.TTLSeconds != nil {
+		expiresAt := now.Add(time.Duration(*req.TTLSeconds) * time.Second)
+		deadline = &expiresAt
+	}
+	if deadline != nil && !req.RunAt.Before(*deadline) {
+		return []models.FieldError{{Field: "run_at", Message: "run_at must be before the deadline"}}
+	}
+	return nil
+}
+
+// applyDefaultTaskType 请求未指定 type 时使用 models.default_task_type
+func (s *TaskService) applyDefaultTaskType(req *models.TaskCreateRequest) {
+	if req.Type == "" {
+		req.Type = s.config.Models.DefaultTaskType
+	}
+}
+
+// validateTaskType 任务类型必须在 models.allowed_task_types（未配置时为内置类型）中，
+// 模型配置了 allow_custom_types 时不受限制
+func (s *TaskService) validateTaskType(model *models.Model, taskType string) error {
+	if model.AllowsCustomTaskTypes() {
+		return nil
+	}
+
+	allowed := s.config.Models.AllowedTaskTypes
+	if len(allowed) == 0 {
+		allowed = models.BuiltinTaskTypes
+	}
+	for _, t := range allowed {
+		if t == taskType {
+			return nil
+		}
+	}
+	return &ValidationError{Fields: []models.FieldError{{
+		Field:   "type",
+		Message: fmt.Sprintf("unsupported task type %q, allowed types: %s", taskType, strings.Join(allowed, ", ")),
+	}}}
+}
+
+// validateInputSchema 模型配置了 input_schema 时，任务输入必须是符合该 schema 的 JSON
+func validateInputSchema(model *models.Model, input string) error {
+	schema, ok := model.GetInputSchema()
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return &ValidationError{Fields: []models.FieldError{{
+			Field:   "input",
+			Message: fmt.Sprintf("input must be valid JSON for model %s: %v", model.Name, err),
+		}}}
+	}
+
+	schemaErrs := utils.ValidateJSONSchema(schema, value)
+	if len(schemaErrs) == 0 {
+		return nil
+	}
+	fields := make([]models.FieldError, 0, len(schemaErrs))
+	for _, schemaErr := range schemaErrs {
+		field := "input"
+		if schemaErr.Path != "" {
+			field += "." + schemaErr.Path
+		}
+		fields = append(fields, models.FieldError{Field: field, Message: schemaErr.Message})
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// validateModelConfig 校验模型配置中的 input_schema 和 prompt_template 本身是否合法
+func validateModelConfig(config models.ModelConfig) error {
+	var fields []models.FieldError
+	if schema, exists := config["input_schema"]; exists {
+		if err := utils.CheckJSONSchema(schema); err != nil {
+			fields = append(fields, models.FieldError{Field: "config.input_schema", Message: err.Error()})
+		}
+	}
+	if _, exists := config["prompt_template"]; exists {
+		model := models.Model{Config: config}
+		if _, err := model.GetPromptTemplate(); err != nil {
+			fields = append(fields, models.FieldError{Field: "config.prompt_template", Message: err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// registerBuiltinValidators 注册内置任务类型的校验器
+func (s *TaskService) registerBuiltinValidators() {
+	s.RegisterTaskValidator(models.TaskTypeTextGeneration, validateNonEmptyInput)
+	s.RegisterTaskValidator(models.TaskTypeSummarization, validateNonEmptyInput)
+	s.RegisterTaskValidator(models.TaskTypeEmbedding, validateNonEmptyInput)
+	s.RegisterTaskValidator(models.TaskTypeTranslation, validateTranslation)
+	s.RegisterTaskValidator(models.TaskTypePipeline, validatePipeline)
+}
+
+// validateRequiredFields 校验创建请求的必填字段，批量创建时逐条使用
+func validateRequiredFields(req *models.TaskCreateRequest) []models.FieldError {
+	// model_id 可省略，未配置默认模型时由 buildTask 报错
+	var errs []models.FieldError
+	if req.Type == "" {
+		errs = append(errs, models.FieldError{Field: "type", Message: "type is required"})
+	}
+	if req.Input == "" {
+		errs = append(errs, models.FieldError{Field: "input", Message: "input is required"})
+	}
+	return errs
+}
+
+// validateProviderOverride 校验任务级模型服务地址覆盖
+func validateProviderOverride(override *models.ProviderOverride) []models.FieldError {
+	if override == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(override.BaseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return []models.FieldError{{
+			Field:   "provider_override.base_url",
+			Message: "base_url must be an absolute http(s) URL",
+		}}
+	}
+
+	var errs []models.FieldError
+	for name := range override.Headers {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, models.FieldError{
+				Field:   "provider_override.headers",
+				Message: "header name must not be blank",
+			})
+			break
+		}
+	}
+	return errs
+}
+
+// validateTags 校验任务标签的数量、键名和值长度
+func validateTags(tags models.TaskTags) []models.FieldError {
+	if len(tags) > models.MaxTaskTags {
+		return []models.FieldError{{
+			Field:   "tags",
+			Message: fmt.Sprintf("tags must not exceed %d entries", models.MaxTaskTags),
+		}}
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []models.FieldError
+	for _, key := range keys {
+		if !models.IsValidTagKey(key) {
+			errs = append(errs, models.FieldError{
+				Field:   "tags." + key,
+				Message: fmt.Sprintf("tag key must be 1-%d letters, digits, '_', '-' or '.'", models.MaxTagKeyLength),
+			})
+			continue
+		}
+		if len(tags[key]) > models.MaxTagValueLength {
+			errs = append(errs, models.FieldError{
+				Field:   "tags." + key,
+				Message: fmt.Sprintf("tag value must not exceed %d bytes", models.MaxTagValueLength),
+			})
+		}
+	}
+	return errs
+}
+
+// validateNonEmptyInput 输入不能为空白
+func validateNonEmptyInput(req *models.TaskCreateRequest) []models.FieldError {
+	if strings.TrimSpace(req.Input) == "" {
+		return []models.FieldError{{Field: "input", Message: "input must not be blank"}}
+	}
+	return nil
+}
+
+// validateTranslation 翻译任务需要非空输入和目标语言
+func validateTranslation(req *models.TaskCreateRequest) []models.FieldError {
+	errs := validateNonEmptyInput(req)
+
+	targetLanguage, ok := req.Params.GetString("target_language")
+	if !ok || strings.TrimSpace(targetLanguage) == "" {
+		errs = append(errs, models.FieldError{
+			Field:   "params.target_language",
+			Message: "target_language is required for translation tasks",
+		})
+	}
+
+	return errs
+}
+
+// validatePipeline 流水线任务的输入必须是合法的步骤定义，步骤不能嵌套流水线，翻译步骤需要目标语言
+func validatePipeline(req *models.TaskCreateRequest) []models.FieldError {
+	def, err := models.ParsePipeline(req.Input)
+	if err != nil {
+		return []models.FieldError{{Field: "input", Message: err.Error()}}
+	}
+
+	var errs []models.FieldError
+	if strings.TrimSpace(def.Input) == "" {
+		errs = append(errs, models.FieldError{Field: "input.input", Message: "pipeline input must not be blank"})
+	}
+	if len(def.Steps) == 0 {
+		errs = append(errs, models.FieldError{Field: "input.steps", Message: "pipeline must have at least one step"})
+	}
+	if len(def.Steps) > models.MaxPipelineSteps {
+		errs = append(errs, models.FieldError{
+			Field:   "input.steps",
+			Message: fmt.Sprintf("pipeline must not have more than %d steps", models.MaxPipelineSteps),
+		})
+	}
+
+	for i, step := range def.Steps {
+		field := fmt.Sprintf("input.steps[%d]", i)
+		switch step.Type {
+		case "":
+			errs = append(errs, models.FieldError{Field: field + ".type", Message: "step type is required"})
+		case models.TaskTypePipeline:
+			errs = append(errs, models.FieldError{Field: field + ".type", Message: "pipeline steps must not be pipelines"})
+		case models.TaskTypeTranslation:
+			targetLanguage, ok := step.Params.GetString("target_language")
+			if !ok || strings.TrimSpace(targetLanguage) == "" {
+				errs = append(errs, models.FieldError{
+					Field:   field + ".params.target_language",
+					Message: "target_language is required for translation steps",
+				})
+			}
+		}
+	}
+	return errs
+}
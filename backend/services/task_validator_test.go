@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// fieldNames 返回校验错误中的字段名，err 不是 ValidationError 时终止测试
+func fieldNames(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	names := make([]string, 0, len(validationErr.Fields))
+	for _, field := range validationErr.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+func TestValidateTaskRequestPerType(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	tests := []struct {
+		name   string
+		req    models.TaskCreateRequest
+		fields string
+	}{
+		{"text generation", models.TaskCreateRequest{Type: models.TaskTypeTextGeneration, Input: "hello"}, ""},
+		{"blank text generation", models.TaskCreateRequest{Type: models.TaskTypeTextGeneration, Input: "  \n"}, "input"},
+		{"blank embedding", models.TaskCreateRequest{Type: models.TaskTypeEmbedding, Input: " "}, "input"},
+		{"translation without target", models.TaskCreateRequest{Type: models.TaskTypeTranslation, Input: "hello"}, "params.target_language"},
+		{"translation with blank target", models.TaskCreateRequest{
+			Type: models.TaskTypeTranslation, Input: "hello", Params: models.TaskParams{"target_language": " "},
+		}, "params.target_language"},
+		{"translation", models.TaskCreateRequest{
+			Type: models.TaskTypeTranslation, Input: "hello", Params: models.TaskParams{"target_language": "fr"},
+		}, ""},
+		{"blank translation without target", models.TaskCreateRequest{Type: models.TaskTypeTranslation, Input: ""}, "input,params.target_language"},
+		{"missing type", models.TaskCreateRequest{Input: "hello"}, "type"},
+		{"type without validator", models.TaskCreateRequest{Type: "custom", Input: " "}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			got := strings.Join(fieldNames(t, env.tasks.ValidateTaskRequest(&req)), ",")
+			if got != tt.fields {
+				t.Fatalf("invalid fields = %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestRegisterTaskValidatorReplacesBuiltin(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.tasks.RegisterTaskValidator(models.TaskTypeTextGeneration, func(req *models.TaskCreateRequest) []models.FieldError {
+		if len(req.Input) < 5 {
+			return []models.FieldError{{Field: "input", Message: "too short"}}
+		}
+		return nil
+	})
+
+	// 注册的校验器替换内置校验器：空白但足够长的输入不再被拒绝，短输入被拒绝
+	if err := env.tasks.ValidateTaskRequest(&models.TaskCreateRequest{Type: models.TaskTypeTextGeneration, Input: "      "}); err != nil {
+		t.Fatalf("expected custom validator to accept input, got %v", err)
+	}
+	_, err := env.tasks.CreateTask(context.Background(), &models.TaskCreateRequest{
+		ModelID: env.modelID, Type: models.TaskTypeTextGeneration, Input: "hi",
+	})
+	if names := fieldNames(t, err); len(names) != 1 || names[0] != "input" {
+		t.Fatalf("expected input validation error, got %v", err)
+	}
+
+	var count int64
+	env.db.Model(&models.Task{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no task created, got %d", count)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localScheme 本地磁盘存储的 URI 前缀，后接对象键
+const localScheme = "local://"
+
+// LocalStorage 本地磁盘存储，对象键映射为 dir 下的相对路径
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage 创建本地磁盘存储，目录不存在时自动创建
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage dir: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %w", err)
+	}
+	return &LocalStorage{dir: abs}, nil
+}
+
+// Put 先写临时文件再重命名，读取方不会看到写了一半的对象
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create object dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	return localScheme + key, nil
+}
+
+// Get 读取 local:// URI 对应的文件
+func (s *LocalStorage) Get(ctx context.Context, uri string) ([]byte, error) {
+	key, ok := strings.CutPrefix(uri, localScheme)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURI, uri)
+	}
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// path 将对象键转换为文件路径，拒绝指向存储目录之外的键
+func (s *LocalStorage) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return path, nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"llm-scheduler/config"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "us-east-1"
+)
+
+// fakeS3 按路径风格地址保存对象的 S3 桩服务，校验每个请求的 SigV4 签名
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	paths   []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := verifySignature(r, body); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.EscapedPath())
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// requestPaths 返回已通过签名校验的请求路径
+func (f *fakeS3) requestPaths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.paths...)
+}
+
+// objectCount 返回保存的对象数
+func (f *fakeS3) objectCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.objects)
+}
+
+// verifySignature 按服务端收到的请求重新计算 SigV4 签名并与 Authorization 头比较
+func verifySignature(r *http.Request, body []byte) error {
+	payloadHash := hex.EncodeToString(sha256Sum(body))
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return errors.New("payload hash mismatch")
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return errors.New("missing x-amz-date")
+	}
+	date := amzDate[:8]
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+
+	canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		payloadHash
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sha256Sum([]byte(canonical)))
+
+	key := hmacSum([]byte("AWS4"+testSecretKey), date)
+	for _, part := range []string{testRegion, "s3", "aws4_request"} {
+		key = hmacSum(key, part)
+	}
+	want := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		testAccessKey, scope, hex.EncodeToString(hmacSum(key, stringToSign)))
+	if r.Header.Get("Authorization") != want {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func hmacSum(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// newTestS3Storage 创建连接桩服务的 S3 存储，endpoint 可带路径前缀
+func newTestS3Storage(endpoint, secret string) *S3Storage {
+	return NewS3Storage(&config.S3StorageConfig{
+		Endpoint:        endpoint,
+		Region:          testRegion,
+		Bucket:          "outputs",
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: secret,
+	})
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		wantPath string
+	}{
+		{"plain key", "tasks/42/output.txt", "/outputs/tasks/42/output.txt"},
+		// 空格和 = 等字符按 SigV4 规则编码，签名与服务端看到的路径一致
+		{"escaped key", "tasks/a b=c/output.txt", "/outputs/tasks/a%20b%3Dc/output.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := newFakeS3(t)
+			store := newTestS3Storage(server.URL+"/", testSecretKey)
+			ctx := context.Background()
+
+			data := []byte(strings.Repeat("large output ", 1000))
+			uri, err := store.Put(ctx, tt.key, data)
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if uri != "s3://outputs/"+tt.key {
+				t.Fatalf("uri = %q, want s3://outputs/%s", uri, tt.key)
+			}
+
+			got, err := store.Get(ctx, uri)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if string(got) != string(data) {
+				t.Fatalf("round trip returned %d bytes, want %d", len(got), len(data))
+			}
+			if paths := fake.requestPaths(); len(paths) != 2 || paths[0] != tt.wantPath {
+				t.Fatalf("request paths = %v, want %s", paths, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	fake, server := newFakeS3(t)
+	ctx := context.Background()
+
+	// 不存在的对象返回包含状态码的错误
+	store := newTestS3Storage(server.URL, testSecretKey)
+	if _, err := store.Get(ctx, "s3://outputs/tasks/missing.txt"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("Get missing error = %v, want status 404", err)
+	}
+
+	// 其他 bucket 或其他后端的 URI 不发送请求
+	requests := len(fake.requestPaths())
+	for _, uri := range []string{"s3://other-bucket/tasks/1/output.txt", "local://tasks/1/output.txt"} {
+		if _, err := store.Get(ctx, uri); !errors.Is(err, ErrUnsupportedURI) {
+			t.Fatalf("Get(%q) error = %v, want ErrUnsupportedURI", uri, err)
+		}
+	}
+	if len(fake.requestPaths()) != requests {
+		t.Fatalf("unsupported uri sent a request")
+	}
+
+	// 密钥错误时签名校验失败，Put 返回错误
+	wrong := newTestS3Storage(server.URL, "wrong-secret")
+	if _, err := wrong.Put(ctx, "tasks/1/output.txt", []byte("x")); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with wrong secret error = %v, want signature error", err)
+	}
+	if fake.objectCount() != 0 {
+		t.Fatalf("object stored despite bad signature")
+	}
+
+	// 服务不可用时返回请求错误
+	server.Close()
+	if _, err := store.Put(ctx, "tasks/1/output.txt", []byte("x")); err == nil {
+		t.Fatalf("expected error when s3 is unreachable")
+	}
+}
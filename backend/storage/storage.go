@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"llm-scheduler/config"
+)
+
+// ErrUnsupportedURI URI 不属于当前存储后端，通常是切换 storage.backend 之前写入的对象
+var ErrUnsupportedURI = errors.New("unsupported storage uri")
+
+// OutputStorage 任务输出外部存储
+type OutputStorage interface {
+	// Put 保存对象，返回写入数据库的引用 URI
+	Put(ctx context.Context, key string, data []byte) (string, error)
+	// Get 按 Put 返回的 URI 读取对象
+	Get(ctx context.Context, uri string) ([]byte, error)
+}
+
+// New 根据配置创建输出存储，未启用（output_threshold 为 0）时返回 nil
+func New(cfg *config.StorageConfig) (OutputStorage, error) {
+	if cfg.OutputThreshold <= 0 {
+		return nil, nil
+	}
+
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStorage(cfg.LocalDir)
+	case "s3":
+		return NewS3Storage(&cfg.S3), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
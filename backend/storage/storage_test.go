@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"llm-scheduler/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.StorageConfig
+		want    string
+		wantErr bool
+	}{
+		{"disabled", config.StorageConfig{Backend: "s3"}, "", false},
+		{"local", config.StorageConfig{OutputThreshold: 1024, Backend: "local", LocalDir: t.TempDir()}, "*storage.LocalStorage", false},
+		{"s3", config.StorageConfig{OutputThreshold: 1024, Backend: "s3", S3: config.S3StorageConfig{Endpoint: "http://minio:9000", Bucket: "outputs"}}, "*storage.S3Storage", false},
+		{"unknown backend", config.StorageConfig{OutputThreshold: 1024, Backend: "gcs"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(&tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %T", store)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if tt.want == "" {
+				if store != nil {
+					t.Fatalf("expected nil storage when disabled, got %T", store)
+				}
+				return
+			}
+			if got := fmt.Sprintf("%T", store); got != tt.want {
+				t.Fatalf("storage type = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStorage(filepath.Join(dir, "outputs"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	ctx := context.Background()
+
+	uri, err := store.Put(ctx, "tasks/42/output.txt", []byte("first"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if uri != "local://tasks/42/output.txt" {
+		t.Fatalf("uri = %q, want local://tasks/42/output.txt", uri)
+	}
+
+	// 重复写入同一个键时覆盖原对象，目录中不留下临时文件
+	if _, err := store.Put(ctx, "tasks/42/output.txt", []byte("second")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := store.Get(ctx, uri)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != "second" {
+		t.Fatalf("data = %q, want second", data)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "outputs", "tasks", "42"))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "output.txt" {
+		t.Fatalf("unexpected files in object dir: %v", entries)
+	}
+}
+
+func TestLocalStorageRejectsInvalidKeysAndURIs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStorage(filepath.Join(dir, "outputs"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	ctx := context.Background()
+
+	// 指向存储目录之外的键被拒绝，不会写入文件
+	for _, key := range []string{"../escape.txt", "tasks/../../escape.txt", ""} {
+		if _, err := store.Put(ctx, key, []byte("x")); err == nil || !strings.Contains(err.Error(), "invalid object key") {
+			t.Fatalf("Put(%q) error = %v, want invalid object key", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("object written outside storage dir")
+	}
+	if _, err := store.Get(ctx, "local://../escape.txt"); err == nil {
+		t.Fatalf("expected error reading outside storage dir")
+	}
+
+	// 其他后端写入的 URI 返回 ErrUnsupportedURI
+	if _, err := store.Get(ctx, "s3://outputs/tasks/1/output.txt"); !errors.Is(err, ErrUnsupportedURI) {
+		t.Fatalf("Get s3 uri error = %v, want ErrUnsupportedURI", err)
+	}
+	if _, err := store.Get(ctx, "local://tasks/missing.txt"); err == nil {
+		t.Fatalf("expected error for missing object")
+	}
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"llm-scheduler/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 本服务创建 span 使用的 Tracer 名称，也是未配置 app.name 时的服务名
+const tracerName = "llm-scheduler"
+
+// traceParentHeader W3C Trace Context 的传播字段
+const traceParentHeader = "traceparent"
+
+// propagator 追踪上下文的传播格式，HTTP 请求头和队列项都使用 W3C Trace Context
+var propagator = propagation.TraceContext{}
+
+// Init 按配置初始化全局 TracerProvider，返回停止时刷新并关闭导出器的函数。
+// tracing.endpoint 为空时不导出，span 均为 no-op，追踪上下文仍会随请求和队列项传递
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Tracing.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint)}
+	if cfg.Tracing.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := NewProvider(cfg, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider 创建带服务信息和采样策略的 TracerProvider，opts 指定导出方式（测试中使用内存导出器）
+func NewProvider(cfg *config.Config, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName := cfg.App.Name
+	if serviceName == "" {
+		serviceName = tracerName
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", cfg.App.Version),
+	)
+
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.GetSampleRatio()))
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	}, opts...)...)
+}
+
+// Tracer 返回本服务的 Tracer，使用全局 TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Inject 将 ctx 中的追踪上下文编码为 W3C traceparent，随队列项保存；没有有效的追踪上下文时返回空字符串
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier[traceParentHeader]
+}
+
+// Extract 将队列项中的 traceparent 作为远端父 span 写入 ctx，为空或格式错误时原样返回 ctx
+func Extract(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
+
+// ExtractHTTP 读取请求头中上游传入的追踪上下文
+func ExtractHTTP(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// End 结束 span，err 不为 nil 时记录错误并将状态标记为 Error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
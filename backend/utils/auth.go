@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// gin 上下文中保存调用方身份的键，JWT 认证的调用方名称为 jwt:<sub>
+const (
+	APIKeyNameContextKey  = "api_key_name"
+	APIKeyLimitContextKey = "api_key_rate_limit"
+	RoleContextKey        = "auth_role"
+)
+
+// AuthMiddleware 认证中间件，校验 X-API-Key 请求头或（启用 JWT 时）Authorization: Bearer 令牌，
+// 并在上下文中记录调用方名称和角色
+func AuthMiddleware(cfg *config.AuthConfig) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(cfg.ExemptPaths))
+	for _, path := range cfg.ExemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if !cfg.Enabled || exempt[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		if token, ok := bearerToken(c); ok && cfg.JWT.Enabled {
+			claims, err := ParseJWT(token, &cfg.JWT, time.Now())
+			if err != nil {
+				if errors.Is(err, ErrTokenExpired) {
+					Unauthorized(c, "令牌已过期")
+				} else {
+					Unauthorized(c, "无效的令牌")
+				}
+				c.Abort()
+				return
+			}
+			c.Set(APIKeyNameContextKey, "jwt:"+claims.Subject)
+			c.Set(RoleContextKey, claims.Role)
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			if cfg.JWT.Enabled {
+				Unauthorized(c, "缺少 API Key 或令牌")
+			} else {
+				Unauthorized(c, "缺少 API Key")
+			}
+			c.Abort()
+			return
+		}
+
+		if apiKey := cfg.LookupKey(key); apiKey != nil {
+			c.Set(APIKeyNameContextKey, apiKey.Name)
+			c.Set(APIKeyLimitContextKey, apiKey.RateLimit)
+			c.Set(RoleContextKey, apiKey.GetRole())
+			c.Next()
+			return
+		}
+
+		Unauthorized(c, "无效的 API Key")
+		c.Abort()
+	}
+}
+
+// RequireRole 要求调用方具有指定角色，admin 满足任何角色要求，角色不足返回 403。
+// 未启用认证或路径免认证时上下文中没有角色，直接放行
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get(RoleContextKey)
+		if !exists {
+			c.Next()
+			return
+		}
+
+		current, _ := value.(string)
+		if current != config.RoleAdmin && current != role {
+			Forbidden(c, "权限不足")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken 读取 Authorization: Bearer 请求头中的令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// RateLimitMiddleware 按 API Key 限流，使用 Redis 滑动窗口统计请求数，超限返回 429 和 Retry-After
+func RateLimitMiddleware(client *redis.Client, cfg *config.AuthConfig, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.GetString(APIKeyNameContextKey)
+		if !cfg.Enabled || name == "" {
+			c.Next()
+			return
+		}
+
+		limit := c.GetInt(APIKeyLimitContextKey)
+		if limit <= 0 {
+			limit = cfg.DefaultRateLimit
+		}
+		window := cfg.RateLimitWindow
+		if limit <= 0 || window <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := allowRequest(c.Request.Context(), client, cfg, name, limit, window)
+		if err != nil {
+			// 限流存储不可用时放行，避免 Redis 故障导致 API 整体不可用
+			logger.WithError(err).WithField("api_key", name).Warn("Rate limit check failed")
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			TooManyRequests(c, "请求过于频繁，请稍后重试")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allowRequest 在调用方的滑动窗口中记录一次请求，返回是否放行以及超限时需要等待的时间
+func allowRequest(ctx context.Context, client *redis.Client, cfg *config.AuthConfig, name string, limit int, window time.Duration) (bool, time.Duration, error) {
+	return SlidingWindowAllow(ctx, client, getRateLimitKey(cfg, name), limit, window)
+}
+
+// getRateLimitKey 获取 API Key 限流记录的键名
+func getRateLimitKey(cfg *config.AuthConfig, name string) string {
+	prefix := cfg.RateLimitKey
+	if prefix == "" {
+		prefix = "llm_tasks:ratelimit"
+	}
+	return prefix + ":" + name
+}
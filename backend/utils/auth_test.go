@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// newAuthTestConfig 返回启用认证的配置：team-a 每分钟 2 次，team-b 使用默认的 5 次
+func newAuthTestConfig() *config.AuthConfig {
+	return &config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{
+			{Name: "team-a", Key: "key-a", RateLimit: 2},
+			{Name: "team-b", Key: "key-b"},
+		},
+		ExemptPaths:      []string{"/api/v1/health"},
+		RateLimitWindow:  time.Minute,
+		DefaultRateLimit: 5,
+	}
+}
+
+// newAuthRouter 注册认证和限流中间件，处理函数返回调用方名称
+func newAuthRouter(client *redis.Client, cfg *config.AuthConfig) *gin.Engine {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	router := gin.New()
+	router.Use(AuthMiddleware(cfg), RateLimitMiddleware(client, cfg, logger))
+	handler := func(c *gin.Context) { c.String(http.StatusOK, c.GetString(APIKeyNameContextKey)) }
+	router.GET("/api/v1/tasks", handler)
+	router.GET("/api/v1/health", handler)
+	return router
+}
+
+// get 携带 X-API-Key 发送 GET 请求，key 为空时不带该请求头
+func get(router http.Handler, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer client.Close()
+	router := newAuthRouter(client, newAuthTestConfig())
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+		caller string
+	}{
+		{"valid key", "/api/v1/tasks", "key-a", http.StatusOK, "team-a"},
+		{"missing key", "/api/v1/tasks", "", http.StatusUnauthorized, ""},
+		{"unknown key", "/api/v1/tasks", "wrong", http.StatusUnauthorized, ""},
+		{"prefix of a valid key", "/api/v1/tasks", "key-", http.StatusUnauthorized, ""},
+		{"exempt path", "/api/v1/health", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(router, tt.path, tt.key)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.caller {
+				t.Fatalf("caller = %q, want %q", w.Body.String(), tt.caller)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer client.Close()
+	cfg := newAuthTestConfig()
+	cfg.Enabled = false
+
+	// 未启用认证时不校验也不限流
+	router := newAuthRouter(client, cfg)
+	for i := 0; i < 10; i++ {
+		if w := get(router, "/api/v1/tasks", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddlewarePerKey(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer client.Close()
+	router := newAuthRouter(client, newAuthTestConfig())
+
+	// team-a 单独配置了每分钟 2 次，第三次返回 429 和 Retry-After
+	for i := 0; i < 2; i++ {
+		if w := get(router, "/api/v1/tasks", "key-a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+	w := get(router, "/api/v1/tasks", "key-a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+		t.Fatalf("Retry-After = %q, want positive seconds", retryAfter)
+	}
+
+	// 其他 Key 有独立的窗口，使用 default_rate_limit
+	for i := 0; i < 5; i++ {
+		if w := get(router, "/api/v1/tasks", "key-b"); w.Code != http.StatusOK {
+			t.Fatalf("team-b request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+	if w := get(router, "/api/v1/tasks", "key-b"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("team-b status = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitMiddlewareAllowsWhenRedisUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	router := newAuthRouter(client, newAuthTestConfig())
+
+	// 限流存储不可用时放行，认证仍然生效
+	server.Close()
+	for i := 0; i < 3; i++ {
+		if w := get(router, "/api/v1/tasks", "key-a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+	if w := get(router, "/api/v1/tasks", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+}
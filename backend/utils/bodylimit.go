@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware 限制请求体大小，避免超大请求在绑定时耗尽内存。
+// Content-Length 已超过 limit 时直接返回 413；否则读取超过 limit 时绑定失败，由 ValidationError 返回 413
+func BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			PayloadTooLarge(c, limit)
+			c.Abort()
+			return
+		}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// PayloadTooLarge 413 错误
+func PayloadTooLarge(c *gin.Context, limit int64) {
+	Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("请求体不能超过 %d 字节", limit))
+}
+
+// bodyTooLarge 绑定错误是否因为请求体超过 BodyLimitMiddleware 的限制
+func bodyTooLarge(err error) (int64, bool) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return maxBytesErr.Limit, true
+	}
+	return 0, false
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WantsCSV 请求通过 ?format=csv 或 Accept: text/csv 要求返回 CSV，format 参数优先
+func WantsCSV(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(strings.ToLower(c.GetHeader("Accept")), "text/csv")
+}
+
+// RespondCSV 将查询结果写为 CSV 附件。columns 指定的列按给定顺序排在前面，
+// 结果中的其他列按名称排序追加在后，保证每次导出的列顺序一致
+func RespondCSV(c *gin.Context, filename string, rows []map[string]interface{}, columns ...string) {
+	header := csvColumns(rows, columns)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(header)
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i, column := range header {
+			record[i] = csvValue(row[column])
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+}
+
+// csvColumns 合并指定列和结果中出现的其他列
+func csvColumns(rows []map[string]interface{}, columns []string) []string {
+	seen := make(map[string]bool, len(columns))
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !seen[column] {
+			seen[column] = true
+			header = append(header, column)
+		}
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				extra = append(extra, column)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(header, extra...)
+}
+
+// csvValue 格式化单元格：NULL 为空，日期列只保留日期；以 = + - @ 开头的文本加前缀 '，
+// 避免在电子表格中被当作公式执行
+func csvValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Sprint(v)
+	}
+
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return "'" + s
+		}
+	}
+	return s
+}
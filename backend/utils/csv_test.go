@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWantsCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   bool
+	}{
+		{"default json", "", "", false},
+		{"accept header", "", "text/csv", true},
+		{"accept list", "", "application/json, TEXT/CSV;q=0.9", true},
+		{"format param", "?format=CSV", "", true},
+		// format 参数优先于 Accept
+		{"format json overrides accept", "?format=json", "text/csv", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/stats"+tt.query, nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+			if got := WantsCSV(c); got != tt.want {
+				t.Fatalf("WantsCSV = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	rows := []map[string]interface{}{
+		{"zeta": "z", "total": int64(3), "date": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), "rate": 66.5, "note": "=SUM(A1)"},
+		{"total": int64(1), "alpha": []byte("a"), "note": "-2.5", "rate": nil},
+	}
+
+	// 指定的列在前，其他列按名称排序；日期只保留日期，公式文本加前缀，负数保持原样
+	RespondCSV(c, "stats.csv", rows, "date", "total")
+
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="stats.csv"` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+	want := "date,total,alpha,note,rate,zeta\n" +
+		"2024-05-01,3,,'=SUM(A1),66.5,z\n" +
+		",1,a,-2.5,,\n"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("body =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 支持的 JSON Schema 子集：type、enum、const、required、properties、additionalProperties、
+// items、minItems、maxItems、minLength、maxLength、minimum、maximum，其余关键字被忽略
+
+// SchemaError JSON Schema 校验错误，Path 为出错位置（根为空字符串，对象字段用 . 分隔，数组元素用 [i]）
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+// jsonSchemaTypes JSON Schema 支持的类型名称
+var jsonSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// CheckJSONSchema 检查 schema 本身是否合法，保存模型配置时调用，避免创建任务时才发现 schema 错误
+func CheckJSONSchema(schema interface{}) error {
+	return checkSchema(schema, "")
+}
+
+func checkSchema(schema interface{}, path string) error {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("schema%s must be an object", pathSuffix(path))
+	}
+
+	if t, exists := s["type"]; exists {
+		names, ok := schemaTypeNames(t)
+		if !ok {
+			return fmt.Errorf("schema%s: type must be a string or an array of strings", pathSuffix(path))
+		}
+		for _, name := range names {
+			if !jsonSchemaTypes[name] {
+				return fmt.Errorf("schema%s: unknown type %q", pathSuffix(path), name)
+			}
+		}
+	}
+	if enum, exists := s["enum"]; exists {
+		if _, ok := enum.([]interface{}); !ok {
+			return fmt.Errorf("schema%s: enum must be an array", pathSuffix(path))
+		}
+	}
+	if required, exists := s["required"]; exists {
+		list, ok := required.([]interface{})
+		if !ok {
+			return fmt.Errorf("schema%s: required must be an array of strings", pathSuffix(path))
+		}
+		for _, name := range list {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("schema%s: required must be an array of strings", pathSuffix(path))
+			}
+		}
+	}
+	for _, key := range []string{"minItems", "maxItems", "minLength", "maxLength", "minimum", "maximum"} {
+		if value, exists := s[key]; exists {
+			if _, ok := value.(float64); !ok {
+				return fmt.Errorf("schema%s: %s must be a number", pathSuffix(path), key)
+			}
+		}
+	}
+	if properties, exists := s["properties"]; exists {
+		props, ok := properties.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("schema%s: properties must be an object", pathSuffix(path))
+		}
+		for name, prop := range props {
+			if err := checkSchema(prop, joinSchemaPath(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+	if additional, exists := s["additionalProperties"]; exists {
+		if _, ok := additional.(bool); !ok {
+			if err := checkSchema(additional, path+".additionalProperties"); err != nil {
+				return err
+			}
+		}
+	}
+	if items, exists := s["items"]; exists {
+		if err := checkSchema(items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateJSONSchema 按 schema 校验已解析的 JSON 值，返回所有校验错误，schema 需先通过 CheckJSONSchema
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) []SchemaError {
+	var errs []SchemaError
+	validateSchema(schema, value, "", &errs)
+	return errs
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string, errs *[]SchemaError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, exists := schema["type"]; exists {
+		names, _ := schemaTypeNames(t)
+		matched := false
+		for _, name := range names {
+			if jsonTypeMatches(name, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must be of type %s, got %s", strings.Join(names, " or "), jsonTypeName(value))
+			// 类型不符时其余约束没有意义
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %s", compactJSON(enum))
+		}
+	}
+	if expected, exists := schema["const"]; exists && !reflect.DeepEqual(expected, value) {
+		fail("must be %s", compactJSON(expected))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path, errs)
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			fail("must contain at least %d items", int(min))
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			fail("must contain at most %d items", int(max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := schema["minLength"].(float64); ok && float64(length) < min {
+			fail("must be at least %d characters", int(min))
+		}
+		if max, ok := schema["maxLength"].(float64); ok && float64(length) > max {
+			fail("must be at most %d characters", int(max))
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			fail("must be >= %v", min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			fail("must be <= %v", max)
+		}
+	}
+}
+
+// validateObject 校验对象的 required、properties 和 additionalProperties，字段按名称排序以保证错误顺序稳定
+func validateObject(schema map[string]interface{}, value map[string]interface{}, path string, errs *[]SchemaError) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			field, _ := name.(string)
+			if _, exists := value[field]; !exists {
+				*errs = append(*errs, SchemaError{Path: joinSchemaPath(path, field), Message: "is required"})
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := joinSchemaPath(path, name)
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			validateSchema(prop, value[name], fieldPath, errs)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, SchemaError{Path: fieldPath, Message: "is not allowed"})
+			}
+		case map[string]interface{}:
+			validateSchema(additional, value[name], fieldPath, errs)
+		}
+	}
+}
+
+// schemaTypeNames 读取 type 关键字，支持单个类型或类型数组
+func schemaTypeNames(t interface{}) ([]string, bool) {
+	switch v := t.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, name)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// jsonTypeMatches 判断值是否符合 JSON Schema 类型，integer 要求数值没有小数部分
+func jsonTypeMatches(name string, value interface{}) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return jsonTypeName(value) == name
+	}
+}
+
+// jsonTypeName 获取 encoding/json 解析结果对应的 JSON 类型名称
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func pathSuffix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " at " + path
+}
+
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
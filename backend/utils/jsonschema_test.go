@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// mustParseJSON 解析测试用的 JSON 文本
+func mustParseJSON(t *testing.T, text string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		t.Fatalf("parse %s: %v", text, err)
+	}
+	return value
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := mustParseJSON(t, `{
+		"type": "object",
+		"required": ["text", "lang"],
+		"additionalProperties": false,
+		"properties": {
+			"text": {"type": "string", "minLength": 1, "maxLength": 10},
+			"lang": {"enum": ["en", "zh"]},
+			"count": {"type": "integer", "minimum": 1, "maximum": 5},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"meta": {"type": "object", "required": ["source"]}
+		}
+	}`).(map[string]interface{})
+
+	tests := []struct {
+		name  string
+		input string
+		want  []SchemaError
+	}{
+		{"valid", `{"text":"hello","lang":"en","count":3,"tags":["a"],"meta":{"source":"x"}}`, nil},
+		{"missing required", `{"text":"hello"}`, []SchemaError{{"lang", "is required"}}},
+		{"wrong root type", `["hello"]`, []SchemaError{{"", "must be of type object, got array"}}},
+		{"enum", `{"text":"hello","lang":"fr"}`, []SchemaError{{"lang", `must be one of ["en","zh"]`}}},
+		{"string length", `{"text":"","lang":"en"}`, []SchemaError{{"text", "must be at least 1 characters"}}},
+		{"integer", `{"text":"a","lang":"en","count":1.5}`, []SchemaError{{"count", "must be of type integer, got number"}}},
+		{"maximum", `{"text":"a","lang":"en","count":6}`, []SchemaError{{"count", "must be <= 5"}}},
+		{"array item path", `{"text":"a","lang":"en","tags":["a",1]}`, []SchemaError{{"tags[1]", "must be of type string, got number"}}},
+		{"max items", `{"text":"a","lang":"en","tags":["a","b","c"]}`, []SchemaError{{"tags", "must contain at most 2 items"}}},
+		{"nested required", `{"text":"a","lang":"en","meta":{}}`, []SchemaError{{"meta.source", "is required"}}},
+		{"additional property", `{"text":"a","lang":"en","extra":true}`, []SchemaError{{"extra", "is not allowed"}}},
+		{"several errors sorted", `{"text":"a","zeta":1,"alpha":2}`, []SchemaError{{"lang", "is required"}, {"alpha", "is not allowed"}, {"zeta", "is not allowed"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateJSONSchema(schema, mustParseJSON(t, tt.input))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckJSONSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{"valid", `{"type":"object","required":["text"],"properties":{"text":{"type":["string","null"]}}}`, false},
+		{"not an object", `"string"`, true},
+		{"unknown type", `{"type":"text"}`, true},
+		{"enum not array", `{"enum":"a"}`, true},
+		{"required not strings", `{"required":[1]}`, true},
+		{"invalid nested property", `{"properties":{"text":{"type":"str"}}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckJSONSchema(mustParseJSON(t, tt.schema))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckJSONSchema error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
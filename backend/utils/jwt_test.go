@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// signJWT 使用 secret 按 HS256 签发令牌，header 中的 alg 可以任意指定
+func signJWT(t *testing.T, alg, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal jwt segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validClaims 返回以 now 为基准一小时后过期的 viewer 声明
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sub":  "alice",
+		"role": config.RoleViewer,
+		"iss":  "scheduler",
+		"exp":  now.Add(time.Hour).Unix(),
+	}
+}
+
+// withClaim 复制声明并修改（value 为 nil 时删除）指定字段
+func withClaim(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(claims))
+	for k, v := range claims {
+		out[k] = v
+	}
+	if value == nil {
+		delete(out, key)
+	} else {
+		out[key] = value
+	}
+	return out
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cfg := &config.JWTConfig{Enabled: true, Secret: testJWTSecret, Issuer: "scheduler", Leeway: 30 * time.Second}
+	claims := validClaims(now)
+
+	// alg 为 none 时签名段为空
+	noneToken := signJWT(t, "none", testJWTSecret, claims)
+	noneToken = noneToken[:strings.LastIndex(noneToken, ".")+1]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", signJWT(t, "HS256", testJWTSecret, claims), nil},
+		{"expired", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "exp", now.Add(-time.Minute).Unix())), ErrTokenExpired},
+		{"expired within leeway", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "exp", now.Add(-10*time.Second).Unix())), nil},
+		{"not valid yet", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "nbf", now.Add(time.Minute).Unix())), ErrInvalidToken},
+		{"alg none", noneToken, ErrInvalidToken},
+		{"alg RS256", signJWT(t, "RS256", testJWTSecret, claims), ErrInvalidToken},
+		{"bad signature", signJWT(t, "HS256", "other-secret", claims), ErrInvalidToken},
+		{"malformed", "not-a-jwt", ErrInvalidToken},
+		{"missing exp", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "exp", nil)), ErrInvalidToken},
+		{"missing sub", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "sub", nil)), ErrInvalidToken},
+		{"missing role", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "role", nil)), ErrInvalidToken},
+		{"unknown role", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "role", "root")), ErrInvalidToken},
+		{"wrong issuer", signJWT(t, "HS256", testJWTSecret, withClaim(claims, "iss", "someone-else")), ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJWT(tt.token, cfg, now)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.Subject != "alice" || got.Role != config.RoleViewer {
+					t.Fatalf("unexpected claims: %+v", got)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareJWTRoles(t *testing.T) {
+	cfg := newAuthTestConfig()
+	cfg.JWT = config.JWTConfig{Enabled: true, Secret: testJWTSecret}
+
+	router := gin.New()
+	router.Use(AuthMiddleware(cfg))
+	handler := func(c *gin.Context) { c.String(http.StatusOK, c.GetString(APIKeyNameContextKey)) }
+	router.GET("/api/v1/tasks", RequireRole(config.RoleViewer), handler)
+	router.POST("/api/v1/models", RequireRole(config.RoleAdmin), handler)
+
+	now := time.Now()
+	viewer := signJWT(t, "HS256", testJWTSecret, validClaims(now))
+	admin := signJWT(t, "HS256", testJWTSecret, withClaim(validClaims(now), "role", config.RoleAdmin))
+	expired := signJWT(t, "HS256", testJWTSecret, withClaim(validClaims(now), "exp", now.Add(-time.Hour).Unix()))
+	forged := signJWT(t, "HS256", "other-secret", withClaim(validClaims(now), "role", config.RoleAdmin))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"viewer reads", http.MethodGet, "/api/v1/tasks", viewer, http.StatusOK},
+		{"viewer writes", http.MethodPost, "/api/v1/models", viewer, http.StatusForbidden},
+		{"admin writes", http.MethodPost, "/api/v1/models", admin, http.StatusOK},
+		{"expired token", http.MethodGet, "/api/v1/tasks", expired, http.StatusUnauthorized},
+		{"forged signature", http.MethodPost, "/api/v1/models", forged, http.StatusUnauthorized},
+		{"no credentials", http.MethodGet, "/api/v1/tasks", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "jwt:alice" {
+				t.Fatalf("caller = %q, want jwt:alice", w.Body.String())
+			}
+		})
+	}
+}
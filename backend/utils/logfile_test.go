@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigureLogOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		check  func(t *testing.T, line string)
+	}{
+		{"json", "json", func(t *testing.T, line string) {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil || entry["msg"] != "hello" || entry["task_id"] != float64(7) {
+				t.Fatalf("line = %q, want JSON entry with msg and task_id", line)
+			}
+		}},
+		{"text", "text", func(t *testing.T, line string) {
+			if !strings.Contains(line, `msg=hello`) || !strings.Contains(line, "task_id=7") || strings.HasPrefix(line, "{") {
+				t.Fatalf("line = %q, want text entry", line)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "logs", "app.log")
+			logger := logrus.New()
+			closer, err := ConfigureLogOutput(logger, config.LoggingConfig{Format: tt.format, Output: "file", FilePath: path, MaxSize: 1})
+			if err != nil {
+				t.Fatalf("ConfigureLogOutput: %v", err)
+			}
+			logger.WithField("task_id", 7).Info("hello")
+			if err := closer.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read log file: %v", err)
+			}
+			tt.check(t, strings.TrimSpace(string(data)))
+		})
+	}
+}
+
+func TestConfigureLogOutputStdout(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	closer, err := ConfigureLogOutput(logger, config.LoggingConfig{Output: "stdout", FilePath: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil || closer != nil {
+		t.Fatalf("ConfigureLogOutput = %v, %v, want no closer", closer, err)
+	}
+	if logger.Out != os.Stdout {
+		t.Fatalf("output = %v, want stdout", logger.Out)
+	}
+	if _, ok := logger.Formatter.(*logrus.JSONFormatter); !ok {
+		t.Fatalf("formatter = %T, want JSON by default", logger.Formatter)
+	}
+}
+
+// newSmallRotatingFile 创建单个文件上限为 maxBytes 字节的日志文件，便于测试轮转
+func newSmallRotatingFile(t *testing.T, path string, maxBytes int64, maxBackups int, compress bool) *RotatingFile {
+	t.Helper()
+	r, err := NewRotatingFile(path, 1, 0, maxBackups, compress)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	r.maxSize = maxBytes
+	return r
+}
+
+// logBackups 返回日志目录中的备份文件名
+func logBackups(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != "app.log" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileRotatesAtSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r := newSmallRotatingFile(t, path, 100, 0, false)
+
+	first := strings.Repeat("a", 59) + "\n"
+	second := strings.Repeat("b", 59) + "\n"
+	for _, line := range []string{first, second} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// 第二次写入会超过 100 字节，写入前先轮转，当前文件只有第二行
+	if data, _ := os.ReadFile(path); string(data) != second {
+		t.Fatalf("current file = %q, want only the second line", data)
+	}
+	backups := logBackups(t, dir)
+	if len(backups) != 1 || !strings.HasPrefix(backups[0], "app-") || !strings.HasSuffix(backups[0], ".log") {
+		t.Fatalf("backups = %v, want one app-<time>.log", backups)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, backups[0])); string(data) != first {
+		t.Fatalf("backup = %q, want the first line", data)
+	}
+}
+
+func TestRotatingFileCountsExistingSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 80), 0o644); err != nil {
+		t.Fatalf("write existing log: %v", err)
+	}
+
+	// 重新打开时接着已有内容计算大小
+	r := newSmallRotatingFile(t, path, 100, 0, false)
+	if _, err := r.Write([]byte(strings.Repeat("y", 30))); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	r.Close()
+	if backups := logBackups(t, dir); len(backups) != 1 {
+		t.Fatalf("backups = %v, want the existing file rotated", backups)
+	}
+}
+
+func TestRotatingFilePrunesAndCompressesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r := newSmallRotatingFile(t, path, 10, 2, true)
+
+	// 每次写入都超过上限，产生 4 个备份；备份名精确到毫秒，两次轮转之间稍作等待
+	lines := []string{"line-0000\n", "line-0001\n", "line-0002\n", "line-0003\n", "line-0004\n"}
+	for _, line := range lines {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// 只保留最新的 2 个备份，且都已压缩
+	backups := logBackups(t, dir)
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want 2", backups)
+	}
+	for i, name := range backups {
+		if !strings.HasSuffix(name, ".log.gz") {
+			t.Fatalf("backup %s is not compressed", name)
+		}
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("open backup: %v", err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		data, _ := io.ReadAll(gz)
+		file.Close()
+		if want := lines[i+2]; string(data) != want {
+			t.Fatalf("backup %s = %q, want %q", name, data, want)
+		}
+	}
+}
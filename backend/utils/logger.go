@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"llm-scheduler/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigureLogOutput 按 logging 配置设置日志格式和输出位置。输出到文件时按大小轮转，
+// 返回的 io.Closer 需在进程退出前关闭；输出到 stdout 时返回 nil
+func ConfigureLogOutput(logger *logrus.Logger, cfg config.LoggingConfig) (io.Closer, error) {
+	switch cfg.Format {
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	if cfg.Output != "file" {
+		logger.SetOutput(os.Stdout)
+		return nil, nil
+	}
+
+	file, err := NewRotatingFile(cfg.FilePath, cfg.MaxSize, cfg.MaxAge, cfg.MaxBackups, cfg.Compress)
+	if err != nil {
+		return nil, err
+	}
+	logger.SetOutput(file)
+	return file, nil
+}
+
+// SetLogLevel 运行时修改日志级别，级别无效时保持原级别不变
+func SetLogLevel(logger *logrus.Logger, level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+
+	if previous := logger.GetLevel(); previous != parsed {
+		logger.SetLevel(parsed)
+		logger.WithFields(logrus.Fields{
+			"previous": previous.String(),
+			"level":    parsed.String(),
+		}).Info("Log level changed")
+	}
+	return nil
+}
@@ -1,10 +1,16 @@
 package utils
 
 import (
+	"net/http"
 	"time"
 
+	"llm-scheduler/tracing"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LoggerMiddleware 日志中间件
@@ -12,6 +18,52 @@ func LoggerMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	return gin.LoggerWithWriter(gin.DefaultWriter)
 }
 
+// RequestIDMiddleware 读取或生成请求关联 ID，写入 gin 上下文、请求 context 和响应头，
+// 创建任务时随任务保存，贯穿队列、Worker 和任务日志
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validTraceID(requestID) {
+			requestID = NewTraceID()
+		}
+
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(WithTraceID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// TracingMiddleware 为每个请求创建服务端 span，沿用请求头 traceparent 中的上游追踪上下文。
+// span 写入请求 context，请求中创建的任务入队时随队列项传给 Worker
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := tracing.ExtractHTTP(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request_id", c.GetString("request_id")),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
 // RequestLoggerMiddleware 请求日志中间件
 func RequestLoggerMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -23,6 +75,7 @@ func RequestLoggerMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 		// 记录请求日志
 		duration := time.Since(startTime)
 		logger.WithFields(logrus.Fields{
+			"request_id": c.GetString("request_id"),
 			"method":     c.Request.Method,
 			"path":       c.Request.URL.Path,
 			"status":     c.Writer.Status(),
@@ -41,28 +94,11 @@ func ErrorHandlerMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 		// 处理错误
 		for _, err := range c.Errors {
 			logger.WithFields(logrus.Fields{
-				"method": c.Request.Method,
-				"path":   c.Request.URL.Path,
-				"error":  err.Error(),
+				"request_id": c.GetString("request_id"),
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+				"error":      err.Error(),
 			}).Error("Request error")
 		}
 	}
 }
-
-// RateLimitMiddleware 限流中间件（简单实现）
-func RateLimitMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 这里可以实现限流逻辑
-		// 例如使用 Redis 存储访问频率
-		c.Next()
-	}
-}
-
-// AuthMiddleware 认证中间件（预留）
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 这里可以实现认证逻辑
-		// 例如验证 JWT Token
-		c.Next()
-	}
-}
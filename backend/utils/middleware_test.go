@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddlewareContinuesUpstreamTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(&config.Config{}, sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	}()
+
+	var handlerSpan trace.SpanContext
+	router := gin.New()
+	router.Use(TracingMiddleware())
+	router.GET("/api/v1/tasks/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	const upstreamTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/42", nil)
+	req.Header.Set("traceparent", "00-"+upstreamTraceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /api/v1/tasks/:id" {
+		t.Errorf("span name = %q, want route template", span.Name)
+	}
+	if span.SpanContext.TraceID().String() != upstreamTraceID {
+		t.Errorf("trace = %s, want upstream trace %s", span.SpanContext.TraceID(), upstreamTraceID)
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("handler context span = %s, want request span %s", handlerSpan.SpanID(), span.SpanContext.SpanID())
+	}
+	var status int64
+	for _, kv := range span.Attributes {
+		if kv.Key == attribute.Key("http.status_code") {
+			status = kv.Value.AsInt64()
+		}
+	}
+	if status != http.StatusServiceUnavailable || span.Status.Code != codes.Error {
+		t.Errorf("status attribute = %d, span status = %v, want 503 and error", status, span.Status.Code)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"client id kept", "req-42.a:b_c", true},
+		{"missing id generated", "", false},
+		{"invalid characters replaced", "bad id\nInjected: 1", false},
+		{"too long replaced", strings.Repeat("a", 65), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromGin, fromContext string
+			router := gin.New()
+			router.Use(RequestIDMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				fromGin = c.GetString("request_id")
+				fromContext = TraceIDFromContext(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// gin 上下文、请求 context 和响应头中是同一个 ID
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" || fromGin != got || fromContext != got {
+				t.Fatalf("response %q, gin %q, context %q, want the same id", got, fromGin, fromContext)
+			}
+			if tt.keep {
+				if got != tt.header {
+					t.Fatalf("request id = %q, want %q", got, tt.header)
+				}
+				return
+			}
+			// 生成的 ID 为 32 位十六进制
+			if len(got) != 32 || strings.Trim(got, "0123456789abcdef") != "" {
+				t.Fatalf("generated request id = %q, want 32 hex characters", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitSeq 保证同一毫秒内的请求在滑动窗口中是不同的成员
+var rateLimitSeq uint64
+
+// slidingWindowScript 滑动窗口限流：清理窗口外的记录，未超限时记录本次请求。
+// 返回 {1, 0} 表示放行，{0, retry_after_ms} 表示超限
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, tonumber(oldest[2]) + window - now}
+`)
+
+// SlidingWindowAllow 在 key 的滑动窗口中记录一次请求，窗口内最多允许 limit 次，所有实例共享同一个窗口。
+// 返回是否放行以及超限时需要等待的时间
+func SlidingWindowAllow(ctx context.Context, client *redis.Client, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%d-%d", now, atomic.AddUint64(&rateLimitSeq, 1))
+
+	result, err := slidingWindowScript.Run(ctx, client, []string{key},
+		now, window.Milliseconds(), limit, member).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit result: %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	retryMs, _ := result[1].(int64)
+	if retryMs < 0 {
+		retryMs = 0
+	}
+	return allowed == 1, time.Duration(retryMs) * time.Millisecond, nil
+}
@@ -23,6 +23,15 @@ type PagedResponse struct {
 	Size    int         `json:"size"`
 }
 
+// CursorResponse 游标分页响应结构
+type CursorResponse struct {
+	Code       int         `json:"code"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor *uint64     `json:"next_cursor"`
+	Limit      int         `json:"limit"`
+}
+
 // Success 成功响应
 func Success(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, Response{
@@ -53,6 +62,17 @@ func SuccessPaged(c *gin.Context, data interface{}, total int64, page, size int)
 	})
 }
 
+// SuccessCursor 游标分页成功响应，nextCursor 为 nil 表示没有下一页
+func SuccessCursor(c *gin.Context, data interface{}, nextCursor *uint64, limit int) {
+	c.JSON(http.StatusOK, CursorResponse{
+		Code:       0,
+		Message:    "success",
+		Data:       data,
+		NextCursor: nextCursor,
+		Limit:      limit,
+	})
+}
+
 // Error 错误响应
 func Error(c *gin.Context, code int, message string) {
 	c.JSON(code, Response{
@@ -76,17 +96,36 @@ func Forbidden(c *gin.Context, message string) {
 	Error(c, http.StatusForbidden, message)
 }
 
+// Conflict 409 错误
+func Conflict(c *gin.Context, message string) {
+	Error(c, http.StatusConflict, message)
+}
+
 // NotFound 404 错误
 func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, message)
 }
 
+// TooManyRequests 429 错误
+func TooManyRequests(c *gin.Context, message string) {
+	Error(c, http.StatusTooManyRequests, message)
+}
+
+// ServiceUnavailable 503 错误
+func ServiceUnavailable(c *gin.Context, message string) {
+	Error(c, http.StatusServiceUnavailable, message)
+}
+
 // InternalServerError 500 错误
 func InternalServerError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, message)
 }
 
-// ValidationError 参数验证错误
-func ValidationError(c *gin.Context, err error) {
-	BadRequest(c, "参数验证失败: "+err.Error())
+// ValidationFailed 参数验证错误（带字段级详情）
+func ValidationFailed(c *gin.Context, details interface{}) {
+	c.JSON(http.StatusBadRequest, Response{
+		Code:    -1,
+		Message: "参数验证失败",
+		Data:    details,
+	})
 }
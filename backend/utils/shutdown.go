@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+type shutdownKey struct{}
+
+// WithShutdown 返回携带关闭通知的 context 和发出通知的函数，用作 http.Server 的 BaseContext。
+// 与取消 context 不同，通知不会中断进行中的普通请求，只有 SSE 等长连接监听它并主动结束
+func WithShutdown(parent context.Context) (context.Context, func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	notify := func() {
+		once.Do(func() { close(done) })
+	}
+	return context.WithValue(parent, shutdownKey{}, (<-chan struct{})(done)), notify
+}
+
+// ShutdownNotify 返回服务开始关闭时关闭的通道，context 不带关闭通知时返回 nil（永远不会就绪）
+func ShutdownNotify(ctx context.Context) <-chan struct{} {
+	done, _ := ctx.Value(shutdownKey{}).(<-chan struct{})
+	return done
+}
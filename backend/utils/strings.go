@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"unicode/utf8"
+)
+
+// TruncateBytes 截断字符串到不超过 maxBytes 字节，不会截断多字节字符；返回截断后的字符串和是否发生了截断
+func TruncateBytes(s string, maxBytes int) (string, bool) {
+	if maxBytes < 0 || len(s) <= maxBytes {
+		return s, false
+	}
+
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end], true
+}
+
+// TruncateRunes 截断字符串到不超过 n 个字符
+func TruncateRunes(s string, n int) string {
+	if n < 0 {
+		return s
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+func TestTruncateBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		maxBytes  int
+		want      string
+		truncated bool
+	}{
+		{"within limit", "hello", 10, "hello", false},
+		{"exact limit", "hello", 5, "hello", false},
+		{"ascii", "hello world", 5, "hello", true},
+		{"unlimited", "hello", -1, "hello", false},
+		{"zero", "hello", 0, "", true},
+		// "你好" 每个字符 3 字节，不能截断在字符中间
+		{"multibyte boundary", "你好世界", 6, "你好", true},
+		{"multibyte mid rune", "你好世界", 7, "你好", true},
+		{"multibyte first rune", "你好", 2, "", true},
+		{"emoji", "ab😀cd", 4, "ab", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := TruncateBytes(tt.s, tt.maxBytes)
+			if got != tt.want || truncated != tt.truncated {
+				t.Fatalf("TruncateBytes(%q, %d) = %q, %v; want %q, %v", tt.s, tt.maxBytes, got, truncated, tt.want, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 3, "hel"},
+		{"hello", 10, "hello"},
+		{"你好世界", 2, "你好"},
+		{"ab😀cd", 3, "ab😀"},
+		{"hello", 0, ""},
+		{"hello", -1, "hello"},
+	}
+	for _, tt := range tests {
+		if got := TruncateRunes(tt.s, tt.n); got != tt.want {
+			t.Errorf("TruncateRunes(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
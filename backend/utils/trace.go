@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader 请求关联 ID 的 HTTP 头，客户端传入时沿用，否则由服务端生成
+const RequestIDHeader = "X-Request-ID"
+
+// maxTraceIDLength 接受的客户端请求 ID 最大长度，超过或包含非法字符时重新生成
+const maxTraceIDLength = 64
+
+type traceIDKey struct{}
+
+// WithTraceID 将关联 ID 写入 context
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext 读取 context 中的关联 ID，不存在时返回空字符串
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// NewTraceID 生成 32 位十六进制的关联 ID
+func NewTraceID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validTraceID 客户端传入的 ID 只允许字母、数字和 - _ . : 并限制长度，避免日志注入
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
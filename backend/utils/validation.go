@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"llm-scheduler/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames 让请求绑定的校验错误使用 json（或 form）标签中的字段名，
+// 返回给客户端的 field 与请求中的字段一致，需要在注册路由前调用
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// ValidationError 参数验证错误：校验规则和字段类型错误转换为字段级详情，其他错误（如 JSON 格式错误）返回错误信息
+func ValidationError(c *gin.Context, err error) {
+	if limit, ok := bodyTooLarge(err); ok {
+		PayloadTooLarge(c, limit)
+		return
+	}
+	if fields := bindingFieldErrors(err); len(fields) > 0 {
+		ValidationFailed(c, fields)
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		BadRequest(c, "请求体不是合法的 JSON")
+		return
+	}
+	BadRequest(c, "参数验证失败: "+err.Error())
+}
+
+// BindStrictJSON 与 ShouldBindJSON 相同，但请求体包含结构体中没有的字段时返回错误，
+// 用于字段拼写错误会被静默忽略、后果较严重的接口（如创建任务）
+func BindStrictJSON(c *gin.Context, obj interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// unknownField 解析 DisallowUnknownFields 产生的错误，返回未知字段名
+func unknownField(err error) (string, bool) {
+	field, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	return strings.Trim(field, `"`), true
+}
+
+// bindingFieldErrors 将请求绑定错误转换为字段级错误，无法转换时返回 nil
+func bindingFieldErrors(err error) []models.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]models.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, models.FieldError{
+				Field:   fieldPath(fe),
+				Tag:     fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+		return fields
+	}
+
+	if field, ok := unknownField(err); ok {
+		return []models.FieldError{{
+			Field:   field,
+			Tag:     "unknown",
+			Message: "不支持的字段",
+		}}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []models.FieldError{{
+			Field:   typeErr.Field,
+			Tag:     "type",
+			Message: fmt.Sprintf("类型错误，应为 %s", goJSONTypeName(typeErr.Type)),
+		}}
+	}
+	return nil
+}
+
+// fieldPath 去掉校验错误命名空间中的结构体名，嵌套字段保留路径，例如 tasks[0].type
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+// validationMessage 按校验规则生成面向客户端的错误信息
+func validationMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "不能为空"
+	case "oneof":
+		return "必须是以下值之一: " + strings.Join(strings.Fields(param), ", ")
+	case "min":
+		if isSized(fe.Kind()) {
+			return fmt.Sprintf("长度不能小于 %s", param)
+		}
+		return fmt.Sprintf("不能小于 %s", param)
+	case "max":
+		if isSized(fe.Kind()) {
+			return fmt.Sprintf("长度不能大于 %s", param)
+		}
+		return fmt.Sprintf("不能大于 %s", param)
+	case "len":
+		return fmt.Sprintf("长度必须为 %s", param)
+	case "gt":
+		return fmt.Sprintf("必须大于 %s", param)
+	case "gte":
+		return fmt.Sprintf("不能小于 %s", param)
+	case "lt":
+		return fmt.Sprintf("必须小于 %s", param)
+	case "lte":
+		return fmt.Sprintf("不能大于 %s", param)
+	case "email":
+		return "必须是合法的邮箱地址"
+	case "url":
+		return "必须是合法的 URL"
+	default:
+		return fmt.Sprintf("不满足校验规则 %s", fe.Tag())
+	}
+}
+
+// isSized 字符串、切片和 map 的 min/max 表示长度
+func isSized(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+// goJSONTypeName 将 Go 类型转换为 JSON 中的类型名称
+func goJSONTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return t.String()
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	UseJSONFieldNames()
+}
+
+// bindAndRespond 按 handler 的方式绑定请求体，绑定失败时写入 ValidationError 响应并返回解析后的响应体
+func bindAndRespond(t *testing.T, body string, obj interface{}, strict bool) (int, map[string]interface{}) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var err error
+	if strict {
+		err = BindStrictJSON(c, obj)
+	} else {
+		err = c.ShouldBindJSON(obj)
+	}
+	if err == nil {
+		t.Fatalf("binding %s succeeded, want error", body)
+	}
+	ValidationError(c, err)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, resp
+}
+
+// assertFieldErrors 检查响应为 400 且 data 为给定的字段错误数组
+func assertFieldErrors(t *testing.T, code int, resp map[string]interface{}, want []map[string]interface{}) {
+	t.Helper()
+	if code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", code)
+	}
+	if resp["code"] != float64(-1) || resp["message"] != "参数验证失败" {
+		t.Errorf("response = %v, want code -1 and message 参数验证失败", resp)
+	}
+
+	data, ok := resp["data"].([]interface{})
+	if !ok {
+		t.Fatalf("data = %#v, want array", resp["data"])
+	}
+	got := make([]map[string]interface{}, len(data))
+	for i, item := range data {
+		got[i], _ = item.(map[string]interface{})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+}
+
+func TestValidationErrorMissingRequiredField(t *testing.T) {
+	code, resp := bindAndRespond(t, `{"model_id": 1, "type": "translation"}`, &models.TaskCreateRequest{}, false)
+
+	assertFieldErrors(t, code, resp, []map[string]interface{}{
+		{"field": "input", "tag": "required", "message": "不能为空"},
+	})
+}
+
+func TestValidationErrorBadEnumValue(t *testing.T) {
+	var req struct {
+		Status models.ModelStatus `json:"status" binding:"required,oneof=online offline maintenance"`
+	}
+	code, resp := bindAndRespond(t, `{"status": "paused"}`, &req, false)
+
+	assertFieldErrors(t, code, resp, []map[string]interface{}{
+		{"field": "status", "tag": "oneof", "message": "必须是以下值之一: online, offline, maintenance"},
+	})
+}
+
+func TestValidationErrorFieldType(t *testing.T) {
+	code, resp := bindAndRespond(t, `{"input": "hi", "priority": "high"}`, &models.TaskCreateRequest{}, false)
+
+	assertFieldErrors(t, code, resp, []map[string]interface{}{
+		{"field": "priority", "tag": "type", "message": "类型错误，应为 number"},
+	})
+}
+
+func TestValidationErrorUnknownField(t *testing.T) {
+	code, resp := bindAndRespond(t, `{"input": "hi", "priorty": 3}`, &models.TaskCreateRequest{}, true)
+
+	assertFieldErrors(t, code, resp, []map[string]interface{}{
+		{"field": "priorty", "tag": "unknown", "message": "不支持的字段"},
+	})
+}
+
+func TestValidationErrorMalformedJSON(t *testing.T) {
+	code, resp := bindAndRespond(t, `{"input": `, &models.TaskCreateRequest{}, false)
+
+	if code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", code)
+	}
+	if _, hasData := resp["data"]; hasData {
+		t.Errorf("response = %v, want no field details for malformed JSON", resp)
+	}
+}
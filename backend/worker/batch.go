@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// batchPollInterval 凑批期间队列为空时的轮询间隔
+const batchPollInterval = 50 * time.Millisecond
+
+// batchSize 获取嵌入任务每批最多的任务数，未开启批量执行时为 1
+func (w *Worker) batchSize() int {
+	if w.config.Worker.BatchSize > 1 {
+		return w.config.Worker.BatchSize
+	}
+	return 1
+}
+
+// processEmbeddingBatch 以 first 为首凑一批嵌入任务一次执行。凑批时取到的其他类型任务在批次完成后逐个执行
+func (w *Worker) processEmbeddingBatch(first *models.Task) error {
+	batch, others := w.collectBatch(first)
+
+	err := w.executeEmbeddingBatch(batch)
+	for _, task := range others {
+		if w.ctx.Err() != nil {
+			w.requeueInterrupted(task)
+			continue
+		}
+		if execErr := w.executeTask(task); execErr != nil {
+			w.logger.WithError(execErr).WithField("worker_id", w.id).Error("Error processing task")
+		}
+	}
+	return err
+}
+
+// collectBatch 在 worker.batch_wait 内继续为本模型领取任务，直到凑满 batch_size 个嵌入任务
+func (w *Worker) collectBatch(first *models.Task) (batch, others []*models.Task) {
+	batch = []*models.Task{first}
+	size := w.batchSize()
+	deadline := time.Now().Add(w.config.Worker.BatchWait)
+
+	for len(batch) < size {
+		items, err := w.queueManager.DequeueBatch(w.ctx, w.modelID, w.getTaskTypes(), size-len(batch))
+		if err != nil {
+			w.logger.WithError(err).WithField("worker_id", w.id).Warn("Failed to dequeue batch")
+			break
+		}
+
+		for _, item := range items {
+			task, err := w.taskService.GetTask(item.TaskID)
+			if errors.Is(err, services.ErrTaskNotFound) {
+				w.discardOrphan(item)
+				continue
+			}
+			if err != nil {
+				w.logger.WithError(err).WithField("task_id", item.TaskID).Error("Failed to get task")
+				w.releaseItem(item)
+				continue
+			}
+			task.TraceParent = item.TraceParent
+			if task.IsCompleted() {
+				_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+				continue
+			}
+			if w.dropExpired(task) {
+				continue
+			}
+			if task.Type == models.TaskTypeEmbedding {
+				batch = append(batch, task)
+			} else {
+				others = append(others, task)
+			}
+		}
+
+		if len(batch) >= size || !time.Now().Before(deadline) {
+			break
+		}
+		if err := sleepContext(w.ctx, batchPollInterval); err != nil {
+			break
+		}
+	}
+	return batch, others
+}
+
+// executeEmbeddingBatch 一次调用模型完成一批嵌入任务，结果按顺序分发给各任务。
+// 用户取消其中的任务不会中断整批调用，只丢弃该任务的结果
+func (w *Worker) executeEmbeddingBatch(tasks []*models.Task) error {
+	w.setBusy(tasks[0].ID)
+	defer w.setIdle()
+
+	w.logger.WithFields(logrus.Fields{
+		"worker_id":  w.id,
+		"model_id":   w.modelID,
+		"batch_size": len(tasks),
+	}).Info("Executing embedding batch")
+
+	model, err := w.modelService.GetModel(tasks[0].ModelID)
+	if err != nil {
+		for _, task := range tasks {
+			w.failUnexecuted(task, "Failed to get model information")
+		}
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+
+	breaker := w.breakers.get(model.ID)
+	if allowed, delay := breaker.allow(); !allowed {
+		for _, task := range tasks {
+			w.deferTask(task, delay, "Model circuit breaker open, task deferred")
+		}
+		return nil
+	}
+
+	// 一批任务合并为一次上游调用，只占用一个限流令牌
+	if allowed, delay := w.acquireRateLimit(model); !allowed {
+		breaker.release()
+		for _, task := range tasks {
+			w.deferTask(task, delay, "Model rate limit reached, task deferred")
+		}
+		return nil
+	}
+
+	// 先登记再标记开始执行，与单任务执行一致
+	started := make([]*models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		w.registry.register(task.ID, func() {})
+		if err := w.taskService.StartTask(task.ID); err != nil {
+			w.registry.unregister(task.ID)
+			if errors.Is(err, services.ErrInvalidStatusTransition) {
+				w.skipUnstartable(task)
+			} else {
+				w.taskLogger(task).WithError(err).Error("Failed to mark task as started")
+				w.deferTask(task, w.config.Queue.RetryDelay, "Task deferred after failing to mark it as started")
+			}
+			continue
+		}
+		started = append(started, task)
+	}
+	if len(started) == 0 {
+		breaker.release()
+		return nil
+	}
+
+	timeout := w.getTaskTimeout(model)
+	execCtx, cancel := context.WithTimeout(w.ctx, timeout)
+	defer cancel()
+
+	inputs := make([]string, len(started))
+	for i, task := range started {
+		inputs[i] = task.Input
+	}
+	outputs, err := w.embedBatch(execCtx, model, inputs)
+	if err == nil && len(outputs) != len(started) {
+		err = fmt.Errorf("embedding batch returned %d results for %d inputs", len(outputs), len(started))
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("execution timeout after %s", timeout)
+	}
+	if errors.Is(err, context.Canceled) && w.ctx.Err() != nil {
+		breaker.release()
+	} else {
+		w.recordCircuit(breaker, model, err)
+	}
+
+	for i, task := range started {
+		cancelled := w.registry.unregister(task.ID)
+		switch {
+		case cancelled:
+			w.finishCancelled(task)
+		case err != nil && errors.Is(err, context.Canceled) && w.ctx.Err() != nil:
+			// Worker 被强制停止，整批放回队列
+			w.requeueInterrupted(task)
+		case err != nil:
+			w.finishFailed(task, model, err)
+		default:
+			w.finishCompleted(task, model, outputs[i], nil)
+		}
+	}
+
+	if err != nil && w.ctx.Err() == nil {
+		return fmt.Errorf("embedding batch failed: %w", err)
+	}
+	return nil
+}
+
+// embedBatch 批量向量化，输出与输入一一对应。
+// 这里应该实现实际的批量调用（如 OpenAI embeddings 接口的 input 数组），请求需绑定 ctx 以便超时后取消
+func (w *Worker) embedBatch(ctx context.Context, model *models.Model, inputs []string) ([]string, error) {
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
+	// 模拟批量向量化结果
+	outputs := make([]string, len(inputs))
+	for i := range inputs {
+		outputs[i] = "[0.1, 0.2, 0.3]"
+	}
+	return outputs, nil
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// createEmbeddingTasks 创建 n 个嵌入任务并按创建顺序入队
+func (env *taskTestEnv) createEmbeddingTasks(t *testing.T, n int) []*models.Task {
+	t.Helper()
+	tasks := make([]*models.Task, 0, n)
+	for i := 0; i < n; i++ {
+		task := env.createTask(t, models.TaskStatusPending)
+		task.Type = models.TaskTypeEmbedding
+		if err := env.db.Model(task).Update("type", task.Type).Error; err != nil {
+			t.Fatalf("update task type: %v", err)
+		}
+		if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+			t.Fatalf("enqueue task: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// captureBatchSizes 把 Worker 日志改为 JSON 输出，返回读取每次批量执行记录的 batch_size 的函数
+func captureBatchSizes(t *testing.T, w *Worker) func() []int {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	w.logger = logger
+	return func() []int {
+		var sizes []int
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry struct {
+				Msg       string `json:"msg"`
+				BatchSize int    `json:"batch_size"`
+			}
+			if line == "" || json.Unmarshal([]byte(line), &entry) != nil {
+				continue
+			}
+			if entry.Msg == "Executing embedding batch" {
+				sizes = append(sizes, entry.BatchSize)
+			}
+		}
+		return sizes
+	}
+}
+
+func TestProcessNextTaskBatchesEmbeddings(t *testing.T) {
+	tests := []struct {
+		name          string
+		batchSize     int
+		queued        int
+		wantBatches   []int
+		wantCompleted int
+	}{
+		// 队列中的嵌入任务合并为一次调用
+		{"whole queue in one batch", 3, 3, []int{3}, 3},
+		// 一批最多 batch_size 个，多出的任务留在队列中
+		{"capped at batch size", 2, 3, []int{2}, 2},
+		// 队列中只有一个任务时不等满批次，到期后直接执行
+		{"partial batch after wait", 4, 1, []int{1}, 1},
+		// 未开启批量执行时逐个执行
+		{"batching disabled", 1, 3, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			env.cfg.Worker.BatchSize = tt.batchSize
+			env.cfg.Worker.BatchWait = 0
+			if err := env.queue.SetModelCapacity(context.Background(), env.model.ID, 10); err != nil {
+				t.Fatalf("set capacity: %v", err)
+			}
+			batchSizes := captureBatchSizes(t, env.worker)
+			tasks := env.createEmbeddingTasks(t, tt.queued)
+
+			if err := env.worker.processNextTask(); err != nil {
+				t.Fatalf("processNextTask: %v", err)
+			}
+
+			sizes := batchSizes()
+			if len(sizes) != len(tt.wantBatches) {
+				t.Fatalf("batches = %v, want %v", sizes, tt.wantBatches)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.wantBatches[i] {
+					t.Fatalf("batches = %v, want %v", sizes, tt.wantBatches)
+				}
+			}
+
+			// 先入队的任务先执行，每个任务都拿到自己的向量结果
+			for i, task := range tasks {
+				got := env.reloadTask(t, task.ID)
+				if i < tt.wantCompleted {
+					if got.Status != models.TaskStatusCompleted || got.Output == nil || *got.Output == "" {
+						t.Fatalf("task %d: status %s, want completed with output", i, got.Status)
+					}
+					continue
+				}
+				if got.Status != models.TaskStatusPending {
+					t.Fatalf("task %d: status %s, want pending", i, got.Status)
+				}
+			}
+			if n := processingCount(t, env.redis); n != 0 {
+				t.Fatalf("expected processing queue empty, got %d", n)
+			}
+			if n := env.modelInflight(t); n != 0 {
+				t.Fatalf("expected model inflight 0, got %d", n)
+			}
+		})
+	}
+}
+
+func TestProcessNextTaskRunsOtherTypesAfterBatch(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.BatchSize = 3
+	env.cfg.Worker.BatchWait = 0
+	if err := env.queue.SetModelCapacity(context.Background(), env.model.ID, 10); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	batchSizes := captureBatchSizes(t, env.worker)
+
+	// 嵌入任务之间夹着一个文本生成任务
+	first := env.createEmbeddingTasks(t, 1)[0]
+	text := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.EnqueueTask(context.Background(), text); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	second := env.createEmbeddingTasks(t, 1)[0]
+
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+
+	// 两个嵌入任务同批执行，凑批时取到的文本生成任务在批次完成后单独执行
+	if sizes := batchSizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("batches = %v, want [2]", sizes)
+	}
+	for _, task := range []*models.Task{first, second} {
+		if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusCompleted {
+			t.Fatalf("task %d: status %s, want completed", task.ID, got.Status)
+		}
+	}
+	// 测试模型不支持文本生成，任务执行后失败，说明它没有被留在处理中队列
+	if got := env.reloadTask(t, text.ID); got.StartedAt == nil || got.Status != models.TaskStatusFailed {
+		t.Fatalf("text task: status %s, want executed after the batch", got.Status)
+	}
+	if n := processingCount(t, env.redis); n != 0 {
+		t.Fatalf("expected processing queue empty, got %d", n)
+	}
+}
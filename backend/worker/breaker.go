@@ -0,0 +1,229 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// circuitBreaker 单个模型的熔断器，记录最近 window 次上游调用的结果。
+// closed 时失败率达到阈值转为 open；open 持续 cooldown 后转为 half_open 并只放行一次试探调用，
+// 试探成功回到 closed，失败重新 open
+type circuitBreaker struct {
+	mu       sync.Mutex
+	cfg      config.CircuitBreakerConfig
+	state    models.CircuitState
+	outcomes []bool // 环形缓冲区，true 表示失败
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// newCircuitBreaker 创建处于 closed 状态的熔断器
+func newCircuitBreaker(cfg config.CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		cfg:      cfg,
+		state:    models.CircuitClosed,
+		outcomes: make([]bool, cfg.Window),
+		now:      time.Now,
+	}
+}
+
+// allow 判断是否可以调用上游，不允许时返回距离冷却结束的时间。
+// half_open 状态下放行的试探调用结束后必须调用 record 或 release
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case models.CircuitOpen:
+		remaining := b.cfg.Cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return false, remaining
+		}
+		b.state = models.CircuitHalfOpen
+		b.probing = true
+		return true, 0
+	case models.CircuitHalfOpen:
+		if b.probing {
+			// 试探调用尚未结束，其他任务等待一个冷却周期后再试
+			return false, b.cfg.Cooldown
+		}
+		b.probing = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// record 记录一次上游调用结果，返回状态是否发生变化
+func (b *circuitBreaker) record(failed bool) (models.CircuitState, bool) {
+	if b == nil {
+		return models.CircuitClosed, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.state
+	switch b.state {
+	case models.CircuitHalfOpen:
+		b.probing = false
+		if failed {
+			b.trip()
+		} else {
+			b.reset()
+		}
+	case models.CircuitClosed:
+		b.push(failed)
+		if b.count >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRate*float64(b.count) {
+			b.trip()
+		}
+	}
+	// open 状态下的结果来自熔断前已开始的调用，不影响状态
+	return b.state, b.state != previous
+}
+
+// release 放行的调用没有真正请求上游（任务被取消、限流延后等）时归还试探机会
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == models.CircuitHalfOpen {
+		b.probing = false
+	}
+}
+
+// currentState 返回熔断器状态
+func (b *circuitBreaker) currentState() models.CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) push(failed bool) {
+	if b.count == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.count++
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+}
+
+func (b *circuitBreaker) trip() {
+	b.state = models.CircuitOpen
+	b.openedAt = b.now()
+}
+
+func (b *circuitBreaker) reset() {
+	b.state = models.CircuitClosed
+	b.outcomes = make([]bool, b.cfg.Window)
+	b.next, b.count, b.failures = 0, 0, 0
+}
+
+// breakerRegistry 本实例各模型的熔断器，由同一模型的所有 Worker 共享
+type breakerRegistry struct {
+	mu       sync.Mutex
+	cfg      config.CircuitBreakerConfig
+	breakers map[uint64]*circuitBreaker
+}
+
+// newBreakerRegistry 创建熔断器注册表，cfg.Window 为 0 时不启用熔断
+func newBreakerRegistry(cfg config.CircuitBreakerConfig) *breakerRegistry {
+	return &breakerRegistry{
+		cfg:      cfg,
+		breakers: make(map[uint64]*circuitBreaker),
+	}
+}
+
+// get 获取模型的熔断器，未启用熔断时返回 nil（nil 熔断器总是放行）
+func (r *breakerRegistry) get(modelID uint64) *circuitBreaker {
+	if r == nil || r.cfg.Window <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	breaker, ok := r.breakers[modelID]
+	if !ok {
+		breaker = newCircuitBreaker(r.cfg)
+		r.breakers[modelID] = breaker
+	}
+	return breaker
+}
+
+// states 返回已有调用记录的模型的熔断器状态
+func (r *breakerRegistry) states() map[uint64]models.CircuitState {
+	states := make(map[uint64]models.CircuitState)
+	if r == nil {
+		return states
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for modelID, breaker := range r.breakers {
+		states[modelID] = breaker.currentState()
+	}
+	return states
+}
+
+// isProviderFailure 判断执行错误是否说明上游不可用：超时、网络错误、429 和 5xx 计为失败，
+// 其他 4xx 通常是请求本身的问题，不计入熔断
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	// 输入模板错误是模型配置问题，上游并未被调用
+	var promptErr *promptTemplateError
+	if errors.As(err, &promptErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// recordCircuit 将一次上游调用的结果计入模型的熔断器，状态变化时记录日志
+func (w *Worker) recordCircuit(breaker *circuitBreaker, model *models.Model, err error) {
+	state, changed := breaker.record(isProviderFailure(err))
+	if !changed {
+		return
+	}
+	logger := w.logger.WithFields(logrus.Fields{
+		"worker_id":     w.id,
+		"model_id":      model.ID,
+		"model_name":    model.Name,
+		"circuit_state": state,
+	})
+	switch state {
+	case models.CircuitOpen:
+		logger.WithError(err).WithField("cooldown", breaker.cfg.Cooldown.String()).Warn("Model circuit breaker opened")
+	case models.CircuitClosed:
+		logger.Info("Model circuit breaker closed")
+	}
+}
+
+// GetCircuitStates 获取本实例各模型的熔断器状态
+func (m *Manager) GetCircuitStates() map[uint64]models.CircuitState {
+	return m.breakers.states()
+}
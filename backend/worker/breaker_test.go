@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestCircuitBreakerStates(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b := newCircuitBreaker(config.CircuitBreakerConfig{Window: 4, MinRequests: 2, FailureRate: 0.75, Cooldown: time.Minute})
+	b.now = clock.Now
+
+	expectState := func(want models.CircuitState) {
+		t.Helper()
+		if got := b.currentState(); got != want {
+			t.Fatalf("state = %s, want %s", got, want)
+		}
+	}
+	expectAllow := func(want bool, wantDelay time.Duration) {
+		t.Helper()
+		allowed, delay := b.allow()
+		if allowed != want || delay != wantDelay {
+			t.Fatalf("allow = %v, %v, want %v, %v", allowed, delay, want, wantDelay)
+		}
+	}
+
+	// closed：失败率低于阈值时继续放行
+	for _, failed := range []bool{false, true, true} {
+		if state, changed := b.record(failed); changed || state != models.CircuitClosed {
+			t.Fatalf("record(%v) = %s, %v, want closed without change", failed, state, changed)
+		}
+		expectAllow(true, 0)
+	}
+
+	// 最近 4 次中 3 次失败，达到阈值后 open，冷却期内不放行
+	if state, changed := b.record(true); !changed || state != models.CircuitOpen {
+		t.Fatalf("record = %s, %v, want open", state, changed)
+	}
+	expectAllow(false, time.Minute)
+	clock.Advance(20 * time.Second)
+	expectAllow(false, 40*time.Second)
+	// 熔断前已开始的调用结果不影响状态
+	if _, changed := b.record(false); changed {
+		t.Fatal("record in open state changed the state")
+	}
+
+	// 冷却结束后 half_open，只放行一次试探调用
+	clock.Advance(40 * time.Second)
+	expectAllow(true, 0)
+	expectState(models.CircuitHalfOpen)
+	expectAllow(false, time.Minute)
+	// 试探调用没有请求上游时归还试探机会
+	b.release()
+	expectAllow(true, 0)
+
+	// 试探失败重新 open
+	if state, changed := b.record(true); !changed || state != models.CircuitOpen {
+		t.Fatalf("failed probe = %s, %v, want open", state, changed)
+	}
+	expectAllow(false, time.Minute)
+
+	// 再次冷却后试探成功，回到 closed 并清空窗口
+	clock.Advance(time.Minute)
+	expectAllow(true, 0)
+	if state, changed := b.record(false); !changed || state != models.CircuitClosed {
+		t.Fatalf("successful probe = %s, %v, want closed", state, changed)
+	}
+	if state, _ := b.record(true); state != models.CircuitClosed {
+		t.Fatalf("single failure after reset = %s, want closed below min_requests", state)
+	}
+	expectAllow(true, 0)
+}
+
+func TestCircuitBreakerSlidingWindow(t *testing.T) {
+	b := newCircuitBreaker(config.CircuitBreakerConfig{Window: 3, MinRequests: 3, FailureRate: 1, Cooldown: time.Minute})
+
+	// 较早的成功移出窗口后，最近 3 次全部失败才 open
+	for i, failed := range []bool{false, true, true} {
+		if state, _ := b.record(failed); state != models.CircuitClosed {
+			t.Fatalf("record %d = %s, want closed", i, state)
+		}
+	}
+	if state, _ := b.record(true); state != models.CircuitOpen {
+		t.Fatalf("state = %s, want open once the window holds only failures", state)
+	}
+}
+
+func TestBreakerRegistryDisabled(t *testing.T) {
+	registry := newBreakerRegistry(config.CircuitBreakerConfig{})
+	b := registry.get(1)
+	if b != nil {
+		t.Fatalf("breaker = %+v, want nil when window is 0", b)
+	}
+	// 未启用熔断时总是放行
+	for i := 0; i < 3; i++ {
+		b.record(true)
+	}
+	if allowed, _ := b.allow(); !allowed {
+		t.Fatal("disabled breaker rejected a call")
+	}
+	if states := registry.states(); len(states) != 0 {
+		t.Fatalf("states = %v, want none", states)
+	}
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"server error", fmt.Errorf("call: %w", &statusError{StatusCode: http.StatusBadGateway}), true},
+		{"rate limited", &statusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad request", &statusError{StatusCode: http.StatusBadRequest}, false},
+		{"network error", errors.New("connection refused"), true},
+		{"timeout", context.DeadlineExceeded, true},
+		{"cancelled", fmt.Errorf("call: %w", context.Canceled), false},
+		{"prompt template", &promptTemplateError{err: errors.New("bad template")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProviderFailure(tt.err); got != tt.want {
+				t.Fatalf("isProviderFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessNextTaskDefersWhileCircuitOpen(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.CircuitBreaker = config.CircuitBreakerConfig{Window: 2, MinRequests: 2, FailureRate: 1, Cooldown: time.Minute}
+	env.worker.breakers = newBreakerRegistry(env.cfg.Worker.CircuitBreaker)
+	ctx := context.Background()
+
+	// 测试模型不支持文本生成，连续两次调用失败后熔断
+	tasks := make([]*models.Task, 3)
+	for i := range tasks {
+		tasks[i] = env.createTask(t, models.TaskStatusPending)
+		if err := env.queue.EnqueueTask(ctx, tasks[i]); err != nil {
+			t.Fatalf("enqueue task: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := env.worker.processNextTask(); err == nil {
+			t.Fatalf("task %d: expected execution error", i)
+		}
+	}
+	if states := env.worker.breakers.states(); states[env.model.ID] != models.CircuitOpen {
+		t.Fatalf("circuit states = %v, want model %d open", states, env.model.ID)
+	}
+
+	// 熔断期间不执行任务，放回延迟队列并释放并发名额
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+	got := env.reloadTask(t, tasks[2].ID)
+	if got.Status != models.TaskStatusPending || got.StartedAt != nil {
+		t.Fatalf("deferred task: status %s, started_at %v, want pending and not started", got.Status, got.StartedAt)
+	}
+	delayed, err := env.redis.ZMembers(env.cfg.Queue.DelayedQueue)
+	if err != nil || len(delayed) != 1 {
+		t.Fatalf("delayed queue = %v (err %v), want the deferred task", delayed, err)
+	}
+	if score, _ := env.redis.ZScore(env.cfg.Queue.DelayedQueue, delayed[0]); time.Unix(int64(score), 0).Before(time.Now().Add(50 * time.Second)) {
+		t.Fatalf("deferred until %v, want about one cooldown from now", time.Unix(int64(score), 0))
+	}
+	if n := processingCount(t, env.redis); n != 0 {
+		t.Fatalf("expected processing queue empty, got %d", n)
+	}
+	if n := env.modelInflight(t); n != 0 {
+		t.Fatalf("expected model inflight 0, got %d", n)
+	}
+}
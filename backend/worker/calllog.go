@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// modelCallAttempt 一次模型服务 HTTP 往返的结果，DoWithRetry 每次重试各算一次
+type modelCallAttempt struct {
+	Attempt    int
+	StatusCode int // 连接失败时为 0
+	Latency    time.Duration
+	Err        error
+}
+
+// attemptTransport 记录经过的每次 HTTP 往返的状态码和耗时（到收到响应头为止）
+type attemptTransport struct {
+	base     http.RoundTripper
+	attempts int
+	observe  func(modelCallAttempt)
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *attemptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.attempts++
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	attempt := modelCallAttempt{Attempt: t.attempts, Latency: time.Since(start), Err: err}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+	}
+	t.observe(attempt)
+	return resp, err
+}
+
+// observeModelCalls 将模型请求摘要和每次尝试的状态、耗时以 debug 级别写入任务日志，
+// 单个任务的调用过程在任务日志中即可排查。只记录地址（不含查询参数）、请求头名称和请求体大小，不记录密钥和内容
+func (w *Worker) observeModelCalls(cfg *localRequestConfig, task *models.Task) {
+	cfg.OnRequest = func(body []byte) {
+		w.taskService.AddTaskLog(task.ID, models.LogLevelDebug, "Model request", models.LogData{
+			"url":        redactURL(cfg.URL),
+			"model":      cfg.ModelName,
+			"headers":    headerNames(cfg.Headers),
+			"body_bytes": len(body),
+		})
+	}
+	cfg.OnAttempt = func(attempt modelCallAttempt) {
+		data := models.LogData{
+			"attempt":    attempt.Attempt,
+			"status":     attempt.StatusCode,
+			"latency_ms": attempt.Latency.Milliseconds(),
+		}
+		if attempt.Err != nil {
+			data["error"] = attempt.Err.Error()
+		}
+		w.taskService.AddTaskLog(task.ID, models.LogLevelDebug, "Model response", data)
+	}
+}
+
+// redactURL 去掉地址中的用户信息、查询参数和片段，这些部分可能携带密钥
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// headerNames 返回排序后的请求头名称，不包含取值
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestCancelTaskInterruptsRunningWorker(t *testing.T) {
+	env := newTaskTestEnv(t)
+
+	// 上游一直不返回，直到调用方断开请求；读完请求体后服务端才能感知连接断开
+	started := make(chan struct{}, 1)
+	aborted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			aborted <- struct{}{}
+		case <-time.After(10 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer server.Close()
+
+	if err := env.db.Model(env.model).Updates(map[string]interface{}{
+		"type":   models.ModelTypeOpenAI,
+		"config": models.ModelConfig{"base_url": server.URL, "api_key": "secret", "task_timeout": "30s"},
+	}).Error; err != nil {
+		t.Fatalf("update model: %v", err)
+	}
+
+	m := newPoolTestManager(t, env)
+	go m.listenTaskCancels()
+	if !waitFor(2*time.Second, func() bool { return len(env.redis.PubSubChannels("")) > 0 }) {
+		t.Fatal("manager never subscribed to task cancels")
+	}
+
+	task := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+		t.Fatalf("start worker: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker never called the model")
+	}
+
+	if err := env.tasks.CancelTask(context.Background(), task.ID); err != nil {
+		t.Fatalf("cancel task: %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight model request was not aborted after cancel")
+	}
+
+	// Worker 放弃任务后恢复空闲，且不会覆盖 cancelled 状态
+	worker := m.activeWorkers(env.model.ID)[0]
+	if !waitFor(2*time.Second, func() bool { return !worker.IsBusy() }) {
+		t.Fatal("worker still busy after cancel")
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled {
+		t.Fatalf("task status = %s, want cancelled", got.Status)
+	}
+	if got.Output != nil && *got.Output != "" {
+		t.Fatalf("cancelled task got output %q", *got.Output)
+	}
+	assertClaimReleased(t, env)
+}
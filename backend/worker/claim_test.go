@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm"
+)
+
+// modelInflight 返回模型占用的并发名额数
+func (env *taskTestEnv) modelInflight(t *testing.T) int64 {
+	t.Helper()
+	inflight, err := env.queue.GetModelInflight(context.Background())
+	if err != nil {
+		t.Fatalf("get inflight: %v", err)
+	}
+	return inflight[env.model.ID]
+}
+
+// failUpdates 让之后的 UPDATE 都返回错误，模拟数据库写入不可用
+func failUpdates(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	name := "test:fail_updates"
+	if err := db.Callback().Update().Before("gorm:update").Register(name, func(tx *gorm.DB) {
+		tx.AddError(errors.New("database unavailable"))
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	t.Cleanup(func() { db.Callback().Update().Remove(name) })
+}
+
+// assertClaimReleased 检查任务不在处理中队列且模型并发名额已归还
+func assertClaimReleased(t *testing.T, env *taskTestEnv) {
+	t.Helper()
+	if n := processingCount(t, env.redis); n != 0 {
+		t.Fatalf("expected processing queue empty, got %d", n)
+	}
+	if n := env.modelInflight(t); n != 0 {
+		t.Fatalf("expected model inflight 0, got %d", n)
+	}
+}
+
+func TestExecuteTaskReleasesClaimWhenModelMissing(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	env.claimTask(t, task)
+
+	// 模型在任务排队期间被删除，任务标记为失败并释放处理中队列项和并发名额
+	if err := env.db.Unscoped().Delete(env.model).Error; err != nil {
+		t.Fatalf("delete model: %v", err)
+	}
+	if err := env.worker.executeTask(task); err == nil {
+		t.Fatalf("expected model lookup error")
+	}
+
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusFailed {
+		t.Fatalf("expected failed, got %s", got.Status)
+	}
+	assertClaimReleased(t, env)
+}
+
+func TestExecuteTaskDefersWhenTaskCannotBeFailed(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	env.claimTask(t, task)
+
+	// 读取模型和标记失败都出错时任务仍为 pending，放回延迟队列稍后重新领取
+	if err := env.db.Unscoped().Delete(env.model).Error; err != nil {
+		t.Fatalf("delete model: %v", err)
+	}
+	failUpdates(t, env.db)
+	if err := env.worker.executeTask(task); err == nil {
+		t.Fatalf("expected model lookup error")
+	}
+
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusPending {
+		t.Fatalf("expected pending, got %s", got.Status)
+	}
+	assertClaimReleased(t, env)
+	if !env.redis.Exists("llm_tasks:delayed") {
+		t.Fatalf("expected task in delayed queue")
+	}
+}
+
+func TestExecuteTaskDefersWhenStartFails(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	env.claimTask(t, task)
+
+	// 标记开始执行失败时不调用模型，任务放回延迟队列
+	failUpdates(t, env.db)
+	if err := env.worker.executeTask(task); err == nil {
+		t.Fatalf("expected start error")
+	}
+
+	if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusPending {
+		t.Fatalf("expected pending, got %s", got.Status)
+	}
+	assertClaimReleased(t, env)
+	if !env.redis.Exists("llm_tasks:delayed") {
+		t.Fatalf("expected task in delayed queue")
+	}
+}
+
+func TestExecuteEmbeddingBatchReleasesClaimsWhenModelMissing(t *testing.T) {
+	env := newTaskTestEnv(t)
+	first := env.createTask(t, models.TaskStatusPending)
+	second := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.SetModelCapacity(context.Background(), env.model.ID, 2); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	env.claimTask(t, first)
+	env.claimTask(t, second)
+
+	if err := env.db.Unscoped().Delete(env.model).Error; err != nil {
+		t.Fatalf("delete model: %v", err)
+	}
+	if err := env.worker.executeEmbeddingBatch([]*models.Task{first, second}); err == nil {
+		t.Fatalf("expected model lookup error")
+	}
+
+	for _, task := range []*models.Task{first, second} {
+		if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusFailed {
+			t.Fatalf("expected task %d failed, got %s", task.ID, got.Status)
+		}
+	}
+	assertClaimReleased(t, env)
+}
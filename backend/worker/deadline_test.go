@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// createDeadlineTask 创建并入队截止时间为 deadline 的摘要任务；queued 为 false 时队列项不携带截止时间，
+// 模拟领取之后才过期的任务
+func (env *taskTestEnv) createDeadlineTask(t *testing.T, deadline time.Time, queued bool) *models.Task {
+	t.Helper()
+	task := env.createTask(t, models.TaskStatusPending)
+	task.Type = models.TaskTypeSummarization
+	if err := env.db.Model(task).Updates(map[string]interface{}{"type": task.Type, "deadline": deadline}).Error; err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	if queued {
+		task.Deadline = &deadline
+	}
+	if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	return task
+}
+
+func TestProcessNextTaskRunsTaskBeforeDeadline(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createDeadlineTask(t, time.Now().Add(time.Minute), true)
+
+	// 在截止时间之前领取的任务正常执行
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCompleted || got.StartedAt == nil {
+		t.Fatalf("status = %s, want completed", got.Status)
+	}
+}
+
+func TestProcessNextTaskSkipsTaskPastDeadline(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createDeadlineTask(t, time.Now().Add(-time.Second), false)
+
+	// 领取时已超过截止时间，取消而不是执行
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled || got.StartedAt != nil {
+		t.Fatalf("status = %s started_at = %v, want cancelled without running", got.Status, got.StartedAt)
+	}
+	if got.ErrorMessage == nil || *got.ErrorMessage != models.DeadlineExceededMessage {
+		t.Fatalf("error_message = %v, want %q", got.ErrorMessage, models.DeadlineExceededMessage)
+	}
+	if n := processingCount(t, env.redis); n != 0 {
+		t.Fatalf("expected processing queue empty, got %d", n)
+	}
+	if n := env.modelInflight(t); n != 0 {
+		t.Fatalf("expected model inflight 0, got %d", n)
+	}
+}
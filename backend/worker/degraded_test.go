@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newDegradedTestManager 创建按队列深度自动切换降级模式的 Worker 管理器
+func newDegradedTestManager(env *taskTestEnv, depth int64) *Manager {
+	env.cfg.Queue.Degraded.AutoEnabled = true
+	env.cfg.Queue.Degraded.QueueDepthThreshold = depth
+	env.cfg.Queue.Degraded.MinPriority = int(models.TaskPriorityHigh)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := NewManager(env.cfg, env.db, env.queue, env.tasks, env.models, nil, logger)
+	m.ctx = context.Background()
+	return m
+}
+
+func TestCheckDegradedModeFollowsQueueDepth(t *testing.T) {
+	env := newTaskTestEnv(t)
+	m := newDegradedTestManager(env, 2)
+	ctx := context.Background()
+
+	low := env.createTask(t, models.TaskStatusPending)
+	env.db.Model(low).Update("priority", models.TaskPriorityLow)
+	low.Priority = models.TaskPriorityLow
+	high := env.createTask(t, models.TaskStatusPending)
+	high.Priority = models.TaskPriorityHigh
+	for _, task := range []*models.Task{low, high} {
+		if err := env.queue.EnqueueTask(ctx, task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	// 队列深度达到阈值，自动进入降级模式，低优先级任务被保留
+	m.checkDegradedMode()
+	mode, err := env.queue.GetDegradedMode(ctx)
+	if err != nil || !mode.Enabled || mode.Source != models.DegradedModeSourceAuto || mode.MinPriority != models.TaskPriorityHigh {
+		t.Fatalf("expected auto degraded mode, got %+v, %v", mode, err)
+	}
+	item, err := env.queue.DequeueTask(ctx, env.model.ID, nil)
+	if err != nil || item == nil || item.TaskID != high.ID {
+		t.Fatalf("expected high priority task, got %+v, %v", item, err)
+	}
+	if item, err := env.queue.DequeueTask(ctx, env.model.ID, nil); err != nil || item != nil {
+		t.Fatalf("expected low priority task to be held, got %+v, %v", item, err)
+	}
+
+	// 队列深度回落后自动退出降级，被保留的任务恢复消费
+	m.checkDegradedMode()
+	if mode, err := env.queue.GetDegradedMode(ctx); err != nil || mode.Enabled {
+		t.Fatalf("expected degraded mode cleared, got %+v, %v", mode, err)
+	}
+	item, err = env.queue.DequeueTask(ctx, env.model.ID, nil)
+	if err != nil || item == nil || item.TaskID != low.ID {
+		t.Fatalf("expected held task after recovery, got %+v, %v", item, err)
+	}
+}
+
+func TestCheckDegradedModeKeepsManualMode(t *testing.T) {
+	env := newTaskTestEnv(t)
+	m := newDegradedTestManager(env, 100)
+	ctx := context.Background()
+
+	// 人工开启的降级模式不会因为压力解除而被自动关闭
+	if err := env.queue.SetDegradedMode(ctx, models.TaskPriorityMedium, models.DegradedModeSourceManual, "maintenance"); err != nil {
+		t.Fatalf("set degraded mode: %v", err)
+	}
+	m.checkDegradedMode()
+
+	mode, err := env.queue.GetDegradedMode(ctx)
+	if err != nil || !mode.Enabled || mode.Source != models.DegradedModeSourceManual {
+		t.Fatalf("expected manual degraded mode to stay, got %+v, %v", mode, err)
+	}
+}
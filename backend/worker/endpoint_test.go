@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestResolveEndpointPrefersTaskOverride(t *testing.T) {
+	w := newStatusTestWorker()
+	w.config.Models.OpenAI.BaseURL = "https://api.openai.com/v1"
+	model := &models.Model{Type: models.ModelTypeOpenAI, Config: models.ModelConfig{"base_url": "https://model.example.com"}}
+
+	// 未设置覆盖时使用模型配置
+	endpoint, err := w.resolveEndpoint(&models.Task{}, model)
+	if err != nil {
+		t.Fatalf("resolveEndpoint: %v", err)
+	}
+	if endpoint.BaseURL != "https://model.example.com" || endpoint.Overridden {
+		t.Fatalf("unexpected endpoint without override: %+v", endpoint)
+	}
+
+	// 任务级覆盖优先于模型配置，并携带覆盖的请求头
+	task := &models.Task{ProviderOverride: &models.ProviderOverride{
+		BaseURL: "https://staging.example.com",
+		Headers: map[string]string{"X-Tenant": "a"},
+	}}
+	endpoint, err = w.resolveEndpoint(task, model)
+	if err != nil {
+		t.Fatalf("resolveEndpoint: %v", err)
+	}
+	if endpoint.BaseURL != "https://staging.example.com" || !endpoint.Overridden || endpoint.Headers["X-Tenant"] != "a" {
+		t.Fatalf("unexpected endpoint with override: %+v", endpoint)
+	}
+
+	// base_url 为空的覆盖视为未设置
+	task.ProviderOverride = &models.ProviderOverride{}
+	endpoint, err = w.resolveEndpoint(task, model)
+	if err != nil {
+		t.Fatalf("resolveEndpoint: %v", err)
+	}
+	if endpoint.Overridden {
+		t.Fatalf("empty override should fall back to model config: %+v", endpoint)
+	}
+}
+
+func TestModelEndpoint(t *testing.T) {
+	w := newStatusTestWorker()
+	w.config.Models.OpenAI.BaseURL = "https://api.openai.com/v1"
+
+	tests := []struct {
+		name    string
+		model   *models.Model
+		want    string
+		wantErr bool
+	}{
+		{"local host and port", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "localhost", "port": float64(8080)}}, "http://localhost:8080", false},
+		{"local string port", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "localhost", "port": "8080"}}, "http://localhost:8080", false},
+		{"local fractional port", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "localhost", "port": 8080.5}}, "", true},
+		{"local ipv6 host", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "::1", "port": float64(11434)}}, "http://[::1]:11434", false},
+		{"local missing port", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "localhost"}}, "", true},
+		{"local port out of range", &models.Model{Type: models.ModelTypeLocal, Config: models.ModelConfig{"host": "localhost", "port": float64(70000)}}, "", true},
+		{"openai base_url", &models.Model{Type: models.ModelTypeOpenAI, Config: models.ModelConfig{"base_url": "https://proxy.example.com"}}, "https://proxy.example.com", false},
+		{"openai default", &models.Model{Type: models.ModelTypeOpenAI, Config: models.ModelConfig{}}, "https://api.openai.com/v1", false},
+		{"openai base_url wrong type", &models.Model{Type: models.ModelTypeOpenAI, Config: models.ModelConfig{"base_url": true}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := modelEndpoint(w.config, tt.model)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", endpoint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if endpoint.BaseURL != tt.want || endpoint.Overridden {
+				t.Fatalf("endpoint = %+v, want %s", endpoint, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestFinishCompletedAfterCancelKeepsCancelled(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	env.claimTask(t, task)
+	if err := env.tasks.StartTask(task.ID); err != nil {
+		t.Fatalf("start task: %v", err)
+	}
+
+	// 模型返回结果之前任务被用户取消，结果被丢弃，不计入模型的成功请求
+	if err := env.tasks.CancelTask(context.Background(), task.ID); err != nil {
+		t.Fatalf("cancel task: %v", err)
+	}
+	env.worker.finishCompleted(task, env.model, "late output", nil)
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled || got.Output != nil {
+		t.Fatalf("expected cancelled task without output, got %s", got.Status)
+	}
+	if model := env.reloadModel(t); model.TotalRequests != 0 || model.SuccessRequests != 0 {
+		t.Fatalf("expected no request counted, got total %d success %d", model.TotalRequests, model.SuccessRequests)
+	}
+	if n := processingCount(t, env.redis); n != 0 {
+		t.Fatalf("expected processing queue empty, got %d", n)
+	}
+}
+
+func TestFinishFailedAfterCancelKeepsCancelled(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	env.claimTask(t, task)
+	if err := env.tasks.StartTask(task.ID); err != nil {
+		t.Fatalf("start task: %v", err)
+	}
+
+	if err := env.tasks.CancelTask(context.Background(), task.ID); err != nil {
+		t.Fatalf("cancel task: %v", err)
+	}
+	env.worker.finishFailed(task, env.model, errors.New("upstream error"))
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCancelled || got.ErrorMessage != nil {
+		t.Fatalf("expected cancelled task without error message, got %s", got.Status)
+	}
+	if model := env.reloadModel(t); model.TotalRequests != 0 {
+		t.Fatalf("expected no request counted, got %d", model.TotalRequests)
+	}
+}
+
+func TestFinishCompletedSetsOutputFormat(t *testing.T) {
+	tests := []struct {
+		taskType string
+		output   string
+		want     models.OutputFormat
+	}{
+		// 嵌入任务的输出是浮点数组的 JSON，其他任务为文本
+		{models.TaskTypeEmbedding, "[0.1, 0.2, 0.3]", models.OutputFormatJSON},
+		{models.TaskTypeTextGeneration, "hello", models.OutputFormatText},
+		{models.TaskTypeSummarization, "summary", models.OutputFormatText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.taskType, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			task := env.createTask(t, models.TaskStatusPending)
+			task.Type = tt.taskType
+			env.claimTask(t, task)
+			if err := env.tasks.StartTask(task.ID); err != nil {
+				t.Fatalf("start task: %v", err)
+			}
+
+			env.worker.finishCompleted(task, env.model, tt.output, nil)
+
+			got := env.reloadTask(t, task.ID)
+			if got.Status != models.TaskStatusCompleted || got.OutputFormat != tt.want {
+				t.Fatalf("status = %s output_format = %q, want completed with %q", got.Status, got.OutputFormat, tt.want)
+			}
+		})
+	}
+}
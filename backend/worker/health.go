@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// staleHeartbeats 心跳超过该倍数的心跳间隔未更新时视为 Worker 已停滞
+const staleHeartbeats = 2
+
+// IsHealthy 检查本实例的 Worker 是否存活，返回是否健康以及停滞 Worker 的说明
+func (m *Manager) IsHealthy() (bool, []string) {
+	var problems []string
+	for _, health := range m.GetWorkerHealth() {
+		if !health.Healthy {
+			problems = append(problems, fmt.Sprintf("worker %s (model %d) last heartbeat at %s",
+				health.WorkerID, health.ModelID, health.LastHeartbeat.Format(time.RFC3339)))
+		}
+	}
+	return len(problems) == 0, problems
+}
+
+// GetWorkerHealth 获取本实例各 Worker 的存活状态，心跳超过 2 个心跳间隔未更新的 Worker 为不健康
+func (m *Manager) GetWorkerHealth() []models.WorkerHealth {
+	staleAfter := staleHeartbeats * heartbeatInterval(m.config)
+	now := time.Now()
+
+	m.workersMutex.RLock()
+	health := make([]models.WorkerHealth, 0, len(m.workers))
+	for _, worker := range m.workers {
+		status := worker.GetStatus()
+		health = append(health, models.WorkerHealth{
+			WorkerID:      status.WorkerID,
+			ModelID:       status.ModelID,
+			Status:        status.Status,
+			LastHeartbeat: status.LastHeartbeat,
+			Healthy:       now.Sub(status.LastHeartbeat) <= staleAfter,
+		})
+	}
+	m.workersMutex.RUnlock()
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].WorkerID < health[j].WorkerID
+	})
+	return health
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestIsHealthyFlagsStaleHeartbeats(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.HeartbeatInterval = 10 * time.Second
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := NewManager(env.cfg, env.db, env.queue, env.tasks, env.models, nil, logger)
+
+	// 没有 Worker 时视为健康
+	if healthy, problems := m.IsHealthy(); !healthy || len(problems) != 0 {
+		t.Fatalf("empty pool: healthy=%v problems=%v", healthy, problems)
+	}
+
+	now := time.Now()
+	heartbeats := map[string]time.Time{
+		"worker-1-0": now,
+		"worker-1-1": now.Add(-19 * time.Second), // 未超过 2 个心跳间隔
+		"worker-1-2": now.Add(-21 * time.Second), // 停滞
+	}
+	for id, at := range heartbeats {
+		w := NewWorker(id, env.model.ID, env.cfg, env.queue, env.tasks, env.models, logger)
+		atomic.StoreInt64(&w.lastHeartbeat, at.UnixNano())
+		m.workers[id] = w
+	}
+
+	healthy, problems := m.IsHealthy()
+	if healthy {
+		t.Fatal("expected unhealthy pool with a stalled worker")
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "worker-1-2") {
+		t.Fatalf("problems = %v, want only worker-1-2", problems)
+	}
+
+	health := m.GetWorkerHealth()
+	if len(health) != 3 {
+		t.Fatalf("expected 3 workers in breakdown, got %d", len(health))
+	}
+	for i, want := range []bool{true, true, false} {
+		if health[i].Healthy != want {
+			t.Errorf("%s healthy = %v, want %v", health[i].WorkerID, health[i].Healthy, want)
+		}
+	}
+
+	// 恢复心跳后重新变为健康
+	m.workers["worker-1-2"].touchHeartbeat()
+	if healthy, problems := m.IsHealthy(); !healthy {
+		t.Fatalf("expected healthy after heartbeat, problems %v", problems)
+	}
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/database/testdb"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// taskTestEnv 连接内存数据库和 miniredis 的 Worker 测试环境
+type taskTestEnv struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	redis  *miniredis.Miniredis
+	queue  *queue.Manager
+	tasks  *services.TaskService
+	models *services.ModelService
+	model  *models.Model
+	worker *Worker
+}
+
+// newTaskTestEnv 创建测试环境，登记一个在线模型并为其创建一个未启动的 Worker
+func newTaskTestEnv(t *testing.T) *taskTestEnv {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newWarmupTestConfig()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db := testdb.New(t)
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	env := &taskTestEnv{
+		cfg:    cfg,
+		db:     db,
+		redis:  server,
+		queue:  queueManager,
+		tasks:  services.NewTaskService(db, queueManager, nil, cfg, logger),
+		models: services.NewModelService(db, logger),
+	}
+
+	env.model = &models.Model{
+		Name:       "test-model",
+		Type:       models.ModelTypeCustom,
+		Status:     models.ModelStatusOnline,
+		MaxWorkers: 1,
+	}
+	if err := db.Create(env.model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+
+	env.worker = NewWorker(workerIDFor(env.model.ID, 0), env.model.ID, cfg, queueManager, env.tasks, env.models, logger)
+	env.worker.ctx = context.Background()
+	env.worker.registry = newTaskRegistry()
+	env.worker.breakers = newBreakerRegistry(cfg.Worker.CircuitBreaker)
+	return env
+}
+
+// createTask 在数据库中创建指定状态的任务
+func (env *taskTestEnv) createTask(t *testing.T, status models.TaskStatus) *models.Task {
+	t.Helper()
+	task := &models.Task{
+		ModelID:  env.model.ID,
+		Type:     models.TaskTypeTextGeneration,
+		Input:    "hello",
+		Priority: models.TaskPriorityMedium,
+		Status:   status,
+	}
+	if err := env.db.Create(task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+// claimTask 将任务入队后由 Worker 领取，任务进入处理中队列并占用模型并发名额
+func (env *taskTestEnv) claimTask(t *testing.T, task *models.Task) {
+	t.Helper()
+	ctx := context.Background()
+	if err := env.queue.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	item, err := env.queue.DequeueTask(ctx, env.model.ID, nil)
+	if err != nil || item == nil || item.TaskID != task.ID {
+		t.Fatalf("expected to claim task %d, got %+v (err %v)", task.ID, item, err)
+	}
+}
+
+// reloadTask 从数据库读取任务的最新状态
+func (env *taskTestEnv) reloadTask(t *testing.T, id uint64) *models.Task {
+	t.Helper()
+	var task models.Task
+	if err := env.db.First(&task, id).Error; err != nil {
+		t.Fatalf("reload task %d: %v", id, err)
+	}
+	return &task
+}
+
+// reloadModel 从数据库读取模型的最新状态
+func (env *taskTestEnv) reloadModel(t *testing.T) *models.Model {
+	t.Helper()
+	var model models.Model
+	if err := env.db.First(&model, env.model.ID).Error; err != nil {
+		t.Fatalf("reload model: %v", err)
+	}
+	return &model
+}
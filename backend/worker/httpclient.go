@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter 单次重试最多按 Retry-After 等待的时间，避免服务端返回过大的值长时间占用 Worker
+const maxRetryAfter = time.Minute
+
+// statusError 上游服务返回非 2xx 状态码
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("model returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryableStatus 5xx 和 429 可以重试，其余客户端错误重试也不会成功
+func retryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusTooManyRequests
+}
+
+// DoWithRetry 发送 HTTP 请求，连接失败、429 和 5xx 最多重试 maxRetries 次。
+// 重试间隔从 backoff 开始按指数增长，响应带 Retry-After 时按其等待（最长 1 分钟）。
+// 其余状态码的响应直接返回，由调用方关闭 Body；重试耗尽时返回包装了最后一次错误的 error。
+// 带请求体的请求需要可以通过 GetBody 重放（http.NewRequest 传入 bytes.Reader 等即可）
+func DoWithRetry(ctx context.Context, client *http.Client, req *http.Request, maxRetries int, backoff time.Duration) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	var wait time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq, err := cloneRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(attemptReq)
+		if err != nil {
+			// 请求被取消或超时时直接返回，交由上层处理
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("failed to connect to %s: %w", req.URL.Redacted(), err)
+			wait = backoffDelay(backoff, attempt)
+			continue
+		}
+
+		if !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		lastErr = readStatusError(resp)
+		wait = backoffDelay(backoff, attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = retryAfter
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// cloneRequest 为每次尝试复制请求并重新获取请求体
+func cloneRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	clone := req.Clone(ctx)
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body of %s cannot be replayed", req.URL.Redacted())
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay request body: %w", err)
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// readStatusError 读取并关闭非 2xx 响应，错误信息中最多保留 maxErrorBodyBytes 字节的响应体
+func readStatusError(resp *http.Response) *statusError {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes+1))
+
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > maxErrorBodyBytes {
+		msg = msg[:maxErrorBodyBytes] + "..."
+	}
+	return &statusError{StatusCode: resp.StatusCode, Body: msg}
+}
+
+// backoffDelay 第 attempt 次尝试失败后的等待时间：backoff * 2^attempt
+func backoffDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	delay := backoff << uint(attempt)
+	if delay <= 0 || delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = at.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
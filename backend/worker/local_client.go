@@ -0,0 +1,282 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+// 本地模型请求默认使用 Ollama /api/generate 的格式
+const (
+	defaultLocalPath          = "/api/generate"
+	defaultLocalModelField    = "model"
+	defaultLocalPromptField   = "prompt"
+	defaultLocalResponseField = "response"
+	defaultLocalTimeout       = 120 * time.Second
+
+	// maxErrorBodyBytes 错误信息中最多保留的响应体长度
+	maxErrorBodyBytes = 512
+	// retryBackoff 模型请求第一次重试前的等待时间，之后按指数增长
+	retryBackoff = time.Second
+)
+
+// localRequestConfig 本地模型 HTTP 调用配置，均可在模型配置中覆盖
+type localRequestConfig struct {
+	URL           string
+	ModelName     string
+	ModelField    string
+	PromptField   string
+	ResponseField string
+	Headers       map[string]string
+	// Stream 为 true 时以流式方式调用，增量输出通过回调逐段推送；健康探测和模型测试使用非流式调用
+	Stream bool
+	// TraceID 任务的请求关联 ID，通过 X-Request-ID 头传给模型服务
+	TraceID string
+	// OnRequest、OnAttempt 不为空时分别在发送请求前和每次 HTTP 往返后调用，用于记录任务日志
+	OnRequest func(body []byte)
+	OnAttempt func(modelCallAttempt)
+}
+
+// buildLocalRequestConfig 根据模型配置生成请求配置：
+// path、model、model_field、prompt_field、response_field 未配置时使用 Ollama 的默认值
+func buildLocalRequestConfig(endpoint providerEndpoint, model *models.Model) localRequestConfig {
+	cfg := localRequestConfig{
+		ModelName:     model.Name,
+		ModelField:    defaultLocalModelField,
+		PromptField:   defaultLocalPromptField,
+		ResponseField: defaultLocalResponseField,
+		Headers:       endpoint.Headers,
+	}
+
+	path := defaultLocalPath
+	if s := configString(model, "path"); s != "" {
+		path = s
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	cfg.URL = strings.TrimRight(endpoint.BaseURL, "/") + path
+
+	if s := configString(model, "model"); s != "" {
+		cfg.ModelName = s
+	}
+	if s := configString(model, "model_field"); s != "" {
+		cfg.ModelField = s
+	}
+	if s := configString(model, "prompt_field"); s != "" {
+		cfg.PromptField = s
+	}
+	if s := configString(model, "response_field"); s != "" {
+		cfg.ResponseField = s
+	}
+	return cfg
+}
+
+// configString 读取模型配置中的可选字符串值，未配置或类型不符时返回空字符串（使用默认值）
+func configString(model *models.Model, key string) string {
+	s, _ := model.GetConfigString(key)
+	return s
+}
+
+// doLocalRequest 以流式方式调用本地模型，每段增量输出通过 onChunk 推送，返回完整输出和 token 用量
+// （响应没有携带时为 nil）。连接失败、5xx 和 429 按 models.local.max_retries 重试，开始接收输出后不再重试
+func (w *Worker) doLocalRequest(ctx context.Context, cfg localRequestConfig, prompt string, onChunk func(string)) (string, *models.TokenUsage, error) {
+	cfg.Stream = true
+	body, err := localRequestBody(cfg, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	timeout := w.config.Models.Local.Timeout
+	if timeout <= 0 {
+		timeout = defaultLocalTimeout
+	}
+	resp, err := postModelRequest(ctx, cfg, body, w.config.Models.Local.MaxRetries, timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	return readLocalStream(resp.Body, cfg.ResponseField, onChunk)
+}
+
+// localRequestBody 生成请求体，cfg.Stream 为 true 时请求流式响应
+func localRequestBody(cfg localRequestConfig, prompt string) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		cfg.ModelField:  cfg.ModelName,
+		cfg.PromptField: prompt,
+		"stream":        cfg.Stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode local model request: %w", err)
+	}
+	return body, nil
+}
+
+// readLocalStream 读取 Ollama 风格的流式响应：依次解码 JSON 对象（通常每行一个），response_field 中的增量文本
+// 通过 onChunk 推送，done 为 true 的对象携带用量并结束读取。不支持流式的服务返回单个完整 JSON 对象时同样适用；
+// 所有对象都不含 response_field 时返回字段缺失的错误
+func readLocalStream(r io.Reader, field string, onChunk func(string)) (string, *models.TokenUsage, error) {
+	decoder := json.NewDecoder(r)
+
+	var output strings.Builder
+	var usage *models.TokenUsage
+	var fieldErr error
+	found := false
+	for {
+		var chunk interface{}
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", nil, fmt.Errorf("invalid model stream: %w", err)
+		}
+		if err := streamError(chunk); err != nil {
+			return "", nil, err
+		}
+		if u, ok := parseResponseUsage(chunk); ok {
+			usage = &u
+		}
+
+		text, err := extractResponseField(chunk, field)
+		if err != nil {
+			fieldErr = err
+		} else {
+			found = true
+			if text != "" {
+				output.WriteString(text)
+				onChunk(text)
+			}
+		}
+
+		if result, ok := chunk.(map[string]interface{}); ok && result["done"] == true {
+			break
+		}
+	}
+
+	if !found && fieldErr != nil {
+		return "", nil, fieldErr
+	}
+	return output.String(), usage, nil
+}
+
+// sendModelRequest 发送模型请求并从响应中提取生成文本和 token 用量，本地模型和 OpenAI 兼容接口共用。
+// 每次尝试的超时为 timeout，连接失败、5xx 和 429 最多重试 maxRetries 次
+func sendModelRequest(ctx context.Context, cfg localRequestConfig, body []byte, maxRetries int, timeout time.Duration) (string, *models.TokenUsage, error) {
+	resp, err := postModelRequest(ctx, cfg, body, maxRetries, timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read model response: %w", err)
+	}
+	return parseModelResponse(data, cfg.ResponseField)
+}
+
+// postModelRequest 发送模型请求，返回 2xx 响应，由调用方读取并关闭 Body。
+// 每次尝试的超时为 timeout（包括读取响应体），连接失败、5xx 和 429 最多重试 maxRetries 次；
+// 流式调用收到响应头后不再重试
+func postModelRequest(ctx context.Context, cfg localRequestConfig, body []byte, maxRetries int, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	if cfg.TraceID != "" {
+		req.Header.Set(utils.RequestIDHeader, cfg.TraceID)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if cfg.OnAttempt != nil {
+		client.Transport = &attemptTransport{base: http.DefaultTransport, observe: cfg.OnAttempt}
+	}
+	if cfg.OnRequest != nil {
+		cfg.OnRequest(body)
+	}
+
+	resp, err := DoWithRetry(ctx, client, req, maxRetries, retryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("model request to %s failed: %w", cfg.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, readStatusError(resp)
+	}
+	return resp, nil
+}
+
+// parseModelResponse 解析非流式调用的完整响应，提取 field 指定的生成文本和 token 用量
+func parseModelResponse(data []byte, field string) (string, *models.TokenUsage, error) {
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", nil, fmt.Errorf("invalid model response: %w", err)
+	}
+	output, err := extractResponseField(result, field)
+	if err != nil {
+		return "", nil, err
+	}
+	if usage, ok := parseResponseUsage(result); ok {
+		return output, &usage, nil
+	}
+	return output, nil, nil
+}
+
+// streamError 流式响应中途返回的错误，如 {"error": "..."} 或 {"error": {"message": "..."}}，没有错误时返回 nil
+func streamError(value interface{}) error {
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch e := result["error"].(type) {
+	case nil:
+		return nil
+	case string:
+		return fmt.Errorf("model stream error: %s", e)
+	case map[string]interface{}:
+		if msg, ok := e["message"].(string); ok {
+			return fmt.Errorf("model stream error: %s", msg)
+		}
+	}
+	return fmt.Errorf("model stream error: %v", result["error"])
+}
+
+// extractResponseField 按点分隔的路径提取响应中的文本，数组元素使用下标，例如 choices.0.text
+func extractResponseField(value interface{}, path string) (string, error) {
+	current := value
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return "", fmt.Errorf("model response missing field %q", path)
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return "", fmt.Errorf("model response missing field %q", path)
+			}
+			current = v[idx]
+		default:
+			return "", fmt.Errorf("model response missing field %q", path)
+		}
+	}
+
+	text, ok := current.(string)
+	if !ok {
+		return "", fmt.Errorf("model response field %q is not a string", path)
+	}
+	return text, nil
+}
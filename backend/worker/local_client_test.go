@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestSendModelRequestRetriesThenParsesResponse(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-ID") != "trace-1" {
+			t.Errorf("X-Request-ID = %q, want trace-1", r.Header.Get("X-Request-ID"))
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "hello"}}},
+			"usage":   map[string]int{"prompt_tokens": 3, "completion_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	cfg := localRequestConfig{URL: server.URL, ResponseField: openAIResponseField, TraceID: "trace-1"}
+	output, usage, err := sendModelRequest(context.Background(), cfg, []byte(`{}`), 2, time.Second)
+	if err != nil {
+		t.Fatalf("sendModelRequest: %v", err)
+	}
+	if output != "hello" {
+		t.Errorf("output = %q, want hello", output)
+	}
+	if usage == nil || usage.PromptTokens != 3 || usage.CompletionTokens != 1 {
+		t.Errorf("usage = %+v, want 3 prompt and 1 completion tokens", usage)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestSendModelRequestGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	cfg := localRequestConfig{URL: server.URL, ResponseField: defaultLocalResponseField}
+	_, _, err := sendModelRequest(context.Background(), cfg, []byte(`{}`), 1, time.Second)
+
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want wrapped 429 status error", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestDoLocalRequestStreamsOllamaChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultLocalPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, defaultLocalPath)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if body["stream"] != true || body["model"] != "llama3" || body["prompt"] != "hi" {
+			t.Errorf("request = %v, want streaming request for llama3", body)
+		}
+
+		// Ollama 流式响应每行一个 JSON 对象，最后一行 done 为 true 并携带用量
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher := w.(http.Flusher)
+		for _, line := range []string{
+			`{"model":"llama3","response":"Hel","done":false}`,
+			`{"model":"llama3","response":"lo","done":false}`,
+			`{"model":"llama3","response":"","done":true,"prompt_eval_count":4,"eval_count":2}`,
+		} {
+			fmt.Fprintln(w, line)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	model := &models.Model{Name: "llama3", Type: models.ModelTypeLocal}
+	cfg := buildLocalRequestConfig(providerEndpoint{BaseURL: server.URL}, model)
+	w := &Worker{config: &config.Config{}}
+
+	var chunks []string
+	output, usage, err := w.doLocalRequest(context.Background(), cfg, "hi", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("doLocalRequest: %v", err)
+	}
+	if output != "Hello" {
+		t.Errorf("output = %q, want Hello", output)
+	}
+	if want := []string{"Hel", "lo"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	if usage == nil || usage.PromptTokens != 4 || usage.CompletionTokens != 2 {
+		t.Errorf("usage = %+v, want 4 prompt and 2 completion tokens", usage)
+	}
+}
+
+func TestReadLocalStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      string
+		stream     string
+		wantOutput string
+		wantChunks []string
+		wantErr    string
+	}{
+		{
+			name:       "stops at done",
+			field:      "response",
+			stream:     `{"response":"a"}` + "\n" + `{"response":"b","done":true}` + "\n" + `{"response":"ignored"}`,
+			wantOutput: "ab",
+			wantChunks: []string{"a", "b"},
+		},
+		{
+			name:       "single pretty printed object from non-streaming backend",
+			field:      "output.text",
+			stream:     "{\n  \"output\": {\"text\": \"whole\"}\n}\n",
+			wantOutput: "whole",
+			wantChunks: []string{"whole"},
+		},
+		{
+			name:    "response field missing",
+			field:   "text",
+			stream:  `{"response":"a","done":true}`,
+			wantErr: `model response missing field "text"`,
+		},
+		{
+			name:       "error line",
+			field:      "response",
+			stream:     `{"response":"a"}` + "\n" + `{"error":"model not found"}`,
+			wantChunks: []string{"a"},
+			wantErr:    "model not found",
+		},
+		{
+			name:       "truncated stream",
+			field:      "response",
+			stream:     `{"response":"a"}` + "\n" + `{"respo`,
+			wantChunks: []string{"a"},
+			wantErr:    "invalid model stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks []string
+			output, _, err := readLocalStream(strings.NewReader(tt.stream), tt.field, func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("readLocalStream: %v", err)
+			} else if output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if !reflect.DeepEqual(chunks, tt.wantChunks) {
+				t.Errorf("chunks = %q, want %q", chunks, tt.wantChunks)
+			}
+		})
+	}
+}
+
+func TestLocalRequestBodyStreamFlag(t *testing.T) {
+	cfg := buildLocalRequestConfig(providerEndpoint{BaseURL: "http://localhost:11434"}, &models.Model{Name: "llama3"})
+	body, err := localRequestBody(cfg, "ping")
+	if err != nil {
+		t.Fatalf("localRequestBody: %v", err)
+	}
+	// 健康探测和模型测试使用默认配置，以非流式方式调用
+	if !strings.Contains(string(body), `"stream":false`) {
+		t.Errorf("body = %s, want non-streaming request by default", body)
+	}
+}
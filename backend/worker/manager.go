@@ -3,6 +3,8 @@ package worker
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,6 +17,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// stopTimeout 未通过 Shutdown 指定截止时间时，停止时等待 Worker 排空的最长时间
+const stopTimeout = 30 * time.Second
+
 // Manager Worker 管理器
 type Manager struct {
 	config       *config.Config
@@ -22,11 +27,23 @@ type Manager struct {
 	queueManager *queue.Manager
 	taskService  *services.TaskService
 	modelService *services.ModelService
+	schedules    *services.ScheduleService
 	logger       *logrus.Logger
 	workers      map[string]*Worker
 	workersMutex sync.RWMutex
-	ctx          context.Context
-	cancel       context.CancelFunc
+	targets      map[uint64]int
+	tasks        *taskRegistry
+	breakers     *breakerRegistry
+	scaleMutex   sync.Mutex
+	// probeFailures 各模型连续健康探测失败次数，只在探测协程中访问
+	probeFailures map[uint64]int
+	// ready 预热完成后关闭，Worker 在此之前不领取任务
+	ready  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopMutex 保护 cancel 和 stopDeadline，Shutdown 可能在 Start 设置 cancel 之前调用
+	stopMutex    sync.Mutex
+	stopDeadline time.Time
 }
 
 // NewManager 创建 Worker 管理器
@@ -36,23 +53,32 @@ func NewManager(
 	queueManager *queue.Manager,
 	taskService *services.TaskService,
 	modelService *services.ModelService,
+	scheduleService *services.ScheduleService,
 	logger *logrus.Logger,
 ) *Manager {
 	return &Manager{
-		config:       cfg,
-		db:           db,
-		queueManager: queueManager,
-		taskService:  taskService,
-		modelService: modelService,
-		logger:       logger,
-		workers:      make(map[string]*Worker),
+		config:        cfg,
+		db:            db,
+		queueManager:  queueManager,
+		taskService:   taskService,
+		modelService:  modelService,
+		schedules:     scheduleService,
+		logger:        logger,
+		workers:       make(map[string]*Worker),
+		targets:       make(map[uint64]int),
+		tasks:         newTaskRegistry(),
+		breakers:      newBreakerRegistry(cfg.Worker.CircuitBreaker),
+		probeFailures: make(map[uint64]int),
+		ready:         make(chan struct{}),
 	}
 }
 
 // Start 启动 Worker 管理器
 func (m *Manager) Start(ctx context.Context) error {
+	m.stopMutex.Lock()
 	m.ctx, m.cancel = context.WithCancel(ctx)
-	
+	m.stopMutex.Unlock()
+
 	m.logger.Info("Starting worker manager")
 
 	// 启动延迟任务处理协程
@@ -60,10 +86,40 @@ func (m *Manager) Start(ctx context.Context) error {
 	
 	// 启动清理卡住任务的协程
 	go m.cleanupStuckTasks()
-	
+
+	// 启动处理中队列与数据库的对账协程
+	go m.reconcileProcessing()
+
 	// 启动 Worker 监控协程
 	go m.monitorWorkers()
 
+	// 启动降级模式自动检测协程
+	go m.monitorDegradedMode()
+
+	// 启动任务取消消息监听协程
+	go m.listenTaskCancels()
+
+	// 启动定时任务调度协程
+	go m.runSchedules()
+
+	// 启动历史任务归档协程
+	go m.archiveTasks()
+
+	// 启动等待过久任务的优先级提升协程
+	go m.promoteAgedTasks()
+
+	// 启动模型健康探测协程
+	go m.probeModels()
+
+	// 启动队列深度采样协程
+	go m.sampleQueueMetrics()
+
+	// 同步模型并发上限和队列后端
+	m.syncModelQueueSettings()
+
+	// 启动预热协程，完成前 Worker 池已创建但不领取任务
+	go m.warmup()
+
 	// 启动默认 Worker 池
 	if err := m.startDefaultWorkers(); err != nil {
 		return fmt.Errorf("failed to start default workers: %w", err)
@@ -80,9 +136,43 @@ func (m *Manager) Start(ctx context.Context) error {
 
 // Stop 停止 Worker 管理器
 func (m *Manager) Stop() {
-	if m.cancel != nil {
-		m.cancel()
+	m.stopMutex.Lock()
+	cancel := m.cancel
+	m.stopMutex.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Shutdown 停止 Worker 管理器，Worker 排空的时间以 ctx 的截止时间为限（仍不超过 worker_timeout），
+// 超时后强制取消执行中的任务并放回队列。Start 会在排空后返回，调用方通过 Start 的返回确认停止完成
+func (m *Manager) Shutdown(ctx context.Context) {
+	m.stopMutex.Lock()
+	if deadline, ok := ctx.Deadline(); ok {
+		m.stopDeadline = deadline
+	}
+	m.stopMutex.Unlock()
+	m.Stop()
+}
+
+// drainBudget 等待 Worker 排空的时长：Shutdown 指定了截止时间时为截止前的剩余时间（预留强制取消后放回队列的时间），
+// 否则为 stopTimeout；两者都不超过 worker_timeout
+func (m *Manager) drainBudget() time.Duration {
+	m.stopMutex.Lock()
+	deadline := m.stopDeadline
+	m.stopMutex.Unlock()
+
+	budget := stopTimeout
+	if !deadline.IsZero() {
+		budget = time.Until(deadline) - requeueTimeout
+		if budget < 0 {
+			budget = 0
+		}
 	}
+	if timeout := m.config.Worker.WorkerTimeout; timeout > 0 && timeout < budget {
+		budget = timeout
+	}
+	return budget
 }
 
 // startDefaultWorkers 启动默认 Worker
@@ -99,7 +189,11 @@ func (m *Manager) startDefaultWorkers() error {
 		if workerCount <= 0 {
 			workerCount = 1
 		}
-		
+
+		m.workersMutex.Lock()
+		m.targets[model.ID] = workerCount
+		m.workersMutex.Unlock()
+
 		for i := 0; i < workerCount; i++ {
 			if err := m.startWorker(&model); err != nil {
 				m.logger.WithError(err).WithFields(logrus.Fields{
@@ -115,24 +209,35 @@ func (m *Manager) startDefaultWorkers() error {
 
 // startWorker 启动单个 Worker
 func (m *Manager) startWorker(model *models.Model) error {
-	workerID := fmt.Sprintf("worker-%d-%d", model.ID, time.Now().UnixNano())
-	
+	if m.ctx.Err() != nil {
+		return fmt.Errorf("worker manager stopped")
+	}
+
+	// 分配槽位与登记 Worker 在同一把锁内完成，避免并发启动时取到相同的 ID
+	m.workersMutex.Lock()
+	slot := m.nextWorkerSlot(model.ID)
+	workerID := workerIDFor(model.ID, slot)
 	worker := NewWorker(
 		workerID,
 		model.ID,
+		m.config,
 		m.queueManager,
 		m.taskService,
 		m.modelService,
 		m.logger,
 	)
-	
-	m.workersMutex.Lock()
+	worker.registry = m.tasks
+	worker.breakers = m.breakers
+	worker.ready = m.ready
+	worker.modelName = model.Name
+	worker.slot = slot
 	m.workers[workerID] = worker
+	m.assignAffinity(model.ID)
 	m.workersMutex.Unlock()
 
-	// 在新协程中启动 Worker
+	// 在新协程中启动 Worker，Worker 不随管理器上下文取消，由 stopAllWorkers 排空后停止
 	go func() {
-		if err := worker.Start(m.ctx); err != nil {
+		if err := worker.Start(context.Background()); err != nil {
 			m.logger.WithError(err).WithField("worker_id", workerID).Error("Worker stopped with error")
 		}
 		
@@ -152,36 +257,117 @@ func (m *Manager) startWorker(model *models.Model) error {
 		"worker_id":  workerID,
 		"model_id":   model.ID,
 		"model_name": model.Name,
+		"task_types": worker.getTaskTypes(),
 	}).Info("Worker started")
 
 	return nil
 }
 
-// stopAllWorkers 停止所有 Worker
-func (m *Manager) stopAllWorkers() {
-	m.workersMutex.Lock()
-	defer m.workersMutex.Unlock()
+// workerIDFor 生成 worker-{模型ID}-{槽位} 形式的 Worker ID
+func workerIDFor(modelID uint64, slot int) string {
+	return fmt.Sprintf("worker-%d-%d", modelID, slot)
+}
+
+// nextWorkerSlot 获取模型 Worker 池中最小的空闲槽位，Worker 退出后槽位释放，
+// 替换它的 Worker 沿用同一 ID 和任务类型限制，调用方需持有 workersMutex
+func (m *Manager) nextWorkerSlot(modelID uint64) int {
+	for slot := 0; ; slot++ {
+		if _, exists := m.workers[workerIDFor(modelID, slot)]; !exists {
+			return slot
+		}
+	}
+}
 
+// assignAffinity 按槽位顺序为模型的活跃 Worker 重新分配任务类型限制，池中最后一个 Worker 始终不限类型。
+// Worker 启动或排空后调用，缩容后剩下的 Worker 中仍有一个领取所有类型的任务，调用方需持有 workersMutex
+func (m *Manager) assignAffinity(modelID uint64) {
+	var active []*Worker
 	for _, worker := range m.workers {
-		worker.Stop()
+		if worker.modelID == modelID && !worker.IsDraining() {
+			active = append(active, worker)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].slot < active[j].slot
+	})
+	for rank, worker := range active {
+		worker.setTaskTypes(m.config.Worker.AffinityFor(modelID, rank, len(active)))
+	}
+}
+
+// stopAllWorkers 停止所有 Worker：先排空，等待当前任务完成，超时后强制取消并将任务放回队列
+func (m *Manager) stopAllWorkers() {
+	m.workersMutex.RLock()
+	workers := make([]*Worker, 0, len(m.workers))
+	for _, worker := range m.workers {
+		workers = append(workers, worker)
+	}
+	m.workersMutex.RUnlock()
+
+	for _, worker := range workers {
+		worker.Drain()
+	}
+
+	budget := m.drainBudget()
+	m.logger.WithFields(logrus.Fields{
+		"workers": len(workers),
+		"budget":  budget.String(),
+	}).Info("Draining workers")
+	deadline := time.Now().Add(budget)
+
+	var remaining []*Worker
+	for _, worker := range workers {
+		if !worker.Wait(time.Until(deadline)) {
+			remaining = append(remaining, worker)
+		}
+	}
+
+	if len(remaining) == 0 {
+		m.logger.Info("All workers stopped")
+		return
+	}
+
+	m.logger.WithField("workers", len(remaining)).Warn("Timeout waiting for workers to drain, cancelling")
+	for _, worker := range remaining {
+		worker.Kill()
+	}
+	for _, worker := range remaining {
+		if !worker.Wait(requeueTimeout) {
+			m.logger.WithField("worker_id", worker.id).Error("Worker did not stop after cancel")
+		}
 	}
-	
-	// 等待所有 Worker 停止
-	timeout := time.After(30 * time.Second)
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
 
-	for len(m.workers) > 0 {
+	m.logger.Info("All workers stopped")
+}
+
+// listenTaskCancels 监听任务取消消息，中断本实例正在执行的对应任务
+func (m *Manager) listenTaskCancels() {
+	pubsub, err := m.queueManager.SubscribeTaskCancels(m.ctx)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to subscribe task cancels")
+		return
+	}
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
 		select {
-		case <-timeout:
-			m.logger.Warn("Timeout waiting for workers to stop")
+		case <-m.ctx.Done():
 			return
-		case <-ticker.C:
-			// 继续等待
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			taskID, err := strconv.ParseUint(msg.Payload, 10, 64)
+			if err != nil {
+				m.logger.WithField("payload", msg.Payload).Warn("Invalid task cancel message")
+				continue
+			}
+			if m.tasks.cancel(taskID) {
+				m.logger.WithField("task_id", taskID).Info("Cancelling running task")
+			}
 		}
 	}
-	
-	m.logger.Info("All workers stopped")
 }
 
 // processDelayedTasks 处理延迟任务
@@ -201,6 +387,79 @@ func (m *Manager) processDelayedTasks() {
 	}
 }
 
+// runSchedules 定时任务调度，每秒为到期的定时任务创建任务。
+// 启动时先跳过停机期间错过的执行，避免重启后集中补跑
+func (m *Manager) runSchedules() {
+	if err := m.schedules.SkipMissedRuns(time.Now()); err != nil {
+		m.logger.WithError(err).Error("Failed to skip missed schedule runs")
+	}
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.schedules.RunDueSchedules(m.ctx, now)
+		}
+	}
+}
+
+// archiveTasks 按 queue.archive_interval 定期归档超过 queue.task_retention_days 的已结束任务
+func (m *Manager) archiveTasks() {
+	retentionDays := m.config.Queue.TaskRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	interval := m.config.Queue.ArchiveInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			before := now.AddDate(0, 0, -retentionDays)
+			if _, err := m.taskService.ArchiveTasks(before); err != nil {
+				m.logger.WithError(err).Error("Failed to archive tasks")
+			}
+		}
+	}
+}
+
+// promoteAgedTasks 每30秒将等待超过 queue.aging_threshold 的任务提升一级优先级
+func (m *Manager) promoteAgedTasks() {
+	threshold := m.config.Queue.AgingThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			promoted, err := m.queueManager.PromoteAgedTasks(m.ctx, threshold)
+			if err != nil {
+				m.logger.WithError(err).Error("Failed to promote aged tasks")
+			}
+			if len(promoted) > 0 {
+				m.logger.WithField("count", len(promoted)).Info("Promoted aged tasks")
+			}
+		}
+	}
+}
+
 // cleanupStuckTasks 清理卡住的任务
 func (m *Manager) cleanupStuckTasks() {
 	ticker := time.NewTicker(1 * time.Minute) // 每分钟检查一次
@@ -218,6 +477,54 @@ func (m *Manager) cleanupStuckTasks() {
 	}
 }
 
+// reconcileProcessing 每分钟清理处理中队列里数据库任务已结束或已删除的条目，
+// 这些条目通常是 Worker 更新数据库后未能移除队列项留下的，会一直占用模型并发名额
+func (m *Manager) reconcileProcessing() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.reconcileProcessingOnce(); err != nil {
+				m.logger.WithError(err).Error("Failed to reconcile processing queue")
+			}
+		}
+	}
+}
+
+// reconcileProcessingOnce 执行一次处理中队列对账
+func (m *Manager) reconcileProcessingOnce() error {
+	processing, err := m.queueManager.ProcessingTaskIDs(m.ctx)
+	if err != nil {
+		return err
+	}
+	if len(processing) == 0 {
+		return nil
+	}
+
+	taskIDs := make([]uint64, 0, len(processing))
+	for taskID := range processing {
+		taskIDs = append(taskIDs, taskID)
+	}
+
+	stale, err := m.taskService.InactiveTaskIDs(taskIDs)
+	if err != nil {
+		return err
+	}
+	for _, taskID := range stale {
+		if err := m.queueManager.CompleteTask(m.ctx, taskID); err != nil {
+			return fmt.Errorf("failed to remove task %d from processing queue: %w", taskID, err)
+		}
+	}
+	if len(stale) > 0 {
+		m.logger.WithField("count", len(stale)).Warn("Removed processing entries for finished or missing tasks")
+	}
+	return nil
+}
+
 // monitorWorkers 监控 Worker 状态
 func (m *Manager) monitorWorkers() {
 	ticker := time.NewTicker(30 * time.Second) // 每30秒检查一次
@@ -229,15 +536,54 @@ func (m *Manager) monitorWorkers() {
 			return
 		case <-ticker.C:
 			m.checkWorkerHealth()
+			m.rerouteUnavailableModels()
+		}
+	}
+}
+
+// rerouteUnavailableModels 将离线或维护中模型的等待任务改派到备用模型
+func (m *Manager) rerouteUnavailableModels() {
+	candidates, err := m.modelService.GetRerouteCandidates()
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to get models to reroute")
+		return
+	}
+
+	for i := range candidates {
+		if _, err := m.taskService.RerouteTasks(m.ctx, &candidates[i]); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"model_id": candidates[i].ID,
+				"status":   candidates[i].Status,
+			}).Warn("Failed to reroute tasks of unavailable model")
 		}
 	}
 }
 
-// checkWorkerHealth 检查 Worker 健康状态
+// syncModelQueueSettings 将各模型的并发上限和队列后端同步到队列管理器，
+// 并发上限为 MaxWorkers 乘以每个 Worker 一批最多处理的任务数
+func (m *Manager) syncModelQueueSettings() {
+	modelList, err := m.modelService.ListModels(nil, nil)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to list models for queue settings sync")
+		return
+	}
+
+	for _, model := range modelList {
+		m.queueManager.SetModelBackend(model.ID, model.GetQueueBackend())
+		capacity := model.MaxWorkers
+		if m.config.Worker.BatchSize > 1 {
+			capacity *= m.config.Worker.BatchSize
+		}
+		if err := m.queueManager.SetModelCapacity(m.ctx, model.ID, capacity); err != nil {
+			m.logger.WithError(err).WithField("model_id", model.ID).Error("Failed to sync model capacity")
+		}
+	}
+}
+
+// checkWorkerHealth 检查 Worker 健康状态，为在线模型补齐意外退出的 Worker
 func (m *Manager) checkWorkerHealth() {
-	m.workersMutex.RLock()
-	workerCount := len(m.workers)
-	m.workersMutex.RUnlock()
+	// 模型配置可能已更新，顺带刷新队列设置
+	m.syncModelQueueSettings()
 
 	// 获取在线模型
 	models, err := m.modelService.GetAvailableModels()
@@ -246,24 +592,309 @@ func (m *Manager) checkWorkerHealth() {
 		return
 	}
 
-	expectedWorkers := 0
-	for _, model := range models {
-		expectedWorkers += model.MaxWorkers
+	// 与扩缩容互斥，避免同时调整同一模型的 Worker
+	m.scaleMutex.Lock()
+	defer m.scaleMutex.Unlock()
+
+	for i := range models {
+		m.recoverWorkers(&models[i])
+	}
+}
+
+// recoverWorkers 按模型的期望数量补齐 Worker：期望数量以运行时设置的目标为准，
+// 未设置目标时为 max_workers，且不超过 max_workers 和全局 Worker 上限
+func (m *Manager) recoverWorkers(model *models.Model) {
+	m.workersMutex.RLock()
+	expected, ok := m.targets[model.ID]
+	if !ok || expected > model.MaxWorkers {
+		expected = model.MaxWorkers
+	}
+	total := 0
+	for _, worker := range m.workers {
+		if !worker.IsDraining() {
+			total++
+		}
 	}
+	m.workersMutex.RUnlock()
 
-	if workerCount < expectedWorkers {
-		m.logger.WithFields(logrus.Fields{
-			"current_workers":  workerCount,
-			"expected_workers": expectedWorkers,
-		}).Warn("Worker count is below expected")
-		
-		// 尝试启动缺失的 Worker
-		// 这里可以添加自动恢复逻辑
+	current := len(m.activeWorkers(model.ID))
+	missing := expected - current
+	if missing <= 0 {
+		return
+	}
+	if limit := m.config.Worker.MaxWorkers; limit > 0 && total+missing > limit {
+		missing = limit - total
+		if missing <= 0 {
+			m.logger.WithField("model_id", model.ID).Warn("Worker count is below expected but global worker limit reached")
+			return
+		}
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"model_id":         model.ID,
+		"current_workers":  current,
+		"expected_workers": expected,
+		"starting":         missing,
+	}).Warn("Worker count is below expected, starting replacement workers")
+
+	for i := 0; i < missing; i++ {
+		if err := m.startWorker(model); err != nil {
+			m.logger.WithError(err).WithField("model_id", model.ID).Error("Failed to start replacement worker")
+			return
+		}
+	}
+}
+
+// monitorDegradedMode 根据队列深度和失败率自动进入/退出降级模式
+func (m *Manager) monitorDegradedMode() {
+	cfg := m.config.Queue.Degraded
+	if !cfg.AutoEnabled {
+		return
+	}
+
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkDegradedMode()
+		}
+	}
+}
+
+// checkDegradedMode 检查系统压力并切换降级模式
+func (m *Manager) checkDegradedMode() {
+	cfg := m.config.Queue.Degraded
+
+	mode, err := m.queueManager.GetDegradedMode(m.ctx)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to get degraded mode")
+		return
+	}
+
+	// 人工设置的降级模式只能人工解除
+	if mode.Enabled && mode.Source == models.DegradedModeSourceManual {
+		return
+	}
+
+	reason := ""
+	if cfg.QueueDepthThreshold > 0 {
+		status, err := m.queueManager.GetQueueStatus(m.ctx)
+		if err != nil {
+			m.logger.WithError(err).Error("Failed to get queue status for degraded mode check")
+			return
+		}
+		depth := status.HighPriorityCount + status.MediumPriorityCount + status.LowPriorityCount
+		if depth >= cfg.QueueDepthThreshold {
+			reason = fmt.Sprintf("queue depth %d exceeds threshold %d", depth, cfg.QueueDepthThreshold)
+		}
+	}
+
+	if reason == "" && cfg.FailureRateThreshold > 0 {
+		window := cfg.FailureRateWindow
+		if window <= 0 {
+			window = 5 * time.Minute
+		}
+		rate, err := m.taskService.GetRecentFailureRate(window)
+		if err != nil {
+			m.logger.WithError(err).Error("Failed to get failure rate for degraded mode check")
+			return
+		}
+		if rate >= cfg.FailureRateThreshold {
+			reason = fmt.Sprintf("failure rate %.1f%% exceeds threshold %.1f%%", rate, cfg.FailureRateThreshold)
+		}
+	}
+
+	switch {
+	case reason != "" && !mode.Enabled:
+		minPriority := models.TaskPriority(cfg.MinPriority)
+		if !minPriority.IsValid() {
+			minPriority = models.TaskPriorityHigh
+		}
+		if err := m.queueManager.SetDegradedMode(m.ctx, minPriority, models.DegradedModeSourceAuto, reason); err != nil {
+			m.logger.WithError(err).Error("Failed to enable degraded mode")
+		}
+	case reason == "" && mode.Enabled:
+		if err := m.queueManager.ClearDegradedMode(m.ctx); err != nil {
+			m.logger.WithError(err).Error("Failed to clear degraded mode")
+		}
+	}
+}
+
+// SetTargetWorkers 设置模型的目标 Worker 数量，并启动或排空 Worker 以达到目标
+func (m *Manager) SetTargetWorkers(modelID uint64, target int) (*models.WorkerPoolStatus, error) {
+	if m.ctx == nil {
+		return nil, fmt.Errorf("worker manager not started")
+	}
+
+	model, err := m.modelService.GetModel(modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target < 0 || target > model.MaxWorkers {
+		return nil, fmt.Errorf("target workers must be between 0 and %d", model.MaxWorkers)
+	}
+
+	m.scaleMutex.Lock()
+	defer m.scaleMutex.Unlock()
+
+	if err := m.applyTarget(model, target); err != nil {
+		return nil, err
+	}
+	return m.GetPoolStatus(model), nil
+}
+
+// ScaleModel 运行时调整模型的 Worker 数量，同时将模型的 max_workers 更新为该数量；
+// 缩容时多余的 Worker 先排空再停止。数量为 0 时停止全部 Worker，max_workers 保持不变
+func (m *Manager) ScaleModel(modelID uint64, count int) (*models.WorkerPoolStatus, error) {
+	if m.ctx == nil {
+		return nil, fmt.Errorf("worker manager not started")
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("worker count must not be negative")
+	}
+
+	model, err := m.modelService.GetModel(modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.scaleMutex.Lock()
+	defer m.scaleMutex.Unlock()
+
+	if err := m.applyTarget(model, count); err != nil {
+		return nil, err
+	}
+
+	if count > 0 && count != model.MaxWorkers {
+		if err := m.modelService.SetMaxWorkers(modelID, count); err != nil {
+			return nil, err
+		}
+		model.MaxWorkers = count
+	}
+
+	return m.GetPoolStatus(model), nil
+}
+
+// applyTarget 校验全局 Worker 上限后记录目标数量并调整 Worker，调用方需持有 scaleMutex
+func (m *Manager) applyTarget(model *models.Model, target int) error {
+	modelID := model.ID
+	if target > 0 && model.Status != models.ModelStatusOnline {
+		return fmt.Errorf("model is not online")
+	}
+
+	m.workersMutex.Lock()
+	others := 0
+	for _, worker := range m.workers {
+		if worker.modelID != modelID && !worker.IsDraining() {
+			others++
+		}
+	}
+	if limit := m.config.Worker.MaxWorkers; limit > 0 && others+target > limit {
+		m.workersMutex.Unlock()
+		return fmt.Errorf("target exceeds global worker limit: %d workers used by other models, limit %d", others, limit)
+	}
+	m.targets[modelID] = target
+	m.workersMutex.Unlock()
+
+	m.reconcileWorkers(model, target)
+
+	m.logger.WithFields(logrus.Fields{
+		"model_id":       modelID,
+		"target_workers": target,
+	}).Info("Model worker target updated")
+
+	return nil
+}
+
+// reconcileWorkers 启动或排空模型的 Worker，使活跃 Worker 数量与目标一致
+func (m *Manager) reconcileWorkers(model *models.Model, target int) {
+	active := m.activeWorkers(model.ID)
+
+	for i := len(active); i < target; i++ {
+		if err := m.startWorker(model); err != nil {
+			m.logger.WithError(err).WithField("model_id", model.ID).Error("Failed to start worker")
+		}
+	}
+
+	if excess := len(active) - target; excess > 0 {
+		// 排空槽位最大的 Worker，使 Worker ID 保持紧凑；排空的 Worker 执行完当前任务后退出，正在执行的任务不受影响
+		sort.Slice(active, func(i, j int) bool {
+			return active[i].slot > active[j].slot
+		})
+		for _, worker := range active[:excess] {
+			worker.Drain()
+			m.logger.WithFields(logrus.Fields{
+				"worker_id": worker.id,
+				"model_id":  model.ID,
+			}).Info("Worker draining")
+		}
+
+		// 剩下的 Worker 按新的池大小重新分配任务类型，保证仍有不限类型的 Worker
+		m.workersMutex.Lock()
+		m.assignAffinity(model.ID)
+		m.workersMutex.Unlock()
+	}
+}
+
+// activeWorkers 获取模型未处于排空状态的 Worker
+func (m *Manager) activeWorkers(modelID uint64) []*Worker {
+	m.workersMutex.RLock()
+	defer m.workersMutex.RUnlock()
+
+	var active []*Worker
+	for _, worker := range m.workers {
+		if worker.modelID == modelID && !worker.IsDraining() {
+			active = append(active, worker)
+		}
+	}
+	return active
+}
+
+// GetPoolStatus 获取模型 Worker 池的目标数量与当前数量
+func (m *Manager) GetPoolStatus(model *models.Model) *models.WorkerPoolStatus {
+	m.workersMutex.RLock()
+	defer m.workersMutex.RUnlock()
+
+	status := &models.WorkerPoolStatus{
+		ModelID:       model.ID,
+		TargetWorkers: m.targets[model.ID],
+		MaxWorkers:    model.MaxWorkers,
+	}
+	for _, worker := range m.workers {
+		if worker.modelID != model.ID {
+			continue
+		}
+		if worker.IsDraining() {
+			status.DrainingWorkers++
+		} else {
+			status.CurrentWorkers++
+		}
+	}
+
+	return status
+}
+
+// GetWorkerStatus 获取所有实例的 Worker 状态（从 Redis 名册读取），名册不可用时只返回本实例的 Worker
+func (m *Manager) GetWorkerStatus(ctx context.Context) []models.WorkerStatus {
+	workers, err := m.queueManager.ListWorkers(ctx)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to list workers from roster")
+		return m.GetLocalWorkerStatus()
 	}
+	return workers
 }
 
-// GetWorkerStatus 获取 Worker 状态
-func (m *Manager) GetWorkerStatus() []models.WorkerStatus {
+// GetLocalWorkerStatus 获取本实例内存中的 Worker 状态
+func (m *Manager) GetLocalWorkerStatus() []models.WorkerStatus {
 	m.workersMutex.RLock()
 	defer m.workersMutex.RUnlock()
 
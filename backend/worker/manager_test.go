@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"io"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newAffinityTestManager 创建只用于调整 Worker 池的管理器，不连接数据库和 Redis
+func newAffinityTestManager(affinity ...config.TypeAffinityConfig) *Manager {
+	cfg := &config.Config{}
+	cfg.Worker.TypeAffinity = affinity
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewManager(cfg, nil, nil, nil, nil, nil, logger)
+}
+
+// addTestWorkers 为模型登记 count 个未启动的 Worker 并分配任务类型
+func addTestWorkers(m *Manager, modelID uint64, count int) {
+	m.workersMutex.Lock()
+	defer m.workersMutex.Unlock()
+	for i := 0; i < count; i++ {
+		slot := m.nextWorkerSlot(modelID)
+		worker := NewWorker(workerIDFor(modelID, slot), modelID, m.config, nil, nil, nil, m.logger)
+		worker.slot = slot
+		m.workers[worker.id] = worker
+		m.assignAffinity(modelID)
+	}
+}
+
+// activeTaskTypes 按槽位返回模型活跃 Worker 的任务类型限制
+func activeTaskTypes(m *Manager, modelID uint64) map[int][]string {
+	types := make(map[int][]string)
+	for _, worker := range m.activeWorkers(modelID) {
+		types[worker.slot] = worker.getTaskTypes()
+	}
+	return types
+}
+
+func TestAssignAffinityKeepsLastWorkerUnrestricted(t *testing.T) {
+	m := newAffinityTestManager(config.TypeAffinityConfig{Types: []string{"embedding"}, Workers: 2})
+	addTestWorkers(m, 1, 4)
+
+	types := activeTaskTypes(m, 1)
+	for slot := 0; slot < 2; slot++ {
+		if len(types[slot]) != 1 || types[slot][0] != "embedding" {
+			t.Errorf("slot %d task types = %v, want [embedding]", slot, types[slot])
+		}
+	}
+	for slot := 2; slot < 4; slot++ {
+		if types[slot] != nil {
+			t.Errorf("slot %d task types = %v, want unrestricted", slot, types[slot])
+		}
+	}
+}
+
+func TestScaleDownKeepsUnrestrictedWorker(t *testing.T) {
+	m := newAffinityTestManager(config.TypeAffinityConfig{Types: []string{"embedding"}, Workers: 2})
+	addTestWorkers(m, 1, 4)
+
+	// 空闲与否不影响排空顺序，槽位最大的 Worker 先排空
+	m.workers[workerIDFor(1, 0)].setBusy(100)
+	model := &models.Model{ID: 1}
+	m.reconcileWorkers(model, 2)
+
+	types := activeTaskTypes(m, 1)
+	if len(types) != 2 {
+		t.Fatalf("active workers = %d, want 2", len(types))
+	}
+	if _, ok := types[0]; !ok {
+		t.Fatalf("slot 0 drained, want highest slots drained first")
+	}
+	if len(types[0]) != 1 || types[0][0] != "embedding" {
+		t.Errorf("slot 0 task types = %v, want [embedding]", types[0])
+	}
+	if types[1] != nil {
+		t.Errorf("slot 1 task types = %v, want unrestricted after scale down", types[1])
+	}
+
+	// 缩容到只剩一个 Worker 时它领取所有类型的任务
+	m.reconcileWorkers(model, 1)
+	types = activeTaskTypes(m, 1)
+	if len(types) != 1 || types[0] != nil {
+		t.Errorf("remaining workers = %v, want one unrestricted worker in slot 0", types)
+	}
+}
+
+func TestDrainingWorkersDoNotCountTowardsAffinity(t *testing.T) {
+	m := newAffinityTestManager(config.TypeAffinityConfig{Types: []string{"embedding"}, Workers: 1})
+	addTestWorkers(m, 1, 2)
+	model := &models.Model{ID: 1}
+
+	m.reconcileWorkers(model, 1)
+	// 排空中的 Worker 仍占用槽位 1，新 Worker 取得槽位 2
+	addTestWorkers(m, 1, 1)
+
+	types := activeTaskTypes(m, 1)
+	if len(types[0]) != 1 || types[0][0] != "embedding" {
+		t.Errorf("slot 0 task types = %v, want [embedding]", types[0])
+	}
+	if got, ok := types[2]; !ok || got != nil {
+		t.Errorf("slot 2 task types = %v (present %v), want unrestricted", got, ok)
+	}
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"llm-scheduler/models"
+	"llm-scheduler/utils"
+)
+
+// modelTestPrompt 连通性测试发送的提示词
+const modelTestPrompt = "ping"
+
+// modelTestOutputRunes 测试结果中返回的模型输出最大字符数
+const modelTestOutputRunes = 200
+
+// TestModel 按模型配置构造与 Worker 相同的请求，发送一次极短的生成请求检查配置是否可用。
+// 不重试、不创建任务，也不计入模型的请求统计和熔断器
+func (m *Manager) TestModel(ctx context.Context, model *models.Model) *models.ModelTestResult {
+	result := &models.ModelTestResult{ModelID: model.ID}
+
+	start := time.Now()
+	output, err := m.sendTestRequest(ctx, model)
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Error = err.Error()
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			result.StatusCode = statusErr.StatusCode
+		}
+		return result
+	}
+	result.Success = true
+	result.Output = utils.TruncateRunes(output, modelTestOutputRunes)
+	return result
+}
+
+// sendTestRequest 按模型类型发送测试请求，超时与 Worker 调用相同
+func (m *Manager) sendTestRequest(ctx context.Context, model *models.Model) (string, error) {
+	endpoint, err := modelEndpoint(m.config, model)
+	if err != nil {
+		return "", err
+	}
+
+	switch model.Type {
+	case models.ModelTypeOpenAI:
+		cfg, err := buildOpenAIRequestConfig(endpoint, model)
+		if err != nil {
+			return "", err
+		}
+		body, err := openAIRequestBody(cfg, model, modelTestPrompt)
+		if err != nil {
+			return "", err
+		}
+		timeout := m.config.Models.OpenAI.Timeout
+		if timeout <= 0 {
+			timeout = defaultOpenAITimeout
+		}
+		output, _, err := sendModelRequest(ctx, cfg, body, 0, timeout)
+		return output, err
+	case models.ModelTypeLocal:
+		cfg := buildLocalRequestConfig(endpoint, model)
+		body, err := localRequestBody(cfg, modelTestPrompt)
+		if err != nil {
+			return "", err
+		}
+		timeout := m.config.Models.Local.Timeout
+		if timeout <= 0 {
+			timeout = defaultLocalTimeout
+		}
+		output, _, err := sendModelRequest(ctx, cfg, body, 0, timeout)
+		return output, err
+	default:
+		return "", fmt.Errorf("unsupported model type: %s", model.Type)
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newModelTestServer 模拟上游模型服务：Authorization 不是 Bearer sk-valid 时返回 401，
+// 否则按请求路径返回 OpenAI 或 Ollama 格式的响应
+func newModelTestServer(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		switch r.URL.Path {
+		case openAIChatPath:
+			if r.Header.Get("Authorization") != "Bearer sk-valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				io.WriteString(w, `{"error":{"message":"invalid api key"}}`)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]string{"content": "pong"}}},
+			})
+		case defaultLocalPath:
+			if body["prompt"] != modelTestPrompt {
+				t.Errorf("local prompt = %v, want %s", body["prompt"], modelTestPrompt)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"response": "pong", "done": true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestManagerTestModel(t *testing.T) {
+	var calls int32
+	server := newModelTestServer(t, &calls)
+	host, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+
+	tests := []struct {
+		name       string
+		modelType  models.ModelType
+		config     models.ModelConfig
+		wantOK     bool
+		wantStatus int
+		wantError  string
+		wantCalls  int32
+	}{
+		{"openai success", models.ModelTypeOpenAI, models.ModelConfig{"base_url": server.URL, "api_key": "sk-valid"}, true, 0, "", 1},
+		// 认证失败只请求一次，不重试，返回上游状态码
+		{"openai auth failure", models.ModelTypeOpenAI, models.ModelConfig{"base_url": server.URL, "api_key": "sk-wrong"}, false, http.StatusUnauthorized, "401", 1},
+		{"local success", models.ModelTypeLocal, models.ModelConfig{"host": host, "port": port}, true, 0, "", 1},
+		// 配置不完整时不发送请求
+		{"missing api key", models.ModelTypeOpenAI, models.ModelConfig{"base_url": server.URL}, false, 0, "API key not configured", 0},
+		{"missing host", models.ModelTypeLocal, models.ModelConfig{}, false, 0, "host/port not configured", 0},
+		{"unreachable host", models.ModelTypeLocal, models.ModelConfig{"host": "127.0.0.1", "port": 1}, false, 0, "connection refused", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			m := NewManager(env.cfg, env.db, env.queue, env.tasks, env.models, nil, logger)
+			model := &models.Model{Name: "probe-target", Type: tt.modelType, Config: tt.config, Status: models.ModelStatusOffline, MaxWorkers: 1}
+			if err := env.db.Create(model).Error; err != nil {
+				t.Fatalf("create model: %v", err)
+			}
+			atomic.StoreInt32(&calls, 0)
+
+			result := m.TestModel(context.Background(), model)
+			if result.ModelID != model.ID || result.Success != tt.wantOK || result.StatusCode != tt.wantStatus {
+				t.Fatalf("result = %+v, want success %v and status %d", result, tt.wantOK, tt.wantStatus)
+			}
+			if tt.wantOK && (result.Output != "pong" || result.Error != "") {
+				t.Fatalf("result = %+v, want output pong", result)
+			}
+			if !tt.wantOK && !strings.Contains(result.Error, tt.wantError) {
+				t.Fatalf("error = %q, want it to mention %q", result.Error, tt.wantError)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			// 测试不创建任务，也不计入模型的请求统计
+			var tasks int64
+			env.db.Model(&models.Task{}).Count(&tasks)
+			var stored models.Model
+			env.db.First(&stored, model.ID)
+			if tasks != 0 || stored.TotalRequests != 0 {
+				t.Fatalf("tasks = %d, total_requests = %d, want no side effects", tasks, stored.TotalRequests)
+			}
+		})
+	}
+}
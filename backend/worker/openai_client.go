@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// OpenAI 兼容接口默认使用公开 OpenAI 的 Chat Completions 格式
+const (
+	defaultOpenAIAuthHeader = "Authorization"
+	defaultOpenAITimeout    = 60 * time.Second
+	openAIChatPath          = "/chat/completions"
+	openAIResponseField     = "choices.0.message.content"
+
+	// openAIDeltaField 流式响应每个事件中增量文本的位置
+	openAIDeltaField = "choices.0.delta.content"
+	// openAIStreamDone 流式响应结束标记
+	openAIStreamDone = "[DONE]"
+	// maxStreamLineBytes 流式响应单行的最大长度
+	maxStreamLineBytes = 1 << 20
+)
+
+// buildOpenAIRequestConfig 根据模型配置生成 OpenAI 兼容接口的请求配置：
+//   - base_url：服务地址，默认 models.openai.base_url
+//   - deployment：Azure 部署名，设置后请求 /openai/deployments/{deployment}/chat/completions
+//   - api_version：作为 api-version 查询参数
+//   - auth_header：携带 api_key 的请求头，默认 Authorization（值为 Bearer {api_key}），其他请求头直接使用 api_key
+func buildOpenAIRequestConfig(endpoint providerEndpoint, model *models.Model) (localRequestConfig, error) {
+	cfg := localRequestConfig{
+		ModelName:     model.Name,
+		ResponseField: openAIResponseField,
+		Headers:       make(map[string]string, len(endpoint.Headers)+1),
+	}
+	if s := configString(model, "model"); s != "" {
+		cfg.ModelName = s
+	}
+	for name, value := range endpoint.Headers {
+		cfg.Headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	// 覆盖地址自带认证头时不再要求模型配置 api_key
+	authHeader := http.CanonicalHeaderKey(defaultOpenAIAuthHeader)
+	if s := configString(model, "auth_header"); s != "" {
+		authHeader = http.CanonicalHeaderKey(s)
+	}
+	if _, hasAuth := cfg.Headers[authHeader]; !hasAuth {
+		apiKey := configString(model, "api_key")
+		if apiKey == "" {
+			return cfg, fmt.Errorf("OpenAI API key not configured")
+		}
+		if authHeader == defaultOpenAIAuthHeader {
+			apiKey = "Bearer " + apiKey
+		}
+		cfg.Headers[authHeader] = apiKey
+	}
+
+	path := openAIChatPath
+	if deployment := configString(model, "deployment"); deployment != "" {
+		path = "/openai/deployments/" + url.PathEscape(deployment) + openAIChatPath
+	}
+	cfg.URL = strings.TrimRight(endpoint.BaseURL, "/") + path
+	if version := configString(model, "api_version"); version != "" {
+		cfg.URL += "?" + url.Values{"api-version": {version}}.Encode()
+	}
+	return cfg, nil
+}
+
+// openAIRequestBody 生成 Chat Completions 请求体，cfg.Stream 为 true 时请求 SSE 流式响应。模型配置中的 max_tokens（整数）、temperature（数值）
+// 按类型转换后传递，字符串形式的数字同样接受
+func openAIRequestBody(cfg localRequestConfig, model *models.Model, prompt string) ([]byte, error) {
+	request := map[string]interface{}{
+		"model": cfg.ModelName,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream": cfg.Stream,
+	}
+	if maxTokens, err := model.GetConfigInt("max_tokens"); err == nil {
+		request["max_tokens"] = maxTokens
+	} else if !errors.Is(err, models.ErrConfigMissing) {
+		return nil, fmt.Errorf("invalid model config: %w", err)
+	}
+	if temperature, err := model.GetConfigFloat("temperature"); err == nil {
+		request["temperature"] = temperature
+	} else if !errors.Is(err, models.ErrConfigMissing) {
+		return nil, fmt.Errorf("invalid model config: %w", err)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAI request: %w", err)
+	}
+	return body, nil
+}
+
+// doOpenAIRequest 以流式方式调用 OpenAI 兼容接口，每段增量输出通过 onChunk 推送，返回完整输出；
+// 超时（包括读取整个流）和重试次数使用 models.openai 配置，开始接收输出后不再重试
+func (w *Worker) doOpenAIRequest(ctx context.Context, cfg localRequestConfig, model *models.Model, prompt string, onChunk func(string)) (string, *models.TokenUsage, error) {
+	cfg.Stream = true
+	body, err := openAIRequestBody(cfg, model, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	timeout := w.config.Models.OpenAI.Timeout
+	if timeout <= 0 {
+		timeout = defaultOpenAITimeout
+	}
+	resp, err := postModelRequest(ctx, cfg, body, w.config.Models.OpenAI.MaxRetries, timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	// 不支持流式的兼容网关会忽略 stream 参数，直接返回完整响应
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read model response: %w", err)
+		}
+		output, usage, err := parseModelResponse(data, cfg.ResponseField)
+		if err == nil && output != "" {
+			onChunk(output)
+		}
+		return output, usage, err
+	}
+	return readOpenAIStream(resp.Body, onChunk)
+}
+
+// readOpenAIStream 读取 Chat Completions 的 SSE 流，每个 data: 事件中 choices.0.delta.content 的增量文本
+// 通过 onChunk 推送，收到 [DONE] 或流正常结束时返回拼接后的完整输出。
+// 事件携带 usage 时返回最后一次的用量，否则用量为 nil，由调用方估算
+func readOpenAIStream(r io.Reader, onChunk func(string)) (string, *models.TokenUsage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+
+	var output strings.Builder
+	var usage *models.TokenUsage
+	for scanner.Scan() {
+		// 空行分隔事件，冒号开头的行是注释（保活），只处理 data 字段
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == openAIStreamDone {
+			return output.String(), usage, nil
+		}
+
+		var event interface{}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return "", nil, fmt.Errorf("invalid model stream event: %w", err)
+		}
+		if err := streamError(event); err != nil {
+			return "", nil, err
+		}
+		if u, ok := parseResponseUsage(event); ok {
+			usage = &u
+		}
+		// 首个事件只带 role，最后一个事件只带 finish_reason，没有增量文本的事件直接跳过
+		if delta, err := extractResponseField(event, openAIDeltaField); err == nil && delta != "" {
+			output.WriteString(delta)
+			onChunk(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to read model stream: %w", err)
+	}
+	return output.String(), usage, nil
+}
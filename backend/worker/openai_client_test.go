@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+// newOpenAITestWorker 创建只用于调用 OpenAI 兼容接口的 Worker
+func newOpenAITestWorker() *Worker {
+	return &Worker{config: &config.Config{}}
+}
+
+// newOpenAITestModel 创建指向 baseURL 的 Azure 风格模型配置
+func newOpenAITestModel(baseURL string) *models.Model {
+	return &models.Model{
+		Name: "gpt-4o",
+		Type: models.ModelTypeOpenAI,
+		Config: models.ModelConfig{
+			"base_url":    baseURL,
+			"api_key":     "secret",
+			"auth_header": "api-key",
+			"deployment":  "prod-gpt4o",
+			"api_version": "2024-06-01",
+		},
+	}
+}
+
+// writeSSE 按 SSE 格式逐个写出事件并刷新
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	for _, event := range events {
+		fmt.Fprintf(w, "data: %s\n\n", event)
+		flusher.Flush()
+	}
+}
+
+func TestDoOpenAIRequestStreamsDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/prod-gpt4o/chat/completions" {
+			t.Errorf("path = %s, want Azure deployment path", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-06-01" {
+			t.Errorf("api-version = %q, want 2024-06-01", got)
+		}
+		if got := r.Header.Get("api-key"); got != "secret" {
+			t.Errorf("api-key header = %q, want secret", got)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if body["stream"] != true {
+			t.Errorf("stream = %v, want true", body["stream"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		writeSSE(w,
+			`{"choices":[{"delta":{"role":"assistant"}}]}`,
+			`{"choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`,
+			"[DONE]",
+		)
+	}))
+	defer server.Close()
+
+	model := newOpenAITestModel(server.URL)
+	cfg, err := buildOpenAIRequestConfig(providerEndpoint{BaseURL: server.URL}, model)
+	if err != nil {
+		t.Fatalf("buildOpenAIRequestConfig: %v", err)
+	}
+
+	var chunks []string
+	output, usage, err := newOpenAITestWorker().doOpenAIRequest(context.Background(), cfg, model, "hi", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("doOpenAIRequest: %v", err)
+	}
+	if output != "Hello" {
+		t.Errorf("output = %q, want Hello", output)
+	}
+	if want := []string{"Hel", "lo"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	if usage == nil || usage.PromptTokens != 5 || usage.CompletionTokens != 2 {
+		t.Errorf("usage = %+v, want 5 prompt and 2 completion tokens", usage)
+	}
+}
+
+func TestDoOpenAIRequestFallsBackToFullResponse(t *testing.T) {
+	// 兼容网关忽略 stream 参数时返回完整的 JSON 响应
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"whole answer"}}]}`)
+	}))
+	defer server.Close()
+
+	model := newOpenAITestModel(server.URL)
+	cfg, err := buildOpenAIRequestConfig(providerEndpoint{BaseURL: server.URL}, model)
+	if err != nil {
+		t.Fatalf("buildOpenAIRequestConfig: %v", err)
+	}
+
+	var chunks []string
+	output, usage, err := newOpenAITestWorker().doOpenAIRequest(context.Background(), cfg, model, "hi", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("doOpenAIRequest: %v", err)
+	}
+	if output != "whole answer" || !reflect.DeepEqual(chunks, []string{"whole answer"}) {
+		t.Errorf("output = %q, chunks = %q, want whole answer pushed once", output, chunks)
+	}
+	if usage != nil {
+		t.Errorf("usage = %+v, want nil", usage)
+	}
+}
+
+func TestReadOpenAIStreamErrorEvent(t *testing.T) {
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n" +
+		"data: {\"error\":{\"message\":\"content filtered\"}}\n\n"
+
+	var chunks []string
+	_, _, err := readOpenAIStream(strings.NewReader(stream), func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err == nil || !strings.Contains(err.Error(), "content filtered") {
+		t.Fatalf("error = %v, want stream error with provider message", err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"partial"}) {
+		t.Errorf("chunks = %q, want chunks before the error", chunks)
+	}
+}
+
+func TestOpenAIRequestBodyStreamFlag(t *testing.T) {
+	model := newOpenAITestModel("http://localhost")
+	for _, stream := range []bool{false, true} {
+		body, err := openAIRequestBody(localRequestConfig{ModelName: "gpt-4o", Stream: stream}, model, "hi")
+		if err != nil {
+			t.Fatalf("openAIRequestBody: %v", err)
+		}
+		var request map[string]interface{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if request["stream"] != stream {
+			t.Errorf("stream = %v, want %v", request["stream"], stream)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// deleteTask 从数据库中硬删除任务，模拟手动清理留下的孤立队列项
+func (env *taskTestEnv) deleteTask(t *testing.T, task *models.Task) {
+	t.Helper()
+	if err := env.db.Unscoped().Delete(&models.Task{}, task.ID).Error; err != nil {
+		t.Fatalf("delete task: %v", err)
+	}
+}
+
+func TestProcessNextTaskDiscardsOrphanItem(t *testing.T) {
+	env := newTaskTestEnv(t)
+	task := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+	env.deleteTask(t, task)
+
+	// 任务已不存在时丢弃队列项而不是返回错误，避免 Worker 反复出错重试
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+	assertClaimReleased(t, env)
+	if status, err := env.queue.GetQueueStatus(context.Background()); err != nil || status.TotalCount != 0 {
+		t.Fatalf("queue status = %+v (err %v), want empty", status, err)
+	}
+}
+
+func TestCollectBatchDiscardsOrphanItems(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.BatchSize = 3
+	env.cfg.Worker.BatchWait = 0
+	if err := env.queue.SetModelCapacity(context.Background(), env.model.ID, 10); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	batchSizes := captureBatchSizes(t, env.worker)
+	tasks := env.createEmbeddingTasks(t, 3)
+	env.deleteTask(t, tasks[1])
+
+	// 凑批时遇到已删除的任务跳过它，其余任务照常合并执行
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+	if sizes := batchSizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("batches = %v, want [2]", sizes)
+	}
+	for _, task := range []*models.Task{tasks[0], tasks[2]} {
+		if got := env.reloadTask(t, task.ID); got.Status != models.TaskStatusCompleted {
+			t.Fatalf("task %d: status %s, want completed", task.ID, got.Status)
+		}
+	}
+	assertClaimReleased(t, env)
+}
+
+func TestReconcileProcessingOnce(t *testing.T) {
+	env := newTaskTestEnv(t)
+	if err := env.queue.SetModelCapacity(context.Background(), env.model.ID, 10); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	m := newPoolTestManager(t, env)
+
+	running := env.createTask(t, models.TaskStatusPending)
+	finished := env.createTask(t, models.TaskStatusPending)
+	deleted := env.createTask(t, models.TaskStatusPending)
+	for _, task := range []*models.Task{running, finished, deleted} {
+		env.claimTask(t, task)
+	}
+	// 一个任务仍在执行，一个已在数据库中结束但未移出处理中队列，一个已被删除
+	if err := env.db.Model(running).Update("status", models.TaskStatusRunning).Error; err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	if err := env.db.Model(finished).Update("status", models.TaskStatusCompleted).Error; err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	env.deleteTask(t, deleted)
+
+	if err := m.reconcileProcessingOnce(); err != nil {
+		t.Fatalf("reconcileProcessingOnce: %v", err)
+	}
+
+	// 只保留仍在执行的任务，其余条目移除并归还并发名额
+	processing, err := env.queue.ProcessingTaskIDs(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessingTaskIDs: %v", err)
+	}
+	if len(processing) != 1 || !processing[running.ID] {
+		t.Fatalf("processing = %v, want only task %d", processing, running.ID)
+	}
+	if n := env.modelInflight(t); n != 1 {
+		t.Fatalf("model inflight = %d, want 1", n)
+	}
+}
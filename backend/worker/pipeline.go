@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"llm-scheduler/models"
+)
+
+// executePipeline 按顺序执行流水线的各个步骤，上一步的输出作为下一步的输入，最后一步的输出为任务输出。
+// 每一步的输出记录在任务日志中，任意一步失败则整个任务失败，错误信息中注明失败的步骤
+func (w *Worker) executePipeline(ctx context.Context, task *models.Task, model *models.Model) (string, *models.TokenUsage, error) {
+	def, err := models.ParsePipeline(task.Input)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(def.Steps) == 0 {
+		return "", nil, fmt.Errorf("pipeline has no steps")
+	}
+
+	current := def.Input
+	var total *models.TokenUsage
+	for i, step := range def.Steps {
+		if step.Type == models.TaskTypePipeline {
+			return "", nil, fmt.Errorf("pipeline step %d failed: nested pipelines are not supported", i+1)
+		}
+
+		stepModel := model
+		if step.ModelID != 0 && step.ModelID != model.ID {
+			if stepModel, err = w.modelService.GetModel(step.ModelID); err != nil {
+				return "", nil, fmt.Errorf("pipeline step %d (%s) failed: %w", i+1, step.Type, err)
+			}
+		}
+
+		// 每一步作为一个临时任务交给对应类型的执行器，ID 等字段沿用流水线任务
+		stepTask := *task
+		stepTask.Type = step.Type
+		stepTask.Input = current
+		stepTask.Params = step.Params
+
+		output, usage, err := w.executeTaskByType(ctx, &stepTask, stepModel)
+		if err != nil {
+			return "", nil, fmt.Errorf("pipeline step %d (%s) failed: %w", i+1, step.Type, err)
+		}
+		if usage != nil {
+			if total == nil {
+				total = &models.TokenUsage{}
+			}
+			total.PromptTokens += usage.PromptTokens
+			total.CompletionTokens += usage.CompletionTokens
+		}
+
+		w.taskService.RecordPipelineStep(task.ID, i, step, stepModel.ID, output)
+		current = output
+	}
+
+	return current, total, nil
+}
@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// createPipelineTask 创建以指定步骤定义为输入的流水线任务
+func (env *taskTestEnv) createPipelineTask(t *testing.T, def models.PipelineDefinition) *models.Task {
+	t.Helper()
+	input, err := json.Marshal(def)
+	if err != nil {
+		t.Fatalf("marshal pipeline: %v", err)
+	}
+	task := env.createTask(t, models.TaskStatusRunning)
+	task.Type = models.TaskTypePipeline
+	task.Input = string(input)
+	if err := env.db.Model(task).Updates(map[string]interface{}{"type": task.Type, "input": task.Input}).Error; err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	return task
+}
+
+// pipelineStepLogs 返回任务日志中记录的流水线步骤
+func (env *taskTestEnv) pipelineStepLogs(t *testing.T, taskID uint64) []models.TaskLog {
+	t.Helper()
+	logs, _, err := env.tasks.ListTaskLogs(taskID, &models.TaskLogListRequest{Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	var steps []models.TaskLog
+	for _, log := range logs {
+		if strings.HasPrefix(log.Message, "Pipeline step ") {
+			steps = append(steps, log)
+		}
+	}
+	return steps
+}
+
+func TestExecutePipelineChainsSteps(t *testing.T) {
+	env := newTaskTestEnv(t)
+	summarizer := &models.Model{Name: "summarizer", Type: models.ModelTypeCustom, Status: models.ModelStatusOnline, MaxWorkers: 1}
+	if err := env.db.Create(summarizer).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+
+	// 先用流水线所属模型翻译，再用另一个模型摘要
+	task := env.createPipelineTask(t, models.PipelineDefinition{
+		Input: "hello",
+		Steps: []models.PipelineStep{
+			{Type: models.TaskTypeTranslation, Params: models.TaskParams{"target_language": "fr"}},
+			{Type: models.TaskTypeSummarization, ModelID: summarizer.ID},
+		},
+	})
+
+	output, _, err := env.worker.executeTaskByType(context.Background(), task, env.model)
+	if err != nil {
+		t.Fatalf("executePipeline: %v", err)
+	}
+	// 上一步的输出作为下一步的输入，最后一步的输出为任务输出
+	translated := "translation result (fr): hello"
+	if want := "summarization result: " + translated; output != want {
+		t.Fatalf("output = %q, want %q", output, want)
+	}
+
+	// 中间输出记录在任务日志中
+	steps := env.pipelineStepLogs(t, task.ID)
+	if len(steps) != 2 {
+		t.Fatalf("got %d step logs, want 2", len(steps))
+	}
+	tests := []struct {
+		message string
+		kind    string
+		modelID uint64
+		output  string
+	}{
+		{"Pipeline step 1 completed", models.TaskTypeTranslation, env.model.ID, translated},
+		{"Pipeline step 2 completed", models.TaskTypeSummarization, summarizer.ID, output},
+	}
+	for i, tt := range tests {
+		log := steps[i]
+		if log.Message != tt.message {
+			t.Fatalf("log %d message = %q, want %q", i, log.Message, tt.message)
+		}
+		if log.Data["type"] != tt.kind || log.Data["output"] != tt.output {
+			t.Fatalf("log %d data = %+v, want type %s and output %q", i, log.Data, tt.kind, tt.output)
+		}
+		if id, _ := log.Data["model_id"].(float64); uint64(id) != tt.modelID {
+			t.Fatalf("log %d model_id = %v, want %d", i, log.Data["model_id"], tt.modelID)
+		}
+	}
+}
+
+func TestExecutePipelineFailsMidPipeline(t *testing.T) {
+	env := newTaskTestEnv(t)
+
+	// 第二步是测试模型不支持的文本生成，第一步已经执行完成
+	task := env.createPipelineTask(t, models.PipelineDefinition{
+		Input: "hello",
+		Steps: []models.PipelineStep{
+			{Type: models.TaskTypeSummarization},
+			{Type: models.TaskTypeTextGeneration},
+			{Type: models.TaskTypeSummarization},
+		},
+	})
+
+	_, _, err := env.worker.executeTaskByType(context.Background(), task, env.model)
+	if err == nil || !strings.Contains(err.Error(), "pipeline step 2 (text-generation) failed") {
+		t.Fatalf("error = %v, want failure at step 2", err)
+	}
+	// 失败之后的步骤不再执行
+	steps := env.pipelineStepLogs(t, task.ID)
+	if len(steps) != 1 || steps[0].Message != "Pipeline step 1 completed" {
+		t.Fatalf("step logs = %+v, want only step 1", steps)
+	}
+}
+
+func TestExecutePipelineRejectsInvalidDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"invalid json", "not json", "pipeline input must be a JSON object"},
+		{"no steps", `{"input":"hello","steps":[]}`, "pipeline has no steps"},
+		{"nested pipeline", `{"input":"hello","steps":[{"type":"pipeline"}]}`, "pipeline step 1 failed: nested pipelines are not supported"},
+		{"unknown step model", `{"input":"hello","steps":[{"type":"summarization","model_id":999}]}`, "pipeline step 1 (summarization) failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			task := env.createTask(t, models.TaskStatusRunning)
+			task.Type = models.TaskTypePipeline
+			task.Input = tt.input
+
+			_, _, err := env.worker.executeTaskByType(context.Background(), task, env.model)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if steps := env.pipelineStepLogs(t, task.ID); len(steps) != 0 {
+				t.Fatalf("step logs = %+v, want none", steps)
+			}
+		})
+	}
+}
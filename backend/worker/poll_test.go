@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPollIntervalsFromConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		idle        time.Duration
+		backoff     time.Duration
+		wantIdle    time.Duration
+		wantBackoff time.Duration
+	}{
+		{"configured", 200 * time.Millisecond, 2 * time.Second, 200 * time.Millisecond, 2 * time.Second},
+		// 未配置时使用默认值
+		{"defaults", 0, 0, time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Worker.IdlePollInterval = tt.idle
+			cfg.Worker.ErrorBackoff = tt.backoff
+			if got := idlePollInterval(cfg); got != tt.wantIdle {
+				t.Fatalf("idlePollInterval = %v, want %v", got, tt.wantIdle)
+			}
+			if got := errorBackoff(cfg); got != tt.wantBackoff {
+				t.Fatalf("errorBackoff = %v, want %v", got, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestProcessNextTaskIdleLatency(t *testing.T) {
+	for _, interval := range []time.Duration{20 * time.Millisecond, 200 * time.Millisecond} {
+		t.Run(interval.String(), func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			env.cfg.Worker.IdlePollInterval = interval
+
+			// 队列为空时只等待一个轮询间隔，不会叠加其他等待
+			start := time.Now()
+			if err := env.worker.processNextTask(); err != nil {
+				t.Fatalf("processNextTask: %v", err)
+			}
+			elapsed := time.Since(start)
+			if elapsed < interval || elapsed > interval+150*time.Millisecond {
+				t.Fatalf("idle poll took %v, want about %v", elapsed, interval)
+			}
+		})
+	}
+}
+
+// countingHook 统计指定消息的日志条数
+type countingHook struct {
+	mu      sync.Mutex
+	message string
+	count   int
+}
+
+func (h *countingHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *countingHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry.Message == h.message {
+		h.count++
+	}
+	return nil
+}
+
+func (h *countingHook) Count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func TestWorkerBacksOffAfterError(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.ErrorBackoff = 50 * time.Millisecond
+	hook := &countingHook{message: "Error processing task"}
+	env.worker.logger.AddHook(hook)
+
+	// Redis 不可用时每次领取都出错，两次领取之间暂停 error_backoff
+	env.redis.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = env.worker.Start(ctx)
+	}()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	<-done
+
+	if n := hook.Count(); n < 2 || n > 7 {
+		t.Fatalf("got %d dequeue errors in 300ms, want one per 50ms backoff", n)
+	}
+}
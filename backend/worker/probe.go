@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// probePrompt 本地模型未配置 health_check_path 时用于探测的极短生成请求
+const probePrompt = "ping"
+
+// probeModels 按 worker.health_probe_interval 探测模型服务，连续失败达到阈值的在线模型切换为 maintenance，
+// 被自动切换为 maintenance 的模型探测成功后恢复为 online
+func (m *Manager) probeModels() {
+	interval := m.config.Worker.HealthProbeInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.runHealthProbes()
+		}
+	}
+}
+
+// runHealthProbes 并发探测所有需要探测的模型，再依次处理结果
+func (m *Manager) runHealthProbes() {
+	modelList, err := m.modelService.GetProbeModels()
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to get models for health probe")
+		return
+	}
+
+	errs := make([]error, len(modelList))
+	probed := make([]bool, len(modelList))
+	var wg sync.WaitGroup
+	for i := range modelList {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			probed[i], errs[i] = m.probeModel(m.ctx, &modelList[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	seen := make(map[uint64]bool, len(modelList))
+	for i := range modelList {
+		if !probed[i] {
+			continue
+		}
+		seen[modelList[i].ID] = true
+		m.applyProbeResult(&modelList[i], errs[i])
+	}
+
+	// 不再需要探测的模型（已手动下线、删除等）清除失败计数
+	for id := range m.probeFailures {
+		if !seen[id] {
+			delete(m.probeFailures, id)
+		}
+	}
+}
+
+// applyProbeResult 根据一次探测结果切换模型状态并调整本实例的 Worker
+func (m *Manager) applyProbeResult(model *models.Model, probeErr error) {
+	logger := m.logger.WithFields(logrus.Fields{
+		"model_id":   model.ID,
+		"model_name": model.Name,
+	})
+
+	if probeErr == nil {
+		delete(m.probeFailures, model.ID)
+		if model.Status != models.ModelStatusMaintenance {
+			return
+		}
+		changed, err := m.modelService.SetModelHealth(model.ID, true)
+		if err != nil {
+			logger.WithError(err).Error("Failed to restore model after health probe")
+			return
+		}
+		if changed {
+			logger.Info("Model health probe recovered, status changed to online")
+		}
+		model.Status = models.ModelStatusOnline
+
+		m.scaleMutex.Lock()
+		m.recoverWorkers(model)
+		m.scaleMutex.Unlock()
+		return
+	}
+
+	m.probeFailures[model.ID]++
+	failures := m.probeFailures[model.ID]
+	logger = logger.WithError(probeErr).WithField("failures", failures)
+	if failures < m.config.Worker.HealthProbeFailures {
+		logger.Warn("Model health probe failed")
+		return
+	}
+
+	if model.Status == models.ModelStatusOnline {
+		changed, err := m.modelService.SetModelHealth(model.ID, false)
+		if err != nil {
+			logger.Error("Failed to mark unhealthy model as maintenance")
+			return
+		}
+		if changed {
+			logger.Warn("Model health probe failed repeatedly, status changed to maintenance")
+		}
+	}
+
+	// 其他实例可能已切换模型状态，本实例的 Worker 同样需要排空，停止领取该模型的任务
+	if len(m.activeWorkers(model.ID)) > 0 {
+		m.scaleMutex.Lock()
+		m.reconcileWorkers(model, 0)
+		m.scaleMutex.Unlock()
+	}
+}
+
+// probeModel 探测模型服务是否可用：配置了 health_check_path 时 GET 该路径并要求返回 2xx，
+// 未配置时本地模型发送一个极短的生成请求，其他类型不探测；返回的 bool 表示是否进行了探测
+func (m *Manager) probeModel(ctx context.Context, model *models.Model) (bool, error) {
+	endpoint, err := modelEndpoint(m.config, model)
+	if err != nil {
+		return false, nil
+	}
+	timeout := m.config.Worker.HealthProbeTimeout
+
+	if path := configString(model, "health_check_path"); path != "" {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return true, probeHealthPath(ctx, strings.TrimRight(endpoint.BaseURL, "/")+path, endpoint.Headers, timeout)
+	}
+
+	if model.Type != models.ModelTypeLocal {
+		return false, nil
+	}
+	cfg := buildLocalRequestConfig(endpoint, model)
+	body, err := localRequestBody(cfg, probePrompt)
+	if err != nil {
+		return true, err
+	}
+	_, _, err = sendModelRequest(ctx, cfg, body, 0, timeout)
+	return true, err
+}
+
+// probeHealthPath 请求健康检查地址，非 2xx 视为失败
+func probeHealthPath(ctx context.Context, url string, headers map[string]string, timeout time.Duration) error {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodyBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("health check %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
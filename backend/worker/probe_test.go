@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// newStubModelServer 启动模型服务桩，健康检查路径按 healthy 返回 200 或 503
+func newStubModelServer(t *testing.T, healthy *atomic.Bool) (host string, port float64) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("split host port: %v", err)
+	}
+	p, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("parse port: %v", err)
+	}
+	return host, float64(p)
+}
+
+func TestHealthProbeTogglesMaintenance(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.HealthProbeFailures = 2
+	env.cfg.Worker.HealthProbeTimeout = time.Second
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	host, port := newStubModelServer(t, &healthy)
+	env.model.Type = models.ModelTypeLocal
+	env.model.Config = models.ModelConfig{"host": host, "port": port, "health_check_path": "healthz"}
+	if err := env.db.Save(env.model).Error; err != nil {
+		t.Fatalf("save model: %v", err)
+	}
+
+	m := newPoolTestManager(t, env)
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+		t.Fatalf("start workers: %v", err)
+	}
+
+	// 服务正常时模型保持在线
+	m.runHealthProbes()
+	if got := env.reloadModel(t); got.Status != models.ModelStatusOnline {
+		t.Fatalf("healthy model status = %s, want online", got.Status)
+	}
+
+	// 服务停止后连续失败达到阈值才切换为 maintenance，并排空本实例的 Worker
+	healthy.Store(false)
+	m.runHealthProbes()
+	if got := env.reloadModel(t); got.Status != models.ModelStatusOnline {
+		t.Fatalf("status after one failure = %s, want online", got.Status)
+	}
+	m.runHealthProbes()
+	got := env.reloadModel(t)
+	if got.Status != models.ModelStatusMaintenance || !got.AutoMaintenance {
+		t.Fatalf("status after two failures = %s (auto %v), want automatic maintenance", got.Status, got.AutoMaintenance)
+	}
+	waitForPool(t, m, got, 0, 0)
+
+	// 服务恢复后回到 online 并重新启动 Worker
+	healthy.Store(true)
+	m.runHealthProbes()
+	got = env.reloadModel(t)
+	if got.Status != models.ModelStatusOnline || got.AutoMaintenance {
+		t.Fatalf("status after recovery = %s (auto %v), want online", got.Status, got.AutoMaintenance)
+	}
+	waitForPool(t, m, got, 1, 0)
+	if len(m.probeFailures) != 0 {
+		t.Fatalf("probe failures not reset: %v", m.probeFailures)
+	}
+
+	// 单次失败后恢复会清零失败计数，之后再失败一次不会切换状态
+	healthy.Store(false)
+	m.runHealthProbes()
+	healthy.Store(true)
+	m.runHealthProbes()
+	healthy.Store(false)
+	m.runHealthProbes()
+	if got := env.reloadModel(t); got.Status != models.ModelStatusOnline {
+		t.Fatalf("status after non-consecutive failures = %s, want online", got.Status)
+	}
+}
+
+func TestHealthProbeLeavesManualMaintenance(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.HealthProbeFailures = 1
+	env.cfg.Worker.HealthProbeTimeout = time.Second
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	host, port := newStubModelServer(t, &healthy)
+	env.model.Type = models.ModelTypeLocal
+	env.model.Status = models.ModelStatusMaintenance
+	env.model.Config = models.ModelConfig{"host": host, "port": port, "health_check_path": "/healthz"}
+	if err := env.db.Save(env.model).Error; err != nil {
+		t.Fatalf("save model: %v", err)
+	}
+	m := newPoolTestManager(t, env)
+
+	// 手动设置的 maintenance 不会因为探测成功而恢复
+	m.runHealthProbes()
+	if got := env.reloadModel(t); got.Status != models.ModelStatusMaintenance {
+		t.Fatalf("manual maintenance status = %s, want maintenance", got.Status)
+	}
+	if status := m.GetPoolStatus(env.model); status.CurrentWorkers != 0 {
+		t.Fatalf("workers started for manual maintenance model: %+v", status)
+	}
+}
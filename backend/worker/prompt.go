@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"strings"
+
+	"llm-scheduler/models"
+)
+
+// promptTemplateError 模型的 prompt_template 无法解析或渲染，任务以该错误失败
+type promptTemplateError struct {
+	err error
+}
+
+func (e *promptTemplateError) Error() string {
+	return "failed to render prompt_template: " + e.err.Error()
+}
+
+func (e *promptTemplateError) Unwrap() error {
+	return e.err
+}
+
+// renderPrompt 按模型配置的 prompt_template 包装任务输入，未配置模板时返回原始输入
+func renderPrompt(task *models.Task, model *models.Model) (string, error) {
+	tmpl, err := model.GetPromptTemplate()
+	if err != nil {
+		return "", &promptTemplateError{err: err}
+	}
+	if tmpl == nil {
+		return task.Input, nil
+	}
+
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, models.PromptData{
+		Input:  task.Input,
+		Type:   task.Type,
+		Params: task.Params,
+		Tags:   task.Tags,
+		TaskID: task.ID,
+		Model:  model.Name,
+	}); err != nil {
+		return "", &promptTemplateError{err: err}
+	}
+	return prompt.String(), nil
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	task := &models.Task{
+		ID:     7,
+		Type:   models.TaskTypeTextGeneration,
+		Input:  "What is Go?",
+		Params: models.TaskParams{"tone": "formal"},
+		Tags:   models.TaskTags{"lang": "en"},
+	}
+	tests := []struct {
+		name     string
+		template interface{}
+		want     string
+		wantErr  string
+	}{
+		// 未配置模板时使用原始输入
+		{"no template", nil, "What is Go?", ""},
+		{"system instruction", "System: answer briefly.\nUser: {{.Input}}", "System: answer briefly.\nUser: What is Go?", ""},
+		{"task fields", "[{{.Model}}/{{.Type}}/{{.Tags.lang}}/{{.Params.tone}}/{{.TaskID}}] {{.Input}}", "[test-model/text-generation/en/formal/7] What is Go?", ""},
+		// 不存在的键渲染为空而不是报错
+		{"missing tag", "{{.Tags.region}}|{{.Input}}", "|What is Go?", ""},
+		{"parse error", "{{.Input", "", "failed to render prompt_template"},
+		{"execution error", "{{.Input.Missing}}", "", "failed to render prompt_template"},
+		{"not a string", []interface{}{"x"}, "", "failed to render prompt_template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &models.Model{Name: "test-model", Config: models.ModelConfig{}}
+			if tt.template != nil {
+				model.Config["prompt_template"] = tt.template
+			}
+			got, err := renderPrompt(task, model)
+			if tt.wantErr != "" {
+				var promptErr *promptTemplateError
+				if !errors.As(err, &promptErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderPrompt error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderPrompt: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("renderPrompt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// useLocalPromptServer 把测试模型改为本地模型并指向记录请求 prompt 的模拟服务
+func (env *taskTestEnv) useLocalPromptServer(t *testing.T, template string) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		mu.Lock()
+		prompts = append(prompts, body["prompt"].(string))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"response": "ok", "done": true})
+	}))
+	t.Cleanup(server.Close)
+
+	host, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+	modelConfig := models.ModelConfig{"host": host, "port": port, "prompt_template": template}
+	if err := env.db.Model(env.model).Updates(map[string]interface{}{"type": models.ModelTypeLocal, "config": modelConfig}).Error; err != nil {
+		t.Fatalf("update model: %v", err)
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), prompts...)
+	}
+}
+
+func TestProcessNextTaskRendersPromptTemplate(t *testing.T) {
+	env := newTaskTestEnv(t)
+	prompts := env.useLocalPromptServer(t, "System: reply in {{.Tags.lang}}.\n\n{{.Input}}")
+	task := env.createTask(t, models.TaskStatusPending)
+	if err := env.db.Model(task).Update("tags", models.TaskTags{"lang": "French"}).Error; err != nil {
+		t.Fatalf("update tags: %v", err)
+	}
+	if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+
+	if err := env.worker.processNextTask(); err != nil {
+		t.Fatalf("processNextTask: %v", err)
+	}
+
+	// 上游收到的是模板渲染后的输入，任务保存的输入不变
+	want := "System: reply in French.\n\nhello"
+	if got := prompts(); len(got) != 1 || got[0] != want {
+		t.Fatalf("prompts = %q, want [%q]", got, want)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusCompleted || got.Input != "hello" {
+		t.Fatalf("status = %s input = %q, want completed with original input", got.Status, got.Input)
+	}
+}
+
+func TestProcessNextTaskFailsOnPromptTemplateError(t *testing.T) {
+	env := newTaskTestEnv(t)
+	prompts := env.useLocalPromptServer(t, "{{.Input.Missing}}")
+	task := env.createTask(t, models.TaskStatusPending)
+	if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+
+	if err := env.worker.processNextTask(); err == nil {
+		t.Fatalf("expected execution error")
+	}
+
+	// 渲染失败时不调用上游，任务以明确的原因失败
+	if got := prompts(); len(got) != 0 {
+		t.Fatalf("prompts = %q, want no upstream call", got)
+	}
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusFailed || got.ErrorMessage == nil || !strings.Contains(*got.ErrorMessage, "failed to render prompt_template") {
+		t.Fatalf("status = %s error = %v, want failed with template error", got.Status, got.ErrorMessage)
+	}
+}
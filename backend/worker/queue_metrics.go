@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"time"
+
+	"llm-scheduler/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// sampleQueueMetrics 按 queue.metrics_interval 记录队列深度样本，并删除超过 queue.metrics_retention 的样本
+func (m *Manager) sampleQueueMetrics() {
+	interval := m.config.Queue.MetricsInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.recordQueueMetric(now.Truncate(interval))
+		}
+	}
+}
+
+// recordQueueMetric 写入一条队列深度样本。采样时间按间隔对齐，其他实例已写入同一时间点时忽略
+func (m *Manager) recordQueueMetric(sampledAt time.Time) {
+	status, err := m.queueManager.GetQueueStatus(m.ctx)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to get queue status for metrics")
+		return
+	}
+
+	metric := models.QueueMetric{
+		SampledAt:           sampledAt,
+		HighPriorityCount:   status.HighPriorityCount,
+		MediumPriorityCount: status.MediumPriorityCount,
+		LowPriorityCount:    status.LowPriorityCount,
+		ProcessingCount:     status.ProcessingCount,
+		DelayedCount:        status.DelayedCount,
+		TotalCount:          status.TotalCount,
+	}
+	if err := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&metric).Error; err != nil {
+		m.logger.WithError(err).Warn("Failed to record queue metrics")
+		return
+	}
+
+	if retention := m.config.Queue.MetricsRetention; retention > 0 {
+		if err := m.db.Where("sampled_at < ?", sampledAt.Add(-retention)).Delete(&models.QueueMetric{}).Error; err != nil {
+			m.logger.WithError(err).Warn("Failed to prune queue metrics")
+		}
+	}
+}
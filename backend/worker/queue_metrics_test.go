@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// queueMetrics 按采样时间升序读取所有队列深度样本
+func (env *taskTestEnv) queueMetrics(t *testing.T) []models.QueueMetric {
+	t.Helper()
+	var metrics []models.QueueMetric
+	if err := env.db.Order("sampled_at ASC").Find(&metrics).Error; err != nil {
+		t.Fatalf("list queue metrics: %v", err)
+	}
+	return metrics
+}
+
+func TestRecordQueueMetric(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Queue.MetricsRetention = time.Hour
+	m := newPoolTestManager(t, env)
+
+	// 两个中优先级任务和一个低优先级任务在排队
+	for _, priority := range []models.TaskPriority{models.TaskPriorityMedium, models.TaskPriorityMedium, models.TaskPriorityLow} {
+		task := env.createTask(t, models.TaskStatusPending)
+		task.Priority = priority
+		if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+			t.Fatalf("enqueue task: %v", err)
+		}
+	}
+
+	sampledAt := time.Now().Truncate(time.Minute)
+	m.recordQueueMetric(sampledAt)
+	metrics := env.queueMetrics(t)
+	if len(metrics) != 1 {
+		t.Fatalf("got %d samples, want 1", len(metrics))
+	}
+	got := metrics[0]
+	if !got.SampledAt.Equal(sampledAt) || got.MediumPriorityCount != 2 || got.LowPriorityCount != 1 || got.HighPriorityCount != 0 || got.TotalCount != 3 {
+		t.Fatalf("sample = %+v, want 2 medium and 1 low at %v", got, sampledAt)
+	}
+
+	// 其他实例已写入同一时间点时不重复记录
+	m.recordQueueMetric(sampledAt)
+	if n := len(env.queueMetrics(t)); n != 1 {
+		t.Fatalf("got %d samples after duplicate sample, want 1", n)
+	}
+
+	// 超过保留时间的样本在写入新样本时删除
+	later := sampledAt.Add(2 * time.Hour)
+	m.recordQueueMetric(later)
+	metrics = env.queueMetrics(t)
+	if len(metrics) != 1 || !metrics[0].SampledAt.Equal(later) {
+		t.Fatalf("samples = %+v, want only the sample at %v", metrics, later)
+	}
+}
+
+func TestSampleQueueMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     bool
+	}{
+		{"periodic samples", 20 * time.Millisecond, true},
+		// 采样间隔为 0 表示不采样
+		{"disabled", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			env.cfg.Queue.MetricsInterval = tt.interval
+			env.cfg.Queue.MetricsRetention = time.Hour
+			m := newPoolTestManager(t, env)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				m.sampleQueueMetrics()
+			}()
+			sampled := waitFor(time.Second, func() bool { return len(env.queueMetrics(t)) >= 2 })
+			m.cancel()
+			<-done
+
+			if sampled != tt.want {
+				t.Fatalf("sampled = %v, want %v", sampled, tt.want)
+			}
+			// 采样时间按间隔对齐
+			for _, metric := range env.queueMetrics(t) {
+				if !metric.SampledAt.Equal(metric.SampledAt.Truncate(tt.interval)) {
+					t.Fatalf("sample at %v not aligned to %v", metric.SampledAt, tt.interval)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// acquireRateLimit 调用上游前按模型的 requests_per_minute 获取令牌。需要等待的时间不超过
+// worker.rate_limit_max_wait 时原地等待，否则返回 false 和建议的延后时间，由调用方放回延迟队列
+func (w *Worker) acquireRateLimit(model *models.Model) (bool, time.Duration) {
+	rpm := model.GetRequestsPerMinute()
+	if rpm <= 0 {
+		return true, 0
+	}
+
+	for {
+		allowed, retryAfter, err := w.queueManager.AcquireRateLimit(w.ctx, model.ID, rpm)
+		if err != nil {
+			// 限流存储不可用时放行，避免 Redis 故障导致任务全部停滞
+			w.logger.WithError(err).WithField("model_id", model.ID).Warn("Model rate limit check failed")
+			return true, 0
+		}
+		if allowed {
+			return true, 0
+		}
+		if retryAfter > w.config.Worker.RateLimitMaxWait {
+			return false, retryAfter
+		}
+		if err := sleepContext(w.ctx, retryAfter); err != nil {
+			return false, 0
+		}
+	}
+}
+
+// deferTask 将尚未开始执行的任务原样放回延迟队列，不计入 delay_count，用于模型限流和熔断
+func (w *Worker) deferTask(task *models.Task, delay time.Duration, reason string) {
+	logger := w.taskLogger(task).WithFields(logrus.Fields{
+		"model_id": task.ModelID,
+		"delay":    delay.String(),
+	})
+	// Worker 可能正在停止，使用独立的 ctx 保证任务能放回队列
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	if err := w.queueManager.DeferTask(ctx, task.ID, delay); err != nil {
+		logger.WithError(err).Error("Failed to defer task")
+		return
+	}
+	logger.Info(reason)
+}
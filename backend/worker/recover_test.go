@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+// killWorker 模拟 Worker 协程意外退出，等待它从管理器中移除
+func killWorker(t *testing.T, m *Manager, workerID string) {
+	t.Helper()
+	m.workersMutex.RLock()
+	worker := m.workers[workerID]
+	m.workersMutex.RUnlock()
+	if worker == nil {
+		t.Fatalf("worker %s not found", workerID)
+	}
+	// Worker 刚启动时可能尚未创建上下文，轮询时重复取消
+	if !waitFor(2*time.Second, func() bool {
+		worker.Kill()
+		m.workersMutex.RLock()
+		defer m.workersMutex.RUnlock()
+		_, exists := m.workers[workerID]
+		return !exists
+	}) {
+		t.Fatalf("worker %s did not exit", workerID)
+	}
+}
+
+func TestCheckWorkerHealthRestoresExitedWorkers(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.db.Model(env.model).Update("max_workers", 2)
+	env.model.MaxWorkers = 2
+	m := newPoolTestManager(t, env)
+
+	// 未设置目标时补齐到 max_workers
+	m.checkWorkerHealth()
+	if n := m.GetWorkerCount(); n != 2 {
+		t.Fatalf("worker count = %d, want 2", n)
+	}
+
+	// Worker 退出后，下一次检查用同一槽位补齐，且不超过 max_workers
+	exited := workerIDFor(env.model.ID, 1)
+	killWorker(t, m, exited)
+	m.checkWorkerHealth()
+	m.checkWorkerHealth()
+	if n := m.GetWorkerCount(); n != 2 {
+		t.Fatalf("worker count after recovery = %d, want 2", n)
+	}
+	m.workersMutex.RLock()
+	_, restored := m.workers[exited]
+	m.workersMutex.RUnlock()
+	if !restored {
+		t.Fatalf("worker %s was not restarted", exited)
+	}
+
+	// 运行时设置的目标低于 max_workers 时按目标补齐
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+		t.Fatalf("set target: %v", err)
+	}
+	if !waitFor(2*time.Second, func() bool { return m.GetWorkerCount() == 1 }) {
+		t.Fatalf("worker count = %d, want 1", m.GetWorkerCount())
+	}
+	m.checkWorkerHealth()
+	if n := m.GetWorkerCount(); n != 1 {
+		t.Fatalf("worker count with target 1 = %d, want 1", n)
+	}
+
+	// 模型不在线时不补齐
+	killWorker(t, m, workerIDFor(env.model.ID, 0))
+	env.db.Model(env.model).Update("status", models.ModelStatusOffline)
+	m.checkWorkerHealth()
+	if n := m.GetWorkerCount(); n != 0 {
+		t.Fatalf("worker count for offline model = %d, want 0", n)
+	}
+}
+
+func TestCheckWorkerHealthRespectsGlobalLimit(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.db.Model(env.model).Update("max_workers", 3)
+	env.model.MaxWorkers = 3
+	env.cfg.Worker.MaxWorkers = 2
+	m := newPoolTestManager(t, env)
+
+	m.checkWorkerHealth()
+	if n := m.GetWorkerCount(); n != 2 {
+		t.Fatalf("worker count = %d, want global limit 2", n)
+	}
+}
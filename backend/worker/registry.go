@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// taskRegistry 记录本实例正在执行的任务及其取消函数
+type taskRegistry struct {
+	mu        sync.Mutex
+	running   map[uint64]context.CancelFunc
+	cancelled map[uint64]bool
+}
+
+// newTaskRegistry 创建任务注册表
+func newTaskRegistry() *taskRegistry {
+	return &taskRegistry{
+		running:   make(map[uint64]context.CancelFunc),
+		cancelled: make(map[uint64]bool),
+	}
+}
+
+// register 登记正在执行的任务
+func (r *taskRegistry) register(taskID uint64, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running[taskID] = cancel
+	delete(r.cancelled, taskID)
+}
+
+// unregister 任务执行结束后移除登记，返回任务是否已被取消
+func (r *taskRegistry) unregister(taskID uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancelled := r.cancelled[taskID]
+	delete(r.running, taskID)
+	delete(r.cancelled, taskID)
+	return cancelled
+}
+
+// cancel 取消正在执行的任务，任务不在本实例执行时返回 false
+func (r *taskRegistry) cancel(taskID uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, exists := r.running[taskID]
+	if !exists {
+		return false
+	}
+	r.cancelled[taskID] = true
+	cancel()
+	return true
+}
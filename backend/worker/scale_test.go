@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newPoolTestManager 创建已启动、预热完成的 Worker 管理器，测试结束时停止全部 Worker
+func newPoolTestManager(t *testing.T, env *taskTestEnv) *Manager {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	m := NewManager(env.cfg, env.db, env.queue, env.tasks, env.models, nil, logger)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	close(m.ready)
+	t.Cleanup(func() {
+		m.workersMutex.RLock()
+		workers := make([]*Worker, 0, len(m.workers))
+		for _, worker := range m.workers {
+			workers = append(workers, worker)
+		}
+		m.workersMutex.RUnlock()
+		for _, worker := range workers {
+			worker.Drain()
+			worker.Wait(time.Second)
+		}
+		m.cancel()
+	})
+	return m
+}
+
+// waitForPool 等待模型 Worker 池达到指定的活跃和排空数量
+func waitForPool(t *testing.T, m *Manager, model *models.Model, current, draining int) {
+	t.Helper()
+	if !waitFor(2*time.Second, func() bool {
+		status := m.GetPoolStatus(model)
+		return status.CurrentWorkers == current && status.DrainingWorkers == draining
+	}) {
+		t.Fatalf("pool = %+v, want %d current and %d draining", m.GetPoolStatus(model), current, draining)
+	}
+}
+
+func TestSetTargetWorkersScalesInPlace(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.db.Model(env.model).Update("max_workers", 3)
+	env.model.MaxWorkers = 3
+	m := newPoolTestManager(t, env)
+
+	status, err := m.SetTargetWorkers(env.model.ID, 3)
+	if err != nil {
+		t.Fatalf("scale up: %v", err)
+	}
+	if status.TargetWorkers != 3 || status.CurrentWorkers != 3 || status.MaxWorkers != 3 {
+		t.Fatalf("pool after scale up = %+v", status)
+	}
+
+	// 缩容时多余的 Worker 先排空，空闲的 Worker 随即退出，剩下槽位最小的 Worker
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+		t.Fatalf("scale down: %v", err)
+	}
+	waitForPool(t, m, env.model, 1, 0)
+	if !waitFor(2*time.Second, func() bool { return m.GetWorkerCount() == 1 }) {
+		t.Fatalf("worker count = %d, want 1", m.GetWorkerCount())
+	}
+	workers := m.activeWorkers(env.model.ID)
+	if len(workers) != 1 || workers[0].id != workerIDFor(env.model.ID, 0) {
+		t.Fatalf("remaining workers = %v, want slot 0", workers)
+	}
+}
+
+func TestSetTargetWorkersRejectsInvalidTargets(t *testing.T) {
+	env := newTaskTestEnv(t)
+	m := newPoolTestManager(t, env)
+
+	// 超过 max_workers 或为负数时拒绝，不启动 Worker
+	for _, target := range []int{-1, env.model.MaxWorkers + 1} {
+		if _, err := m.SetTargetWorkers(env.model.ID, target); err == nil {
+			t.Errorf("target %d: expected error", target)
+		}
+	}
+
+	// 模型不在线时不能启动 Worker
+	env.db.Model(env.model).Update("status", models.ModelStatusOffline)
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err == nil {
+		t.Error("expected error for offline model")
+	}
+	if n := m.GetWorkerCount(); n != 0 {
+		t.Fatalf("worker count = %d, want 0", n)
+	}
+}
+
+func TestSetTargetWorkersRespectsGlobalLimit(t *testing.T) {
+	env := newTaskTestEnv(t)
+	env.cfg.Worker.MaxWorkers = 1
+	m := newPoolTestManager(t, env)
+
+	other := &models.Model{Name: "other", Type: models.ModelTypeCustom, Status: models.ModelStatusOnline, MaxWorkers: 1}
+	if err := env.db.Create(other).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+		t.Fatalf("scale first model: %v", err)
+	}
+
+	// 全局上限已被其他模型占满
+	if _, err := m.SetTargetWorkers(other.ID, 1); err == nil {
+		t.Fatal("expected global worker limit error")
+	}
+	if status := m.GetPoolStatus(other); status.CurrentWorkers != 0 {
+		t.Fatalf("other model pool = %+v, want no workers", status)
+	}
+}
+
+func TestScaleModelUpdatesMaxWorkers(t *testing.T) {
+	env := newTaskTestEnv(t)
+	m := newPoolTestManager(t, env)
+
+	// 扩容超过 max_workers 时同步更新 max_workers
+	status, err := m.ScaleModel(env.model.ID, 3)
+	if err != nil {
+		t.Fatalf("scale up: %v", err)
+	}
+	if status.CurrentWorkers != 3 || status.MaxWorkers != 3 || m.GetWorkerCount() != 3 {
+		t.Fatalf("pool after scale up = %+v, worker count %d", status, m.GetWorkerCount())
+	}
+	if got := env.reloadModel(t); got.MaxWorkers != 3 {
+		t.Fatalf("max_workers = %d, want 3", got.MaxWorkers)
+	}
+
+	// 缩容时多余的 Worker 排空后退出
+	if _, err := m.ScaleModel(env.model.ID, 1); err != nil {
+		t.Fatalf("scale down: %v", err)
+	}
+	if !waitFor(2*time.Second, func() bool { return m.GetWorkerCount() == 1 }) {
+		t.Fatalf("worker count = %d, want 1", m.GetWorkerCount())
+	}
+	if got := env.reloadModel(t); got.MaxWorkers != 1 {
+		t.Fatalf("max_workers = %d, want 1", got.MaxWorkers)
+	}
+
+	// 数量为 0 时停止全部 Worker，max_workers 保持不变
+	if _, err := m.ScaleModel(env.model.ID, 0); err != nil {
+		t.Fatalf("scale to zero: %v", err)
+	}
+	if !waitFor(2*time.Second, func() bool { return m.GetWorkerCount() == 0 }) {
+		t.Fatalf("worker count = %d, want 0", m.GetWorkerCount())
+	}
+	if got := env.reloadModel(t); got.MaxWorkers != 1 {
+		t.Fatalf("max_workers = %d, want 1", got.MaxWorkers)
+	}
+
+	if _, err := m.ScaleModel(env.model.ID, -1); err == nil {
+		t.Fatal("expected error for negative count")
+	}
+}
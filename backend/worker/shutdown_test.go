@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"llm-scheduler/models"
+)
+
+func TestStopAllWorkersDrainsOrRequeuesInflightTask(t *testing.T) {
+	tests := []struct {
+		name          string
+		workerTimeout time.Duration
+		want          models.TaskStatus
+		queued        int
+	}{
+		// 排空时间足够，任务执行完成后 Worker 退出
+		{"drained", 3 * time.Second, models.TaskStatusCompleted, 0},
+		// 排空超时后强制取消，任务重置为 pending 并放回队列
+		{"cancelled", 100 * time.Millisecond, models.TaskStatusPending, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			env.cfg.Worker.WorkerTimeout = tt.workerTimeout
+			m := newPoolTestManager(t, env)
+
+			// 摘要任务执行约 1 秒，停止时仍在执行
+			task := env.createTask(t, models.TaskStatusPending)
+			task.Type = models.TaskTypeSummarization
+			env.db.Model(task).Update("type", task.Type)
+			if err := env.queue.EnqueueTask(context.Background(), task); err != nil {
+				t.Fatalf("enqueue task: %v", err)
+			}
+			if _, err := m.SetTargetWorkers(env.model.ID, 1); err != nil {
+				t.Fatalf("start worker: %v", err)
+			}
+			if !waitFor(2*time.Second, func() bool {
+				return env.reloadTask(t, task.ID).Status == models.TaskStatusRunning
+			}) {
+				t.Fatalf("task never started, status %s", env.reloadTask(t, task.ID).Status)
+			}
+
+			m.stopAllWorkers()
+
+			got := env.reloadTask(t, task.ID)
+			if got.Status != tt.want {
+				t.Fatalf("task status after stop = %s, want %s", got.Status, tt.want)
+			}
+			assertClaimReleased(t, env)
+
+			queued := 0
+			if env.redis.Exists(env.cfg.Queue.MediumPriorityQueue) {
+				members, _ := env.redis.ZMembers(env.cfg.Queue.MediumPriorityQueue)
+				queued = len(members)
+			}
+			if queued != tt.queued {
+				t.Fatalf("ready queue has %d tasks, want %d", queued, tt.queued)
+			}
+			if got.Status == models.TaskStatusPending && got.StartedAt != nil {
+				t.Fatalf("requeued task kept started_at %v", got.StartedAt)
+			}
+		})
+	}
+}
+
+func TestDrainBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		workerTimeout time.Duration
+		deadline      time.Duration // 0 表示未通过 Shutdown 指定截止时间
+		want          time.Duration
+	}{
+		{"default", 0, 0, stopTimeout},
+		{"capped by worker timeout", time.Second, 0, time.Second},
+		// 截止前的剩余时间预留强制取消后放回队列的时间
+		{"shutdown deadline", 0, requeueTimeout + 10*time.Second, 10 * time.Second},
+		{"deadline capped by worker timeout", time.Second, requeueTimeout + 10*time.Second, time.Second},
+		{"deadline too close", 0, requeueTimeout / 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTaskTestEnv(t)
+			env.cfg.Worker.WorkerTimeout = tt.workerTimeout
+			m := newPoolTestManager(t, env)
+			if tt.deadline > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+				defer cancel()
+				m.Shutdown(ctx)
+			}
+
+			// 截止时间在调用 Shutdown 时开始计时，允许少量误差
+			got := m.drainBudget()
+			if got > tt.want || got < tt.want-100*time.Millisecond {
+				t.Fatalf("drainBudget = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
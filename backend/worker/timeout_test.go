@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+)
+
+func TestGetTaskTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		config models.ModelConfig
+		global time.Duration
+		want   time.Duration
+	}{
+		{"model duration string", models.ModelConfig{"task_timeout": "90s"}, time.Minute, 90 * time.Second},
+		{"model seconds", models.ModelConfig{"task_timeout": float64(2)}, time.Minute, 2 * time.Second},
+		{"invalid model value uses global", models.ModelConfig{"task_timeout": "soon"}, time.Minute, time.Minute},
+		{"non-positive model value uses global", models.ModelConfig{"task_timeout": float64(0)}, time.Minute, time.Minute},
+		{"global", models.ModelConfig{}, 2 * time.Minute, 2 * time.Minute},
+		{"default", models.ModelConfig{}, 0, 300 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Queue.TaskTimeout = tt.global
+			w := &Worker{config: cfg}
+			if got := w.getTaskTimeout(&models.Model{Config: tt.config}); got != tt.want {
+				t.Fatalf("timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTaskFailsAfterModelTimeout(t *testing.T) {
+	env := newTaskTestEnv(t)
+	if err := env.db.Model(env.model).Update("config", models.ModelConfig{"task_timeout": "50ms"}).Error; err != nil {
+		t.Fatalf("update model config: %v", err)
+	}
+
+	// 摘要任务执行约 1 秒，超过模型的 task_timeout 后被中断并标记为失败
+	task := env.createTask(t, models.TaskStatusPending)
+	task.Type = models.TaskTypeSummarization
+	env.db.Model(task).Update("type", task.Type)
+	env.claimTask(t, task)
+
+	start := time.Now()
+	if err := env.worker.executeTask(task); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("execution took %s, want it interrupted by the timeout", elapsed)
+	}
+
+	got := env.reloadTask(t, task.ID)
+	if got.Status != models.TaskStatusFailed {
+		t.Fatalf("expected failed task, got %s", got.Status)
+	}
+	if got.ErrorMessage == nil || !strings.Contains(*got.ErrorMessage, "execution timeout after 50ms") {
+		t.Fatalf("error message = %v, want execution timeout", got.ErrorMessage)
+	}
+	assertClaimReleased(t, env)
+	if env.worker.IsBusy() {
+		t.Fatal("expected worker to be idle after timeout")
+	}
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/services"
+	"llm-scheduler/tracing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useMemoryTracer 将全局 TracerProvider 替换为同步写入内存导出器的实现，测试结束后恢复
+func useMemoryTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(&config.Config{}, sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// spanAttribute 读取 span 的属性值
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestExecuteTaskRecordsSpanPerTask(t *testing.T) {
+	exporter := useMemoryTracer(t)
+
+	w, queueManager, _ := newWarmupTestWorker(t, nil)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	w.modelService = services.NewModelService(newUnreachableDB(t), logger)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	defer w.cancel()
+
+	// 入队时处于请求的追踪中，Worker 的 span 应延续该追踪
+	ctx, root := tracing.Tracer().Start(context.Background(), "POST /api/v1/tasks")
+	taskTypes := []string{models.TaskTypeTextGeneration, models.TaskTypeTranslation, models.TaskTypeEmbedding}
+	for i, taskType := range taskTypes {
+		task := &models.Task{ModelID: 1, Type: taskType, Priority: models.TaskPriorityMedium, Status: models.TaskStatusPending}
+		task.ID = uint64(i + 1)
+		if err := queueManager.EnqueueTask(ctx, task); err != nil {
+			t.Fatalf("enqueue task: %v", err)
+		}
+	}
+	root.End()
+
+	for range taskTypes {
+		item, err := queueManager.DequeueTask(context.Background(), 1, nil)
+		if err != nil || item == nil {
+			t.Fatalf("dequeue = %v, %v, want item", item, err)
+		}
+		task := &models.Task{ModelID: item.ModelID, Type: item.Type, TraceParent: item.TraceParent}
+		task.ID = item.TaskID
+		// 测试数据库不可用，读取模型失败，任务以 failed 结束
+		if err := w.executeTask(task); err == nil {
+			t.Errorf("executeTask(%d) succeeded, want model lookup error", task.ID)
+		}
+	}
+
+	var executed []tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "worker.executeTask" {
+			executed = append(executed, span)
+		}
+	}
+	if len(executed) != len(taskTypes) {
+		t.Fatalf("executeTask spans = %d, want %d", len(executed), len(taskTypes))
+	}
+
+	rootTraceID := root.SpanContext().TraceID()
+	for i, span := range executed {
+		if span.SpanContext.TraceID() != rootTraceID {
+			t.Errorf("span %d trace = %s, want request trace %s", i, span.SpanContext.TraceID(), rootTraceID)
+		}
+		if got, _ := spanAttribute(span, "task.type"); got.AsString() != taskTypes[i] {
+			t.Errorf("span %d task.type = %q, want %q", i, got.AsString(), taskTypes[i])
+		}
+		if got, _ := spanAttribute(span, "model.id"); got.AsInt64() != 1 {
+			t.Errorf("span %d model.id = %d, want 1", i, got.AsInt64())
+		}
+		if got, _ := spanAttribute(span, "task.outcome"); got.AsString() != "failed" {
+			t.Errorf("span %d task.outcome = %q, want failed", i, got.AsString())
+		}
+		if span.Status.Code != codes.Error {
+			t.Errorf("span %d status = %v, want error", i, span.Status.Code)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"llm-scheduler/models"
+)
+
+// estimateTokens 粗略估算文本的 token 数：ASCII 字符约 4 个一个 token，其余字符（如中文）每个计一个 token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// estimateUsage 上游未返回用量时根据输入输出估算
+func estimateUsage(input, output string) models.TokenUsage {
+	return models.TokenUsage{
+		PromptTokens:     estimateTokens(input),
+		CompletionTokens: estimateTokens(output),
+	}
+}
+
+// parseResponseUsage 从模型响应中读取 token 用量，支持 OpenAI 兼容接口的 usage 字段
+// 和 Ollama 的 prompt_eval_count / eval_count；响应中没有用量信息时返回 false
+func parseResponseUsage(value interface{}) (models.TokenUsage, bool) {
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return models.TokenUsage{}, false
+	}
+
+	if usage, ok := result["usage"].(map[string]interface{}); ok {
+		prompt, hasPrompt := usage["prompt_tokens"].(float64)
+		completion, hasCompletion := usage["completion_tokens"].(float64)
+		if hasPrompt || hasCompletion {
+			return models.TokenUsage{PromptTokens: int(prompt), CompletionTokens: int(completion)}, true
+		}
+	}
+
+	prompt, hasPrompt := result["prompt_eval_count"].(float64)
+	completion, hasCompletion := result["eval_count"].(float64)
+	if hasPrompt || hasCompletion {
+		return models.TokenUsage{PromptTokens: int(prompt), CompletionTokens: int(completion)}, true
+	}
+	return models.TokenUsage{}, false
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+func TestParseResponseUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		want   models.TokenUsage
+		wantOK bool
+	}{
+		{"openai usage block", `{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":30,"total_tokens":42}}`, models.TokenUsage{PromptTokens: 12, CompletionTokens: 30}, true},
+		{"openai prompt only", `{"usage":{"prompt_tokens":7}}`, models.TokenUsage{PromptTokens: 7}, true},
+		{"ollama counts", `{"response":"hi","prompt_eval_count":5,"eval_count":9}`, models.TokenUsage{PromptTokens: 5, CompletionTokens: 9}, true},
+		{"usage without token fields", `{"usage":{"total_tokens":42}}`, models.TokenUsage{}, false},
+		{"no usage", `{"response":"hi"}`, models.TokenUsage{}, false},
+		{"not an object", `["usage"]`, models.TokenUsage{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.body), &value); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, ok := parseResponseUsage(value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("parseResponseUsage = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveUsage(t *testing.T) {
+	model := &models.Model{Config: models.ModelConfig{
+		"prompt_price_per_1k":     0.5,
+		"completion_price_per_1k": 1.5,
+	}}
+	task := &models.Task{Input: "abcdefgh你好"} // 8 个 ASCII 字符计 2 个 token，2 个中文字符各计 1 个
+
+	tests := []struct {
+		name     string
+		output   string
+		reported *models.TokenUsage
+		want     models.TokenUsage
+	}{
+		{"reported usage", "ignored", &models.TokenUsage{PromptTokens: 1000, CompletionTokens: 2000}, models.TokenUsage{PromptTokens: 1000, CompletionTokens: 2000, CostUSD: 3.5}},
+		{"estimated usage", "abcde", nil, models.TokenUsage{PromptTokens: 4, CompletionTokens: 2, CostUSD: 0.005}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveUsage(task, model, tt.output, tt.reported)
+			if got.PromptTokens != tt.want.PromptTokens || got.CompletionTokens != tt.want.CompletionTokens || math.Abs(got.CostUSD-tt.want.CostUSD) > 1e-9 {
+				t.Fatalf("resolveUsage = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// 未配置价格时费用为 0
+	if got := resolveUsage(task, &models.Model{}, "", &models.TokenUsage{PromptTokens: 100}); got.CostUSD != 0 {
+		t.Fatalf("cost without prices = %v, want 0", got.CostUSD)
+	}
+}
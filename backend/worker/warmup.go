@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// readinessRetryInterval 模型均未就绪时再次探测的间隔
+const readinessRetryInterval = 5 * time.Second
+
+// warmup 等待 worker.startup_delay，开启 worker.readiness_gate 时再等到至少一个模型健康探测成功，
+// 之后放行 Worker 领取任务；管理器在此之前停止时不放行
+func (m *Manager) warmup() {
+	if delay := m.config.Worker.StartupDelay; delay > 0 {
+		m.logger.WithField("delay", delay.String()).Info("Waiting before workers start consuming")
+		if err := sleepContext(m.ctx, delay); err != nil {
+			return
+		}
+	}
+
+	if m.config.Worker.ReadinessGate && !m.waitModelReady() {
+		return
+	}
+
+	close(m.ready)
+	m.logger.Info("Warmup completed, workers start consuming")
+}
+
+// waitModelReady 反复探测模型直到至少一个模型就绪，超过 worker.readiness_timeout 后放弃等待，
+// 管理器停止时返回 false
+func (m *Manager) waitModelReady() bool {
+	var deadline time.Time
+	if timeout := m.config.Worker.ReadinessTimeout; timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		if m.anyModelReady() {
+			return true
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			m.logger.WithField("timeout", m.config.Worker.ReadinessTimeout.String()).
+				Warn("No model passed readiness probe before timeout, workers start consuming anyway")
+			return true
+		}
+		if err := sleepContext(m.ctx, readinessRetryInterval); err != nil {
+			return false
+		}
+	}
+}
+
+// anyModelReady 依次探测需要探测的模型，任一模型探测成功，或没有可探测的模型时返回 true；
+// 探测结果不计入健康探测的失败次数，也不切换模型状态
+func (m *Manager) anyModelReady() bool {
+	modelList, err := m.modelService.GetProbeModels()
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to get models for readiness probe")
+		return false
+	}
+
+	probedAny := false
+	for i := range modelList {
+		model := &modelList[i]
+		probed, err := m.probeModel(m.ctx, model)
+		if !probed {
+			continue
+		}
+		probedAny = true
+		if err == nil {
+			m.logger.WithFields(logrus.Fields{
+				"model_id":   model.ID,
+				"model_name": model.Name,
+			}).Info("Model passed readiness probe")
+			return true
+		}
+		m.logger.WithError(err).WithField("model_id", model.ID).Debug("Model readiness probe failed")
+	}
+
+	if !probedAny {
+		m.logger.Info("No model can be probed, skipping readiness gate")
+	}
+	return !probedAny
+}
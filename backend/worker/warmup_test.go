@@ -0,0 +1,241 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"llm-scheduler/config"
+	"llm-scheduler/models"
+	"llm-scheduler/queue"
+	"llm-scheduler/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newWarmupTestConfig 返回与 config.yaml 一致的队列键名配置
+func newWarmupTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Queue = config.QueueConfig{
+		HighPriorityQueue:   "llm_tasks:high",
+		MediumPriorityQueue: "llm_tasks:medium",
+		LowPriorityQueue:    "llm_tasks:low",
+		DelayedQueue:        "llm_tasks:delayed",
+		ProcessingQueue:     "llm_tasks:processing",
+		InflightKey:         "llm_tasks:model_inflight",
+		CapacityKey:         "llm_tasks:model_capacity",
+		KeyInflightKey:      "llm_tasks:key_inflight",
+		ModelQueuesKey:      "llm_tasks:model_queues",
+	}
+	cfg.Queue.Degraded.Key = "llm_tasks:degraded"
+	cfg.Worker.InstanceID = "test-instance"
+	cfg.Worker.RosterKey = "llm_tasks:workers"
+	cfg.Worker.IdlePollInterval = 10 * time.Millisecond
+	cfg.Worker.ErrorBackoff = time.Hour
+	return cfg
+}
+
+// newUnreachableDB 返回连接不到数据库的 gorm 实例，所有查询都返回错误
+func newUnreachableDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "test:test@tcp(127.0.0.1:1)/llm_scheduler?timeout=100ms",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true, Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	return db
+}
+
+// newWarmupTestWorker 创建连接 miniredis 的 Worker，ready 未关闭前不领取任务；
+// 取到任务后读取数据库失败，任务放回延迟队列
+func newWarmupTestWorker(t *testing.T, ready <-chan struct{}) (*Worker, *queue.Manager, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := newWarmupTestConfig()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	queueManager := queue.NewManager(&queue.Clients{Default: client}, cfg, logger)
+	taskService := services.NewTaskService(newUnreachableDB(t), queueManager, nil, cfg, logger)
+
+	w := NewWorker(workerIDFor(1, 0), 1, cfg, queueManager, taskService, nil, logger)
+	w.ready = ready
+	return w, queueManager, server
+}
+
+// processingCount 返回处理中队列中的任务数
+func processingCount(t *testing.T, server *miniredis.Miniredis) int {
+	t.Helper()
+	if !server.Exists("llm_tasks:processing") {
+		return 0
+	}
+	members, err := server.ZMembers("llm_tasks:processing")
+	if err != nil {
+		t.Fatalf("read processing queue: %v", err)
+	}
+	return len(members)
+}
+
+// waitFor 轮询 cond 直到其返回 true，超时返回 false
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestWorkerDoesNotDequeueBeforeReady(t *testing.T) {
+	ready := make(chan struct{})
+	w, queueManager, server := newWarmupTestWorker(t, ready)
+
+	task := &models.Task{ModelID: 1, Type: "translation", Priority: models.TaskPriorityMedium, Status: models.TaskStatusPending}
+	task.ID = 1
+	if err := queueManager.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+
+	go w.Start(context.Background())
+	t.Cleanup(func() {
+		w.Kill()
+		w.Wait(time.Second)
+	})
+
+	if !waitFor(time.Second, func() bool { return w.GetStatus().Status == "warming_up" }) {
+		t.Fatalf("worker status = %s, want warming_up", w.GetStatus().Status)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := processingCount(t, server); n != 0 {
+		t.Fatalf("processing tasks before ready = %d, want 0", n)
+	}
+
+	close(ready)
+	if !waitFor(2*time.Second, func() bool { return !server.Exists("llm_tasks:medium") }) {
+		t.Fatalf("worker did not dequeue after warmup completed")
+	}
+	// 读取数据库失败，领取的任务放回延迟队列，不占用处理中队列
+	if !waitFor(time.Second, func() bool { return server.Exists("llm_tasks:delayed") }) {
+		t.Fatalf("task was not released to the delayed queue")
+	}
+	if n := processingCount(t, server); n != 0 {
+		t.Fatalf("processing tasks after release = %d, want 0", n)
+	}
+}
+
+func TestWorkerDrainedDuringWarmupNeverDequeues(t *testing.T) {
+	w, queueManager, server := newWarmupTestWorker(t, make(chan struct{}))
+
+	task := &models.Task{ModelID: 1, Type: "translation", Priority: models.TaskPriorityMedium, Status: models.TaskStatusPending}
+	task.ID = 1
+	if err := queueManager.EnqueueTask(context.Background(), task); err != nil {
+		t.Fatalf("enqueue task: %v", err)
+	}
+
+	go w.Start(context.Background())
+	w.Drain()
+	if !w.Wait(3 * readyPollInterval) {
+		w.Kill()
+		t.Fatalf("worker drained during warmup did not exit")
+	}
+	if n := processingCount(t, server); n != 0 {
+		t.Errorf("processing tasks = %d, want 0", n)
+	}
+}
+
+// newWarmupTestManager 创建只运行预热流程的管理器，模型服务连接不到数据库
+func newWarmupTestManager(t *testing.T, configure func(*config.WorkerConfig)) *Manager {
+	t.Helper()
+	cfg := newWarmupTestConfig()
+	configure(&cfg.Worker)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := NewManager(cfg, nil, nil, nil, services.NewModelService(newUnreachableDB(t), logger), nil, logger)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	t.Cleanup(m.cancel)
+	return m
+}
+
+// isReady 返回管理器是否已放行 Worker
+func isReady(m *Manager) bool {
+	select {
+	case <-m.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWarmupWaitsForStartupDelay(t *testing.T) {
+	m := newWarmupTestManager(t, func(w *config.WorkerConfig) {
+		w.StartupDelay = 200 * time.Millisecond
+	})
+
+	start := time.Now()
+	go m.warmup()
+
+	select {
+	case <-m.ready:
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("ready after %s, want at least startup_delay", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("warmup did not complete after startup_delay")
+	}
+}
+
+func TestWarmupReadinessGateHoldsUntilModelReady(t *testing.T) {
+	// 模型列表读取失败视为没有模型就绪，Worker 一直不放行，管理器停止时也不放行
+	m := newWarmupTestManager(t, func(w *config.WorkerConfig) {
+		w.ReadinessGate = true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.warmup()
+		close(done)
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	if isReady(m) {
+		t.Fatal("workers released before any model passed readiness probe")
+	}
+
+	m.cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("warmup did not return after manager stopped")
+	}
+	if isReady(m) {
+		t.Error("workers released after manager stopped during warmup")
+	}
+}
+
+func TestWarmupReadinessGateTimeout(t *testing.T) {
+	m := newWarmupTestManager(t, func(w *config.WorkerConfig) {
+		w.ReadinessGate = true
+		w.ReadinessTimeout = time.Nanosecond
+	})
+
+	go m.warmup()
+	select {
+	case <-m.ready:
+	case <-time.After(readinessRetryInterval + 2*time.Second):
+		t.Fatal("workers not released after readiness_timeout")
+	}
+}
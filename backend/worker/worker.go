@@ -2,34 +2,58 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"llm-scheduler/config"
 	"llm-scheduler/models"
 	"llm-scheduler/queue"
 	"llm-scheduler/services"
+	"llm-scheduler/tracing"
+	"llm-scheduler/utils"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Worker struct {
 	id            string
 	modelID       uint64
+	config        *config.Config
 	queueManager  *queue.Manager
 	taskService   *services.TaskService
 	modelService  *services.ModelService
 	logger        *logrus.Logger
+	mu            sync.Mutex // 保护 status、currentTask、modelName、taskTypes 和 cancel，Worker 协程写入、GetStatus 等并发读取
 	status        string
 	currentTask   *uint64
+	modelName     string
 	startTime     time.Time
-	lastHeartbeat time.Time
+	lastHeartbeat int64 // UnixNano，心跳协程写入、健康检查读取，使用原子操作
+	draining      int32
+	registry      *taskRegistry
+	breakers      *breakerRegistry
+	slot          int             // 在模型 Worker 池中的槽位，决定 Worker ID
+	taskTypes     []string        // 只领取这些类型的任务，为空表示不限类型；Worker 池调整后由管理器重新分配
+	ready         <-chan struct{} // 管理器预热完成后关闭，为 nil 时不等待
+	done          chan struct{}
 	ctx           context.Context
 	cancel        context.CancelFunc
 }
 
+// requeueTimeout 强制停止时将执行中任务放回队列的超时时间
+const requeueTimeout = 5 * time.Second
+
 func NewWorker(
 	id string,
 	modelID uint64,
+	cfg *config.Config,
 	queueManager *queue.Manager,
 	taskService *services.TaskService,
 	modelService *services.ModelService,
@@ -38,17 +62,22 @@ func NewWorker(
 	return &Worker{
 		id:           id,
 		modelID:      modelID,
+		config:       cfg,
 		queueManager: queueManager,
 		taskService:  taskService,
 		modelService: modelService,
 		logger:       logger,
 		status:       "idle",
 		startTime:    time.Now(),
+		done:         make(chan struct{}),
 	}
 }
 
 func (w *Worker) Start(ctx context.Context) error {
+	w.mu.Lock()
 	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.mu.Unlock()
+	defer close(w.done)
 	w.logger.WithFields(logrus.Fields{
 		"worker_id": w.id,
 		"model_id":  w.modelID,
@@ -56,212 +85,789 @@ func (w *Worker) Start(ctx context.Context) error {
 
 	go w.heartbeat()
 
+	if !w.waitReady() {
+		w.logger.WithField("worker_id", w.id).Info("Worker stopped before warmup completed")
+		w.cancel()
+		return nil
+	}
+
 	for {
 		select {
 		case <-w.ctx.Done():
 			w.logger.WithField("worker_id", w.id).Info("Worker stopped")
 			return nil
 		default:
+			if w.IsDraining() {
+				// 排空完成：当前任务已结束，不再领取新任务
+				w.logger.WithField("worker_id", w.id).Info("Worker drained")
+				w.cancel()
+				return nil
+			}
 			if err := w.processNextTask(); err != nil {
 				w.logger.WithError(err).WithField("worker_id", w.id).Error("Error processing task")
-				// 短暂休息后继续
-				time.Sleep(5 * time.Second)
+				// 短暂休息后继续，停止时立即返回
+				_ = sleepContext(w.ctx, errorBackoff(w.config))
 			}
 		}
 	}
 }
 
+// readyPollInterval 等待预热期间检查 Worker 是否被排空的间隔
+const readyPollInterval = time.Second
+
+// waitReady 等待 Worker 管理器预热完成后再领取任务，期间 Worker 被排空或停止时返回 false
+func (w *Worker) waitReady() bool {
+	if w.ready == nil {
+		return true
+	}
+	select {
+	case <-w.ready:
+		return true
+	default:
+	}
+
+	w.mu.Lock()
+	w.status = "warming_up"
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.status = "idle"
+		w.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ready:
+			return true
+		case <-w.ctx.Done():
+			return false
+		case <-ticker.C:
+			if w.IsDraining() {
+				return false
+			}
+		}
+	}
+}
+
+// Stop 请求 Worker 排空：不再领取新任务，等待当前任务完成（最长 WorkerTimeout）后返回，
+// 超时后强制取消，执行中的任务会被放回队列
 func (w *Worker) Stop() {
-	if w.cancel != nil {
-		w.cancel()
+	w.Drain()
+	if !w.Wait(w.config.Worker.WorkerTimeout) {
+		w.Kill()
+		w.Wait(requeueTimeout)
+	}
+}
+
+// Kill 强制取消 Worker，执行中的任务会被放回队列
+func (w *Worker) Kill() {
+	w.mu.Lock()
+	cancel := w.cancel
+	w.mu.Unlock()
+	if cancel != nil {
+		cancel()
 	}
 }
 
+// Wait 等待 Worker 退出，超时返回 false
+func (w *Worker) Wait(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Drain 让 Worker 完成当前任务后退出，不再领取新任务
+func (w *Worker) Drain() {
+	atomic.StoreInt32(&w.draining, 1)
+}
+
+// IsDraining 检查 Worker 是否处于排空状态
+func (w *Worker) IsDraining() bool {
+	return atomic.LoadInt32(&w.draining) == 1
+}
+
+// IsBusy 检查 Worker 是否正在执行任务
+func (w *Worker) IsBusy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status == "busy"
+}
+
+// setBusy 标记 Worker 开始执行任务
+func (w *Worker) setBusy(taskID uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = "busy"
+	w.currentTask = &taskID
+}
+
+// setIdle 标记 Worker 当前没有执行任务
+func (w *Worker) setIdle() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = "idle"
+	w.currentTask = nil
+}
+
+// getTaskTypes 获取 Worker 当前只领取的任务类型，nil 表示不限类型
+func (w *Worker) getTaskTypes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.taskTypes
+}
+
+// setTaskTypes 更新 Worker 只领取的任务类型，从下一次领取开始生效
+func (w *Worker) setTaskTypes(types []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.taskTypes = types
+}
+
+// setModelName 更新状态中显示的模型名称，模型改名后在下一个任务时生效
+func (w *Worker) setModelName(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.modelName = name
+}
+
 func (w *Worker) processNextTask() error {
-	queueItem, err := w.queueManager.DequeueTask(w.ctx, w.modelID)
+	queueItem, err := w.queueManager.DequeueTask(w.ctx, w.modelID, w.getTaskTypes())
 	if err != nil {
 		return fmt.Errorf("failed to dequeue task: %w", err)
 	}
 
+	// 就绪队列是有序集合，出队不会阻塞，队列为空时等待后再领取
 	if queueItem == nil {
-		time.Sleep(1 * time.Second)
+		_ = sleepContext(w.ctx, idlePollInterval(w.config))
 		return nil
 	}
 
 	task, err := w.taskService.GetTask(queueItem.TaskID)
+	if errors.Is(err, services.ErrTaskNotFound) {
+		w.discardOrphan(queueItem)
+		return nil
+	}
 	if err != nil {
 		w.logger.WithError(err).WithField("task_id", queueItem.TaskID).Error("Failed to get task")
+		w.releaseItem(queueItem)
 		return err
 	}
+	task.TraceParent = queueItem.TraceParent
+
+	// 任务在排队期间已被取消，直接从处理队列中移除
+	if task.IsCompleted() {
+		w.taskLogger(task).WithField("status", task.Status).Info("Skipping task already in terminal status")
+		_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+		return nil
+	}
+	if w.dropExpired(task) {
+		return nil
+	}
 
+	if task.Type == models.TaskTypeEmbedding && w.batchSize() > 1 {
+		return w.processEmbeddingBatch(task)
+	}
 	return w.executeTask(task)
 }
 
-func (w *Worker) executeTask(task *models.Task) error {
-	w.status = "busy"
-	w.currentTask = &task.ID
+// executeTask 执行单个任务。执行过程记录为入队追踪下的 span，属性包括任务类型、模型和执行结果
+func (w *Worker) executeTask(task *models.Task) (err error) {
+	w.setBusy(task.ID)
+	defer w.setIdle()
+
+	ctx, span := tracing.Tracer().Start(tracing.Extract(w.ctx, task.TraceParent), "worker.executeTask",
+		trace.WithAttributes(
+			attribute.Int64("task.id", int64(task.ID)),
+			attribute.String("task.type", task.Type),
+			attribute.Int64("model.id", int64(task.ModelID)),
+			attribute.String("worker.id", w.id),
+		))
+	outcome := "failed"
 	defer func() {
-		w.status = "idle"
-		w.currentTask = nil
+		span.SetAttributes(attribute.String("task.outcome", outcome))
+		tracing.End(span, err)
 	}()
 
-	w.logger.WithFields(logrus.Fields{
-		"worker_id": w.id,
-		"task_id":   task.ID,
-		"task_type": task.Type,
-	}).Info("Executing task")
+	// 获取模型信息
+	model, err := w.modelService.GetModel(task.ModelID)
+	if err != nil {
+		w.failUnexecuted(task, "Failed to get model information")
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+	w.setModelName(model.Name)
+	span.SetAttributes(attribute.String("model.name", model.Name))
+
+	// 模型熔断期间不调用上游，任务在冷却结束后重新领取
+	breaker := w.breakers.get(model.ID)
+	if allowed, delay := breaker.allow(); !allowed {
+		outcome = "deferred"
+		w.deferTask(task, delay, "Model circuit breaker open, task deferred")
+		return nil
+	}
+
+	// 模型配置了 requests_per_minute 时先获取令牌，超限的任务在开始执行前放回队列
+	if allowed, delay := w.acquireRateLimit(model); !allowed {
+		outcome = "deferred"
+		breaker.release()
+		w.deferTask(task, delay, "Model rate limit reached, task deferred")
+		return nil
+	}
+
+	w.taskLogger(task).WithField("task_type", task.Type).Info("Executing task")
+
+	// 执行具体任务，超时后取消上游调用并释放 Worker；在标记开始执行前登记取消函数，
+	// 状态切换到 running 之后到达的取消消息也能中断执行
+	timeout := w.getTaskTimeout(model)
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	w.registry.register(task.ID, cancel)
 
 	// 标记任务开始执行
 	if err := w.taskService.StartTask(task.ID); err != nil {
-		w.logger.WithError(err).Error("Failed to mark task as started")
+		w.registry.unregister(task.ID)
+		breaker.release()
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			outcome = "skipped"
+			w.skipUnstartable(task)
+			return nil
+		}
+		w.taskLogger(task).WithError(err).Error("Failed to mark task as started")
+		w.deferTask(task, w.config.Queue.RetryDelay, "Task deferred after failing to mark it as started")
 		return err
 	}
 
-	// 获取模型信息
-	model, err := w.modelService.GetModel(task.ModelID)
-	if err != nil {
-		w.taskService.FailTask(task.ID, "Failed to get model information")
-		return fmt.Errorf("failed to get model: %w", err)
+	output, reported, err := w.executeTaskByType(execCtx, task, model)
+	cancelled := w.registry.unregister(task.ID)
+
+	// 任务已被用户取消，状态由 CancelTask 维护，不再覆盖
+	if cancelled {
+		outcome = "cancelled"
+		breaker.release()
+		w.finishCancelled(task)
+		return nil
 	}
 
-	// 执行具体任务
-	output, err := w.executeTaskByType(task, model)
 	if err != nil {
-		// 任务失败
-		_ = w.taskService.FailTask(task.ID, err.Error())
-		_ = w.modelService.IncrementRequestCount(model.ID, false)
+		// Worker 被强制停止，任务未执行完，放回队列而不是标记失败
+		if errors.Is(err, context.Canceled) && w.ctx.Err() != nil {
+			outcome = "requeued"
+			breaker.release()
+			w.requeueInterrupted(task)
+			return nil
+		}
 
-		// 从处理队列中移除任务
-		_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("execution timeout after %s", timeout)
+		}
 
+		w.recordCircuit(breaker, model, err)
+		w.finishFailed(task, model, err)
 		return fmt.Errorf("task execution failed: %w", err)
 	}
 
-	// 任务成功完成
-	if err := w.taskService.CompleteTask(task.ID, output); err != nil {
-		w.logger.WithError(err).Error("Failed to mark task as completed")
+	outcome = "completed"
+	w.recordCircuit(breaker, model, nil)
+	w.finishCompleted(task, model, output, reported)
+	return nil
+}
+
+// dropExpired 出队时任务已超过截止时间则标记为取消并从处理队列移除，不再执行
+func (w *Worker) dropExpired(task *models.Task) bool {
+	if !task.DeadlineExceeded(time.Now()) {
+		return false
+	}
+	if err := w.taskService.ExpireTask(w.ctx, task.ID); err != nil {
+		w.taskLogger(task).WithError(err).Error("Failed to expire task")
+	}
+	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	w.taskLogger(task).WithField("deadline", task.Deadline).Info("Skipping task past its deadline")
+	return true
+}
+
+// discardOrphan 丢弃数据库中已不存在的任务（如被手动删除）对应的队列项，只从处理队列移除，不再重试
+func (w *Worker) discardOrphan(item *queue.QueueItem) {
+	w.logger.WithFields(logrus.Fields{
+		"worker_id": w.id,
+		"task_id":   item.TaskID,
+		"model_id":  item.ModelID,
+		"trace_id":  item.TraceID,
+	}).Warn("Discarding queue item for missing task")
+	if err := w.queueManager.CompleteTask(w.ctx, item.TaskID); err != nil {
+		w.logger.WithError(err).WithField("task_id", item.TaskID).Error("Failed to remove orphan queue item")
+	}
+}
+
+// releaseItem 读取任务失败时将已领取的任务放回延迟队列，释放处理中队列项和并发名额，稍后重新领取
+func (w *Worker) releaseItem(item *queue.QueueItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+	if err := w.queueManager.DeferTask(ctx, item.TaskID, w.config.Queue.RetryDelay); err != nil {
+		w.logger.WithError(err).WithField("task_id", item.TaskID).Error("Failed to release task claim")
+	}
+}
+
+// failUnexecuted 将未能开始执行的任务标记为失败，并释放处理中队列项和并发名额。
+// 标记失败本身出错（如数据库不可用）时任务仍为 pending，放回延迟队列稍后重新领取
+func (w *Worker) failUnexecuted(task *models.Task, message string) {
+	err := w.taskService.FailTask(task.ID, message)
+	if err != nil && !errors.Is(err, services.ErrInvalidStatusTransition) {
+		w.taskLogger(task).WithError(err).Error("Failed to mark task as failed")
+		w.deferTask(task, w.config.Queue.RetryDelay, "Task deferred after failing to mark it as failed")
+		return
+	}
+
+	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	if err == nil {
+		w.publishDone(task.ID, models.TaskStatusFailed, message)
+		w.queueManager.OnTaskCompleted(w.ctx, task.ID, models.TaskStatusFailed)
+	}
+}
+
+// skipUnstartable 任务在出队后已被取消或由其他操作改变了状态，不再执行，只从处理队列移除
+func (w *Worker) skipUnstartable(task *models.Task) {
+	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	w.taskLogger(task).Info("Skipping task no longer pending")
+}
+
+// finishCancelled 收尾已被用户取消的任务，只从处理队列移除并通知订阅者
+func (w *Worker) finishCancelled(task *models.Task) {
+	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	w.publishDone(task.ID, models.TaskStatusCancelled, "")
+
+	w.taskLogger(task).Info("Task execution cancelled")
+}
+
+// finishFailed 将任务标记为失败并从处理队列移除
+func (w *Worker) finishFailed(task *models.Task, model *models.Model, err error) {
+	if failErr := w.taskService.FailTask(task.ID, err.Error()); errors.Is(failErr, services.ErrInvalidStatusTransition) {
+		// 执行期间已被取消，取消操作已完成收尾，不再记为失败
+		w.finishCancelled(task)
+		return
+	}
+	_ = w.modelService.IncrementRequestCount(model.ID, false)
+	w.publishDone(task.ID, models.TaskStatusFailed, err.Error())
+
+	// 从处理队列中移除任务
+	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	w.queueManager.OnTaskCompleted(w.ctx, task.ID, models.TaskStatusFailed)
+}
+
+// finishCompleted 保存任务结果和用量并从处理队列移除
+func (w *Worker) finishCompleted(task *models.Task, model *models.Model, output string, reported *models.TokenUsage) {
+	usage := resolveUsage(task, model, output, reported)
+	if err := w.taskService.CompleteTask(task.ID, output, outputFormat(task.Type), usage); err != nil {
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			// 结果返回前任务已被取消，丢弃结果，不触发依赖任务
+			w.finishCancelled(task)
+			return
+		}
+		w.taskLogger(task).WithError(err).Error("Failed to mark task as completed")
 	}
 
 	_ = w.modelService.IncrementRequestCount(model.ID, true)
+	if err := w.modelService.RecordUsage(model.ID, usage); err != nil {
+		w.taskLogger(task).WithError(err).WithField("model_id", model.ID).Warn("Failed to record model usage")
+	}
+	w.publishDone(task.ID, models.TaskStatusCompleted, "")
 
 	// 从处理队列中移除任务
 	_ = w.queueManager.CompleteTask(w.ctx, task.ID)
+	w.queueManager.OnTaskCompleted(w.ctx, task.ID, models.TaskStatusCompleted)
 
-	w.logger.WithFields(logrus.Fields{
-		"worker_id": w.id,
-		"task_id":   task.ID,
-		"task_type": task.Type,
-	}).Info("Task completed successfully")
+	w.taskLogger(task).WithField("task_type", task.Type).Info("Task completed successfully")
+}
 
-	return nil
+// getTaskTimeout 获取任务执行超时时间，模型配置 task_timeout 优先于全局配置
+func (w *Worker) getTaskTimeout(model *models.Model) time.Duration {
+	if timeout, ok := model.GetTaskTimeout(); ok {
+		return timeout
+	}
+	if w.config.Queue.TaskTimeout > 0 {
+		return w.config.Queue.TaskTimeout
+	}
+	return 300 * time.Second
 }
 
-func (w *Worker) executeTaskByType(task *models.Task, model *models.Model) (string, error) {
+// executeTaskByType 按任务类型执行，上游返回了 token 用量时一并返回，否则用量为 nil
+func (w *Worker) executeTaskByType(ctx context.Context, task *models.Task, model *models.Model) (string, *models.TokenUsage, error) {
+	var output string
+	var err error
 	switch task.Type {
-	case "text-generation":
-		return w.executeTextGeneration(task, model)
-	case "translation":
-		return w.executeTranslation(task, model)
-	case "summarization":
-		return w.executeSummarization(task, model)
-	case "embedding":
-		return w.executeEmbedding(task, model)
+	case models.TaskTypeTextGeneration:
+		return w.executeTextGeneration(ctx, task, model)
+	case models.TaskTypePipeline:
+		return w.executePipeline(ctx, task, model)
+	case models.TaskTypeTranslation:
+		output, err = w.executeTranslation(ctx, task, model)
+	case models.TaskTypeSummarization:
+		output, err = w.executeSummarization(ctx, task, model)
+	case models.TaskTypeEmbedding:
+		output, err = w.executeEmbedding(ctx, task, model)
 	default:
-		return w.executeCustomTask(task, model)
+		output, err = w.executeCustomTask(ctx, task, model)
+	}
+	return output, nil, err
+}
+
+// outputFormat 按任务类型确定输出格式，嵌入任务输出为浮点数组的 JSON
+func outputFormat(taskType string) models.OutputFormat {
+	if taskType == models.TaskTypeEmbedding {
+		return models.OutputFormatJSON
 	}
+	return models.OutputFormatText
 }
 
-func (w *Worker) executeTextGeneration(task *models.Task, model *models.Model) (string, error) {
+// resolveUsage 确定任务的 token 用量并按模型价格计算费用，上游未返回用量时根据输入输出估算
+func resolveUsage(task *models.Task, model *models.Model, output string, reported *models.TokenUsage) models.TokenUsage {
+	usage := estimateUsage(task.Input, output)
+	if reported != nil {
+		usage = *reported
+	}
+	usage.CostUSD = model.EstimateCost(usage.PromptTokens, usage.CompletionTokens)
+	return usage
+}
+
+func (w *Worker) executeTextGeneration(ctx context.Context, task *models.Task, model *models.Model) (string, *models.TokenUsage, error) {
+	// 生成过程中的增量输出推送到任务输出流
+	onChunk := func(chunk string) {
+		event := &models.TaskStreamEvent{
+			TaskID: task.ID,
+			Event:  models.TaskStreamEventChunk,
+			Chunk:  chunk,
+		}
+		if err := w.queueManager.PublishTaskEvent(w.ctx, event); err != nil {
+			w.taskLogger(task).WithError(err).Warn("Failed to publish output chunk")
+		}
+	}
+
 	switch model.Type {
 	case models.ModelTypeOpenAI:
-		return w.callOpenAIAPI(task, model)
+		return w.callOpenAIAPI(ctx, task, model, onChunk)
 	case models.ModelTypeLocal:
-		return w.callLocalAPI(task, model)
+		return w.callLocalAPI(ctx, task, model, onChunk)
 	default:
-		return "", fmt.Errorf("unsupported model type: %s", model.Type)
+		return "", nil, fmt.Errorf("unsupported model type: %s", model.Type)
 	}
 }
 
-func (w *Worker) executeTranslation(task *models.Task, model *models.Model) (string, error) {
-	time.Sleep(1 * time.Second)
+func (w *Worker) executeTranslation(ctx context.Context, task *models.Task, model *models.Model) (string, error) {
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return "", err
+	}
 	// 模拟翻译结果
-	return fmt.Sprintf("translation result: %s", task.Input), nil
+	targetLanguage, _ := task.Params.GetString("target_language")
+	return fmt.Sprintf("translation result (%s): %s", targetLanguage, task.Input), nil
 }
 
-func (w *Worker) executeSummarization(task *models.Task, model *models.Model) (string, error) {
-	time.Sleep(1 * time.Second)
+func (w *Worker) executeSummarization(ctx context.Context, task *models.Task, model *models.Model) (string, error) {
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return "", err
+	}
 	// 模拟摘要结果
-	return fmt.Sprintf("summarization result: %s", task.Input[:min(50, len(task.Input))]), nil
+	return fmt.Sprintf("summarization result: %s", utils.TruncateRunes(task.Input, 50)), nil
 }
 
-func (w *Worker) executeEmbedding(task *models.Task, model *models.Model) (string, error) {
-	time.Sleep(1 * time.Second)
+func (w *Worker) executeEmbedding(ctx context.Context, task *models.Task, model *models.Model) (string, error) {
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return "", err
+	}
 	// 模拟向量化结果
-	return "[0.1, 0.2, 0.3, ...]", nil
+	return "[0.1, 0.2, 0.3]", nil
 }
 
-func (w *Worker) executeCustomTask(task *models.Task, model *models.Model) (string, error) {
-	time.Sleep(1 * time.Second)
+func (w *Worker) executeCustomTask(ctx context.Context, task *models.Task, model *models.Model) (string, error) {
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("custom task done: %s", task.Input), nil
 }
 
-func (w *Worker) callOpenAIAPI(task *models.Task, model *models.Model) (string, error) {
-	// 这里应该实现实际的 OpenAI API 调用
-	time.Sleep(3 * time.Second)
+// callOpenAIAPI 以流式方式调用 OpenAI 或兼容接口（Azure OpenAI、Groq、本地网关等），增量输出通过 onChunk 推送，
+// 地址、认证头和部署名在模型配置中设置
+func (w *Worker) callOpenAIAPI(ctx context.Context, task *models.Task, model *models.Model, onChunk func(string)) (string, *models.TokenUsage, error) {
+	endpoint, err := w.resolveEndpoint(task, model)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := buildOpenAIRequestConfig(endpoint, model)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg.TraceID = task.TraceID
+	w.observeModelCalls(&cfg, task)
 
-	apiKey, exists := model.GetConfigValue("api_key")
-	if !exists || apiKey == "" {
-		return "", fmt.Errorf("OpenAI API key not configured")
+	prompt, err := renderPrompt(task, model)
+	if err != nil {
+		return "", nil, err
 	}
 
-	// 模拟 API 调用结果
-	return fmt.Sprintf("OpenAI 响应: 根据输入 '%s' 生成的内容", task.Input), nil
+	w.logEndpoint(task, endpoint)
+
+	return w.doOpenAIRequest(ctx, cfg, model, prompt, onChunk)
 }
 
-func (w *Worker) callLocalAPI(task *models.Task, model *models.Model) (string, error) {
-	// 这里应该实现实际的本地模型 API 调用
-	time.Sleep(5 * time.Second)
+// callLocalAPI 以流式方式调用本地部署的模型服务，增量输出通过 onChunk 推送，
+// 请求地址、字段名在模型配置中设置，默认兼容 Ollama
+func (w *Worker) callLocalAPI(ctx context.Context, task *models.Task, model *models.Model, onChunk func(string)) (string, *models.TokenUsage, error) {
+	endpoint, err := w.resolveEndpoint(task, model)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prompt, err := renderPrompt(task, model)
+	if err != nil {
+		return "", nil, err
+	}
+
+	w.logEndpoint(task, endpoint)
 
-	host, _ := model.GetConfigValue("host")
-	port, _ := model.GetConfigValue("port")
+	cfg := buildLocalRequestConfig(endpoint, model)
+	cfg.TraceID = task.TraceID
+	w.observeModelCalls(&cfg, task)
+	return w.doLocalRequest(ctx, cfg, prompt, onChunk)
+}
 
-	if host == nil || port == nil {
-		return "", fmt.Errorf("local model host/port not configured")
+// providerEndpoint 模型服务调用地址
+type providerEndpoint struct {
+	BaseURL    string
+	Headers    map[string]string
+	Overridden bool
+}
+
+// resolveEndpoint 获取任务调用的模型服务地址，任务设置了 provider_override 时优先使用，
+// 否则使用模型配置；模型未配置地址或配置类型错误时返回错误
+func (w *Worker) resolveEndpoint(task *models.Task, model *models.Model) (providerEndpoint, error) {
+	if task.ProviderOverride != nil && task.ProviderOverride.BaseURL != "" {
+		return providerEndpoint{
+			BaseURL:    task.ProviderOverride.BaseURL,
+			Headers:    task.ProviderOverride.Headers,
+			Overridden: true,
+		}, nil
 	}
 
-	// 模拟本地 API 调用结果
-	return fmt.Sprintf("本地模型响应: 基于输入 '%s' 的处理结果", task.Input), nil
+	return modelEndpoint(w.config, model)
+}
+
+// modelEndpoint 获取模型配置的服务地址：本地模型为 host:port，其他类型为 base_url，未配置时使用 OpenAI 默认地址。
+// 配置值按类型读取，JSON 中的数字端口不会被格式化为 8080.000000 之类的字符串
+func modelEndpoint(cfg *config.Config, model *models.Model) (providerEndpoint, error) {
+	switch model.Type {
+	case models.ModelTypeLocal:
+		host, err := model.GetConfigString("host")
+		if errors.Is(err, models.ErrConfigMissing) || (err == nil && host == "") {
+			return providerEndpoint{}, fmt.Errorf("local model host/port not configured")
+		}
+		if err != nil {
+			return providerEndpoint{}, fmt.Errorf("invalid local model config: %w", err)
+		}
+		port, err := model.GetConfigInt("port")
+		if errors.Is(err, models.ErrConfigMissing) {
+			return providerEndpoint{}, fmt.Errorf("local model host/port not configured")
+		}
+		if err != nil {
+			return providerEndpoint{}, fmt.Errorf("invalid local model config: %w", err)
+		}
+		if port <= 0 || port > 65535 {
+			return providerEndpoint{}, fmt.Errorf("invalid local model config: port %d out of range", port)
+		}
+		return providerEndpoint{BaseURL: "http://" + net.JoinHostPort(host, strconv.Itoa(port))}, nil
+	default:
+		baseURL, err := model.GetConfigString("base_url")
+		if err != nil && !errors.Is(err, models.ErrConfigMissing) {
+			return providerEndpoint{}, fmt.Errorf("invalid model config: %w", err)
+		}
+		if baseURL != "" {
+			return providerEndpoint{BaseURL: baseURL}, nil
+		}
+		return providerEndpoint{BaseURL: cfg.Models.OpenAI.BaseURL}, nil
+	}
+}
+
+// logEndpoint 记录任务实际调用的服务地址，不输出请求头
+func (w *Worker) logEndpoint(task *models.Task, endpoint providerEndpoint) {
+	w.taskLogger(task).WithFields(logrus.Fields{
+		"base_url":   endpoint.BaseURL,
+		"overridden": endpoint.Overridden,
+	}).Debug("Calling model provider")
+}
+
+// sleepContext 等待指定时长，ctx 取消或超时时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// requeueInterrupted 强制停止时将执行中的任务放回队列，避免任务停留在 running 状态
+func (w *Worker) requeueInterrupted(task *models.Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	logger := w.taskLogger(task)
+
+	if err := w.taskService.ResetTask(task.ID, "Task interrupted by worker shutdown, requeued"); err != nil {
+		// 任务已被取消或已结束，不再放回队列
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			_ = w.queueManager.CompleteTask(ctx, task.ID)
+			logger.WithError(err).Info("Interrupted task no longer running, not requeued")
+			return
+		}
+		logger.WithError(err).Error("Failed to reset interrupted task")
+		return
+	}
+
+	_ = w.queueManager.CompleteTask(ctx, task.ID)
+
+	item := &queue.QueueItem{
+		TaskID:      task.ID,
+		ModelID:     task.ModelID,
+		Priority:    int(task.Priority),
+		CreatedAt:   task.CreatedAt,
+		TraceID:     task.TraceID,
+		TraceParent: task.TraceParent,
+		Deadline:    task.Deadline,
+		APIKey:      task.APIKeyName,
+		Type:        task.Type,
+	}
+	if err := w.queueManager.RequeueTask(ctx, item, 0); err != nil {
+		logger.WithError(err).Error("Failed to requeue interrupted task")
+		return
+	}
+
+	logger.Warn("Task interrupted by shutdown and requeued")
+}
+
+// taskLogger 获取带有 Worker、任务和请求关联 ID 的日志记录器，任务相关的日志都应使用它
+func (w *Worker) taskLogger(task *models.Task) *logrus.Entry {
+	return w.logger.WithFields(logrus.Fields{
+		"worker_id": w.id,
+		"task_id":   task.ID,
+		"trace_id":  task.TraceID,
+	})
+}
+
+// publishDone 发布任务结束事件，通知输出流订阅者
+func (w *Worker) publishDone(taskID uint64, status models.TaskStatus, errorMsg string) {
+	event := &models.TaskStreamEvent{
+		TaskID:       taskID,
+		Event:        models.TaskStreamEventDone,
+		Status:       status,
+		ErrorMessage: errorMsg,
+	}
+	if err := w.queueManager.PublishTaskEvent(w.ctx, event); err != nil {
+		w.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to publish task done event")
+	}
 }
 
 func (w *Worker) heartbeat() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(heartbeatInterval(w.config))
 	defer ticker.Stop()
 
+	w.touchHeartbeat()
+	w.reportHeartbeat()
+
 	for {
 		select {
 		case <-w.ctx.Done():
+			// Worker 退出后从名册中移除，ctx 已取消，使用独立的超时上下文
+			ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+			if err := w.queueManager.RemoveWorker(ctx, w.id); err != nil {
+				w.logger.WithError(err).WithField("worker_id", w.id).Warn("Failed to remove worker from roster")
+			}
+			cancel()
 			return
 		case <-ticker.C:
-			w.lastHeartbeat = time.Now()
+			w.touchHeartbeat()
+			w.reportHeartbeat()
 			w.logger.WithField("worker_id", w.id).Debug("Worker heartbeat")
 		}
 	}
 }
 
+// touchHeartbeat 记录最近一次心跳时间
+func (w *Worker) touchHeartbeat() {
+	atomic.StoreInt64(&w.lastHeartbeat, time.Now().UnixNano())
+}
+
+// LastHeartbeat 获取最近一次心跳时间，尚未心跳时返回启动时间
+func (w *Worker) LastHeartbeat() time.Time {
+	if ns := atomic.LoadInt64(&w.lastHeartbeat); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return w.startTime
+}
+
+// heartbeatInterval 获取 Worker 心跳间隔
+func heartbeatInterval(cfg *config.Config) time.Duration {
+	if cfg.Worker.HeartbeatInterval > 0 {
+		return cfg.Worker.HeartbeatInterval
+	}
+	return 30 * time.Second
+}
+
+// idlePollInterval 获取队列为空时的轮询间隔
+func idlePollInterval(cfg *config.Config) time.Duration {
+	if cfg.Worker.IdlePollInterval > 0 {
+		return cfg.Worker.IdlePollInterval
+	}
+	return time.Second
+}
+
+// errorBackoff 获取出错后的暂停时间
+func errorBackoff(cfg *config.Config) time.Duration {
+	if cfg.Worker.ErrorBackoff > 0 {
+		return cfg.Worker.ErrorBackoff
+	}
+	return 5 * time.Second
+}
+
+// reportHeartbeat 将 Worker 状态写入共享名册
+func (w *Worker) reportHeartbeat() {
+	if err := w.queueManager.ReportWorker(w.ctx, w.GetStatus()); err != nil {
+		w.logger.WithError(err).WithField("worker_id", w.id).Warn("Failed to report worker heartbeat")
+	}
+}
+
+// GetStatus 获取 Worker 状态的一致快照，可以在任意协程中调用
 func (w *Worker) GetStatus() models.WorkerStatus {
+	w.mu.Lock()
+	status := w.status
+	modelName := w.modelName
+	taskTypes := w.taskTypes
+	var currentTask *uint64
+	if w.currentTask != nil {
+		id := *w.currentTask
+		currentTask = &id
+	}
+	w.mu.Unlock()
+
+	if w.IsDraining() {
+		status = "draining"
+	}
+
 	return models.WorkerStatus{
 		WorkerID:      w.id,
+		InstanceID:    w.queueManager.InstanceID(),
 		ModelID:       w.modelID,
-		Status:        w.status,
-		CurrentTaskID: w.currentTask,
+		ModelName:     modelName,
+		Status:        status,
+		CurrentTaskID: currentTask,
+		TaskTypes:     taskTypes,
 		StartTime:     w.startTime,
-		LastHeartbeat: w.lastHeartbeat,
-	}
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
+		LastHeartbeat: w.LastHeartbeat(),
 	}
-	return b
 }
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"llm-scheduler/models"
+)
+
+// localWorkerIDs 返回管理器当前 Worker 的 ID，按字典序排列
+func localWorkerIDs(m *Manager) []string {
+	var ids []string
+	for _, status := range m.GetLocalWorkerStatus() {
+		ids = append(ids, status.WorkerID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// startWorkers 为模型启动 n 个 Worker
+func startWorkers(t *testing.T, m *Manager, model *models.Model, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := m.startWorker(model); err != nil {
+			t.Fatalf("start worker: %v", err)
+		}
+	}
+}
+
+func TestStartWorkerAssignsSlotIDs(t *testing.T) {
+	env := newTaskTestEnv(t)
+	other := &models.Model{Name: "other-model", Type: models.ModelTypeCustom, Status: models.ModelStatusOnline, MaxWorkers: 1}
+	if err := env.db.Create(other).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	m := newPoolTestManager(t, env)
+
+	// ID 由模型 ID 和池内槽位组成，各模型的槽位分别从 0 开始
+	startWorkers(t, m, env.model, 3)
+	startWorkers(t, m, other, 1)
+	want := []string{workerIDFor(env.model.ID, 0), workerIDFor(env.model.ID, 1), workerIDFor(env.model.ID, 2), workerIDFor(other.ID, 0)}
+	sort.Strings(want)
+	if got := localWorkerIDs(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("worker IDs = %v, want %v", got, want)
+	}
+
+	// 崩溃的 Worker 由沿用同一 ID 的新 Worker 替换，而不是分配新的槽位
+	crashed := workerIDFor(env.model.ID, 1)
+	killWorker(t, m, crashed)
+	startWorkers(t, m, env.model, 1)
+	if got := localWorkerIDs(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("worker IDs after replacing %s = %v, want %v", crashed, got, want)
+	}
+}
+
+func TestWorkerIDsStableAcrossRestart(t *testing.T) {
+	env := newTaskTestEnv(t)
+
+	// 重启后的管理器为同一模型生成与之前相同的 ID，便于跨重启关联日志和监控
+	var runs [][]string
+	for i := 0; i < 2; i++ {
+		m := newPoolTestManager(t, env)
+		startWorkers(t, m, env.model, 2)
+		runs = append(runs, localWorkerIDs(m))
+		m.stopAllWorkers()
+	}
+	if fmt.Sprint(runs[0]) != fmt.Sprint(runs[1]) {
+		t.Fatalf("worker IDs changed across restart: %v then %v", runs[0], runs[1])
+	}
+	if want := []string{workerIDFor(env.model.ID, 0), workerIDFor(env.model.ID, 1)}; fmt.Sprint(runs[0]) != fmt.Sprint(want) {
+		t.Fatalf("worker IDs = %v, want %v", runs[0], want)
+	}
+}
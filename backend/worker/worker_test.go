@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"llm-scheduler/config"
+	"llm-scheduler/queue"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newStatusTestWorker 创建不连接 Redis 和数据库的 Worker，只用于读写状态
+func newStatusTestWorker() *Worker {
+	cfg := &config.Config{}
+	cfg.Worker.InstanceID = "test-instance"
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	queueManager := queue.NewManager(&queue.Clients{}, cfg, logger)
+	return NewWorker("worker-1-0", 1, cfg, queueManager, nil, nil, logger)
+}
+
+// 使用 go test -race 运行时检查状态读写没有数据竞争
+func TestGetStatusConcurrentWithUpdates(t *testing.T) {
+	w := newStatusTestWorker()
+
+	const iterations = 1000
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			w.setBusy(uint64(i + 1))
+			w.setModelName("gpt-4")
+			w.setTaskTypes([]string{"embedding"})
+			w.touchHeartbeat()
+			w.setIdle()
+			w.setTaskTypes(nil)
+		}
+		w.Drain()
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				status := w.GetStatus()
+				// 状态与当前任务来自同一个快照，不会出现 busy 而没有任务或 idle 却带着任务
+				switch status.Status {
+				case "busy":
+					if status.CurrentTaskID == nil {
+						t.Errorf("busy worker without current task")
+						return
+					}
+				case "idle":
+					if status.CurrentTaskID != nil {
+						t.Errorf("idle worker with current task %d", *status.CurrentTaskID)
+						return
+					}
+				}
+				_ = w.IsBusy()
+				_ = w.LastHeartbeat()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	status := w.GetStatus()
+	if status.Status != "draining" {
+		t.Errorf("status = %q, want draining", status.Status)
+	}
+	if status.ModelName != "gpt-4" {
+		t.Errorf("model name = %q, want gpt-4", status.ModelName)
+	}
+	if status.InstanceID != "test-instance" {
+		t.Errorf("instance id = %q, want test-instance", status.InstanceID)
+	}
+}
+
+func TestGetStatusReturnsCopyOfCurrentTask(t *testing.T) {
+	w := newStatusTestWorker()
+	w.setBusy(42)
+
+	status := w.GetStatus()
+	if status.CurrentTaskID == nil || *status.CurrentTaskID != 42 {
+		t.Fatalf("current task = %v, want 42", status.CurrentTaskID)
+	}
+
+	// 快照不随 Worker 后续状态变化
+	w.setBusy(43)
+	if *status.CurrentTaskID != 42 {
+		t.Errorf("snapshot current task changed to %d", *status.CurrentTaskID)
+	}
+}
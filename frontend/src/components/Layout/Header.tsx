@@ -36,6 +36,7 @@ const Header: React.FC = () => {
   // 获取健康状态颜色
   const getHealthStatusColor = () => {
     if (!healthStatus) return 'default';
+    if (healthStatus.status === 'degraded') return 'warning';
     return healthStatus.status === 'ok' ? 'success' : 'error';
   };
 
@@ -43,6 +44,7 @@ const Header: React.FC = () => {
   const getHealthStatusText = () => {
     if (loading) return '检查中...';
     if (!healthStatus) return '未知';
+    if (healthStatus.status === 'degraded') return '降级';
     return healthStatus.status === 'ok' ? '正常' : '异常';
   };
 
@@ -60,6 +62,9 @@ const Header: React.FC = () => {
     if (healthStatus.queue !== 'ok') {
       details.push(`队列: ${healthStatus.queue_error || '异常'}`);
     }
+    if (healthStatus.workers === 'degraded') {
+      details.push(`Worker: ${healthStatus.worker_problems?.length || 0} 个停滞`);
+    }
     
     if (details.length === 0) {
       return '所有服务运行正常';
@@ -273,10 +273,10 @@ const TaskDetail: React.FC = () => {
       </Card>
 
       {/* 输出结果 */}
-      {task.output && (
+      {task.output != null && (
         <Card title="输出结果" style={{ marginBottom: 16 }}>
           <Text code style={{ fontSize: '12px', lineHeight: 1.6, whiteSpace: 'pre-wrap' }}>
-            {task.output}
+            {typeof task.output === 'string' ? task.output : JSON.stringify(task.output, null, 2)}
           </Text>
         </Card>
       )}
@@ -207,6 +207,7 @@ const TaskList: React.FC = () => {
         { text: '翻译', value: 'translation' },
         { text: '摘要', value: 'summarization' },
         { text: '向量化', value: 'embedding' },
+        { text: '流水线', value: 'pipeline' },
       ],
     },
     {
@@ -452,9 +453,24 @@ const TaskList: React.FC = () => {
               <Option value="translation">翻译</Option>
               <Option value="summarization">摘要</Option>
               <Option value="embedding">向量化</Option>
+              <Option value="pipeline">流水线</Option>
               <Option value="custom">自定义</Option>
             </Select>
           </Form.Item>
+
+          <Form.Item noStyle shouldUpdate={(prev, cur) => prev.type !== cur.type}>
+            {({ getFieldValue }) =>
+              getFieldValue('type') === 'translation' ? (
+                <Form.Item
+                  label="目标语言"
+                  name={['params', 'target_language']}
+                  rules={[{ required: true, message: '请输入目标语言' }]}
+                >
+                  <Input placeholder="例如: zh、en、ja" />
+                </Form.Item>
+              ) : null
+            }
+          </Form.Item>
           
           <Form.Item
             label="模型"
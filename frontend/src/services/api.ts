@@ -3,15 +3,37 @@ import { message } from 'antd';
 import {
   ApiResponse,
   PagedResponse,
+  CursorResponse,
+  TaskCursorParams,
   Task,
   TaskCreateRequest,
+  BatchResult,
+  BulkTaskResult,
+  RecoverResult,
+  ArchiveResult,
   TaskUpdateRequest,
   TaskListParams,
+  TaskFilterParams,
+  TaskLog,
+  TaskLogListParams,
   TaskStats,
+  TaskTimeline,
+  TaskExport,
   Model,
+  ModelConfig,
+  ModelTestResult,
   ModelStats,
+  WorkerPoolStatus,
+  ScheduledTask,
+  ScheduledTaskRequest,
   DashboardStats,
+  CostSummary,
+  LatencyPercentiles,
+  QueueMetric,
   HealthStatus,
+  QueueStatus,
+  QueueItem,
+  QueuePurgeResult,
   SystemInfo,
 } from '../types';
 
@@ -27,11 +49,14 @@ const api = axios.create({
 // 请求拦截器
 api.interceptors.request.use(
   (config) => {
-    // 可以在这里添加认证 token
-    // const token = localStorage.getItem('token');
-    // if (token) {
-    //   config.headers.Authorization = `Bearer ${token}`;
-    // }
+    // 后端启用 auth 时需要携带 API Key 或 JWT
+    const token = process.env.REACT_APP_API_TOKEN;
+    const apiKey = process.env.REACT_APP_API_KEY;
+    if (token) {
+      config.headers['Authorization'] = `Bearer ${token}`;
+    } else if (apiKey) {
+      config.headers['X-API-Key'] = apiKey;
+    }
     return config;
   },
   (error) => {
@@ -52,15 +77,18 @@ api.interceptors.response.use(
           message.error(data.message || '请求参数错误');
           break;
         case 401:
-          message.error('未授权访问');
+          message.error(data.message || '未授权访问');
           // 可以跳转到登录页
           break;
         case 403:
-          message.error('禁止访问');
+          message.error(data.message || '禁止访问');
           break;
         case 404:
           message.error('请求的资源不存在');
           break;
+        case 429:
+          message.error('请求过于频繁，请稍后重试');
+          break;
         case 500:
           message.error('服务器内部错误');
           break;
@@ -85,21 +113,71 @@ export const systemApi = {
   // 系统信息
   info: (): Promise<ApiResponse<SystemInfo>> =>
     api.get('/system/info').then((res) => res.data),
+
+  // 恢复崩溃后遗留的 running 任务，olderThan 如 '10m'，默认使用 queue.task_timeout
+  recover: (olderThan?: string): Promise<ApiResponse<RecoverResult>> =>
+    api.post('/system/recover', null, { params: { older_than: olderThan } }).then((res) => res.data),
+};
+
+// 队列 API
+export const queueApi = {
+  // 队列状态
+  status: (): Promise<ApiResponse<QueueStatus>> =>
+    api.get('/queue/status').then((res) => res.data),
+
+  // 查看即将执行的任务
+  peek: (priority: 'high' | 'medium' | 'low', limit = 10): Promise<ApiResponse<QueueItem[]>> =>
+    api.get('/queue/peek', { params: { priority, limit } }).then((res) => res.data),
+
+  // 清空指定优先级队列，队列中的任务会被取消
+  purge: (priority: 'high' | 'medium' | 'low', reason?: string): Promise<ApiResponse<QueuePurgeResult>> =>
+    api.delete(`/queue/${priority}`, { params: { confirm: true, reason } }).then((res) => res.data),
+};
+
+// 标签过滤条件展开为 tag.<key>=<value> 查询参数
+const taskFilterQuery = ({ tags, ...params }: TaskFilterParams): Record<string, any> => {
+  const query: Record<string, any> = { ...params };
+  Object.entries(tags ?? {}).forEach(([key, value]) => {
+    query[`tag.${key}`] = value;
+  });
+  return query;
 };
 
 // 任务 API
 export const taskApi = {
   // 创建任务
-  create: (data: TaskCreateRequest): Promise<ApiResponse<Task>> =>
-    api.post('/tasks', data).then((res) => res.data),
+  create: (data: TaskCreateRequest, idempotencyKey?: string): Promise<ApiResponse<Task>> =>
+    api
+      .post('/tasks', data, idempotencyKey ? { headers: { 'Idempotency-Key': idempotencyKey } } : undefined)
+      .then((res) => res.data),
+
+  // 批量创建任务
+  createBatch: (tasks: TaskCreateRequest[]): Promise<ApiResponse<BatchResult[]>> =>
+    api.post('/tasks/batch', { tasks }).then((res) => res.data),
 
   // 获取任务列表
   list: (params: TaskListParams): Promise<PagedResponse<Task[]>> =>
-    api.get('/tasks', { params }).then((res) => res.data),
+    api.get('/tasks', { params: taskFilterQuery(params) }).then((res) => res.data),
+
+  // 游标分页获取任务列表，适合遍历大量任务
+  listByCursor: (params: TaskCursorParams): Promise<CursorResponse<Task[]>> =>
+    api.get('/tasks', { params: taskFilterQuery(params) }).then((res) => res.data),
+
+  // 获取任务详情，outputUri 为 true 时不读取外部存储中的输出，只返回 output_uri
+  get: (id: number, outputUri?: boolean): Promise<ApiResponse<Task>> =>
+    api.get(`/tasks/${id}`, outputUri ? { params: { output: 'uri' } } : undefined).then((res) => res.data),
+
+  // 分页获取任务日志
+  logs: (id: number, params?: TaskLogListParams): Promise<PagedResponse<TaskLog[]>> =>
+    api.get(`/tasks/${id}/logs`, { params }).then((res) => res.data),
 
-  // 获取任务详情
-  get: (id: number): Promise<ApiResponse<Task>> =>
-    api.get(`/tasks/${id}`).then((res) => res.data),
+  // 获取任务执行时间线
+  timeline: (id: number): Promise<ApiResponse<TaskTimeline>> =>
+    api.get(`/tasks/${id}/timeline`).then((res) => res.data),
+
+  // 导出任务、全部日志和模型快照
+  export: (id: number): Promise<ApiResponse<TaskExport>> =>
+    api.get(`/tasks/${id}/export`).then((res) => res.data),
 
   // 更新任务
   update: (id: number, data: TaskUpdateRequest): Promise<ApiResponse<Task>> =>
@@ -113,9 +191,21 @@ export const taskApi = {
   retry: (id: number): Promise<ApiResponse> =>
     api.post(`/tasks/${id}/retry`).then((res) => res.data),
 
+  // 按过滤条件批量取消
+  bulkCancel: (params: TaskFilterParams): Promise<ApiResponse<BulkTaskResult>> =>
+    api.post('/tasks/bulk/cancel', null, { params: taskFilterQuery(params) }).then((res) => res.data),
+
+  // 按过滤条件批量重试
+  bulkRetry: (params: TaskFilterParams): Promise<ApiResponse<BulkTaskResult>> =>
+    api.post('/tasks/bulk/retry', null, { params: taskFilterQuery(params) }).then((res) => res.data),
+
   // 获取任务统计
   stats: (): Promise<ApiResponse<TaskStats>> =>
     api.get('/tasks/stats').then((res) => res.data),
+
+  // 归档早于指定日期的已结束任务
+  cleanup: (before: string): Promise<ApiResponse<ArchiveResult>> =>
+    api.delete('/tasks/cleanup', { params: { before } }).then((res) => res.data),
 };
 
 // 模型 API
@@ -124,6 +214,10 @@ export const modelApi = {
   create: (data: Partial<Model>): Promise<ApiResponse<Model>> =>
     api.post('/models', data).then((res) => res.data),
 
+  // 按名称创建或更新模型
+  upsert: (data: Partial<Model>): Promise<ApiResponse<Model>> =>
+    api.put('/models', data).then((res) => res.data),
+
   // 获取模型列表
   list: (params?: { type?: string; status?: string }): Promise<ApiResponse<Model[]>> =>
     api.get('/models', { params }).then((res) => res.data),
@@ -144,13 +238,54 @@ export const modelApi = {
   update: (id: number, data: Partial<Model>): Promise<ApiResponse<Model>> =>
     api.put(`/models/${id}`, data).then((res) => res.data),
 
+  // 测试模型连通性
+  test: (id: number): Promise<ApiResponse<ModelTestResult>> =>
+    api.post(`/models/${id}/test`).then((res) => res.data),
+
+  // 合并更新模型配置，只修改传入的键，值为 null 的键被删除
+  mergeConfig: (id: number, patch: ModelConfig): Promise<ApiResponse<Model>> =>
+    api.patch(`/models/${id}/config`, patch).then((res) => res.data),
+
   // 删除模型
   delete: (id: number): Promise<ApiResponse> =>
     api.delete(`/models/${id}`).then((res) => res.data),
 
   // 更新模型状态
-  updateStatus: (id: number, status: string): Promise<ApiResponse> =>
-    api.put(`/models/${id}/status`, { status }).then((res) => res.data),
+  updateStatus: (id: number, status: string, cancelPending: boolean = false): Promise<ApiResponse<{ cancelled: number } | null>> =>
+    api
+      .put(`/models/${id}/status`, { status }, { params: cancelPending ? { cancel_pending: true } : undefined })
+      .then((res) => res.data),
+
+  // 获取 Worker 池状态
+  getWorkers: (id: number): Promise<ApiResponse<WorkerPoolStatus>> =>
+    api.get(`/models/${id}/workers`).then((res) => res.data),
+
+  // 扩缩容 Worker
+  scaleWorkers: (id: number, count: number): Promise<ApiResponse<WorkerPoolStatus>> =>
+    api.post(`/models/${id}/workers`, { count }).then((res) => res.data),
+};
+
+// 定时任务 API
+export const scheduleApi = {
+  // 创建定时任务
+  create: (data: ScheduledTaskRequest): Promise<ApiResponse<ScheduledTask>> =>
+    api.post('/schedules', data).then((res) => res.data),
+
+  // 获取定时任务列表
+  list: (): Promise<ApiResponse<ScheduledTask[]>> =>
+    api.get('/schedules').then((res) => res.data),
+
+  // 获取定时任务详情
+  get: (id: number): Promise<ApiResponse<ScheduledTask>> =>
+    api.get(`/schedules/${id}`).then((res) => res.data),
+
+  // 更新定时任务
+  update: (id: number, data: ScheduledTaskRequest): Promise<ApiResponse<ScheduledTask>> =>
+    api.put(`/schedules/${id}`, data).then((res) => res.data),
+
+  // 删除定时任务
+  delete: (id: number): Promise<ApiResponse> =>
+    api.delete(`/schedules/${id}`).then((res) => res.data),
 };
 
 // 统计 API
@@ -167,9 +302,27 @@ export const statsApi = {
   tasksByModel: (): Promise<ApiResponse<any[]>> =>
     api.get('/stats/tasks/model').then((res) => res.data),
 
+  // 导出按日期或按模型的任务统计 CSV
+  exportCSV: (by: 'date' | 'model', days: number = 7): Promise<Blob> =>
+    api
+      .get(`/stats/tasks/${by}`, { params: { format: 'csv', days }, responseType: 'blob' })
+      .then((res) => res.data),
+
   // 按类型获取任务统计
   tasksByType: (): Promise<ApiResponse<any[]>> =>
     api.get('/stats/tasks/type').then((res) => res.data),
+
+  // 费用统计
+  cost: (days: number = 30): Promise<ApiResponse<CostSummary>> =>
+    api.get('/stats/cost', { params: { days } }).then((res) => res.data),
+
+  // 处理耗时分位数
+  latency: (params: { hours?: number; model_id?: number; type?: string } = {}): Promise<ApiResponse<LatencyPercentiles>> =>
+    api.get('/stats/latency', { params }).then((res) => res.data),
+
+  // 队列深度历史
+  queueHistory: (hours: number = 6): Promise<ApiResponse<QueueMetric[]>> =>
+    api.get('/stats/queue/history', { params: { hours } }).then((res) => res.data),
 };
 
 export default api;
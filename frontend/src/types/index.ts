@@ -15,20 +15,50 @@ export interface PagedResponse<T = any> {
   size: number;
 }
 
+// 游标分页响应，next_cursor 为 null 表示没有下一页
+export interface CursorResponse<T = any> {
+  code: number;
+  message: string;
+  data?: T;
+  next_cursor: number | null;
+  limit: number;
+}
+
 // 任务相关类型
 export type TaskStatus = 'pending' | 'running' | 'completed' | 'failed' | 'cancelled';
 export type TaskPriority = 1 | 2 | 3; // 1-低，2-中，3-高
 
+export type OutputFormat = 'text' | 'json' | 'base64';
+
 export interface Task {
   id: number;
   model_id: number;
   type: string;
   input: string;
-  output?: string;
+  params?: Record<string, any>;
+  tags?: Record<string, string>; // 任务标签（如项目、客户）
+  output?: string | unknown; // output_format 为 json 时为解析后的 JSON 值
+  output_truncated: boolean;
+  output_uri?: string; // 输出保存在外部存储时的引用 URI
+  output_format: OutputFormat;
   status: TaskStatus;
   priority: TaskPriority;
   retry_count: number;
   max_retries: number;
+  depends_on?: number[];
+  waiting_dependencies?: boolean;
+  needs_attention?: boolean;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost_usd: number;
+  trace_id?: string;
+  api_key_name?: string; // 创建任务的 API Key 名称，未启用认证时为空
+  dedup_key?: string;
+  deduplicated?: boolean; // 创建请求因 dedup_key 相同返回了已有任务
+  cached_from_id?: number; // 结果来自缓存时复用的任务 ID
+  estimated_wait_ms?: number; // 排队中任务的预计等待时间（毫秒），尽力估算
+  deadline?: string; // 截止时间，超过后仍在排队的任务被取消
+  run_at?: string; // 计划执行时间
   error_message?: string;
   started_at?: string;
   completed_at?: string;
@@ -39,10 +69,61 @@ export interface Task {
 }
 
 export interface TaskCreateRequest {
+  model_id?: number;
+  type?: string; // 为空时使用 models.default_task_type
+  input: string;
+  params?: Record<string, any>;
+  tags?: Record<string, string>;
+  priority?: TaskPriority;
+  max_retries?: number;
+  depends_on?: number[];
+  cache?: boolean; // 复用 cache_ttl 内相同模型、类型、输入和参数的已完成任务结果
+  dedup_key?: string; // 已有相同键的进行中任务时返回该任务，不再创建
+  deadline?: string; // 截止时间（RFC 3339），与 ttl_seconds 二选一
+  ttl_seconds?: number; // 从创建起的有效秒数
+  run_at?: string; // 计划执行时间（RFC 3339），必须在未来
+}
+
+export interface BatchResult {
+  index: number;
+  task_id?: number;
+  errors?: { field: string; tag?: string; message: string }[];
+  error?: string;
+}
+
+export interface ScheduledTask {
+  id: number;
+  name: string;
+  cron: string;
   model_id: number;
   type: string;
-  input: string;
+  input_template: string;
+  params?: Record<string, any>;
+  priority: TaskPriority;
+  enabled: boolean;
+  last_run_at?: string;
+  next_run_at?: string;
+  last_task_id?: number;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+  model?: Model;
+}
+
+export interface ScheduledTaskRequest {
+  name: string;
+  cron: string;
+  model_id: number;
+  type: string;
+  input_template: string;
+  params?: Record<string, any>;
   priority?: TaskPriority;
+  enabled?: boolean;
+}
+
+export interface ArchiveResult {
+  before: string;
+  archived: number;
 }
 
 export interface TaskUpdateRequest {
@@ -55,12 +136,71 @@ export interface TaskListParams {
   status?: TaskStatus;
   type?: string;
   priority?: TaskPriority;
+  q?: string;
+  created_after?: string;
+  created_before?: string;
+  tags?: Record<string, string>; // 按标签过滤，发送为 tag.<key>=<value>
   page?: number;
   page_size?: number;
   order_by?: string;
   order?: 'asc' | 'desc';
 }
 
+// 游标分页参数，按 ID 排序，cursor 为 0 表示从头开始
+export interface TaskCursorParams extends Omit<TaskListParams, 'page' | 'page_size' | 'order_by'> {
+  cursor: number;
+  limit?: number;
+}
+
+// 过滤条件，与任务列表相同，不含分页和排序
+export type TaskFilterParams = Omit<TaskListParams, 'page' | 'page_size' | 'order_by' | 'order'>;
+
+// 任务时间线事件，stage 为 created/enqueued/started/completed/failed/cancelled，其他日志为 log
+export interface TaskTimelineEvent {
+  time: string;
+  stage: string;
+  message?: string;
+  level?: string;
+  since_prev_ms: number;
+}
+
+// 任务执行时间线，未结束的任务耗时计算到当前时间
+export interface TaskTimeline {
+  task_id: number;
+  status: TaskStatus;
+  in_progress: boolean;
+  events: TaskTimelineEvent[];
+  queue_wait_ms: number;
+  execution_ms: number | null;
+  total_ms: number;
+}
+
+// 任务导出包，敏感配置已屏蔽
+export interface TaskExport {
+  exported_at: string;
+  task: Task;
+  model?: Model;
+  logs: TaskLog[];
+  timings: {
+    queue_wait_ms: number;
+    execution_ms: number | null;
+    total_ms: number;
+  };
+  warnings?: string[];
+}
+
+// 批量取消/重试结果
+export interface BulkTaskResult {
+  matched: number;
+  affected: number;
+}
+
+export interface RecoverResult {
+  scanned: number; // running 超过时间阈值的任务数
+  recovered: number; // 重新入队的任务数
+  task_ids: number[];
+}
+
 export interface TaskStats {
   total_tasks: number;
   pending_tasks: number;
@@ -87,11 +227,26 @@ export interface Model {
   config: ModelConfig;
   status: ModelStatus;
   max_workers: number;
+  fallback_model_id?: number | null;
+  auto_maintenance: boolean; // 由健康探测自动切换为维护中
   current_workers: number;
   total_requests: number;
   success_requests: number;
+  total_prompt_tokens: number;
+  total_completion_tokens: number;
+  total_cost_usd: number;
   created_at: string;
   updated_at: string;
+  deleted_at?: string | null;
+}
+
+// 模型 Worker 池状态
+export interface WorkerPoolStatus {
+  model_id: number;
+  target_workers: number;
+  current_workers: number;
+  draining_workers: number;
+  max_workers: number;
 }
 
 export interface ModelStats extends Model {
@@ -99,6 +254,49 @@ export interface ModelStats extends Model {
   running_tasks: number;
   success_rate: number;
   avg_response_ms: number;
+  circuit_state?: 'closed' | 'open' | 'half_open'; // 本实例中模型熔断器的状态
+  degraded: boolean; // 熔断中，任务暂不调用上游
+}
+
+// 模型连通性测试结果
+export interface ModelTestResult {
+  model_id: number;
+  success: boolean;
+  latency_ms: number;
+  status_code?: number;
+  output?: string;
+  error?: string;
+}
+
+// 费用统计
+export interface CostBreakdown {
+  name: string; // 模型名称或任务类型
+  tasks: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost_usd: number;
+}
+
+export interface CostSummary {
+  days: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost_usd: number;
+  by_model: CostBreakdown[];
+  by_type: CostBreakdown[];
+}
+
+export interface LatencyPercentiles {
+  model_id?: number;
+  type?: string;
+  hours: number;
+  count: number;
+  sampled: boolean; // 任务数超过上限时只统计最近完成的部分任务
+  p50_ms: number;
+  p90_ms: number;
+  p95_ms: number;
+  p99_ms: number;
+  max_ms: number;
 }
 
 // 任务日志类型
@@ -110,9 +308,16 @@ export interface TaskLog {
   level: LogLevel;
   message: string;
   data?: { [key: string]: any };
+  trace_id?: string;
   created_at: string;
 }
 
+export interface TaskLogListParams {
+  level?: LogLevel;
+  page?: number;
+  page_size?: number;
+}
+
 // 队列状态
 export interface QueueStatus {
   high_priority_count: number;
@@ -121,15 +326,50 @@ export interface QueueStatus {
   processing_count: number;
   delayed_count: number;
   total_count: number;
+  model_queues?: Record<number, ModelQueueCount>; // 开启队列隔离时各模型就绪队列中的任务数
+}
+
+// 模型独立就绪队列中的任务数
+export interface ModelQueueCount {
+  high_priority_count: number;
+  medium_priority_count: number;
+  low_priority_count: number;
+  total_count: number;
+}
+
+// 队列深度样本，用于趋势图
+export interface QueueMetric extends QueueStatus {
+  id: number;
+  sampled_at: string;
+}
+
+// 清空队列结果
+export interface QueuePurgeResult {
+  priority: TaskPriority;
+  removed: number;
+  cancelled: number;
+}
+
+// 队列中的任务
+export interface QueueItem {
+  task_id: number;
+  model_id: number;
+  priority: TaskPriority;
+  created_at: string;
+  delay_count?: number;
+  trace_id?: string;
+  promoted_at?: string; // 因等待过久被提升优先级的时间，priority 为提升后的优先级
 }
 
 // Worker 状态
 export interface WorkerStatus {
   worker_id: string;
+  instance_id: string;
   model_id: number;
   model_name: string;
   status: string;
   current_task_id?: number;
+  task_types?: string[]; // 类型专用 Worker 只领取这些类型的任务
   start_time: string;
   last_heartbeat: string;
 }
@@ -155,6 +395,14 @@ export interface DashboardStats {
   worker_status: WorkerStatus[];
   system_stats: SystemStats;
   recent_tasks: Task[];
+  instances: InstanceWorkerStatus[];
+}
+
+// 单个实例的 Worker 状态
+export interface InstanceWorkerStatus {
+  instance_id: string;
+  last_heartbeat: string;
+  workers: WorkerStatus[];
 }
 
 // 图表数据类型
@@ -168,14 +416,25 @@ export interface ChartData {
 
 // 系统健康状态
 export interface HealthStatus {
-  status: 'ok' | 'error';
+  status: 'ok' | 'degraded' | 'error';
   database: 'ok' | 'error';
   redis: 'ok' | 'error';
   queue: 'ok' | 'error';
+  workers: 'ok' | 'degraded';
   database_error?: string;
   redis_error?: string;
   queue_error?: string;
   queue_status?: QueueStatus;
+  worker_health?: WorkerHealth[];
+  worker_problems?: string[];
+}
+
+export interface WorkerHealth {
+  worker_id: string;
+  model_id: number;
+  status: string;
+  last_heartbeat: string;
+  healthy: boolean;
 }
 
 // 系统信息
@@ -11,12 +11,20 @@ CREATE TABLE IF NOT EXISTS models (
     config JSON NOT NULL COMMENT '模型配置（API Key、参数等）',
     status ENUM('online', 'offline', 'maintenance') DEFAULT 'offline' COMMENT '模型状态',
     max_workers INT DEFAULT 1 COMMENT '最大并发 Worker 数量',
+    fallback_model_id BIGINT NULL COMMENT '离线或维护时改派任务的备用模型ID',
+    auto_maintenance BOOLEAN DEFAULT FALSE COMMENT '是否由健康探测自动切换为维护中',
     current_workers INT DEFAULT 0 COMMENT '当前活跃 Worker 数量',
     total_requests BIGINT DEFAULT 0 COMMENT '总请求次数',
     success_requests BIGINT DEFAULT 0 COMMENT '成功请求次数',
+    total_prompt_tokens BIGINT UNSIGNED DEFAULT 0 COMMENT '累计 prompt token 数',
+    total_completion_tokens BIGINT UNSIGNED DEFAULT 0 COMMENT '累计 completion token 数',
+    total_cost_usd DECIMAL(14,6) DEFAULT 0 COMMENT '累计费用（美元）',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    deleted_at DATETIME NULL COMMENT '删除时间（软删除）',
     INDEX idx_type_status (type, status),
+    INDEX idx_deleted_at (deleted_at),
+    INDEX idx_fallback_model_id (fallback_model_id),
     INDEX idx_updated_at (updated_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模型配置表';
 
@@ -26,12 +34,32 @@ CREATE TABLE IF NOT EXISTS tasks (
     model_id BIGINT NOT NULL COMMENT '关联模型ID',
     type VARCHAR(50) NOT NULL COMMENT '任务类型',
     input TEXT NOT NULL COMMENT '输入内容',
+    params JSON COMMENT '任务附加参数（如翻译目标语言）',
+    tags JSON COMMENT '任务标签（如项目、客户）',
+    provider_override JSON COMMENT '任务级模型服务地址覆盖',
     output TEXT COMMENT '输出内容（完成后填充）',
+    output_truncated BOOLEAN DEFAULT FALSE COMMENT '输出是否因超过长度限制被截断',
+    output_uri VARCHAR(512) COMMENT '输出保存在外部存储时的引用URI',
+    output_format VARCHAR(16) DEFAULT 'text' COMMENT '输出格式：text/json/base64',
     status ENUM('pending', 'running', 'completed', 'failed', 'cancelled') DEFAULT 'pending' COMMENT '任务状态',
     priority TINYINT DEFAULT 1 COMMENT '优先级（1-低，2-中，3-高）',
     retry_count INT DEFAULT 0 COMMENT '已重试次数',
     max_retries INT DEFAULT 3 COMMENT '最大重试次数',
+    depends_on JSON COMMENT '依赖的任务ID列表',
+    waiting_dependencies BOOLEAN DEFAULT FALSE COMMENT '是否在等待依赖任务完成',
+    needs_attention BOOLEAN DEFAULT FALSE COMMENT '反复超时后被升级，需人工处理',
+    prompt_tokens INT DEFAULT 0 COMMENT 'prompt token 数',
+    completion_tokens INT DEFAULT 0 COMMENT 'completion token 数',
+    cost_usd DECIMAL(12,6) DEFAULT 0 COMMENT '费用（美元）',
+    trace_id VARCHAR(64) DEFAULT '' COMMENT '创建任务的请求关联ID（X-Request-ID）',
+    api_key_name VARCHAR(128) DEFAULT '' COMMENT '创建任务的 API Key 名称',
+    cache_key CHAR(64) DEFAULT '' COMMENT '模型、类型、输入和参数的哈希，用于结果缓存',
+    dedup_key VARCHAR(128) DEFAULT '' COMMENT '去重键，同一时间最多一个 pending/running 任务',
+    active_dedup_key VARCHAR(128) GENERATED ALWAYS AS (IF(status IN ('pending', 'running') AND dedup_key <> '', dedup_key, NULL)) VIRTUAL COMMENT '进行中任务的去重键，用于唯一约束',
+    cached_from_id BIGINT COMMENT '结果来自缓存时复用的任务ID',
     error_message TEXT COMMENT '错误信息',
+    deadline DATETIME COMMENT '截止时间，超过后仍在排队的任务被取消',
+    run_at DATETIME COMMENT '计划执行时间，之前任务停留在延迟队列中',
     started_at DATETIME COMMENT '开始执行时间',
     completed_at DATETIME COMMENT '完成时间',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -40,7 +68,15 @@ CREATE TABLE IF NOT EXISTS tasks (
     INDEX idx_model_status (model_id, status),
     INDEX idx_status_priority (status, priority DESC),
     INDEX idx_created_at (created_at DESC),
-    INDEX idx_type (type)
+    INDEX idx_type (type),
+    INDEX idx_waiting_dependencies (waiting_dependencies),
+    INDEX idx_needs_attention (needs_attention),
+    INDEX idx_trace_id (trace_id),
+    INDEX idx_cache_key (cache_key),
+    INDEX idx_dedup_key (dedup_key),
+    INDEX idx_deadline (deadline),
+    UNIQUE INDEX uk_tasks_active_dedup_key (active_dedup_key),
+    FULLTEXT INDEX ft_tasks_content (input, error_message) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务表';
 
 -- 任务日志表
@@ -50,10 +86,12 @@ CREATE TABLE IF NOT EXISTS task_logs (
     level ENUM('info', 'warn', 'error', 'debug') DEFAULT 'info' COMMENT '日志级别',
     message TEXT NOT NULL COMMENT '日志内容',
     data JSON COMMENT '附加数据',
+    trace_id VARCHAR(64) DEFAULT '' COMMENT '所属任务的请求关联ID',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '记录时间',
     FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
     INDEX idx_task_created (task_id, created_at DESC),
-    INDEX idx_level_created (level, created_at DESC)
+    INDEX idx_level_created (level, created_at DESC),
+    INDEX idx_trace_id (trace_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务日志表';
 
 -- 系统统计表
@@ -71,6 +109,74 @@ CREATE TABLE IF NOT EXISTS system_stats (
     INDEX idx_stat_date (stat_date DESC)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='系统统计表';
 
+-- 队列深度样本表
+CREATE TABLE IF NOT EXISTS queue_metrics (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    sampled_at DATETIME NOT NULL COMMENT '采样时间（按采样间隔对齐）',
+    high_priority_count BIGINT DEFAULT 0 COMMENT '高优先级队列任务数',
+    medium_priority_count BIGINT DEFAULT 0 COMMENT '中优先级队列任务数',
+    low_priority_count BIGINT DEFAULT 0 COMMENT '低优先级队列任务数',
+    processing_count BIGINT DEFAULT 0 COMMENT '处理中任务数',
+    delayed_count BIGINT DEFAULT 0 COMMENT '延迟队列任务数',
+    total_count BIGINT DEFAULT 0 COMMENT '任务总数',
+    UNIQUE KEY uk_sampled_at (sampled_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='队列深度样本表';
+
+-- 归档任务表
+CREATE TABLE IF NOT EXISTS archived_tasks (
+    id BIGINT PRIMARY KEY COMMENT '原任务ID',
+    model_id BIGINT NOT NULL COMMENT '使用的模型ID',
+    type VARCHAR(50) NOT NULL COMMENT '任务类型',
+    input TEXT NOT NULL COMMENT '任务输入',
+    params JSON COMMENT '任务参数',
+    tags JSON COMMENT '任务标签',
+    output TEXT COMMENT '任务输出',
+    output_truncated BOOLEAN DEFAULT FALSE COMMENT '输出是否被截断',
+    output_uri VARCHAR(512) COMMENT '输出在外部存储中的引用URI',
+    output_format VARCHAR(16) DEFAULT 'text' COMMENT '输出格式',
+    status ENUM('pending', 'running', 'completed', 'failed', 'cancelled') COMMENT '任务状态',
+    priority TINYINT COMMENT '任务优先级',
+    retry_count INT DEFAULT 0 COMMENT '重试次数',
+    max_retries INT DEFAULT 3 COMMENT '最大重试次数',
+    depends_on JSON COMMENT '依赖的任务ID列表',
+    needs_attention BOOLEAN DEFAULT FALSE COMMENT '是否需要人工处理',
+    prompt_tokens INT DEFAULT 0 COMMENT 'prompt token 数',
+    completion_tokens INT DEFAULT 0 COMMENT 'completion token 数',
+    cost_usd DECIMAL(12,6) DEFAULT 0 COMMENT '费用（美元）',
+    trace_id VARCHAR(64) DEFAULT '' COMMENT '创建任务的请求关联ID',
+    error_message TEXT COMMENT '错误信息',
+    started_at DATETIME NULL COMMENT '开始时间',
+    completed_at DATETIME NULL COMMENT '完成时间',
+    created_at DATETIME COMMENT '创建时间',
+    updated_at DATETIME COMMENT '更新时间',
+    archived_at DATETIME NOT NULL COMMENT '归档时间',
+    INDEX idx_model_id (model_id),
+    INDEX idx_created_at (created_at),
+    INDEX idx_archived_at (archived_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='归档任务表';
+
+-- 定时任务表
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL COMMENT '定时任务名称',
+    cron VARCHAR(100) NOT NULL COMMENT 'cron 表达式',
+    model_id BIGINT NOT NULL COMMENT '使用的模型ID',
+    type VARCHAR(50) NOT NULL COMMENT '任务类型',
+    input_template TEXT NOT NULL COMMENT '输入模板',
+    params JSON COMMENT '任务参数',
+    priority TINYINT DEFAULT 2 COMMENT '任务优先级',
+    enabled BOOLEAN DEFAULT TRUE COMMENT '是否启用',
+    last_run_at DATETIME NULL COMMENT '上次执行时间',
+    next_run_at DATETIME NULL COMMENT '下次执行时间',
+    last_task_id BIGINT NULL COMMENT '上次创建的任务ID',
+    last_error TEXT NULL COMMENT '上次创建任务的错误',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (model_id) REFERENCES models(id) ON DELETE CASCADE,
+    INDEX idx_model_id (model_id),
+    INDEX idx_enabled_next_run (enabled, next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='定时任务表';
+
 -- 插入初始模型配置
 INSERT INTO models (name, type, config, status, max_workers) VALUES 
 (